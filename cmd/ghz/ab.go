@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bojand/ghz/printer"
+	"github.com/bojand/ghz/runner"
+	"go.uber.org/zap"
+)
+
+// runAB runs the phases of the A/B comparison of the config and prints their reports
+func runAB(cfg *runner.Config, logger *zap.SugaredLogger) {
+	if len(cfg.Parallel) > 0 || len(cfg.Scenario) > 0 {
+		handleErrorWithCode(errors.New("an A/B comparison cannot be used with parallel calls and scenarios"), exitSetupError)
+	}
+
+	if cfg.OutputRotate > 0 || strings.TrimSpace(cfg.WireLog) != "" || cfg.SummaryOnly || *dryRun {
+		handleErrorWithCode(errors.New("an A/B comparison does not support output rotation, wire logs, summary only and dry runs"), exitSetupError)
+	}
+
+	// each phase would read the standard input again
+	if readsStdin(cfg) {
+		handleErrorWithCode(errors.New("data and metadata from stdin cannot be used with an A/B comparison"), exitSetupError)
+	}
+
+	b := runner.ABTarget{Host: cfg.ABHost, Authority: cfg.ABAuthority}
+	if b.Host == "" {
+		b.Host = cfg.Host
+	}
+
+	if b.Authority == "" {
+		b.Authority = cfg.Authority
+	}
+
+	options := []runner.Option{runner.WithConfig(cfg)}
+	if logger != nil {
+		options = append(options, runner.WithLogger(logger))
+	}
+
+	if logger != nil {
+		logger.Debugw("Start A/B comparison", "config", cfg)
+	}
+
+	report, err := runner.RunAB(runner.ABRun{
+		Call:    cfg.Call,
+		A:       runner.ABTarget{Host: cfg.Host, Authority: cfg.Authority},
+		B:       b,
+		Rounds:  cfg.ABRounds,
+		Pause:   time.Duration(cfg.ABPause),
+		Options: options,
+	})
+	if err != nil {
+		if logger != nil {
+			logger.Errorf("Error from A/B comparison: %+v", err.Error())
+		}
+
+		// the comparisons failing to start have no report
+		code := exitRunError
+		if report == nil || len(report.Phases) == 0 {
+			code = exitSetupError
+		}
+
+		handleErrorWithCode(err, code)
+	}
+
+	output := os.Stdout
+
+	if outputPath := strings.TrimSpace(cfg.Output); outputPath != "" {
+		f, err := os.Create(outputPath)
+		handleError(err)
+
+		defer func() {
+			handleError(f.Close())
+		}()
+
+		output = f
+	}
+
+	handleError(printer.PrintAB(output, report, cfg.Format))
+
+	for _, rep := range report.Phases {
+		checkStopError(rep)
+		checkThresholds(rep)
+	}
+}
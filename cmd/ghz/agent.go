@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/alecthomas/kingpin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// runAgent runs an agent making the shares of the tests distributed with --agents
+//
+//	ghz agent --port 7777
+func runAgent(args []string) {
+	app := kingpin.New("ghz agent", "Run an agent making a share of the tests run with --agents, streaming the results back to the ghz process running the test.")
+	app.HelpFlag.Short('h')
+
+	port := app.Flag("port", "The port to listen on.").Short('p').Default("7777").Uint()
+	host := app.Flag("host", "The host to listen on. Default is all interfaces.").PlaceHolder(" ").String()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	agent, err := runner.NewAgent()
+	kingpin.FatalIfError(err, "")
+
+	addr := net.JoinHostPort(*host, strconv.FormatUint(uint64(*port), 10))
+
+	lis, err := net.Listen("tcp", addr)
+	handleErrorWithCode(err, exitSetupError)
+
+	s := grpc.NewServer()
+	handleErrorWithCode(agent.Register(s), exitSetupError)
+	reflection.Register(s)
+
+	fmt.Fprintf(os.Stderr, "ghz agent listening on %s\n", lis.Addr())
+
+	handleError(s.Serve(lis))
+}
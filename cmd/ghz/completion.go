@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/bojand/ghz/protodesc"
+)
+
+const fishCompletionScript = `function __complete_ghz
+    set -lx COMP_LINE (commandline -cp)
+    test -z (commandline -ct)
+    and set COMP_LINE "$COMP_LINE "
+    ghz --completion-bash (commandline -opc)[2..-1] (commandline -ct)
+end
+complete -f -c ghz -a "(__complete_ghz)"
+`
+
+// setupCompletion registers the completion hints that depend on other flag values
+// and the fish completion script flag. kingpin provides bash and zsh scripts.
+func setupCompletion(app *kingpin.Application) {
+	app.GetFlag("call").HintAction(completeCall)
+
+	app.Flag("completion-script-fish", "Generate completion script for fish.").
+		Hidden().
+		PreAction(func(*kingpin.ParseContext) error {
+			fmt.Print(fishCompletionScript)
+			os.Exit(0)
+			return nil
+		}).
+		Bool()
+}
+
+// completeCall lists the fully qualified method names available for the call flag.
+// The proto or protoset is used if set, otherwise we try server reflection on the host.
+func completeCall() []string {
+	var names []string
+	var err error
+
+	if p := strings.TrimSpace(*proto); p != "" {
+		imports := []string{filepath.Dir(p), "."}
+		if ip := strings.TrimSpace(*paths); ip != "" {
+			imports = append(strings.Split(ip, ","), imports...)
+		}
+
+		names, err = protodesc.GetMethodNamesFromProto(p, imports)
+	} else if ps := strings.TrimSpace(*protoset); ps != "" {
+		names, err = protodesc.GetMethodNamesFromProtoSet(ps)
+	} else if h := strings.TrimSpace(*host); h != "" {
+		names, err = reflectMethodNames(h)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return names
+}
+
+func reflectMethodNames(target string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *ct)
+	defer cancel()
+
+	cc, err := dialReflection(ctx, target, *insecure, *skipVerify, *cname)
+	if err != nil {
+		return nil, err
+	}
+	defer cc.Close()
+
+	if rmdStr := strings.TrimSpace(*rmd); rmdStr != "" {
+		var rmdMap map[string]string
+		if err := json.Unmarshal([]byte(rmdStr), &rmdMap); err != nil {
+			return nil, err
+		}
+
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(rmdMap))
+	}
+
+	client := grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(cc))
+	defer client.Reset()
+
+	return protodesc.GetMethodNamesFromReflect(client)
+}
+
+// dialReflection connects to the target for use with the reflection client
+func dialReflection(ctx context.Context, target string, insecure, skipVerify bool, cname string) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{grpc.WithBlock()}
+	if insecure {
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		tlsConf := &tls.Config{InsecureSkipVerify: skipVerify, ServerName: cname}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+	}
+
+	return grpc.DialContext(ctx, target, opts...)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecthomas/kingpin"
+
+	"github.com/bojand/ghz/operator"
+)
+
+// runOperator runs the load tests declared as LoadTest resources of a Kubernetes cluster
+//
+//	ghz operator --namespace perf
+func runOperator(args []string) {
+	app := kingpin.New("ghz operator", "Run the load tests declared as LoadTest custom resources of a Kubernetes cluster, writing the results into their status.")
+	app.HelpFlag.Short('h')
+
+	namespace := app.Flag("namespace", "The namespace of the load tests. Default is all namespaces.").PlaceHolder(" ").String()
+	server := app.Flag("server", "The URL of the API server, such as http://localhost:8001 with kubectl proxy. Default is the API server of the cluster the pod runs in.").PlaceHolder(" ").String()
+	tokenFile := app.Flag("token-file", "The file of the bearer token of the API server requests, if --server is set.").PlaceHolder(" ").String()
+	resync := app.Flag("resync", "The interval the load tests are listed again at.").Default(operator.DefaultResync.String()).Duration()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	client := &operator.Client{Server: *server, TokenFile: *tokenFile}
+	if *server == "" {
+		client, err = operator.InClusterClient()
+		handleErrorWithCode(err, exitSetupError)
+	}
+
+	op := operator.New(client)
+	op.Namespace = *namespace
+	op.Resync = *resync
+	op.Log = os.Stderr
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	fmt.Fprintf(os.Stderr, "ghz operator watching the load tests of %s\n", describeNamespace(*namespace))
+
+	handleError(op.Run(ctx))
+}
+
+// describeNamespace returns the description of the namespace of the load tests
+func describeNamespace(namespace string) string {
+	if namespace == "" {
+		return "all namespaces"
+	}
+
+	return "namespace " + namespace
+}
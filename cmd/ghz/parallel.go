@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/bojand/ghz/printer"
+	"github.com/bojand/ghz/runner"
+	"go.uber.org/zap"
+)
+
+// runParallel runs the parallel calls of the config and prints their reports
+func runParallel(cfg *runner.Config, logger *zap.SugaredLogger) {
+	if cfg.OutputRotate > 0 || strings.TrimSpace(cfg.WireLog) != "" || cfg.SummaryOnly || *dryRun {
+		handleErrorWithCode(errors.New("parallel calls do not support output rotation, wire logs, summary only and dry runs"), exitSetupError)
+	}
+
+	runs := make([]runner.ParallelRun, len(cfg.Parallel))
+	for i := range cfg.Parallel {
+		pcfg := cfg.Parallel[i].Config(cfg)
+
+		options := []runner.Option{runner.WithConfig(&pcfg)}
+		if logger != nil {
+			options = append(options, runner.WithLogger(logger))
+		}
+
+		runs[i] = runner.ParallelRun{Name: pcfg.Name, Call: pcfg.Call, Host: pcfg.Host, Options: options}
+	}
+
+	if logger != nil {
+		logger.Debugw("Start parallel runs", "config", cfg)
+	}
+
+	report, err := runner.RunParallel(runs, cfg.ParallelBaseline)
+	if err != nil {
+		if logger != nil {
+			logger.Errorf("Error from parallel runs: %+v", err.Error())
+		}
+
+		// the runs failing to start have no report
+		code := exitRunError
+		if report == nil || len(report.Reports) == 0 {
+			code = exitSetupError
+		} else {
+			for _, rep := range report.Reports {
+				if rep == nil {
+					code = exitSetupError
+				}
+			}
+		}
+
+		handleErrorWithCode(err, code)
+	}
+
+	output := os.Stdout
+
+	if outputPath := strings.TrimSpace(cfg.Output); outputPath != "" {
+		f, err := os.Create(outputPath)
+		handleError(err)
+
+		defer func() {
+			handleError(f.Close())
+		}()
+
+		output = f
+	}
+
+	handleError(printer.PrintParallel(output, report, cfg.Format))
+
+	for _, rep := range report.Reports {
+		checkStopError(rep)
+		checkThresholds(rep)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecthomas/kingpin"
+
+	"github.com/bojand/ghz/load"
+)
+
+// runRateServer runs a token server pacing the tests of multiple processes on the host
+//
+//	ghz rate-server --socket /tmp/ghz-rate.sock --rps 1000
+func runRateServer(args []string) {
+	app := kingpin.New("ghz rate-server", "Run a token server on a unix socket pacing the tests using --rate-socket, so the aggregate rate of all the processes on the host matches the rate.")
+	app.HelpFlag.Short('h')
+
+	socket := app.Flag("socket", "The path of the unix socket to listen on.").Default("/tmp/ghz-rate.sock").String()
+	rps := app.Flag("rps", "The aggregate requests per second of all the processes.").Required().Uint()
+	total := app.Flag("total", "The aggregate number of requests of all the processes after which the tests are stopped. Default is no limit.").
+		Short('n').Default("0").Uint()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	if *rps == 0 {
+		kingpin.Fatalf("rps must be greater than 0")
+	}
+
+	s, err := load.NewTokenServer(*socket, &load.ConstantPacer{Freq: uint64(*rps), Max: uint64(*total)})
+	handleErrorWithCode(err, exitSetupError)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		_ = s.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "ghz rate-server listening on %s at %d requests per second\n", *socket, *rps)
+
+	handleError(s.Serve())
+}
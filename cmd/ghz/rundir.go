@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// runConfigSuffix is the suffix of the effective configs stored along the reports in a directory of runs
+const runConfigSuffix = ".config.json"
+
+// reportExts are the file extensions of the reports of each format written to a directory of runs
+var reportExts = map[string]string{
+	"summary":        ".txt",
+	"csv":            ".csv",
+	"json":           ".json",
+	"pretty":         ".json",
+	"html":           ".html",
+	"influx-summary": ".txt",
+	"influx-details": ".txt",
+	"folded":         ".folded",
+	"parquet":        ".parquet",
+	"openmetrics":    ".txt",
+}
+
+// isRunDir returns whether the output path is an existing directory of runs
+func isRunDir(output string) bool {
+	output = strings.TrimSpace(output)
+	if output == "" || strings.Contains(output, "{{") {
+		return false
+	}
+
+	info, err := os.Stat(output)
+
+	return err == nil && info.IsDir()
+}
+
+// prepareRunDir prints the changes of the effective config from the config of the most recent
+// run stored in the directory, then stores the config of this run and sets the output to the
+// report of this run within the directory
+func prepareRunDir(cfg *runner.Config, w io.Writer) error {
+	dir := strings.TrimSpace(cfg.Output)
+
+	ext, ok := reportExts[cfg.Format]
+	if !ok {
+		ext = ".txt"
+	}
+
+	name := "{{.Date}}-{{.Time}}-{{.Name}}"
+	if cfg.OutputRotate > 0 {
+		name += "-{{.Rotation}}"
+	}
+
+	cfg.Output = filepath.Join(dir, name+ext)
+
+	prevPath, err := latestRunConfig(dir)
+	if err != nil {
+		return err
+	}
+
+	if prevPath != "" {
+		// the stored config is read as is, without the defaults applied when loading configs
+		var prev runner.Config
+		b, err := ioutil.ReadFile(prevPath)
+		if err == nil {
+			err = json.Unmarshal(b, &prev)
+		}
+
+		if err != nil {
+			return fmt.Errorf("error reading the config of the previous run %s: %v", prevPath, err)
+		}
+
+		changes, err := runner.DiffConfig(&prev, cfg)
+		if err != nil {
+			return err
+		}
+
+		printConfigChanges(w, filepath.Base(prevPath), changes)
+	}
+
+	path := filepath.Join(dir, time.Now().Format("2006-01-02-150405")+runConfigSuffix)
+
+	return runner.SaveConfig(path, cfg)
+}
+
+// latestRunConfig returns the path of the most recent config stored in the directory, if any
+func latestRunConfig(dir string) (string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var latest os.FileInfo
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), runConfigSuffix) {
+			continue
+		}
+
+		if latest == nil || !f.ModTime().Before(latest.ModTime()) {
+			latest = f
+		}
+	}
+
+	if latest == nil {
+		return "", nil
+	}
+
+	return filepath.Join(dir, latest.Name()), nil
+}
+
+func printConfigChanges(w io.Writer, prev string, changes []runner.ConfigChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "No config changes since the previous run %s.\n", prev)
+		return
+	}
+
+	fmt.Fprintf(w, "Config changes since the previous run %s:\n", prev)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+}
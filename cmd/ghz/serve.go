@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/alecthomas/kingpin"
+
+	"github.com/bojand/ghz/daemon"
+)
+
+// runServe runs ghz in daemon mode, accepting run configurations over HTTP
+//
+//	ghz serve --port 8080
+func runServe(args []string) {
+	app := kingpin.New("ghz serve", "Run an HTTP server that accepts test run configurations and executes them.")
+	app.HelpFlag.Short('h')
+
+	port := app.Flag("port", "The port to listen on.").Short('p').Default("8080").Uint()
+	host := app.Flag("host", "The host to listen on. Default is all interfaces.").PlaceHolder(" ").String()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	addr := net.JoinHostPort(*host, strconv.FormatUint(uint64(*port), 10))
+
+	fmt.Fprintf(os.Stderr, "ghz serve listening on %s\n", addr)
+
+	handleError(http.ListenAndServe(addr, daemon.New()))
+}
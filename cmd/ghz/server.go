@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/bojand/ghz/internal/echo"
+)
+
+// runServer runs a gRPC echo server for validating test configurations
+//
+//	ghz server --port 50051 --latency 10ms --error-rate 1
+func runServer(args []string) {
+	app := kingpin.New("ghz server", "Run a gRPC echo server with artificial latency and errors, for validating test configurations without a real target.")
+	app.HelpFlag.Short('h')
+
+	port := app.Flag("port", "The port to listen on.").Short('p').Default("50051").Uint()
+	host := app.Flag("host", "The host to listen on. Default is all interfaces.").PlaceHolder(" ").String()
+
+	latency := app.Flag("latency", "The artificial latency of the replies. The mean or minimum latency depending on the distribution.").
+		Default("0").Duration()
+	jitter := app.Flag("latency-jitter", "The spread of the latency distribution.").
+		Default("0").Duration()
+	dist := app.Flag("latency-distribution", "The latency distribution. One of: const, uniform, normal, exponential.").
+		Default(echo.DistConst).Enum(echo.DistConst, echo.DistUniform, echo.DistNormal, echo.DistExponential)
+
+	errorRate := app.Flag("error-rate", "The percentage of replies failing with the error code, between 0 and 100.").
+		Default("0").Float64()
+	errorCode := app.Flag("error-code", "The status code of the injected errors.").
+		Default("UNAVAILABLE").String()
+
+	responseSize := app.Flag("response-size", "The size of the payload of the replies in bytes. Default is to echo the payload of the request.").
+		Default("0").Uint()
+	streamCount := app.Flag("stream-count", "The number of replies to server streaming calls.").
+		Default("10").Uint()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(strings.TrimSpace(*errorCode))))); err != nil {
+		kingpin.Fatalf("unknown error code %q", *errorCode)
+	}
+
+	svc, err := echo.New(echo.Options{
+		Latency:      *latency,
+		Jitter:       *jitter,
+		Distribution: *dist,
+		ErrorRate:    *errorRate,
+		ErrorCode:    code,
+		ResponseSize: int(*responseSize),
+		StreamCount:  int(*streamCount),
+	})
+	kingpin.FatalIfError(err, "")
+
+	addr := net.JoinHostPort(*host, strconv.FormatUint(uint64(*port), 10))
+
+	lis, err := net.Listen("tcp", addr)
+	handleErrorWithCode(err, exitSetupError)
+
+	s := grpc.NewServer()
+	handleErrorWithCode(svc.Register(s), exitSetupError)
+	reflection.Register(s)
+
+	fmt.Fprintf(os.Stderr, "ghz server listening on %s with service %s\n", lis.Addr(), echo.ServiceName)
+
+	handleError(s.Serve(lis))
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/bojand/ghz/runner"
+)
+
+// runWizard interactively builds a run config and writes it to a file
+//
+//	ghz wizard --insecure localhost:50051
+func runWizard(args []string) {
+	app := kingpin.New("ghz wizard", "Interactively build a test run config. Services are listed using server reflection unless a proto or protoset file is given.")
+	app.HelpFlag.Short('h')
+
+	proto := app.Flag("proto", "The Protocol Buffer .proto file.").PlaceHolder(" ").String()
+	protoset := app.Flag("protoset", "The compiled protoset file. Alternative to proto. -proto takes precedence.").PlaceHolder(" ").String()
+	paths := app.Flag("import-paths", "Comma separated list of proto import paths.").Short('i').PlaceHolder(" ").String()
+	insecure := app.Flag("insecure", "Use plaintext and insecure connection.").Bool()
+	skipVerify := app.Flag("skipTLS", "Skip TLS client verification of the server's certificate chain and host name.").Bool()
+	cname := app.Flag("cname", "Server name override when validating TLS certificate.").PlaceHolder(" ").String()
+	ct := app.Flag("connect-timeout", "Connection timeout for the reflection connection.").Default("10s").Duration()
+	output := app.Flag("output", "Path of the config file to write. The format is determined by the extension: .json, .toml or .yaml.").
+		Short('o').Default("ghz.json").String()
+	host := app.Arg("host", "Host and port to test.").Required().String()
+
+	_, err := app.Parse(args)
+	kingpin.FatalIfError(err, "")
+
+	cfg := &runner.Config{
+		Host:          *host,
+		Proto:         strings.TrimSpace(*proto),
+		Protoset:      strings.TrimSpace(*protoset),
+		Insecure:      *insecure,
+		SkipTLSVerify: *skipVerify,
+		CName:         *cname,
+	}
+
+	if ip := strings.TrimSpace(*paths); ip != "" {
+		cfg.ImportPaths = strings.Split(ip, ",")
+	}
+
+	w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+
+	handleErrorWithCode(w.run(cfg, *ct, strings.TrimSpace(*output)), exitSetupError)
+}
+
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (w *wizard) run(cfg *runner.Config, dialTimeout time.Duration, output string) error {
+	var names []string
+	var getMethod func(call string) (*desc.MethodDescriptor, error)
+	var err error
+
+	if cfg.Proto != "" {
+		imports := append(cfg.ImportPaths, filepath.Dir(cfg.Proto), ".")
+		names, err = protodesc.GetMethodNamesFromProto(cfg.Proto, imports)
+		getMethod = func(call string) (*desc.MethodDescriptor, error) {
+			return protodesc.GetMethodDescFromProto(call, cfg.Proto, imports)
+		}
+	} else if cfg.Protoset != "" {
+		names, err = protodesc.GetMethodNamesFromProtoSet(cfg.Protoset)
+		getMethod = func(call string) (*desc.MethodDescriptor, error) {
+			return protodesc.GetMethodDescFromProtoSet(call, cfg.Protoset)
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+
+		cc, dialErr := dialReflection(ctx, cfg.Host, cfg.Insecure, cfg.SkipTLSVerify, cfg.CName)
+		if dialErr != nil {
+			return fmt.Errorf("error connecting to %s: %v", cfg.Host, dialErr)
+		}
+		defer cc.Close()
+
+		client := grpcreflect.NewClient(context.Background(), reflectpb.NewServerReflectionClient(cc))
+		defer client.Reset()
+
+		names, err = protodesc.GetMethodNamesFromReflect(client)
+		getMethod = func(call string) (*desc.MethodDescriptor, error) {
+			return protodesc.GetMethodDescFromReflect(call, client)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return errors.New("no methods found")
+	}
+
+	fmt.Fprintln(w.out, "Methods:")
+	for i, name := range names {
+		fmt.Fprintf(w.out, "  %d) %s\n", i+1, name)
+	}
+
+	call, err := w.choose(names)
+	if err != nil {
+		return err
+	}
+
+	mtd, err := getMethod(call)
+	if err != nil {
+		return err
+	}
+
+	cfg.Call = call
+	cfg.Data = protodesc.MessageTemplate(mtd.GetInputType())
+
+	w.printFields(mtd.GetInputType())
+
+	data, err := json.MarshalIndent(cfg.Data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.out, "\nData template:\n%s\n\n", data)
+
+	n, err := w.askNumber("Total number of requests", 200)
+	if err != nil {
+		return err
+	}
+
+	c, err := w.askNumber("Number of concurrent workers", 50)
+	if err != nil {
+		return err
+	}
+
+	cfg.N = n
+	cfg.C = c
+
+	if output, err = w.ask("Config file", output); err != nil {
+		return err
+	}
+
+	if err := runner.SaveConfig(output, cfg); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.out, "\nConfig written to %s. Edit the data as needed and run it with:\n\n  ghz --config %s\n", output, output)
+
+	return nil
+}
+
+func (w *wizard) printFields(md *desc.MessageDescriptor) {
+	fmt.Fprintf(w.out, "\nInput %s:\n", md.GetFullyQualifiedName())
+
+	tw := tabwriter.NewWriter(w.out, 0, 0, 2, ' ', 0)
+	for _, f := range protodesc.DescribeFields(md) {
+		line := "  " + f.Path + "\t" + f.Type
+		if f.Comment != "" {
+			line += "\t// " + f.Comment
+		}
+
+		fmt.Fprintln(tw, line)
+	}
+
+	_ = tw.Flush()
+}
+
+// choose asks for the number of the option until a valid one is entered
+func (w *wizard) choose(options []string) (string, error) {
+	for {
+		answer, err := w.ask(fmt.Sprintf("Select a method [1-%d]", len(options)), "")
+		if err != nil {
+			return "", err
+		}
+
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			return options[i-1], nil
+		}
+
+		// allow entering the name directly
+		for _, o := range options {
+			if o == answer {
+				return o, nil
+			}
+		}
+	}
+}
+
+func (w *wizard) askNumber(prompt string, def uint) (uint, error) {
+	for {
+		answer, err := w.ask(prompt, strconv.FormatUint(uint64(def), 10))
+		if err != nil {
+			return 0, err
+		}
+
+		if v, err := strconv.ParseUint(answer, 10, 32); err == nil && v > 0 {
+			return uint(v), nil
+		}
+	}
+}
+
+// ask prints the prompt and reads the answer, returning def for an empty answer
+func (w *wizard) ask(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+
+	line, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errors.New("no input")
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+
+	return def, nil
+}
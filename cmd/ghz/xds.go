@@ -0,0 +1,8 @@
+//go:build xds
+// +build xds
+
+package main
+
+// the xDS name resolver and balancers are registered for the xds:/// targets,
+// configured by the bootstrap file of the GRPC_XDS_BOOTSTRAP environment variable
+import _ "google.golang.org/grpc/xds"
@@ -0,0 +1,456 @@
+// Package daemon implements an HTTP API for accepting test run configurations
+// and executing them locally.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// Status of a run
+type Status string
+
+const (
+	// StatusRunning means the run is in progress
+	StatusRunning Status = "running"
+
+	// StatusDone means the run has completed and the report is available
+	StatusDone Status = "done"
+
+	// StatusFailed means the run has failed
+	StatusFailed Status = "failed"
+)
+
+// maxRuns is the maximum number of finished runs we keep around
+const maxRuns = 100
+
+// ErrRunInProgress is returned when a run is requested while another one is in progress
+var ErrRunInProgress = errors.New("another run is in progress")
+
+// ErrRunExists is returned when a run is requested with the ID of a run already kept
+var ErrRunExists = errors.New("a run with the same ID already exists")
+
+// Run is a single test run
+type Run struct {
+	ID       string           `json:"id"`
+	Status   Status           `json:"status"`
+	Error    string           `json:"error,omitempty"`
+	Config   *runner.Config   `json:"config,omitempty"`
+	Progress *runner.Snapshot `json:"progress,omitempty"`
+	Report   *runner.Report   `json:"report,omitempty"`
+
+	reqr *runner.Requester
+	done chan struct{}
+}
+
+// Server accepts run configurations and executes them one at a time
+type Server struct {
+	// ProgressInterval is the interval between progress updates when streaming progress
+	ProgressInterval time.Duration
+
+	lock     sync.Mutex
+	runs     map[string]*Run
+	order    []string
+	active   *Run
+	starting bool
+	mux      *http.ServeMux
+}
+
+// New creates a new server
+func New() *Server {
+	s := &Server{
+		ProgressInterval: time.Second,
+		runs:             make(map[string]*Run),
+		mux:              http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/runs", s.handleRuns)
+	s.mux.HandleFunc("/runs/", s.handleRun)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+//
+//	POST   /runs               start a new run using a JSON config
+//	GET    /runs               list all the runs
+//	GET    /runs/:id           get the run and its report once done
+//	GET    /runs/:id?since=N   get the run with the progress since the snapshot N
+//	GET    /runs/:id/progress  stream the progress of the run as JSON lines
+//	GET    /runs/:id/progress?delta=true  stream the progress since the previous line
+//	DELETE /runs/:id           stop the run
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start starts a new run from the config
+func (s *Server) Start(cfg *runner.Config) (*Run, error) {
+	s.lock.Lock()
+	if s.active != nil || s.starting {
+		s.lock.Unlock()
+		return nil, ErrRunInProgress
+	}
+
+	if _, ok := s.runs[cfg.RunID]; ok {
+		s.lock.Unlock()
+		return nil, ErrRunExists
+	}
+
+	// the requester is created without holding the lock, as it may resolve the method
+	// using server reflection, while the other runs are kept from starting
+	s.starting = true
+	s.lock.Unlock()
+
+	reqr, err := newRequester(cfg)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.starting = false
+	if err != nil {
+		return nil, err
+	}
+
+	// checked again as the ID may have been generated
+	if _, ok := s.runs[cfg.RunID]; ok {
+		return nil, ErrRunExists
+	}
+
+	run := &Run{
+		ID:     cfg.RunID,
+		Status: StatusRunning,
+		Config: cfg,
+		reqr:   reqr,
+		done:   make(chan struct{}),
+	}
+
+	s.add(run)
+	s.active = run
+
+	go s.execute(run)
+
+	return run, nil
+}
+
+// newRequester creates the requester of the config
+func newRequester(cfg *runner.Config) (*runner.Requester, error) {
+	if strings.TrimSpace(cfg.RunID) == "" {
+		cfg.RunID = runner.NewRunID()
+	}
+
+	c, err := runner.NewConfig(cfg.Call, cfg.Host, runner.WithConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.NewRequester(c)
+}
+
+// Stop stops the run with the given ID
+func (s *Server) Stop(id string) bool {
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	s.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	run.reqr.Stop(runner.ReasonCancel)
+
+	return true
+}
+
+// Get returns a copy of the current state of the run
+func (s *Server) Get(id string) (*Run, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, false
+	}
+
+	v, _ := s.view(run, true, false, 0)
+
+	return v, true
+}
+
+// GetSince returns a copy of the current state of the run, with the progress since
+// the snapshot with the sequence number, or since the start of the run if it is 0.
+// runner.ErrSnapshotNotFound is returned if the snapshot is not one of the recent snapshots.
+func (s *Server) GetSince(id string, since uint64) (*Run, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	v, err := s.view(run, true, true, since)
+
+	return v, true, err
+}
+
+// List returns the current state of all the runs without the reports
+func (s *Server) List() []*Run {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	runs := make([]*Run, 0, len(s.order))
+	for _, id := range s.order {
+		v, _ := s.view(s.runs[id], false, false, 0)
+		runs = append(runs, v)
+	}
+
+	return runs
+}
+
+// execute runs the test, which the requester stops once its duration or max duration is up
+// and which fails if any of its thresholds failed
+func (s *Server) execute(run *Run) {
+	report, err := run.reqr.Run()
+	if err == nil && report != nil && !report.ThresholdsPassed() {
+		err = runner.ErrThresholdsFailed
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	run.Report = report
+	run.Status = StatusDone
+	if err != nil {
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+
+	s.active = nil
+	close(run.done)
+}
+
+// add adds the run, dropping the oldest finished runs if needed
+// must be called with the lock held
+func (s *Server) add(run *Run) {
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+
+	for len(s.order) > maxRuns {
+		oldest := s.runs[s.order[0]]
+		if oldest == s.active {
+			break
+		}
+
+		delete(s.runs, oldest.ID)
+		s.order = s.order[1:]
+	}
+}
+
+// view returns a copy of the run with the current progress, or with the progress since
+// the snapshot with the sequence number since if delta is set
+// must be called with the lock held
+func (s *Server) view(run *Run, withReport, delta bool, since uint64) (*Run, error) {
+	v := &Run{
+		ID:     run.ID,
+		Status: run.Status,
+		Error:  run.Error,
+		Config: run.Config,
+	}
+
+	if run.Status == StatusRunning {
+		var snapshot runner.Snapshot
+		var err error
+		if delta {
+			snapshot, err = run.reqr.SnapshotSince(since)
+		} else {
+			snapshot = run.reqr.Snapshot()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		v.Progress = &snapshot
+	}
+
+	if withReport {
+		v.Report = run.Report
+	}
+
+	return v, nil
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.List())
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var cfg runner.Config
+		if err := runner.LoadConfigJSON(body, &cfg); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		run, err := s.Start(&cfg)
+		if err == ErrRunInProgress || err == ErrRunExists {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		v, _ := s.Get(run.ID)
+		writeJSON(w, http.StatusCreated, v)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/runs/"), "/"), "/")
+	id := parts[0]
+
+	if len(parts) == 2 && parts[1] == "progress" && r.Method == http.MethodGet {
+		s.streamProgress(w, r, id)
+		return
+	}
+
+	if len(parts) != 1 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("since") != "" {
+			s.getSince(w, r, id)
+			return
+		}
+
+		run, ok := s.Get(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, errors.New("run not found"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, run)
+	case http.MethodDelete:
+		if !s.Stop(id) {
+			writeError(w, http.StatusNotFound, errors.New("run not found"))
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// getSince writes the run with the progress since the snapshot of the since query parameter
+func (s *Server) getSince(w http.ResponseWriter, r *http.Request, id string) {
+	since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid since: "+err.Error()))
+		return
+	}
+
+	run, ok, err := s.GetSince(id, since)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("run not found"))
+		return
+	}
+
+	// the snapshot is too old, or was not taken
+	if err != nil {
+		writeError(w, http.StatusGone, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
+
+// streamProgress writes the state of the run as a JSON line on every progress interval
+// until the run is done or the client goes away. With the delta query parameter
+// the progress of each line is the progress since the previous line.
+func (s *Server) streamProgress(w http.ResponseWriter, r *http.Request, id string) {
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	s.lock.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("run not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	delta, _ := strconv.ParseBool(r.URL.Query().Get("delta"))
+
+	ticker := time.NewTicker(s.ProgressInterval)
+	defer ticker.Stop()
+
+	var since uint64
+	for {
+		s.lock.Lock()
+		v, err := s.view(run, false, delta, since)
+		s.lock.Unlock()
+
+		if err != nil {
+			return
+		}
+
+		if v.Progress != nil {
+			since = v.Progress.Seq
+		}
+
+		if err := enc.Encode(v); err != nil {
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if v.Status != StatusRunning {
+			return
+		}
+
+		select {
+		case <-run.done:
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"message": err.Error()})
+}
@@ -0,0 +1,288 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	srv := New()
+	srv.ProgressInterval = 100 * time.Millisecond
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	post := func(body string) (*http.Response, Run) {
+		res, err := http.Post(ts.URL+"/runs", "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		var run Run
+		_ = json.NewDecoder(res.Body).Decode(&run)
+		return res, run
+	}
+
+	get := func(id string) Run {
+		res, err := http.Get(ts.URL + "/runs/" + id)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var run Run
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&run))
+		return run
+	}
+
+	wait := func(id string) Run {
+		for i := 0; i < 100; i++ {
+			run := get(id)
+			if run.Status != StatusRunning {
+				return run
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		assert.FailNow(t, "run did not finish")
+		return Run{}
+	}
+
+	t.Run("run to completion", func(t *testing.T) {
+		res, run := post(fmt.Sprintf(`{"proto":"../testdata/greeter.proto","call":"helloworld.Greeter.SayHello","host":"%s","insecure":true,"total":20,"concurrency":2,"data":{"name":"bob"}}`, internal.TestLocalhost))
+
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Len(t, run.ID, 26)
+
+		run = wait(run.ID)
+		assert.Equal(t, StatusDone, run.Status)
+		assert.Empty(t, run.Error)
+		assert.NotNil(t, run.Report)
+		assert.Equal(t, uint64(20), run.Report.Count)
+		assert.Equal(t, run.ID, run.Report.RunID)
+		assert.Equal(t, runner.ReasonNormalEnd, run.Report.EndReason)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		res, _ := post(`{"call":"helloworld.Greeter.SayHello"}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res, _ = post(`{"call":`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, err := http.Get(ts.URL + "/runs/foo")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("progress, conflict and stop", func(t *testing.T) {
+		res, run := post(fmt.Sprintf(`{"proto":"../testdata/greeter.proto","call":"helloworld.Greeter.SayHello","host":"%s","insecure":true,"duration":"20s","concurrency":2,"rps":100,"data":{"name":"bob"}}`, internal.TestLocalhost))
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		id := run.ID
+
+		res, _ = post(fmt.Sprintf(`{"proto":"../testdata/greeter.proto","call":"helloworld.Greeter.SayHello","host":"%s","insecure":true,"data":{"name":"bob"}}`, internal.TestLocalhost))
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+		getSince := func(since string) (*http.Response, Run) {
+			res, err := http.Get(ts.URL + "/runs/" + id + "?since=" + since)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			var run Run
+			_ = json.NewDecoder(res.Body).Decode(&run)
+			return res, run
+		}
+
+		sres, first := getSince("0")
+		assert.Equal(t, http.StatusOK, sres.StatusCode)
+		if assert.NotNil(t, first.Progress) {
+			time.Sleep(200 * time.Millisecond)
+
+			sres, next := getSince(fmt.Sprint(first.Progress.Seq))
+			assert.Equal(t, http.StatusOK, sres.StatusCode)
+			assert.Equal(t, first.Progress.Seq, next.Progress.Since)
+			assert.True(t, next.Progress.Seq > first.Progress.Seq)
+			assert.True(t, next.Progress.Elapsed < time.Second)
+		}
+
+		sres, _ = getSince("1000000")
+		assert.Equal(t, http.StatusGone, sres.StatusCode)
+
+		sres, _ = getSince("foo")
+		assert.Equal(t, http.StatusBadRequest, sres.StatusCode)
+
+		dres, err := http.Get(ts.URL + "/runs/" + id + "/progress?delta=true")
+		assert.NoError(t, err)
+
+		dscanner := bufio.NewScanner(dres.Body)
+		var prev uint64
+		for i := 0; i < 3 && dscanner.Scan(); i++ {
+			var run Run
+			assert.NoError(t, json.Unmarshal(dscanner.Bytes(), &run))
+			assert.Equal(t, prev, run.Progress.Since)
+			prev = run.Progress.Seq
+		}
+		dres.Body.Close()
+
+		pres, err := http.Get(ts.URL + "/runs/" + id + "/progress")
+		assert.NoError(t, err)
+		defer pres.Body.Close()
+		assert.Equal(t, "application/x-ndjson", pres.Header.Get("Content-Type"))
+
+		scanner := bufio.NewScanner(pres.Body)
+		updates := 0
+		var last Run
+		for scanner.Scan() {
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+			updates++
+
+			if updates == 5 {
+				req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/runs/"+id, nil)
+				dres, err := http.DefaultClient.Do(req)
+				assert.NoError(t, err)
+				dres.Body.Close()
+				assert.Equal(t, http.StatusAccepted, dres.StatusCode)
+			}
+		}
+
+		assert.True(t, updates > 5)
+		assert.Equal(t, StatusDone, last.Status)
+
+		run = wait(id)
+		assert.Equal(t, runner.ReasonCancel, run.Report.EndReason)
+		assert.True(t, run.Report.Count > 0)
+
+		lres, err := http.Get(ts.URL + "/runs")
+		assert.NoError(t, err)
+		defer lres.Body.Close()
+
+		var runs []Run
+		assert.NoError(t, json.NewDecoder(lres.Body).Decode(&runs))
+		assert.Len(t, runs, 2)
+		assert.Nil(t, runs[0].Report)
+	})
+
+	t.Run("thresholds failed", func(t *testing.T) {
+		res, run := post(fmt.Sprintf(`{"proto":"../testdata/greeter.proto","call":"helloworld.Greeter.SayHello","host":"%s","insecure":true,"total":10,"concurrency":2,"thresholds":["rps>100000000"],"data":{"name":"bob"}}`, internal.TestLocalhost))
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		run = wait(run.ID)
+		assert.Equal(t, StatusFailed, run.Status)
+		assert.Equal(t, runner.ErrThresholdsFailed.Error(), run.Error)
+		if assert.NotNil(t, run.Report) {
+			assert.Equal(t, uint64(10), run.Report.Count)
+		}
+	})
+
+	t.Run("max duration", func(t *testing.T) {
+		res, run := post(fmt.Sprintf(`{"proto":"../testdata/greeter.proto","call":"helloworld.Greeter.SayHello","host":"%s","insecure":true,"total":100000,"concurrency":2,"rps":50,"max-duration":"300ms","data":{"name":"bob"}}`, internal.TestLocalhost))
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+		run = wait(run.ID)
+		assert.Equal(t, StatusDone, run.Status)
+		if assert.NotNil(t, run.Report) {
+			assert.Equal(t, runner.ReasonTimeout, run.Report.EndReason)
+			assert.True(t, run.Report.Count < 100000)
+		}
+	})
+}
+
+func TestServer_Start_starting(t *testing.T) {
+	srv := New()
+
+	// a run being started keeps the others from starting without blocking the reads
+	srv.lock.Lock()
+	srv.starting = true
+	srv.lock.Unlock()
+
+	_, err := srv.Start(&runner.Config{Call: "helloworld.Greeter.SayHello"})
+	assert.Equal(t, ErrRunInProgress, err)
+	assert.Empty(t, srv.List())
+
+	srv.lock.Lock()
+	srv.starting = false
+	srv.lock.Unlock()
+
+	_, err = srv.Start(&runner.Config{Call: "helloworld.Greeter.SayHello"})
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrRunInProgress, err)
+	assert.False(t, srv.starting)
+}
+
+func TestServer_Start_duplicateID(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	srv := New()
+
+	config := func(id string) *runner.Config {
+		return &runner.Config{
+			Proto:       "../testdata/greeter.proto",
+			Call:        "helloworld.Greeter.SayHello",
+			Host:        internal.TestLocalhost,
+			Insecure:    true,
+			N:           1,
+			C:           1,
+			Connections: 1,
+			RunID:       id,
+		}
+	}
+
+	wait := func(run *Run) {
+		select {
+		case <-run.done:
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "run not done")
+		}
+	}
+
+	run, err := srv.Start(config("dup"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	wait(run)
+
+	_, err = srv.Start(config("dup"))
+	assert.Equal(t, ErrRunExists, err)
+	assert.Len(t, srv.List(), 1)
+
+	// the oldest runs are dropped once the others are started, without leaving a run active
+	for i := 0; i < maxRuns; i++ {
+		run, err = srv.Start(config(fmt.Sprintf("run-%d", i)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		wait(run)
+	}
+
+	assert.Len(t, srv.List(), maxRuns)
+
+	_, ok := srv.Get("dup")
+	assert.False(t, ok)
+
+	run, err = srv.Start(config("dup"))
+	if assert.NoError(t, err) {
+		wait(run)
+	}
+}
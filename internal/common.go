@@ -39,6 +39,23 @@ func StartServer(secure bool) (*helloworld.Greeter, *grpc.Server, error) {
 		opts = []grpc.ServerOption{grpc.Creds(creds)}
 	}
 
+	gs, s := serveGreeter(lis, opts...)
+
+	TestPort = strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)
+	TestLocalhost = "localhost:" + TestPort
+
+	return gs, s, err
+}
+
+// StartServerOnListener starts the plaintext server on the listener, such as a Unix domain
+// socket or an in-memory listener.
+//
+// For testing only.
+func StartServerOnListener(lis net.Listener) (*helloworld.Greeter, *grpc.Server) {
+	return serveGreeter(lis)
+}
+
+func serveGreeter(lis net.Listener, opts ...grpc.ServerOption) (*helloworld.Greeter, *grpc.Server) {
 	stats := helloworld.NewHWStats()
 
 	opts = append(opts, grpc.StatsHandler(stats))
@@ -51,14 +68,11 @@ func StartServer(secure bool) (*helloworld.Greeter, *grpc.Server, error) {
 
 	gs.Stats = stats
 
-	TestPort = strconv.Itoa(lis.Addr().(*net.TCPAddr).Port)
-	TestLocalhost = "localhost:" + TestPort
-
 	go func() {
 		_ = s.Serve(lis)
 	}()
 
-	return gs, s, err
+	return gs, s
 }
 
 // StartSleepServer starts the sleep test server
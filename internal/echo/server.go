@@ -0,0 +1,341 @@
+// Package echo implements a configurable echo service for validating
+// test configurations without a real target.
+package echo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the fully-qualified name of the echo service
+const ServiceName = "echo.Echo"
+
+// Proto is the definition of the echo service
+const Proto = `syntax = "proto3";
+
+package echo;
+
+// Echo replies with the message and payload of the requests
+service Echo {
+  // Echo replies to a single request
+  rpc Echo (EchoRequest) returns (EchoReply) {}
+
+  // EchoServerStream replies to a single request with a stream of replies
+  rpc EchoServerStream (EchoRequest) returns (stream EchoReply) {}
+
+  // EchoClientStream replies to the last request of the stream once the stream is closed
+  rpc EchoClientStream (stream EchoRequest) returns (EchoReply) {}
+
+  // EchoBidi replies to each request of the stream
+  rpc EchoBidi (stream EchoRequest) returns (stream EchoReply) {}
+}
+
+message EchoRequest {
+  string message = 1;
+  bytes payload = 2;
+}
+
+message EchoReply {
+  string message = 1;
+  bytes payload = 2;
+
+  // the number of requests received within the call
+  int64 count = 3;
+}
+`
+
+// Latency distributions
+const (
+	DistConst       = "const"
+	DistUniform     = "uniform"
+	DistNormal      = "normal"
+	DistExponential = "exponential"
+)
+
+// Options are the options of the echo service
+type Options struct {
+	// Latency is the artificial latency added to every reply.
+	// Depending on the distribution it is the mean or the minimum latency.
+	Latency time.Duration
+
+	// Jitter is the spread of the latency distribution
+	Jitter time.Duration
+
+	// Distribution is the latency distribution. One of const, uniform, normal or exponential.
+	// The uniform distribution is within Latency ± Jitter, the normal distribution has a mean
+	// of Latency and a standard deviation of Jitter, and the exponential distribution adds an
+	// exponentially distributed delay with a mean of Jitter to Latency for a long tail.
+	Distribution string
+
+	// ErrorRate is the percentage of replies failing with ErrorCode
+	ErrorRate float64
+
+	// ErrorCode is the status code of the injected errors
+	ErrorCode codes.Code
+
+	// ResponseSize is the size of the payload of the replies in bytes.
+	// If 0 the payload of the request is echoed.
+	ResponseSize int
+
+	// StreamCount is the number of replies to server streaming calls
+	StreamCount int
+}
+
+// Server is the echo service
+type Server struct {
+	opts Options
+	sd   *desc.ServiceDescriptor
+}
+
+// New creates an echo service with the options
+func New(opts Options) (*Server, error) {
+	switch opts.Distribution {
+	case "":
+		opts.Distribution = DistConst
+	case DistConst, DistUniform, DistNormal, DistExponential:
+	default:
+		return nil, fmt.Errorf("unknown latency distribution %q", opts.Distribution)
+	}
+
+	if opts.Latency < 0 || opts.Jitter < 0 {
+		return nil, fmt.Errorf("latency and jitter must not be negative")
+	}
+
+	if opts.ErrorRate < 0 || opts.ErrorRate > 100 {
+		return nil, fmt.Errorf("error rate must be between 0 and 100: %v", opts.ErrorRate)
+	}
+
+	if opts.ErrorRate > 0 && opts.ErrorCode == codes.OK {
+		return nil, fmt.Errorf("error code must not be OK")
+	}
+
+	if opts.ResponseSize < 0 {
+		return nil, fmt.Errorf("response size must not be negative: %v", opts.ResponseSize)
+	}
+
+	if opts.StreamCount <= 0 {
+		opts.StreamCount = 1
+	}
+
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"echo.proto": Proto}),
+	}
+
+	fds, err := p.ParseFiles("echo.proto")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{opts: opts, sd: fds[0].FindService(ServiceName)}, nil
+}
+
+// Register registers the echo service with the gRPC server.
+// The service descriptor is included so the service can be used with server reflection.
+func (s *Server) Register(gs *grpc.Server) error {
+	fdb, err := proto.Marshal(s.sd.GetFile().AsFileDescriptorProto())
+	if err != nil {
+		return err
+	}
+
+	// the reflection service expects a gzipped file descriptor
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(fdb); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	gsd := &grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    buf.Bytes(),
+	}
+
+	for _, mtd := range s.sd.GetMethods() {
+		mtd := mtd
+
+		if !mtd.IsClientStreaming() && !mtd.IsServerStreaming() {
+			gsd.Methods = append(gsd.Methods, grpc.MethodDesc{
+				MethodName: mtd.GetName(),
+				Handler:    s.unaryHandler(mtd),
+			})
+
+			continue
+		}
+
+		gsd.Streams = append(gsd.Streams, grpc.StreamDesc{
+			StreamName:    mtd.GetName(),
+			Handler:       s.streamHandler(mtd),
+			ServerStreams: mtd.IsServerStreaming(),
+			ClientStreams: mtd.IsClientStreaming(),
+		})
+	}
+
+	gs.RegisterService(gsd, s)
+
+	return nil
+}
+
+func (s *Server) unaryHandler(mtd *desc.MethodDescriptor) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := dynamic.NewMessage(mtd.GetInputType())
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.reply(ctx, mtd, req.(*dynamic.Message), 1)
+		}
+
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+
+		info := &grpc.UnaryServerInfo{
+			Server:     s,
+			FullMethod: "/" + ServiceName + "/" + mtd.GetName(),
+		}
+
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+func (s *Server) streamHandler(mtd *desc.MethodDescriptor) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		ctx := stream.Context()
+
+		if !mtd.IsClientStreaming() {
+			req := dynamic.NewMessage(mtd.GetInputType())
+			if err := stream.RecvMsg(req); err != nil {
+				return err
+			}
+
+			for i := 0; i < s.opts.StreamCount; i++ {
+				res, err := s.reply(ctx, mtd, req, 1)
+				if err != nil {
+					return err
+				}
+
+				if err := stream.SendMsg(res); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		var last *dynamic.Message
+		var count int64
+
+		for {
+			req := dynamic.NewMessage(mtd.GetInputType())
+			err := stream.RecvMsg(req)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			count++
+			last = req
+
+			if mtd.IsServerStreaming() {
+				res, err := s.reply(ctx, mtd, req, count)
+				if err != nil {
+					return err
+				}
+
+				if err := stream.SendMsg(res); err != nil {
+					return err
+				}
+			}
+		}
+
+		if mtd.IsServerStreaming() {
+			return nil
+		}
+
+		if last == nil {
+			last = dynamic.NewMessage(mtd.GetInputType())
+		}
+
+		res, err := s.reply(ctx, mtd, last, count)
+		if err != nil {
+			return err
+		}
+
+		return stream.SendMsg(res)
+	}
+}
+
+// reply waits for the artificial latency and returns the reply to the request
+// or the injected error
+func (s *Server) reply(ctx context.Context, mtd *desc.MethodDescriptor, req *dynamic.Message, count int64) (*dynamic.Message, error) {
+	if d := s.latency(); d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	if s.opts.ErrorRate > 0 && rand.Float64()*100 < s.opts.ErrorRate {
+		return nil, status.Errorf(s.opts.ErrorCode, "injected error")
+	}
+
+	payload, _ := req.GetFieldByName("payload").([]byte)
+	if s.opts.ResponseSize > 0 {
+		payload = make([]byte, s.opts.ResponseSize)
+	}
+
+	res := dynamic.NewMessage(mtd.GetOutputType())
+	res.SetFieldByName("message", req.GetFieldByName("message"))
+	res.SetFieldByName("payload", payload)
+	res.SetFieldByName("count", count)
+
+	return res, nil
+}
+
+// latency returns a random latency within the distribution
+func (s *Server) latency() time.Duration {
+	latency := float64(s.opts.Latency)
+	jitter := float64(s.opts.Jitter)
+
+	var d float64
+	switch s.opts.Distribution {
+	case DistUniform:
+		d = latency + (2*rand.Float64()-1)*jitter
+	case DistNormal:
+		d = latency + rand.NormFloat64()*jitter
+	case DistExponential:
+		d = latency + rand.ExpFloat64()*jitter
+	default:
+		d = latency
+	}
+
+	if d < 0 {
+		return 0
+	}
+
+	return time.Duration(d)
+}
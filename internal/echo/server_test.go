@@ -0,0 +1,134 @@
+package echo
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/bojand/ghz/runner"
+)
+
+func startServer(t *testing.T, opts Options) string {
+	svc, err := New(opts)
+	assert.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+
+	s := grpc.NewServer()
+	assert.NoError(t, svc.Register(s))
+	reflection.Register(s)
+
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestNew(t *testing.T) {
+	for _, opts := range []Options{
+		{Distribution: "foo"},
+		{Latency: -time.Second},
+		{ErrorRate: 101},
+		{ErrorRate: 10},
+		{ResponseSize: -1},
+	} {
+		_, err := New(opts)
+		assert.Error(t, err)
+	}
+
+	s, err := New(Options{Latency: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, DistConst, s.opts.Distribution)
+	assert.Equal(t, 1, s.opts.StreamCount)
+	assert.Equal(t, time.Millisecond, s.latency())
+}
+
+func TestServer_latency(t *testing.T) {
+	for _, dist := range []string{DistUniform, DistNormal, DistExponential} {
+		t.Run(dist, func(t *testing.T) {
+			s, err := New(Options{Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond, Distribution: dist})
+			assert.NoError(t, err)
+
+			var sum time.Duration
+			for i := 0; i < 1000; i++ {
+				d := s.latency()
+				assert.True(t, d >= 0)
+
+				if dist == DistUniform {
+					assert.True(t, d >= 5*time.Millisecond && d <= 15*time.Millisecond)
+				}
+
+				if dist == DistExponential {
+					assert.True(t, d >= 10*time.Millisecond)
+				}
+
+				sum += d
+			}
+
+			// the average is close to the mean of the distribution
+			mean := 10 * time.Millisecond
+			if dist == DistExponential {
+				mean = 15 * time.Millisecond
+			}
+
+			assert.InDelta(t, float64(mean), float64(sum/1000), float64(time.Millisecond))
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Run("unary", func(t *testing.T) {
+		host := startServer(t, Options{Latency: 5 * time.Millisecond})
+
+		report, err := runner.Run(ServiceName+".Echo", host,
+			runner.WithInsecure(true),
+			runner.WithTotalRequests(10),
+			runner.WithConcurrency(2),
+			runner.WithData(map[string]interface{}{"message": "hi"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), report.Count)
+		assert.Equal(t, 10, report.StatusCodeDist["OK"])
+		assert.True(t, report.Fastest >= 5*time.Millisecond)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		host := startServer(t, Options{ErrorRate: 100, ErrorCode: codes.ResourceExhausted})
+
+		report, err := runner.Run(ServiceName+".Echo", host,
+			runner.WithInsecure(true),
+			runner.WithTotalRequests(10),
+			runner.WithConcurrency(2),
+			runner.WithData(map[string]interface{}{"message": "hi"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, report.StatusCodeDist["ResourceExhausted"])
+	})
+
+	for _, call := range []string{"EchoServerStream", "EchoClientStream", "EchoBidi"} {
+		t.Run(call, func(t *testing.T) {
+			host := startServer(t, Options{StreamCount: 3, ResponseSize: 64})
+
+			report, err := runner.Run(ServiceName+"."+call, host,
+				runner.WithInsecure(true),
+				runner.WithTotalRequests(5),
+				runner.WithConcurrency(1),
+				runner.WithData([]interface{}{
+					map[string]interface{}{"message": "a"},
+					map[string]interface{}{"message": "b"},
+				}),
+			)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 5, report.StatusCodeDist["OK"])
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package load
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdaptiveAction is a change of the load made by the AdaptivePacer
+type AdaptiveAction struct {
+	// Elapsed is the elapsed duration of the test when the action was taken
+	Elapsed time.Duration `json:"elapsed"`
+
+	// ErrorRate is the error rate within the last interval, between 0 and 1
+	ErrorRate float64 `json:"errorRate"`
+
+	// Factor is the new fraction of the offered load, between MinFactor and 1
+	Factor float64 `json:"factor"`
+}
+
+// AdaptivePacer wraps another pacer and reduces the offered load when the error rate
+// crosses the threshold. Once the error rate recovers the load is ramped back up.
+// The load is reduced by slowing down the time of the wrapped pacer, so load schedules
+// are paused rather than skipped over while backing off.
+type AdaptivePacer struct {
+	Pacer Pacer // The wrapped pacer
+
+	ErrorRate float64       // Error rate threshold between 0 and 1
+	Interval  time.Duration // Interval between load adjustments. Default is 1s
+	Decrease  float64       // Factor the load is multiplied by when backing off. Default is 0.5
+	Increase  float64       // Fraction of the full load added back on each recovered interval. Default is 0.1
+	MinFactor float64       // Minimum fraction of the load. Default is 0.05
+
+	// Feedback returns the total number of results and errors so far
+	Feedback func() (count, errors uint64)
+
+	once sync.Once
+	mu   sync.Mutex
+
+	factor    float64
+	virtual   time.Duration // elapsed time for the wrapped pacer
+	lastReal  time.Duration
+	lastCheck time.Duration
+	lastCount uint64
+	lastErrs  uint64
+	lastHits  uint64
+	baseRate  float64 // observed rate when backing off for unlimited wrapped pacers
+	actions   []AdaptiveAction
+}
+
+func (p *AdaptivePacer) initialize() {
+	p.once.Do(func() {
+		p.factor = 1
+
+		if p.Interval <= 0 {
+			p.Interval = time.Second
+		}
+
+		if p.Decrease <= 0 || p.Decrease >= 1 {
+			p.Decrease = 0.5
+		}
+
+		if p.Increase <= 0 {
+			p.Increase = 0.1
+		}
+
+		if p.MinFactor <= 0 || p.MinFactor > 1 {
+			p.MinFactor = 0.05
+		}
+	})
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *AdaptivePacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	p.initialize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.virtual += time.Duration(float64(elapsed-p.lastReal) * p.factor)
+	p.lastReal = elapsed
+
+	p.adjust(elapsed, hits)
+
+	wait, stop := p.Pacer.Pace(p.virtual, hits)
+	if stop || p.factor >= 1 {
+		return wait, stop
+	}
+
+	if p.Pacer.Rate(p.virtual) == 0 {
+		// the wrapped pacer is not rate limited so space the hits out
+		// based on the rate observed before backing off
+		if p.baseRate <= 0 {
+			return wait, stop
+		}
+
+		return time.Duration(float64(time.Second) / (p.baseRate * p.factor)), false
+	}
+
+	return time.Duration(float64(wait) / p.factor), false
+}
+
+// Rate returns the hit rate at the given elapsed duration.
+// This is the rate of the wrapped pacer scaled by the current load factor.
+func (p *AdaptivePacer) Rate(elapsed time.Duration) float64 {
+	p.initialize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.Pacer.Rate(p.virtual+time.Duration(float64(elapsed-p.lastReal)*p.factor)) * p.factor
+}
+
+// Factor returns the current fraction of the offered load
+func (p *AdaptivePacer) Factor() float64 {
+	p.initialize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.factor
+}
+
+// Actions returns the load adjustments made so far
+func (p *AdaptivePacer) Actions() []AdaptiveAction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]AdaptiveAction(nil), p.actions...)
+}
+
+// String returns a pretty-printed description of the AdaptivePacer's behaviour
+func (p *AdaptivePacer) String() string {
+	return fmt.Sprintf("Adaptive{%v, error rate: %v}", p.Pacer, p.ErrorRate)
+}
+
+// adjust changes the load factor based on the error rate within the last interval
+// must be called with the lock held
+func (p *AdaptivePacer) adjust(elapsed time.Duration, hits uint64) {
+	if p.Feedback == nil || elapsed-p.lastCheck < p.Interval {
+		return
+	}
+
+	count, errs := p.Feedback()
+	window := elapsed - p.lastCheck
+
+	dCount := count - p.lastCount
+	dErrs := errs - p.lastErrs
+	dHits := hits - p.lastHits
+
+	p.lastCheck = elapsed
+	p.lastCount = count
+	p.lastErrs = errs
+	p.lastHits = hits
+
+	if dCount == 0 {
+		return
+	}
+
+	errorRate := float64(dErrs) / float64(dCount)
+
+	factor := p.factor
+	if errorRate >= p.ErrorRate {
+		if factor == 1 {
+			p.baseRate = float64(dHits) / window.Seconds()
+		}
+
+		factor = factor * p.Decrease
+		if factor < p.MinFactor {
+			factor = p.MinFactor
+		}
+	} else if factor < 1 {
+		factor += p.Increase
+		if factor > 1 {
+			factor = 1
+		}
+	}
+
+	if factor != p.factor {
+		p.factor = factor
+		p.actions = append(p.actions, AdaptiveAction{
+			Elapsed:   elapsed,
+			ErrorRate: errorRate,
+			Factor:    factor,
+		})
+	}
+}
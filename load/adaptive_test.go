@@ -0,0 +1,143 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptivePacer(t *testing.T) {
+	t.Run("backs off and recovers", func(t *testing.T) {
+		var count, errs uint64
+
+		p := &AdaptivePacer{
+			Pacer:     &ConstantPacer{Freq: 10},
+			ErrorRate: 0.2,
+			Feedback: func() (uint64, uint64) {
+				return count, errs
+			},
+		}
+
+		wait, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+		assert.Equal(t, 100*time.Millisecond, wait)
+		assert.Equal(t, 1.0, p.Factor())
+
+		// 50% errors in the first second
+		count, errs = 10, 5
+		wait, stop = p.Pace(time.Second, 10)
+		assert.False(t, stop)
+		assert.Equal(t, 0.5, p.Factor())
+		assert.Equal(t, 5.0, p.Rate(time.Second))
+
+		// the wrapped pacer sees half the elapsed time from now on
+		vwait, _ := (&ConstantPacer{Freq: 10}).Pace(time.Second, 10)
+		assert.Equal(t, 2*vwait, wait)
+
+		// still failing
+		count, errs = 15, 9
+		p.Pace(2*time.Second, 15)
+		assert.Equal(t, 0.25, p.Factor())
+
+		// recovered
+		count, errs = 18, 9
+		p.Pace(3*time.Second, 18)
+		assert.InDelta(t, 0.35, p.Factor(), 0.0001)
+
+		// not enough time since last check
+		count, errs = 18, 18
+		p.Pace(3500*time.Millisecond, 18)
+		assert.InDelta(t, 0.35, p.Factor(), 0.0001)
+
+		actions := p.Actions()
+		assert.Len(t, actions, 3)
+		assert.Equal(t, AdaptiveAction{Elapsed: time.Second, ErrorRate: 0.5, Factor: 0.5}, actions[0])
+		assert.Equal(t, 2*time.Second, actions[1].Elapsed)
+		assert.Equal(t, 0.8, actions[1].ErrorRate)
+		assert.Equal(t, 0.25, actions[1].Factor)
+		assert.Equal(t, 0.0, actions[2].ErrorRate)
+	})
+
+	t.Run("recovers fully", func(t *testing.T) {
+		count, errs := uint64(10), uint64(10)
+
+		p := &AdaptivePacer{
+			Pacer:     &ConstantPacer{Freq: 10},
+			ErrorRate: 0.1,
+			Increase:  0.3,
+			Feedback: func() (uint64, uint64) {
+				return count, errs
+			},
+		}
+
+		p.Pace(time.Second, 10)
+		assert.Equal(t, 0.5, p.Factor())
+
+		count = 20
+		p.Pace(2*time.Second, 20)
+		assert.Equal(t, 0.8, p.Factor())
+
+		count = 30
+		p.Pace(3*time.Second, 30)
+		assert.Equal(t, 1.0, p.Factor())
+
+		count = 40
+		p.Pace(4*time.Second, 40)
+		assert.Equal(t, 1.0, p.Factor())
+		assert.Len(t, p.Actions(), 3)
+	})
+
+	t.Run("minimum factor", func(t *testing.T) {
+		var count uint64
+
+		p := &AdaptivePacer{
+			Pacer:     &ConstantPacer{Freq: 10},
+			ErrorRate: 0.1,
+			MinFactor: 0.2,
+			Feedback: func() (uint64, uint64) {
+				return count, count
+			},
+		}
+
+		for i := 1; i <= 5; i++ {
+			count = uint64(i * 10)
+			p.Pace(time.Duration(i)*time.Second, count)
+		}
+
+		assert.Equal(t, 0.2, p.Factor())
+		assert.Len(t, p.Actions(), 3)
+	})
+
+	t.Run("unlimited rate", func(t *testing.T) {
+		var count, errs uint64
+
+		p := &AdaptivePacer{
+			Pacer:     &ConstantPacer{Freq: 0},
+			ErrorRate: 0.5,
+			Feedback: func() (uint64, uint64) {
+				return count, errs
+			},
+		}
+
+		wait, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+		assert.Equal(t, time.Duration(0), wait)
+
+		count, errs = 1000, 1000
+		wait, stop = p.Pace(time.Second, 1000)
+		assert.False(t, stop)
+		assert.Equal(t, 0.5, p.Factor())
+		assert.Equal(t, 2*time.Millisecond, wait)
+	})
+
+	t.Run("stops with wrapped pacer", func(t *testing.T) {
+		p := &AdaptivePacer{
+			Pacer:     &ConstantPacer{Freq: 10, Max: 5},
+			ErrorRate: 0.5,
+		}
+
+		_, stop := p.Pace(time.Second, 5)
+		assert.True(t, stop)
+	})
+}
@@ -0,0 +1,186 @@
+package load
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LatencyAction is an adjustment of the rate made by the LatencyPacer
+type LatencyAction struct {
+	// Elapsed is the elapsed duration of the test when the rate was adjusted
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Latency is the p99 latency of the results within the last interval
+	Latency time.Duration `json:"latency"`
+
+	// Achieved is the rate of results per second within the last interval
+	Achieved float64 `json:"achieved"`
+
+	// Rate is the new offered rate of hits per second
+	Rate float64 `json:"rate"`
+}
+
+// LatencyPacer is a closed loop pacer that adjusts the offered rate to hold the p99 latency
+// at the target. On every interval the rate is multiplied by the output of a PID controller
+// computed from the relative error of the latency to the target.
+type LatencyPacer struct {
+	Target   time.Duration // Target p99 latency
+	Interval time.Duration // Interval between rate adjustments. Default is 1s
+	Start    float64       // Starting rate of hits per second. Default is 10
+	MinRate  float64       // Minimum rate of hits per second. Default is 1
+	MaxRate  float64       // Optional maximum rate of hits per second
+	Max      uint64        // Optional maximum allowed hits
+
+	// Gains of the controller. Defaults are 0.2, 0.5 and 0.1
+	Kp, Ki, Kd float64
+
+	// Feedback returns the total number of results so far and the p99 latency
+	// of the results since it was last called, or 0 if there are none
+	Feedback func() (count uint64, latency time.Duration)
+
+	once sync.Once
+	mu   sync.Mutex
+
+	rate        float64
+	baseElapsed time.Duration // elapsed duration when the rate was last changed
+	baseHits    uint64        // hits when the rate was last changed
+	lastCheck   time.Duration
+	lastCount   uint64
+	lastErr     float64
+	prevErr     float64
+	actions     []LatencyAction
+}
+
+func (p *LatencyPacer) initialize() {
+	p.once.Do(func() {
+		if p.Interval <= 0 {
+			p.Interval = time.Second
+		}
+
+		if p.MinRate <= 0 {
+			p.MinRate = 1
+		}
+
+		if p.Start <= 0 {
+			p.Start = 10
+		}
+
+		if p.Kp == 0 && p.Ki == 0 && p.Kd == 0 {
+			p.Kp, p.Ki, p.Kd = 0.2, 0.5, 0.1
+		}
+
+		p.rate = p.clamp(p.Start)
+	})
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *LatencyPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	p.initialize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Max > 0 && hits >= p.Max {
+		return 0, true
+	}
+
+	p.adjust(elapsed, hits)
+
+	// the hits are spaced out at the current rate since it was last changed,
+	// so a backlog from a higher rate is not carried over
+	sent := float64(hits - p.baseHits)
+	due := p.baseElapsed + time.Duration((sent+1)/p.rate*nano)
+	if due <= elapsed {
+		return 0, false
+	}
+
+	return due - elapsed, false
+}
+
+// Rate returns the current offered rate of hits per second.
+func (p *LatencyPacer) Rate(elapsed time.Duration) float64 {
+	p.initialize()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.rate
+}
+
+// Actions returns the rate adjustments made so far
+func (p *LatencyPacer) Actions() []LatencyAction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]LatencyAction(nil), p.actions...)
+}
+
+// String returns a pretty-printed description of the LatencyPacer's behaviour
+func (p *LatencyPacer) String() string {
+	return fmt.Sprintf("Latency{p99 %v, start %v hits / 1s}", p.Target, p.Start)
+}
+
+// adjust changes the rate based on the latency within the last interval
+// must be called with the lock held
+func (p *LatencyPacer) adjust(elapsed time.Duration, hits uint64) {
+	if p.Feedback == nil || elapsed-p.lastCheck < p.Interval {
+		return
+	}
+
+	count, latency := p.Feedback()
+	window := elapsed - p.lastCheck
+
+	dCount := count - p.lastCount
+
+	p.lastCheck = elapsed
+	p.lastCount = count
+
+	if dCount == 0 || latency <= 0 {
+		return
+	}
+
+	// relative error, positive while the latency is below the target
+	e := float64(p.Target-latency) / float64(p.Target)
+	if e < -1 {
+		e = -1
+	} else if e > 1 {
+		e = 1
+	}
+
+	// velocity form of the PID controller, the output is the relative change of the rate
+	u := p.Kp*(e-p.lastErr) + p.Ki*e + p.Kd*(e-2*p.lastErr+p.prevErr)
+	p.prevErr = p.lastErr
+	p.lastErr = e
+
+	// limit the change within a single interval
+	factor := 1 + u
+	if factor < 0.5 {
+		factor = 0.5
+	} else if factor > 2 {
+		factor = 2
+	}
+
+	p.rate = p.clamp(p.rate * factor)
+	p.baseElapsed = elapsed
+	p.baseHits = hits
+
+	p.actions = append(p.actions, LatencyAction{
+		Elapsed:  elapsed,
+		Latency:  latency,
+		Achieved: float64(dCount) / window.Seconds(),
+		Rate:     p.rate,
+	})
+}
+
+func (p *LatencyPacer) clamp(rate float64) float64 {
+	if p.MaxRate > 0 && rate > p.MaxRate {
+		rate = p.MaxRate
+	}
+
+	if rate < p.MinRate {
+		rate = p.MinRate
+	}
+
+	return rate
+}
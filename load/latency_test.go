@@ -0,0 +1,113 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyPacer(t *testing.T) {
+	t.Run("adjusts the rate to the latency", func(t *testing.T) {
+		var count uint64
+		var latency time.Duration
+
+		p := &LatencyPacer{
+			Target: 100 * time.Millisecond,
+			Start:  10,
+			Kp:     0.5,
+			Ki:     0.5,
+			Kd:     0,
+			Feedback: func() (uint64, time.Duration) {
+				return count, latency
+			},
+		}
+
+		wait, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+		assert.Equal(t, 100*time.Millisecond, wait)
+		assert.Equal(t, 10.0, p.Rate(0))
+
+		// running behind
+		wait, _ = p.Pace(500*time.Millisecond, 2)
+		assert.Equal(t, time.Duration(0), wait)
+
+		// half the target latency in the first second
+		count, latency = 10, 50*time.Millisecond
+		wait, stop = p.Pace(time.Second, 10)
+		assert.False(t, stop)
+		assert.Equal(t, 15.0, p.Rate(time.Second))
+		assert.InDelta(t, float64(time.Second)/15, float64(wait), 1)
+
+		// same error, only the integral term changes the rate
+		count, latency = 25, 50*time.Millisecond
+		p.Pace(2*time.Second, 25)
+		assert.Equal(t, 18.75, p.Rate(0))
+
+		// twice the target latency
+		count, latency = 40, 200*time.Millisecond
+		p.Pace(3*time.Second, 40)
+		assert.Equal(t, 9.375, p.Rate(0))
+
+		// not enough time since last check
+		count, latency = 50, 10*time.Millisecond
+		p.Pace(3500*time.Millisecond, 50)
+		assert.Equal(t, 9.375, p.Rate(0))
+
+		actions := p.Actions()
+		assert.Len(t, actions, 3)
+		assert.Equal(t, LatencyAction{Elapsed: time.Second, Latency: 50 * time.Millisecond, Achieved: 10, Rate: 15}, actions[0])
+		assert.Equal(t, 15.0, actions[1].Achieved)
+		assert.Equal(t, 200*time.Millisecond, actions[2].Latency)
+		assert.Equal(t, 9.375, actions[2].Rate)
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		p := &LatencyPacer{
+			Target: 100 * time.Millisecond,
+			Feedback: func() (uint64, time.Duration) {
+				return 0, 0
+			},
+		}
+
+		p.Pace(time.Second, 10)
+		assert.Equal(t, 10.0, p.Rate(0))
+		assert.Empty(t, p.Actions())
+	})
+
+	t.Run("rate limits", func(t *testing.T) {
+		latency := 10 * time.Millisecond
+		count := uint64(0)
+
+		p := &LatencyPacer{
+			Target:  100 * time.Millisecond,
+			Start:   10,
+			MinRate: 5,
+			MaxRate: 12,
+			Feedback: func() (uint64, time.Duration) {
+				count += 10
+				return count, latency
+			},
+		}
+
+		p.Pace(time.Second, 10)
+		assert.Equal(t, 12.0, p.Rate(0))
+
+		latency = time.Second
+		for i := 2; i < 10; i++ {
+			p.Pace(time.Duration(i)*time.Second, uint64(i*10))
+		}
+
+		assert.Equal(t, 5.0, p.Rate(0))
+	})
+
+	t.Run("stops at max", func(t *testing.T) {
+		p := &LatencyPacer{Target: 100 * time.Millisecond, Max: 10}
+
+		_, stop := p.Pace(time.Second, 9)
+		assert.False(t, stop)
+
+		_, stop = p.Pace(time.Second, 10)
+		assert.True(t, stop)
+	})
+}
@@ -0,0 +1,93 @@
+package load
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OverridePacer is a pacer whose rate can be changed while the test is running.
+// Until the rate is set the hits are paced by the wrapped pacer. Once set the hits
+// are paced at the constant rate from the time of the change.
+type OverridePacer struct {
+	Pacer Pacer  // The wrapped pacer
+	Max   uint64 // Optional maximum allowed hits
+
+	mu      sync.Mutex
+	set     bool
+	pending bool
+	rate    float64
+
+	baseElapsed time.Duration // elapsed duration when the rate was changed
+	baseHits    uint64        // hits when the rate was changed
+}
+
+// SetRate sets the rate of hits per second, 0 meaning no limit.
+// The rate takes effect on the next hit.
+func (p *OverridePacer) SetRate(rate float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rate < 0 {
+		rate = 0
+	}
+
+	p.set = true
+	p.pending = true
+	p.rate = rate
+}
+
+// Pace determines the length of time to sleep until the next hit is sent.
+func (p *OverridePacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.set {
+		return p.Pacer.Pace(elapsed, hits)
+	}
+
+	if p.Max > 0 && hits >= p.Max {
+		return 0, true
+	}
+
+	if p.pending {
+		p.pending = false
+		p.baseElapsed = elapsed
+		p.baseHits = hits
+	}
+
+	if p.rate == 0 {
+		return 0, false
+	}
+
+	due := p.baseElapsed + time.Duration(float64(hits-p.baseHits)/p.rate*nano)
+	if due <= elapsed {
+		return 0, false
+	}
+
+	return due - elapsed, false
+}
+
+// Rate returns the current rate of hits per second.
+func (p *OverridePacer) Rate(elapsed time.Duration) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.set {
+		return p.Pacer.Rate(elapsed)
+	}
+
+	return p.rate
+}
+
+// String returns a pretty-printed description of the OverridePacer's behaviour
+func (p *OverridePacer) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.set {
+		return fmt.Sprintf("Override{%v}", p.Pacer)
+	}
+
+	return fmt.Sprintf("Override{%v hits / 1s}", p.rate)
+}
@@ -0,0 +1,56 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverridePacer(t *testing.T) {
+	t.Run("overrides the rate", func(t *testing.T) {
+		p := &OverridePacer{Pacer: &ConstantPacer{Freq: 10, Max: 100}, Max: 100}
+
+		wait, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+		assert.Equal(t, 100*time.Millisecond, wait)
+		assert.Equal(t, 10.0, p.Rate(0))
+		assert.Equal(t, "Override{Constant{10 hits / 1s}}", p.String())
+
+		p.SetRate(20)
+		assert.Equal(t, 20.0, p.Rate(0))
+
+		// paced from the time of the change
+		wait, stop = p.Pace(2*time.Second, 20)
+		assert.False(t, stop)
+		assert.Equal(t, time.Duration(0), wait)
+
+		wait, _ = p.Pace(2*time.Second, 21)
+		assert.Equal(t, 50*time.Millisecond, wait)
+
+		wait, _ = p.Pace(2500*time.Millisecond, 25)
+		assert.Equal(t, time.Duration(0), wait)
+
+		wait, _ = p.Pace(2500*time.Millisecond, 31)
+		assert.Equal(t, 50*time.Millisecond, wait)
+
+		assert.Equal(t, "Override{20 hits / 1s}", p.String())
+
+		_, stop = p.Pace(3*time.Second, 100)
+		assert.True(t, stop)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		p := &OverridePacer{Pacer: &ConstantPacer{Freq: 1}}
+
+		p.SetRate(0)
+
+		wait, stop := p.Pace(time.Second, 1000)
+		assert.False(t, stop)
+		assert.Equal(t, time.Duration(0), wait)
+		assert.Equal(t, 0.0, p.Rate(0))
+
+		p.SetRate(-1)
+		assert.Equal(t, 0.0, p.Rate(0))
+	})
+}
@@ -0,0 +1,270 @@
+package load
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// the bytes of the token protocol, a client requests a hit and the server replies
+// once the hit is due, or tells the client to stop once the pacer is done
+const (
+	tokenHit  byte = 'h'
+	tokenStop byte = 's'
+)
+
+// TokenServer paces the hits of multiple processes on the same host over a unix socket,
+// so that the aggregate rate of all the processes matches the rate of the pacer.
+// The hits are handed out in the order they are requested. The pacing starts with the
+// first request and starts over once all the clients have disconnected.
+type TokenServer struct {
+	Pacer Pacer // The pacer of the aggregate hits
+
+	ln       net.Listener
+	path     string
+	requests chan net.Conn
+	done     chan struct{}
+
+	mu      sync.Mutex
+	clients map[net.Conn]struct{}
+	reset   bool
+	closed  bool
+}
+
+// NewTokenServer creates a token server listening on the unix socket path.
+// A stale socket file left by a previous server is removed.
+func NewTokenServer(path string, p Pacer) (*TokenServer, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", path); err == nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("a token server is already listening on %s", path)
+		}
+
+		_ = os.Remove(path)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenServer{
+		Pacer:    p,
+		ln:       ln,
+		path:     path,
+		requests: make(chan net.Conn),
+		done:     make(chan struct{}),
+		clients:  make(map[net.Conn]struct{}),
+		reset:    true,
+	}, nil
+}
+
+// Addr returns the address of the socket the server is listening on
+func (s *TokenServer) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Serve accepts the clients and hands out the hits until the server is closed
+func (s *TokenServer) Serve() error {
+	go s.dispatch()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+
+			return nil
+		}
+
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(c)
+	}
+}
+
+// Close stops the server and removes the socket
+func (s *TokenServer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	close(s.done)
+
+	// the clients are disconnected so their goroutines return
+	for c := range s.clients {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.ln.Close()
+
+	if rErr := os.Remove(s.path); rErr != nil && !os.IsNotExist(rErr) && err == nil {
+		err = rErr
+	}
+
+	return err
+}
+
+func (s *TokenServer) serveConn(c net.Conn) {
+	defer func() {
+		_ = c.Close()
+
+		s.mu.Lock()
+		delete(s.clients, c)
+		if len(s.clients) == 0 {
+			s.reset = true
+		}
+		s.mu.Unlock()
+	}()
+
+	buf := make([]byte, 1)
+	for {
+		if _, err := c.Read(buf); err != nil {
+			return
+		}
+
+		if buf[0] == tokenHit {
+			select {
+			case s.requests <- c:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// dispatch replies to the requests in order once the hits are due
+func (s *TokenServer) dispatch() {
+	var began time.Time
+	var hits uint64
+
+	for {
+		var c net.Conn
+		select {
+		case c = <-s.requests:
+		case <-s.done:
+			return
+		}
+
+		s.mu.Lock()
+		if s.reset {
+			s.reset = false
+			began = time.Now()
+			hits = 0
+		}
+		s.mu.Unlock()
+
+		wait, stop := s.Pacer.Pace(time.Since(began), hits)
+		if stop {
+			_, _ = c.Write([]byte{tokenStop})
+			continue
+		}
+
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-s.done:
+				t.Stop()
+				return
+			}
+		}
+
+		hits++
+
+		_, _ = c.Write([]byte{tokenHit})
+	}
+}
+
+// SharedPacer is a pacer whose hits are paced by a TokenServer shared with other processes.
+// Pace blocks until the server hands out the hit. The test stops if the server goes away.
+type SharedPacer struct {
+	Max uint64 // Optional maximum allowed hits
+
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu  sync.Mutex
+	err error
+}
+
+// DialSharedPacer connects to the token server listening on the unix socket path
+func DialSharedPacer(path string, max uint64) (*SharedPacer, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the token server: %v", err)
+	}
+
+	return &SharedPacer{Max: max, conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// Pace waits for the token server to hand out the next hit.
+func (p *SharedPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	if p.Max > 0 && hits >= p.Max {
+		return 0, true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return 0, true
+	}
+
+	if _, err := p.conn.Write([]byte{tokenHit}); err != nil {
+		p.err = err
+		return 0, true
+	}
+
+	b, err := p.r.ReadByte()
+	if err != nil {
+		p.err = err
+		return 0, true
+	}
+
+	return 0, b != tokenHit
+}
+
+// Rate returns 0 as the rate is only known to the token server
+func (p *SharedPacer) Rate(elapsed time.Duration) float64 {
+	return 0
+}
+
+// Err returns the error communicating with the token server, if any
+func (p *SharedPacer) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// Close disconnects from the token server
+func (p *SharedPacer) Close() error {
+	return p.conn.Close()
+}
+
+// String returns a pretty-printed description of the SharedPacer's behaviour
+func (p *SharedPacer) String() string {
+	return fmt.Sprintf("Shared{%v}", p.conn.RemoteAddr())
+}
@@ -0,0 +1,164 @@
+package load
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startTokenServer(t *testing.T, p Pacer) (*TokenServer, string) {
+	path := filepath.Join(t.TempDir(), "rate.sock")
+
+	s, err := NewTokenServer(path, p)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	go func() {
+		_ = s.Serve()
+	}()
+
+	return s, path
+}
+
+func TestSharedPacer(t *testing.T) {
+	t.Run("paces the aggregate rate", func(t *testing.T) {
+		s, path := startTokenServer(t, &ConstantPacer{Freq: 100})
+		defer s.Close()
+
+		var wg sync.WaitGroup
+		start := time.Now()
+
+		for i := 0; i < 2; i++ {
+			p, err := DialSharedPacer(path, 25)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			defer p.Close()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				var hits uint64
+				for {
+					_, stop := p.Pace(time.Since(start), hits)
+					if stop {
+						return
+					}
+
+					hits++
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		// 50 hits at 100 hits per second between both clients
+		elapsed := time.Since(start)
+		assert.True(t, elapsed >= 450*time.Millisecond, "elapsed %v", elapsed)
+		assert.True(t, elapsed < 1500*time.Millisecond, "elapsed %v", elapsed)
+	})
+
+	t.Run("stops when the server pacer is done", func(t *testing.T) {
+		s, path := startTokenServer(t, &ConstantPacer{Freq: 1000, Max: 3})
+		defer s.Close()
+
+		p, err := DialSharedPacer(path, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		defer p.Close()
+
+		var hits uint64
+		for ; hits < 10; hits++ {
+			if _, stop := p.Pace(0, hits); stop {
+				break
+			}
+		}
+
+		assert.Equal(t, uint64(3), hits)
+		assert.NoError(t, p.Err())
+	})
+
+	t.Run("removes the socket on close", func(t *testing.T) {
+		s, path := startTokenServer(t, &ConstantPacer{Freq: 1000})
+
+		p, err := DialSharedPacer(path, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		defer p.Close()
+
+		_, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+
+		assert.NoError(t, s.Close())
+
+		_, err = DialSharedPacer(path, 0)
+		assert.Error(t, err)
+
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+		assert.NoError(t, s.Close())
+	})
+
+	t.Run("stops the pending hits on close", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rate.sock")
+
+		s, err := NewTokenServer(path, &ConstantPacer{Freq: 1})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		served := make(chan error, 1)
+		go func() {
+			served <- s.Serve()
+		}()
+
+		p, err := DialSharedPacer(path, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		defer p.Close()
+
+		// the second hit is due in a second
+		_, stop := p.Pace(0, 0)
+		assert.False(t, stop)
+
+		paced := make(chan bool, 1)
+		go func() {
+			_, stop := p.Pace(0, 1)
+			paced <- stop
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		start := time.Now()
+		assert.NoError(t, s.Close())
+
+		assert.True(t, <-paced)
+		assert.NoError(t, <-served)
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+
+	t.Run("refuses a second server", func(t *testing.T) {
+		s, path := startTokenServer(t, &ConstantPacer{Freq: 1000})
+		defer s.Close()
+
+		_, err := NewTokenServer(path, &ConstantPacer{Freq: 1000})
+		assert.Error(t, err)
+	})
+
+	t.Run("fails without a server", func(t *testing.T) {
+		_, err := DialSharedPacer(filepath.Join(t.TempDir(), "missing.sock"), 0)
+		assert.Error(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package load
 
 import (
+	"sync"
 	"time"
 )
 
@@ -55,6 +56,29 @@ type StepWorkerTicker struct {
 	StepDuration time.Duration // Duration to apply the step change
 	Stop         uint          // Final number of workers
 	MaxDuration  time.Duration // Maximum duration
+
+	// the ticker may be finished while still running, if the run ends before the schedule
+	lock    sync.Mutex
+	stop    chan struct{}
+	runDone chan struct{}
+	started bool
+}
+
+func (c *StepWorkerTicker) init() {
+	if c.stop == nil {
+		c.stop = make(chan struct{})
+		c.runDone = make(chan struct{})
+	}
+}
+
+// send sends the tick value, returning false if the ticker was finished meanwhile
+func (c *StepWorkerTicker) send(tv TickValue) bool {
+	select {
+	case c.C <- tv:
+		return true
+	case <-c.stop:
+		return false
+	}
 }
 
 // Ticker returns the ticker channel.
@@ -64,59 +88,84 @@ func (c *StepWorkerTicker) Ticker() <-chan TickValue {
 
 // Run runs the ticker.
 func (c *StepWorkerTicker) Run() {
+	c.lock.Lock()
+	c.init()
+	select {
+	case <-c.stop:
+		c.lock.Unlock()
+		return
+	default:
+	}
+	c.started = true
+	c.lock.Unlock()
+
+	defer close(c.runDone)
 
 	stepUp := c.Step > 0
 	wc := int(c.Start)
-	done := make(chan bool)
 
 	ticker := time.NewTicker(c.StepDuration)
 	defer ticker.Stop()
 
 	begin := time.Now()
 
-	c.C <- TickValue{Delta: int(c.Start)}
-
-	go func() {
-		for range ticker.C {
-			// we have load duration and we eclipsed it
-			if c.MaxDuration > 0 && time.Since(begin) >= c.MaxDuration {
-				if stepUp && c.Stop > 0 && c.Stop >= uint(wc) {
-					// if we have step up and stop value is > current count
-					// send the final diff
-					c.C <- TickValue{Delta: int(c.Stop - uint(wc)), Done: true}
-				} else if !stepUp && c.Stop > 0 && c.Stop <= uint(wc) {
-					// if we have step down and stop value is < current count
-					// send the final diff
-					c.C <- TickValue{Delta: int(c.Stop - uint(wc)), Done: true}
-				} else {
-					// send done signal
-					c.C <- TickValue{Delta: 0, Done: true}
-				}
-
-				done <- true
-				return
-			} else if (c.MaxDuration == 0) && ((c.Stop > 0 && stepUp && wc >= int(c.Stop)) ||
-				(!stepUp && wc <= int(c.Stop))) {
-				// we do not have load duration
-				// if we have stop and are step up and current count >= stop
-				// or if we have stop and are step down and current count <= stop
+	if !c.send(TickValue{Delta: int(c.Start)}) {
+		return
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+
+		// we have load duration and we eclipsed it
+		if c.MaxDuration > 0 && time.Since(begin) >= c.MaxDuration {
+			if stepUp && c.Stop > 0 && c.Stop >= uint(wc) {
+				// if we have step up and stop value is > current count
+				// send the final diff
+				c.send(TickValue{Delta: int(c.Stop - uint(wc)), Done: true})
+			} else if !stepUp && c.Stop > 0 && c.Stop <= uint(wc) {
+				// if we have step down and stop value is < current count
+				// send the final diff
+				c.send(TickValue{Delta: int(c.Stop - uint(wc)), Done: true})
+			} else {
 				// send done signal
+				c.send(TickValue{Delta: 0, Done: true})
+			}
 
-				c.C <- TickValue{Delta: 0, Done: true}
-				done <- true
+			return
+		} else if (c.MaxDuration == 0) && ((c.Stop > 0 && stepUp && wc >= int(c.Stop)) ||
+			(!stepUp && wc <= int(c.Stop))) {
+			// we do not have load duration
+			// if we have stop and are step up and current count >= stop
+			// or if we have stop and are step down and current count <= stop
+			// send done signal
+
+			c.send(TickValue{Delta: 0, Done: true})
+			return
+		} else {
+			if !c.send(TickValue{Delta: c.Step}) {
 				return
-			} else {
-				c.C <- TickValue{Delta: c.Step}
-				wc = wc + c.Step
 			}
+			wc = wc + c.Step
 		}
-	}()
-
-	<-done
+	}
 }
 
-// Finish closes the channel.
+// Finish stops the ticker if it is still running and closes the channel.
 func (c *StepWorkerTicker) Finish() {
+	c.lock.Lock()
+	c.init()
+	close(c.stop)
+	started := c.started
+	c.lock.Unlock()
+
+	if started {
+		<-c.runDone
+	}
+
 	close(c.C)
 }
 
@@ -130,7 +179,23 @@ type LineWorkerTicker struct {
 	Stop        uint          // Final number of workers
 	MaxDuration time.Duration // Maximum adjustment duration
 
-	stepTicker StepWorkerTicker
+	once       sync.Once
+	stepTicker *StepWorkerTicker
+}
+
+func (c *LineWorkerTicker) ticker() *StepWorkerTicker {
+	c.once.Do(func() {
+		c.stepTicker = &StepWorkerTicker{
+			C:            c.C,
+			Start:        c.Start,
+			Step:         c.Slope,
+			StepDuration: 1 * time.Second,
+			Stop:         c.Stop,
+			MaxDuration:  c.MaxDuration,
+		}
+	})
+
+	return c.stepTicker
 }
 
 // Ticker returns the ticker channel.
@@ -140,20 +205,10 @@ func (c *LineWorkerTicker) Ticker() <-chan TickValue {
 
 // Run runs the ticker.
 func (c *LineWorkerTicker) Run() {
-
-	c.stepTicker = StepWorkerTicker{
-		C:            c.C,
-		Start:        c.Start,
-		Step:         c.Slope,
-		StepDuration: 1 * time.Second,
-		Stop:         c.Stop,
-		MaxDuration:  c.MaxDuration,
-	}
-
-	c.stepTicker.Run()
+	c.ticker().Run()
 }
 
-// Finish closes the internal tick value channel.
+// Finish stops the ticker if it is still running and closes the channel.
 func (c *LineWorkerTicker) Finish() {
-	c.stepTicker.Finish()
+	c.ticker().Finish()
 }
@@ -0,0 +1,238 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The API group, version and resource of the LoadTest custom resource
+const (
+	Group    = "ghz.sh"
+	Version  = "v1alpha1"
+	Resource = "loadtests"
+)
+
+// The credentials of the service account mounted in the pods
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Client is a client of the LoadTest resources of the Kubernetes API server
+type Client struct {
+	// Server is the URL of the API server, such as https://kubernetes.default.svc,
+	// or http://localhost:8001 when using kubectl proxy
+	Server string
+
+	// TokenFile is the file of the bearer token of the requests, if any. It is read on every
+	// request, so the rotated tokens of the service accounts are picked up.
+	TokenFile string
+
+	// HTTPClient is the client of the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// InClusterClient returns the client of the API server of the cluster the pod runs in,
+// authenticated as the service account of the pod
+func InClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	ca, err := ioutil.ReadFile(serviceAccountCA)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", serviceAccountCA)
+	}
+
+	return &Client{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		TokenFile: serviceAccountToken,
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// Event is a change of a LoadTest resource, whose type is ADDED, MODIFIED or DELETED
+type Event struct {
+	Type   string   `json:"type"`
+	Object LoadTest `json:"object"`
+}
+
+// watchEvent is an event as sent by the API server, whose object is the status of the error of ERROR events
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// apiStatus is the status of a failed request
+type apiStatus struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// List lists the LoadTest resources of the namespace, or of all the namespaces if it is empty
+func (c *Client) List(ctx context.Context, namespace string) (*LoadTestList, error) {
+	res, err := c.do(ctx, http.MethodGet, c.url(namespace, "", false), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	list := &LoadTestList{}
+	if err := json.NewDecoder(res.Body).Decode(list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Watch watches the changes of the LoadTest resources of the namespace since the resource version,
+// calling the function with each change until the server ends the watch after the timeout, or the
+// context is done
+func (c *Client) Watch(ctx context.Context, namespace, resourceVersion string, timeout time.Duration, fn func(*Event)) error {
+	q := url.Values{}
+	q.Set("watch", "true")
+	q.Set("resourceVersion", resourceVersion)
+	if timeout > 0 {
+		q.Set("timeoutSeconds", strconv.Itoa(int(timeout.Seconds())))
+	}
+
+	res, err := c.do(ctx, http.MethodGet, c.url(namespace, "", false)+"?"+q.Encode(), "", nil)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var we watchEvent
+		if err := dec.Decode(&we); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if we.Type == "ERROR" {
+			var st apiStatus
+			_ = json.Unmarshal(we.Object, &st)
+
+			return fmt.Errorf("watch error %d: %s", st.Code, st.Message)
+		}
+
+		e := &Event{Type: we.Type}
+		if err := json.Unmarshal(we.Object, &e.Object); err != nil {
+			return err
+		}
+
+		fn(e)
+	}
+}
+
+// UpdateStatus replaces the status of the LoadTest resource
+func (c *Client) UpdateStatus(ctx context.Context, namespace, name string, status *LoadTestStatus) error {
+	body, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+
+	res, err := c.do(ctx, http.MethodPatch, c.url(namespace, name, true), "application/merge-patch+json", body)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return res.Body.Close()
+}
+
+// url returns the URL of the resources of the namespace, or of the resource, or of its status
+func (c *Client) url(namespace, name string, status bool) string {
+	p := "/apis/" + Group + "/" + Version
+	if namespace != "" {
+		p += "/namespaces/" + url.PathEscape(namespace)
+	}
+
+	p += "/" + Resource
+	if name != "" {
+		p += "/" + url.PathEscape(name)
+	}
+
+	if status {
+		p += "/status"
+	}
+
+	return strings.TrimSuffix(c.Server, "/") + p
+}
+
+// do sends the request, returning an error with the message of the API server if it failed
+func (c *Client) do(ctx context.Context, method, u, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if c.TokenFile != "" {
+		token, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+
+		var st apiStatus
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		if json.Unmarshal(msg, &st) == nil && st.Message != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, u, res.Status, st.Message)
+		}
+
+		return nil, fmt.Errorf("%s %s: %s: %s", method, u, res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,132 @@
+package operator
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// Phase is the phase of a load test
+type Phase string
+
+const (
+	// PhasePending means the load test waits for the run of another load test to complete
+	PhasePending Phase = "Pending"
+
+	// PhaseRunning means the run of the load test is in progress
+	PhaseRunning Phase = "Running"
+
+	// PhaseSucceeded means the run has completed and all the thresholds passed
+	PhaseSucceeded Phase = "Succeeded"
+
+	// PhaseFailed means the run could not be started, failed, or some of the thresholds failed
+	PhaseFailed Phase = "Failed"
+)
+
+// LoadTest is a load test declared as a custom resource
+type LoadTest struct {
+	APIVersion string         `json:"apiVersion,omitempty"`
+	Kind       string         `json:"kind,omitempty"`
+	Metadata   ObjectMeta     `json:"metadata"`
+	Spec       LoadTestSpec   `json:"spec"`
+	Status     LoadTestStatus `json:"status"`
+}
+
+// LoadTestList is a list of LoadTest resources
+type LoadTestList struct {
+	Metadata ListMeta   `json:"metadata"`
+	Items    []LoadTest `json:"items"`
+}
+
+// ObjectMeta is the metadata of a resource
+type ObjectMeta struct {
+	Name              string     `json:"name"`
+	Namespace         string     `json:"namespace,omitempty"`
+	UID               string     `json:"uid,omitempty"`
+	ResourceVersion   string     `json:"resourceVersion,omitempty"`
+	Generation        int64      `json:"generation,omitempty"`
+	DeletionTimestamp *time.Time `json:"deletionTimestamp,omitempty"`
+}
+
+// ListMeta is the metadata of a list of resources
+type ListMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// LoadTestSpec is the run of a load test
+type LoadTestSpec struct {
+	// Config is the config of the run, with the same properties and defaults as the config file
+	Config json.RawMessage `json:"config"`
+
+	// Artifacts is the directory the report of the run is written to, or the http or https URL
+	// the report is uploaded to, if any
+	Artifacts string `json:"artifacts,omitempty"`
+
+	// Format is the format of the report, json by default
+	Format string `json:"format,omitempty"`
+}
+
+// LoadTestStatus is the status of the run of a load test. The fields are not omitted when empty,
+// so the status of a previous run is replaced as a whole when the status is updated.
+type LoadTestStatus struct {
+	Phase   Phase  `json:"phase"`
+	Message string `json:"message"`
+	RunID   string `json:"runId"`
+
+	// ObservedGeneration is the generation of the spec of the run, so it is run again once the spec changes
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	StartTime      *time.Time `json:"startTime"`
+	CompletionTime *time.Time `json:"completionTime"`
+
+	Summary *Summary `json:"summary"`
+
+	// Artifact is the path or the URL of the report of the run
+	Artifact string `json:"artifact"`
+}
+
+// Summary is the summary of the report of a run. The latencies are formatted as durations.
+type Summary struct {
+	Count     uint64            `json:"count"`
+	Errors    int               `json:"errors"`
+	Rps       float64           `json:"rps"`
+	Average   string            `json:"average"`
+	Fastest   string            `json:"fastest"`
+	Slowest   string            `json:"slowest"`
+	P99       string            `json:"p99,omitempty"`
+	EndReason runner.StopReason `json:"endReason"`
+
+	Thresholds []runner.ThresholdResult `json:"thresholds,omitempty"`
+}
+
+// summarize returns the summary of the report
+func summarize(report *runner.Report) *Summary {
+	s := &Summary{
+		Count:      report.Count,
+		Rps:        math.Round(report.Rps*100) / 100,
+		Average:    report.Average.String(),
+		Fastest:    report.Fastest.String(),
+		Slowest:    report.Slowest.String(),
+		EndReason:  report.EndReason,
+		Thresholds: report.Thresholds,
+	}
+
+	for _, n := range report.ErrorDist {
+		s.Errors += n
+	}
+
+	for _, ld := range report.LatencyDistribution {
+		if ld.Percentage == 99 {
+			s.P99 = ld.Latency.String()
+		}
+	}
+
+	return s
+}
+
+// key returns the namespace and the name of the load test
+func (lt *LoadTest) key() string {
+	return lt.Metadata.Namespace + "/" + lt.Metadata.Name
+}
@@ -0,0 +1,426 @@
+// Package operator runs the load tests declared as LoadTest custom resources of a Kubernetes
+// cluster, writing the results into the status of the resources and the reports to their
+// artifacts, so the load tests can be declared in GitOps workflows.
+package operator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/printer"
+	"github.com/bojand/ghz/runner"
+)
+
+// DefaultResync is the default interval the resources are listed again at
+const DefaultResync = 5 * time.Minute
+
+// retryDelay is the delay before listing the resources again after a failed request
+const retryDelay = 5 * time.Second
+
+// updateTimeout is the timeout of the status updates made once a run is done
+const updateTimeout = 30 * time.Second
+
+// reportExtensions are the extensions of the report files of the formats, txt for the others
+var reportExtensions = map[string]string{
+	"json":    "json",
+	"pretty":  "json",
+	"csv":     "csv",
+	"html":    "html",
+	"parquet": "parquet",
+}
+
+// Operator watches the LoadTest resources and runs them one at a time, in the order they are listed
+type Operator struct {
+	// Namespace is the namespace of the watched resources, all the namespaces if empty
+	Namespace string
+
+	// Resync is the interval the resources are listed again at, in case a change was missed
+	Resync time.Duration
+
+	// Log is where the changes of the phases of the load tests are written, if any
+	Log io.Writer
+
+	client *Client
+
+	lock     sync.Mutex
+	active   *activeRun
+	finished chan struct{}
+	runs     sync.WaitGroup
+}
+
+// activeRun is the run in progress
+type activeRun struct {
+	key string
+	uid string
+
+	// the requester of the run, which is nil for the distributed runs
+	reqr *runner.Requester
+}
+
+// New creates a new operator
+func New(client *Client) *Operator {
+	return &Operator{
+		Resync:   DefaultResync,
+		client:   client,
+		finished: make(chan struct{}, 1),
+	}
+}
+
+// Run watches the resources and runs the load tests until the context is done. The run in
+// progress is then stopped, and its status updated with the results gathered so far.
+func (o *Operator) Run(ctx context.Context) error {
+	defer o.runs.Wait()
+	defer o.stop("")
+
+	for {
+		rv, err := o.sync(ctx)
+		if err == nil {
+			err = o.watch(ctx, rv)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			o.logf("Error watching the load tests: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
+
+// sync reconciles all the listed resources, returning the resource version of the list
+func (o *Operator) sync(ctx context.Context) (string, error) {
+	list, err := o.client.List(ctx, o.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range list.Items {
+		o.reconcile(ctx, &list.Items[i])
+	}
+
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch reconciles the changed resources until the resync interval has passed, or a run is done
+// so the pending load tests are listed again
+func (o *Operator) watch(ctx context.Context, rv string) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan *Event)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- o.client.Watch(wctx, o.Namespace, rv, o.Resync, func(e *Event) {
+			select {
+			case events <- e:
+			case <-wctx.Done():
+			}
+		})
+	}()
+
+	for {
+		select {
+		case e := <-events:
+			if e.Type == "DELETED" {
+				o.stop(e.Object.Metadata.UID)
+				continue
+			}
+
+			o.reconcile(ctx, &e.Object)
+		case <-o.finished:
+			cancel()
+			<-done
+
+			return nil
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+// reconcile starts the run of a new or changed load test if no other run is in progress
+func (o *Operator) reconcile(ctx context.Context, lt *LoadTest) {
+	o.lock.Lock()
+	active := o.active
+	o.lock.Unlock()
+
+	if lt.Metadata.DeletionTimestamp != nil {
+		o.stop(lt.Metadata.UID)
+		return
+	}
+
+	switch lt.Status.Phase {
+	case PhaseRunning:
+		// the run is not in progress if the operator was restarted meanwhile
+		if active == nil || active.uid != lt.Metadata.UID {
+			o.update(ctx, lt, &LoadTestStatus{
+				Phase:              PhaseFailed,
+				Message:            "the run was interrupted by a restart of the operator",
+				RunID:              lt.Status.RunID,
+				ObservedGeneration: lt.Status.ObservedGeneration,
+				StartTime:          lt.Status.StartTime,
+			})
+		}
+
+		return
+	case PhaseSucceeded, PhaseFailed:
+		if lt.Status.ObservedGeneration >= lt.Metadata.Generation {
+			return
+		}
+	}
+
+	if active != nil {
+		if lt.Status.Phase != PhasePending {
+			o.update(ctx, lt, &LoadTestStatus{
+				Phase:              PhasePending,
+				Message:            "waiting for the run of " + active.key,
+				ObservedGeneration: lt.Metadata.Generation,
+			})
+		}
+
+		return
+	}
+
+	o.start(ctx, lt)
+}
+
+// start starts the run of the load test
+func (o *Operator) start(ctx context.Context, lt *LoadTest) {
+	failed := func(err error) {
+		o.update(ctx, lt, &LoadTestStatus{
+			Phase:              PhaseFailed,
+			Message:            err.Error(),
+			ObservedGeneration: lt.Metadata.Generation,
+		})
+	}
+
+	var cfg runner.Config
+	if err := runner.LoadConfigJSON(lt.Spec.Config, &cfg); err != nil {
+		failed(fmt.Errorf("invalid config: %v", err))
+		return
+	}
+
+	if strings.TrimSpace(cfg.RunID) == "" {
+		cfg.RunID = runner.NewRunID()
+	}
+
+	if strings.TrimSpace(cfg.Name) == "" {
+		cfg.Name = lt.key()
+	}
+
+	run := &activeRun{key: lt.key(), uid: lt.Metadata.UID}
+
+	// the distributed runs are set up by the agents
+	if len(cfg.Agents) == 0 {
+		c, err := runner.NewConfig(cfg.Call, cfg.Host, runner.WithConfig(&cfg))
+		if err != nil {
+			failed(err)
+			return
+		}
+
+		if run.reqr, err = runner.NewRequester(c); err != nil {
+			failed(err)
+			return
+		}
+	}
+
+	now := time.Now()
+	status := &LoadTestStatus{
+		Phase:              PhaseRunning,
+		RunID:              cfg.RunID,
+		ObservedGeneration: lt.Metadata.Generation,
+		StartTime:          &now,
+	}
+
+	if !o.update(ctx, lt, status) {
+		return
+	}
+
+	o.lock.Lock()
+	o.active = run
+	o.lock.Unlock()
+
+	o.runs.Add(1)
+
+	go func() {
+		defer o.runs.Done()
+
+		o.execute(lt, &cfg, run, status)
+	}()
+}
+
+// execute executes the run, updating the status of the load test with the results once done
+func (o *Operator) execute(lt *LoadTest, cfg *runner.Config, run *activeRun, status *LoadTestStatus) {
+	var report *runner.Report
+	var err error
+
+	if run.reqr != nil {
+		// the requester stops the run once its duration or max duration is up
+		report, err = run.reqr.Run()
+		if err == nil && report != nil && !report.ThresholdsPassed() {
+			err = runner.ErrThresholdsFailed
+		}
+
+		if err == nil && report != nil && report.EndReason == runner.ReasonCancel {
+			err = errors.New("the run was stopped")
+		}
+	} else {
+		report, err = runner.RunDistributed(cfg)
+	}
+
+	now := time.Now()
+	status.CompletionTime = &now
+	status.Phase = PhaseSucceeded
+
+	if err != nil {
+		status.Phase = PhaseFailed
+		status.Message = err.Error()
+	}
+
+	if report != nil {
+		status.Summary = summarize(report)
+
+		artifact, aerr := o.writeArtifact(lt.Spec, report)
+		if aerr != nil {
+			status.Message = strings.TrimPrefix(status.Message+"; error writing the report: "+aerr.Error(), "; ")
+		}
+
+		status.Artifact = artifact
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	o.update(ctx, lt, status)
+
+	o.lock.Lock()
+	o.active = nil
+	o.lock.Unlock()
+
+	select {
+	case o.finished <- struct{}{}:
+	default:
+	}
+}
+
+// stop stops the run in progress of the load test with the UID, or any run in progress if it is empty
+func (o *Operator) stop(uid string) {
+	o.lock.Lock()
+	active := o.active
+	o.lock.Unlock()
+
+	if active == nil || (uid != "" && active.uid != uid) {
+		return
+	}
+
+	// the distributed runs cannot be stopped and run to completion
+	if active.reqr != nil {
+		active.reqr.Stop(runner.ReasonCancel)
+	}
+}
+
+// update replaces the status of the load test, returning whether it was updated
+func (o *Operator) update(ctx context.Context, lt *LoadTest, status *LoadTestStatus) bool {
+	if err := o.client.UpdateStatus(ctx, lt.Metadata.Namespace, lt.Metadata.Name, status); err != nil {
+		o.logf("Error updating the status of %s: %v", lt.key(), err)
+		return false
+	}
+
+	lt.Status = *status
+
+	if status.Message != "" {
+		o.logf("%s: %s: %s", lt.key(), status.Phase, status.Message)
+	} else {
+		o.logf("%s: %s", lt.key(), status.Phase)
+	}
+
+	return true
+}
+
+// writeArtifact writes the report to the artifacts of the load test, returning the path or the URL
+// of the report. The report is named after the run ID, and uploaded using PUT if the artifacts are
+// at an http or https URL.
+func (o *Operator) writeArtifact(spec LoadTestSpec, report *runner.Report) (string, error) {
+	artifacts := strings.TrimSpace(spec.Artifacts)
+	if artifacts == "" {
+		return "", nil
+	}
+
+	format := spec.Format
+	if format == "" {
+		format = "json"
+	}
+
+	ext, ok := reportExtensions[format]
+	if !ok {
+		ext = "txt"
+	}
+
+	buf := &bytes.Buffer{}
+	p := printer.ReportPrinter{Out: buf, Report: report}
+	if err := p.Print(format); err != nil {
+		return "", err
+	}
+
+	name := report.RunID + "." + ext
+
+	if !strings.HasPrefix(artifacts, "http://") && !strings.HasPrefix(artifacts, "https://") {
+		if err := os.MkdirAll(artifacts, 0755); err != nil {
+			return "", err
+		}
+
+		path := filepath.Join(artifacts, name)
+
+		return path, ioutil.WriteFile(path, buf.Bytes(), 0644)
+	}
+
+	u := strings.TrimSuffix(artifacts, "/") + "/" + name
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPut, u, buf)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", errors.New("PUT " + u + ": " + res.Status)
+	}
+
+	return u, nil
+}
+
+func (o *Operator) logf(format string, args ...interface{}) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.Log != nil {
+		fmt.Fprintf(o.Log, format+"\n", args...)
+	}
+}
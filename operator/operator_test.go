@@ -0,0 +1,346 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+// apiServer is a fake API server of the LoadTest resources of the default namespace
+type apiServer struct {
+	lock   sync.Mutex
+	items  map[string]*LoadTest
+	order  []string
+	phases map[string][]Phase
+	tokens []string
+}
+
+func newAPIServer(items ...*LoadTest) *apiServer {
+	s := &apiServer{items: make(map[string]*LoadTest), phases: make(map[string][]Phase)}
+	for _, lt := range items {
+		s.items[lt.Metadata.Name] = lt
+		s.order = append(s.order, lt.Metadata.Name)
+	}
+
+	return s
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.tokens = append(s.tokens, r.Header.Get("Authorization"))
+	s.lock.Unlock()
+
+	prefix := "/apis/ghz.sh/v1alpha1/namespaces/default/loadtests"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/status")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("watch") == "true":
+		// no changes are sent until the watch is closed
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	case r.Method == http.MethodGet:
+		s.lock.Lock()
+		list := LoadTestList{Metadata: ListMeta{ResourceVersion: "1"}}
+		for _, n := range s.order {
+			list.Items = append(list.Items, *s.items[n])
+		}
+		s.lock.Unlock()
+
+		_ = json.NewEncoder(w).Encode(list)
+	case r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/status"):
+		var patch struct {
+			Status LoadTestStatus `json:"status"`
+		}
+
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" || json.NewDecoder(r.Body).Decode(&patch) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		lt, ok := s.items[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind":"Status","message":"loadtests \"` + name + `\" not found","code":404}`))
+			return
+		}
+
+		lt.Status = patch.Status
+		s.phases[name] = append(s.phases[name], patch.Status.Phase)
+
+		_ = json.NewEncoder(w).Encode(lt)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// status returns the status of the load test
+func (s *apiServer) status(name string) LoadTestStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.items[name].Status
+}
+
+func loadTest(name string, config string) *LoadTest {
+	return &LoadTest{
+		Metadata: ObjectMeta{Name: name, Namespace: "default", UID: name + "-uid", Generation: 1},
+		Spec:     LoadTestSpec{Config: json.RawMessage(config)},
+	}
+}
+
+func TestOperator(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	dir, err := ioutil.TempDir("", "ghz-operator")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600))
+
+	config := `{"proto": "../testdata/greeter.proto", "call": "helloworld.Greeter.SayHello", "host": "` +
+		internal.TestLocalhost + `", "insecure": true, "total": 10, "data": {"name": "bob"}`
+
+	// the rate keeps the first run in progress while the others are listed
+	smoke := loadTest("smoke", config+`, "run-id": "smoke-1", "rps": 50}`)
+	smoke.Spec.Artifacts = filepath.Join(dir, "reports")
+
+	slo := loadTest("slo", config+`, "thresholds": ["rps>100000000"]}`)
+
+	invalid := loadTest("invalid", strings.Replace(config, "greeter.proto", "missing.proto", 1)+`}`)
+
+	interrupted := loadTest("interrupted", config+`}`)
+	interrupted.Status = LoadTestStatus{Phase: PhaseRunning, RunID: "run1", ObservedGeneration: 1}
+
+	done := loadTest("done", config+`}`)
+	done.Status = LoadTestStatus{Phase: PhaseSucceeded, RunID: "run2", ObservedGeneration: 1}
+
+	api := newAPIServer(smoke, slo, invalid, interrupted, done)
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	op := New(&Client{Server: ts.URL, TokenFile: tokenFile})
+	op.Namespace = "default"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- op.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if api.status("slo").Phase == PhaseFailed && api.status("invalid").Phase == PhaseFailed {
+			break
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	assert.NoError(t, <-stopped)
+
+	st := api.status("smoke")
+	assert.Equal(t, PhaseSucceeded, st.Phase)
+	assert.Equal(t, "smoke-1", st.RunID)
+	assert.Equal(t, int64(1), st.ObservedGeneration)
+	assert.NotNil(t, st.StartTime)
+	assert.NotNil(t, st.CompletionTime)
+	if assert.NotNil(t, st.Summary) {
+		assert.Equal(t, uint64(10), st.Summary.Count)
+		assert.Equal(t, 0, st.Summary.Errors)
+		assert.NotEmpty(t, st.Summary.P99)
+	}
+
+	assert.Equal(t, filepath.Join(dir, "reports", "smoke-1.json"), st.Artifact)
+
+	b, err := ioutil.ReadFile(st.Artifact)
+	assert.NoError(t, err)
+
+	var report runner.Report
+	assert.NoError(t, json.Unmarshal(b, &report))
+	assert.Equal(t, "default/smoke", report.Name)
+	assert.Equal(t, uint64(10), report.Count)
+
+	st = api.status("slo")
+	assert.Equal(t, PhaseFailed, st.Phase)
+	assert.Equal(t, runner.ErrThresholdsFailed.Error(), st.Message)
+	if assert.NotNil(t, st.Summary) && assert.Len(t, st.Summary.Thresholds, 1) {
+		assert.False(t, st.Summary.Thresholds[0].Pass)
+	}
+
+	st = api.status("invalid")
+	assert.Equal(t, PhaseFailed, st.Phase)
+	assert.Nil(t, st.StartTime)
+	assert.NotEmpty(t, st.Message)
+
+	st = api.status("interrupted")
+	assert.Equal(t, PhaseFailed, st.Phase)
+	assert.Equal(t, "the run was interrupted by a restart of the operator", st.Message)
+	assert.Equal(t, "run1", st.RunID)
+
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	// the load tests listed during a run wait for it to complete
+	assert.Equal(t, []Phase{PhaseRunning, PhaseSucceeded}, api.phases["smoke"])
+	assert.Equal(t, []Phase{PhasePending, PhaseRunning, PhaseFailed}, api.phases["slo"])
+	assert.Equal(t, []Phase{PhasePending, PhaseFailed}, api.phases["invalid"])
+	assert.Empty(t, api.phases["done"])
+
+	for _, token := range api.tokens {
+		assert.Equal(t, "Bearer secret", token)
+	}
+}
+
+func TestOperator_maxDuration(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	limited := loadTest("limited", `{"proto": "../testdata/greeter.proto", "call": "helloworld.Greeter.SayHello", "host": "`+
+		internal.TestLocalhost+`", "insecure": true, "total": 100000, "rps": 50, "max-duration": "300ms", "data": {"name": "bob"}}`)
+
+	api := newAPIServer(limited)
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	op := New(&Client{Server: ts.URL})
+	op.Namespace = "default"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+
+	go func() {
+		stopped <- op.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) && api.status("limited").Phase != PhaseSucceeded {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	assert.NoError(t, <-stopped)
+
+	st := api.status("limited")
+	assert.Equal(t, PhaseSucceeded, st.Phase)
+	if assert.NotNil(t, st.Summary) {
+		assert.Equal(t, runner.ReasonTimeout, st.Summary.EndReason)
+		assert.True(t, st.Summary.Count < 100000)
+	}
+}
+
+func TestOperator_reconcile(t *testing.T) {
+	t.Run("changed spec", func(t *testing.T) {
+		lt := loadTest("changed", `{"call": "helloworld.Greeter.SayHello"}`)
+		lt.Metadata.Generation = 2
+		lt.Status = LoadTestStatus{Phase: PhaseSucceeded, RunID: "run1", ObservedGeneration: 1}
+
+		api := newAPIServer(lt)
+		ts := httptest.NewServer(api)
+		defer ts.Close()
+
+		op := New(&Client{Server: ts.URL})
+		op.active = &activeRun{key: "default/other", uid: "other"}
+
+		op.reconcile(context.Background(), lt)
+
+		st := api.status("changed")
+		assert.Equal(t, PhasePending, st.Phase)
+		assert.Equal(t, "waiting for the run of default/other", st.Message)
+		assert.Equal(t, int64(2), st.ObservedGeneration)
+		assert.Nil(t, st.Summary)
+		assert.Equal(t, []Phase{PhasePending}, api.phases["changed"])
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		op := New(&Client{})
+
+		// stopping a distributed run or another load test is a no-op
+		op.active = &activeRun{key: "default/other", uid: "other"}
+		op.stop("deleted-uid")
+		op.stop("other")
+	})
+}
+
+func TestClient(t *testing.T) {
+	t.Run("url", func(t *testing.T) {
+		c := &Client{Server: "https://kubernetes.default.svc/"}
+
+		assert.Equal(t, "https://kubernetes.default.svc/apis/ghz.sh/v1alpha1/loadtests", c.url("", "", false))
+		assert.Equal(t, "https://kubernetes.default.svc/apis/ghz.sh/v1alpha1/namespaces/perf/loadtests/smoke/status",
+			c.url("perf", "smoke", true))
+	})
+
+	t.Run("in cluster", func(t *testing.T) {
+		os.Unsetenv("KUBERNETES_SERVICE_HOST")
+
+		_, err := InClusterClient()
+		assert.Error(t, err)
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "true", r.URL.Query().Get("watch"))
+			assert.Equal(t, "42", r.URL.Query().Get("resourceVersion"))
+			assert.Equal(t, "60", r.URL.Query().Get("timeoutSeconds"))
+
+			_, _ = w.Write([]byte(`{"type": "ADDED", "object": {"metadata": {"name": "smoke", "uid": "1"}}}` + "\n"))
+			_, _ = w.Write([]byte(`{"type": "DELETED", "object": {"metadata": {"name": "smoke", "uid": "1"}}}` + "\n"))
+			_, _ = w.Write([]byte(`{"type": "ERROR", "object": {"kind": "Status", "message": "too old resource version", "code": 410}}` + "\n"))
+		}))
+		defer ts.Close()
+
+		var events []string
+		err := (&Client{Server: ts.URL}).Watch(context.Background(), "", "42", time.Minute, func(e *Event) {
+			events = append(events, e.Type+" "+e.Object.Metadata.Name)
+		})
+
+		assert.EqualError(t, err, "watch error 410: too old resource version")
+		assert.Equal(t, []string{"ADDED smoke", "DELETED smoke"}, events)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"kind": "Status", "message": "loadtests.ghz.sh is forbidden", "code": 403}`))
+		}))
+		defer ts.Close()
+
+		_, err := (&Client{Server: ts.URL}).List(context.Background(), "")
+		assert.EqualError(t, err, "GET "+ts.URL+"/apis/ghz.sh/v1alpha1/loadtests: 403 Forbidden: loadtests.ghz.sh is forbidden")
+	})
+}
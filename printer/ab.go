@@ -0,0 +1,99 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// PrintAB prints the phases of the A/B comparison followed by the comparison of the targets.
+// The json and pretty formats print the whole report as JSON. The other formats are not supported.
+func PrintAB(out io.Writer, r *runner.ABReport, format string) error {
+	switch format {
+	case "json", "pretty":
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		if format == "pretty" {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, b, "", "  "); err != nil {
+				return err
+			}
+
+			b = buf.Bytes()
+		}
+
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	case "", "summary":
+	default:
+		return fmt.Errorf("format %s is not supported for an A/B comparison", format)
+	}
+
+	_, err := fmt.Fprint(out, "\nPhases:\n"+formatABPhases(r.Phases)+"\nComparison:\n"+formatABComparison(r)+"\n")
+	return err
+}
+
+func formatABPhases(phases []*runner.Report) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Phase\tTarget\tCount\tRequests/sec\tAverage\tp99\tErrors\t\n")
+	for i, rep := range phases {
+		_, _ = fmt.Fprintf(w, "  %d\t%s\t%d\t%s\t%s\t%s\t%.2f %%\t\n", i+1, rep.Name, rep.Count,
+			formatSeconds(rep.Rps), formatNanoUnit(rep.Average), formatNanoUnit(abP99(rep)), abErrorRate(rep))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatABComparison(r *runner.ABReport) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Target\tPhases\tCount\tRequests/sec\tAverage\tp99\tErrors\t\n")
+	_, _ = fmt.Fprintf(w, "  A %s\t%d\t%d\t%s\t%s\t%s\t%.2f %%\t\n", r.A.Name, r.A.Phases, r.A.Count,
+		formatSeconds(r.A.Rps), formatNanoUnit(r.A.Average), formatNanoUnit(r.A.P99), r.A.ErrorRate)
+	_, _ = fmt.Fprintf(w, "  B %s\t%d\t%d\t%s (%s)\t%s (%s)\t%s (%s)\t%.2f %%\t\n", r.B.Name, r.B.Phases, r.B.Count,
+		formatSeconds(r.B.Rps), formatChange(r.RpsChange),
+		formatNanoUnit(r.B.Average), formatChange(r.AverageChange),
+		formatNanoUnit(r.B.P99), formatChange(r.P99Change),
+		r.B.ErrorRate)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func abP99(r *runner.Report) time.Duration {
+	for _, ld := range r.LatencyDistribution {
+		if ld.Percentage == 99 {
+			return ld.Latency
+		}
+	}
+
+	return 0
+}
+
+// abErrorRate returns the percentage of the calls of the report which failed
+func abErrorRate(r *runner.Report) float64 {
+	if r.Count == 0 {
+		return 0
+	}
+
+	errs := 0
+	for _, n := range r.ErrorDist {
+		errs += n
+	}
+
+	return float64(errs) / float64(r.Count) * 100
+}
@@ -0,0 +1,100 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// PrintEstimate prints the upfront estimate of a run.
+// The json and pretty formats print the estimate as JSON, any other format prints the summary.
+func PrintEstimate(out io.Writer, e *runner.Estimate, format string) error {
+	if format == "json" || format == "pretty" {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if format == "pretty" {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, b, "", "  "); err != nil {
+				return err
+			}
+
+			b = buf.Bytes()
+		}
+
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	}
+
+	_, err := fmt.Fprint(out, "\nEstimate:\n"+formatEstimate(e)+"\n")
+	return err
+}
+
+func formatEstimate(e *runner.Estimate) string {
+	latencyBound := "depends on the latency, the requests are not rate-paced"
+
+	unbound := latencyBound
+	if e.Unlimited {
+		unbound = "unlimited, the run goes on until interrupted"
+	}
+
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Call:\t%s\n", e.Call)
+	_, _ = fmt.Fprintf(w, "  Host:\t%s\n", e.Host)
+	_, _ = fmt.Fprintf(w, "  Concurrency:\t%d workers, %d connections\n", e.Concurrency, e.Connections)
+
+	if e.Requests > 0 {
+		_, _ = fmt.Fprintf(w, "  Requests:\t%d\n", e.Requests)
+	} else {
+		_, _ = fmt.Fprintf(w, "  Requests:\t%s\n", unbound)
+	}
+
+	if e.Duration > 0 {
+		_, _ = fmt.Fprintf(w, "  Duration:\t%s\n", formatEstimateDuration(e.Duration))
+	} else {
+		_, _ = fmt.Fprintf(w, "  Duration:\t%s\n", unbound)
+	}
+
+	if e.Paced && e.Unlimited && e.PeakRate == 0 {
+		_, _ = fmt.Fprintf(w, "  Peak rate:\t%s\n", unbound)
+	} else if e.Paced {
+		_, _ = fmt.Fprintf(w, "  Peak rate:\t%s requests/sec\n", formatSeconds(e.PeakRate))
+	} else {
+		_, _ = fmt.Fprintf(w, "  Peak rate:\t%s\n", latencyBound)
+	}
+
+	if e.MessagesPerCall > 0 {
+		if e.MessagesPerCall > 1 {
+			_, _ = fmt.Fprintf(w, "  Request size:\t%s x %d messages per call\n", formatBytes(uint64(e.RequestSize)), e.MessagesPerCall)
+		} else {
+			_, _ = fmt.Fprintf(w, "  Request size:\t%s\n", formatBytes(uint64(e.RequestSize)))
+		}
+
+		if e.Paced {
+			_, _ = fmt.Fprintf(w, "  Peak bandwidth:\t%s/sec\n", formatBytes(uint64(e.PeakBandwidth)))
+		}
+	} else {
+		_, _ = fmt.Fprintf(w, "  Request size:\tunknown, use a proto file or a protoset\n")
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatEstimateDuration(d time.Duration) string {
+	if d >= time.Minute {
+		return d.Round(time.Second).String()
+	}
+
+	return d.Round(time.Millisecond).String()
+}
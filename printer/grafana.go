@@ -0,0 +1,235 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// The data sources of the Grafana dashboard
+const (
+	// GrafanaPrometheus queries the metrics of the openmetrics format
+	GrafanaPrometheus = "prometheus"
+
+	// GrafanaInflux queries the ghz_run measurement of the influx-summary format
+	GrafanaInflux = "influx"
+)
+
+// grafanaDashboard is a dashboard in the format of the Grafana dashboard export, whose data source
+// input is selected when the dashboard is imported
+type grafanaDashboard struct {
+	Inputs        []grafanaInput `json:"__inputs"`
+	UID           string         `json:"uid"`
+	Title         string         `json:"title"`
+	Tags          []string       `json:"tags"`
+	Time          grafanaTime    `json:"time"`
+	SchemaVersion int            `json:"schemaVersion"`
+	Panels        []grafanaPanel `json:"panels"`
+}
+
+type grafanaInput struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	PluginID   string `json:"pluginId"`
+	PluginName string `json:"pluginName"`
+}
+
+type grafanaTime struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Type        string             `json:"type"`
+	Title       string             `json:"title"`
+	Datasource  string             `json:"datasource"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	FieldConfig grafanaFieldConfig `json:"fieldConfig"`
+	Targets     []grafanaTarget    `json:"targets"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit"`
+	} `json:"defaults"`
+}
+
+type grafanaTarget struct {
+	RefID string `json:"refId"`
+
+	// the PromQL query of Prometheus
+	Expr         string `json:"expr,omitempty"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+
+	// the raw InfluxQL query of InfluxDB
+	Query        string `json:"query,omitempty"`
+	RawQuery     bool   `json:"rawQuery,omitempty"`
+	ResultFormat string `json:"resultFormat,omitempty"`
+	Alias        string `json:"alias,omitempty"`
+}
+
+// grafanaQuery is a query of a panel with its legend
+type grafanaQuery struct {
+	legend string
+	query  string
+}
+
+// PrintGrafanaDashboard prints a Grafana dashboard to be imported, whose panels query the statistics
+// of the runs with the same name, call, host and tags as the report, either in Prometheus from the
+// openmetrics format or in InfluxDB from the influx-summary format. The dashboard has the same UID
+// for all of these runs, so importing it again replaces it.
+func (rp *ReportPrinter) PrintGrafanaDashboard(datasource string) error {
+	r := rp.Report
+
+	var input grafanaInput
+	var panels []grafanaPanel
+
+	switch datasource {
+	case GrafanaPrometheus:
+		input = grafanaInput{Name: "DS_PROMETHEUS", Label: "Prometheus", Type: "datasource", PluginID: "prometheus", PluginName: "Prometheus"}
+
+		labels := rp.openMetricsLabels()
+		selector := func(metric, extra string) string {
+			if extra != "" {
+				return metric + "{" + labels + "," + extra + "}"
+			}
+
+			return metric + "{" + labels + "}"
+		}
+
+		panels = []grafanaPanel{
+			grafanaPromPanel("Requests per second", "reqps", grafanaQuery{"rps", selector("ghz_requests_per_second", "")}),
+			grafanaPromPanel("Latency", "s",
+				grafanaQuery{"p{{quantile}}", selector("ghz_latency_seconds", `quantile=~"0.5|0.9|0.95|0.99"`)},
+				grafanaQuery{"average", selector("ghz_latency_seconds_sum", "") + " / " + selector("ghz_latency_seconds_count", "")}),
+			grafanaPromPanel("Requests", "short",
+				grafanaQuery{"requests", selector("ghz_requests_total", "")},
+				grafanaQuery{"errors", selector("ghz_errors_total", "")}),
+			grafanaPromPanel("Responses by status", "short", grafanaQuery{"{{status}}", selector("ghz_responses_total", "")}),
+		}
+	case GrafanaInflux:
+		input = grafanaInput{Name: "DS_INFLUXDB", Label: "InfluxDB", Type: "datasource", PluginID: "influxdb", PluginName: "InfluxDB"}
+
+		// the values of the tags of the influx formats are quoted, and the tags of the run are a JSON string
+		where := make([]string, 0, 4)
+		if r.Name != "" {
+			where = append(where, influxQLMatch("name", `"`+strings.TrimSpace(r.Name)+`"`))
+		}
+
+		callTags := `""`
+		if len(r.Tags) > 0 {
+			if b, err := json.Marshal(r.Tags); err == nil {
+				if b, err = json.Marshal(string(b)); err == nil {
+					callTags = string(b)
+				}
+			}
+		}
+
+		where = append(where,
+			influxQLMatch("call", `"`+r.Options.Call+`"`),
+			influxQLMatch("host", `"`+r.Options.Host+`"`),
+			influxQLMatch("tags", callTags))
+		filter := strings.Join(where, " AND ")
+
+		query := func(fields string) string {
+			return fmt.Sprintf(`SELECT %s FROM "ghz_run" WHERE %s AND $timeFilter GROUP BY time($__interval) fill(none)`, fields, filter)
+		}
+
+		panels = []grafanaPanel{
+			grafanaInfluxPanel("Requests per second", "reqps", grafanaQuery{"rps", query(`mean("rps")`)}),
+			grafanaInfluxPanel("Latency", "ns",
+				grafanaQuery{"average", query(`mean("average")`)},
+				grafanaQuery{"median", query(`mean("median")`)},
+				grafanaQuery{"p95", query(`mean("p95")`)}),
+			grafanaInfluxPanel("Requests", "short",
+				grafanaQuery{"requests", query(`sum("count")`)},
+				grafanaQuery{"errors", query(`sum("errors")`)}),
+			grafanaInfluxPanel("Duration", "ns", grafanaQuery{"duration", query(`mean("total")`)}),
+		}
+	default:
+		return fmt.Errorf("unknown Grafana data source: %s", datasource)
+	}
+
+	for i := range panels {
+		panels[i].ID = i + 1
+		panels[i].Datasource = "${" + input.Name + "}"
+		panels[i].GridPos = grafanaGridPos{H: 8, W: 12, X: (i % 2) * 12, Y: (i / 2) * 8}
+	}
+
+	title := r.Name
+	if title == "" {
+		title = r.Options.Call
+	}
+
+	dashboard := grafanaDashboard{
+		Inputs:        []grafanaInput{input},
+		UID:           grafanaUID(datasource, rp.openMetricsLabels()),
+		Title:         "ghz: " + title,
+		Tags:          []string{"ghz"},
+		Time:          grafanaTime{From: "now-7d", To: "now"},
+		SchemaVersion: 27,
+		Panels:        panels,
+	}
+
+	b, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return rp.print(string(b) + "\n")
+}
+
+// grafanaPromPanel returns a time series panel of the Prometheus queries
+func grafanaPromPanel(title, unit string, queries ...grafanaQuery) grafanaPanel {
+	p := grafanaPanel{Type: "timeseries", Title: title}
+	p.FieldConfig.Defaults.Unit = unit
+
+	for i, q := range queries {
+		p.Targets = append(p.Targets, grafanaTarget{RefID: string(rune('A' + i)), Expr: q.query, LegendFormat: q.legend})
+	}
+
+	return p
+}
+
+// grafanaInfluxPanel returns a time series panel of the raw InfluxQL queries
+func grafanaInfluxPanel(title, unit string, queries ...grafanaQuery) grafanaPanel {
+	p := grafanaPanel{Type: "timeseries", Title: title}
+	p.FieldConfig.Defaults.Unit = unit
+
+	for i, q := range queries {
+		p.Targets = append(p.Targets, grafanaTarget{
+			RefID:        string(rune('A' + i)),
+			Query:        q.query,
+			RawQuery:     true,
+			ResultFormat: "time_series",
+			Alias:        q.legend,
+		})
+	}
+
+	return p
+}
+
+var influxQLEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// influxQLMatch returns the condition matching the value of the tag
+func influxQLMatch(tag, value string) string {
+	return fmt.Sprintf(`"%s" = '%s'`, tag, influxQLEscaper.Replace(value))
+}
+
+// grafanaUID returns the UID of the dashboard of the runs with the labels
+func grafanaUID(datasource, labels string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(datasource + "\n" + labels))
+
+	return fmt.Sprintf("ghz-%x", h.Sum64())
+}
@@ -0,0 +1,171 @@
+package printer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// invalidMetricChars matches the characters not allowed in metric and label names
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// openMetricsName returns the name as a valid metric or label name
+func openMetricsName(name string) string {
+	name = invalidMetricChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+var openMetricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// openMetricsFamily is a metric family with its samples
+type openMetricsFamily struct {
+	name    string
+	typ     string
+	help    string
+	unit    string
+	samples []string
+}
+
+// sample adds a sample of the family with the suffix and the extra label, if any
+func (f *openMetricsFamily) sample(suffix, labels, extra string, v float64) {
+	if extra != "" {
+		if labels != "" {
+			labels += ","
+		}
+
+		labels += extra
+	}
+
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+
+	f.samples = append(f.samples, fmt.Sprintf("%s%s%s %s", f.name, suffix, labels, strconv.FormatFloat(v, 'g', -1, 64)))
+}
+
+// openMetricsLabels returns the labels of all the samples: the name, call and host of the run
+// and its tags. The sample timestamps are left out, as they are not supported by the textfile
+// collector of the node exporter nor the Pushgateway.
+func (rp *ReportPrinter) openMetricsLabels() string {
+	labels := map[string]string{}
+	for k, v := range rp.Report.Tags {
+		labels[openMetricsName(k)] = v
+	}
+
+	if rp.Report.Name != "" {
+		labels["name"] = rp.Report.Name
+	}
+
+	labels["call"] = rp.Report.Options.Call
+	labels["host"] = rp.Report.Options.Host
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = fmt.Sprintf(`%s="%s"`, k, openMetricsLabelEscaper.Replace(labels[k]))
+	}
+
+	return strings.Join(s, ",")
+}
+
+// printOpenMetrics prints the summary metrics of the run in the OpenMetrics text format,
+// to be collected by the textfile collector of the node exporter or pushed to a Pushgateway
+func (rp *ReportPrinter) printOpenMetrics() error {
+	r := rp.Report
+	labels := rp.openMetricsLabels()
+
+	var families []*openMetricsFamily
+	family := func(name, typ, unit, help string) *openMetricsFamily {
+		f := &openMetricsFamily{name: name, typ: typ, unit: unit, help: help}
+		families = append(families, f)
+		return f
+	}
+
+	family("ghz_requests", "counter", "", "Number of calls of the run.").
+		sample("_total", labels, "", float64(r.Count))
+
+	family("ghz_errors", "counter", "", "Number of calls of the run which failed.").
+		sample("_total", labels, "", float64(rp.errorCount()))
+
+	responses := family("ghz_responses", "counter", "", "Number of calls of the run by status code.")
+	for _, code := range sortedCodes(r.StatusCodeDist) {
+		responses.sample("_total", labels, fmt.Sprintf(`status="%s"`, openMetricsLabelEscaper.Replace(code)), float64(r.StatusCodeDist[code]))
+	}
+
+	family("ghz_duration_seconds", "gauge", "seconds", "Duration of the run.").
+		sample("", labels, "", r.Total.Seconds())
+
+	family("ghz_requests_per_second", "gauge", "", "Rate of the calls of the run.").
+		sample("", labels, "", r.Rps)
+
+	latency := family("ghz_latency_seconds", "summary", "seconds", "Latency of the calls of the run.")
+	for _, l := range r.LatencyDistribution {
+		latency.sample("", labels, fmt.Sprintf(`quantile="%s"`, strconv.FormatFloat(float64(l.Percentage)/100, 'g', -1, 64)), l.Latency.Seconds())
+	}
+
+	latency.sample("_sum", labels, "", r.Average.Seconds()*float64(r.Count))
+	latency.sample("_count", labels, "", float64(r.Count))
+
+	family("ghz_latency_average_seconds", "gauge", "seconds", "Average latency of the calls of the run.").
+		sample("", labels, "", r.Average.Seconds())
+
+	family("ghz_latency_fastest_seconds", "gauge", "seconds", "Latency of the fastest call of the run.").
+		sample("", labels, "", r.Fastest.Seconds())
+
+	family("ghz_latency_slowest_seconds", "gauge", "seconds", "Latency of the slowest call of the run.").
+		sample("", labels, "", r.Slowest.Seconds())
+
+	if r.Apdex != nil {
+		family("ghz_apdex_score", "gauge", "", "Apdex score of the run.").
+			sample("", labels, "", r.Apdex.Score)
+	}
+
+	if len(r.Thresholds) > 0 || r.Comparison != nil {
+		passed := 0.0
+		if r.ThresholdsPassed() {
+			passed = 1
+		}
+
+		family("ghz_thresholds_passed", "gauge", "", "Whether all the thresholds of the run passed.").
+			sample("", labels, "", passed)
+	}
+
+	for _, m := range r.Metrics {
+		if m.Error == "" {
+			family("ghz_metric_"+openMetricsName(m.Name), "gauge", "", "Custom metric "+m.Name+" of the run.").
+				sample("", labels, "", m.Value)
+		}
+	}
+
+	family("ghz_run_timestamp_seconds", "gauge", "seconds", "Time the run was started, in seconds since the epoch.").
+		sample("", labels, "", float64(r.Date.UnixNano())/1e9)
+
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		if f.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", f.name, f.unit)
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		for _, s := range f.samples {
+			b.WriteString(s)
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	return rp.print(b.String())
+}
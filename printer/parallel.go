@@ -0,0 +1,80 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// PrintParallel prints the reports of the calls run in parallel, followed by the interference summary.
+// The json and pretty formats print all the reports as JSON, the summary format prints the summary of
+// each report. The other formats are not supported.
+func PrintParallel(out io.Writer, r *runner.ParallelReport, format string) error {
+	switch format {
+	case "json", "pretty":
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		if format == "pretty" {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, b, "", "  "); err != nil {
+				return err
+			}
+
+			b = buf.Bytes()
+		}
+
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	case "", "summary":
+	default:
+		return fmt.Errorf("format %s is not supported for parallel calls", format)
+	}
+
+	for _, rep := range r.Reports {
+		if _, err := fmt.Fprintf(out, "\nRun %s:\n", rep.Name); err != nil {
+			return err
+		}
+
+		p := ReportPrinter{Report: rep, Out: out}
+		if err := p.Print("summary"); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Interference) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprint(out, "\nInterference compared to the baseline:\n"+formatInterference(r.Interference)+"\n")
+	return err
+}
+
+func formatInterference(interference []runner.Interference) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Run\tRequests/sec\tAverage\tp99\tErrors\t\n")
+	for _, in := range interference {
+		_, _ = fmt.Fprintf(w, "  %s\t%s (%s)\t%s (%s)\t%s (%s)\t%.2f %% (was %.2f %%)\t\n", in.Name,
+			formatSeconds(in.Rps), formatChange(in.RpsChange),
+			formatNanoUnit(in.Average), formatChange(in.AverageChange),
+			formatNanoUnit(in.P99), formatChange(in.P99Change),
+			in.ErrorRate, in.BaselineErrorRate)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+// formatChange formats the relative change as a signed percentage
+func formatChange(change float64) string {
+	return fmt.Sprintf("%+.1f %%", change*100)
+}
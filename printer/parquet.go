@@ -0,0 +1,370 @@
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// parquetMagic starts and ends the Parquet files
+const parquetMagic = "PAR1"
+
+// parquetRowGroupSize is the number of details written per row group
+const parquetRowGroupSize = 100000
+
+// The Parquet types, converted types and encodings used by the details
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+// parquetColumn is a required column of the details
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 if none
+
+	int64s  func(d *runner.ResultDetail) int64
+	strings func(d *runner.ResultDetail) string
+}
+
+// parquetColumns returns the columns of the details, followed by a column per tag of the report
+func parquetColumns(tags map[string]string) []parquetColumn {
+	cols := []parquetColumn{
+		{name: "timestamp", typ: parquetInt64, converted: parquetTimestampMicros,
+			int64s: func(d *runner.ResultDetail) int64 { return d.Timestamp.UnixNano() / 1000 }},
+		{name: "latency_ns", typ: parquetInt64, converted: -1,
+			int64s: func(d *runner.ResultDetail) int64 { return int64(d.Latency) }},
+		{name: "status", typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(d *runner.ResultDetail) string { return d.Status }},
+		{name: "error", typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(d *runner.ResultDetail) string { return d.Error }},
+		{name: "bytes_sent", typ: parquetInt64, converted: -1,
+			int64s: func(d *runner.ResultDetail) int64 { return int64(d.BytesSent) }},
+		{name: "bytes_received", typ: parquetInt64, converted: -1,
+			int64s: func(d *runner.ResultDetail) int64 { return int64(d.BytesReceived) }},
+		{name: "worker", typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(d *runner.ResultDetail) string { return d.Worker }},
+		{name: "label", typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(d *runner.ResultDetail) string { return d.Label }},
+		{name: "lag_ns", typ: parquetInt64, converted: -1,
+			int64s: func(d *runner.ResultDetail) int64 { return int64(d.Lag) }},
+		{name: "trace_id", typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(d *runner.ResultDetail) string { return d.TraceID }},
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := tags[k]
+		cols = append(cols, parquetColumn{name: "tag_" + k, typ: parquetByteArray, converted: parquetUTF8,
+			strings: func(*runner.ResultDetail) string { return v }})
+	}
+
+	return cols
+}
+
+// parquetChunk is a column chunk of a row group
+type parquetChunk struct {
+	col    parquetColumn
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup is a row group of the details
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	size   int64
+}
+
+// printParquet writes the details of the report as an uncompressed Parquet file,
+// with a row per call and the tags of the report as constant columns.
+// The compact details are decoded a row group at a time.
+func (rp *ReportPrinter) printParquet() error {
+	details := rp.Report.Details
+	count := len(details)
+	cols := parquetColumns(rp.Report.Tags)
+
+	var cur *runner.DetailCursor
+	if rp.Report.DetailColumns != nil {
+		cur = rp.Report.DetailColumns.Cursor()
+		count = rp.Report.DetailColumns.Len()
+		details = make([]runner.ResultDetail, parquetRowGroupSize)
+	}
+
+	w := bufio.NewWriter(rp.Out)
+
+	if _, err := w.WriteString(parquetMagic); err != nil {
+		return err
+	}
+
+	offset := int64(len(parquetMagic))
+
+	var rowGroups []parquetRowGroup
+
+	for start := 0; start < count; start += parquetRowGroupSize {
+		end := start + parquetRowGroupSize
+		if end > count {
+			end = count
+		}
+
+		var rows []runner.ResultDetail
+		if cur != nil {
+			rows = details[:end-start]
+			for i := range rows {
+				cur.Next(&rows[i])
+			}
+		} else {
+			rows = details[start:end]
+		}
+		rg := parquetRowGroup{rows: int64(len(rows))}
+
+		for _, col := range cols {
+			page := parquetPage(col, rows)
+
+			header := &thriftWriter{}
+			header.structBegin()
+			header.i32Field(1, 0) // data page
+			header.i32Field(2, int32(len(page)))
+			header.i32Field(3, int32(len(page)))
+			header.structField(5)
+			header.i32Field(1, int32(len(rows)))
+			header.i32Field(2, parquetPlain)
+			header.i32Field(3, parquetRLE)
+			header.i32Field(4, parquetRLE)
+			header.structEnd()
+			header.structEnd()
+
+			if _, err := w.Write(header.buf.Bytes()); err != nil {
+				return err
+			}
+
+			if _, err := w.Write(page); err != nil {
+				return err
+			}
+
+			chunk := parquetChunk{col: col, offset: offset, size: int64(header.buf.Len() + len(page))}
+			rg.chunks = append(rg.chunks, chunk)
+			rg.size += chunk.size
+			offset += chunk.size
+		}
+
+		rowGroups = append(rowGroups, rg)
+	}
+
+	footer := rp.parquetFooter(cols, rowGroups, count)
+
+	if _, err := w.Write(footer); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+
+	if _, err := w.WriteString(parquetMagic); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// parquetFooter returns the encoded file metadata with the schema, the row groups
+// and the name, call, host and date of the report as key value metadata
+func (rp *ReportPrinter) parquetFooter(cols []parquetColumn, rowGroups []parquetRowGroup, count int) []byte {
+	t := &thriftWriter{}
+	t.structBegin()
+	t.i32Field(1, 1) // version
+
+	t.listField(2, thriftStruct, len(cols)+1)
+	t.structBegin()
+	t.binaryField(4, "schema")
+	t.i32Field(5, int32(len(cols)))
+	t.structEnd()
+
+	for _, col := range cols {
+		t.structBegin()
+		t.i32Field(1, col.typ)
+		t.i32Field(3, 0) // required
+		t.binaryField(4, col.name)
+		if col.converted >= 0 {
+			t.i32Field(6, col.converted)
+		}
+		t.structEnd()
+	}
+
+	t.i64Field(3, int64(count))
+
+	t.listField(4, thriftStruct, len(rowGroups))
+	for _, rg := range rowGroups {
+		t.structBegin()
+		t.listField(1, thriftStruct, len(rg.chunks))
+		for _, chunk := range rg.chunks {
+			t.structBegin()
+			t.i64Field(2, chunk.offset)
+			t.structField(3)
+			t.i32Field(1, chunk.col.typ)
+			t.listField(2, thriftI32, 2)
+			t.zigzag(parquetPlain)
+			t.zigzag(parquetRLE)
+			t.listField(3, thriftBinary, 1)
+			t.binary(chunk.col.name)
+			t.i32Field(4, 0) // uncompressed
+			t.i64Field(5, rg.rows)
+			t.i64Field(6, chunk.size)
+			t.i64Field(7, chunk.size)
+			t.i64Field(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64Field(2, rg.size)
+		t.i64Field(3, rg.rows)
+		t.structEnd()
+	}
+
+	kvs := [][2]string{
+		{"name", rp.Report.Name},
+		{"call", rp.Report.Options.Call},
+		{"host", rp.Report.Options.Host},
+		{"date", rp.Report.Date.Format(time.RFC3339)},
+	}
+
+	t.listField(5, thriftStruct, len(kvs))
+	for _, kv := range kvs {
+		t.structBegin()
+		t.binaryField(1, kv[0])
+		t.binaryField(2, kv[1])
+		t.structEnd()
+	}
+
+	t.binaryField(6, "ghz")
+	t.structEnd()
+
+	return t.buf.Bytes()
+}
+
+// parquetPage returns the plain encoded values of the column. The columns are required,
+// so the page has no repetition and definition levels.
+func parquetPage(col parquetColumn, rows []runner.ResultDetail) []byte {
+	buf := &bytes.Buffer{}
+	b := make([]byte, 8)
+
+	for i := range rows {
+		if col.int64s != nil {
+			binary.LittleEndian.PutUint64(b, uint64(col.int64s(&rows[i])))
+			buf.Write(b)
+
+			continue
+		}
+
+		s := col.strings(&rows[i])
+		binary.LittleEndian.PutUint32(b, uint32(len(s)))
+		buf.Write(b[:4])
+		buf.WriteString(s)
+	}
+
+	return buf.Bytes()
+}
+
+// The thrift compact protocol types used by the Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata using the thrift compact protocol
+type thriftWriter struct {
+	buf bytes.Buffer
+
+	lastID  int16
+	lastIDs []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	t.buf.Write(b[:binary.PutUvarint(b, v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.lastID; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+
+	t.lastID = id
+}
+
+func (t *thriftWriter) structBegin() {
+	t.lastIDs = append(t.lastIDs, t.lastID)
+	t.lastID = 0
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+
+	t.lastID = t.lastIDs[len(t.lastIDs)-1]
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+// structField writes the header of a struct field, followed by its fields and structEnd
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) binaryField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(s)
+}
+
+// listField writes the header of a list field, followed by its elements
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xf0 | elemType)
+		t.varint(uint64(size))
+	}
+}
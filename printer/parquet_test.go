@@ -0,0 +1,172 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+// thriftReader decodes the thrift compact protocol into maps of the field IDs to the values
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		s := string(r.b[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		h := r.b[r.pos]
+		r.pos++
+		size := int(h >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int64]interface{} {
+	fields := map[int64]interface{}{}
+	var id int64
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return fields
+		}
+
+		if delta := int64(h >> 4); delta > 0 {
+			id += delta
+		} else {
+			id = r.zigzag()
+		}
+
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+func TestPrinter_printParquet(t *testing.T) {
+	date := time.Date(2021, 3, 7, 14, 43, 12, 0, time.UTC)
+
+	buf := &bytes.Buffer{}
+	p := ReportPrinter{Out: buf, Report: &runner.Report{
+		Name:    "nightly",
+		Date:    date,
+		Options: runner.Options{Call: "helloworld.Greeter.SayHello", Host: "localhost:50051"},
+		Tags:    map[string]string{"env": "staging"},
+		Details: []runner.ResultDetail{
+			{Timestamp: date, Latency: 5 * time.Millisecond, Status: "OK", Worker: "g0c0w0", BytesSent: 12, BytesReceived: 19},
+			{Timestamp: date.Add(time.Second), Latency: 7 * time.Millisecond, Status: "Unavailable", Error: "unavailable", Worker: "g0c0w1"},
+		},
+	}}
+
+	assert.NoError(t, p.Print("parquet"))
+
+	b := buf.Bytes()
+	assert.Equal(t, parquetMagic, string(b[:4]))
+	assert.Equal(t, parquetMagic, string(b[len(b)-4:]))
+
+	size := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	footer := (&thriftReader{b: b[len(b)-8-size : len(b)-8]}).readStruct()
+
+	assert.Equal(t, int64(2), footer[3])
+
+	var names []string
+	for _, el := range footer[2].([]interface{}) {
+		names = append(names, el.(map[int64]interface{})[4].(string))
+	}
+
+	assert.Equal(t, []string{"schema", "timestamp", "latency_ns", "status", "error", "bytes_sent", "bytes_received",
+		"worker", "label", "lag_ns", "trace_id", "tag_env"}, names)
+
+	kvs := map[string]string{}
+	for _, el := range footer[5].([]interface{}) {
+		kv := el.(map[int64]interface{})
+		kvs[kv[1].(string)] = kv[2].(string)
+	}
+
+	assert.Equal(t, "nightly", kvs["name"])
+	assert.Equal(t, "helloworld.Greeter.SayHello", kvs["call"])
+
+	rowGroups := footer[4].([]interface{})
+	assert.Len(t, rowGroups, 1)
+
+	chunks := rowGroups[0].(map[int64]interface{})[1].([]interface{})
+	assert.Len(t, chunks, 11)
+
+	// reads the plain encoded values of the column chunk
+	page := func(i int) []byte {
+		md := chunks[i].(map[int64]interface{})[3].(map[int64]interface{})
+		start, size := int(md[9].(int64)), int(md[7].(int64))
+
+		r := &thriftReader{b: b[start : start+size]}
+		header := r.readStruct()
+		assert.Equal(t, int64(2), header[5].(map[int64]interface{})[1])
+
+		return r.b[r.pos:]
+	}
+
+	latency := page(1)
+	assert.Equal(t, uint64(5*time.Millisecond), binary.LittleEndian.Uint64(latency))
+	assert.Equal(t, uint64(7*time.Millisecond), binary.LittleEndian.Uint64(latency[8:]))
+
+	assert.Equal(t, uint64(date.UnixNano()/1000), binary.LittleEndian.Uint64(page(0)))
+	assert.Equal(t, "\x02\x00\x00\x00OK\x0b\x00\x00\x00Unavailable", string(page(2)))
+	assert.Equal(t, uint64(12), binary.LittleEndian.Uint64(page(4)))
+	assert.Equal(t, "\x07\x00\x00\x00staging\x07\x00\x00\x00staging", string(page(10)))
+}
+
+func TestPrinter_printParquet_compact(t *testing.T) {
+	date := time.Date(2021, 3, 7, 14, 43, 12, 0, time.UTC)
+
+	details := make([]runner.ResultDetail, parquetRowGroupSize+10)
+	for i := range details {
+		details[i] = runner.ResultDetail{Timestamp: date.Add(time.Duration(i) * time.Millisecond),
+			Latency: time.Duration(i) * time.Microsecond, Status: "OK", Worker: "g0c0w0"}
+	}
+
+	report := &runner.Report{Name: "nightly", Date: date, Details: details}
+
+	expected := &bytes.Buffer{}
+	assert.NoError(t, (&ReportPrinter{Out: expected, Report: report}).Print("parquet"))
+
+	// the compact details are written the same, a row group at a time
+	compact := *report
+	compact.Details = nil
+	compact.DetailColumns = runner.CompactDetails(details)
+
+	actual := &bytes.Buffer{}
+	assert.NoError(t, (&ReportPrinter{Out: actual, Report: &compact}).Print("parquet"))
+
+	assert.True(t, bytes.Equal(expected.Bytes(), actual.Bytes()))
+}
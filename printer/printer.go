@@ -1,26 +1,44 @@
 package printer
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/alecthomas/template"
+	"github.com/bojand/ghz/load"
 	"github.com/bojand/ghz/runner"
 )
 
 const (
 	barChar = "∎"
+
+	// the width of the bars of the waterfall of the scenario iterations
+	waterfallWidth = 40
 )
 
 // ReportPrinter is used for printing the report
 type ReportPrinter struct {
 	Out    io.Writer
 	Report *runner.Report
+
+	// Template is the template of the summary format executed with the report instead of
+	// the default summary. The functions formatting the sections of the default summary
+	// are available in it.
+	Template string
+}
+
+// CheckTemplate checks that the text is a valid template of the summary format
+func CheckTemplate(text string) error {
+	_, err := template.New("tmpl").Funcs(tmplFuncMap).Parse(text)
+	return err
 }
 
 // Print the report using the given format
@@ -36,6 +54,9 @@ type ReportPrinter struct {
 // 		html
 // 		influx-summary
 // 		influx-details
+// 		folded
+// 		parquet
+// 		openmetrics
 func (rp *ReportPrinter) Print(format string) error {
 	if format == "" {
 		format = "summary"
@@ -43,12 +64,21 @@ func (rp *ReportPrinter) Print(format string) error {
 
 	switch format {
 	case "summary", "csv":
+		if format == "csv" && rp.Report.DetailColumns != nil {
+			return rp.printCSVColumns()
+		}
+
 		outputTmpl := defaultTmpl
 		if format == "csv" {
 			outputTmpl = csvTmpl
+		} else if rp.Template != "" {
+			outputTmpl = rp.Template
 		}
 		buf := &bytes.Buffer{}
-		templ := template.Must(template.New("tmpl").Funcs(tmplFuncMap).Parse(outputTmpl))
+		templ, err := template.New("tmpl").Funcs(tmplFuncMap).Parse(outputTmpl)
+		if err != nil {
+			return err
+		}
 		if err := templ.Execute(buf, *rp.Report); err != nil {
 			return err
 		}
@@ -80,11 +110,65 @@ func (rp *ReportPrinter) Print(format string) error {
 		return rp.print(rp.getInfluxLine())
 	case "influx-details":
 		return rp.printInfluxDetails()
+	case "folded":
+		return rp.printFolded()
+	case "parquet":
+		return rp.printParquet()
+	case "openmetrics":
+		return rp.printOpenMetrics()
 	default:
 		return fmt.Errorf("unknown format: %s", format)
 	}
 }
 
+// OutputPathData is the data available to output path templates
+type OutputPathData struct {
+	RunID     string // the unique ID of the run
+	Name      string // the name of the run
+	Date      string // the date of the run in YYYY-MM-DD format
+	Time      string // the time of the run in HHMMSS format
+	Timestamp int64  // the unix timestamp of the run in seconds
+	Host      string // the host
+	Call      string // the fully-qualified method name
+	Rotation  int    // the rotation number of partial reports, 0 for the full report
+}
+
+// OutputPath renders the output path template using the report.
+// Any path separators and colons in the values are replaced with underscores.
+//
+//	path, err := rp.OutputPath("report-{{.Name}}-{{.Date}}-{{.Host}}.json")
+func (rp *ReportPrinter) OutputPath(pathTmpl string) (string, error) {
+	if !strings.Contains(pathTmpl, "{{") {
+		return pathTmpl, nil
+	}
+
+	templ, err := template.New("path").Parse(pathTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	clean := strings.NewReplacer("/", "_", "\\", "_", ":", "_", " ", "_")
+	date := rp.Report.Date
+
+	data := OutputPathData{
+		RunID:     rp.Report.RunID,
+		Name:      clean.Replace(strings.TrimSpace(rp.Report.Name)),
+		Date:      date.Format("2006-01-02"),
+		Time:      date.Format("150405"),
+		Timestamp: date.Unix(),
+		Host:      clean.Replace(rp.Report.Options.Host),
+		Call:      clean.Replace(rp.Report.Options.Call),
+		Rotation:  rp.Report.Rotation,
+	}
+
+	buf := &bytes.Buffer{}
+	if err := templ.Execute(buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
 func (rp *ReportPrinter) getInfluxLine() string {
 	measurement := "ghz_run"
 	tags := rp.getInfluxTags(true)
@@ -101,7 +185,12 @@ func (rp *ReportPrinter) printInfluxDetails() error {
 	measurement := "ghz_detail"
 	commonTags := rp.getInfluxTags(false)
 
-	for _, v := range rp.Report.Details {
+	var err error
+	rp.Report.EachDetail(func(v *runner.ResultDetail) {
+		if err != nil {
+			return
+		}
+
 		values := make([]string, 3)
 		values[0] = fmt.Sprintf("latency=%v", v.Latency.Nanoseconds())
 		values[1] = fmt.Sprintf(`error="%v"`, cleanInfluxString(v.Error))
@@ -119,11 +208,69 @@ func (rp *ReportPrinter) printInfluxDetails() error {
 
 		fields := strings.Join(values, ",")
 
-		if _, err := fmt.Fprintf(rp.Out, "%v,%v %v %v\n", measurement, tags, fields, timestamp); err != nil {
-			return err
+		_, err = fmt.Fprintf(rp.Out, "%v,%v %v %v\n", measurement, tags, fields, timestamp)
+	})
+	return err
+}
+
+// printFolded prints the server-side stage timings as folded stacks, one line per stage
+// with its self time in microseconds, for use with flame graph tools. The time of the
+// calls not spent in any of the top level stages is the self time of the call itself.
+func (rp *ReportPrinter) printFolded() error {
+	st := rp.Report.StageTiming
+	if st == nil {
+		return fmt.Errorf("folded output requires stage timings")
+	}
+
+	root := strings.ReplaceAll(rp.Report.Options.Call, " ", "_")
+	if root == "" {
+		root = "call"
+	}
+
+	totals := make(map[string]time.Duration, len(st.Stages))
+	for _, s := range st.Stages {
+		totals[s.Stage] = s.Total
+	}
+
+	// the self time of each stage is its total without the totals of its nested stages
+	self := make(map[string]time.Duration, len(st.Stages))
+	rootSelf := st.Latency
+	for _, s := range st.Stages {
+		self[s.Stage] += s.Total
+
+		if parent, ok := parentStage(s.Stage, totals); ok {
+			self[parent] -= s.Total
+		} else {
+			rootSelf -= s.Total
 		}
 	}
-	return nil
+
+	lines := []string{fmt.Sprintf("%s %d", root, foldedMicros(rootSelf))}
+	for _, s := range st.Stages {
+		lines = append(lines, fmt.Sprintf("%s;%s %d", root, strings.ReplaceAll(s.Stage, " ", "_"), foldedMicros(self[s.Stage])))
+	}
+
+	return rp.print(strings.Join(lines, "\n") + "\n")
+}
+
+// parentStage returns the closest enclosing stage of the nested stage which has timings
+func parentStage(stage string, totals map[string]time.Duration) (string, bool) {
+	for i := strings.LastIndex(stage, ";"); i > 0; i = strings.LastIndex(stage, ";") {
+		stage = stage[:i]
+		if _, ok := totals[stage]; ok {
+			return stage, true
+		}
+	}
+
+	return "", false
+}
+
+func foldedMicros(d time.Duration) int64 {
+	if d < 0 {
+		return 0
+	}
+
+	return d.Microseconds()
 }
 
 func (rp *ReportPrinter) getInfluxTags(addErrors bool) string {
@@ -133,6 +280,10 @@ func (rp *ReportPrinter) getInfluxTags(addErrors bool) string {
 		s = append(s, fmt.Sprintf(`name="%v"`, cleanInfluxString(strings.TrimSpace(rp.Report.Name))))
 	}
 
+	if rp.Report.RunID != "" {
+		s = append(s, fmt.Sprintf(`run_id="%v"`, rp.Report.RunID))
+	}
+
 	options := rp.Report.Options
 
 	if options.Proto != "" {
@@ -258,16 +409,118 @@ func (rp *ReportPrinter) getInfluxFields() string {
 		}
 	}
 
+	if rp.Report.Apdex != nil {
+		s = append(s, fmt.Sprintf("apdex=%.4f", rp.Report.Apdex.Score))
+	}
+
+	s = append(s, fmt.Sprintf("errors=%v", rp.errorCount()))
+
+	for _, status := range sortedCodes(rp.Report.StatusCodeDist) {
+		s = append(s, fmt.Sprintf("code_%v=%v", status, rp.Report.StatusCodeDist[status]))
+	}
+
+	if len(rp.Report.Thresholds) > 0 || rp.Report.Comparison != nil {
+		s = append(s, fmt.Sprintf("thresholds_passed=%v", rp.Report.ThresholdsPassed()))
+	}
+
+	for _, m := range rp.Report.Metrics {
+		if m.Error == "" {
+			s = append(s, fmt.Sprintf("metric_%v=%v", m.Name, formatMetricValue(m.Value)))
+		}
+	}
+
+	return strings.Join(s, ",")
+}
+
+// PrintSummaryLine prints a single line summary of the report made up of
+// space separated key=value pairs, intended to be easily parsed by scripts.
+// All durations are in milliseconds.
+//
+//	count=200 ok=195 errors=5 rps=2000.00 total_ms=2000.00 average_ms=10.00 ... reason=normal
+func (rp *ReportPrinter) PrintSummaryLine() error {
+	return rp.print(rp.getSummaryLine() + "\n")
+}
+
+func (rp *ReportPrinter) getSummaryLine() string {
+	r := rp.Report
+
+	s := make([]string, 0, 14)
+
+	s = append(s, fmt.Sprintf("count=%v", r.Count))
+	s = append(s, fmt.Sprintf("ok=%v", r.StatusCodeDist["OK"]))
+	s = append(s, fmt.Sprintf("errors=%v", rp.errorCount()))
+	s = append(s, fmt.Sprintf("rps=%4.2f", r.Rps))
+	s = append(s, fmt.Sprintf("total_ms=%v", formatMilliNumber(r.Total)))
+	s = append(s, fmt.Sprintf("average_ms=%v", formatMilliNumber(r.Average)))
+	s = append(s, fmt.Sprintf("fastest_ms=%v", formatMilliNumber(r.Fastest)))
+	s = append(s, fmt.Sprintf("slowest_ms=%v", formatMilliNumber(r.Slowest)))
+
+	for _, v := range r.LatencyDistribution {
+		switch v.Percentage {
+		case 50, 90, 95, 99:
+			s = append(s, fmt.Sprintf("p%v_ms=%v", v.Percentage, formatMilliNumber(v.Latency)))
+		}
+	}
+
+	if r.Apdex != nil {
+		s = append(s, fmt.Sprintf("apdex=%.4f", r.Apdex.Score))
+	}
+
+	s = append(s, fmt.Sprintf("reason=%v", r.EndReason))
+
+	if len(r.Thresholds) > 0 || r.Comparison != nil {
+		thresholds := "pass"
+		if !r.ThresholdsPassed() {
+			thresholds = "fail"
+		}
+
+		s = append(s, fmt.Sprintf("thresholds=%v", thresholds))
+	}
+
+	if r.RunID != "" {
+		s = append(s, fmt.Sprintf("run_id=%v", r.RunID))
+	}
+
+	for _, m := range r.Metrics {
+		if m.Error == "" {
+			s = append(s, fmt.Sprintf("metric_%v=%v", m.Name, formatMetricValue(m.Value)))
+		}
+	}
+
+	return strings.Join(s, " ")
+}
+
+func (rp *ReportPrinter) errorCount() int {
 	errCount := 0
-	if len(rp.Report.ErrorDist) > 0 {
-		for _, v := range rp.Report.ErrorDist {
-			errCount += v
+	for _, v := range rp.Report.ErrorDist {
+		errCount += v
+	}
+
+	return errCount
+}
+
+// printCSVColumns prints the compact details of the report as the CSV template does,
+// reading one detail at a time
+func (rp *ReportPrinter) printCSVColumns() error {
+	w := bufio.NewWriter(rp.Out)
+
+	if _, err := w.WriteString("\nduration (ms),status,error"); err != nil {
+		return err
+	}
+
+	var d runner.ResultDetail
+	cur := rp.Report.DetailColumns.Cursor()
+	for cur.Next(&d) {
+		if _, err := fmt.Fprintf(w, "\n%s,%s,%s", formatMilli(d.Latency.Seconds()), d.Status, d.Error); err != nil {
+			return err
 		}
 	}
 
-	s = append(s, fmt.Sprintf("errors=%v", errCount))
+	if _, err := w.WriteString("\n"); err != nil {
+		return err
+	}
 
-	return strings.Join(s, ",")
+	return w.Flush()
 }
 
 func (rp *ReportPrinter) print(s string) error {
@@ -276,16 +529,79 @@ func (rp *ReportPrinter) print(s string) error {
 }
 
 var tmplFuncMap = template.FuncMap{
-	"formatMilli":      formatMilli,
-	"formatSeconds":    formatSeconds,
-	"histogram":        histogram,
-	"jsonify":          jsonify,
-	"formatMark":       formatMarkMs,
-	"formatPercent":    formatPercent,
-	"formatStatusCode": formatStatusCode,
-	"formatErrorDist":  formatErrorDist,
-	"formatDate":       formatDate,
-	"formatNanoUnit":   formatNanoUnit,
+	"formatMilli":           formatMilli,
+	"formatSeconds":         formatSeconds,
+	"histogram":             histogram,
+	"jsonify":               jsonify,
+	"formatMark":            formatMarkMs,
+	"formatPercent":         formatPercent,
+	"formatStatusCode":      formatStatusCode,
+	"formatErrorDist":       formatErrorDist,
+	"formatStatusBreakdown": formatStatusBreakdown,
+	"formatThresholds":      formatThresholds,
+	"formatComparison":      formatComparison,
+	"formatBackoff":         formatBackoff,
+	"formatLatencyCtl":      formatLatencyControl,
+	"formatLabelLatency":    formatLabelLatency,
+	"formatWorkerGroups":    formatWorkerGroups,
+	"formatMethods":         formatMethods,
+	"formatTimeSeries":      formatTimeSeries,
+	"formatTimeSlices":      formatTimeSlices,
+	"formatStream":          formatStream,
+	"formatStreamAcks":      formatStreamAcks,
+	"formatStreamMessages":  formatStreamMessages,
+	"formatBandwidth":       formatBandwidth,
+	"formatBackpressure":    formatBackpressure,
+	"formatSchedulerLag":    formatSchedulerLag,
+	"formatHeaderLatency":   formatHeaderLatency,
+	"formatBaseline":        formatBaseline,
+	"formatPhases":          formatPhases,
+	"formatPacing":          formatPacing,
+	"formatAsyncQueue":      formatAsyncQueue,
+	"formatDeadline":        formatDeadline,
+	"formatGraceRetries":    formatGraceRetries,
+	"formatDrain":           formatDrain,
+	"formatHedging":         formatHedging,
+	"formatCompression":     formatCompression,
+	"formatDecompression":   formatDecompression,
+	"formatUpload":          formatUpload,
+	"formatTraces":          formatTraces,
+	"formatFieldStats":      formatFieldStats,
+	"formatSchemaDrift":     formatSchemaDrift,
+	"formatPagination":      formatPagination,
+	"formatIterations":      formatIterations,
+	"formatTenants":         formatTenants,
+	"formatStageTiming":     formatStageTiming,
+	"formatConnections":     formatConnections,
+	"formatShards":          formatShards,
+	"formatChurn":           formatChurn,
+	"formatScaling":         formatScaling,
+	"formatConnect":         formatConnect,
+	"formatAssertions":      formatAssertions,
+	"formatAdjustments":     formatAdjustments,
+	"formatSchemaChanges":   formatSchemaChanges,
+	"formatRecommendations": formatRecommendations,
+	"formatServer":          formatServer,
+	"formatReadiness":       formatReadiness,
+	"formatCanary":          formatCanary,
+	"formatMetrics":         formatMetrics,
+	"formatApdex":           formatApdex,
+	"formatNormalized":      formatNormalized,
+	"formatMetricValue":     formatMetricValue,
+	"formatDate":            formatDate,
+	"formatNanoUnit":        formatNanoUnit,
+	"latencyTimeline":       latencyTimeline,
+	"reportDetails":         reportDetails,
+}
+
+// reportDetails returns the details of the report to encode, which are the compact details
+// if any, so that they are encoded one at a time rather than decoded into a slice first
+func reportDetails(report runner.Report) interface{} {
+	if report.DetailColumns != nil {
+		return report.DetailColumns
+	}
+
+	return report.Details
 }
 
 func jsonify(v interface{}, pretty bool) string {
@@ -321,6 +637,10 @@ func formatMilli(duration float64) string {
 	return fmt.Sprintf("%4.2f", duration*1000)
 }
 
+func formatMilliNumber(d time.Duration) string {
+	return fmt.Sprintf("%4.2f", float64(d.Nanoseconds())/1e6)
+}
+
 func formatDate(d time.Time) string {
 	return d.Format("Mon Jan _2 2006 @ 15:04:05")
 }
@@ -367,22 +687,1051 @@ func formatStatusCode(statusCodeDist map[string]int) string {
 	padding := 3
 	buf := &bytes.Buffer{}
 	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
-	for status, count := range statusCodeDist {
+	for _, status := range sortedCodes(statusCodeDist) {
 		// bytes.Buffer can be assumed to not fail on write
-		_, _ = fmt.Fprintf(w, "  [%+s]\t%+v responses\t\n", status, count)
+		_, _ = fmt.Fprintf(w, "  [%+s]\t%+v responses\t\n", status, statusCodeDist[status])
 	}
 	// bytes.Buffer can be assumed to not fail on write
 	_ = w.Flush()
 	return buf.String()
 }
 
-func formatErrorDist(errDist map[string]int) string {
+func formatThresholds(thresholds []runner.ThresholdResult) string {
 	padding := 3
 	buf := &bytes.Buffer{}
 	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
-	for status, count := range errDist {
+	for _, t := range thresholds {
+		result := "pass"
+		if !t.Pass {
+			result = "fail"
+		}
+
 		// bytes.Buffer can be assumed to not fail on write
-		_, _ = fmt.Fprintf(w, "  [%+v]\t%+s\t\n", count, status)
+		_, _ = fmt.Fprintf(w, "  [%s]\t%s\tactual %v\t\n", result, t.Threshold, t.Actual)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatComparison(c *runner.Comparison) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Metric\tBaseline\tCurrent\tChange\tTolerance\t\n")
+	for _, m := range c.Metrics {
+		change := formatChange(m.Change)
+		if m.Metric == runner.MetricErrorRate {
+			change = fmt.Sprintf("%+.2f pts", m.Change)
+		}
+
+		tolerance, result := "-", ""
+		if m.Tolerance != nil {
+			tolerance = fmt.Sprintf("%.1f %%", *m.Tolerance*100)
+			if m.Metric == runner.MetricErrorRate {
+				tolerance = fmt.Sprintf("%.2f pts", *m.Tolerance)
+			}
+
+			result = "[pass]"
+			if m.Regressed {
+				result = "[regressed]"
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", m.Metric,
+			formatMetricComparisonValue(m.Metric, m.Baseline), formatMetricComparisonValue(m.Metric, m.Current),
+			change, tolerance, result)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+// formatMetricComparisonValue formats the value of the metric, which is in milliseconds for the latencies
+func formatMetricComparisonValue(m runner.Metric, v float64) string {
+	switch m {
+	case runner.MetricErrorRate:
+		return fmt.Sprintf("%.2f %%", v)
+	case runner.MetricRPS:
+		return fmt.Sprintf("%.2f", v)
+	}
+
+	return fmt.Sprintf("%.2f ms", v)
+}
+
+func formatAssertions(assertions []runner.AssertionStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, a := range assertions {
+		result := "pass"
+		if a.Failed > 0 {
+			result = "fail"
+		}
+
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%s\t%d passed\t%d failed\t\n", result, a.Rule, a.Passed, a.Failed)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatBackoff(actions []load.AdaptiveAction) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, a := range actions {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\terror rate %.2f %%\tload %.0f %%\t\n", formatNanoUnit(a.Elapsed), a.ErrorRate*100, a.Factor*100)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatLatencyControl(actions []load.LatencyAction) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, a := range actions {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\tp99 %s\tachieved %s rps\trate %s rps\t\n", formatNanoUnit(a.Elapsed), formatNanoUnit(a.Latency), formatSeconds(a.Achieved), formatSeconds(a.Rate))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatLabelLatency(labels []runner.LabelLatency) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, l := range labels {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%d responses\tavg %s", l.Label, l.Count, formatNanoUnit(l.Average))
+		for _, ld := range l.LatencyDistribution {
+			if ld.Percentage == 50 || ld.Percentage == 90 || ld.Percentage == 99 {
+				_, _ = fmt.Fprintf(w, "\tp%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+		}
+		_, _ = fmt.Fprint(w, "\t\n")
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatWorkerGroups(groups []runner.WorkerGroupStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, g := range groups {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%d: %s]\t%d workers\t%d responses\tavg %s", g.Group, g.CPUs, g.Workers, g.Count, formatNanoUnit(g.Average))
+		for _, ld := range g.LatencyDistribution {
+			if ld.Percentage == 50 || ld.Percentage == 90 || ld.Percentage == 99 {
+				_, _ = fmt.Fprintf(w, "\tp%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+		}
+		_, _ = fmt.Fprint(w, "\t\n")
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatMethods(methods []runner.MethodStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, m := range methods {
+		errs := 0
+		for _, n := range m.ErrorDist {
+			errs += n
+		}
+
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%d responses\t%.2f rps\tavg %s", m.Method, m.Count, m.Rps, formatNanoUnit(m.Average))
+		for _, ld := range m.LatencyDistribution {
+			if ld.Percentage == 50 || ld.Percentage == 90 || ld.Percentage == 99 {
+				_, _ = fmt.Fprintf(w, "\tp%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+		}
+		_, _ = fmt.Fprintf(w, "\t%d errors\t\n", errs)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatTimeSeries(windows []runner.TimeWindow) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, tw := range windows {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%d responses\t%.2f rps\t%.2f %% errors\tp50 %s\tp95 %s\tp99 %s\t\n",
+			tw.Start, tw.Count, tw.Rps, tw.ErrorRate*100, formatNanoUnit(tw.P50), formatNanoUnit(tw.P95), formatNanoUnit(tw.P99))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatTimeSlices(slices []runner.TimeSlice) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, s := range slices {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s - %s]\t%d responses, %.2f rps, %.2f %% errors\n",
+			s.Start, s.End, s.Count, s.Rps, s.ErrorRate*100)
+		if len(s.LatencyDistribution) > 0 {
+			_, _ = fmt.Fprintf(w, "  \tavg %s, fastest %s, slowest %s\n",
+				formatNanoUnit(s.Average), formatNanoUnit(s.Fastest), formatNanoUnit(s.Slowest))
+
+			pcts := make([]string, len(s.LatencyDistribution))
+			for i, ld := range s.LatencyDistribution {
+				pcts[i] = fmt.Sprintf("p%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+			_, _ = fmt.Fprintf(w, "  \t%s\n", strings.Join(pcts, ", "))
+		}
+
+		codes := make([]string, 0, len(s.StatusCodeDist))
+		for code := range s.StatusCodeDist {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for i, code := range codes {
+			codes[i] = fmt.Sprintf("[%s] %d", code, s.StatusCodeDist[code])
+		}
+		if len(codes) > 0 {
+			_, _ = fmt.Fprintf(w, "  \t%s\n", strings.Join(codes, ", "))
+		}
+
+		errs := make([]string, 0, len(s.ErrorDist))
+		for e := range s.ErrorDist {
+			errs = append(errs, e)
+		}
+		sort.Strings(errs)
+		for _, e := range errs {
+			_, _ = fmt.Fprintf(w, "  \t[%d] %s\n", s.ErrorDist[e], e)
+		}
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatSchemaDrift(s *runner.SchemaDrift) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Responses:\t%d\n", s.Responses)
+	_, _ = fmt.Fprintf(w, "  Drifted:\t%d\n", s.Drifted)
+	for _, f := range s.Fields {
+		_, _ = fmt.Fprintf(w, "  [%s]\t%s\t%d responses\t\n", f.Field, f.Message, f.Count)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatStream(s *runner.StreamStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Correlation field:\t%s\n", s.Field)
+	_, _ = fmt.Fprintf(w, "  Sent:\t%d\n", s.Sent)
+	_, _ = fmt.Fprintf(w, "  Received:\t%d\n", s.Received)
+	_, _ = fmt.Fprintf(w, "  Matched:\t%d\n", s.Matched)
+	_, _ = fmt.Fprintf(w, "  Unanswered:\t%d\n", s.Unanswered)
+	_, _ = fmt.Fprintf(w, "  Server pushed:\t%d (%s/sec)\n", s.Pushed, formatSeconds(s.PushRate))
+	if s.Matched > 0 {
+		_, _ = fmt.Fprintf(w, "  Response latency:\tavg %s, fastest %s, slowest %s\n",
+			formatNanoUnit(s.Average), formatNanoUnit(s.Fastest), formatNanoUnit(s.Slowest))
+		for _, ld := range s.LatencyDistribution {
+			_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+		}
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatStreamAcks(s *runner.StreamAckStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Ack field:\t%s\n", s.Field)
+	_, _ = fmt.Fprintf(w, "  Streams:\t%d\n", s.Streams)
+	_, _ = fmt.Fprintf(w, "  Matched:\t%d\n", s.Matched)
+	_, _ = fmt.Fprintf(w, "  Mismatched:\t%d\n", s.Mismatched)
+	_, _ = fmt.Fprintf(w, "  Dropped:\t%d messages\n", s.Dropped)
+	if s.Missing > 0 {
+		_, _ = fmt.Fprintf(w, "  Missing field:\t%d responses\n", s.Missing)
+	}
+	for _, m := range s.Mismatches {
+		_, _ = fmt.Fprintf(w, "\tsent %d, acked %d\n", m.Sent, m.Acked)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatStreamMessages(m *runner.StreamMessages) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Streams:\t%d\n", m.Streams)
+	_, _ = fmt.Fprintf(w, "  Sent:\t%d messages, avg %s per stream, max %d (%s/sec)\n",
+		m.Sent, formatSeconds(m.SentPerStream), m.MaxSent, formatSeconds(m.SendRate))
+	_, _ = fmt.Fprintf(w, "  Received:\t%d messages, avg %s per stream, max %d (%s/sec)\n",
+		m.Received, formatSeconds(m.ReceivedPerStream), m.MaxReceived, formatSeconds(m.ReceiveRate))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatBandwidth(b *runner.Bandwidth) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Sent:\t%s (%s/sec), avg %s per message\n",
+		formatBytes(b.BytesSent), formatBytes(uint64(b.SendThroughput)), formatBytes(uint64(b.AverageRequestSize)))
+	_, _ = fmt.Fprintf(w, "  Received:\t%s (%s/sec), avg %s per message\n",
+		formatBytes(b.BytesReceived), formatBytes(uint64(b.ReceiveThroughput)), formatBytes(uint64(b.AverageResponseSize)))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatBackpressure(s *runner.BackpressureStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Receive delay:\t%s\n", formatNanoUnit(s.Delay))
+	_, _ = fmt.Fprintf(w, "  Streams:\t%d\n", s.Streams)
+	_, _ = fmt.Fprintf(w, "  Messages:\t%d (%s/sec)\n", s.Messages, formatSeconds(s.MessageRate))
+	_, _ = fmt.Fprintf(w, "  Stalled:\t%d\n", s.Stalled)
+	if len(s.LatencyDistribution) > 0 {
+		_, _ = fmt.Fprintf(w, "  Message wait:\tavg %s, fastest %s, slowest %s\n",
+			formatNanoUnit(s.Average), formatNanoUnit(s.Fastest), formatNanoUnit(s.Slowest))
+		for _, ld := range s.LatencyDistribution {
+			_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+		}
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatPhases(phases []runner.SchedulePhase) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, p := range phases {
+		rate := "unlimited"
+		if p.TargetRPS > 0 {
+			rate = fmt.Sprintf("%.2f rps", p.TargetRPS)
+		}
+
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%d]	%s	%d workers	%s	%d responses	%d errors	%.2f rps	avg %s",
+			p.Phase, p.Start.Round(time.Millisecond), p.Concurrency, rate, p.Count, p.Errors, p.Rps, formatNanoUnit(p.Average))
+		for _, ld := range p.LatencyDistribution {
+			if ld.Percentage == 50 || ld.Percentage == 90 || ld.Percentage == 99 {
+				_, _ = fmt.Fprintf(w, "\tp%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+		}
+		_, _ = fmt.Fprint(w, "\t\n")
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatPacing(p *runner.PacingStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Pace:\t%s\n", p.Pace)
+	_, _ = fmt.Fprintf(w, "  Think time:\t%s\n", formatNanoUnit(p.ThinkTime))
+	_, _ = fmt.Fprintf(w, "  Workers:\t%d\n", p.Workers)
+	_, _ = fmt.Fprintf(w, "  Worker rate:\tavg %s, min %s, max %s req/s\n",
+		formatSeconds(p.AverageRate), formatSeconds(p.MinRate), formatSeconds(p.MaxRate))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatSchedulerLag(l *runner.SchedulerLag) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Paced requests:\t%d\n", l.Count)
+	_, _ = fmt.Fprintf(w, "  Average:\t%s\n", formatNanoUnit(l.Average))
+	_, _ = fmt.Fprintf(w, "  Max:\t%s\n", formatNanoUnit(l.Max))
+	for _, ld := range l.LatencyDistribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatHeaderLatency(h *runner.HeaderLatency) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Requests:\t%d\n", h.Count)
+	_, _ = fmt.Fprintf(w, "  Average:\t%s\n", formatNanoUnit(h.Average))
+	_, _ = fmt.Fprintf(w, "  Fastest:\t%s\n", formatNanoUnit(h.Fastest))
+	_, _ = fmt.Fprintf(w, "  Slowest:\t%s\n", formatNanoUnit(h.Slowest))
+	for _, ld := range h.LatencyDistribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatBaseline(b *runner.Baseline) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Requests:\t%d\n", b.Count)
+	_, _ = fmt.Fprintf(w, "  Overhead:\t%s\n", formatNanoUnit(b.Average))
+	_, _ = fmt.Fprintf(w, "  Adjusted:\t%s\n", formatNanoUnit(b.AdjustedAverage))
+	for _, ld := range b.AdjustedDistribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatApdex(a *runner.Apdex) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Score:\t%.2f\n", a.Score)
+	_, _ = fmt.Fprintf(w, "  Threshold:\t%s\n", formatNanoUnit(a.Threshold))
+	_, _ = fmt.Fprintf(w, "  Satisfied:\t%d\n", a.Satisfied)
+	_, _ = fmt.Fprintf(w, "  Tolerating:\t%d\n", a.Tolerating)
+	_, _ = fmt.Fprintf(w, "  Frustrated:\t%d\n", a.Frustrated)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatNormalized(n *runner.Normalized) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	if n.CPUTime > 0 {
+		_, _ = fmt.Fprintf(w, "  CPU time:\t%s\n", formatNanoUnit(n.CPUTime))
+		_, _ = fmt.Fprintf(w, "  Cores used:\t%.2f\n", n.CoresUsed)
+		_, _ = fmt.Fprintf(w, "  Requests/sec per core used:\t%.2f\n", n.RpsPerCore)
+	}
+	_, _ = fmt.Fprintf(w, "  Requests/sec per CPU:\t%.2f\n", n.RpsPerCPU)
+	_, _ = fmt.Fprintf(w, "  Requests/sec per connection:\t%.2f\n", n.RpsPerConnection)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatDeadline(b *runner.DeadlineBudget) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Timeout:\t%s\n", formatNanoUnit(b.Timeout))
+	_, _ = fmt.Fprintf(w, "  Average:\t%s\n", formatFraction(b.Average))
+	_, _ = fmt.Fprintf(w, "  Max:\t%s\n", formatFraction(b.Max))
+	for _, bd := range b.Distribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% of calls within %s\n", bd.Percentage, formatFraction(bd.Fraction))
+	}
+	_, _ = fmt.Fprintf(w, "  Exceeded:\t%d calls\n", b.Exceeded)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+// formatFraction formats a fraction as a percentage
+func formatFraction(f float64) string {
+	return fmt.Sprintf("%4.2f %%", f*100)
+}
+
+func formatGraceRetries(g *runner.GraceRetries) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Period:\t%s\n", formatNanoUnit(g.Period))
+	_, _ = fmt.Fprintf(w, "  Retried:\t%d calls\n", g.Count)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String() + formatErrorDist(g.ErrorDist)
+}
+
+func formatDrain(d *runner.DrainStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Timeout:\t%s\n", formatNanoUnit(d.Timeout))
+	_, _ = fmt.Fprintf(w, "  Duration:\t%s\n", formatNanoUnit(d.Duration))
+	_, _ = fmt.Fprintf(w, "  In flight:\t%d calls\n", d.InFlight)
+	_, _ = fmt.Fprintf(w, "  Drained:\t%d calls\n", d.Drained)
+	_, _ = fmt.Fprintf(w, "  Cut:\t%d calls\n", d.Cut)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatHedging(h *runner.HedgeStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Policy:\t%s of calls after %s\n", formatFraction(h.Percent/100), formatNanoUnit(h.Delay))
+	_, _ = fmt.Fprintf(w, "  Calls:\t%d selected, %d hedged\n", h.Calls, h.Hedged)
+	_, _ = fmt.Fprintf(w, "  Hedge wins:\t%d (%s)\n", h.Wins, formatFraction(h.WinRate))
+	_, _ = fmt.Fprintf(w, "  Hedged latency:\taverage %s, p99 %s\n", formatNanoUnit(h.Average), formatNanoUnit(h.P99))
+	_, _ = fmt.Fprintf(w, "  Control latency:\taverage %s, p99 %s\n", formatNanoUnit(h.ControlAverage), formatNanoUnit(h.ControlP99))
+	_, _ = fmt.Fprintf(w, "  p99 improvement:\t%s\n", formatFraction(h.Improvement))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatCompression(c *runner.CompressionStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Sent:\t%s of %s (%s)\n", formatBytes(c.BytesSent), formatBytes(c.UncompressedBytesSent), formatFraction(c.SentRatio))
+	_, _ = fmt.Fprintf(w, "  Received:\t%s of %s (%s)\n", formatBytes(c.BytesReceived), formatBytes(c.UncompressedBytesReceived), formatFraction(c.ReceivedRatio))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatDecompression(d *runner.DecompressionStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Messages:\t%d\n", d.Messages)
+	_, _ = fmt.Fprintf(w, "  Size:\t%s of %s (%s), %s saved\n", formatBytes(d.CompressedBytes), formatBytes(d.DecompressedBytes), formatFraction(d.Ratio), formatBytes(d.SavedBytes))
+	_, _ = fmt.Fprintf(w, "  Time:\t%s total, %s average, %s slowest\n", formatNanoUnit(d.Total), formatNanoUnit(d.Average), formatNanoUnit(d.Slowest))
+	_, _ = fmt.Fprintf(w, "  Throughput:\t%s/sec\n", formatBytes(uint64(d.Throughput)))
+	if d.SavedBytes > 0 {
+		_, _ = fmt.Fprintf(w, "  Cost:\t%s per MB saved\n", formatNanoUnit(d.TimePerSavedMB))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatUpload(u *runner.UploadStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Payload:\t%s in %d chunks of %s\n", formatBytes(uint64(u.Size)), u.Chunks, formatBytes(uint64(u.ChunkSize)))
+	_, _ = fmt.Fprintf(w, "  Uploads:\t%d\n", u.Uploads)
+	_, _ = fmt.Fprintf(w, "  Throughput:\t%s/sec, %s/sec per call\n", formatBytes(uint64(u.Throughput)), formatBytes(uint64(u.CallThroughput)))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatAsyncQueue(q *runner.AsyncQueueStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Senders:\t%d per worker\n", q.Senders)
+	_, _ = fmt.Fprintf(w, "  Handlers:\t%d per worker\n", q.Handlers)
+	_, _ = fmt.Fprintf(w, "  Max in flight:\t%d\n", q.MaxInFlight)
+	_, _ = fmt.Fprintf(w, "  Queued responses:\taverage %s, max %d\n", formatSeconds(q.AverageQueued), q.MaxQueued)
+	_, _ = fmt.Fprintf(w, "  Blocked sends:\t%d\n", q.Blocked)
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatFieldStats(f *runner.ResponseFieldStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Responses:\t%d\n", f.Count)
+	if v := f.Values; v != nil {
+		_, _ = fmt.Fprintf(w, "  Min:\t%s\n", formatFieldValue(v.Min))
+		_, _ = fmt.Fprintf(w, "  Mean:\t%s\n", formatFieldValue(v.Mean))
+		_, _ = fmt.Fprintf(w, "  Max:\t%s\n", formatFieldValue(v.Max))
+		for _, d := range v.Distribution {
+			_, _ = fmt.Fprintf(w, "\t%d %% <= %s\n", d.Percentage, formatFieldValue(d.Value))
+		}
+		_, _ = fmt.Fprintf(w, "  Latency correlation:\t%.2f\n", v.Correlation)
+	}
+	for _, c := range f.Categories {
+		_, _ = fmt.Fprintf(w, "  %s\t%d\t%s %%\tavg latency %s\t\n",
+			c.Value, c.Count, formatPercent(int(c.Count), f.Count), formatNanoUnit(c.Average))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatStageTiming(st *runner.StageTimingStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Calls:\t%d\t\n", st.Calls)
+	for _, s := range st.Stages {
+		_, _ = fmt.Fprintf(w, "  %s\t%d\tavg %s\tmax %s\t%.2f %%\t\n",
+			s.Stage, s.Count, formatNanoUnit(s.Average), formatNanoUnit(s.Slowest), s.Share*100)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatPagination(p *runner.PaginationStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Operations:\t%d\n", p.Operations)
+	_, _ = fmt.Fprintf(w, "  Exhausted:\t%d\n", p.Exhausted)
+	_, _ = fmt.Fprintf(w, "  Truncated:\t%d\n", p.Truncated)
+	_, _ = fmt.Fprintf(w, "  Failed:\t%d\n", p.Failed)
+	_, _ = fmt.Fprintf(w, "  Pages:\t%d\n", p.Pages)
+	_, _ = fmt.Fprintf(w, "  Pages per operation:\taverage %.2f, max %d\n", p.AveragePages, p.MostPages)
+	_, _ = fmt.Fprintf(w, "  Average:\t%s\n", formatNanoUnit(p.Average))
+	_, _ = fmt.Fprintf(w, "  Fastest:\t%s\n", formatNanoUnit(p.Fastest))
+	_, _ = fmt.Fprintf(w, "  Slowest:\t%s\n", formatNanoUnit(p.Slowest))
+	for _, ld := range p.LatencyDistribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatIterations(s *runner.IterationStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Iterations:\t%d\n", s.Iterations)
+	_, _ = fmt.Fprintf(w, "  Rate:\t%.2f per second, target %d\n", s.Rps, s.Rate)
+	_, _ = fmt.Fprintf(w, "  Calls per iteration:\t%d\n", s.Calls)
+	_, _ = fmt.Fprintf(w, "  Completed:\t%d\n", s.Completed)
+	_, _ = fmt.Fprintf(w, "  Failed:\t%d\n", s.Failed)
+	_, _ = fmt.Fprintf(w, "  Average:\t%s\n", formatNanoUnit(s.Average))
+	_, _ = fmt.Fprintf(w, "  Fastest:\t%s\n", formatNanoUnit(s.Fastest))
+	_, _ = fmt.Fprintf(w, "  Slowest:\t%s\n", formatNanoUnit(s.Slowest))
+	for _, ld := range s.LatencyDistribution {
+		_, _ = fmt.Fprintf(w, "\t%d %% in %s\n", ld.Percentage, formatNanoUnit(ld.Latency))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	if len(s.Waterfall) > 0 {
+		_, _ = fmt.Fprint(buf, "  Waterfall:\n")
+		_, _ = fmt.Fprint(buf, formatWaterfall(s.Waterfall))
+	}
+	return buf.String()
+}
+
+// formatWaterfall formats the steps of the iterations, each with a bar starting at the average
+// offset of the step and as long as its average latency
+func formatWaterfall(steps []runner.IterationStep) string {
+	var end time.Duration
+	for _, st := range steps {
+		if e := st.Offset + st.Average; e > end {
+			end = e
+		}
+	}
+
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, st := range steps {
+		pad, bar := 0, 1
+		if end > 0 {
+			pad = int(waterfallWidth * int64(st.Offset) / int64(end))
+			if l := int(waterfallWidth * int64(st.Average) / int64(end)); l > bar {
+				bar = l
+			}
+		}
+
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "    %d [%s]\tstart +%s\tgap %s\tavg %s\tslowest %s\t%.2f %%\t|%s%s\n",
+			st.Step, st.Method, formatNanoUnit(st.Offset), formatNanoUnit(st.Gap), formatNanoUnit(st.Average),
+			formatNanoUnit(st.Slowest), st.Share, strings.Repeat(" ", pad), strings.Repeat(barChar, bar))
+	}
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatTenants(f *runner.TenantFairness) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, t := range f.Tenants {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%d responses\t%d errors (%.2f %%)\tgoodput %.2f\tavg %s", t.Tenant, t.Count, t.Errors, t.ErrorRate*100, t.Goodput, formatNanoUnit(t.Average))
+		for _, ld := range t.LatencyDistribution {
+			if ld.Percentage == 50 || ld.Percentage == 90 || ld.Percentage == 99 {
+				_, _ = fmt.Fprintf(w, "\tp%d %s", ld.Percentage, formatNanoUnit(ld.Latency))
+			}
+		}
+		_, _ = fmt.Fprint(w, "\t\n")
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	_, _ = fmt.Fprintf(buf, "  Fairness index: %.4f, latency %.4f\n", f.Fairness, f.LatencyFairness)
+	return buf.String()
+}
+
+func formatFieldValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// maxPrintedTraces is the number of the slowest sampled traces printed in the summary
+const maxPrintedTraces = 10
+
+func formatTraces(traces []runner.Trace) string {
+	if len(traces) > maxPrintedTraces {
+		traces = traces[:maxPrintedTraces]
+	}
+
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, t := range traces {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  %s	%s	[%s]	\n", formatNanoUnit(t.Latency), t.TraceID, t.Status)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatConnections(conns []runner.ConnectionStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, c := range conns {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%d]\t%d workers\t%d calls\tmax %d streams\tsent %s\treceived %s\tconnects %d\tlifetime %s\t%s\t%s\t\n",
+			c.ID, c.Workers, c.Calls, c.MaxStreams, formatBytes(c.BytesSent), formatBytes(c.BytesReceived), c.Connects, formatNanoUnit(c.Lifetime),
+			c.Family, c.RemoteAddr)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatShards(s *runner.ShardStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for i, c := range s.Counts {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%d]\t%d calls\t\n", i, c)
+	}
+	if c := s.Connections; c != nil {
+		_, _ = fmt.Fprintf(w, "  Dedicated\t%d calls\tmax %d connections\tpeak %d\t\n", c.Calls, c.Max, c.Peak)
+		_, _ = fmt.Fprintf(w, "  Opened\t%d\treopened %d\toverflows %d\t\n", c.Opened, c.Reopened, c.Overflows)
+		_, _ = fmt.Fprintf(w, "  Recycled\t%d\t%.2f calls each\tlifetime %s\t\n", c.Recycled, c.RecycledCalls, formatNanoUnit(c.RecycledLifetime))
+	}
+	if s.Unkeyed > 0 {
+		_, _ = fmt.Fprintf(w, "  Unkeyed\t%d calls\t\n", s.Unkeyed)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatChurn(c *runner.ChurnStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	if c.Interval > 0 {
+		_, _ = fmt.Fprintf(w, "  Interval:\t%s\n", formatNanoUnit(c.Interval))
+	}
+	if c.Requests > 0 {
+		_, _ = fmt.Fprintf(w, "  Requests:\t%d calls\n", c.Requests)
+	}
+	_, _ = fmt.Fprintf(w, "  Re-dialed:\t%d connections\n", c.Redials)
+	if c.Failed > 0 {
+		_, _ = fmt.Fprintf(w, "  Failed:\t%d re-dials\n", c.Failed)
+	}
+	if c.Redials > 0 {
+		_, _ = fmt.Fprintf(w, "  Lifetime:\t%s\n", formatNanoUnit(c.Lifetime))
+		_, _ = fmt.Fprintf(w, "  Calls:\t%.2f per connection\n", c.Calls)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatScaling(s *runner.ScalingStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Maximum:\t%d connections\t\n", s.Max)
+	_, _ = fmt.Fprintf(w, "  Ratio:\t%.2f\t\n", s.Ratio)
+	_, _ = fmt.Fprintf(w, "  Connections:\t%d\t\n", s.Connections)
+	if s.Failed > 0 {
+		_, _ = fmt.Fprintf(w, "  Failed:\t%d connections\t\n", s.Failed)
+	}
+	for _, e := range s.Events {
+		_, _ = fmt.Fprintf(w, "  At %s:\t%d connections\tqueue %s, server %s\tafter queue %s, server %s\t\n",
+			formatNanoUnit(e.Time), e.Connections, formatNanoUnit(e.Queue), formatNanoUnit(e.Server),
+			formatNanoUnit(e.QueueAfter), formatNanoUnit(e.ServerAfter))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatConnect(c *runner.ConnectStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Policy:\t%s\n", c.Policy)
+	_, _ = fmt.Fprintf(w, "  Connected:\t%d of %d\n", c.Connected, c.Requested)
+	_, _ = fmt.Fprintf(w, "  Retries:\t%d\n", c.Retries)
+	if c.Failed > 0 {
+		_, _ = fmt.Fprintf(w, "  Failed:\t%d connections\n", c.Failed)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatServer(s *runner.ServerInfo) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	if len(s.Services) > 0 {
+		_, _ = fmt.Fprintf(w, "  Services:\t%s\t\n", strings.Join(s.Services, ", "))
+	}
+	if s.Health != "" {
+		_, _ = fmt.Fprintf(w, "  Health:\t%s\t\n", s.Health)
+	}
+	if s.VersionCall != "" {
+		_, _ = fmt.Fprintf(w, "  Version:\t%s\t%s\t\n", s.VersionCall, string(s.Version))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatReadiness(r *runner.ReadinessStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	probe := r.Probe
+	if probe == "" {
+		probe = "health"
+	}
+	_, _ = fmt.Fprintf(w, "  Probe:\t%s\n", probe)
+	_, _ = fmt.Fprintf(w, "  Attempts:\t%d over %d connections\n", r.Attempts, r.Connections)
+	_, _ = fmt.Fprintf(w, "  Wait:\t%s\n", formatNanoUnit(r.Wait))
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatCanary(c *runner.CanaryStats) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	// bytes.Buffer can be assumed to not fail on write
+	_, _ = fmt.Fprintf(w, "  Checks:\t%d\t\n", c.Checks)
+	_, _ = fmt.Fprintf(w, "  Mismatches:\t%d\t\n", c.Mismatches)
+	_, _ = fmt.Fprintf(w, "  Errors:\t%d\t\n", c.Errors)
+	if d := c.FirstDivergence; d != nil {
+		_, _ = fmt.Fprintf(w, "  First divergence:\tcheck %d after %v\t\n", d.Check, d.Elapsed.Round(time.Millisecond))
+		_, _ = fmt.Fprintf(w, "    Response:\t%s\t\n", string(d.Response))
+		_, _ = fmt.Fprintf(w, "    Expected:\t%s\t\n", string(d.Expected))
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatMetrics(metrics []runner.DerivedMetric) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, m := range metrics {
+		value := formatMetricValue(m.Value)
+		if m.Error != "" {
+			value = "error: " + m.Error
+		}
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t\n", m.Name, value)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+// formatMetricValue formats the metric value with up to 6 significant digits, without an exponent
+func formatMetricValue(v float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 6, 64), 64)
+	if err != nil {
+		rounded = v
+	}
+
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+func formatAdjustments(adjustments []runner.Adjustment) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, a := range adjustments {
+		value := strconv.FormatUint(uint64(a.Value), 10)
+		if a.Setting == "rps" && a.Value == 0 {
+			value = "unlimited"
+		}
+		if a.Setting == "paused" {
+			value = strconv.FormatBool(a.Value > 0)
+		}
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%s\t%s\t\n", formatNanoUnit(a.Elapsed), a.Setting, value)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatSchemaChanges(changes []runner.SchemaChange) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, c := range changes {
+		for _, change := range c.Changes {
+			// bytes.Buffer can be assumed to not fail on write
+			_, _ = fmt.Fprintf(w, "  [%s]\t%s\t\n", formatNanoUnit(c.Elapsed), change)
+		}
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatRecommendations(recs []runner.Recommendation) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, r := range recs {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%s\t\n", r.Reason, r.Message)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatBytes(b uint64) string {
+	if b < 1024 {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	v := float64(b) / 1024
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		if v < 1024 {
+			return fmt.Sprintf("%.2f %s", v, unit)
+		}
+
+		v = v / 1024
+	}
+
+	return fmt.Sprintf("%.2f TiB", v)
+}
+
+// sortedCodes returns the status codes of the distribution in sorted order
+func sortedCodes(statusCodeDist map[string]int) []string {
+	codes := make([]string, 0, len(statusCodeDist))
+	for status := range statusCodeDist {
+		codes = append(codes, status)
+	}
+
+	sort.Strings(codes)
+
+	return codes
+}
+
+func formatErrorDist(errDist map[string]int) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for status, count := range errDist {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%+v]\t%+s\t\n", count, status)
+	}
+	// bytes.Buffer can be assumed to not fail on write
+	_ = w.Flush()
+	return buf.String()
+}
+
+func formatStatusBreakdown(breakdown []runner.StatusBreakdown) string {
+	padding := 3
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	for _, b := range breakdown {
+		// bytes.Buffer can be assumed to not fail on write
+		_, _ = fmt.Fprintf(w, "  [%s]\t%d responses\t\n", b.Code, b.Count)
+		for _, m := range b.Messages {
+			_, _ = fmt.Fprintf(w, "    [%d]\t%s\t\n", m.Count, m.Message)
+		}
+		for _, s := range b.Samples {
+			_, _ = fmt.Fprintf(w, "    sample\t%s\t\n", s.Message)
+			for _, d := range s.Details {
+				_, _ = fmt.Fprintf(w, "      details\t%s\t\n", d)
+			}
+
+			keys := make([]string, 0, len(s.Trailer))
+			for k := range s.Trailer {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				_, _ = fmt.Fprintf(w, "      trailer\t%s: %s\t\n", k, strings.Join(s.Trailer[k], ", "))
+			}
+		}
 	}
 	// bytes.Buffer can be assumed to not fail on write
 	_ = w.Flush()
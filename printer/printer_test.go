@@ -2,10 +2,13 @@ package printer
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/bojand/ghz/load"
 	"github.com/bojand/ghz/runner"
 	"github.com/stretchr/testify/assert"
 )
@@ -113,7 +116,7 @@ func TestPrinter_getInfluxLine(t *testing.T) {
 					},
 				},
 			},
-			fmt.Sprintf(`ghz_run,name="run\ name",proto="/apis/greeter.proto",call="helloworld.Greeter.SayHello",host="0.0.0.0:50051",n=200,c=50,rps=0,z=0,timeout=0,dial_timeout=0,keepalive=0,data="{\"name\":\"Bob\ Smith\"}",metadata="{\"foo\ bar\":\"biz\ baz\"}",tags="",errors=5,has_errors=true count=200,total=2000000000,average=10000000,fastest=1000000,slowest=100000000,rps=2000.00,median=5000000,p95=20000000,errors=5 %+v`, unixTimeNow),
+			fmt.Sprintf(`ghz_run,name="run\ name",proto="/apis/greeter.proto",call="helloworld.Greeter.SayHello",host="0.0.0.0:50051",n=200,c=50,rps=0,z=0,timeout=0,dial_timeout=0,keepalive=0,data="{\"name\":\"Bob\ Smith\"}",metadata="{\"foo\ bar\":\"biz\ baz\"}",tags="",errors=5,has_errors=true count=200,total=2000000000,average=10000000,fastest=1000000,slowest=100000000,rps=2000.00,median=5000000,p95=20000000,errors=5,code_DeadlineExceeded=2,code_Internal=3,code_OK=195 %+v`, unixTimeNow),
 		},
 	}
 
@@ -245,3 +248,1387 @@ func TestPrinter_printInfluxDetails(t *testing.T) {
 		})
 	}
 }
+
+func TestPrinter_printFolded(t *testing.T) {
+	report := runner.Report{
+		Options: runner.Options{Call: "helloworld.Greeter.SayHello"},
+		StageTiming: &runner.StageTimingStats{
+			Calls:   10,
+			Latency: 100 * time.Millisecond,
+			Stages: []runner.StageStats{
+				{Stage: "handler", Total: 80 * time.Millisecond},
+				{Stage: "handler;cache", Total: 5 * time.Millisecond},
+				{Stage: "handler;db", Total: 60 * time.Millisecond},
+				{Stage: "queue", Total: 10 * time.Millisecond},
+			},
+		},
+	}
+
+	buf := bytes.NewBufferString("")
+	p := ReportPrinter{Report: &report, Out: buf}
+	assert.NoError(t, p.Print("folded"))
+	assert.Equal(t, "helloworld.Greeter.SayHello 10000\n"+
+		"helloworld.Greeter.SayHello;handler 15000\n"+
+		"helloworld.Greeter.SayHello;handler;cache 5000\n"+
+		"helloworld.Greeter.SayHello;handler;db 60000\n"+
+		"helloworld.Greeter.SayHello;queue 10000\n", buf.String())
+
+	p = ReportPrinter{Report: &runner.Report{}, Out: buf}
+	assert.EqualError(t, p.Print("folded"), "folded output requires stage timings")
+}
+
+func TestPrinter_printOpenMetrics(t *testing.T) {
+	report := runner.Report{
+		Name:    "nightly",
+		Date:    time.Unix(1615128192, 0),
+		Options: runner.Options{Call: "helloworld.Greeter.SayHello", Host: "localhost:50051"},
+		Tags:    map[string]string{"env": "ci", "build-id": `a"1`},
+		Count:   4,
+		Total:   2 * time.Second,
+		Average: 25 * time.Millisecond,
+		Fastest: 10 * time.Millisecond,
+		Slowest: 40 * time.Millisecond,
+		Rps:     2,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 20 * time.Millisecond},
+			{Percentage: 99, Latency: 40 * time.Millisecond},
+		},
+		ErrorDist:      map[string]int{"unavailable": 1},
+		StatusCodeDist: map[string]int{"OK": 3, "Unavailable": 1},
+		Metrics:        []runner.DerivedMetric{{Name: "p99-ratio", Value: 1.6}, {Name: "broken", Error: "bad"}},
+	}
+
+	buf := bytes.NewBufferString("")
+	p := ReportPrinter{Report: &report, Out: buf}
+	assert.NoError(t, p.Print("openmetrics"))
+
+	labels := `build_id="a\"1",call="helloworld.Greeter.SayHello",env="ci",host="localhost:50051",name="nightly"`
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE ghz_requests counter\n# HELP ghz_requests Number of calls of the run.\nghz_requests_total{"+labels+"} 4\n")
+	assert.Contains(t, out, "ghz_errors_total{"+labels+"} 1\n")
+	assert.Contains(t, out, "ghz_responses_total{"+labels+`,status="OK"} 3`+"\n")
+	assert.Contains(t, out, "ghz_responses_total{"+labels+`,status="Unavailable"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE ghz_latency_seconds summary\n# UNIT ghz_latency_seconds seconds\n")
+	assert.Contains(t, out, "ghz_latency_seconds{"+labels+`,quantile="0.5"} 0.02`+"\n")
+	assert.Contains(t, out, "ghz_latency_seconds{"+labels+`,quantile="0.99"} 0.04`+"\n")
+	assert.Contains(t, out, "ghz_latency_seconds_sum{"+labels+"} 0.1\n")
+	assert.Contains(t, out, "ghz_latency_seconds_count{"+labels+"} 4\n")
+	assert.Contains(t, out, "ghz_metric_p99_ratio{"+labels+"} 1.6\n")
+	assert.NotContains(t, out, "ghz_metric_broken")
+	assert.NotContains(t, out, "ghz_apdex_score")
+	assert.Contains(t, out, "ghz_run_timestamp_seconds{"+labels+"} 1.615128192e+09\n")
+	assert.True(t, strings.HasSuffix(out, "\n# EOF\n"))
+}
+
+func TestPrinter_PrintGrafanaDashboard(t *testing.T) {
+	report := runner.Report{
+		Name:    "nightly",
+		RunID:   "01E6T4XH7ZCSH0K9AQMMA0Y4R1",
+		Options: runner.Options{Call: "helloworld.Greeter.SayHello", Host: "localhost:50051"},
+		Tags:    map[string]string{"env": "ci"},
+	}
+
+	dashboard := func(datasource string) map[string]interface{} {
+		buf := bytes.NewBufferString("")
+		p := ReportPrinter{Report: &report, Out: buf}
+		assert.NoError(t, p.PrintGrafanaDashboard(datasource))
+
+		var d map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &d))
+
+		return d
+	}
+
+	t.Run("prometheus", func(t *testing.T) {
+		d := dashboard(GrafanaPrometheus)
+
+		assert.Equal(t, "ghz: nightly", d["title"])
+		assert.Equal(t, "DS_PROMETHEUS", d["__inputs"].([]interface{})[0].(map[string]interface{})["name"])
+
+		panels := d["panels"].([]interface{})
+		assert.Len(t, panels, 4)
+
+		panel := panels[1].(map[string]interface{})
+		assert.Equal(t, "Latency", panel["title"])
+		assert.Equal(t, "${DS_PROMETHEUS}", panel["datasource"])
+		assert.Equal(t, map[string]interface{}{"h": 8.0, "w": 12.0, "x": 12.0, "y": 0.0}, panel["gridPos"])
+
+		target := panel["targets"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, `ghz_latency_seconds{call="helloworld.Greeter.SayHello",env="ci",host="localhost:50051",name="nightly",quantile=~"0.5|0.9|0.95|0.99"}`, target["expr"])
+		assert.Equal(t, "p{{quantile}}", target["legendFormat"])
+	})
+
+	t.Run("influx", func(t *testing.T) {
+		d := dashboard(GrafanaInflux)
+
+		assert.Equal(t, "DS_INFLUXDB", d["__inputs"].([]interface{})[0].(map[string]interface{})["name"])
+
+		panel := d["panels"].([]interface{})[0].(map[string]interface{})
+		target := panel["targets"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, `SELECT mean("rps") FROM "ghz_run" WHERE "name" = '"nightly"' AND "call" = '"helloworld.Greeter.SayHello"' AND `+
+			`"host" = '"localhost:50051"' AND "tags" = '"{\\"env\\":\\"ci\\"}"' AND $timeFilter GROUP BY time($__interval) fill(none)`, target["query"])
+		assert.Equal(t, true, target["rawQuery"])
+	})
+
+	t.Run("same runs", func(t *testing.T) {
+		uid := dashboard(GrafanaPrometheus)["uid"]
+
+		report.RunID = "01E6T4XH7ZCSH0K9AQMMA0Y4R2"
+		assert.Equal(t, uid, dashboard(GrafanaPrometheus)["uid"])
+		assert.NotEqual(t, uid, dashboard(GrafanaInflux)["uid"])
+
+		report.Tags = map[string]string{"env": "staging"}
+		assert.NotEqual(t, uid, dashboard(GrafanaPrometheus)["uid"])
+	})
+
+	t.Run("unknown data source", func(t *testing.T) {
+		p := ReportPrinter{Report: &report, Out: bytes.NewBufferString("")}
+		assert.EqualError(t, p.PrintGrafanaDashboard("graphite"), "unknown Grafana data source: graphite")
+	})
+}
+
+func TestPrinter_getSummaryLine(t *testing.T) {
+	report := runner.Report{
+		EndReason: runner.ReasonTimeout,
+		Count:     200,
+		Total:     time.Duration(2 * time.Second),
+		Average:   time.Duration(10 * time.Millisecond),
+		Fastest:   time.Duration(1500 * time.Microsecond),
+		Slowest:   time.Duration(100 * time.Millisecond),
+		Rps:       100,
+		ErrorDist: map[string]int{
+			"rpc error: code = Internal desc = Internal error.":            3,
+			"rpc error: code = DeadlineExceeded desc = Deadline exceeded.": 2},
+		StatusCodeDist: map[string]int{
+			"OK":               195,
+			"Internal":         3,
+			"DeadlineExceeded": 2},
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 10, Latency: time.Duration(1 * time.Millisecond)},
+			{Percentage: 50, Latency: time.Duration(5 * time.Millisecond)},
+			{Percentage: 90, Latency: time.Duration(15 * time.Millisecond)},
+			{Percentage: 95, Latency: time.Duration(20 * time.Millisecond)},
+			{Percentage: 99, Latency: time.Duration(25 * time.Millisecond)},
+		},
+	}
+
+	p := ReportPrinter{Report: &report}
+	actual := p.getSummaryLine()
+	assert.Equal(t, "count=200 ok=195 errors=5 rps=100.00 total_ms=2000.00 average_ms=10.00 fastest_ms=1.50 slowest_ms=100.00 p50_ms=5.00 p90_ms=15.00 p95_ms=20.00 p99_ms=25.00 reason=timeout", actual)
+
+	report.RunID = "01E6T4XH7ZCSH0K9AQMMA0Y4R1"
+	actual = p.getSummaryLine()
+	assert.True(t, strings.HasSuffix(actual, " reason=timeout run_id=01E6T4XH7ZCSH0K9AQMMA0Y4R1"), actual)
+
+	report.Thresholds = []runner.ThresholdResult{
+		{Threshold: "Internal==0", Actual: 3, Pass: false},
+	}
+	actual = p.getSummaryLine()
+	assert.True(t, strings.HasSuffix(actual, " reason=timeout thresholds=fail run_id=01E6T4XH7ZCSH0K9AQMMA0Y4R1"), actual)
+}
+
+func TestPrinter_formatThresholds(t *testing.T) {
+	actual := formatThresholds([]runner.ThresholdResult{
+		{Threshold: "Unavailable==0", Actual: 0, Pass: true},
+		{Threshold: "DeadlineExceeded<1%", Actual: 2.5, Pass: false},
+	})
+
+	assert.Equal(t, "  [pass]   Unavailable==0        actual 0     \n  [fail]   DeadlineExceeded<1%   actual 2.5   \n", actual)
+}
+
+func TestPrinter_formatAssertions(t *testing.T) {
+	actual := formatAssertions([]runner.AssertionStats{
+		{Rule: "status == OK", Passed: 10},
+		{Rule: `$.message != ""`, Passed: 7, Failed: 3},
+	})
+
+	assert.Equal(t, "  [pass]   status == OK      10 passed   0 failed   \n  [fail]   $.message != \"\"   7 passed    3 failed   \n", actual)
+}
+
+func TestPrinter_OutputPath(t *testing.T) {
+	report := runner.Report{
+		RunID: "01E6T4XH7ZCSH0K9AQMMA0Y4R1",
+		Name:  "my run",
+		Date:  time.Date(2020, time.March, 7, 14, 5, 9, 0, time.UTC),
+		Options: runner.Options{
+			Call: "helloworld.Greeter.SayHello",
+			Host: "localhost:50051",
+		},
+	}
+
+	var tests = []struct {
+		name     string
+		tmpl     string
+		expected string
+		ok       bool
+	}{
+		{"no template", "report.json", "report.json", true},
+		{"name date host", "report-{{.Name}}-{{.Date}}-{{.Host}}.json", "report-my_run-2020-03-07-localhost_50051.json", true},
+		{"time and call", "out/{{.Call}}-{{.Date}}T{{.Time}}.html", "out/helloworld.Greeter.SayHello-2020-03-07T140509.html", true},
+		{"timestamp", "{{.Timestamp}}.json", "1583589909.json", true},
+		{"run id", "report-{{.RunID}}.json", "report-01E6T4XH7ZCSH0K9AQMMA0Y4R1.json", true},
+		{"rotation", "report-{{.Rotation}}.json", "report-0.json", true},
+		{"invalid template", "report-{{.Name.json", "", false},
+		{"unknown field", "report-{{.Foo}}.json", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ReportPrinter{Report: &report}
+			actual, err := p.OutputPath(tt.tmpl)
+			if tt.ok {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, actual)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPrinter_formatBackoff(t *testing.T) {
+	actual := formatBackoff([]load.AdaptiveAction{
+		{Elapsed: time.Second, ErrorRate: 0.5, Factor: 0.5},
+		{Elapsed: 2 * time.Second, ErrorRate: 0, Factor: 0.6},
+	})
+
+	assert.Equal(t, "  [1.00 s]   error rate 50.00 %   load 50 %   \n  [2.00 s]   error rate 0.00 %    load 60 %   \n", actual)
+}
+
+func TestPrinter_formatLatencyControl(t *testing.T) {
+	actual := formatLatencyControl([]load.LatencyAction{
+		{Elapsed: time.Second, Latency: 120 * time.Millisecond, Achieved: 10, Rate: 15},
+		{Elapsed: 2 * time.Second, Latency: 250 * time.Millisecond, Achieved: 14.5, Rate: 9.375},
+	})
+
+	assert.Equal(t, "  [1.00 s]   p99 120.00 ms   achieved 10.00 rps   rate 15.00 rps   \n  [2.00 s]   p99 250.00 ms   achieved 14.50 rps   rate 9.38 rps    \n", actual)
+}
+
+func TestPrinter_formatWorkerGroups(t *testing.T) {
+	actual := formatWorkerGroups([]runner.WorkerGroupStats{
+		{
+			Group:   0,
+			CPUs:    "0-1",
+			Workers: 2,
+			Count:   10,
+			Average: 20 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 10, Latency: 5 * time.Millisecond},
+				{Percentage: 50, Latency: 15 * time.Millisecond},
+				{Percentage: 90, Latency: 30 * time.Millisecond},
+				{Percentage: 99, Latency: 50 * time.Millisecond},
+			},
+		},
+		{
+			Group:   1,
+			CPUs:    "any",
+			Workers: 2,
+			Count:   8,
+			Average: 2 * time.Millisecond,
+		},
+	})
+
+	assert.Equal(t, "  [0: 0-1]   2 workers   10 responses   avg 20.00 ms   p50 15.00 ms   p90 30.00 ms   p99 50.00 ms   \n"+
+		"  [1: any]   2 workers   8 responses    avg 2.00 ms    \n", actual)
+}
+
+func TestPrinter_formatLabelLatency(t *testing.T) {
+	actual := formatLabelLatency([]runner.LabelLatency{
+		{
+			Label:   "large",
+			Count:   10,
+			Average: 20 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 10, Latency: 5 * time.Millisecond},
+				{Percentage: 50, Latency: 15 * time.Millisecond},
+				{Percentage: 90, Latency: 30 * time.Millisecond},
+				{Percentage: 99, Latency: 50 * time.Millisecond},
+			},
+		},
+		{
+			Label:   "small",
+			Count:   5,
+			Average: 2 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 50, Latency: time.Millisecond},
+				{Percentage: 90, Latency: 3 * time.Millisecond},
+				{Percentage: 99, Latency: 4 * time.Millisecond},
+			},
+		},
+	})
+
+	assert.Equal(t, "  [large]   10 responses   avg 20.00 ms   p50 15.00 ms   p90 30.00 ms   p99 50.00 ms   \n"+
+		"  [small]   5 responses    avg 2.00 ms    p50 1.00 ms    p90 3.00 ms    p99 4.00 ms    \n", actual)
+}
+
+func TestPrinter_formatMethods(t *testing.T) {
+	actual := formatMethods([]runner.MethodStats{
+		{
+			Method:  "get",
+			Count:   70,
+			Rps:     700,
+			Average: 2 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 50, Latency: time.Millisecond},
+				{Percentage: 90, Latency: 3 * time.Millisecond},
+				{Percentage: 99, Latency: 4 * time.Millisecond},
+			},
+			ErrorDist: map[string]int{"not found": 2, "unavailable": 1},
+		},
+		{
+			Method:  "list",
+			Count:   30,
+			Rps:     300,
+			Average: 20 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 50, Latency: 15 * time.Millisecond},
+				{Percentage: 90, Latency: 30 * time.Millisecond},
+				{Percentage: 99, Latency: 50 * time.Millisecond},
+			},
+		},
+	})
+
+	assert.Equal(t, "  [get]    70 responses   700.00 rps   avg 2.00 ms    p50 1.00 ms    p90 3.00 ms    p99 4.00 ms    3 errors   \n"+
+		"  [list]   30 responses   300.00 rps   avg 20.00 ms   p50 15.00 ms   p90 30.00 ms   p99 50.00 ms   0 errors   \n", actual)
+}
+
+func TestPrinter_formatStream(t *testing.T) {
+	actual := formatStream(&runner.StreamStats{
+		Field:      "id",
+		Sent:       10,
+		Received:   12,
+		Matched:    8,
+		Unanswered: 2,
+		Pushed:     4,
+		PushRate:   2,
+		Average:    2 * time.Millisecond,
+		Fastest:    time.Millisecond,
+		Slowest:    3 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 2 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Correlation field:   id\n"+
+		"  Sent:                10\n"+
+		"  Received:            12\n"+
+		"  Matched:             8\n"+
+		"  Unanswered:          2\n"+
+		"  Server pushed:       4 (2.00/sec)\n"+
+		"  Response latency:    avg 2.00 ms, fastest 1.00 ms, slowest 3.00 ms\n"+
+		"                       50 % in 2.00 ms\n", actual)
+}
+
+func TestPrinter_formatStreamAcks(t *testing.T) {
+	actual := formatStreamAcks(&runner.StreamAckStats{
+		Field:      "count",
+		Streams:    20,
+		Matched:    18,
+		Mismatched: 2,
+		Dropped:    3,
+		Mismatches: []runner.StreamAckMismatch{{Sent: 10, Acked: 8}, {Sent: 10, Acked: 9}},
+	})
+
+	assert.Equal(t, "  Ack field:    count\n"+
+		"  Streams:      20\n"+
+		"  Matched:      18\n"+
+		"  Mismatched:   2\n"+
+		"  Dropped:      3 messages\n"+
+		"                sent 10, acked 8\n"+
+		"                sent 10, acked 9\n", actual)
+}
+
+func TestPrinter_formatSchedulerLag(t *testing.T) {
+	actual := formatSchedulerLag(&runner.SchedulerLag{
+		Count:   200,
+		Average: 2 * time.Millisecond,
+		Max:     15 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 1500 * time.Microsecond},
+			{Percentage: 99, Latency: 12 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Paced requests:   200\n"+
+		"  Average:          2.00 ms\n"+
+		"  Max:              15.00 ms\n"+
+		"                    50 % in 1.50 ms\n"+
+		"                    99 % in 12.00 ms\n", actual)
+}
+
+func TestPrinter_formatHeaderLatency(t *testing.T) {
+	actual := formatHeaderLatency(&runner.HeaderLatency{
+		Count:   200,
+		Average: 2 * time.Millisecond,
+		Fastest: 500 * time.Microsecond,
+		Slowest: 15 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 1500 * time.Microsecond},
+			{Percentage: 99, Latency: 12 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Requests:   200\n"+
+		"  Average:    2.00 ms\n"+
+		"  Fastest:    0.50 ms\n"+
+		"  Slowest:    15.00 ms\n"+
+		"              50 % in 1.50 ms\n"+
+		"              99 % in 12.00 ms\n", actual)
+}
+
+func TestPrinter_formatPhases(t *testing.T) {
+	actual := formatPhases([]runner.SchedulePhase{
+		{Phase: 1, Concurrency: 10, TargetRPS: 100, Count: 3000, Rps: 100, Average: 2 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{{Percentage: 50, Latency: time.Millisecond}}},
+		{Phase: 2, Start: 30 * time.Second, Concurrency: 20, Count: 9000, Errors: 12, Rps: 300, Average: 5 * time.Millisecond},
+	})
+
+	assert.Equal(t, "  [1]   0s    10 workers   100.00 rps   3000 responses   0 errors    100.00 rps   avg 2.00 ms   p50 1.00 ms   \n"+
+		"  [2]   30s   20 workers   unlimited    9000 responses   12 errors   300.00 rps   avg 5.00 ms   \n", actual)
+}
+
+func TestPrinter_formatDeadline(t *testing.T) {
+	actual := formatDeadline(&runner.DeadlineBudget{
+		Timeout: 250 * time.Millisecond,
+		Count:   100,
+		Average: 0.125,
+		Max:     1,
+		Distribution: []runner.BudgetDistribution{
+			{Percentage: 50, Fraction: 0.1},
+			{Percentage: 99, Fraction: 0.955},
+		},
+		Exceeded: 2,
+	})
+
+	assert.Equal(t, "  Timeout:    250.00 ms\n"+
+		"  Average:    12.50 %\n"+
+		"  Max:        100.00 %\n"+
+		"              50 % of calls within 10.00 %\n"+
+		"              99 % of calls within 95.50 %\n"+
+		"  Exceeded:   2 calls\n", actual)
+}
+
+func TestPrinter_formatPacing(t *testing.T) {
+	actual := formatPacing(&runner.PacingStats{
+		Pace:        "poisson:100ms",
+		ThinkTime:   98 * time.Millisecond,
+		Workers:     10,
+		AverageRate: 9.5,
+		MinRate:     8.25,
+		MaxRate:     10.75,
+	})
+
+	assert.Equal(t, "  Pace:          poisson:100ms\n"+
+		"  Think time:    98.00 ms\n"+
+		"  Workers:       10\n"+
+		"  Worker rate:   avg 9.50, min 8.25, max 10.75 req/s\n", actual)
+}
+
+func TestPrinter_formatGraceRetries(t *testing.T) {
+	actual := formatGraceRetries(&runner.GraceRetries{
+		Period:    10 * time.Second,
+		Count:     15,
+		ErrorDist: map[string]int{"rpc error: code = Unavailable desc = connection refused": 15},
+	})
+
+	assert.Equal(t, "  Period:    10.00 s\n"+
+		"  Retried:   15 calls\n"+
+		"  [15]   rpc error: code = Unavailable desc = connection refused   \n", actual)
+}
+
+func TestPrinter_formatDrain(t *testing.T) {
+	actual := formatDrain(&runner.DrainStats{
+		Timeout:  10 * time.Second,
+		Duration: 2 * time.Second,
+		InFlight: 12,
+		Drained:  10,
+		Cut:      2,
+	})
+
+	assert.Equal(t, "  Timeout:     10.00 s\n"+
+		"  Duration:    2.00 s\n"+
+		"  In flight:   12 calls\n"+
+		"  Drained:     10 calls\n"+
+		"  Cut:         2 calls\n", actual)
+}
+
+func TestPrinter_formatHedging(t *testing.T) {
+	actual := formatHedging(&runner.HedgeStats{
+		Percent:        10,
+		Delay:          50 * time.Millisecond,
+		Calls:          100,
+		Hedged:         8,
+		Wins:           6,
+		WinRate:        0.75,
+		Average:        20 * time.Millisecond,
+		P99:            80 * time.Millisecond,
+		ControlAverage: 25 * time.Millisecond,
+		ControlP99:     200 * time.Millisecond,
+		Improvement:    0.6,
+	})
+
+	assert.Equal(t, "  Policy:            10.00 % of calls after 50.00 ms\n"+
+		"  Calls:             100 selected, 8 hedged\n"+
+		"  Hedge wins:        6 (75.00 %)\n"+
+		"  Hedged latency:    average 20.00 ms, p99 80.00 ms\n"+
+		"  Control latency:   average 25.00 ms, p99 200.00 ms\n"+
+		"  p99 improvement:   60.00 %\n", actual)
+}
+
+func TestPrinter_formatCompression(t *testing.T) {
+	actual := formatCompression(&runner.CompressionStats{
+		Compressor:                "gzip",
+		BytesSent:                 512,
+		UncompressedBytesSent:     2048,
+		BytesReceived:             1050,
+		UncompressedBytesReceived: 1000,
+		SentRatio:                 0.25,
+		ReceivedRatio:             1.05,
+	})
+
+	assert.Equal(t, "  Sent:       512 B of 2.00 KiB (25.00 %)\n"+
+		"  Received:   1.03 KiB of 1000 B (105.00 %)\n", actual)
+}
+
+func TestPrinter_formatDecompression(t *testing.T) {
+	actual := formatDecompression(&runner.DecompressionStats{
+		Compressor:        "gzip",
+		Messages:          200,
+		CompressedBytes:   51200,
+		DecompressedBytes: 204800,
+		SavedBytes:        153600,
+		Ratio:             0.25,
+		Total:             40 * time.Millisecond,
+		Average:           200 * time.Microsecond,
+		Slowest:           2 * time.Millisecond,
+		Throughput:        5120000,
+		TimePerSavedMB:    260 * time.Millisecond,
+	})
+
+	assert.Equal(t, "  Messages:     200\n"+
+		"  Size:         50.00 KiB of 200.00 KiB (25.00 %), 150.00 KiB saved\n"+
+		"  Time:         40.00 ms total, 0.20 ms average, 2.00 ms slowest\n"+
+		"  Throughput:   4.88 MiB/sec\n"+
+		"  Cost:         260.00 ms per MB saved\n", actual)
+}
+
+func TestPrinter_formatUpload(t *testing.T) {
+	actual := formatUpload(&runner.UploadStats{
+		Size:           50 * 1024 * 1024,
+		ChunkSize:      1024 * 1024,
+		Chunks:         50,
+		Uploads:        20,
+		Throughput:     200 * 1024 * 1024,
+		CallThroughput: 25 * 1024 * 1024,
+	})
+
+	assert.Equal(t, "  Payload:      50.00 MiB in 50 chunks of 1.00 MiB\n"+
+		"  Uploads:      20\n"+
+		"  Throughput:   200.00 MiB/sec, 25.00 MiB/sec per call\n", actual)
+}
+
+func TestPrinter_formatAsyncQueue(t *testing.T) {
+	actual := formatAsyncQueue(&runner.AsyncQueueStats{
+		Senders:       10,
+		Handlers:      2,
+		MaxInFlight:   500,
+		AverageQueued: 12.5,
+		MaxQueued:     64,
+		Blocked:       3,
+	})
+
+	assert.Equal(t, "  Senders:            10 per worker\n"+
+		"  Handlers:           2 per worker\n"+
+		"  Max in flight:      500\n"+
+		"  Queued responses:   average 12.50, max 64\n"+
+		"  Blocked sends:      3\n", actual)
+}
+
+func TestPrinter_formatFieldStats(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		actual := formatFieldStats(&runner.ResponseFieldStats{
+			Field: "queue_depth",
+			Count: 200,
+			Values: &runner.FieldValueStats{
+				Min:  0,
+				Mean: 4.25,
+				Max:  40,
+				Distribution: []runner.FieldDistribution{
+					{Percentage: 50, Value: 3},
+					{Percentage: 99, Value: 38},
+				},
+				Correlation: 0.8312,
+			},
+		})
+
+		assert.Equal(t, "  Responses:             200\n"+
+			"  Min:                   0\n"+
+			"  Mean:                  4.25\n"+
+			"  Max:                   40\n"+
+			"                         50 % <= 3\n"+
+			"                         99 % <= 38\n"+
+			"  Latency correlation:   0.83\n", actual)
+	})
+
+	t.Run("categories", func(t *testing.T) {
+		actual := formatFieldStats(&runner.ResponseFieldStats{
+			Field: "source",
+			Count: 200,
+			Categories: []runner.FieldCategory{
+				{Value: "SOURCE_CACHE", Count: 150, Average: 2 * time.Millisecond},
+				{Value: "SOURCE_DATABASE", Count: 50, Average: 20 * time.Millisecond},
+			},
+		})
+
+		assert.Equal(t, "  Responses:        200\n"+
+			"  SOURCE_CACHE      150   75.00 %   avg latency 2.00 ms    \n"+
+			"  SOURCE_DATABASE   50    25.00 %   avg latency 20.00 ms   \n", actual)
+	})
+}
+
+func TestPrinter_formatStageTiming(t *testing.T) {
+	actual := formatStageTiming(&runner.StageTimingStats{
+		Calls:   200,
+		Latency: 2 * time.Second,
+		Stages: []runner.StageStats{
+			{Stage: "handler", Count: 200, Average: 8 * time.Millisecond, Slowest: 20 * time.Millisecond, Share: 0.8},
+			{Stage: "handler;db", Count: 150, Average: 6 * time.Millisecond, Slowest: 15 * time.Millisecond, Share: 0.45},
+		},
+	})
+
+	assert.Equal(t, "  Calls:       200   \n"+
+		"  handler      200   avg 8.00 ms   max 20.00 ms   80.00 %   \n"+
+		"  handler;db   150   avg 6.00 ms   max 15.00 ms   45.00 %   \n", actual)
+}
+
+func TestPrinter_formatPagination(t *testing.T) {
+	actual := formatPagination(&runner.PaginationStats{
+		Fields:       "page_token:next_page_token",
+		Operations:   10,
+		Exhausted:    8,
+		Truncated:    1,
+		Failed:       1,
+		Pages:        35,
+		AveragePages: 3.5,
+		MostPages:    5,
+		Average:      12 * time.Millisecond,
+		Fastest:      3 * time.Millisecond,
+		Slowest:      25 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 11 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Operations:            10\n"+
+		"  Exhausted:             8\n"+
+		"  Truncated:             1\n"+
+		"  Failed:                1\n"+
+		"  Pages:                 35\n"+
+		"  Pages per operation:   average 3.50, max 5\n"+
+		"  Average:               12.00 ms\n"+
+		"  Fastest:               3.00 ms\n"+
+		"  Slowest:               25.00 ms\n"+
+		"                         50 % in 11.00 ms\n", actual)
+}
+
+func TestPrinter_formatIterations(t *testing.T) {
+	actual := formatIterations(&runner.IterationStats{
+		Rate:       10,
+		Calls:      3,
+		Iterations: 100,
+		Rps:        9.95,
+		Completed:  98,
+		Failed:     2,
+		Average:    42 * time.Millisecond,
+		Fastest:    30 * time.Millisecond,
+		Slowest:    80 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 40 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Iterations:            100\n"+
+		"  Rate:                  9.95 per second, target 10\n"+
+		"  Calls per iteration:   3\n"+
+		"  Completed:             98\n"+
+		"  Failed:                2\n"+
+		"  Average:               42.00 ms\n"+
+		"  Fastest:               30.00 ms\n"+
+		"  Slowest:               80.00 ms\n"+
+		"                         50 % in 40.00 ms\n", actual)
+
+	actual = formatWaterfall([]runner.IterationStep{
+		{Step: 1, Method: "create", Count: 100, Offset: time.Millisecond, Gap: time.Millisecond, Average: 9 * time.Millisecond,
+			Slowest: 20 * time.Millisecond, Share: 22.5},
+		{Step: 2, Method: "send", Count: 98, Offset: 10 * time.Millisecond, Average: 30 * time.Millisecond,
+			Slowest: 60 * time.Millisecond, Share: 75},
+	})
+
+	assert.Equal(t, "    1 [create]   start +1.00 ms    gap 1.00 ms   avg 9.00 ms    slowest 20.00 ms   22.50 %   | ∎∎∎∎∎∎∎∎∎\n"+
+		"    2 [send]     start +10.00 ms   gap 0 ns      avg 30.00 ms   slowest 60.00 ms   75.00 %   |          ∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎∎\n", actual)
+}
+
+func TestPrinter_formatTenants(t *testing.T) {
+	actual := formatTenants(&runner.TenantFairness{
+		Tenants: []runner.TenantStats{
+			{
+				Tenant: "acme", Count: 100, Goodput: 50, Average: 10 * time.Millisecond,
+				LatencyDistribution: []runner.LatencyDistribution{
+					{Percentage: 50, Latency: 9 * time.Millisecond},
+					{Percentage: 99, Latency: 20 * time.Millisecond},
+				},
+			},
+			{
+				Tenant: "globex", Count: 100, Errors: 10, ErrorRate: 0.1, Goodput: 45, Average: 30 * time.Millisecond,
+				LatencyDistribution: []runner.LatencyDistribution{
+					{Percentage: 50, Latency: 25 * time.Millisecond},
+					{Percentage: 99, Latency: 80 * time.Millisecond},
+				},
+			},
+		},
+		Fairness:        0.9972,
+		LatencyFairness: 0.8,
+	})
+
+	assert.Equal(t, "  [acme]     100 responses   0 errors (0.00 %)     goodput 50.00   avg 10.00 ms   p50 9.00 ms    p99 20.00 ms   \n"+
+		"  [globex]   100 responses   10 errors (10.00 %)   goodput 45.00   avg 30.00 ms   p50 25.00 ms   p99 80.00 ms   \n"+
+		"  Fairness index: 0.9972, latency 0.8000\n", actual)
+}
+
+func TestPrinter_formatTraces(t *testing.T) {
+	traces := []runner.Trace{
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", Latency: 120 * time.Millisecond, Status: "OK"},
+		{TraceID: "0af7651916cd43dd8448eb211c80319c", Latency: 9500 * time.Microsecond, Status: "Unavailable"},
+	}
+
+	for i := 0; i < 10; i++ {
+		traces = append(traces, runner.Trace{TraceID: "00000000000000000000000000000001", Latency: time.Millisecond, Status: "OK"})
+	}
+
+	actual := formatTraces(traces)
+	lines := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	assert.Len(t, lines, 10)
+	assert.Equal(t, "  120.00 ms   4bf92f3577b34da6a3ce929d0e0e4736   [OK]            ", lines[0])
+	assert.Equal(t, "  9.50 ms     0af7651916cd43dd8448eb211c80319c   [Unavailable]   ", lines[1])
+}
+
+func TestPrinter_formatConnections(t *testing.T) {
+	actual := formatConnections([]runner.ConnectionStats{
+		{ID: 0, Workers: 30, Calls: 120, MaxStreams: 25, BytesSent: 512, BytesReceived: 2048, Connects: 1, Lifetime: 2 * time.Second},
+		{ID: 1, Workers: 20, Calls: 80, MaxStreams: 25, BytesSent: 3 * 1024 * 1024, BytesReceived: 1536, Connects: 2, Lifetime: 1500 * time.Millisecond,
+			RemoteAddr: "[::1]:50051", Family: "ipv6"},
+	})
+
+	assert.Equal(t, "  [0]   30 workers   120 calls   max 25 streams   sent 512 B      received 2.00 KiB   connects 1   lifetime 2.00 s                        \n"+
+		"  [1]   20 workers   80 calls    max 25 streams   sent 3.00 MiB   received 1.50 KiB   connects 2   lifetime 1.50 s   ipv6   [::1]:50051   \n", actual)
+}
+
+func TestPrinter_formatShards(t *testing.T) {
+	actual := formatShards(&runner.ShardStats{Key: "user-id", Counts: []uint64{120, 80, 0}, Unkeyed: 5})
+
+	assert.Equal(t, "  [0]       120 calls   \n"+
+		"  [1]       80 calls    \n"+
+		"  [2]       0 calls     \n"+
+		"  Unkeyed   5 calls     \n", actual)
+}
+
+func TestPrinter_formatApdex(t *testing.T) {
+	actual := formatApdex(&runner.Apdex{
+		Threshold:  100 * time.Millisecond,
+		Score:      0.9166,
+		Satisfied:  900,
+		Tolerating: 80,
+		Frustrated: 20,
+	})
+
+	assert.Equal(t, "  Score:        0.92\n"+
+		"  Threshold:    100.00 ms\n"+
+		"  Satisfied:    900\n"+
+		"  Tolerating:   80\n"+
+		"  Frustrated:   20\n", actual)
+}
+
+func TestPrinter_formatNormalized(t *testing.T) {
+	actual := formatNormalized(&runner.Normalized{
+		CPUTime:          4 * time.Second,
+		CoresUsed:        2,
+		RpsPerCore:       5000,
+		RpsPerCPU:        1250,
+		RpsPerConnection: 10000,
+	})
+
+	assert.Equal(t, "  CPU time:                      4.00 s\n"+
+		"  Cores used:                    2.00\n"+
+		"  Requests/sec per core used:    5000.00\n"+
+		"  Requests/sec per CPU:          1250.00\n"+
+		"  Requests/sec per connection:   10000.00\n", actual)
+
+	actual = formatNormalized(&runner.Normalized{RpsPerCPU: 1250, RpsPerConnection: 10000})
+
+	assert.Equal(t, "  Requests/sec per CPU:          1250.00\n"+
+		"  Requests/sec per connection:   10000.00\n", actual)
+}
+
+func TestPrinter_formatMetrics(t *testing.T) {
+	actual := formatMetrics([]runner.DerivedMetric{
+		{Name: "cost_per_1m", Value: 0.42},
+		{Name: "apdex", Value: 0.9166666666666666},
+		{Name: "calls", Value: 2500000},
+		{Name: "p98_ms", Error: "division by zero"},
+	})
+
+	assert.Equal(t, "  cost_per_1m   0.42                      \n"+
+		"  apdex         0.916667                  \n"+
+		"  calls         2500000                   \n"+
+		"  p98_ms        error: division by zero   \n", actual)
+}
+
+func TestPrinter_formatServer(t *testing.T) {
+	actual := formatServer(&runner.ServerInfo{
+		Services:    []string{"grpc.health.v1.Health", "helloworld.Greeter"},
+		Health:      "SERVING",
+		VersionCall: "build.Info.GetVersion",
+		Version:     []byte(`{"version":"1.4.2"}`),
+	})
+
+	assert.Equal(t, "  Services:   grpc.health.v1.Health, helloworld.Greeter   \n"+
+		"  Health:     SERVING                                     \n"+
+		"  Version:    build.Info.GetVersion                       {\"version\":\"1.4.2\"}   \n", actual)
+
+	assert.Equal(t, "", formatServer(&runner.ServerInfo{}))
+}
+
+func TestPrinter_formatReadiness(t *testing.T) {
+	actual := formatReadiness(&runner.ReadinessStats{
+		Connections: 2,
+		Attempts:    7,
+		Wait:        1500 * time.Millisecond,
+	})
+
+	assert.Equal(t, "  Probe:      health\n"+
+		"  Attempts:   7 over 2 connections\n"+
+		"  Wait:       1.50 s\n", actual)
+
+	actual = formatReadiness(&runner.ReadinessStats{
+		Probe:       "catalog.Catalog.Ping",
+		Connections: 1,
+		Attempts:    1,
+		Wait:        2 * time.Millisecond,
+	})
+
+	assert.Equal(t, "  Probe:      catalog.Catalog.Ping\n"+
+		"  Attempts:   1 over 1 connections\n"+
+		"  Wait:       2.00 ms\n", actual)
+}
+
+func TestPrinter_formatCanary(t *testing.T) {
+	actual := formatCanary(&runner.CanaryStats{
+		Call:       "helloworld.Greeter.SayHello",
+		Checks:     12,
+		Mismatches: 2,
+		Errors:     1,
+		FirstDivergence: &runner.CanaryDivergence{
+			Check:    7,
+			Elapsed:  35*time.Second + 12*time.Microsecond,
+			Response: []byte(`{"message":"Hello Eve"}`),
+			Expected: []byte(`{"message":"Hello Bob"}`),
+		},
+	})
+
+	assert.Equal(t, "  Checks:             12                        \n"+
+		"  Mismatches:         2                         \n"+
+		"  Errors:             1                         \n"+
+		"  First divergence:   check 7 after 35s         \n"+
+		"    Response:         {\"message\":\"Hello Eve\"}   \n"+
+		"    Expected:         {\"message\":\"Hello Bob\"}   \n", actual)
+}
+
+func TestPrinter_formatAdjustments(t *testing.T) {
+	actual := formatAdjustments([]runner.Adjustment{
+		{Elapsed: 5 * time.Second, Setting: "rps", Value: 200},
+		{Elapsed: 12 * time.Second, Setting: "concurrency", Value: 80},
+		{Elapsed: 30 * time.Second, Setting: "rps", Value: 0},
+		{Elapsed: 40 * time.Second, Setting: "paused", Value: 1},
+		{Elapsed: 45 * time.Second, Setting: "paused", Value: 0},
+	})
+
+	assert.Equal(t, "  [5.00 s]    rps           200         \n"+
+		"  [12.00 s]   concurrency   80          \n"+
+		"  [30.00 s]   rps           unlimited   \n"+
+		"  [40.00 s]   paused        true        \n"+
+		"  [45.00 s]   paused        false       \n", actual)
+}
+
+func TestPrinter_formatSchemaChanges(t *testing.T) {
+	actual := formatSchemaChanges([]runner.SchemaChange{
+		{Elapsed: 90 * time.Second, Changes: []string{
+			"added field helloworld.HelloRequest.age",
+			"removed field helloworld.HelloReply.note",
+		}},
+	})
+
+	assert.Equal(t, "  [90.00 s]   added field helloworld.HelloRequest.age    \n"+
+		"  [90.00 s]   removed field helloworld.HelloReply.note   \n", actual)
+}
+
+func TestPrinter_formatBandwidth(t *testing.T) {
+	actual := formatBandwidth(&runner.Bandwidth{
+		BytesSent:           2 * 1024 * 1024,
+		BytesReceived:       512,
+		SendThroughput:      1024 * 1024,
+		ReceiveThroughput:   256,
+		AverageRequestSize:  2048,
+		AverageResponseSize: 12.5,
+	})
+
+	assert.Equal(t, "  Sent:       2.00 MiB (1.00 MiB/sec), avg 2.00 KiB per message\n"+
+		"  Received:   512 B (256 B/sec), avg 12 B per message\n", actual)
+}
+
+func TestPrinter_formatStreamMessages(t *testing.T) {
+	actual := formatStreamMessages(&runner.StreamMessages{
+		Streams:           10,
+		Sent:              100,
+		Received:          95,
+		SentPerStream:     10,
+		ReceivedPerStream: 9.5,
+		MaxSent:           10,
+		MaxReceived:       12,
+		SendRate:          50,
+		ReceiveRate:       47.5,
+	})
+
+	assert.Equal(t, "  Streams:    10\n"+
+		"  Sent:       100 messages, avg 10.00 per stream, max 10 (50.00/sec)\n"+
+		"  Received:   95 messages, avg 9.50 per stream, max 12 (47.50/sec)\n", actual)
+}
+
+func TestPrinter_formatBackpressure(t *testing.T) {
+	actual := formatBackpressure(&runner.BackpressureStats{
+		Delay:       10 * time.Millisecond,
+		Streams:     2,
+		Messages:    100,
+		MessageRate: 50,
+		Stalled:     3,
+		Average:     2 * time.Millisecond,
+		Fastest:     time.Millisecond,
+		Slowest:     5 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 2 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, "  Receive delay:   10.00 ms\n"+
+		"  Streams:         2\n"+
+		"  Messages:        100 (50.00/sec)\n"+
+		"  Stalled:         3\n"+
+		"  Message wait:    avg 2.00 ms, fastest 1.00 ms, slowest 5.00 ms\n"+
+		"                   50 % in 2.00 ms\n", actual)
+}
+
+func TestPrinter_formatRecommendations(t *testing.T) {
+	actual := formatRecommendations([]runner.Recommendation{
+		{Reason: "reconnects", Option: "connections", Message: "increase --connections"},
+		{Reason: "client-cpu", Option: "concurrency", Message: "reduce --concurrency"},
+	})
+
+	assert.Equal(t, "  [reconnects]   increase --connections   \n"+
+		"  [client-cpu]   reduce --concurrency     \n", actual)
+}
+
+func TestPrinter_formatEstimate(t *testing.T) {
+	t.Run("paced", func(t *testing.T) {
+		actual := formatEstimate(&runner.Estimate{
+			Call:            "helloworld.Greeter.SayHello",
+			Host:            "localhost:50051",
+			Concurrency:     50,
+			Connections:     1,
+			Paced:           true,
+			Requests:        1000000,
+			Duration:        2000 * time.Second,
+			PeakRate:        500,
+			RequestSize:     2048,
+			MessagesPerCall: 1,
+			PeakBandwidth:   1024000,
+		})
+
+		assert.Equal(t, "  Call:             helloworld.Greeter.SayHello\n"+
+			"  Host:             localhost:50051\n"+
+			"  Concurrency:      50 workers, 1 connections\n"+
+			"  Requests:         1000000\n"+
+			"  Duration:         33m20s\n"+
+			"  Peak rate:        500.00 requests/sec\n"+
+			"  Request size:     2.00 KiB\n"+
+			"  Peak bandwidth:   1000.00 KiB/sec\n", actual)
+	})
+
+	t.Run("unpaced", func(t *testing.T) {
+		actual := formatEstimate(&runner.Estimate{
+			Call:        "helloworld.Greeter.SayHello",
+			Host:        "localhost:50051",
+			Concurrency: 50,
+			Connections: 1,
+			Requests:    200,
+		})
+
+		assert.Contains(t, actual, "  Duration:       depends on the latency, the requests are not rate-paced\n")
+		assert.Contains(t, actual, "  Request size:   unknown, use a proto file or a protoset\n")
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		actual := formatEstimate(&runner.Estimate{
+			Call:        "helloworld.Greeter.SayHello",
+			Host:        "localhost:50051",
+			Concurrency: 50,
+			Connections: 1,
+			Paced:       true,
+			Unlimited:   true,
+			PeakRate:    100,
+		})
+
+		assert.Contains(t, actual, "  Requests:       unlimited, the run goes on until interrupted\n")
+		assert.Contains(t, actual, "  Duration:       unlimited, the run goes on until interrupted\n")
+		assert.Contains(t, actual, "  Peak rate:      100.00 requests/sec\n")
+	})
+}
+
+func TestPrinter_formatInterference(t *testing.T) {
+	actual := formatInterference([]runner.Interference{
+		{Name: "hello", Rps: 150, RpsChange: -0.25, Average: 15 * time.Millisecond, AverageChange: 0.5,
+			P99: 40 * time.Millisecond, P99Change: 1, ErrorRate: 5},
+	})
+
+	assert.Equal(t, "  Run     Requests/sec       Average              p99                   Errors                \n"+
+		"  hello   150.00 (-25.0 %)   15.00 ms (+50.0 %)   40.00 ms (+100.0 %)   5.00 % (was 0.00 %)   \n", actual)
+}
+
+func TestPrinter_latencyTimeline(t *testing.T) {
+	start := time.Date(2020, 3, 7, 14, 5, 9, 0, time.UTC)
+
+	var details []runner.ResultDetail
+	for i := 0; i < 200; i++ {
+		details = append(details, runner.ResultDetail{
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Millisecond),
+			Latency:   time.Duration(i%10+1) * time.Millisecond,
+			Status:    "OK",
+		})
+	}
+
+	details = append(details, runner.ResultDetail{
+		Timestamp: start.Add(time.Hour),
+		Latency:   time.Second,
+		Error:     "rpc error: code = Unavailable",
+		Status:    "Unavailable",
+	})
+
+	points := latencyTimeline(runner.Report{Details: details})
+
+	// 1990ms are bucketed into buckets of 20ms, the failed call is not included
+	assert.Len(t, points, 100)
+	assert.Equal(t, timelinePoint{Date: start, Count: 2, Average: 1.5, P99: 2}, points[0])
+	assert.Equal(t, start.Add(1980*time.Millisecond), points[99].Date)
+
+	// the compact details give the same points
+	compact := latencyTimeline(runner.Report{DetailColumns: runner.CompactDetails(details)})
+	if assert.Len(t, compact, 100) {
+		assert.True(t, start.Equal(compact[0].Date))
+		assert.Equal(t, points[0].Count, compact[0].Count)
+		assert.Equal(t, points[99].Average, compact[99].Average)
+	}
+
+	assert.Nil(t, latencyTimeline(runner.Report{}))
+}
+
+func TestPrinter_printHTML(t *testing.T) {
+	report := runner.Report{
+		Count:          2,
+		StatusCodeDist: map[string]int{"OK": 2},
+		Details: []runner.ResultDetail{
+			{Timestamp: time.Now(), Latency: time.Millisecond, Status: "OK"},
+			{Timestamp: time.Now(), Latency: 2 * time.Millisecond, Status: "OK"},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	p := ReportPrinter{Report: &report, Out: buf}
+	assert.NoError(t, p.Print("html"))
+
+	out := buf.String()
+	assert.Contains(t, out, `<div class="js-line-container"></div>`)
+	assert.Contains(t, out, `<div class="js-donut-container"></div>`)
+	assert.Contains(t, out, `{ name: "OK", quantity: 2, percentage: 100.00 },`)
+	assert.Contains(t, out, `const timeline = [{"date":`)
+}
+
+func TestPrinter_formatTimeSeries(t *testing.T) {
+	actual := formatTimeSeries([]runner.TimeWindow{
+		{Count: 100, Rps: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond},
+		{Start: time.Second, Count: 90, Errors: 9, ErrorRate: 0.1, Rps: 90, P50: 60 * time.Millisecond, P95: 120 * time.Millisecond, P99: 150 * time.Millisecond},
+	})
+
+	assert.Equal(t, "  [0s]   100 responses   100.00 rps   0.00 % errors    p50 50.00 ms   p95 95.00 ms    p99 99.00 ms    \n"+
+		"  [1s]   90 responses    90.00 rps    10.00 % errors   p50 60.00 ms   p95 120.00 ms   p99 150.00 ms   \n", actual)
+}
+
+func TestPrinter_formatTimeSlices(t *testing.T) {
+	actual := formatTimeSlices([]runner.TimeSlice{
+		{
+			End:     5 * time.Minute,
+			Count:   1000,
+			Errors:  10,
+			Rps:     3.33,
+			Average: 2 * time.Millisecond,
+			Fastest: time.Millisecond,
+			Slowest: 9 * time.Millisecond,
+			LatencyDistribution: []runner.LatencyDistribution{
+				{Percentage: 50, Latency: 2 * time.Millisecond},
+				{Percentage: 99, Latency: 8 * time.Millisecond},
+			},
+			ErrorRate:      0.01,
+			StatusCodeDist: map[string]int{"Unavailable": 10, "OK": 990},
+			ErrorDist:      map[string]int{"rpc error: code = Unavailable desc = overloaded": 10},
+		},
+		{Start: 5 * time.Minute, End: 7 * time.Minute, StatusCodeDist: map[string]int{}},
+	})
+
+	assert.Equal(t, "  [0s - 5m0s]     1000 responses, 3.33 rps, 1.00 % errors\n"+
+		"                  avg 2.00 ms, fastest 1.00 ms, slowest 9.00 ms\n"+
+		"                  p50 2.00 ms, p99 8.00 ms\n"+
+		"                  [OK] 990, [Unavailable] 10\n"+
+		"                  [10] rpc error: code = Unavailable desc = overloaded\n"+
+		"  [5m0s - 7m0s]   0 responses, 0.00 rps, 0.00 % errors\n", actual)
+}
+
+func TestPrinter_printTemplate(t *testing.T) {
+	report := runner.Report{
+		Name:    "test",
+		Count:   200,
+		Average: 5 * time.Millisecond,
+		Rps:     1000,
+		StatusCodeDist: map[string]int{
+			"OK":          190,
+			"Unavailable": 10,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	p := ReportPrinter{Report: &report, Out: buf, Template: "{{ .Name }}: {{ .Count }} requests, avg {{ formatNanoUnit .Average }}\n{{ formatStatusCode .StatusCodeDist }}"}
+	assert.NoError(t, p.Print("summary"))
+	assert.Equal(t, "test: 200 requests, avg 5.00 ms\n"+
+		"  [OK]            190 responses   \n"+
+		"  [Unavailable]   10 responses    \n", buf.String())
+
+	// the template is only used for the summary format
+	buf.Reset()
+	assert.NoError(t, p.Print("csv"))
+	assert.NotContains(t, buf.String(), "requests, avg")
+
+	p.Template = "{{ .Count "
+	assert.Error(t, p.Print("summary"))
+	assert.Error(t, CheckTemplate(p.Template))
+	assert.NoError(t, CheckTemplate("{{ histogram .Histogram }}"))
+}
+
+func TestPrinter_formatSchemaDrift(t *testing.T) {
+	actual := formatSchemaDrift(&runner.SchemaDrift{
+		Responses: 200,
+		Drifted:   150,
+		Fields: []runner.DriftField{
+			{Field: "9", Message: "fields.QueryReply", Count: 150},
+			{Field: "stats.3", Message: "fields.QueryStats", Count: 20},
+		},
+	})
+
+	assert.Equal(t, "  Responses:   200\n"+
+		"  Drifted:     150\n"+
+		"  [9]          fields.QueryReply   150 responses   \n"+
+		"  [stats.3]    fields.QueryStats   20 responses    \n", actual)
+}
+
+func TestPrinter_formatStatusBreakdown(t *testing.T) {
+	actual := formatStatusBreakdown([]runner.StatusBreakdown{
+		{
+			Code:     "Unavailable",
+			Count:    10,
+			Messages: []runner.ErrorMessage{{Message: "overloaded", Count: 8}, {Message: "connection refused", Count: 2}},
+			Samples: []runner.ErrorSample{{
+				Message: "overloaded",
+				Details: []string{`google.protobuf.StringValue: value:"retry later"`},
+				Trailer: map[string][]string{"retry-after": {"1"}},
+			}},
+		},
+	})
+
+	assert.Equal(t, "  [Unavailable]   10 responses                                       \n"+
+		"    [8]           overloaded                                         \n"+
+		"    [2]           connection refused                                 \n"+
+		"    sample        overloaded                                         \n"+
+		"      details     google.protobuf.StringValue: value:\"retry later\"   \n"+
+		"      trailer     retry-after: 1                                     \n", actual)
+}
+
+func TestPrinter_formatShards_connections(t *testing.T) {
+	actual := formatShards(&runner.ShardStats{
+		Key: "tenant-id",
+		Connections: &runner.ShardConnStats{
+			Max: 100, Calls: 5000, Opened: 400, Recycled: 300, Reopened: 250, Peak: 102, Overflows: 2,
+			RecycledCalls: 12.5, RecycledLifetime: 1500 * time.Millisecond,
+		},
+		Unkeyed: 5,
+	})
+
+	assert.Equal(t, "  Dedicated   5000 calls   max 100 connections   peak 102          \n"+
+		"  Opened      400          reopened 250          overflows 2       \n"+
+		"  Recycled    300          12.50 calls each      lifetime 1.50 s   \n"+
+		"  Unkeyed     5 calls      \n", actual)
+}
+
+func TestPrinter_formatChurn(t *testing.T) {
+	actual := formatChurn(&runner.ChurnStats{
+		Interval: 30 * time.Second,
+		Redials:  80,
+		Failed:   2,
+		Lifetime: 30 * time.Second,
+		Calls:    2990.5,
+	})
+
+	assert.Equal(t, "  Interval:    30.00 s\n"+
+		"  Re-dialed:   80 connections\n"+
+		"  Failed:      2 re-dials\n"+
+		"  Lifetime:    30.00 s\n"+
+		"  Calls:       2990.50 per connection\n", actual)
+
+	actual = formatChurn(&runner.ChurnStats{Requests: 1000})
+
+	assert.Equal(t, "  Requests:    1000 calls\n"+
+		"  Re-dialed:   0 connections\n", actual)
+}
+
+func TestPrinter_formatComparison(t *testing.T) {
+	tolerance := func(v float64) *float64 {
+		return &v
+	}
+
+	actual := formatComparison(&runner.Comparison{
+		Metrics: []runner.MetricComparison{
+			{Metric: runner.MetricAverage, Baseline: 10, Current: 11, Change: 0.1},
+			{Metric: runner.MetricP99, Baseline: 40, Current: 50, Change: 0.25, Tolerance: tolerance(0.1), Regressed: true},
+			{Metric: runner.MetricErrorRate, Baseline: 0.1, Current: 0.5, Change: 0.4, Tolerance: tolerance(0.5)},
+			{Metric: runner.MetricRPS, Baseline: 2000, Current: 1980, Change: -0.01},
+		},
+	})
+
+	assert.Equal(t, "  Metric       Baseline   Current    Change      Tolerance   \n"+
+		"  average      10.00 ms   11.00 ms   +10.0 %     -           \n"+
+		"  p99          40.00 ms   50.00 ms   +25.0 %     10.0 %      [regressed]\n"+
+		"  error-rate   0.10 %     0.50 %     +0.40 pts   0.50 pts    [pass]\n"+
+		"  rps          2000.00    1980.00    -1.0 %      -           \n", actual)
+}
+
+func TestPrinter_formatScaling(t *testing.T) {
+	actual := formatScaling(&runner.ScalingStats{
+		Max:         4,
+		Ratio:       2,
+		Connections: 3,
+		Failed:      1,
+		Events: []runner.ScalingEvent{
+			{
+				Time: 5 * time.Second, Connections: 2,
+				Queue: 50 * time.Millisecond, Server: 10 * time.Millisecond,
+				QueueAfter: 20 * time.Millisecond, ServerAfter: 10 * time.Millisecond,
+			},
+		},
+	})
+
+	assert.Equal(t, "  Maximum:       4 connections   \n"+
+		"  Ratio:         2.00            \n"+
+		"  Connections:   3               \n"+
+		"  Failed:        1 connections   \n"+
+		"  At 5.00 s:     2 connections   queue 50.00 ms, server 10.00 ms   after queue 20.00 ms, server 10.00 ms   \n", actual)
+}
+
+func TestPrinter_formatConnect(t *testing.T) {
+	actual := formatConnect(&runner.ConnectStats{
+		Policy:    "proceed",
+		Requested: 10,
+		Connected: 8,
+		Retries:   6,
+		Failed:    2,
+	})
+
+	assert.Equal(t, "  Policy:      proceed\n"+
+		"  Connected:   8 of 10\n"+
+		"  Retries:     6\n"+
+		"  Failed:      2 connections\n", actual)
+
+	actual = formatConnect(&runner.ConnectStats{Policy: "fail", Requested: 2, Connected: 2})
+
+	assert.Equal(t, "  Policy:      fail\n"+
+		"  Connected:   2 of 2\n"+
+		"  Retries:     0\n", actual)
+}
+
+func TestPrinter_formatBaseline(t *testing.T) {
+	actual := formatBaseline(&runner.Baseline{
+		Count:           1000,
+		Average:         80 * time.Microsecond,
+		AdjustedAverage: 120 * time.Microsecond,
+		AdjustedDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 110 * time.Microsecond},
+			{Percentage: 99, Latency: 400 * time.Microsecond},
+		},
+	})
+
+	assert.Equal(t, "  Requests:   1000\n"+
+		"  Overhead:   0.08 ms\n"+
+		"  Adjusted:   0.12 ms\n"+
+		"              50 % in 0.11 ms\n"+
+		"              99 % in 0.40 ms\n", actual)
+}
+
+func TestPrinter_formatABComparison(t *testing.T) {
+	actual := formatABComparison(&runner.ABReport{
+		A:             runner.ABResult{Name: "blue", Phases: 2, Count: 400, Rps: 200, Average: 10 * time.Millisecond, P99: 20 * time.Millisecond},
+		B:             runner.ABResult{Name: "green", Phases: 2, Count: 300, Rps: 150, Average: 15 * time.Millisecond, P99: 40 * time.Millisecond, ErrorRate: 1.5},
+		RpsChange:     -0.25,
+		AverageChange: 0.5,
+		P99Change:     1,
+	})
+
+	assert.Equal(t, "  Target    Phases   Count   Requests/sec       Average              p99                   Errors   \n"+
+		"  A blue    2        400     200.00             10.00 ms             20.00 ms              0.00 %   \n"+
+		"  B green   2        300     150.00 (-25.0 %)   15.00 ms (+50.0 %)   40.00 ms (+100.0 %)   1.50 %   \n", actual)
+}
+
+func TestPrinter_printCompactDetails(t *testing.T) {
+	date := time.Date(2021, 3, 7, 14, 43, 12, 0, time.UTC)
+	details := []runner.ResultDetail{
+		{Timestamp: date, Latency: 5 * time.Millisecond, Status: "OK"},
+		{Timestamp: date.Add(time.Second), Latency: 7 * time.Millisecond, Status: "Unavailable", Error: "unavailable"},
+	}
+
+	for _, format := range []string{"csv", "json", "influx-details", "html"} {
+		t.Run(format, func(t *testing.T) {
+			report := &runner.Report{Name: "test", Date: date, Details: details}
+
+			expected := &bytes.Buffer{}
+			assert.NoError(t, (&ReportPrinter{Out: expected, Report: report}).Print(format))
+
+			compact := *report
+			compact.Details = nil
+			compact.DetailColumns = runner.CompactDetails(details)
+
+			actual := &bytes.Buffer{}
+			assert.NoError(t, (&ReportPrinter{Out: actual, Report: &compact}).Print(format))
+
+			// the compact details come first in JSON
+			if format == "json" {
+				assert.JSONEq(t, expected.String(), actual.String())
+			} else {
+				assert.Equal(t, expected.String(), actual.String())
+			}
+		})
+	}
+}
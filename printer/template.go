@@ -4,22 +4,130 @@ var (
 	defaultTmpl = `
 Summary:
 {{ if .Name }}  Name:		{{ .Name }}
+{{ end }}{{ if .RunID }}  Run ID:	{{ .RunID }}
+{{ end }}{{ if .Rotation }}  Rotation:	{{ .Rotation }}
 {{ end }}  Count:	{{ .Count }}
   Total:	{{ formatNanoUnit .Total }}
   Slowest:	{{ formatNanoUnit .Slowest }}
   Fastest:	{{ formatNanoUnit .Fastest }}
   Average:	{{ formatNanoUnit .Average }}
   Requests/sec:	{{ formatSeconds .Rps }}
-
+{{ if .StopError }}  Stopped on:	{{ .StopError }}
+{{ end }}
 Response time histogram:
 {{ histogram .Histogram }}
 Latency distribution:{{ range .LatencyDistribution }}
+  {{ .Percentage }} % in {{ formatNanoUnit .Latency }} {{ end }}{{ range .TailLatency }}
+  {{ .Percentile }} % in {{ formatNanoUnit .Latency }} {{ end }}
+
+{{ if .Apdex }}Apdex:
+{{ formatApdex .Apdex }}
+{{ end }}{{ if .Normalized }}Normalized throughput:
+{{ formatNormalized .Normalized }}
+{{ end }}{{ if .Corrected }}Corrected latency distribution:
+  Expected interval {{ formatNanoUnit .Corrected.Interval }}, {{ .Corrected.Count }} latencies, average {{ formatNanoUnit .Corrected.Average }}{{ range .Corrected.LatencyDistribution }}
   {{ .Percentage }} % in {{ formatNanoUnit .Latency }} {{ end }}
 
-{{ if gt (len .StatusCodeDist) 0 }}Status code distribution:
+{{ end }}{{ if .SchedulerLag }}Scheduler lag:
+{{ formatSchedulerLag .SchedulerLag }}
+{{ end }}{{ if .HeaderLatency }}Time to response headers:
+{{ formatHeaderLatency .HeaderLatency }}
+{{ end }}{{ if .Baseline }}Latency less client overhead:
+{{ formatBaseline .Baseline }}
+{{ end }}{{ if gt (len .Phases) 0 }}Schedule phases:
+{{ formatPhases .Phases }}
+{{ end }}{{ if .Pacing }}Worker pacing:
+{{ formatPacing .Pacing }}
+{{ end }}{{ if .AsyncQueue }}Async queues:
+{{ formatAsyncQueue .AsyncQueue }}
+{{ end }}{{ if .DeadlineBudget }}Deadline budget:
+{{ formatDeadline .DeadlineBudget }}
+{{ end }}{{ if gt (len .Traces) 0 }}Slowest sampled traces:
+{{ formatTraces .Traces }}
+{{ end }}{{ if gt (len .LabelLatency) 0 }}Latency by label:
+{{ formatLabelLatency .LabelLatency }}
+{{ end }}{{ if gt (len .WorkerGroups) 0 }}Latency by worker group:
+{{ formatWorkerGroups .WorkerGroups }}
+{{ end }}{{ if gt (len .Methods) 0 }}Methods:
+{{ formatMethods .Methods }}
+{{ end }}{{ if gt (len .TimeSeries) 0 }}Latency over time:
+{{ formatTimeSeries .TimeSeries }}
+{{ end }}{{ if gt (len .TimeSlices) 0 }}Time slices:
+{{ formatTimeSlices .TimeSlices }}
+{{ end }}{{ if .StreamMessages }}Streams:
+{{ formatStreamMessages .StreamMessages }}
+{{ end }}{{ if .Bandwidth }}Bandwidth:
+{{ formatBandwidth .Bandwidth }}
+{{ end }}{{ if .Stream }}Stream messages:
+{{ formatStream .Stream }}
+{{ end }}{{ if .StreamAcks }}Stream acks:
+{{ formatStreamAcks .StreamAcks }}
+{{ end }}{{ if .Backpressure }}Backpressure:
+{{ formatBackpressure .Backpressure }}
+{{ end }}{{ if .ResponseField }}Response field {{ .ResponseField.Field }}:
+{{ formatFieldStats .ResponseField }}
+{{ end }}{{ if .SchemaDrift }}Schema drift:
+{{ formatSchemaDrift .SchemaDrift }}
+{{ end }}{{ if .StageTiming }}Server stages:
+{{ formatStageTiming .StageTiming }}
+{{ end }}{{ if .Pagination }}Pagination by {{ .Pagination.Fields }}:
+{{ formatPagination .Pagination }}
+{{ end }}{{ if .Iterations }}Scenario iterations:
+{{ formatIterations .Iterations }}
+{{ end }}{{ if .Tenants }}Tenants:
+{{ formatTenants .Tenants }}
+{{ end }}{{ if or (gt (len .Connections) 1) (and .Connections .Options.IPVersion) }}Connections:
+{{ formatConnections .Connections }}
+{{ end }}{{ if .Shards }}Shards by {{ .Shards.Key }}:
+{{ formatShards .Shards }}
+{{ end }}{{ if .Churn }}Connection churn:
+{{ formatChurn .Churn }}
+{{ end }}{{ if .Scaling }}Connection scaling:
+{{ formatScaling .Scaling }}
+{{ end }}{{ if .Connect }}Connection setup:
+{{ formatConnect .Connect }}
+{{ end }}{{ if .Server }}Server:
+{{ formatServer .Server }}
+{{ end }}{{ if .Readiness }}Readiness:
+{{ formatReadiness .Readiness }}
+{{ end }}{{ if .Canary }}Canary {{ .Canary.Call }}:
+{{ formatCanary .Canary }}
+{{ end }}{{ if .GraceRetries }}Grace period retries:
+{{ formatGraceRetries .GraceRetries }}
+{{ end }}{{ if .Drain }}Drain:
+{{ formatDrain .Drain }}
+{{ end }}{{ if .Hedging }}Hedging:
+{{ formatHedging .Hedging }}
+{{ end }}{{ if .Compression }}Compression ({{ .Compression.Compressor }}):
+{{ formatCompression .Compression }}
+{{ end }}{{ if .Decompression }}Decompression ({{ .Decompression.Compressor }}):
+{{ formatDecompression .Decompression }}
+{{ end }}{{ if .Upload }}Upload:
+{{ formatUpload .Upload }}
+{{ end }}{{ if gt (len .StatusCodeDist) 0 }}Status code distribution:
 {{ formatStatusCode .StatusCodeDist }}{{ end }}
-{{ if gt (len .ErrorDist) 0 }}Error distribution:
-{{ formatErrorDist .ErrorDist }}{{ end }}
+{{ if gt (len .Thresholds) 0 }}Thresholds:
+{{ formatThresholds .Thresholds }}
+{{ end }}{{ if .Comparison }}Comparison with the baseline{{ if .Comparison.Name }} {{ .Comparison.Name }}{{ end }}:
+{{ formatComparison .Comparison }}
+{{ end }}{{ if gt (len .Assertions) 0 }}Assertions:
+{{ formatAssertions .Assertions }}
+{{ end }}{{ if gt (len .Backoff) 0 }}Load backoff:
+{{ formatBackoff .Backoff }}
+{{ end }}{{ if gt (len .LatencyControl) 0 }}Latency control:
+{{ formatLatencyCtl .LatencyControl }}
+{{ end }}{{ if gt (len .Adjustments) 0 }}Runtime adjustments:
+{{ formatAdjustments .Adjustments }}
+{{ end }}{{ if gt (len .SchemaChanges) 0 }}Schema changes:
+{{ formatSchemaChanges .SchemaChanges }}
+{{ end }}{{ if gt (len .ErrorDist) 0 }}Error distribution:
+{{ formatErrorDist .ErrorDist }}{{ end }}{{ if gt (len .StatusBreakdown) 0 }}
+Error breakdown:
+{{ formatStatusBreakdown .StatusBreakdown }}{{ end }}{{ if gt (len .Metrics) 0 }}
+Metrics:
+{{ formatMetrics .Metrics }}{{ end }}{{ if gt (len .Recommendations) 0 }}
+Recommendations:
+{{ formatRecommendations .Recommendations }}{{ end }}
 `
 
 	csvTmpl = `
@@ -54,6 +162,9 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 			{{ if .Date }}
 				<h2 class="subtitle">{{ formatDate .Date }}</h2>
 			{{ end }}
+			{{ if .RunID }}
+				<p class="is-family-monospace">{{ .RunID }}</p>
+			{{ end }}
 		</div>
 		<br />
 
@@ -84,6 +195,14 @@ duration (ms),status,error{{ range $i, $v := .Details }}
               <span>Latency Distribution</span>
             </a>
           </li>
+          <li>
+            <a href="#timeline">
+              <span class="icon is-small">
+                <i class="fas fa-chart-line" aria-hidden="true"></i>
+              </span>
+              <span>Latency over time</span>
+            </a>
+          </li>
           <li>
             <a href="#status">
               <span class="icon is-small">
@@ -177,6 +296,18 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 									<th>Requests / sec</th>
 									<td>{{ formatSeconds .Rps }}</td>
 								</tr>
+								{{ if .StopError }}
+								<tr>
+									<th>Stopped on</th>
+									<td>{{ .StopError }}</td>
+								</tr>
+								{{ end }}
+								{{ if .Apdex }}
+								<tr>
+									<th>Apdex [T = {{ formatNanoUnit .Apdex.Threshold }}]</th>
+									<td>{{ printf "%.2f" .Apdex.Score }}</td>
+								</tr>
+								{{ end }}
 							</tbody>
 						</table>
 					</div>
@@ -222,6 +353,51 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 						</tr>
 					</tbody>
 				</table>
+				{{ if gt (len .LabelLatency) 0 }}
+				<a name="labels">
+					<h3>Latency by label</h3>
+				</a>
+				<table class="table is-fullwidth is-hoverable">
+					<thead>
+						<tr>
+							<th>Label</th>
+							<th>Count</th>
+							<th>Average</th>
+							<th>Fastest</th>
+							<th>Slowest</th>
+							{{ range (index .LabelLatency 0).LatencyDistribution }}
+								<th>{{ .Percentage }} %</th>
+							{{ end }}
+						</tr>
+					</thead>
+					<tbody>
+						{{ range .LabelLatency }}
+						<tr>
+							<td>{{ .Label }}</td>
+							<td>{{ .Count }}</td>
+							<td>{{ formatNanoUnit .Average }}</td>
+							<td>{{ formatNanoUnit .Fastest }}</td>
+							<td>{{ formatNanoUnit .Slowest }}</td>
+							{{ range .LatencyDistribution }}
+								<td>{{ formatNanoUnit .Latency }}</td>
+							{{ end }}
+						</tr>
+						{{ end }}
+					</tbody>
+				</table>
+				{{ end }}
+			</div>
+		</div>
+
+		<br />
+		<div class="container">
+			<div class="content">
+				<a name="timeline">
+					<h3>Latency over time</h3>
+				</a>
+				<p>
+					<div class="js-line-container"></div>
+				</p>
 			</div>
 		</div>
 
@@ -253,9 +429,80 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 							</table>
 						</div>
 					</div>
+					<div class="column">
+						<div class="js-donut-container"></div>
+					</div>
 				</div>
 			</div>
 
+			{{ if gt (len .Thresholds) 0 }}
+
+				<br />
+				<div class="container">
+					<div class="columns">
+						<div class="column is-narrow">
+							<div class="content">
+								<a name="thresholds">
+									<h3>Thresholds</h3>
+								</a>
+								<table class="table is-hoverable">
+									<thead>
+										<tr>
+											<th>Threshold</th>
+											<th>Actual</th>
+											<th>Result</th>
+										</tr>
+									</thead>
+									<tbody>
+										{{ range .Thresholds }}
+											<tr>
+												<td>{{ .Threshold }}</td>
+												<td>{{ .Actual }}</td>
+												<td>{{ if .Pass }}<span class="tag is-success">pass</span>{{ else }}<span class="tag is-danger">fail</span>{{ end }}</td>
+											</tr>
+											{{ end }}
+										</tbody>
+									</table>
+								</div>
+							</div>
+						</div>
+					</div>
+
+			{{ end }}
+
+			{{ if gt (len .Metrics) 0 }}
+
+				<br />
+				<div class="container">
+					<div class="columns">
+						<div class="column is-narrow">
+							<div class="content">
+								<a name="metrics">
+									<h3>Metrics</h3>
+								</a>
+								<table class="table is-hoverable">
+									<thead>
+										<tr>
+											<th>Metric</th>
+											<th>Value</th>
+										</tr>
+									</thead>
+									<tbody>
+										{{ range .Metrics }}
+											<tr>
+												<td>{{ .Name }}</td>
+												<td>{{ if .Error }}<span class="tag is-danger">{{ .Error }}</span>{{ else }}{{ formatMetricValue .Value }}{{ end }}</td>
+											</tr>
+											{{ end }}
+										</tbody>
+									</table>
+								</div>
+							</div>
+						</div>
+					</div>
+
+			{{ end }}
+
 			{{ if gt (len .ErrorDist) 0 }}
 
 				<br />
@@ -340,7 +587,7 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 
 	const count = {{ .Count }};
 
-	const rawData = {{ jsonify .Details false }};
+	const rawData = {{ jsonify (reportDetails .) false }};
 
 	const data = [
 		{{ range .Histogram }}
@@ -348,6 +595,14 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 		{{ end }}
 	];
 
+	const timeline = {{ jsonify (latencyTimeline .) false }} || [];
+
+	const statusData = [
+		{{ range $code, $num := .StatusCodeDist }}
+			{ name: {{ printf "%q" $code }}, quantity: {{ $num }}, percentage: {{ formatPercent $num $.Count }} },
+		{{ end }}
+	];
+
 	function createHorizontalBarChart() {
 		let barChart = britecharts.bar(),
 			tooltip = britecharts.miniTooltip(),
@@ -402,6 +657,69 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 		}
 	}
 
+	function createLatencyLineChart() {
+		let lineChart = britecharts.line(),
+			tooltip = britecharts.tooltip(),
+			lineContainer = d3.select('.js-line-container'),
+			containerWidth = lineContainer.node() ? lineContainer.node().getBoundingClientRect().width : false,
+			tooltipContainer,
+			dataset;
+
+		if (containerWidth && timeline.length > 0) {
+			dataset = {
+				dataByTopic: [
+					{ topicName: 'Average (ms)', topic: 1, dates: timeline.map(function(p) { return { date: p.date, value: p.average }; }) },
+					{ topicName: '99 % (ms)', topic: 2, dates: timeline.map(function(p) { return { date: p.date, value: p.p99 }; }) }
+				]
+			};
+
+			lineChart
+				.isAnimated(true)
+				.grid('horizontal')
+				.margin({
+					left: 60,
+					right: 20,
+					top: 20,
+					bottom: 40
+				})
+				.colorSchema(britecharts.colors.colorSchemas.teal)
+				.xAxisFormat('custom')
+				.xAxisCustomFormat('%H:%M:%S')
+				.width(containerWidth)
+				.height(400)
+				.on('customMouseOver', tooltip.show)
+				.on('customMouseMove', tooltip.update)
+				.on('customMouseOut', tooltip.hide);
+
+			lineContainer.datum(dataset).call(lineChart);
+
+			tooltip.title('Latency');
+			tooltipContainer = d3.select('.js-line-container .metadata-group .hover-marker');
+			tooltipContainer.datum([]).call(tooltip);
+		}
+	}
+
+	function createStatusDonutChart() {
+		let donutChart = britecharts.donut(),
+			donutContainer = d3.select('.js-donut-container'),
+			containerWidth = donutContainer.node() ? donutContainer.node().getBoundingClientRect().width : false;
+
+		if (containerWidth && statusData.length > 0) {
+			donutChart
+				.isAnimated(true)
+				.highlightSliceById(0)
+				.colorSchema(britecharts.colors.colorSchemas.teal)
+				.width(Math.min(containerWidth, 300))
+				.height(300)
+				.externalRadius(140)
+				.internalRadius(70);
+
+			donutContainer.datum(statusData.map(function(d, i) {
+				return { id: i, name: d.name, quantity: d.quantity, percentage: d.percentage };
+			})).call(donutChart);
+		}
+	}
+
 	function setJSONDownloadLink () {
 		var filename = "data.json";
 		var btn = document.getElementById('dlJSON');
@@ -424,6 +742,10 @@ duration (ms),status,error{{ range $i, $v := .Details }}
 
 	createHorizontalBarChart();
 
+	createLatencyLineChart();
+
+	createStatusDonutChart();
+
 	setJSONDownloadLink();
 
 	setCSVDownloadLink();
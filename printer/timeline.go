@@ -0,0 +1,76 @@
+package printer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// maxTimelinePoints is the maximum number of points of the latency over time chart
+const maxTimelinePoints = 100
+
+// timelinePoint is a point of the latency over time chart, with the latencies in milliseconds
+// of the calls completed within the bucket starting at the date
+type timelinePoint struct {
+	Date    time.Time `json:"date"`
+	Count   int       `json:"count"`
+	Average float64   `json:"average"`
+	P99     float64   `json:"p99"`
+}
+
+// latencyTimeline buckets the successful calls by the time they completed into at most
+// maxTimelinePoints buckets of whole milliseconds, returning the points of the non-empty buckets.
+// The details of the report are read twice rather than decoded into a slice.
+func latencyTimeline(report runner.Report) []timelinePoint {
+	var first, last time.Time
+	report.EachDetail(func(d *runner.ResultDetail) {
+		if d.Error != "" {
+			return
+		}
+
+		if first.IsZero() || d.Timestamp.Before(first) {
+			first = d.Timestamp
+		}
+
+		if d.Timestamp.After(last) {
+			last = d.Timestamp
+		}
+	})
+
+	if first.IsZero() {
+		return nil
+	}
+
+	size := last.Sub(first)/maxTimelinePoints + time.Millisecond
+	size = size.Truncate(time.Millisecond)
+
+	buckets := make(map[int64][]float64)
+	report.EachDetail(func(d *runner.ResultDetail) {
+		if d.Error == "" {
+			i := int64(d.Timestamp.Sub(first) / size)
+			buckets[i] = append(buckets[i], float64(d.Latency)/float64(time.Millisecond))
+		}
+	})
+
+	points := make([]timelinePoint, 0, len(buckets))
+	for i, lats := range buckets {
+		sort.Float64s(lats)
+
+		var sum float64
+		for _, l := range lats {
+			sum += l
+		}
+
+		points = append(points, timelinePoint{
+			Date:    first.Add(time.Duration(i) * size),
+			Count:   len(lats),
+			Average: sum / float64(len(lats)),
+			P99:     lats[(len(lats)*99-1)/100],
+		})
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+
+	return points
+}
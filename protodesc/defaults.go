@@ -0,0 +1,339 @@
+package protodesc
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// DefaultsFile is the name of the proto file defining the ghz defaults options of the services
+// and methods. It can be imported by the proto files without being on the import paths.
+const DefaultsFile = "ghz/defaults.proto"
+
+// defaultsExtension is the number of the extensions of the defaults options
+const defaultsExtension = 50770
+
+// DefaultsProto is the definition of the ghz defaults options
+const DefaultsProto = `syntax = "proto3";
+
+package ghz;
+
+import "google/protobuf/descriptor.proto";
+
+// Defaults are the defaults of the tests of a method, used for the options which are not set
+message Defaults {
+  // the timeout of each request, such as "500ms"
+  string timeout = 1;
+
+  uint32 total = 2;
+  uint32 concurrency = 3;
+  uint32 rps = 4;
+
+  // the duration of the test, such as "30s"
+  string duration = 5;
+
+  // the JSON of the call data
+  string data = 6;
+
+  map<string, string> metadata = 7;
+}
+
+extend google.protobuf.ServiceOptions {
+  // the defaults of all the methods of the service
+  Defaults service_defaults = 50770;
+}
+
+extend google.protobuf.MethodOptions {
+  // the defaults of the method, taking precedence over those of the service
+  Defaults method_defaults = 50770;
+}
+`
+
+// defaultsView is a message whose only field has the number of the defaults extensions,
+// so the defaults can be read from the encoded options whether or not the extensions were resolved
+const defaultsView = `syntax = "proto3";
+
+package ghz;
+
+import "ghz/defaults.proto";
+
+message DefaultsView {
+  Defaults defaults = 50770;
+}
+`
+
+// Defaults are the defaults of the tests of a method set by the ghz options of its proto
+type Defaults struct {
+	Timeout     time.Duration
+	Total       uint
+	Concurrency uint
+	RPS         uint
+	Duration    time.Duration
+
+	// Data is the JSON of the call data
+	Data string
+
+	Metadata map[string]string
+}
+
+// isDefaultsFile returns whether the path of an import is the defaults proto,
+// which is either the import itself or the import joined to an import path
+func isDefaultsFile(filename string) bool {
+	filename = filepath.ToSlash(filename)
+
+	return filename == DefaultsFile || strings.HasSuffix(filename, "/"+DefaultsFile)
+}
+
+// GetMethodDefaults returns the defaults set by the ghz options of the method and its service,
+// or nil if there are none. The options of the method take precedence over those of the service.
+func GetMethodDefaults(mtd *desc.MethodDescriptor) (*Defaults, error) {
+	var b []byte
+
+	if opts := mtd.GetService().GetServiceOptions(); opts != nil {
+		sb, err := proto.Marshal(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, sb...)
+	}
+
+	if opts := mtd.GetMethodOptions(); opts != nil {
+		mb, err := proto.Marshal(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, mb...)
+	}
+
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	md, err := defaultsViewDescriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	// the options of the method are merged into those of the service
+	view := dynamic.NewMessage(md)
+	if err := view.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf("ghz defaults of %s: %v", mtd.GetFullyQualifiedName(), err)
+	}
+
+	if !view.HasFieldNumber(defaultsExtension) {
+		return nil, nil
+	}
+
+	m := view.GetFieldByNumber(defaultsExtension).(*dynamic.Message)
+
+	d := &Defaults{
+		Total:       uint(m.GetFieldByName("total").(uint32)),
+		Concurrency: uint(m.GetFieldByName("concurrency").(uint32)),
+		RPS:         uint(m.GetFieldByName("rps").(uint32)),
+		Data:        m.GetFieldByName("data").(string),
+	}
+
+	if d.Timeout, err = parseDefaultsDuration(mtd, "timeout", m.GetFieldByName("timeout").(string)); err != nil {
+		return nil, err
+	}
+
+	if d.Duration, err = parseDefaultsDuration(mtd, "duration", m.GetFieldByName("duration").(string)); err != nil {
+		return nil, err
+	}
+
+	if md, ok := m.GetFieldByName("metadata").(map[interface{}]interface{}); ok && len(md) > 0 {
+		d.Metadata = make(map[string]string, len(md))
+		for k, v := range md {
+			d.Metadata[k.(string)] = v.(string)
+		}
+	}
+
+	return d, nil
+}
+
+var (
+	viewOnce sync.Once
+	viewDesc *desc.MessageDescriptor
+	viewErr  error
+)
+
+// defaultsViewDescriptor returns the descriptor of the view of the defaults options
+func defaultsViewDescriptor() (*desc.MessageDescriptor, error) {
+	viewOnce.Do(func() {
+		p := protoparse.Parser{
+			Accessor: protoparse.FileContentsFromMap(map[string]string{
+				DefaultsFile:              DefaultsProto,
+				"ghz/defaults_view.proto": defaultsView,
+			}),
+		}
+
+		fds, err := p.ParseFiles("ghz/defaults_view.proto")
+		if err != nil {
+			viewErr = err
+			return
+		}
+
+		viewDesc = fds[0].FindMessage("ghz.DefaultsView")
+	})
+
+	return viewDesc, viewErr
+}
+
+// restoreDefaults restores the defaults options of the services and methods of the parsed file.
+// The parser drops the values of the custom options once they are interpreted, so they are read
+// from the options of the file parsed again without being interpreted, and added back to the
+// options as unknown fields, like the options of the descriptors loaded from protosets or reflection.
+func restoreDefaults(p *protoparse.Parser, fd *desc.FileDescriptor) error {
+	imported := false
+	for _, dep := range fd.GetDependencies() {
+		if dep.GetName() == DefaultsFile {
+			imported = true
+		}
+	}
+
+	if !imported {
+		return nil
+	}
+
+	fdps, err := p.ParseFilesButDoNotLink(fd.GetName())
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if pkg := fd.GetPackage(); pkg != "" {
+		prefix = pkg + "."
+	}
+
+	for _, sdp := range fdps[0].GetService() {
+		sd := fd.FindService(prefix + sdp.GetName())
+		if sd == nil {
+			continue
+		}
+
+		b, err := defaultsOptions(sdp.GetOptions().GetUninterpretedOption(), "ghz.service_defaults")
+		if err != nil {
+			return fmt.Errorf("ghz defaults of %s: %v", sd.GetFullyQualifiedName(), err)
+		}
+
+		if len(b) > 0 && sd.GetServiceOptions() != nil {
+			addUnknown(sd.GetServiceOptions(), b)
+		}
+
+		for _, mdp := range sdp.GetMethod() {
+			mtd := sd.FindMethodByName(mdp.GetName())
+			if mtd == nil {
+				continue
+			}
+
+			b, err := defaultsOptions(mdp.GetOptions().GetUninterpretedOption(), "ghz.method_defaults")
+			if err != nil {
+				return fmt.Errorf("ghz defaults of %s: %v", mtd.GetFullyQualifiedName(), err)
+			}
+
+			if len(b) > 0 && mtd.GetMethodOptions() != nil {
+				addUnknown(mtd.GetMethodOptions(), b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// defaultsOptions returns the encoded defaults of the uninterpreted options of the extension,
+// which are either the whole message, such as option (ghz.method_defaults) = { total: 100 },
+// or one of its fields, such as option (ghz.method_defaults).total = 100
+func defaultsOptions(uos []*descriptor.UninterpretedOption, ext string) ([]byte, error) {
+	var text strings.Builder
+
+	for _, uo := range uos {
+		names := uo.GetName()
+		if len(names) == 0 || !names[0].GetIsExtension() || strings.TrimPrefix(names[0].GetNamePart(), ".") != ext {
+			continue
+		}
+
+		if len(names) > 2 {
+			return nil, fmt.Errorf("unsupported option (%s).%s", ext, names[1].GetNamePart())
+		}
+
+		if len(names) == 1 {
+			text.WriteString(aggregateFields(uo.GetAggregateValue()))
+			text.WriteString("\n")
+			continue
+		}
+
+		text.WriteString(names[1].GetNamePart())
+
+		switch {
+		case uo.AggregateValue != nil:
+			text.WriteString(" { " + aggregateFields(uo.GetAggregateValue()) + " }")
+		case uo.StringValue != nil:
+			text.WriteString(": " + strconv.Quote(string(uo.GetStringValue())))
+		case uo.PositiveIntValue != nil:
+			text.WriteString(": " + strconv.FormatUint(uo.GetPositiveIntValue(), 10))
+		default:
+			return nil, fmt.Errorf("invalid value of option (%s).%s", ext, names[1].GetNamePart())
+		}
+
+		text.WriteString("\n")
+	}
+
+	if text.Len() == 0 {
+		return nil, nil
+	}
+
+	md, err := defaultsViewDescriptor()
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := dynamic.NewMessage(md.FindFieldByNumber(defaultsExtension).GetMessageType())
+	if err := defaults.UnmarshalText([]byte(text.String())); err != nil {
+		return nil, err
+	}
+
+	view := dynamic.NewMessage(md)
+	view.SetFieldByNumber(defaultsExtension, defaults)
+
+	return view.Marshal()
+}
+
+// aggregateFields returns the fields of the aggregate value, without the braces kept by the parser
+func aggregateFields(v string) string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}") {
+		v = v[1 : len(v)-1]
+	}
+
+	return v
+}
+
+// addUnknown adds the encoded fields to the unknown fields of the options
+func addUnknown(opts proto.Message, b []byte) {
+	m := proto.MessageReflect(opts)
+	m.SetUnknown(append(m.GetUnknown(), b...))
+}
+
+func parseDefaultsDuration(mtd *desc.MethodDescriptor, name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("ghz defaults of %s: invalid %s %q", mtd.GetFullyQualifiedName(), name, s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,322 @@
+package protodesc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// supportedEdition is the protobuf edition whose sources can be rewritten to proto2
+const supportedEdition = "2023"
+
+// newerOptions are the options added to the descriptor options after proto2 and proto3 which
+// the parser does not know of. They only affect code generation and validation and can be dropped.
+var newerOptions = map[string]bool{
+	"retention":    true,
+	"targets":      true,
+	"debug_redact": true,
+	"declaration":  true,
+	"verification": true,
+}
+
+// rewriteEditions rewrites a source using edition 2023 to the equivalent proto2 source, since the
+// parser only supports the proto2 and proto3 syntax. The edition declaration becomes a proto2 syntax
+// declaration, the singular fields get the optional label for explicit presence, or the required label
+// for the legacy required presence, and the features options are dropped. The newer field options of
+// any source which only affect code generation, such as retention and targets, are dropped as well.
+// Delimited message encoding can not be expressed in proto2 and is an error.
+func rewriteEditions(src []byte) ([]byte, error) {
+	if !bytes.Contains(src, []byte("edition")) && !containsNewerOption(src) {
+		return src, nil
+	}
+
+	tokens := tokenizeProto(src)
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+
+	var edits []edit
+
+	edition := ""
+	if len(tokens) >= 4 && tokens[0].text == "edition" && tokens[1].text == "=" && tokens[3].text == ";" {
+		edition = strings.Trim(tokens[2].text, `"'`)
+		if edition != supportedEdition {
+			return nil, fmt.Errorf("edition %q is not supported, only edition %q", edition, supportedEdition)
+		}
+
+		edits = append(edits, edit{tokens[0].start, tokens[2].end, `syntax = "proto2"`})
+	}
+
+	var stack []string
+
+	start := 0
+	if edition != "" {
+		start = 4
+	}
+
+	for i := start; i < len(tokens); {
+		end := statementEnd(tokens, i)
+		tok := tokens[i].text
+
+		switch {
+		case tok == "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+
+			i++
+
+			continue
+		case tok == ";":
+			i++
+
+			continue
+		case edition != "" && tok == "option" && i+1 < len(tokens) && isFeature(tokens[i+1].text):
+			if err := checkFeature(tokens[i+1:end]); err != nil {
+				return nil, err
+			}
+
+			// drop the whole option statement
+			edits = append(edits, edit{tokens[i].start, tokens[end].end, ""})
+			i = end + 1
+
+			continue
+		case edition != "" && tok == "import" && strings.HasSuffix(strings.Trim(tokens[end-1].text, `"'`), "_features.proto"):
+			// the language specific features are only used by the dropped options
+			edits = append(edits, edit{tokens[i].start, tokens[end].end, ""})
+			i = end + 1
+
+			continue
+		}
+
+		label := ""
+
+		// the options of the statement, if any
+		for j := i; j < end; j++ {
+			if tokens[j].text != "[" {
+				continue
+			}
+
+			close := matchingBracket(tokens, j)
+			if close < 0 || close > end {
+				break
+			}
+
+			opts, required, err := rewriteOptions(src, tokens[j+1:close])
+			if err != nil {
+				return nil, err
+			}
+
+			if required {
+				label = "required "
+			}
+
+			if opts != nil {
+				text := ""
+				if len(opts) > 0 {
+					text = "[" + strings.Join(opts, ", ") + "]"
+				}
+
+				edits = append(edits, edit{tokens[j].start, tokens[close].end, text})
+			}
+
+			break
+		}
+
+		// singular fields need a label in proto2
+		if edition != "" && tokens[end].text == ";" && len(stack) > 0 &&
+			(stack[len(stack)-1] == "message" || stack[len(stack)-1] == "extend") &&
+			isUnlabeledField(tokens[i:end]) {
+			if label == "" {
+				label = "optional "
+			}
+
+			edits = append(edits, edit{tokens[i].start, tokens[i].start, label})
+		}
+
+		switch tokens[end].text {
+		case "{":
+			stack = append(stack, tok)
+			i = end + 1
+		case "}":
+			// the statement is not terminated, the block is closed by the next iteration
+			i = end
+		default:
+			i = end + 1
+		}
+	}
+
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	sort.SliceStable(edits, func(a, b int) bool {
+		return edits[a].start < edits[b].start
+	})
+
+	var buf bytes.Buffer
+	prev := 0
+	for _, e := range edits {
+		buf.Write(src[prev:e.start])
+		buf.WriteString(e.text)
+		prev = e.end
+	}
+
+	buf.Write(src[prev:])
+
+	return buf.Bytes(), nil
+}
+
+// statementEnd returns the index of the semicolon or the opening brace of the block
+// ending the statement starting at i, skipping option values, or the last index
+func statementEnd(tokens []protoToken, i int) int {
+	depth := 0
+
+	for j := i; j < len(tokens); j++ {
+		switch tokens[j].text {
+		case "[", "(", "<":
+			depth++
+		case "]", ")", ">":
+			depth--
+		case "{":
+			// an aggregate option value is not a block
+			if depth == 0 && (j == i || (tokens[j-1].text != "=" && tokens[j-1].text != ":")) {
+				return j
+			}
+
+			if close := matchingBracket(tokens, j); close > 0 {
+				j = close
+			}
+		case ";", "}":
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+
+	return len(tokens) - 1
+}
+
+// matchingBracket returns the index of the bracket closing the bracket at i, or -1
+func matchingBracket(tokens []protoToken, i int) int {
+	depth := 0
+
+	for j := i; j < len(tokens); j++ {
+		switch tokens[j].text {
+		case "[", "{", "(":
+			depth++
+		case "]", "}", ")":
+			depth--
+
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+
+	return -1
+}
+
+// rewriteOptions returns the options of the option list without the features and the newer field
+// options, and whether the field has legacy required presence. The options are nil if none are dropped.
+func rewriteOptions(src []byte, tokens []protoToken) ([]string, bool, error) {
+	var opts []string
+	var required, dropped bool
+
+	for len(tokens) > 0 {
+		end := len(tokens)
+		depth := 0
+		for j, t := range tokens {
+			switch t.text {
+			case "[", "{", "(":
+				depth++
+			case "]", "}", ")":
+				depth--
+			case ",":
+				if depth == 0 && end == len(tokens) {
+					end = j
+				}
+			}
+		}
+
+		opt := tokens[:end]
+		if end < len(tokens) {
+			tokens = tokens[end+1:]
+		} else {
+			tokens = nil
+		}
+
+		if len(opt) == 0 {
+			continue
+		}
+
+		switch name := opt[0].text; {
+		case isFeature(name):
+			if err := checkFeature(opt); err != nil {
+				return nil, false, err
+			}
+
+			if len(opt) >= 3 && name == "features.field_presence" && opt[2].text == "LEGACY_REQUIRED" {
+				required = true
+			}
+
+			dropped = true
+		case newerOptions[name]:
+			dropped = true
+		default:
+			opts = append(opts, string(src[opt[0].start:opt[len(opt)-1].end]))
+		}
+	}
+
+	if !dropped {
+		return nil, false, nil
+	}
+
+	if opts == nil {
+		opts = []string{}
+	}
+
+	return opts, required, nil
+}
+
+// checkFeature returns an error for the features which can not be expressed in proto2
+func checkFeature(opt []protoToken) error {
+	if len(opt) >= 3 && opt[0].text == "features.message_encoding" && opt[2].text == "DELIMITED" {
+		return fmt.Errorf("delimited message encoding is not supported")
+	}
+
+	return nil
+}
+
+func isFeature(name string) bool {
+	return name == "features" || strings.HasPrefix(name, "features.")
+}
+
+// isUnlabeledField returns whether the statement is a singular field without a label
+func isUnlabeledField(tokens []protoToken) bool {
+	if len(tokens) < 4 {
+		return false
+	}
+
+	switch tokens[0].text {
+	case "optional", "required", "repeated", "map", "option", "reserved", "extensions",
+		"message", "enum", "oneof", "extend", "group":
+		return false
+	}
+
+	return tokens[2].text == "="
+}
+
+// containsNewerOption returns whether the source may use any of the newer options
+func containsNewerOption(src []byte) bool {
+	for name := range newerOptions {
+		if bytes.Contains(src, []byte(name)) {
+			return true
+		}
+	}
+
+	return false
+}
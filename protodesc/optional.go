@@ -0,0 +1,299 @@
+package protodesc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+// newParser returns a parser of the proto files in the import paths which supports
+// proto3 optional fields and edition 2023. The parser does not support the optional label in proto3,
+// so the fields are rewritten to the synthetic oneofs generated by protoc, which gives
+// them the same presence semantics. The fields are marked as proto3 optional once parsed
+// using markProto3Optional. The sources using edition 2023 are rewritten to proto2 by rewriteEditions.
+func newParser(imports []string, sourceInfo bool) (*protoparse.Parser, map[string]bool) {
+	optional := map[string]bool{}
+
+	p := &protoparse.Parser{
+		ImportPaths:           imports,
+		IncludeSourceCodeInfo: sourceInfo,
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			src, err := ioutil.ReadFile(filename)
+			if os.IsNotExist(err) && isDefaultsFile(filename) {
+				return ioutil.NopCloser(strings.NewReader(DefaultsProto)), nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if src, err = rewriteEditions(src); err != nil {
+				return nil, fmt.Errorf("%s: %v", filename, err)
+			}
+
+			src, fields := rewriteProto3Optional(src)
+			for _, f := range fields {
+				optional[f] = true
+			}
+
+			return ioutil.NopCloser(bytes.NewReader(src)), nil
+		},
+	}
+
+	return p, optional
+}
+
+// markProto3Optional marks the rewritten fields of the files and their dependencies as proto3 optional
+func markProto3Optional(fds []*desc.FileDescriptor, optional map[string]bool) {
+	if len(optional) == 0 {
+		return
+	}
+
+	seen := map[string]bool{}
+
+	var markMessages func(mds []*desc.MessageDescriptor)
+	markMessages = func(mds []*desc.MessageDescriptor) {
+		for _, md := range mds {
+			for _, fd := range md.GetFields() {
+				if optional[fd.GetFullyQualifiedName()] {
+					fd.AsFieldDescriptorProto().Proto3Optional = proto.Bool(true)
+				}
+			}
+
+			markMessages(md.GetNestedMessageTypes())
+		}
+	}
+
+	var markFiles func(fds []*desc.FileDescriptor)
+	markFiles = func(fds []*desc.FileDescriptor) {
+		for _, fd := range fds {
+			if seen[fd.GetName()] {
+				continue
+			}
+
+			seen[fd.GetName()] = true
+
+			markMessages(fd.GetMessageTypes())
+			markFiles(fd.GetDependencies())
+		}
+	}
+
+	markFiles(fds)
+}
+
+// protoToken is a token of the proto source with its offsets
+type protoToken struct {
+	text       string
+	start, end int
+}
+
+// rewriteProto3Optional rewrites the optional fields of a proto3 source to the synthetic oneofs
+// generated by protoc, so that "optional string name = 1;" becomes "oneof _name { string name = 1; }"
+// on the same lines. It returns the fully-qualified names of the rewritten fields.
+func rewriteProto3Optional(src []byte) ([]byte, []string) {
+	if !bytes.Contains(src, []byte("optional")) {
+		return src, nil
+	}
+
+	tokens := tokenizeProto(src)
+	if !isProto3(tokens) {
+		return src, nil
+	}
+
+	type block struct {
+		kind, name string
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+
+	var pkg string
+	var stack []block
+	var edits []edit
+	var fields []string
+
+	start := true
+	kind, name := "", ""
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i].text
+
+		if start {
+			start = false
+			kind, name = tok, ""
+			if i+1 < len(tokens) {
+				name = tokens[i+1].text
+			}
+
+			if kind == "package" {
+				pkg = name
+			}
+
+			if tok == "optional" && len(stack) > 0 && stack[len(stack)-1].kind == "message" {
+				if end, field := optionalField(tokens, i); end > 0 {
+					scope := make([]string, 0, len(stack)+2)
+					if pkg != "" {
+						scope = append(scope, pkg)
+					}
+
+					for _, b := range stack {
+						scope = append(scope, b.name)
+					}
+
+					fields = append(fields, strings.Join(append(scope, field), "."))
+					edits = append(edits,
+						edit{tokens[i].start, tokens[i].end, "oneof _" + field + " {"},
+						edit{tokens[end].end, tokens[end].end, " }"})
+
+					i = end
+					start = true
+
+					continue
+				}
+			}
+		}
+
+		switch tok {
+		case "{":
+			stack = append(stack, block{kind, name})
+			start = true
+		case "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			start = true
+		case ";":
+			start = true
+		}
+	}
+
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	var buf bytes.Buffer
+	prev := 0
+	for _, e := range edits {
+		buf.Write(src[prev:e.start])
+		buf.WriteString(e.text)
+		prev = e.end
+	}
+
+	buf.Write(src[prev:])
+
+	return buf.Bytes(), fields
+}
+
+// optionalField returns the index of the semicolon ending the optional field starting at i,
+// and the name of the field, or 0 if it's not a field
+func optionalField(tokens []protoToken, i int) (int, string) {
+	depth := 0
+	name := ""
+
+	for j := i + 1; j < len(tokens); j++ {
+		switch tok := tokens[j].text; tok {
+		case "[", "{", "(":
+			depth++
+		case "]", "}", ")":
+			depth--
+		case "=":
+			if depth == 0 && name == "" && j-1 > i+1 {
+				name = tokens[j-1].text
+			}
+		case ";":
+			if depth == 0 {
+				if name == "" {
+					return 0, ""
+				}
+
+				return j, name
+			}
+		}
+
+		if depth < 0 {
+			return 0, ""
+		}
+	}
+
+	return 0, ""
+}
+
+// isProto3 returns whether the syntax of the tokenized source is proto3
+func isProto3(tokens []protoToken) bool {
+	if len(tokens) < 3 || tokens[0].text != "syntax" || tokens[1].text != "=" {
+		return false
+	}
+
+	s := tokens[2].text
+
+	return s == `"proto3"` || s == `'proto3'`
+}
+
+// tokenizeProto splits the proto source into identifiers, numbers, strings and punctuation,
+// skipping whitespace and comments
+func tokenizeProto(src []byte) []protoToken {
+	var tokens []protoToken
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 4
+			}
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+
+			if j < len(src) {
+				j++
+			} else {
+				j = len(src)
+			}
+
+			tokens = append(tokens, protoToken{string(src[i:j]), i, j})
+			i = j
+		case isIdentChar(c):
+			j := i + 1
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+
+			tokens = append(tokens, protoToken{string(src[i:j]), i, j})
+			i = j
+		default:
+			tokens = append(tokens, protoToken{string(c), i, i + 1})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
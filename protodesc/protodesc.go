@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -18,10 +18,12 @@ import (
 
 var errNoMethodNameSpecified = errors.New("no method name specified")
 
+const reflectionServiceName = "grpc.reflection.v1alpha.ServerReflection"
+
 // GetMethodDescFromProto gets method descritor for the given call symbol from proto file given my path proto
 // imports is used for import paths in parsing the proto file
 func GetMethodDescFromProto(call, proto string, imports []string) (*desc.MethodDescriptor, error) {
-	p := &protoparse.Parser{ImportPaths: imports}
+	p, optional := newParser(imports, true)
 
 	filename := proto
 	if filepath.IsAbs(filename) {
@@ -33,16 +35,122 @@ func GetMethodDescFromProto(call, proto string, imports []string) (*desc.MethodD
 		return nil, err
 	}
 
+	markProto3Optional(fds, optional)
+
 	fileDesc := fds[0]
 
 	files := map[string]*desc.FileDescriptor{}
 	files[fileDesc.GetName()] = fileDesc
 
-	return getMethodDesc(call, files)
+	mtd, err := getMethodDesc(call, files)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := restoreDefaults(p, mtd.GetFile()); err != nil {
+		return nil, err
+	}
+
+	return mtd, nil
 }
 
 // GetMethodDescFromProtoSet gets method descritor for the given call symbol from protoset file given my path protoset
 func GetMethodDescFromProtoSet(call, protoset string) (*desc.MethodDescriptor, error) {
+	resolved, err := loadProtoSet(protoset)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMethodDesc(call, resolved)
+}
+
+// GetMethodDescFromReflect gets method descriptor for the call from reflection using client
+func GetMethodDescFromReflect(call string, client *grpcreflect.Client) (*desc.MethodDescriptor, error) {
+	call = strings.Replace(call, "/", ".", -1)
+	file, err := client.FileContainingSymbol(call)
+	if err != nil || file == nil {
+		return nil, reflectionSupport(err)
+	}
+
+	files := map[string]*desc.FileDescriptor{}
+	files[file.GetName()] = file
+
+	return getMethodDesc(call, files)
+}
+
+// GetMethodNamesFromProto lists the fully-qualified names of all the methods of the services
+// defined in the proto file given by path proto
+func GetMethodNamesFromProto(proto string, imports []string) ([]string, error) {
+	p, _ := newParser(imports, false)
+
+	filename := proto
+	if filepath.IsAbs(filename) {
+		filename = filepath.Base(proto)
+	}
+
+	fds, err := p.ParseFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return methodNames(fds[0].GetServices()), nil
+}
+
+// GetMethodNamesFromProtoSet lists the fully-qualified names of all the methods of the services
+// defined in the protoset file given by path protoset
+func GetMethodNamesFromProtoSet(protoset string) ([]string, error) {
+	resolved, err := loadProtoSet(protoset)
+	if err != nil {
+		return nil, err
+	}
+
+	var svcs []*desc.ServiceDescriptor
+	for _, fd := range resolved {
+		svcs = append(svcs, fd.GetServices()...)
+	}
+
+	return methodNames(svcs), nil
+}
+
+// GetMethodNamesFromReflect lists the fully-qualified names of all the methods of the services
+// exposed by the server using reflection client. The reflection service itself is omitted.
+func GetMethodNamesFromReflect(client *grpcreflect.Client) ([]string, error) {
+	names, err := client.ListServices()
+	if err != nil {
+		return nil, reflectionSupport(err)
+	}
+
+	svcs := make([]*desc.ServiceDescriptor, 0, len(names))
+	for _, name := range names {
+		if name == reflectionServiceName {
+			continue
+		}
+
+		sd, err := client.ResolveService(name)
+		if err != nil {
+			return nil, reflectionSupport(err)
+		}
+
+		svcs = append(svcs, sd)
+	}
+
+	return methodNames(svcs), nil
+}
+
+func methodNames(svcs []*desc.ServiceDescriptor) []string {
+	var names []string
+	for _, sd := range svcs {
+		for _, md := range sd.GetMethods() {
+			names = append(names, md.GetFullyQualifiedName())
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func loadProtoSet(protoset string) (map[string]*desc.FileDescriptor, error) {
 	b, err := ioutil.ReadFile(protoset)
 	if err != nil {
 		return nil, fmt.Errorf("could not load protoset file %q: %v", protoset, err)
@@ -66,21 +174,7 @@ func GetMethodDescFromProtoSet(call, protoset string) (*desc.MethodDescriptor, e
 		}
 	}
 
-	return getMethodDesc(call, resolved)
-}
-
-// GetMethodDescFromReflect gets method descriptor for the call from reflection using client
-func GetMethodDescFromReflect(call string, client *grpcreflect.Client) (*desc.MethodDescriptor, error) {
-	call = strings.Replace(call, "/", ".", -1)
-	file, err := client.FileContainingSymbol(call)
-	if err != nil || file == nil {
-		return nil, reflectionSupport(err)
-	}
-
-	files := map[string]*desc.FileDescriptor{}
-	files[file.GetName()] = file
-
-	return getMethodDesc(call, files)
+	return resolved, nil
 }
 
 func getMethodDesc(call string, files map[string]*desc.FileDescriptor) (*desc.MethodDescriptor, error) {
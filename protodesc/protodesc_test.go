@@ -2,9 +2,18 @@ package protodesc
 
 import (
 	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bojand/ghz/internal"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
@@ -186,3 +195,408 @@ func TestProtodesc_GetMethodDescFromReflect(t *testing.T) {
 		assert.Nil(t, mtd)
 	})
 }
+
+func TestProtodesc_GetMethodNames(t *testing.T) {
+	expected := []string{
+		"helloworld.Greeter.SayHello",
+		"helloworld.Greeter.SayHelloBidi",
+		"helloworld.Greeter.SayHelloCS",
+		"helloworld.Greeter.SayHellos",
+	}
+
+	t.Run("from proto", func(t *testing.T) {
+		names, err := GetMethodNamesFromProto("../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, names)
+	})
+
+	t.Run("from invalid proto", func(t *testing.T) {
+		names, err := GetMethodNamesFromProto("invalid.proto", []string{})
+		assert.Error(t, err)
+		assert.Nil(t, names)
+	})
+
+	t.Run("from protoset", func(t *testing.T) {
+		names, err := GetMethodNamesFromProtoSet("../testdata/bundle.protoset")
+		assert.NoError(t, err)
+		assert.Subset(t, names, expected)
+	})
+
+	t.Run("from reflection", func(t *testing.T) {
+		_, s, err := internal.StartServer(false)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		defer s.Stop()
+
+		conn, err := grpc.DialContext(context.Background(), internal.TestLocalhost, grpc.WithInsecure())
+		assert.NoError(t, err)
+
+		refClient := grpcreflect.NewClient(context.Background(), reflectpb.NewServerReflectionClient(conn))
+
+		names, err := GetMethodNamesFromReflect(refClient)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, names)
+	})
+}
+
+func TestProtodesc_MessageTemplate(t *testing.T) {
+	md, err := GetMethodDescFromProto("template.TemplateService.Create", "../testdata/template.proto", []string{})
+	assert.NoError(t, err)
+
+	t.Run("template", func(t *testing.T) {
+		tmpl := MessageTemplate(md.GetInputType())
+
+		expected := map[string]interface{}{
+			"name":               "",
+			"age":                0,
+			"active":             false,
+			"role":               "ROLE_UNKNOWN",
+			"tags":               []interface{}{""},
+			"scores":             map[string]interface{}{"key": 0},
+			"address":            map[string]interface{}{"city": ""},
+			"previous_addresses": []interface{}{map[string]interface{}{"city": ""}},
+			"created":            "1970-01-01T00:00:00Z",
+			"node":               map[string]interface{}{"id": "", "parent": map[string]interface{}{}},
+			"email":              "",
+		}
+
+		assert.Equal(t, expected, tmpl)
+
+		data, err := json.Marshal(tmpl)
+		assert.NoError(t, err)
+
+		msg := dynamic.NewMessage(md.GetInputType())
+		assert.NoError(t, jsonpb.UnmarshalString(string(data), msg))
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		fields := DescribeFields(md.GetInputType())
+
+		assert.Equal(t, FieldInfo{Path: "name", Type: "string", Comment: "The name of the user"}, fields[0])
+		assert.Contains(t, fields, FieldInfo{Path: "scores", Type: "map<string, int32>"})
+		assert.Contains(t, fields, FieldInfo{Path: "address.city", Type: "string", Comment: "The city name"})
+		assert.Contains(t, fields, FieldInfo{Path: "previous_addresses[].city", Type: "string", Comment: "The city name"})
+		assert.Contains(t, fields, FieldInfo{Path: "node.parent", Type: "template.Node"})
+		assert.Contains(t, fields, FieldInfo{Path: "role", Type: "template.Role"})
+		assert.Contains(t, fields, FieldInfo{Path: "created", Type: "google.protobuf.Timestamp"})
+		assert.NotContains(t, fields, FieldInfo{Path: "node.parent.id", Type: "string"})
+	})
+}
+
+func TestProtodesc_Proto3Optional(t *testing.T) {
+	t.Run("rewrite", func(t *testing.T) {
+		src := []byte(`syntax = "proto3";
+package test;
+message Foo {
+  // optional int32 a = 1;
+  optional int32 a = 1;
+  optional .test.Bar b = 2 [(opt) = { x: "optional;" }];
+  message Baz {
+    optional string c = 1;
+  }
+  string optional_d = 3;
+}`)
+
+		res, fields := rewriteProto3Optional(src)
+		assert.Equal(t, []string{"test.Foo.a", "test.Foo.b", "test.Foo.Baz.c"}, fields)
+		assert.Equal(t, `syntax = "proto3";
+package test;
+message Foo {
+  // optional int32 a = 1;
+  oneof _a { int32 a = 1; }
+  oneof _b { .test.Bar b = 2 [(opt) = { x: "optional;" }]; }
+  message Baz {
+    oneof _c { string c = 1; }
+  }
+  string optional_d = 3;
+}`, string(res))
+	})
+
+	t.Run("proto2", func(t *testing.T) {
+		src := []byte(`syntax = "proto2"; message Foo { optional int32 a = 1; }`)
+
+		res, fields := rewriteProto3Optional(src)
+		assert.Empty(t, fields)
+		assert.Equal(t, src, res)
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		md, err := GetMethodDescFromProto("optional.Optional.Update", "../testdata/optional.proto", []string{})
+		assert.NoError(t, err)
+		assert.NotNil(t, md)
+
+		input := md.GetInputType()
+		count := input.FindFieldByName("count")
+		assert.True(t, count.AsFieldDescriptorProto().GetProto3Optional())
+		assert.Equal(t, "_count", count.GetOneOf().GetName())
+
+		assert.False(t, input.FindFieldByName("id").AsFieldDescriptorProto().GetProto3Optional())
+		assert.False(t, input.FindFieldByName("name").AsFieldDescriptorProto().GetProto3Optional())
+
+		max := input.FindFieldByName("limits").GetMessageType().FindFieldByName("max")
+		assert.True(t, max.AsFieldDescriptorProto().GetProto3Optional())
+	})
+}
+
+func TestProtodesc_Editions(t *testing.T) {
+	t.Run("rewrite", func(t *testing.T) {
+		src := []byte(`edition = "2023";
+package test;
+import "google/protobuf/cpp_features.proto";
+option features.field_presence = IMPLICIT;
+message Foo {
+  option (opt) = { x: 1 };
+  // int32 a = 1;
+  int32 a = 1;
+  .test.Bar b = 2 [(opt) = { x: "a, b" }, features.field_presence = LEGACY_REQUIRED];
+  repeated int32 c = 3 [features.repeated_field_encoding = EXPANDED, packed = true];
+  map<string, int32> d = 4;
+  oneof e {
+    string f = 5 [debug_redact = true];
+  }
+  reserved 6;
+}
+enum Baz {
+  BAZ_UNKNOWN = 0;
+}`)
+
+		res, err := rewriteEditions(src)
+		assert.NoError(t, err)
+		assert.Equal(t, `syntax = "proto2";
+package test;
+
+
+message Foo {
+  option (opt) = { x: 1 };
+  // int32 a = 1;
+  optional int32 a = 1;
+  required .test.Bar b = 2 [(opt) = { x: "a, b" }];
+  repeated int32 c = 3 [packed = true];
+  map<string, int32> d = 4;
+  oneof e {
+    string f = 5 ;
+  }
+  reserved 6;
+}
+enum Baz {
+  BAZ_UNKNOWN = 0;
+}`, string(res))
+	})
+
+	t.Run("newer options", func(t *testing.T) {
+		src := []byte(`syntax = "proto3";
+extend google.protobuf.FieldOptions {
+  string label = 50001 [retention = RETENTION_SOURCE];
+}`)
+
+		res, err := rewriteEditions(src)
+		assert.NoError(t, err)
+		assert.Equal(t, `syntax = "proto3";
+extend google.protobuf.FieldOptions {
+  string label = 50001 ;
+}`, string(res))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := rewriteEditions([]byte(`edition = "2024"; message Foo {}`))
+		assert.EqualError(t, err, `edition "2024" is not supported, only edition "2023"`)
+
+		_, err = rewriteEditions([]byte(`edition = "2023"; message Foo { Foo foo = 1 [features.message_encoding = DELIMITED]; }`))
+		assert.EqualError(t, err, "delimited message encoding is not supported")
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		md, err := GetMethodDescFromProto("editions.Editions.Create", "../testdata/editions.proto", []string{})
+		assert.NoError(t, err)
+		if !assert.NotNil(t, md) {
+			return
+		}
+
+		input := md.GetInputType()
+		assert.True(t, input.FindFieldByName("id").IsRequired())
+		assert.False(t, input.FindFieldByName("name").IsRequired())
+		assert.True(t, input.FindFieldByName("values").IsRepeated())
+		assert.True(t, input.FindFieldByName("tags").IsMap())
+		assert.Equal(t, "target", input.FindFieldByName("email").GetOneOf().GetName())
+	})
+
+	t.Run("protoset", func(t *testing.T) {
+		fdp := &descriptor.FileDescriptorProto{
+			Name:    proto.String("editions.proto"),
+			Package: proto.String("editions"),
+			Syntax:  proto.String("editions"),
+			MessageType: []*descriptor.DescriptorProto{{
+				Name: proto.String("Request"),
+				Field: []*descriptor.FieldDescriptorProto{{
+					Name:     proto.String("name"),
+					Number:   proto.Int32(1),
+					Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					JsonName: proto.String("name"),
+				}},
+			}},
+			Service: []*descriptor.ServiceDescriptorProto{{
+				Name: proto.String("Editions"),
+				Method: []*descriptor.MethodDescriptorProto{{
+					Name:       proto.String("Create"),
+					InputType:  proto.String(".editions.Request"),
+					OutputType: proto.String(".editions.Request"),
+				}},
+			}},
+		}
+
+		fb, err := proto.Marshal(fdp)
+		assert.NoError(t, err)
+
+		// the edition field of the file set to EDITION_2023, which is not known to the descriptor
+		fb = append(fb, 0x70, 0xe8, 0x07)
+
+		// the file field of the file set
+		b := append([]byte{0x0a}, proto.EncodeVarint(uint64(len(fb)))...)
+		b = append(b, fb...)
+
+		path := filepath.Join(t.TempDir(), "editions.protoset")
+		assert.NoError(t, ioutil.WriteFile(path, b, 0644))
+
+		md, err := GetMethodDescFromProtoSet("editions.Editions.Create", path)
+		assert.NoError(t, err)
+		if assert.NotNil(t, md) {
+			assert.NotNil(t, md.GetInputType().FindFieldByName("name"))
+		}
+	})
+}
+
+func TestProtodesc_GetMethodDefaults(t *testing.T) {
+	t.Run("proto", func(t *testing.T) {
+		md, err := GetMethodDescFromProto("helloworld.Greeter.SayHello", "../testdata/defaults.proto", []string{})
+		assert.NoError(t, err)
+
+		d, err := GetMethodDefaults(md)
+		assert.NoError(t, err)
+
+		// the method defaults are merged into the service defaults
+		assert.Equal(t, &Defaults{
+			Timeout:     2 * time.Second,
+			Total:       12,
+			Concurrency: 2,
+			Data:        `{"name":"bob"}`,
+			Metadata:    map[string]string{"team": "greeter", "request-id": "{{.RequestNumber}}"},
+		}, d)
+
+		md, err = GetMethodDescFromProto("helloworld.Greeter.SayHellos", "../testdata/defaults.proto", []string{})
+		assert.NoError(t, err)
+
+		d, err = GetMethodDefaults(md)
+		assert.NoError(t, err)
+		assert.Equal(t, &Defaults{
+			Timeout:     5 * time.Second,
+			Concurrency: 2,
+			RPS:         50,
+			Duration:    10 * time.Second,
+			Metadata:    map[string]string{"team": "greeter"},
+		}, d)
+
+		md, err = GetMethodDescFromProto("helloworld.Greeter.SayHelloCS", "../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+
+		d, err = GetMethodDefaults(md)
+		assert.NoError(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("protoset", func(t *testing.T) {
+		md, err := GetMethodDescFromProto("helloworld.Greeter.SayHello", "../testdata/defaults.proto", []string{})
+		assert.NoError(t, err)
+
+		// the files of the set in the order of their dependencies
+		var files []*descriptor.FileDescriptorProto
+		seen := map[string]bool{}
+
+		var add func(fd *desc.FileDescriptor)
+		add = func(fd *desc.FileDescriptor) {
+			if seen[fd.GetName()] {
+				return
+			}
+
+			seen[fd.GetName()] = true
+			for _, dep := range fd.GetDependencies() {
+				add(dep)
+			}
+
+			files = append(files, fd.AsFileDescriptorProto())
+		}
+
+		add(md.GetFile())
+
+		b, err := proto.Marshal(&descriptor.FileDescriptorSet{File: files})
+		assert.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "defaults.protoset")
+		assert.NoError(t, ioutil.WriteFile(path, b, 0644))
+
+		md, err = GetMethodDescFromProtoSet("helloworld.Greeter.SayHello", path)
+		assert.NoError(t, err)
+
+		d, err := GetMethodDefaults(md)
+		assert.NoError(t, err)
+		if assert.NotNil(t, d) {
+			assert.Equal(t, 2*time.Second, d.Timeout)
+			assert.Equal(t, uint(12), d.Total)
+			assert.Equal(t, uint(2), d.Concurrency)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := defaultsOptions([]*descriptor.UninterpretedOption{{
+			Name:           []*descriptor.UninterpretedOption_NamePart{{NamePart: proto.String("ghz.method_defaults"), IsExtension: proto.Bool(true)}},
+			AggregateValue: proto.String("{ missing: 1 }"),
+		}}, "ghz.method_defaults")
+		assert.Error(t, err)
+
+		b, err := defaultsOptions([]*descriptor.UninterpretedOption{{
+			Name:        []*descriptor.UninterpretedOption_NamePart{{NamePart: proto.String("other"), IsExtension: proto.Bool(true)}},
+			StringValue: []byte("x"),
+		}}, "ghz.method_defaults")
+		assert.NoError(t, err)
+		assert.Nil(t, b)
+	})
+}
+
+func TestProtodesc_WriteProtoSet(t *testing.T) {
+	md, err := GetMethodDescFromProtoSet("helloworld.Greeter.SayHello", "../testdata/bundle.protoset")
+	assert.NoError(t, err)
+
+	cap, err := GetMethodDescFromProtoSet("cap.Capper.Cap", "../testdata/bundle.protoset")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "out", "bundle.protoset")
+
+	// the files written more than once and the shared dependencies are written once
+	assert.NoError(t, WriteProtoSet(path, md.GetFile(), cap.GetFile(), md.GetFile()))
+
+	files, err := LoadProtoSet(path)
+	assert.NoError(t, err)
+	if assert.Len(t, files, 3) {
+		assert.Equal(t, "cap.proto", files[0].GetName())
+		assert.Equal(t, "common.proto", files[1].GetName())
+		assert.Equal(t, "greeter.proto", files[2].GetName())
+	}
+
+	mtd, err := GetMethodDescFromFiles("helloworld.Greeter.SayHello", files)
+	assert.NoError(t, err)
+	if assert.NotNil(t, mtd) {
+		assert.Equal(t, md.GetInputType().GetFullyQualifiedName(), mtd.GetInputType().GetFullyQualifiedName())
+	}
+
+	mtd, err = GetMethodDescFromProtoSet("cap.Capper/Cap", path)
+	assert.NoError(t, err)
+	assert.NotNil(t, mtd)
+
+	_, err = GetMethodDescFromFiles("helloworld.Greeter.Foo", files)
+	assert.Error(t, err)
+
+	_, err = LoadProtoSet(filepath.Join(t.TempDir(), "missing.protoset"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package protodesc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// LoadProtoSet loads the file descriptors of the protoset file, sorted by name
+func LoadProtoSet(protoset string) ([]*desc.FileDescriptor, error) {
+	resolved, err := loadProtoSet(protoset)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*desc.FileDescriptor, 0, len(resolved))
+	for _, fd := range resolved {
+		files = append(files, fd)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].GetName() < files[j].GetName() })
+
+	return files, nil
+}
+
+// GetMethodDescFromFiles gets method descriptor for the given call symbol from the file descriptors
+func GetMethodDescFromFiles(call string, files []*desc.FileDescriptor) (*desc.MethodDescriptor, error) {
+	resolved := make(map[string]*desc.FileDescriptor, len(files))
+	for _, fd := range files {
+		resolved[fd.GetName()] = fd
+	}
+
+	return getMethodDesc(call, resolved)
+}
+
+// WriteProtoSet writes the file descriptors along with all of their dependencies to the protoset
+// file, each dependency before the files importing it, so the file can be used with -protoset.
+// The file is replaced atomically, so it can be read by other processes meanwhile.
+func WriteProtoSet(protoset string, files ...*desc.FileDescriptor) error {
+	fds := &descriptor.FileDescriptorSet{}
+	seen := map[string]bool{}
+
+	var add func(fd *desc.FileDescriptor)
+	add = func(fd *desc.FileDescriptor) {
+		if seen[fd.GetName()] {
+			return
+		}
+
+		seen[fd.GetName()] = true
+
+		for _, dep := range fd.GetDependencies() {
+			add(dep)
+		}
+
+		fds.File = append(fds.File, fd.AsFileDescriptorProto())
+	}
+
+	for _, fd := range files {
+		add(fd)
+	}
+
+	b, err := proto.Marshal(fds)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(protoset)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(protoset)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return os.Rename(tmp.Name(), protoset)
+}
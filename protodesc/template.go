@@ -0,0 +1,195 @@
+package protodesc
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// FieldInfo describes a single field of a message
+type FieldInfo struct {
+	// Path is the path of the field within the top level message, ie "user.address.city"
+	Path string
+
+	// Type is the proto type of the field, ie "string", "repeated int32" or "map<string, int64>"
+	Type string
+
+	// Comment is the comment of the field from the proto source, if available
+	Comment string
+}
+
+// MessageTemplate returns a template for the message with every field set to its default value.
+// The result can be marshalled to JSON and used as a starting point for the call data.
+// Only the first field of every oneof is included.
+func MessageTemplate(md *desc.MessageDescriptor) map[string]interface{} {
+	return messageTemplate(md, map[string]bool{})
+}
+
+// DescribeFields returns the information of all the fields of the message, including the
+// fields of nested messages.
+func DescribeFields(md *desc.MessageDescriptor) []FieldInfo {
+	return describeFields(md, "", map[string]bool{})
+}
+
+func messageTemplate(md *desc.MessageDescriptor, seen map[string]bool) map[string]interface{} {
+	tmpl := make(map[string]interface{})
+
+	// guard against recursive messages
+	if seen[md.GetFullyQualifiedName()] {
+		return tmpl
+	}
+
+	seen[md.GetFullyQualifiedName()] = true
+	defer delete(seen, md.GetFullyQualifiedName())
+
+	oneOfs := map[string]bool{}
+	for _, fd := range md.GetFields() {
+		if oo := fd.GetOneOf(); oo != nil {
+			if oneOfs[oo.GetName()] {
+				continue
+			}
+
+			oneOfs[oo.GetName()] = true
+		}
+
+		tmpl[fd.GetName()] = fieldTemplate(fd, seen)
+	}
+
+	return tmpl
+}
+
+func fieldTemplate(fd *desc.FieldDescriptor, seen map[string]bool) interface{} {
+	if fd.IsMap() {
+		k := "0"
+		switch fd.GetMapKeyType().GetType() {
+		case descriptor.FieldDescriptorProto_TYPE_STRING:
+			k = "key"
+		case descriptor.FieldDescriptorProto_TYPE_BOOL:
+			k = "false"
+		}
+
+		return map[string]interface{}{k: valueTemplate(fd.GetMapValueType(), seen)}
+	}
+
+	v := valueTemplate(fd, seen)
+	if fd.IsRepeated() {
+		return []interface{}{v}
+	}
+
+	return v
+}
+
+func valueTemplate(fd *desc.FieldDescriptor, seen map[string]bool) interface{} {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if values := fd.GetEnumType().GetValues(); len(values) > 0 {
+			return values[0].GetName()
+		}
+
+		return 0
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return wellKnownTemplate(fd.GetMessageType(), seen)
+	default:
+		return 0
+	}
+}
+
+// wellKnownTemplate returns the JSON representation of well known types, which differs
+// from the regular message representation
+func wellKnownTemplate(md *desc.MessageDescriptor, seen map[string]bool) interface{} {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z"
+	case "google.protobuf.Duration":
+		return "0s"
+	case "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return ""
+	case "google.protobuf.BoolValue":
+		return false
+	case "google.protobuf.Int32Value", "google.protobuf.Int64Value",
+		"google.protobuf.UInt32Value", "google.protobuf.UInt64Value",
+		"google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return 0
+	case "google.protobuf.Value":
+		return nil
+	case "google.protobuf.ListValue":
+		return []interface{}{}
+	case "google.protobuf.Struct", "google.protobuf.Empty", "google.protobuf.Any":
+		return map[string]interface{}{}
+	}
+
+	return messageTemplate(md, seen)
+}
+
+func describeFields(md *desc.MessageDescriptor, prefix string, seen map[string]bool) []FieldInfo {
+	if seen[md.GetFullyQualifiedName()] {
+		return nil
+	}
+
+	seen[md.GetFullyQualifiedName()] = true
+	defer delete(seen, md.GetFullyQualifiedName())
+
+	var fields []FieldInfo
+	for _, fd := range md.GetFields() {
+		path := prefix + fd.GetName()
+
+		info := FieldInfo{Path: path, Type: fieldTypeName(fd)}
+		if si := fd.GetSourceInfo(); si != nil {
+			info.Comment = strings.TrimSpace(si.GetLeadingComments())
+			if info.Comment == "" {
+				info.Comment = strings.TrimSpace(si.GetTrailingComments())
+			}
+
+			info.Comment = strings.Join(strings.Fields(info.Comment), " ")
+		}
+
+		fields = append(fields, info)
+
+		msgField := fd
+		if fd.IsMap() {
+			msgField = fd.GetMapValueType()
+			path = path + ".*"
+		} else if fd.IsRepeated() {
+			path = path + "[]"
+		}
+
+		if nested := msgField.GetMessageType(); nested != nil && !isWellKnown(nested) {
+			fields = append(fields, describeFields(nested, path+".", seen)...)
+		}
+	}
+
+	return fields
+}
+
+func fieldTypeName(fd *desc.FieldDescriptor) string {
+	if fd.IsMap() {
+		return "map<" + scalarTypeName(fd.GetMapKeyType()) + ", " + scalarTypeName(fd.GetMapValueType()) + ">"
+	}
+
+	if fd.IsRepeated() {
+		return "repeated " + scalarTypeName(fd)
+	}
+
+	return scalarTypeName(fd)
+}
+
+func scalarTypeName(fd *desc.FieldDescriptor) string {
+	if et := fd.GetEnumType(); et != nil {
+		return et.GetFullyQualifiedName()
+	}
+
+	if mt := fd.GetMessageType(); mt != nil {
+		return mt.GetFullyQualifiedName()
+	}
+
+	return strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+}
+
+func isWellKnown(md *desc.MessageDescriptor) bool {
+	return strings.HasPrefix(md.GetFullyQualifiedName(), "google.protobuf.")
+}
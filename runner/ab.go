@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ABTarget is a target of an A/B comparison, a host and optionally the authority of the calls
+type ABTarget struct {
+	// Name is the name of the target, the host and the authority are used if it is empty
+	Name string
+
+	Host      string
+	Authority string
+}
+
+// ABRun is a run of RunAB
+type ABRun struct {
+	Call string
+	A    ABTarget
+	B    ABTarget
+
+	// Rounds is the number of phases run against each of the targets, 1 if not set
+	Rounds uint
+
+	// Pause is the time waited between the phases
+	Pause time.Duration
+
+	// Options are the options of each phase, such as the load and the data of the calls
+	Options []Option
+}
+
+// ABReport holds the reports of the phases of an A/B comparison and their comparison
+type ABReport struct {
+	// Phases are the reports of the phases in the order they were run, named after their target
+	Phases []*Report `json:"phases"`
+
+	// A and B are the results of all the phases of each target
+	A ABResult `json:"a"`
+	B ABResult `json:"b"`
+
+	// the relative changes of the results of B from those of A, such as 0.25
+	// for an average latency 25% higher with B
+	RpsChange     float64 `json:"rpsChange"`
+	AverageChange float64 `json:"averageChange"`
+	P99Change     float64 `json:"p99Change"`
+}
+
+// ABResult holds the results of the phases of a target of an A/B comparison
+type ABResult struct {
+	Name   string `json:"name"`
+	Phases int    `json:"phases"`
+	Count  uint64 `json:"count"`
+
+	// Rps, Average and P99 are the means of the results of the phases,
+	// the average being weighted by the number of calls of each phase
+	Rps     float64       `json:"rps"`
+	Average time.Duration `json:"average"`
+	P99     time.Duration `json:"p99"`
+
+	// the percentage of the calls of all the phases which failed
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// RunAB runs identical load phases alternating between the two targets, so the results of both
+// are affected alike by the changes of the environment over the test. The phases of each round
+// are run in the reverse order of the previous round, A B B A and so on, and the results of the
+// phases of each target are compared. The reports made before an error are returned along with the error.
+//
+//	report, err := runner.RunAB(runner.ABRun{
+//		Call:    "helloworld.Greeter.SayHello",
+//		A:       runner.ABTarget{Host: "blue.internal:50051"},
+//		B:       runner.ABTarget{Host: "green.internal:50051"},
+//		Rounds:  3,
+//		Options: []runner.Option{runner.WithRunDuration(time.Minute), runner.WithRPS(200)},
+//	})
+func RunAB(r ABRun) (*ABReport, error) {
+	a, b := abName(r.A), abName(r.B)
+	if r.A.Host == r.B.Host && r.A.Authority == r.B.Authority {
+		return nil, errors.New("the targets of an A/B comparison must differ in the host or the authority")
+	}
+
+	if a == b {
+		return nil, fmt.Errorf("the targets of an A/B comparison must have different names: %s", a)
+	}
+
+	rounds := int(r.Rounds)
+	if rounds == 0 {
+		rounds = 1
+	}
+
+	report := &ABReport{}
+
+	for i := 0; i < rounds; i++ {
+		targets := []ABTarget{r.A, r.B}
+		if i%2 == 1 {
+			targets = []ABTarget{r.B, r.A}
+		}
+
+		for j, t := range targets {
+			if r.Pause > 0 && (i > 0 || j > 0) {
+				time.Sleep(r.Pause)
+			}
+
+			rep, err := runABPhase(r, t)
+			if rep != nil {
+				report.Phases = append(report.Phases, rep)
+			}
+
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	report.A = abResult(a, report.Phases)
+	report.B = abResult(b, report.Phases)
+
+	report.RpsChange = relativeChange(report.A.Rps, report.B.Rps)
+	report.AverageChange = relativeChange(float64(report.A.Average), float64(report.B.Average))
+	report.P99Change = relativeChange(float64(report.A.P99), float64(report.B.P99))
+
+	return report, nil
+}
+
+// runABPhase makes a phase of the run against the target, naming the report after the target
+func runABPhase(r ABRun, t ABTarget) (*Report, error) {
+	options := r.Options
+	if t.Authority != "" {
+		options = append(options[:len(options):len(options)], WithAuthority(t.Authority))
+	}
+
+	// the thresholds of each phase are checked using its report
+	rep, err := Run(r.Call, t.Host, options...)
+	if errors.Is(err, ErrThresholdsFailed) {
+		err = nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%s: %v", abName(t), err)
+	}
+
+	if rep != nil {
+		rep.Name = abName(t)
+	}
+
+	return rep, err
+}
+
+func abName(t ABTarget) string {
+	if t.Name != "" {
+		return t.Name
+	}
+
+	if t.Authority != "" {
+		return t.Host + " (" + t.Authority + ")"
+	}
+
+	return t.Host
+}
+
+// abResult combines the results of the phases of the target with the name
+func abResult(name string, phases []*Report) ABResult {
+	res := ABResult{Name: name}
+
+	var rps float64
+	var latency, p99 time.Duration
+	errs := 0
+
+	for _, rep := range phases {
+		if rep.Name != name {
+			continue
+		}
+
+		res.Phases++
+		res.Count += rep.Count
+		rps += rep.Rps
+		latency += rep.Average * time.Duration(rep.Count)
+		p99 += reportP99(rep)
+		for _, n := range rep.ErrorDist {
+			errs += n
+		}
+	}
+
+	if res.Phases > 0 {
+		res.Rps = rps / float64(res.Phases)
+		res.P99 = p99 / time.Duration(res.Phases)
+	}
+
+	if res.Count > 0 {
+		res.Average = latency / time.Duration(res.Count)
+		res.ErrorRate = float64(errs) / float64(res.Count) * 100
+	}
+
+	return res
+}
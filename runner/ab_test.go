@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestABResult(t *testing.T) {
+	phases := []*Report{
+		{
+			Name:                "a",
+			Count:               100,
+			Rps:                 200,
+			Average:             10 * time.Millisecond,
+			LatencyDistribution: []LatencyDistribution{{Percentage: 99, Latency: 20 * time.Millisecond}},
+			ErrorDist:           map[string]int{},
+		},
+		{
+			Name:                "b",
+			Count:               100,
+			Rps:                 150,
+			Average:             15 * time.Millisecond,
+			LatencyDistribution: []LatencyDistribution{{Percentage: 99, Latency: 40 * time.Millisecond}},
+			ErrorDist:           map[string]int{"rpc error: code = Unavailable": 5},
+		},
+		{
+			Name:                "a",
+			Count:               300,
+			Rps:                 100,
+			Average:             30 * time.Millisecond,
+			LatencyDistribution: []LatencyDistribution{{Percentage: 99, Latency: 60 * time.Millisecond}},
+			ErrorDist:           map[string]int{"rpc error: code = Internal": 4},
+		},
+	}
+
+	assert.Equal(t, ABResult{
+		Name:      "a",
+		Phases:    2,
+		Count:     400,
+		Rps:       150,
+		Average:   25 * time.Millisecond,
+		P99:       40 * time.Millisecond,
+		ErrorRate: 1,
+	}, abResult("a", phases))
+
+	assert.Equal(t, ABResult{Name: "c"}, abResult("c", phases))
+}
+
+func TestRunAB(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	run := ABRun{
+		Call: "helloworld.Greeter.SayHello",
+		A:    ABTarget{Host: internal.TestLocalhost},
+		B:    ABTarget{Name: "green", Host: internal.TestLocalhost, Authority: "green.example.com"},
+		Options: []Option{
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		},
+	}
+
+	t.Run("alternating phases", func(t *testing.T) {
+		gs.ResetCounters()
+
+		r := run
+		r.Rounds = 3
+
+		report, err := RunAB(r)
+
+		assert.NoError(t, err)
+
+		names := make([]string, len(report.Phases))
+		for i, rep := range report.Phases {
+			names[i] = rep.Name
+		}
+
+		a := internal.TestLocalhost
+		assert.Equal(t, []string{a, "green", "green", a, a, "green"}, names)
+		assert.Equal(t, "green.example.com", report.Phases[1].Options.Authority)
+
+		assert.Equal(t, a, report.A.Name)
+		assert.Equal(t, 3, report.A.Phases)
+		assert.Equal(t, uint64(30), report.A.Count)
+		assert.Equal(t, "green", report.B.Name)
+		assert.Equal(t, 3, report.B.Phases)
+		assert.Equal(t, uint64(30), report.B.Count)
+
+		assert.Equal(t, 60, gs.GetCount(helloworld.Unary))
+	})
+
+	t.Run("invalid call", func(t *testing.T) {
+		r := run
+		r.Call = "helloworld.Greeter.Missing"
+
+		report, err := RunAB(r)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), internal.TestLocalhost+": ")
+		assert.Empty(t, report.Phases)
+	})
+
+	t.Run("same targets", func(t *testing.T) {
+		r := run
+		r.B = r.A
+
+		_, err := RunAB(r)
+
+		assert.EqualError(t, err, "the targets of an A/B comparison must differ in the host or the authority")
+	})
+
+	t.Run("same names", func(t *testing.T) {
+		r := run
+		r.A.Name = "green"
+
+		_, err := RunAB(r)
+
+		assert.EqualError(t, err, "the targets of an A/B comparison must have different names: green")
+	})
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// affinitySupported is whether the threads can be pinned to CPUs
+const affinitySupported = true
+
+// setThreadAffinity sets the affinity of the calling thread to the CPUs
+func setThreadAffinity(cpus []int) error {
+	var mask [maxAffinityCPU / 64]uint64
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+
+	// the pid 0 is the calling thread
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
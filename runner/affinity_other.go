@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package runner
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// affinitySupported is whether the threads can be pinned to CPUs
+const affinitySupported = false
+
+// setThreadAffinity is not supported outside of Linux
+func setThreadAffinity(cpus []int) error {
+	return fmt.Errorf("CPU affinity is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,302 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The states of the alert events
+const (
+	// AlertBreached is the state of an alert whose threshold failed over the rolling window
+	AlertBreached = "breached"
+
+	// AlertResolved is the state of a breached alert whose threshold passed again
+	AlertResolved = "resolved"
+)
+
+// The defaults of the rolling window of the alerts, and of the cooldown between the events of an alert
+const (
+	defaultAlertWindow   = 30 * time.Second
+	defaultAlertCooldown = 5 * time.Minute
+)
+
+// alertCheckInterval is the interval the alerts are checked at
+const alertCheckInterval = time.Second
+
+// maxAlertEvents is the maximum number of alert events kept for the report
+const maxAlertEvents = 1000
+
+// AlertEvent is an event of an alert, whose threshold either failed or passed again over the rolling
+// window of the calls completed most recently. The actual value of a latency is in milliseconds.
+type AlertEvent struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"runId,omitempty"`
+	Name  string    `json:"name,omitempty"`
+
+	// Alert is the threshold of the alert, and State either breached or resolved
+	Alert  string  `json:"alert"`
+	State  string  `json:"state"`
+	Actual float64 `json:"actual"`
+
+	// Window is the duration of the rolling window, and Count the number of calls within it
+	Window time.Duration `json:"window"`
+	Count  uint64        `json:"count"`
+}
+
+// AlertFunc is called with the events of the alerts while the run is in progress
+type AlertFunc func(event *AlertEvent)
+
+// alert is a threshold checked over the rolling window
+type alert struct {
+	metric    Metric
+	threshold Threshold
+
+	// whether the threshold failed, and the time of the last event
+	breached bool
+	last     time.Time
+}
+
+// alertSample is a call completed within the rolling window
+type alertSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// alertMonitor checks the thresholds of the alerts over the rolling window of the calls while
+// the run is in progress, emitting an event whenever an alert is breached, repeated once the
+// cooldown has passed while it is still breached, and once it is resolved
+type alertMonitor struct {
+	alerts   []*alert
+	window   time.Duration
+	cooldown time.Duration
+
+	runID, name string
+
+	sink    AlertFunc
+	webhook string
+	client  *http.Client
+	log     Logger
+
+	lock    sync.Mutex
+	samples []alertSample
+	events  []AlertEvent
+}
+
+func newAlertMonitor(c *RunConfig) *alertMonitor {
+	if len(c.alerts) == 0 {
+		return nil
+	}
+
+	m := &alertMonitor{
+		window:   c.alertWindow,
+		cooldown: c.alertCooldown,
+		runID:    c.runID,
+		name:     c.name,
+		sink:     c.alertFunc,
+		webhook:  c.alertWebhook,
+		client:   &http.Client{Timeout: pushTimeout},
+	}
+
+	if m.window <= 0 {
+		m.window = defaultAlertWindow
+	}
+
+	if m.cooldown <= 0 {
+		m.cooldown = defaultAlertCooldown
+	}
+
+	for _, metric := range thresholdMetrics {
+		if t, ok := c.alerts[metric]; ok {
+			m.alerts = append(m.alerts, &alert{metric: metric, threshold: t})
+		}
+	}
+
+	if c.hasLog {
+		m.log = c.log
+	}
+
+	return m
+}
+
+// observe records a completed call within the rolling window
+func (m *alertMonitor) observe(status string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	m.samples = append(m.samples, alertSample{at: time.Now(), latency: latency, failed: status != "OK"})
+	m.lock.Unlock()
+}
+
+// begin starts checking the alerts on every interval of the run, returning the function which
+// stops the checks, and returns the events of the alerts
+func (m *alertMonitor) begin(start time.Time) func() []AlertEvent {
+	if m == nil {
+		return func() []AlertEvent { return nil }
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(alertCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				m.check(start, now)
+			}
+		}
+	}()
+
+	return func() []AlertEvent {
+		close(done)
+		<-stopped
+
+		return m.events
+	}
+}
+
+// check checks the alerts over the calls completed within the rolling window. The rate is
+// only checked once a whole window has passed, so it is not breached at the start of the run.
+func (m *alertMonitor) check(start, now time.Time) {
+	w := m.windowReport(now)
+
+	for _, a := range m.alerts {
+		if a.metric == MetricRPS && now.Sub(start) < m.window {
+			continue
+		}
+
+		if a.metric != MetricRPS && w.Count == 0 {
+			continue
+		}
+
+		res := a.threshold.Check(a.metric, w)
+
+		switch {
+		case !res.Pass && (!a.breached || now.Sub(a.last) >= m.cooldown):
+			a.breached = true
+			a.last = now
+			m.emit(a, AlertBreached, res.Actual, w.Count, now)
+		case res.Pass && a.breached:
+			a.breached = false
+			a.last = now
+			m.emit(a, AlertResolved, res.Actual, w.Count, now)
+		}
+	}
+}
+
+// windowReport drops the calls older than the rolling window, returning the report
+// of the metrics of the remaining calls which the thresholds are checked against
+func (m *alertMonitor) windowReport(now time.Time) *Report {
+	m.lock.Lock()
+	from := now.Add(-m.window)
+
+	i := sort.Search(len(m.samples), func(i int) bool { return !m.samples[i].at.Before(from) })
+	m.samples = append(m.samples[:0], m.samples[i:]...)
+
+	lats := make([]float64, len(m.samples))
+	errs := 0
+	for i, s := range m.samples {
+		lats[i] = s.latency.Seconds()
+		if s.failed {
+			errs++
+		}
+	}
+	m.lock.Unlock()
+
+	w := &Report{Count: uint64(len(lats)), Rps: float64(len(lats)) / m.window.Seconds()}
+	if errs > 0 {
+		w.ErrorDist = map[string]int{"errors": errs}
+	}
+
+	if len(lats) == 0 {
+		return w
+	}
+
+	sort.Float64s(lats)
+
+	var sum float64
+	for _, l := range lats {
+		sum += l
+	}
+
+	w.Average = time.Duration(sum / float64(len(lats)) * float64(time.Second))
+	w.Fastest = time.Duration(lats[0] * float64(time.Second))
+	w.Slowest = time.Duration(lats[len(lats)-1] * float64(time.Second))
+	w.LatencyDistribution = latencies(lats)
+
+	return w
+}
+
+// emit records the event of the alert and sends it to the sink and the webhook, if any.
+// The failed webhook requests are logged and do not affect the run.
+func (m *alertMonitor) emit(a *alert, state string, actual float64, count uint64, now time.Time) {
+	e := AlertEvent{
+		Time:   now,
+		RunID:  m.runID,
+		Name:   m.name,
+		Alert:  a.threshold.String(a.metric),
+		State:  state,
+		Actual: actual,
+		Window: m.window,
+		Count:  count,
+	}
+
+	if len(m.events) < maxAlertEvents {
+		m.events = append(m.events, e)
+	}
+
+	if m.log != nil {
+		m.log.Debugw("Alert "+state+": "+e.Alert, "actual", actual, "window", m.window, "count", count)
+	}
+
+	if m.sink != nil {
+		m.sink(&e)
+	}
+
+	if m.webhook != "" {
+		if err := m.post(&e); err != nil && m.log != nil {
+			m.log.Errorw("Error posting the alert event: "+err.Error(), "webhook", m.webhook, "error", err)
+		}
+	}
+}
+
+// post posts the event as JSON to the webhook
+func (m *alertMonitor) post(e *AlertEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.client.Post(m.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertMonitor(t *testing.T) {
+	t.Run("no alerts", func(t *testing.T) {
+		m := newAlertMonitor(&RunConfig{})
+		assert.Nil(t, m)
+
+		m.observe("OK", time.Millisecond)
+		assert.Nil(t, m.begin(time.Now())())
+	})
+
+	t.Run("window report", func(t *testing.T) {
+		m := &alertMonitor{window: 10 * time.Second}
+
+		now := time.Now()
+		m.samples = []alertSample{
+			{at: now.Add(-20 * time.Second), latency: time.Second},
+			{at: now.Add(-5 * time.Second), latency: 10 * time.Millisecond},
+			{at: now.Add(-4 * time.Second), latency: 30 * time.Millisecond, failed: true},
+			{at: now.Add(-3 * time.Second), latency: 20 * time.Millisecond},
+			{at: now.Add(-2 * time.Second), latency: 40 * time.Millisecond},
+		}
+
+		w := m.windowReport(now)
+
+		assert.Len(t, m.samples, 4)
+		assert.Equal(t, uint64(4), w.Count)
+		assert.Equal(t, 0.4, w.Rps)
+		assert.Equal(t, map[string]int{"errors": 1}, w.ErrorDist)
+		assert.Equal(t, 10*time.Millisecond, w.Fastest)
+		assert.Equal(t, 40*time.Millisecond, w.Slowest)
+		assert.Equal(t, 25*time.Millisecond, w.Average)
+
+		w = m.windowReport(now.Add(time.Minute))
+		assert.Empty(t, m.samples)
+		assert.Equal(t, uint64(0), w.Count)
+	})
+
+	t.Run("breached, repeated and resolved", func(t *testing.T) {
+		var events []AlertEvent
+
+		_, p99, err := ParseThreshold("p99<100ms")
+		assert.NoError(t, err)
+		_, rps, err := ParseThreshold("rps>=100")
+		assert.NoError(t, err)
+
+		m := newAlertMonitor(&RunConfig{
+			runID:         "run1",
+			alerts:        map[Metric]Threshold{MetricP99: p99, MetricRPS: rps},
+			alertWindow:   10 * time.Second,
+			alertCooldown: time.Minute,
+			alertFunc:     func(e *AlertEvent) { events = append(events, *e) },
+		})
+
+		assert.Equal(t, 10*time.Second, m.window)
+
+		start := time.Now()
+		slow := func(at time.Time) {
+			m.samples = append(m.samples, alertSample{at: at, latency: 200 * time.Millisecond})
+		}
+
+		// the rate is not checked before a whole window has passed
+		slow(start.Add(time.Second))
+		m.check(start, start.Add(2*time.Second))
+
+		assert.Len(t, events, 1)
+		assert.Equal(t, "p99<100ms", events[0].Alert)
+		assert.Equal(t, AlertBreached, events[0].State)
+		assert.Equal(t, 200.0, events[0].Actual)
+		assert.Equal(t, "run1", events[0].RunID)
+		assert.Equal(t, uint64(1), events[0].Count)
+
+		// the events are not repeated before the cooldown
+		slow(start.Add(3 * time.Second))
+		m.check(start, start.Add(4*time.Second))
+		assert.Len(t, events, 1)
+
+		slow(start.Add(69 * time.Second))
+		m.check(start, start.Add(70*time.Second))
+
+		assert.Len(t, events, 3)
+		assert.Equal(t, "p99<100ms", events[1].Alert)
+		assert.Equal(t, AlertBreached, events[1].State)
+		assert.Equal(t, "rps>=100", events[2].Alert)
+		assert.Equal(t, AlertBreached, events[2].State)
+
+		m.samples = append(m.samples, alertSample{at: start.Add(71 * time.Second), latency: time.Millisecond})
+		m.check(start, start.Add(85*time.Second))
+
+		// the empty window is not checked apart from the rate
+		assert.Len(t, events, 3)
+
+		m.samples = append(m.samples, alertSample{at: start.Add(86 * time.Second), latency: time.Millisecond})
+		m.check(start, start.Add(87*time.Second))
+
+		assert.Len(t, events, 4)
+		assert.Equal(t, "p99<100ms", events[3].Alert)
+		assert.Equal(t, AlertResolved, events[3].State)
+		assert.Equal(t, m.events, events)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		rec := &pushRecorder{}
+		ts := httptest.NewServer(rec)
+		defer ts.Close()
+
+		_, errs, err := ParseThreshold("error-rate<10%")
+		assert.NoError(t, err)
+
+		m := newAlertMonitor(&RunConfig{
+			name:         "smoke",
+			alerts:       map[Metric]Threshold{MetricErrorRate: errs},
+			alertWebhook: ts.URL,
+		})
+
+		assert.Equal(t, defaultAlertWindow, m.window)
+		assert.Equal(t, defaultAlertCooldown, m.cooldown)
+
+		m.observe("OK", time.Millisecond)
+		m.observe("Unavailable", time.Millisecond)
+
+		start := time.Now()
+		m.check(start, start.Add(time.Second))
+
+		rec.lock.Lock()
+		defer rec.lock.Unlock()
+
+		assert.Len(t, rec.bodies, 1)
+
+		var e AlertEvent
+		assert.NoError(t, json.Unmarshal([]byte(rec.bodies[0]), &e))
+		assert.Equal(t, "error-rate<10%", e.Alert)
+		assert.Equal(t, AlertBreached, e.State)
+		assert.Equal(t, 50.0, e.Actual)
+		assert.Equal(t, "smoke", e.Name)
+		assert.Equal(t, "application/json", rec.headers[0].Get("Content-Type"))
+	})
+}
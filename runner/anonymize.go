@@ -0,0 +1,258 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// anonymizer redacts and tokenizes the fields of the call data before it is sent,
+// so that captured production data can be replayed without leaking its contents.
+type anonymizer struct {
+	redact   [][]string
+	tokenize [][]string
+
+	// the key of the HMAC of the tokens, so the tokens cannot be reversed by hashing guesses
+	key []byte
+}
+
+// newAnonymizer creates the anonymizer of the field paths of the message. The tokens of the
+// same value are the same for the same key, and a random key is used for the run if none is set.
+func newAnonymizer(md *desc.MessageDescriptor, redact, tokenize []string, key string) (*anonymizer, error) {
+	a := &anonymizer{key: []byte(key)}
+
+	for _, path := range redact {
+		if _, err := findAnonymizedField(md, path); err != nil {
+			return nil, err
+		}
+
+		a.redact = append(a.redact, strings.Split(path, "."))
+	}
+
+	for _, path := range tokenize {
+		fd, err := findAnonymizedField(md, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if fd.IsMap() || !tokenizable(fd) {
+			return nil, fmt.Errorf("field %q of message %s cannot be tokenized: expected a string, bytes or integer field",
+				path, md.GetFullyQualifiedName())
+		}
+
+		a.tokenize = append(a.tokenize, strings.Split(path, "."))
+	}
+
+	if len(a.key) == 0 {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// findAnonymizedField returns the descriptor of the last field of the dot separated field path
+// of the message. The repeated message fields of the path have each of their messages anonymized.
+func findAnonymizedField(md *desc.MessageDescriptor, path string) (*desc.FieldDescriptor, error) {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(name)
+		}
+
+		if fd == nil {
+			return nil, fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+		}
+
+		if i == len(parts)-1 {
+			return fd, nil
+		}
+
+		if fd.GetMessageType() == nil || fd.IsMap() {
+			return nil, fmt.Errorf("field %q of message %s is not a message", name, md.GetFullyQualifiedName())
+		}
+
+		md = fd.GetMessageType()
+	}
+
+	return nil, fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+}
+
+func tokenizable(fd *desc.FieldDescriptor) bool {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32, descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return true
+	}
+
+	return false
+}
+
+// wrap returns the data provider anonymizing the messages of the data provider
+func (a *anonymizer) wrap(dp DataProviderFunc) DataProviderFunc {
+	return func(ctd *CallData) ([]*dynamic.Message, error) {
+		inputs, err := dp(ctd)
+		if err != nil {
+			return inputs, err
+		}
+
+		res := make([]*dynamic.Message, len(inputs))
+		for i, input := range inputs {
+			if res[i], err = a.anonymize(input); err != nil {
+				return nil, fmt.Errorf("anonymizing the call data: %v", err)
+			}
+		}
+
+		return res, nil
+	}
+}
+
+// anonymize returns the copy of the message with its fields redacted and tokenized.
+// The message is copied since the data providers may share it between the calls.
+func (a *anonymizer) anonymize(msg *dynamic.Message) (*dynamic.Message, error) {
+	b, err := msg.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	res := dynamic.NewMessage(msg.GetMessageDescriptor())
+	if err := res.Unmarshal(b); err != nil {
+		return nil, err
+	}
+
+	for _, path := range a.redact {
+		if err := a.apply(res, path, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range a.tokenize {
+		if err := a.apply(res, path, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// apply redacts or tokenizes the field path of the message, and of each message of the repeated fields
+func (a *anonymizer) apply(msg *dynamic.Message, path []string, tokenize bool) error {
+	md := msg.GetMessageDescriptor()
+
+	fd := md.FindFieldByName(path[0])
+	if fd == nil {
+		fd = md.FindFieldByJSONName(path[0])
+	}
+
+	if fd == nil || !msg.HasField(fd) {
+		return nil
+	}
+
+	if len(path) == 1 {
+		if !tokenize {
+			msg.ClearField(fd)
+			return nil
+		}
+
+		v := msg.GetField(fd)
+		if values, ok := v.([]interface{}); ok {
+			tokens := make([]interface{}, len(values))
+			for i, value := range values {
+				tokens[i] = a.token(value)
+			}
+
+			return msg.TrySetField(fd, tokens)
+		}
+
+		return msg.TrySetField(fd, a.token(v))
+	}
+
+	v := msg.GetField(fd)
+	if values, ok := v.([]interface{}); ok {
+		for _, value := range values {
+			if m, ok := value.(*dynamic.Message); ok {
+				if err := a.apply(m, path[1:], tokenize); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if m, ok := v.(*dynamic.Message); ok {
+		return a.apply(m, path[1:], tokenize)
+	}
+
+	return nil
+}
+
+// token returns the token of the value, being of the same type. The zero values are kept,
+// so the fields which are not set are not sent. Strings are replaced by the hex of the
+// first 8 bytes of the HMAC of the value, bytes by its first 16 bytes, and integers by
+// an integer derived from it.
+func (a *anonymizer) token(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		if value == "" {
+			return value
+		}
+
+		return hex.EncodeToString(a.hash([]byte(value))[:8])
+	case []byte:
+		if len(value) == 0 {
+			return value
+		}
+
+		return a.hash(value)[:16]
+	case int32:
+		if value == 0 {
+			return value
+		}
+
+		return int32(binary.BigEndian.Uint32(a.hash([]byte(strconv.FormatInt(int64(value), 10)))))
+	case int64:
+		if value == 0 {
+			return value
+		}
+
+		return int64(binary.BigEndian.Uint64(a.hash([]byte(strconv.FormatInt(value, 10)))))
+	case uint32:
+		if value == 0 {
+			return value
+		}
+
+		return binary.BigEndian.Uint32(a.hash([]byte(strconv.FormatUint(uint64(value), 10))))
+	case uint64:
+		if value == 0 {
+			return value
+		}
+
+		return binary.BigEndian.Uint64(a.hash([]byte(strconv.FormatUint(value, 10))))
+	}
+
+	return v
+}
+
+func (a *anonymizer) hash(b []byte) []byte {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write(b)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizer(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/Query", "../testdata/fields.proto", []string{})
+	assert.NoError(t, err)
+
+	md := mtd.GetOutputType()
+
+	newReply := func() *dynamic.Message {
+		stats := dynamic.NewMessage(md.FindFieldByName("stats").GetMessageType())
+		stats.SetFieldByName("queue_depth", int32(5))
+		stats.SetFieldByName("load", 0.5)
+
+		msg := dynamic.NewMessage(md)
+		msg.SetFieldByName("results", []string{"alice", "bob", "alice"})
+		msg.SetFieldByName("result_count", uint64(3))
+		msg.SetFieldByName("server", "db-1.prod")
+		msg.SetFieldByName("stats", stats)
+
+		return msg
+	}
+
+	t.Run("invalid paths", func(t *testing.T) {
+		_, err := newAnonymizer(md, []string{"missing"}, nil, "")
+		assert.EqualError(t, err, `field "missing" not found in message fields.QueryReply`)
+
+		_, err = newAnonymizer(md, []string{"server.name"}, nil, "")
+		assert.EqualError(t, err, `field "server" of message fields.QueryReply is not a message`)
+
+		_, err = newAnonymizer(md, nil, []string{"stats.load"}, "")
+		assert.EqualError(t, err, `field "stats.load" of message fields.QueryReply cannot be tokenized: expected a string, bytes or integer field`)
+
+		_, err = newAnonymizer(md, nil, []string{"source"}, "")
+		assert.EqualError(t, err, `field "source" of message fields.QueryReply cannot be tokenized: expected a string, bytes or integer field`)
+
+		a, err := newAnonymizer(md, []string{"stats"}, []string{"stats.queueDepth"}, "")
+		assert.NoError(t, err)
+		assert.Len(t, a.key, 32)
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		a, err := newAnonymizer(md, []string{"server", "stats.load"}, nil, "key")
+		assert.NoError(t, err)
+
+		msg := newReply()
+		res, err := a.anonymize(msg)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "", res.GetFieldByName("server"))
+		assert.False(t, res.HasFieldName("server"))
+		assert.Equal(t, uint64(3), res.GetFieldByName("result_count"))
+
+		stats := res.GetFieldByName("stats").(*dynamic.Message)
+		assert.Equal(t, int32(5), stats.GetFieldByName("queue_depth"))
+		assert.Equal(t, float64(0), stats.GetFieldByName("load"))
+
+		// the original message is not modified
+		assert.Equal(t, "db-1.prod", msg.GetFieldByName("server"))
+		assert.Equal(t, 0.5, msg.GetFieldByName("stats").(*dynamic.Message).GetFieldByName("load"))
+	})
+
+	t.Run("tokenize", func(t *testing.T) {
+		a, err := newAnonymizer(md, nil, []string{"results", "result_count", "server", "stats.queue_depth"}, "key")
+		assert.NoError(t, err)
+
+		res, err := a.anonymize(newReply())
+		assert.NoError(t, err)
+
+		results := res.GetFieldByName("results").([]interface{})
+		assert.Len(t, results, 3)
+		assert.Len(t, results[0].(string), 16)
+		assert.NotEqual(t, "alice", results[0])
+		assert.NotEqual(t, results[0], results[1])
+		assert.Equal(t, results[0], results[2])
+
+		assert.NotEqual(t, uint64(3), res.GetFieldByName("result_count"))
+		assert.NotEqual(t, "db-1.prod", res.GetFieldByName("server"))
+		assert.NotEqual(t, int32(5), res.GetFieldByName("stats").(*dynamic.Message).GetFieldByName("queue_depth"))
+
+		// the tokens are the same for the same key
+		other, err := newAnonymizer(md, nil, []string{"results"}, "key")
+		assert.NoError(t, err)
+
+		res, err = other.anonymize(newReply())
+		assert.NoError(t, err)
+		assert.Equal(t, results, res.GetFieldByName("results"))
+
+		other, err = newAnonymizer(md, nil, []string{"results"}, "other")
+		assert.NoError(t, err)
+
+		res, err = other.anonymize(newReply())
+		assert.NoError(t, err)
+		assert.NotEqual(t, results, res.GetFieldByName("results"))
+	})
+
+	t.Run("zero values", func(t *testing.T) {
+		a, err := newAnonymizer(md, nil, []string{"server", "result_count", "stats.queue_depth"}, "key")
+		assert.NoError(t, err)
+
+		res, err := a.anonymize(dynamic.NewMessage(md))
+		assert.NoError(t, err)
+		assert.False(t, res.HasFieldName("server"))
+		assert.False(t, res.HasFieldName("result_count"))
+		assert.False(t, res.HasFieldName("stats"))
+	})
+}
+
+func TestRunAnonymizedData(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	gs.ResetCounters()
+
+	data := []interface{}{
+		map[string]interface{}{"name": "alice"},
+		map[string]interface{}{"name": "bob"},
+	}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(4),
+		WithConcurrency(1),
+		WithTimeout(time.Duration(20*time.Second)),
+		WithDialTimeout(time.Duration(20*time.Second)),
+		WithData(data),
+		WithDataTokenization("secret", "name"),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+	assert.Equal(t, uint64(4), report.Count)
+	assert.Equal(t, []string{"name"}, report.Options.DataTokenize)
+	assert.Nil(t, report.Options.Data)
+
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	a, err := newAnonymizer(mtd.GetInputType(), nil, []string{"name"}, "secret")
+	assert.NoError(t, err)
+
+	calls := gs.GetCalls(helloworld.Unary)
+	assert.Len(t, calls, 4)
+
+	names := make([]string, len(calls))
+	for i, c := range calls {
+		assert.Len(t, c, 1)
+		names[i] = c[0].GetName()
+	}
+
+	assert.Equal(t, []string{
+		a.token("alice").(string),
+		a.token("bob").(string),
+		a.token("alice").(string),
+		a.token("bob").(string),
+	}, names)
+
+	gs.ResetCounters()
+
+	report, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(2),
+		WithConcurrency(1),
+		WithTimeout(time.Duration(20*time.Second)),
+		WithDialTimeout(time.Duration(20*time.Second)),
+		WithData(data),
+		WithDataRedaction("name"),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), report.Count)
+
+	for _, c := range gs.GetCalls(helloworld.Unary) {
+		assert.Equal(t, "", c[0].GetName())
+	}
+}
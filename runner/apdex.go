@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"time"
+)
+
+// Apdex is the Application Performance Index of the calls for a target latency threshold T.
+// The calls within T are satisfied, the calls within 4T are tolerating and the slower or
+// failed calls are frustrated. The score is the ratio of the satisfied calls plus half the
+// tolerating calls to all the calls, from 0 to 1.
+type Apdex struct {
+	Threshold  time.Duration `json:"threshold"`
+	Score      float64       `json:"score"`
+	Satisfied  uint64        `json:"satisfied"`
+	Tolerating uint64        `json:"tolerating"`
+	Frustrated uint64        `json:"frustrated"`
+}
+
+// apdex returns the Apdex of the results for the threshold
+func apdex(details detailIter, threshold time.Duration) *Apdex {
+	a := &Apdex{Threshold: threshold}
+
+	details.each(func(d *ResultDetail) {
+		switch {
+		case d.Error != "" || d.Latency > 4*threshold:
+			a.Frustrated++
+		case d.Latency > threshold:
+			a.Tolerating++
+		default:
+			a.Satisfied++
+		}
+	})
+
+	if total := a.Satisfied + a.Tolerating + a.Frustrated; total > 0 {
+		a.Score = (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(total)
+	}
+
+	return a
+}
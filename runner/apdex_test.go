@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApdex(t *testing.T) {
+	details := []ResultDetail{
+		{Latency: 50 * time.Millisecond},
+		{Latency: 100 * time.Millisecond},
+		{Latency: 150 * time.Millisecond},
+		{Latency: 400 * time.Millisecond},
+		{Latency: 401 * time.Millisecond},
+		{Latency: 10 * time.Millisecond, Error: "rpc error: code = Unavailable"},
+	}
+
+	actual := apdex(detailIter{details: details}, 100*time.Millisecond)
+
+	assert.Equal(t, &Apdex{
+		Threshold:  100 * time.Millisecond,
+		Score:      0.5,
+		Satisfied:  2,
+		Tolerating: 2,
+		Frustrated: 2,
+	}, actual)
+
+	assert.Equal(t, &Apdex{Threshold: time.Second}, apdex(detailIter{}, time.Second))
+}
+
+func TestReporter_FinalizeApdex(t *testing.T) {
+	c, err := NewConfig("call", "localhost:50050",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithApdexThreshold(20*time.Millisecond))
+	assert.NoError(t, err)
+
+	r := newReporter(nil, c)
+	for _, d := range []time.Duration{10, 15, 30, 90} {
+		r.add(&callResult{duration: d * time.Millisecond, status: "OK"})
+	}
+
+	rep := r.Finalize(ReasonNormalEnd, time.Second)
+
+	if assert.NotNil(t, rep.Apdex) {
+		assert.Equal(t, 0.625, rep.Apdex.Score)
+		assert.Equal(t, uint64(1), rep.Apdex.Frustrated)
+	}
+
+	assert.Equal(t, 20*time.Millisecond, rep.Options.ApdexThreshold)
+}
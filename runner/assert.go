@@ -0,0 +1,562 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrAssertionFailed is the error of the calls which completed without an error
+// but whose response or status failed an assertion
+var ErrAssertionFailed = errors.New("assertion failed")
+
+// AssertionFunc is a function asserting the response of each call, returning an error if it fails
+type AssertionFunc func(resp *dynamic.Message) error
+
+// AssertionStats holds the number of calls which passed and failed an assertion
+type AssertionStats struct {
+	// Rule is the rule of the assertion, or the name of the assertion function
+	Rule string `json:"rule"`
+
+	Passed uint64 `json:"passed"`
+	Failed uint64 `json:"failed"`
+}
+
+// namedAssertion is an assertion function along with its name in the report
+type namedAssertion struct {
+	name string
+	fn   AssertionFunc
+}
+
+// assertRule matches the rules comparing an operand, and assertUnaryRule the rules checking one
+var (
+	assertRule      = regexp.MustCompile(`^(\S+?)\s*(==|!=|>=|<=|=~|>|<)\s*(.+)$`)
+	assertUnaryRule = regexp.MustCompile(`^(\S+)\s+(in|exists|empty)(?:\s+(.+))?$`)
+	assertLenRule   = regexp.MustCompile(`^len\((.+)\)$`)
+	assertIndex     = regexp.MustCompile(`^([^\[\]]+)(?:\[(\d+)\])?$`)
+)
+
+// assertPathPart is a part of the path of a response field, with the index of the
+// element of the repeated field, -1 if none
+type assertPathPart struct {
+	name  string
+	index int
+}
+
+// assertion is an assertion of the calls, being a rule on the status code or a response
+// field, or an assertion function
+type assertion struct {
+	rule string
+
+	// the status codes of the status rules
+	status bool
+	codes  []codes.Code
+
+	// the field of the field rules, and whether its length is compared
+	path   []assertPathPart
+	length bool
+	fd     *desc.FieldDescriptor
+
+	op    string
+	value string
+	re    *regexp.Regexp
+
+	fn AssertionFunc
+
+	passed uint64
+	failed uint64
+}
+
+// assertionTracker evaluates the assertions of the calls and counts their results
+type assertionTracker struct {
+	assertions []*assertion
+}
+
+// newAssertionTracker parses the rules of the response type of the method,
+// followed by the assertion functions
+func newAssertionTracker(mtd *desc.MethodDescriptor, rules []string, funcs []namedAssertion) (*assertionTracker, error) {
+	t := &assertionTracker{}
+
+	for _, rule := range rules {
+		a, err := parseAssertion(mtd.GetOutputType(), rule)
+		if err != nil {
+			return nil, err
+		}
+
+		t.assertions = append(t.assertions, a)
+	}
+
+	for _, f := range funcs {
+		t.assertions = append(t.assertions, &assertion{rule: f.name, fn: f.fn})
+	}
+
+	return t, nil
+}
+
+// parseAssertion parses the rule of the response message, which compares the status code or
+// a response field, such as status == OK, $.message != "" or len($.items) > 0
+func parseAssertion(md *desc.MessageDescriptor, rule string) (*assertion, error) {
+	rule = strings.TrimSpace(rule)
+
+	var operand string
+	a := &assertion{rule: rule}
+
+	if m := assertUnaryRule.FindStringSubmatch(rule); m != nil && (m[2] == "in") == (m[3] != "") {
+		operand, a.op, a.value = m[1], m[2], strings.TrimSpace(m[3])
+	} else if m := assertRule.FindStringSubmatch(rule); m != nil {
+		operand, a.op, a.value = m[1], m[2], unquoteAssertValue(strings.TrimSpace(m[3]))
+	} else {
+		return nil, fmt.Errorf("invalid assertion %q: expected <field|status> <op> <value>", rule)
+	}
+
+	if operand == "status" {
+		return a, a.parseStatus()
+	}
+
+	if m := assertLenRule.FindStringSubmatch(operand); m != nil {
+		operand = m[1]
+		a.length = true
+	}
+
+	if err := a.parsePath(md, operand); err != nil {
+		return nil, err
+	}
+
+	if err := a.checkOp(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// parseStatus parses the status codes of the status rule
+func (a *assertion) parseStatus() error {
+	a.status = true
+
+	values := []string{a.value}
+	switch a.op {
+	case "in":
+		values = strings.Split(a.value, ",")
+	case "==", "!=":
+	default:
+		return fmt.Errorf("invalid assertion %q: status can only be compared with ==, != or in", a.rule)
+	}
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+
+		c, ok := parseStatusCode(v)
+		if !ok {
+			return fmt.Errorf("invalid assertion %q: unknown status code %q", a.rule, v)
+		}
+
+		a.codes = append(a.codes, c)
+	}
+
+	return nil
+}
+
+// parseStatusCode parses the name or the number of the status code
+func parseStatusCode(v string) (codes.Code, bool) {
+	if n, err := strconv.ParseUint(v, 10, 32); err == nil && n <= uint64(codes.Unauthenticated) {
+		return codes.Code(n), true
+	}
+
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(c.String(), v) {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+// parsePath parses the path of the response field, which may start with $. and index the
+// elements of repeated fields, such as $.items[0].name
+func (a *assertion) parsePath(md *desc.MessageDescriptor, path string) error {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return fmt.Errorf("invalid assertion %q: missing field", a.rule)
+	}
+
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		m := assertIndex.FindStringSubmatch(p)
+		if m == nil {
+			return fmt.Errorf("invalid assertion %q: invalid field %q", a.rule, p)
+		}
+
+		part := assertPathPart{name: m[1], index: -1}
+		if m[2] != "" {
+			part.index, _ = strconv.Atoi(m[2])
+		}
+
+		fd := md.FindFieldByName(part.name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(part.name)
+		}
+
+		if fd == nil {
+			return fmt.Errorf("invalid assertion %q: field %q not found in message %s", a.rule, part.name, md.GetFullyQualifiedName())
+		}
+
+		if part.index >= 0 && !fd.IsRepeated() {
+			return fmt.Errorf("invalid assertion %q: field %q is not repeated", a.rule, part.name)
+		}
+
+		a.path = append(a.path, part)
+		a.fd = fd
+
+		if i < len(parts)-1 {
+			if fd.GetMessageType() == nil || fd.IsMap() || (fd.IsRepeated() && part.index < 0) {
+				return fmt.Errorf("invalid assertion %q: field %q is not a message", a.rule, part.name)
+			}
+
+			md = fd.GetMessageType()
+		}
+	}
+
+	return nil
+}
+
+// checkOp checks the operator and the value can be compared with the field
+func (a *assertion) checkOp() error {
+	if a.op == "exists" || a.op == "empty" {
+		return nil
+	}
+
+	last := a.path[len(a.path)-1]
+	list := (a.fd.IsRepeated() && last.index < 0) || a.fd.IsMap()
+
+	if a.op == "in" {
+		return fmt.Errorf("invalid assertion %q: in is only supported for the status", a.rule)
+	}
+
+	if a.length {
+		if !list && a.fd.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING &&
+			a.fd.GetType() != descriptor.FieldDescriptorProto_TYPE_BYTES {
+			return fmt.Errorf("invalid assertion %q: len requires a repeated, string or bytes field", a.rule)
+		}
+
+		if _, err := strconv.ParseFloat(a.value, 64); err != nil || a.op == "=~" {
+			return fmt.Errorf("invalid assertion %q: len must be compared with a number", a.rule)
+		}
+
+		return nil
+	}
+
+	if list || a.fd.GetMessageType() != nil {
+		return fmt.Errorf("invalid assertion %q: field %q must be a scalar, or use exists, empty or len", a.rule, last.name)
+	}
+
+	switch a.fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		if a.op == "=~" {
+			re, err := regexp.Compile(a.value)
+			if err != nil {
+				return fmt.Errorf("invalid assertion %q: %v", a.rule, err)
+			}
+
+			a.re = re
+		}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		if _, err := strconv.ParseBool(a.value); err != nil || (a.op != "==" && a.op != "!=") {
+			return fmt.Errorf("invalid assertion %q: bool fields can only be compared with == or != true or false", a.rule)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		_, err := strconv.ParseFloat(a.value, 64)
+		if err != nil && ((a.op != "==" && a.op != "!=") || a.fd.GetEnumType().FindValueByName(a.value) == nil) {
+			return fmt.Errorf("invalid assertion %q: unknown value %q of enum %s", a.rule, a.value, a.fd.GetEnumType().GetName())
+		}
+	default:
+		if _, err := strconv.ParseFloat(a.value, 64); err != nil || a.op == "=~" {
+			return fmt.Errorf("invalid assertion %q: numeric fields must be compared with a number", a.rule)
+		}
+	}
+
+	return nil
+}
+
+// unquoteAssertValue returns the value of a quoted string, or the value if it is not quoted
+func unquoteAssertValue(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		if s, err := strconv.Unquote(`"` + v[1:len(v)-1] + `"`); err == nil {
+			return s
+		}
+
+		return v[1 : len(v)-1]
+	}
+
+	return v
+}
+
+// check evaluates the assertions of a call with the status code and the last response, if any,
+// returning the error of the first failed assertion. The assertions of the response are only
+// evaluated for the calls which received a response.
+func (t *assertionTracker) check(code codes.Code, resp *dynamic.Message) error {
+	var failed error
+
+	for _, a := range t.assertions {
+		var err error
+
+		switch {
+		case a.status:
+			err = a.checkStatus(code)
+		case resp == nil:
+			continue
+		case a.fn != nil:
+			if err = a.fn(resp); err != nil {
+				err = fmt.Errorf("%w: %s: %v", ErrAssertionFailed, a.rule, err)
+			}
+		default:
+			err = a.checkField(resp)
+		}
+
+		if err != nil {
+			atomic.AddUint64(&a.failed, 1)
+
+			if failed == nil {
+				failed = err
+			}
+
+			continue
+		}
+
+		atomic.AddUint64(&a.passed, 1)
+	}
+
+	return failed
+}
+
+func (a *assertion) checkStatus(code codes.Code) error {
+	in := false
+	for _, c := range a.codes {
+		if c == code {
+			in = true
+
+			break
+		}
+	}
+
+	if in == (a.op != "!=") {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrAssertionFailed, a.rule)
+}
+
+func (a *assertion) checkField(msg *dynamic.Message) error {
+	if a.matches(msg) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrAssertionFailed, a.rule)
+}
+
+// matches returns whether the field of the response matches the rule
+func (a *assertion) matches(msg *dynamic.Message) bool {
+	v, set := a.lookup(msg)
+
+	switch a.op {
+	case "exists":
+		return set
+	case "empty":
+		return !set || isEmptyValue(v)
+	}
+
+	if a.length {
+		return compareNumbers(float64(valueLength(v)), a.op, a.value)
+	}
+
+	// the field of an unset message or a missing element
+	if v == nil {
+		return false
+	}
+
+	switch v := v.(type) {
+	case string:
+		return compareStrings(v, a.op, a.value, a.re)
+	case []byte:
+		return compareStrings(string(v), a.op, a.value, a.re)
+	case bool:
+		b, _ := strconv.ParseBool(a.value)
+
+		return (v == b) == (a.op == "==")
+	case int32:
+		if a.fd.GetEnumType() != nil {
+			if ev := a.fd.GetEnumType().FindValueByName(a.value); ev != nil {
+				return (v == ev.GetNumber()) == (a.op == "==")
+			}
+		}
+	}
+
+	return compareNumbers(numericValue(v), a.op, a.value)
+}
+
+// lookup returns the value of the field of the message, and whether it is set.
+// The fields without presence are set unless they have the default value.
+func (a *assertion) lookup(msg *dynamic.Message) (interface{}, bool) {
+	for i, part := range a.path {
+		fd := msg.GetMessageDescriptor().FindFieldByName(part.name)
+		if fd == nil {
+			fd = msg.GetMessageDescriptor().FindFieldByJSONName(part.name)
+		}
+
+		if fd == nil {
+			return nil, false
+		}
+
+		var v interface{}
+		set := msg.HasField(fd)
+
+		if part.index >= 0 {
+			if part.index >= msg.FieldLength(fd) {
+				return nil, false
+			}
+
+			v = msg.GetRepeatedField(fd, part.index)
+		} else {
+			v = msg.GetField(fd)
+		}
+
+		if i == len(a.path)-1 {
+			return v, set
+		}
+
+		pm, ok := v.(proto.Message)
+		if !ok || (part.index < 0 && !set) {
+			return nil, false
+		}
+
+		var err error
+		if msg, err = dynamic.AsDynamicMessage(pm); err != nil || msg == nil {
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// isEmptyValue returns whether the value is empty or the default value
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []byte:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[interface{}]interface{}:
+		return len(v) == 0
+	case bool:
+		return !v
+	case proto.Message:
+		return proto.Size(v) == 0
+	}
+
+	return numericValue(v) == 0
+}
+
+// valueLength returns the length of a repeated, map, string or bytes value
+func valueLength(v interface{}) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case []interface{}:
+		return len(v)
+	case map[interface{}]interface{}:
+		return len(v)
+	}
+
+	return 0
+}
+
+func compareStrings(v, op, value string, re *regexp.Regexp) bool {
+	switch op {
+	case "=~":
+		return re != nil && re.MatchString(v)
+	case "==":
+		return v == value
+	case "!=":
+		return v != value
+	}
+
+	c := bytes.Compare([]byte(v), []byte(value))
+
+	return compareOrder(c, op)
+}
+
+func compareNumbers(v float64, op, value string) bool {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	c := 0
+	if v < n {
+		c = -1
+	} else if v > n {
+		c = 1
+	}
+
+	return compareOrder(c, op)
+}
+
+// compareOrder returns whether the result of the comparison satisfies the operator
+func compareOrder(c int, op string) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+
+	return false
+}
+
+// responses returns whether any of the assertions are of the responses
+func (t *assertionTracker) responses() bool {
+	for _, a := range t.assertions {
+		if !a.status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stats returns the results of the assertions in the order they were specified
+func (t *assertionTracker) stats() []AssertionStats {
+	s := make([]AssertionStats, len(t.assertions))
+	for i, a := range t.assertions {
+		s[i] = AssertionStats{
+			Rule:   a.rule,
+			Passed: atomic.LoadUint64(&a.passed),
+			Failed: atomic.LoadUint64(&a.failed),
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestAssertionTracker(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/Query", "../testdata/fields.proto", []string{})
+	assert.NoError(t, err)
+
+	reply := dynamic.NewMessage(mtd.GetOutputType())
+	reply.SetFieldByName("results", []string{"a", "b"})
+	reply.SetFieldByName("result_count", uint64(2))
+	reply.SetFieldByName("source", int32(2))
+	reply.SetFieldByName("server", "backend-1")
+
+	empty := dynamic.NewMessage(mtd.GetOutputType())
+
+	t.Run("invalid", func(t *testing.T) {
+		var tests = []struct {
+			rule string
+			err  string
+		}{
+			{"status", "expected <field|status> <op> <value>"},
+			{"status == Teapot", `unknown status code "Teapot"`},
+			{"status > OK", "status can only be compared with ==, != or in"},
+			{"$.missing == 1", `field "missing" not found in message fields.QueryReply`},
+			{"$.stats == 1", `field "stats" must be a scalar`},
+			{"$.results == a", `field "results" must be a scalar`},
+			{"$.server[0] == a", `field "server" is not repeated`},
+			{"$.resultCount == many", "numeric fields must be compared with a number"},
+			{"$.partial > true", "bool fields can only be compared with == or != true or false"},
+			{"$.source == SOURCE_FILE", `unknown value "SOURCE_FILE" of enum Source`},
+			{"len($.partial) > 0", "len requires a repeated, string or bytes field"},
+			{"$.server =~ [", "error parsing regexp"},
+		}
+
+		for _, tt := range tests {
+			_, err := newAssertionTracker(mtd, []string{tt.rule}, nil)
+			if assert.Error(t, err, tt.rule) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		}
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		var tests = []struct {
+			rule  string
+			code  codes.Code
+			resp  *dynamic.Message
+			match bool
+		}{
+			{"status == OK", codes.OK, nil, true},
+			{"status != ok", codes.OK, nil, false},
+			{"status in OK, NotFound", codes.NotFound, nil, true},
+			{"status in 0,5", codes.Unavailable, nil, false},
+			{"$.result_count > 1", codes.OK, reply, true},
+			{"$.resultCount<=1", codes.OK, reply, false},
+			{`$.server == "backend-1"`, codes.OK, reply, true},
+			{`$.server =~ "^backend-\d+$"`, codes.OK, reply, true},
+			{"$.server != backend-1", codes.OK, reply, false},
+			{"$.source == SOURCE_DATABASE", codes.OK, reply, true},
+			{"$.source != 2", codes.OK, reply, false},
+			{"$.partial == false", codes.OK, reply, true},
+			{"len($.results) == 2", codes.OK, reply, true},
+			{"$.results[1] == b", codes.OK, reply, true},
+			{"$.results[2] == b", codes.OK, reply, false},
+			{"$.server exists", codes.OK, reply, true},
+			{"$.server exists", codes.OK, empty, false},
+			{"$.results empty", codes.OK, empty, true},
+			{"$.stats exists", codes.OK, reply, false},
+			{"$.stats.queue_depth == 0", codes.OK, reply, false},
+		}
+
+		for _, tt := range tests {
+			tracker, err := newAssertionTracker(mtd, []string{tt.rule}, nil)
+			if !assert.NoError(t, err, tt.rule) {
+				continue
+			}
+
+			err = tracker.check(tt.code, tt.resp)
+			if tt.match {
+				assert.NoError(t, err, tt.rule)
+			} else if assert.Error(t, err, tt.rule) {
+				assert.True(t, errors.Is(err, ErrAssertionFailed))
+				assert.Equal(t, "assertion failed: "+tt.rule, err.Error())
+			}
+		}
+	})
+
+	t.Run("counts", func(t *testing.T) {
+		fn := func(resp *dynamic.Message) error {
+			if resp.GetFieldByName("server") == "" {
+				return errors.New("no server")
+			}
+
+			return nil
+		}
+
+		tracker, err := newAssertionTracker(mtd, []string{"status == OK", "len($.results) > 0"},
+			[]namedAssertion{{name: "has server", fn: fn}})
+		assert.NoError(t, err)
+
+		assert.NoError(t, tracker.check(codes.OK, reply))
+		assert.EqualError(t, tracker.check(codes.OK, empty), "assertion failed: len($.results) > 0")
+
+		// the assertions of the responses are skipped for the calls without a response
+		assert.EqualError(t, tracker.check(codes.Unavailable, nil), "assertion failed: status == OK")
+
+		assert.Equal(t, []AssertionStats{
+			{Rule: "status == OK", Passed: 2, Failed: 1},
+			{Rule: "len($.results) > 0", Passed: 1, Failed: 1},
+			{Rule: "has server", Passed: 1, Failed: 1},
+		}, tracker.stats())
+	})
+}
+
+func TestRunAssertions(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(6),
+		WithConcurrency(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithAssertions("status == OK", `$.message =~ "^Hello "`, "$.message == Hello alice"),
+		WithAssertionFunc("not empty", func(resp *dynamic.Message) error {
+			return nil
+		}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, int(report.Count))
+	assert.Equal(t, map[string]int{"OK": 6}, report.StatusCodeDist)
+	assert.Equal(t, map[string]int{"assertion failed: $.message == Hello alice": 6}, report.ErrorDist)
+	assert.Equal(t, []string{"status == OK", `$.message =~ "^Hello "`, "$.message == Hello alice"}, report.Options.Assertions)
+	assert.Equal(t, []AssertionStats{
+		{Rule: "status == OK", Passed: 6},
+		{Rule: `$.message =~ "^Hello "`, Passed: 6},
+		{Rule: "$.message == Hello alice", Failed: 6},
+		{Rule: "not empty", Passed: 6},
+	}, report.Assertions)
+}
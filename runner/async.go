@@ -0,0 +1,302 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+	"go.uber.org/multierr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// asyncQueueSize is the capacity of the response queue of each worker
+// using response handlers
+const asyncQueueSize = 1000
+
+// AsyncQueueStats holds the statistics of the queues of async unary calls made using
+// separate sender and response handler pools. A response queue that is often full
+// means the handlers can't keep up and are slowing down the senders.
+type AsyncQueueStats struct {
+	// Senders is the number of sender goroutines of each worker
+	Senders uint `json:"senders"`
+
+	// Handlers is the number of response handler goroutines of each worker
+	Handlers uint `json:"handlers"`
+
+	// MaxInFlight is the highest number of calls in flight across all workers
+	MaxInFlight uint64 `json:"maxInFlight"`
+
+	// AverageQueued is the average number of responses waiting to be handled
+	// across all workers, sampled as the responses are queued
+	AverageQueued float64 `json:"averageQueued"`
+
+	// MaxQueued is the highest number of responses waiting to be handled across all workers
+	MaxQueued uint64 `json:"maxQueued"`
+
+	// Blocked is the number of responses queued while the response queue of the
+	// worker was full, blocking the sender until a handler was available
+	Blocked uint64 `json:"blocked"`
+}
+
+// asyncResponse is the result of an async unary call waiting to be handled
+type asyncResponse struct {
+	ctd     *CallData
+	start   time.Time
+	latency time.Duration
+	reqMD   *metadata.MD
+	req     *dynamic.Message
+	res     rawResponse
+	err     error
+}
+
+// rawResponse is the undecoded response of an async unary call
+type rawResponse []byte
+
+// rawCodec encodes the requests of async unary calls and leaves the responses
+// undecoded, so that the decoding is done by the response handlers
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(*dynamic.Message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected request type %T", v)
+	}
+
+	return m.Marshal()
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	r, ok := v.(*rawResponse)
+	if !ok {
+		return fmt.Errorf("unexpected response type %T", v)
+	}
+
+	// the buffer may be reused once the call returns
+	*r = append((*r)[:0], data...)
+
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// asyncQueue tracks the calls in flight and the queued responses
+// of the sender and response handler pools of all workers
+type asyncQueue struct {
+	senders  uint
+	handlers uint
+
+	lock        sync.Mutex
+	inFlight    uint64
+	maxInFlight uint64
+	queued      uint64
+	maxQueued   uint64
+	queuedSum   float64
+	samples     uint64
+	blocked     uint64
+}
+
+func newAsyncQueue(senders, handlers uint) *asyncQueue {
+	if senders == 0 {
+		return nil
+	}
+
+	return &asyncQueue{senders: senders, handlers: handlers}
+}
+
+func (q *asyncQueue) send() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.inFlight++
+	if q.inFlight > q.maxInFlight {
+		q.maxInFlight = q.inFlight
+	}
+}
+
+func (q *asyncQueue) sent() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.inFlight--
+}
+
+// depths returns the calls being sent and the responses queued for the handlers
+func (q *asyncQueue) depths() (uint64, uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.inFlight, q.queued
+}
+
+func (q *asyncQueue) enqueue(full bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.queued++
+	if q.queued > q.maxQueued {
+		q.maxQueued = q.queued
+	}
+
+	q.queuedSum += float64(q.queued)
+	q.samples++
+
+	if full {
+		q.blocked++
+	}
+}
+
+func (q *asyncQueue) dequeue() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.queued--
+}
+
+func (q *asyncQueue) stats() *AsyncQueueStats {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	s := &AsyncQueueStats{
+		Senders:     q.senders,
+		Handlers:    q.handlers,
+		MaxInFlight: q.maxInFlight,
+		MaxQueued:   q.maxQueued,
+		Blocked:     q.blocked,
+	}
+
+	if q.samples > 0 {
+		s.AverageQueued = q.queuedSum / float64(q.samples)
+	}
+
+	return s
+}
+
+// runAsyncPools makes the calls of the worker using a pool of senders, and
+// hands the responses over to a pool of response handlers
+func (w *Worker) runAsyncPools() error {
+	responses := make(chan *asyncResponse, asyncQueueSize)
+	done := make(chan struct{})
+
+	w.responses = responses
+
+	var errLock sync.Mutex
+	var err error
+
+	var senders, handlers sync.WaitGroup
+	for i := uint(0); i < w.async.senders; i++ {
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				case tv, ok := <-w.ticks:
+					// the ticks are closed once the workers are stopped
+					if !ok {
+						return
+					}
+
+					if rErr := w.makeRequest(tv); rErr != nil {
+						errLock.Lock()
+						err = multierr.Append(err, rErr)
+						errLock.Unlock()
+					}
+				}
+			}
+		}()
+	}
+
+	for i := uint(0); i < w.async.handlers; i++ {
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+
+			for r := range responses {
+				w.async.dequeue()
+				w.handleAsyncResponse(r)
+			}
+		}()
+	}
+
+	<-w.stopCh
+
+	close(done)
+	senders.Wait()
+
+	// the queued responses are handled before stopping
+	close(responses)
+	handlers.Wait()
+
+	return err
+}
+
+// makeAsyncUnaryRequest makes a unary call without decoding the response,
+// and queues the response to be handled by the response handlers
+func (w *Worker) makeAsyncUnaryRequest(ctx *context.Context, ctd *CallData, start time.Time,
+	reqMD *metadata.MD, input *dynamic.Message, gc *graceCall) {
+	var callOptions = []grpc.CallOption{grpc.ForceCodec(rawCodec{})}
+	if compressor := w.config.compressorName(); compressor != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(compressor))
+	}
+
+	callOptions = append(callOptions, w.config.runCallOptions()...)
+
+	r := &asyncResponse{ctd: ctd, start: start, reqMD: reqMD, req: input}
+
+	w.async.send()
+	r.err = w.callConn(*ctx).Invoke(*ctx, "/"+w.mtd.GetService().GetFullyQualifiedName()+"/"+w.mtd.GetName(),
+		input, &r.res, callOptions...)
+	r.latency = time.Since(start)
+	w.async.sent()
+
+	// the call is retried
+	if gc.retry() {
+		return
+	}
+
+	w.async.enqueue(len(w.responses) == cap(w.responses))
+	w.responses <- r
+}
+
+// handleAsyncResponse decodes the response of an async unary call if needed,
+// and records the response details
+func (w *Worker) handleAsyncResponse(r *asyncResponse) {
+	var res proto.Message
+	if r.err == nil && (w.fields != nil || w.drift != nil || w.debugger != nil || w.slowCalls != nil || w.config.hasLog) {
+		m := dynamic.NewMessage(w.mtd.GetOutputType())
+		if err := m.Unmarshal(r.res); err != nil {
+			if w.config.hasLog {
+				w.config.log.Errorw("Error decoding response: "+err.Error(), "workerID", w.workerID,
+					"call", w.mtd.GetFullyQualifiedName(), "error", err)
+			}
+		} else {
+			res = m
+		}
+	}
+
+	if w.config.hasLog {
+		w.config.log.Debugw("Received response", "workerID", w.workerID, "call type", "unary",
+			"call", w.mtd.GetFullyQualifiedName(),
+			"input", r.req, "metadata", r.reqMD,
+			"response", res, "error", r.err)
+	}
+
+	if w.fields != nil && res != nil {
+		w.fields.record(res, r.latency)
+	}
+
+	if w.drift != nil && res != nil {
+		w.drift.record(res)
+	}
+
+	w.recordDebug(r.ctd, r.start, r.latency, r.reqMD, r.req, res, r.err)
+	w.afterCall(r.ctd, r.start, r.latency, r.req, res, r.err)
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawCodec(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter.SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	req := dynamic.NewMessage(mtd.GetInputType())
+	req.SetFieldByName("name", "bob")
+
+	b, err := rawCodec{}.Marshal(req)
+	assert.NoError(t, err)
+
+	var res rawResponse
+	assert.NoError(t, rawCodec{}.Unmarshal(b, &res))
+
+	// the response is a copy of the received bytes
+	b[0] = 0
+	decoded := dynamic.NewMessage(mtd.GetInputType())
+	assert.NoError(t, decoded.Unmarshal(res))
+	assert.Equal(t, "bob", decoded.GetFieldByName("name"))
+
+	_, err = rawCodec{}.Marshal("bob")
+	assert.Error(t, err)
+	assert.Error(t, rawCodec{}.Unmarshal(b, decoded))
+}
+
+func TestAsyncQueue(t *testing.T) {
+	assert.Nil(t, newAsyncQueue(0, 0))
+
+	q := newAsyncQueue(4, 2)
+
+	q.send()
+	q.send()
+	q.sent()
+	q.send()
+	q.sent()
+	q.sent()
+
+	q.enqueue(false)
+	q.enqueue(false)
+	q.dequeue()
+	q.enqueue(true)
+	q.dequeue()
+	q.dequeue()
+
+	assert.Equal(t, &AsyncQueueStats{
+		Senders:       4,
+		Handlers:      2,
+		MaxInFlight:   2,
+		AverageQueued: 5.0 / 3.0,
+		MaxQueued:     2,
+		Blocked:       1,
+	}, q.stats())
+}
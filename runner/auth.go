@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// Auth is the authentication of the calls returned by an auth provider
+type Auth struct {
+	// Metadata is attached to each call, such as the authorization header
+	Metadata map[string]string
+
+	// Credentials are the per-call credentials whose metadata is attached to each call
+	// along with the metadata above, which takes precedence. Optional.
+	Credentials credentials.PerRPCCredentials
+
+	// Expiry is the time the authentication expires, zero if it does not expire
+	Expiry time.Time
+}
+
+// AuthProvider provides the authentication of the calls, such as a custom authentication scheme.
+// The authentication is cached and provided again before it expires.
+type AuthProvider interface {
+	Auth(ctx context.Context) (*Auth, error)
+}
+
+// AuthProviderFunc is a function implementing AuthProvider
+type AuthProviderFunc func(ctx context.Context) (*Auth, error)
+
+// Auth implements AuthProvider
+func (f AuthProviderFunc) Auth(ctx context.Context) (*Auth, error) {
+	return f(ctx)
+}
+
+// tokenAuthProvider provides the token of the source as the authorization metadata
+type tokenAuthProvider struct {
+	source TokenSource
+}
+
+// NewTokenAuthProvider returns the auth provider attaching the token of the source as the
+// authorization metadata, the same as the credentials of NewTokenCredentials
+func NewTokenAuthProvider(source TokenSource) AuthProvider {
+	return &tokenAuthProvider{source: source}
+}
+
+// Auth implements AuthProvider
+func (p *tokenAuthProvider) Auth(ctx context.Context) (*Auth, error) {
+	token, err := p.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil || token.AccessToken == "" {
+		return nil, errors.New("empty access token")
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	return &Auth{
+		Metadata: map[string]string{"authorization": tokenType + " " + token.AccessToken},
+		Expiry:   token.Expiry,
+	}, nil
+}
+
+// authCredentials are the per-call credentials attaching the authentication of the provider to
+// the metadata of each call. The authentication is cached until it is about to expire or the
+// refresh interval elapses, and is provided by a single call while the other calls wait.
+type authCredentials struct {
+	provider AuthProvider
+	refresh  time.Duration
+
+	lock    sync.Mutex
+	auth    *Auth
+	fetched time.Time
+}
+
+// NewAuthCredentials returns the per-call credentials attaching the authentication of the provider
+// to each call. The authentication is provided again before it expires, and after the refresh
+// interval unless it is 0. It is sent on insecure connections as well.
+func NewAuthCredentials(provider AuthProvider, refresh time.Duration) credentials.PerRPCCredentials {
+	return &authCredentials{provider: provider, refresh: refresh}
+}
+
+// valid returns whether the authentication can be used at the time
+func (a *Auth) valid(now time.Time) bool {
+	return a != nil && (a.Expiry.IsZero() || now.Before(a.Expiry))
+}
+
+// current returns the cached authentication, providing a new one if it is due
+func (c *authCredentials) current(ctx context.Context) (*Auth, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+
+	due := !c.auth.valid(now.Add(tokenExpiryDelta)) ||
+		(c.refresh > 0 && now.Sub(c.fetched) >= c.refresh)
+
+	if !due {
+		return c.auth, nil
+	}
+
+	auth, err := c.provider.Auth(ctx)
+	if err == nil && auth == nil {
+		err = errors.New("no authentication provided")
+	}
+
+	if err != nil {
+		// the previous authentication is used until it expires
+		if c.auth.valid(now) {
+			return c.auth, nil
+		}
+
+		return nil, err
+	}
+
+	c.auth = auth
+	c.fetched = now
+
+	return auth, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c *authCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	auth, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	md := make(map[string]string, len(auth.Metadata))
+
+	if auth.Credentials != nil {
+		credsMD, err := auth.Credentials.GetRequestMetadata(ctx, uri...)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range credsMD {
+			md[k] = v
+		}
+	}
+
+	// the keys of the metadata are sent as the lowercase header names
+	for k, v := range auth.Metadata {
+		md[strings.ToLower(k)] = v
+	}
+
+	return md, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (c *authCredentials) RequireTransportSecurity() bool {
+	return false
+}
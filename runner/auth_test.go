@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuthProvider struct {
+	auths []*Auth
+	errs  []error
+	calls int
+}
+
+func (p *testAuthProvider) Auth(ctx context.Context) (*Auth, error) {
+	i := p.calls
+	p.calls++
+
+	if i < len(p.errs) && p.errs[i] != nil {
+		return nil, p.errs[i]
+	}
+
+	return p.auths[i], nil
+}
+
+func TestAuthCredentials(t *testing.T) {
+	t.Run("cached until expiry", func(t *testing.T) {
+		p := &testAuthProvider{auths: []*Auth{
+			{Metadata: map[string]string{"X-Api-Key": "k1"}, Expiry: time.Now().Add(time.Hour)},
+			{Metadata: map[string]string{"x-api-key": "k2"}},
+		}}
+
+		creds := NewAuthCredentials(p, 0)
+		assert.False(t, creds.RequireTransportSecurity())
+
+		for i := 0; i < 3; i++ {
+			md, err := creds.GetRequestMetadata(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"x-api-key": "k1"}, md)
+		}
+
+		assert.Equal(t, 1, p.calls)
+
+		// the authentication is provided again before it expires
+		creds.(*authCredentials).auth.Expiry = time.Now().Add(5 * time.Second)
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"x-api-key": "k2"}, md)
+		assert.Equal(t, 2, p.calls)
+	})
+
+	t.Run("refresh interval", func(t *testing.T) {
+		p := &testAuthProvider{auths: []*Auth{
+			{Metadata: map[string]string{"x-api-key": "k1"}},
+			{Metadata: map[string]string{"x-api-key": "k2"}},
+		}}
+
+		creds := NewAuthCredentials(p, 20*time.Millisecond)
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", md["x-api-key"])
+
+		time.Sleep(30 * time.Millisecond)
+
+		md, err = creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "k2", md["x-api-key"])
+		assert.Equal(t, 2, p.calls)
+	})
+
+	t.Run("with credentials", func(t *testing.T) {
+		src := &testTokenSource{tokens: []*Token{{AccessToken: "t1"}}}
+		p := &testAuthProvider{auths: []*Auth{{
+			Metadata:    map[string]string{"x-tenant": "acme"},
+			Credentials: NewTokenCredentials(src, 0),
+		}}}
+
+		md, err := NewAuthCredentials(p, 0).GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"authorization": "Bearer t1", "x-tenant": "acme"}, md)
+	})
+
+	t.Run("failed refresh", func(t *testing.T) {
+		authErr := errors.New("unavailable")
+		p := &testAuthProvider{
+			auths: []*Auth{{Metadata: map[string]string{"x-api-key": "k1"}, Expiry: time.Now().Add(5 * time.Second)}, nil, nil},
+			errs:  []error{nil, authErr, authErr},
+		}
+
+		creds := NewAuthCredentials(p, 0)
+
+		// the authentication about to expire is provided again, and used while it is still valid
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", md["x-api-key"])
+
+		md, err = creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", md["x-api-key"])
+
+		creds.(*authCredentials).auth.Expiry = time.Now().Add(-time.Second)
+
+		_, err = creds.GetRequestMetadata(context.Background())
+		assert.Equal(t, authErr, err)
+	})
+}
+
+func TestTokenAuthProvider(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	src := &testTokenSource{tokens: []*Token{{AccessToken: "t1", TokenType: "MAC", Expiry: expiry}, {}}}
+	p := NewTokenAuthProvider(src)
+
+	auth, err := p.Auth(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &Auth{Metadata: map[string]string{"authorization": "MAC t1"}, Expiry: expiry}, auth)
+
+	_, err = p.Auth(context.Background())
+	assert.EqualError(t, err, "empty access token")
+}
+
+func TestRunAuthProvider(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	var calls int32
+	provider := AuthProviderFunc(func(ctx context.Context) (*Auth, error) {
+		atomic.AddInt32(&calls, 1)
+		return &Auth{Metadata: map[string]string{"token": "abc"}}, nil
+	})
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(2),
+		WithAuthProvider(provider, 0),
+		WithData(map[string]interface{}{"name": "__record_metadata__"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, int(report.Count))
+	assert.Empty(t, report.ErrorDist)
+
+	// the authentication is provided once and attached to every call
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	for _, msgs := range gs.GetCalls(helloworld.Unary) {
+		for _, msg := range msgs {
+			assert.Equal(t, "__record_metadata__||token:abc", msg.GetName())
+		}
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithAuthProvider(provider, 0),
+		WithTokenFile("token"),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "an auth provider cannot be used with call credentials, OAuth2 or a token file")
+}
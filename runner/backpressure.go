@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// BackpressureStats holds the statistics of the server streaming calls received slowly using
+// the stream receive delay. Once the flow control window of the stream is exhausted the server
+// can not send until the client receives, so the time each message keeps the client waiting in
+// the receive after the delay shows how long the server took to resume sending.
+type BackpressureStats struct {
+	// Delay is the delay of the client after receiving each message
+	Delay time.Duration `json:"delay"`
+
+	Streams  uint64 `json:"streams"`
+	Messages uint64 `json:"messages"`
+
+	// MessageRate is the number of messages received per second of the streams
+	MessageRate float64 `json:"messageRate"`
+
+	// Stalled is the number of messages the client waited for longer than 1ms,
+	// rather than receiving them from the messages already buffered by the stream
+	Stalled uint64 `json:"stalled"`
+
+	// The wait of the client for each message following the first message of the stream
+	TotalWait           time.Duration         `json:"totalWait"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// stallThreshold is the wait for a message above which the stream was stalled
+const stallThreshold = time.Millisecond
+
+// backpressureTracker slows down the receiving of the server streaming calls and gathers
+// the wait for the messages of all the streams of the run
+type backpressureTracker struct {
+	delay time.Duration
+
+	lock      sync.Mutex
+	streams   uint64
+	messages  uint64
+	stalled   uint64
+	duration  time.Duration
+	totalWait time.Duration
+	waits     []float64
+}
+
+func newBackpressureTracker(mtd *desc.MethodDescriptor, delay time.Duration) (*backpressureTracker, error) {
+	if mtd.IsClientStreaming() || !mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("stream receive delay is only supported for server streaming calls")
+	}
+
+	return &backpressureTracker{delay: delay}, nil
+}
+
+// wait delays the receive of the next message until the delay passes or the call is done
+func (t *backpressureTracker) wait(ctx context.Context) {
+	timer := time.NewTimer(t.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// recordWait records the wait for a message following the first message of a stream
+func (t *backpressureTracker) recordWait(w time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.totalWait += w
+
+	if w > stallThreshold {
+		t.stalled++
+	}
+
+	if len(t.waits) < maxResult {
+		t.waits = append(t.waits, w.Seconds())
+	}
+}
+
+// recordStream records a stream with the number of messages received and its duration
+func (t *backpressureTracker) recordStream(messages uint64, duration time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.streams++
+	t.messages += messages
+	t.duration += duration
+}
+
+func (t *backpressureTracker) stats() *BackpressureStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := &BackpressureStats{
+		Delay:     t.delay,
+		Streams:   t.streams,
+		Messages:  t.messages,
+		Stalled:   t.stalled,
+		TotalWait: t.totalWait,
+	}
+
+	if t.duration > 0 {
+		s.MessageRate = float64(t.messages) / t.duration.Seconds()
+	}
+
+	if len(t.waits) == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), t.waits...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, w := range sorted {
+		sum += w
+	}
+
+	s.Average = time.Duration(sum / float64(len(sorted)) * float64(time.Second))
+	s.Fastest = time.Duration(sorted[0] * float64(time.Second))
+	s.Slowest = time.Duration(sorted[len(sorted)-1] * float64(time.Second))
+	s.LatencyDistribution = latencies(sorted)
+
+	return s
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackpressureTracker(t *testing.T) {
+	t.Run("server streaming only", func(t *testing.T) {
+		mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+
+		_, err = newBackpressureTracker(mtd, time.Millisecond)
+		assert.EqualError(t, err, "stream receive delay is only supported for server streaming calls")
+
+		mtd, err = protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHelloBidi", "../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+
+		_, err = newBackpressureTracker(mtd, time.Millisecond)
+		assert.Error(t, err)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHellos", "../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+
+		bp, err := newBackpressureTracker(mtd, 10*time.Millisecond)
+		assert.NoError(t, err)
+
+		for _, w := range []time.Duration{10 * time.Microsecond, 20 * time.Microsecond, 5 * time.Millisecond} {
+			bp.recordWait(w)
+		}
+
+		bp.recordStream(4, time.Second)
+		bp.recordStream(6, time.Second)
+
+		s := bp.stats()
+		assert.Equal(t, 10*time.Millisecond, s.Delay)
+		assert.Equal(t, uint64(2), s.Streams)
+		assert.Equal(t, uint64(10), s.Messages)
+		assert.Equal(t, 5.0, s.MessageRate)
+		assert.Equal(t, uint64(1), s.Stalled)
+		assert.Equal(t, 5030*time.Microsecond, s.TotalWait)
+		assert.Equal(t, 10*time.Microsecond, s.Fastest)
+		assert.Equal(t, 5*time.Millisecond, s.Slowest)
+		assert.NotEmpty(t, s.LatencyDistribution)
+	})
+}
+
+func TestRunStreamRecvDelay(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	start := time.Now()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHellos",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(2),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithStreamRecvDelay(20*time.Millisecond),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), report.Count)
+	assert.Equal(t, 20*time.Millisecond, report.Options.StreamRecvDelay)
+
+	bp := report.Backpressure
+	if assert.NotNil(t, bp) {
+		assert.Equal(t, uint64(2), bp.Streams)
+		assert.True(t, bp.Messages > 2)
+		assert.NotEmpty(t, bp.LatencyDistribution)
+
+		// every message of the streams is followed by the delay
+		assert.True(t, time.Since(start) >= time.Duration(bp.Messages/2)*20*time.Millisecond)
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithStreamRecvDelay(time.Millisecond),
+	)
+
+	assert.EqualError(t, err, "stream receive delay is only supported for server streaming calls")
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"sort"
+	"time"
+)
+
+// Bandwidth holds the wire bytes sent and received by the calls of the run
+type Bandwidth struct {
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
+
+	// SendThroughput and ReceiveThroughput are the bytes per second of the run
+	SendThroughput    float64 `json:"sendThroughput"`
+	ReceiveThroughput float64 `json:"receiveThroughput"`
+
+	// AverageRequestSize and AverageResponseSize are the average wire bytes of the messages
+	AverageRequestSize  float64 `json:"averageRequestSize"`
+	AverageResponseSize float64 `json:"averageResponseSize"`
+
+	// SentHistogram and ReceivedHistogram are the histograms of the bytes sent and received by
+	// each call, with the marks of the buckets in bytes
+	SentHistogram     []Bucket `json:"sentHistogram,omitempty"`
+	ReceivedHistogram []Bucket `json:"receivedHistogram,omitempty"`
+}
+
+// bandwidthTracker counts the bytes and messages sent and received by the calls,
+// keeping the bytes of the first calls for the histograms
+type bandwidthTracker struct {
+	sent         uint64
+	received     uint64
+	sentMsgs     uint64
+	receivedMsgs uint64
+
+	sentSizes     []float64
+	receivedSizes []float64
+}
+
+// add records the bytes and messages sent and received by a call
+func (b *bandwidthTracker) add(res *callResult) {
+	b.sent += res.sent
+	b.received += res.received
+	b.sentMsgs += res.sentMsgs
+	b.receivedMsgs += res.receivedMsgs
+
+	if len(b.sentSizes) < maxResult {
+		b.sentSizes = append(b.sentSizes, float64(res.sent))
+		b.receivedSizes = append(b.receivedSizes, float64(res.received))
+	}
+}
+
+// stats returns the bandwidth of the calls over the total duration of the run,
+// or nil if no bytes were sent or received
+func (b *bandwidthTracker) stats(total time.Duration) *Bandwidth {
+	if b.sent == 0 && b.received == 0 {
+		return nil
+	}
+
+	s := &Bandwidth{
+		BytesSent:         b.sent,
+		BytesReceived:     b.received,
+		SentHistogram:     sizeHistogram(b.sentSizes),
+		ReceivedHistogram: sizeHistogram(b.receivedSizes),
+	}
+
+	if total > 0 {
+		s.SendThroughput = float64(b.sent) / total.Seconds()
+		s.ReceiveThroughput = float64(b.received) / total.Seconds()
+	}
+
+	if b.sentMsgs > 0 {
+		s.AverageRequestSize = float64(b.sent) / float64(b.sentMsgs)
+	}
+
+	if b.receivedMsgs > 0 {
+		s.AverageResponseSize = float64(b.received) / float64(b.receivedMsgs)
+	}
+
+	return s
+}
+
+// sizeHistogram returns the histogram of the sizes, or nil if they are all 0.
+// The histogram has a single bucket if the sizes are all the same, such as those of calls with the same data.
+func sizeHistogram(sizes []float64) []Bucket {
+	sorted := make([]float64, len(sizes))
+	copy(sorted, sizes)
+	sort.Float64s(sorted)
+
+	if len(sorted) == 0 || sorted[len(sorted)-1] == 0 {
+		return nil
+	}
+
+	smallest, largest := sorted[0], sorted[len(sorted)-1]
+	if smallest == largest {
+		return []Bucket{{Mark: largest, Count: len(sorted), Frequency: 1}}
+	}
+
+	return histogram(sorted, largest, smallest)
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthTracker(t *testing.T) {
+	assert.Nil(t, (&bandwidthTracker{}).stats(time.Second))
+
+	b := &bandwidthTracker{}
+	b.add(&callResult{sent: 100, received: 400, sentMsgs: 1, receivedMsgs: 4})
+	b.add(&callResult{sent: 300, received: 400, sentMsgs: 1, receivedMsgs: 4})
+
+	s := b.stats(2 * time.Second)
+	assert.Equal(t, uint64(400), s.BytesSent)
+	assert.Equal(t, uint64(800), s.BytesReceived)
+	assert.Equal(t, 200.0, s.SendThroughput)
+	assert.Equal(t, 400.0, s.ReceiveThroughput)
+	assert.Equal(t, 200.0, s.AverageRequestSize)
+	assert.Equal(t, 100.0, s.AverageResponseSize)
+
+	if assert.Len(t, s.SentHistogram, 11) {
+		assert.Equal(t, Bucket{Mark: 100, Count: 1, Frequency: 0.5}, s.SentHistogram[0])
+		assert.Equal(t, Bucket{Mark: 300, Count: 1, Frequency: 0.5}, s.SentHistogram[10])
+	}
+
+	assert.Equal(t, []Bucket{{Mark: 400, Count: 2, Frequency: 1}}, s.ReceivedHistogram)
+}
+
+func TestRunBandwidth(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, report.Bandwidth) {
+		// the name field of 5 bytes prefixed by the message
+		assert.Equal(t, uint64(10*(5+5)), report.Bandwidth.BytesSent)
+		assert.Equal(t, 10.0, report.Bandwidth.AverageRequestSize)
+		assert.True(t, report.Bandwidth.BytesReceived > 0)
+		assert.True(t, report.Bandwidth.SendThroughput > 0)
+		assert.Equal(t, []Bucket{{Mark: 10, Count: 10, Frequency: 1}}, report.Bandwidth.SentHistogram)
+	}
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Baseline holds the latency of the calls made to a no-op server on the loopback interface
+// before the test, which is the overhead of the client serializing the requests and making
+// the calls, and the latency of the test less the overhead, which is attributable to the server
+type Baseline struct {
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+
+	// AdjustedAverage is the average latency of the test less the average of the baseline,
+	// and AdjustedDistribution each percentile of the test less the percentile of the baseline
+	AdjustedAverage      time.Duration         `json:"adjustedAverage"`
+	AdjustedDistribution []LatencyDistribution `json:"adjustedDistribution"`
+}
+
+// measureBaseline makes the baseline calls using the data and metadata of the test to a server
+// on the loopback interface which receives the request and sends an empty response.
+// The connection is plaintext as the server has no certificate.
+func (b *Requester) measureBaseline() (*Baseline, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %v", err)
+	}
+
+	input, output := b.mtd.GetInputType(), b.mtd.GetOutputType()
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(dynamic.NewMessage(input)); err != nil {
+			return err
+		}
+
+		return stream.SendMsg(dynamic.NewMessage(output))
+	}))
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	defer srv.Stop()
+
+	cc, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(math.MaxInt32),
+			grpc.MaxCallSendMsgSize(math.MaxInt32),
+		))
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %v", err)
+	}
+
+	defer func() {
+		_ = cc.Close()
+	}()
+
+	var callOptions []grpc.CallOption
+	if compressor := b.config.compressorName(); compressor != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(compressor))
+	}
+
+	if b.config.codec != nil {
+		callOptions = append(callOptions, grpc.ForceCodec(b.config.codec), grpc.CallContentSubtype(b.config.codec.Name()))
+	}
+
+	stub := grpcdynamic.NewStub(cc)
+	lats := make([]float64, 0, b.config.baseline)
+
+	// the first call establishes the connection and is not counted
+	for i := -1; i < b.config.baseline; i++ {
+		ctd := newCallData(b.mtd, b.config.funcs, "baseline", int64(i+1))
+		ctd.RunID = b.config.runID
+
+		// the lines of the data read lazily are left for the test
+		req := dynamic.NewMessage(input)
+		if b.config.dataReader == nil {
+			inputs, err := b.dataProvider(ctd)
+			if err != nil {
+				return nil, fmt.Errorf("baseline: %v", err)
+			}
+
+			if len(inputs) > 0 {
+				req = inputs[0]
+			}
+		}
+
+		ctx := context.Background()
+
+		reqMD, err := b.metadataProvider(ctd)
+		if err != nil {
+			return nil, fmt.Errorf("baseline: %v", err)
+		}
+
+		if reqMD != nil {
+			ctx = metadata.NewOutgoingContext(ctx, *reqMD)
+		}
+
+		start := time.Now()
+		if _, err := stub.InvokeRpc(ctx, b.mtd, req, callOptions...); err != nil {
+			return nil, fmt.Errorf("baseline: %v", err)
+		}
+
+		if i >= 0 {
+			lats = append(lats, time.Since(start).Seconds())
+		}
+	}
+
+	h := headerLatency(lats)
+
+	return &Baseline{
+		Count:               h.Count,
+		Average:             h.Average,
+		Fastest:             h.Fastest,
+		Slowest:             h.Slowest,
+		LatencyDistribution: h.LatencyDistribution,
+	}, nil
+}
+
+// adjust sets the latency of the report less the baseline, which is 0 where the
+// latency of the report is below the baseline
+func (bl *Baseline) adjust(r *Report) {
+	if r.Average > bl.Average {
+		bl.AdjustedAverage = r.Average - bl.Average
+	}
+
+	bl.AdjustedDistribution = make([]LatencyDistribution, len(r.LatencyDistribution))
+
+	for i, ld := range r.LatencyDistribution {
+		bl.AdjustedDistribution[i] = LatencyDistribution{Percentage: ld.Percentage, Latency: ld.Latency}
+
+		for _, bld := range bl.LatencyDistribution {
+			if bld.Percentage == ld.Percentage {
+				if ld.Latency > bld.Latency {
+					bl.AdjustedDistribution[i].Latency = ld.Latency - bld.Latency
+				} else {
+					bl.AdjustedDistribution[i].Latency = 0
+				}
+			}
+		}
+	}
+}
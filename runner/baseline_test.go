@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline_adjust(t *testing.T) {
+	bl := &Baseline{
+		Average: 2 * time.Millisecond,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 2 * time.Millisecond},
+			{Percentage: 99, Latency: 8 * time.Millisecond},
+		},
+	}
+
+	bl.adjust(&Report{
+		Average: 5 * time.Millisecond,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 4 * time.Millisecond},
+			{Percentage: 90, Latency: 6 * time.Millisecond},
+			{Percentage: 99, Latency: 7 * time.Millisecond},
+		},
+	})
+
+	assert.Equal(t, 3*time.Millisecond, bl.AdjustedAverage)
+	assert.Equal(t, []LatencyDistribution{
+		{Percentage: 50, Latency: 2 * time.Millisecond},
+		{Percentage: 90, Latency: 6 * time.Millisecond},
+		{Percentage: 99, Latency: 0},
+	}, bl.AdjustedDistribution)
+}
+
+func TestRunBaseline(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(1),
+		WithBaseline(20),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, int(report.Count))
+	assert.Equal(t, uint(20), report.Options.Baseline)
+
+	// the baseline calls are not made to the server of the test
+	assert.Equal(t, 10, gs.GetCount(helloworld.Unary))
+
+	if assert.NotNil(t, report.Baseline) {
+		assert.Equal(t, uint64(20), report.Baseline.Count)
+		assert.True(t, report.Baseline.Average > 0)
+		assert.NotEmpty(t, report.Baseline.LatencyDistribution)
+		assert.Len(t, report.Baseline.AdjustedDistribution, len(report.LatencyDistribution))
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHelloCS",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithBaseline(20),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "baseline is only supported for unary calls")
+}
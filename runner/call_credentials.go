@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// DefaultTokenFileRefresh is the interval at which the token file is read again
+// when no token refresh interval is specified
+const DefaultTokenFileRefresh = 30 * time.Second
+
+// tokenExpiryDelta is how long before its expiry a token is refreshed, so that
+// the token does not expire while a call is in flight
+const tokenExpiryDelta = 10 * time.Second
+
+// Token is a bearer token attached to the calls
+type Token struct {
+	AccessToken string
+
+	// TokenType is the type of the authorization header, Bearer if empty
+	TokenType string
+
+	// Expiry is the time the token expires, zero if it does not expire
+	Expiry time.Time
+}
+
+// TokenSource returns a fresh token when the cached token has to be refreshed
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// tokenCredentials are the per-call credentials attaching the token of the source to the
+// metadata of each call. The token is cached until it is about to expire or the refresh
+// interval elapses, and is fetched by a single call while the other calls wait.
+type tokenCredentials struct {
+	source  TokenSource
+	refresh time.Duration
+
+	lock    sync.Mutex
+	token   *Token
+	fetched time.Time
+}
+
+// NewTokenCredentials returns the per-call credentials attaching the token of the source as the
+// authorization metadata of each call. The token is fetched again before it expires, and after
+// the refresh interval unless it is 0. The token is sent on insecure connections as well.
+func NewTokenCredentials(source TokenSource, refresh time.Duration) credentials.PerRPCCredentials {
+	return &tokenCredentials{source: source, refresh: refresh}
+}
+
+// valid returns whether the token can be used at the time
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Before(t.Expiry))
+}
+
+// current returns the cached token, fetching a new one if it is due
+func (c *tokenCredentials) current(ctx context.Context) (*Token, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+
+	due := !c.token.valid(now.Add(tokenExpiryDelta)) ||
+		(c.refresh > 0 && now.Sub(c.fetched) >= c.refresh)
+
+	if !due {
+		return c.token, nil
+	}
+
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		// the previous token is used until it expires
+		if c.token.valid(now) {
+			return c.token, nil
+		}
+
+		return nil, err
+	}
+
+	c.token = token
+	c.fetched = now
+
+	return token, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	return map[string]string{"authorization": tokenType + " " + token.AccessToken}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// oauth2TokenSource fetches the tokens from the token endpoint using the OAuth2
+// client credentials grant, authenticating the client with HTTP basic authentication
+type oauth2TokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	client       *http.Client
+}
+
+// NewOAuth2TokenSource returns the token source fetching the tokens from the token
+// endpoint using the OAuth2 client credentials grant
+func NewOAuth2TokenSource(tokenURL, clientID, clientSecret string, scopes []string) TokenSource {
+	return &oauth2TokenSource{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// oauth2TokenResponse is the response of the token endpoint
+type oauth2TokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	TokenType   string      `json:"token_type"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// Token implements TokenSource
+func (s *oauth2TokenSource) Token(ctx context.Context) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.scopes) > 0 {
+		form.Set("scope", strings.Join(s.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth2")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+
+	start := time.Now()
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth2: cannot fetch token")
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, errors.Wrap(err, "oauth2: cannot fetch token")
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("oauth2: cannot fetch token: %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tr oauth2TokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, errors.Wrap(err, "oauth2: cannot parse token response")
+	}
+
+	if tr.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+
+	token := &Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType}
+
+	// the expiry is relative to the time the token was requested
+	if expiresIn, err := tr.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		token.Expiry = start.Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// fileTokenSource reads the token from a file, such as a token rotated by an agent
+type fileTokenSource struct {
+	path string
+}
+
+// NewFileTokenSource returns the token source reading the token from the file,
+// with the leading and trailing white space trimmed
+func NewFileTokenSource(path string) TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+// Token implements TokenSource
+func (s *fileTokenSource) Token(ctx context.Context) (*Token, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read token file")
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", s.path)
+	}
+
+	return &Token{AccessToken: token}, nil
+}
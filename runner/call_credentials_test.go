@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTokenSource struct {
+	tokens []*Token
+	errs   []error
+	calls  int
+}
+
+func (s *testTokenSource) Token(ctx context.Context) (*Token, error) {
+	i := s.calls
+	s.calls++
+
+	if i < len(s.errs) && s.errs[i] != nil {
+		return nil, s.errs[i]
+	}
+
+	return s.tokens[i], nil
+}
+
+func TestTokenCredentials(t *testing.T) {
+	t.Run("cached until expiry", func(t *testing.T) {
+		src := &testTokenSource{tokens: []*Token{
+			{AccessToken: "t1", TokenType: "bearer", Expiry: time.Now().Add(time.Hour)},
+			{AccessToken: "t2", TokenType: "MAC"},
+		}}
+
+		creds := NewTokenCredentials(src, 0)
+		assert.False(t, creds.RequireTransportSecurity())
+
+		for i := 0; i < 3; i++ {
+			md, err := creds.GetRequestMetadata(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"authorization": "Bearer t1"}, md)
+		}
+
+		assert.Equal(t, 1, src.calls)
+
+		// the token is refreshed before it expires
+		creds.(*tokenCredentials).token.Expiry = time.Now().Add(5 * time.Second)
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"authorization": "MAC t2"}, md)
+		assert.Equal(t, 2, src.calls)
+	})
+
+	t.Run("refresh interval", func(t *testing.T) {
+		src := &testTokenSource{tokens: []*Token{{AccessToken: "t1"}, {AccessToken: "t2"}, {AccessToken: "t3"}}}
+		creds := NewTokenCredentials(src, 20*time.Millisecond)
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer t1", md["authorization"])
+
+		time.Sleep(30 * time.Millisecond)
+
+		md, err = creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer t2", md["authorization"])
+		assert.Equal(t, 2, src.calls)
+	})
+
+	t.Run("failed refresh", func(t *testing.T) {
+		fetchErr := errors.New("unavailable")
+		src := &testTokenSource{
+			tokens: []*Token{{AccessToken: "t1", Expiry: time.Now().Add(5 * time.Second)}, nil, nil},
+			errs:   []error{nil, fetchErr, fetchErr},
+		}
+
+		creds := NewTokenCredentials(src, 0)
+
+		// the token about to expire is fetched again, and used while it is still valid
+		md, err := creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer t1", md["authorization"])
+
+		md, err = creds.GetRequestMetadata(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer t1", md["authorization"])
+
+		creds.(*tokenCredentials).token.Expiry = time.Now().Add(-time.Second)
+
+		_, err = creds.GetRequestMetadata(context.Background())
+		assert.Equal(t, fetchErr, err)
+	})
+}
+
+func TestOAuth2TokenSource(t *testing.T) {
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "ghz" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+
+			return
+		}
+
+		assert.Equal(t, "client_credentials", r.FormValue("grant_type"))
+		assert.Equal(t, "greeter.read greeter.write", r.FormValue("scope"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token%d","token_type":"bearer","expires_in":3600}`, n)
+	}))
+
+	defer ts.Close()
+
+	src := NewOAuth2TokenSource(ts.URL, "ghz", "secret", []string{"greeter.read", "greeter.write"})
+
+	start := time.Now()
+	token, err := src.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token1", token.AccessToken)
+	assert.Equal(t, "bearer", token.TokenType)
+	assert.WithinDuration(t, start.Add(time.Hour), token.Expiry, 5*time.Second)
+
+	_, err = NewOAuth2TokenSource(ts.URL, "ghz", "wrong", nil).Token(context.Background())
+	assert.EqualError(t, err, `oauth2: cannot fetch token: 401 Unauthorized: {"error":"invalid_client"}`)
+}
+
+func TestFileTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghz-token")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("  abc123\n"), 0600))
+
+	token, err := NewFileTokenSource(path).Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{AccessToken: "abc123"}, token)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("\n"), 0600))
+
+	_, err = NewFileTokenSource(path).Token(context.Background())
+	assert.EqualError(t, err, "token file "+path+" is empty")
+}
+
+func TestRunOAuth2(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"access_token":"abc","token_type":"Bearer","expires_in":3600}`))
+	}))
+
+	defer ts.Close()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(2),
+		WithOAuth2(ts.URL, "ghz", "secret", "greeter.read"),
+		WithData(map[string]interface{}{"name": "__record_metadata__"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, int(report.Count))
+	assert.Empty(t, report.ErrorDist)
+	assert.Equal(t, ts.URL, report.Options.OAuthTokenURL)
+	assert.Equal(t, "ghz", report.Options.OAuthClientID)
+	assert.Equal(t, []string{"greeter.read"}, report.Options.OAuthScopes)
+
+	// the token is fetched once and attached to every call
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	for _, msgs := range gs.GetCalls(helloworld.Unary) {
+		for _, msg := range msgs {
+			assert.Equal(t, "__record_metadata__||authorization:Bearer abc", msg.GetName())
+		}
+	}
+}
@@ -22,6 +22,7 @@ var seededRand *rand.Rand = rand.New(
 
 // CallData represents contextualized data available for templating
 type CallData struct {
+	RunID              string // unique ID of the test run
 	WorkerID           string // unique worker ID
 	RequestNumber      int64  // unique incremented request number for each request
 	FullyQualifiedName string // fully-qualified name of the method call
@@ -36,14 +37,44 @@ type CallData struct {
 	TimestampUnixMilli int64  // timestamp of the call as unix time in milliseconds
 	TimestampUnixNano  int64  // timestamp of the call as unix time in nanoseconds
 	UUID               string // generated UUIDv4 for each call
+	SessionToken       string // session token of the worker when sessions are used
 
-	t *template.Template
+	// LastResponse is the previous response received by the worker, keyed by the proto field names.
+	// It is nil before the worker has received a response.
+	LastResponse map[string]interface{}
+
+	// Vars are the variables extracted from the responses of the previous calls of a sequential
+	// scenario made by the worker in the current pass. It is nil for the first call of each pass.
+	Vars map[string]interface{}
+
+	t     *template.Template
+	label string // label of the data record used for the call
+
+	// the data partition of the worker when the data is partitioned,
+	// and the number of calls made by the worker before this one
+	partition     *dataPartition
+	partitionCall int64
+}
+
+// dataPartition is the partition of the array data used by a worker
+type dataPartition struct {
+	index, count int
+}
+
+// bounds returns the range of the records of the partition out of n records
+func (p *dataPartition) bounds(n int) (int, int) {
+	return p.index * n / p.count, (p.index + 1) * n / p.count
 }
 
 var tmplFuncMap = template.FuncMap{
 	"newUUID":      newUUID,
 	"randomString": randomString,
 	"randomInt":    randomInt,
+	"tsNow":        tsNow,
+	"tsAdd":        tsAdd,
+	"tsRandom":     tsRandom,
+	"tsUnix":       tsUnix,
+	"tsUnixMilli":  tsUnixMilli,
 }
 
 // newCallData returns new CallData
@@ -94,6 +125,7 @@ func (td *CallData) Regenerate() *CallData {
 	newUUID, _ := uuid.NewRandom()
 
 	return &CallData{
+		RunID:              td.RunID,
 		WorkerID:           td.WorkerID,
 		RequestNumber:      td.RequestNumber,
 		FullyQualifiedName: td.FullyQualifiedName,
@@ -108,7 +140,11 @@ func (td *CallData) Regenerate() *CallData {
 		TimestampUnixMilli: now.UnixNano() / 1000000,
 		TimestampUnixNano:  now.UnixNano(),
 		UUID:               newUUID.String(),
+		SessionToken:       td.SessionToken,
+		LastResponse:       td.LastResponse,
 		t:                  td.t,
+		partition:          td.partition,
+		partitionCall:      td.partitionCall,
 	}
 }
 
@@ -139,9 +175,12 @@ func (td *CallData) hasAction(data string) (bool, error) {
 
 func hasAction(node parse.Node) bool {
 	has := false
-	if node.Type() == parse.NodeAction {
+	switch node.Type() {
+	case parse.NodeAction, parse.NodeIf, parse.NodeRange, parse.NodeWith, parse.NodeTemplate:
 		return true
-	} else if ln, ok := node.(*parse.ListNode); ok {
+	}
+
+	if ln, ok := node.(*parse.ListNode); ok {
 		for _, n := range ln.Nodes {
 			v := hasAction(n)
 			if !has && v {
@@ -1,10 +1,12 @@
 package runner
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/bojand/ghz/protodesc"
 	"github.com/google/uuid"
@@ -88,6 +90,32 @@ func TestCallData_ExecuteData(t *testing.T) {
 	}
 }
 
+func TestCallData_HasAction(t *testing.T) {
+	md, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	var tests = []struct {
+		name     string
+		in       string
+		expected bool
+	}{
+		{"no template", `{"name":"bob"}`, false},
+		{"action", `{"name":"{{.WorkerID}}"}`, true},
+		{"with", `{"name":"{{ with .LastResponse }}{{ .message }}{{ else }}bob{{ end }}"}`, true},
+		{"if", `{"name":"{{ if .IsClientStreaming }}bob{{ end }}"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctd := newCallData(md, nil, "worker_id_123", 200)
+
+			has, err := ctd.hasAction(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, has)
+		})
+	}
+}
+
 func TestCallData_ExecuteMetadata(t *testing.T) {
 	md, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
 	assert.NoError(t, err)
@@ -317,4 +345,26 @@ func TestCallTemplateData_ExecuteFuncs(t *testing.T) {
 		assert.Equal(t, "custom-uuid", rm["trace_id"])
 		assert.Equal(t, "custom-sku", rm["sku"])
 	})
+
+	t.Run("timestamps", func(t *testing.T) {
+		ctd := newCallData(md, nil, "worker_id_123", 200)
+
+		r, err := ctd.ExecuteData(`{"from":"{{tsAdd "-24h" "RFC3339" "UTC"}}", "to":"{{tsNow "RFC3339" "UTC"}}", "since":{{tsUnix "-1h"}}}`)
+		assert.NoError(t, err)
+
+		var data struct {
+			From  time.Time `json:"from"`
+			To    time.Time `json:"to"`
+			Since int64     `json:"since"`
+		}
+
+		assert.NoError(t, json.Unmarshal(r, &data))
+		assert.WithinDuration(t, data.To.Add(-24*time.Hour), data.From, time.Second)
+		assert.WithinDuration(t, time.Now().Add(-time.Hour), time.Unix(data.Since, 0), 2*time.Second)
+
+		// the data is left as is if the template fails
+		r, err = ctd.ExecuteData(`{"from":"{{tsAdd "yesterday"}}"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"from":"{{tsAdd "yesterday"}}"}`, string(r))
+	})
 }
@@ -0,0 +1,347 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultCanaryInterval is the interval of the canary calls if none is specified
+const defaultCanaryInterval = 5 * time.Second
+
+// CanaryStats is the result of the canary calls made amid the load, whose responses are
+// compared with the known-good snapshot
+type CanaryStats struct {
+	Call string `json:"call"`
+
+	// Checks is the number of canary calls, Mismatches the number of responses not matching
+	// the snapshot, and Errors the number of calls which failed
+	Checks     uint64 `json:"checks"`
+	Mismatches uint64 `json:"mismatches"`
+	Errors     uint64 `json:"errors"`
+
+	// FirstDivergence is the first response not matching the snapshot, if any
+	FirstDivergence *CanaryDivergence `json:"firstDivergence,omitempty"`
+}
+
+// CanaryDivergence is a canary response which did not match the snapshot
+type CanaryDivergence struct {
+	// Check is the number of the canary call, starting at 1, and Elapsed the time since the start of the run
+	Check   uint64        `json:"check"`
+	Elapsed time.Duration `json:"elapsed"`
+
+	Response json.RawMessage `json:"response"`
+	Expected json.RawMessage `json:"expected"`
+}
+
+// canaryProbe periodically makes the canary call on a separate connection while the load is
+// running, so the calls are not included in the results, and compares the responses with the
+// snapshot, ignoring the fields which legitimately vary between the calls
+type canaryProbe struct {
+	mtd      *desc.MethodDescriptor
+	req      *dynamic.Message
+	md       metadata.MD
+	interval time.Duration
+	timeout  time.Duration
+	log      Logger
+
+	// the paths of the ignored fields by their proto names
+	ignore [][]string
+
+	cc       *grpc.ClientConn
+	stub     grpcdynamic.Stub
+	expected map[string]interface{}
+
+	mu    sync.Mutex
+	stats CanaryStats
+}
+
+// newCanaryProbe returns the canary probe of the config, or nil if there is no canary call.
+// The expected response is checked against the method, so that a wrong snapshot fails early.
+func newCanaryProbe(c *RunConfig, getMethod func(string) (*desc.MethodDescriptor, error)) (*canaryProbe, error) {
+	if c.canaryCall == "" {
+		return nil, nil
+	}
+
+	mtd, err := getMethod(c.canaryCall)
+	if err != nil {
+		return nil, fmt.Errorf("canary call %s: %v", c.canaryCall, err)
+	}
+
+	if mtd.IsClientStreaming() || mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("canary call %s must be unary", c.canaryCall)
+	}
+
+	p := &canaryProbe{
+		mtd:      mtd,
+		interval: c.canaryInterval,
+		timeout:  c.timeout,
+		stats:    CanaryStats{Call: c.canaryCall},
+	}
+
+	if p.interval <= 0 {
+		p.interval = defaultCanaryInterval
+	}
+
+	if c.hasLog {
+		p.log = c.log
+	}
+
+	p.req = dynamic.NewMessage(mtd.GetInputType())
+	if len(c.canaryData) > 0 {
+		inputs, err := createPayloadsFromJSON(string(c.canaryData), mtd)
+		if err != nil {
+			return nil, fmt.Errorf("canary data: %v", err)
+		}
+
+		if len(inputs) != 1 {
+			return nil, fmt.Errorf("canary data must be a single message")
+		}
+
+		p.req = inputs[0]
+	}
+
+	if len(c.canaryMetadata) > 0 {
+		p.md = metadata.New(c.canaryMetadata)
+	}
+
+	for _, path := range c.canaryIgnore {
+		fields, err := canaryFieldPath(mtd.GetOutputType(), path)
+		if err != nil {
+			return nil, fmt.Errorf("canary ignored field: %v", err)
+		}
+
+		p.ignore = append(p.ignore, fields)
+	}
+
+	if len(c.canaryExpected) > 0 {
+		res := dynamic.NewMessage(mtd.GetOutputType())
+		if err := res.UnmarshalJSON(c.canaryExpected); err != nil {
+			return nil, fmt.Errorf("canary expected response: %v", err)
+		}
+
+		if p.expected, err = p.fields(res); err != nil {
+			return nil, fmt.Errorf("canary expected response: %v", err)
+		}
+	}
+
+	return p, nil
+}
+
+// canaryFieldPath returns the proto names of the fields of the dot separated path of the message,
+// whose fields may be named by either their proto or JSON names
+func canaryFieldPath(md *desc.MessageDescriptor, path string) ([]string, error) {
+	var fields []string
+
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		if md == nil {
+			return nil, fmt.Errorf("field %q of %q is not a message", parts[i-1], path)
+		}
+
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(name)
+		}
+
+		if fd == nil {
+			return nil, fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+		}
+
+		fields = append(fields, fd.GetName())
+
+		md = nil
+		if !fd.IsRepeated() {
+			md = fd.GetMessageType()
+		}
+	}
+
+	return fields, nil
+}
+
+// open opens the connection of the canary calls, and captures the snapshot with a canary call if
+// no expected response was specified, before the load starts
+func (p *canaryProbe) open(b *Requester) error {
+	if p == nil {
+		return nil
+	}
+
+	cc, err := b.newClientConn(b.config.host, nil)
+	if err != nil {
+		return err
+	}
+
+	p.cc = cc
+	p.stub = grpcdynamic.NewStub(cc)
+
+	if p.expected != nil {
+		return nil
+	}
+
+	res, err := p.call()
+	if err != nil {
+		p.close()
+
+		return fmt.Errorf("canary call %s: %v", p.stats.Call, err)
+	}
+
+	if p.expected, err = p.fields(res); err != nil {
+		p.close()
+
+		return fmt.Errorf("canary call %s: %v", p.stats.Call, err)
+	}
+
+	return nil
+}
+
+// begin starts making the canary calls on every interval of the run, returning the function which
+// stops the calls and returns their statistics
+func (p *canaryProbe) begin(start time.Time) func() *CanaryStats {
+	if p == nil {
+		return func() *CanaryStats { return nil }
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.check(start)
+			}
+		}
+	}()
+
+	return func() *CanaryStats {
+		close(done)
+		<-stopped
+
+		p.close()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		stats := p.stats
+
+		return &stats
+	}
+}
+
+// check makes a canary call and compares the response with the snapshot. Only the first
+// mismatch is kept, which is logged as soon as it is found.
+func (p *canaryProbe) check(start time.Time) {
+	res, err := p.call()
+
+	var fields map[string]interface{}
+	if err == nil {
+		fields, err = p.fields(res)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Checks++
+
+	if err != nil {
+		p.stats.Errors++
+
+		if p.log != nil {
+			p.log.Debugw("Canary call failed", "call", p.stats.Call, "error", err)
+		}
+
+		return
+	}
+
+	if reflect.DeepEqual(fields, p.expected) {
+		return
+	}
+
+	p.stats.Mismatches++
+
+	if p.stats.FirstDivergence != nil {
+		return
+	}
+
+	response, _ := json.Marshal(fields)
+	expected, _ := json.Marshal(p.expected)
+
+	p.stats.FirstDivergence = &CanaryDivergence{
+		Check:    p.stats.Checks,
+		Elapsed:  time.Since(start),
+		Response: response,
+		Expected: expected,
+	}
+
+	if p.log != nil {
+		p.log.Errorw("Canary response diverged from the snapshot", "call", p.stats.Call,
+			"check", p.stats.Checks, "response", string(response), "expected", string(expected))
+	}
+}
+
+// call makes the canary call
+func (p *canaryProbe) call() (proto.Message, error) {
+	ctx, cancel := requestContext(p.timeout)
+	defer cancel()
+
+	if p.md != nil {
+		ctx = metadata.NewOutgoingContext(ctx, p.md)
+	}
+
+	return p.stub.InvokeRpc(ctx, p.mtd, p.req)
+}
+
+// fields returns the fields of the response compared with the snapshot, without the ignored fields
+func (p *canaryProbe) fields(res proto.Message) (map[string]interface{}, error) {
+	fields, err := responseFields(res)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range p.ignore {
+		removeFieldPath(fields, path)
+	}
+
+	return fields, nil
+}
+
+// removeFieldPath removes the field of the path from the fields of a message
+func removeFieldPath(fields map[string]interface{}, path []string) {
+	for _, name := range path[:len(path)-1] {
+		nested, ok := fields[name].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		fields = nested
+	}
+
+	delete(fields, path[len(path)-1])
+}
+
+// close closes the connection of the canary calls
+func (p *canaryProbe) close() {
+	if p.cc != nil {
+		// purposefully ignoring error as we do not care if there
+		// is an error on close
+		_ = p.cc.Close()
+		p.cc = nil
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestCanaryFieldPath(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	fields, err := canaryFieldPath(mtd.GetOutputType(), "message")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"message"}, fields)
+
+	_, err = canaryFieldPath(mtd.GetOutputType(), "message.length")
+	assert.EqualError(t, err, `field "message" of "message.length" is not a message`)
+
+	_, err = canaryFieldPath(mtd.GetOutputType(), "served_at")
+	assert.EqualError(t, err, `field "served_at" not found in message helloworld.HelloReply`)
+}
+
+func TestRemoveFieldPath(t *testing.T) {
+	fields := map[string]interface{}{
+		"id": "canary-1",
+		"meta": map[string]interface{}{
+			"request_id": "1f3a",
+			"region":     "eu",
+		},
+	}
+
+	removeFieldPath(fields, []string{"meta", "request_id"})
+	removeFieldPath(fields, []string{"missing", "request_id"})
+	removeFieldPath(fields, []string{"id", "value"})
+
+	assert.Equal(t, map[string]interface{}{
+		"id":   "canary-1",
+		"meta": map[string]interface{}{"region": "eu"},
+	}, fields)
+}
+
+func TestRunCanary(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	s := grpc.NewServer()
+	gs := helloworld.NewGreeter()
+	helloworld.RegisterGreeterServer(s, gs)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	host := lis.Addr().String()
+
+	t.Run("matching the snapshot", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(30),
+			WithRPS(100),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithCanary("helloworld.Greeter.SayHello", map[string]interface{}{"name": "canary"}, 50*time.Millisecond),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, report.Canary) {
+			assert.Equal(t, "helloworld.Greeter.SayHello", report.Canary.Call)
+			assert.NotZero(t, report.Canary.Checks)
+			assert.Zero(t, report.Canary.Mismatches)
+			assert.Zero(t, report.Canary.Errors)
+			assert.Nil(t, report.Canary.FirstDivergence)
+
+			// the snapshot and the canary calls are not included in the results
+			assert.Equal(t, int(report.Count+report.Canary.Checks+1), gs.GetCount(helloworld.Unary))
+		}
+
+		assert.Equal(t, "helloworld.Greeter.SayHello", report.Options.Canary)
+	})
+
+	t.Run("diverging from the expected response", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(300*time.Millisecond),
+			WithRPS(100),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithCanary("helloworld.Greeter.SayHello", map[string]interface{}{"name": "canary"}, 50*time.Millisecond),
+			WithCanaryExpected(map[string]interface{}{"message": "Hello bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, report.Canary) && assert.NotNil(t, report.Canary.FirstDivergence) {
+			assert.Equal(t, report.Canary.Checks, report.Canary.Mismatches)
+			assert.Equal(t, uint64(1), report.Canary.FirstDivergence.Check)
+			assert.NotZero(t, report.Canary.FirstDivergence.Elapsed)
+			assert.JSONEq(t, `{"message":"Hello canary"}`, string(report.Canary.FirstDivergence.Response))
+			assert.JSONEq(t, `{"message":"Hello bob"}`, string(report.Canary.FirstDivergence.Expected))
+		}
+	})
+
+	t.Run("ignoring fields", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(200*time.Millisecond),
+			WithRPS(100),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithCanary("helloworld.Greeter.SayHello", map[string]interface{}{"name": "canary"}, 50*time.Millisecond),
+			WithCanaryExpected(map[string]interface{}{"message": "Hello bob"}),
+			WithCanaryIgnore("message"),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, report.Canary) {
+			assert.NotZero(t, report.Canary.Checks)
+			assert.Zero(t, report.Canary.Mismatches)
+		}
+	})
+
+	t.Run("invalid expected response", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithCanary("helloworld.Greeter.SayHello", nil, 0),
+			WithCanaryExpected(map[string]interface{}{"greeting": "Hello bob"}),
+			WithInsecure(true),
+		)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "canary expected response")
+	})
+
+	t.Run("streaming call", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithCanary("helloworld.Greeter.SayHellos", nil, 0),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "canary call helloworld.Greeter.SayHellos must be unary")
+	})
+}
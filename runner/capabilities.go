@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ClientCapabilities are the features of the platform the client relies on, detected when the test
+// is run, so the statistics missing on a platform can be told apart from those which are zero
+type ClientCapabilities struct {
+	// CPUTime is whether the CPU time of the process can be read, for the normalized throughput
+	CPUTime bool `json:"cpuTime"`
+
+	// CPUAffinity is whether the threads of the worker groups can be pinned to their CPUs
+	CPUAffinity bool `json:"cpuAffinity"`
+
+	// ControlSignals is whether the control file is checked right away on a signal,
+	// rather than only periodically
+	ControlSignals bool `json:"controlSignals"`
+
+	// FileMapping is whether the data and upload files are mapped into memory rather than read
+	FileMapping bool `json:"fileMapping"`
+
+	// UnixSockets is whether the unix domain socket targets can be dialed
+	UnixSockets bool `json:"unixSockets"`
+}
+
+var (
+	capabilities     ClientCapabilities
+	capabilitiesOnce sync.Once
+)
+
+// clientCapabilities returns the capabilities of the platform, detected once
+func clientCapabilities() ClientCapabilities {
+	capabilitiesOnce.Do(func() {
+		capabilities = ClientCapabilities{
+			CPUTime:        processCPUTime() > 0,
+			CPUAffinity:    affinitySupported,
+			ControlSignals: len(controlSignals) > 0,
+			FileMapping:    fileMappingSupported,
+			UnixSockets:    unixSocketsSupported(),
+		}
+	})
+
+	return capabilities
+}
+
+// unixSocketsSupported returns whether a unix domain socket can be listened on, which is not
+// the case on the older versions of Windows
+func unixSocketsSupported() bool {
+	dir, err := ioutil.TempDir("", "ghz")
+	if err != nil {
+		return false
+	}
+
+	defer os.RemoveAll(dir)
+
+	l, err := net.Listen("unix", filepath.Join(dir, "probe.sock"))
+	if err != nil {
+		return false
+	}
+
+	_ = l.Close()
+
+	return true
+}
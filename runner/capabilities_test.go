@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCapabilities(t *testing.T) {
+	caps := clientCapabilities()
+
+	assert.Equal(t, affinitySupported, caps.CPUAffinity)
+	assert.Equal(t, fileMappingSupported, caps.FileMapping)
+	assert.Equal(t, len(controlSignals) > 0, caps.ControlSignals)
+
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		assert.True(t, caps.CPUTime)
+		assert.True(t, caps.UnixSockets)
+	}
+
+	// detected once
+	assert.Equal(t, caps, clientCapabilities())
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/status"
+)
+
+// defaultCaptureRate is the fraction of the responses captured when none is set
+const defaultCaptureRate = 0.01
+
+// CapturedResponse is a sampled response written to the response capture file
+type CapturedResponse struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	Latency       time.Duration   `json:"latency"`
+	RunID         string          `json:"runId,omitempty"`
+	WorkerID      string          `json:"workerId"`
+	RequestNumber int64           `json:"requestNumber"`
+	Call          string          `json:"call"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty"`
+}
+
+// responseCapture writes a sample of the responses with their status and latency to a file
+type responseCapture struct {
+	rate float64
+
+	lock sync.Mutex
+	file *os.File
+	out  *bufio.Writer
+}
+
+func newResponseCapture(c *RunConfig) (*responseCapture, error) {
+	if c.capturePath == "" {
+		return nil, nil
+	}
+
+	f, err := os.Create(c.capturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := c.captureRate
+	if rate <= 0 {
+		rate = defaultCaptureRate
+	}
+
+	return &responseCapture{rate: rate, file: f, out: bufio.NewWriter(f)}, nil
+}
+
+// record writes the response of the call started at the time if it is sampled.
+// The response is only included for the unary and client streaming calls.
+// a nil capture is a no-op
+func (rc *responseCapture) record(ctd *CallData, start time.Time, latency time.Duration, res proto.Message, callErr error) error {
+	if rc == nil || (rc.rate < 1 && rand.Float64() >= rc.rate) {
+		return nil
+	}
+
+	rec := CapturedResponse{
+		Timestamp:     start,
+		Latency:       latency,
+		RunID:         ctd.RunID,
+		WorkerID:      ctd.WorkerID,
+		RequestNumber: ctd.RequestNumber,
+		Call:          ctd.FullyQualifiedName,
+		Status:        status.Code(callErr).String(),
+		Response:      messageToJSON(res),
+	}
+
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	_, err = rc.out.Write(append(line, '\n'))
+	return err
+}
+
+// close flushes the captured responses and closes the file
+func (rc *responseCapture) close() error {
+	if rc == nil {
+		return nil
+	}
+
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+
+	err := rc.out.Flush()
+	if cerr := rc.file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
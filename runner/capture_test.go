@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunResponseCapture(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	path := filepath.Join(t.TempDir(), "responses.ndjson")
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithRunID("run1"),
+		WithResponseCapture(1, path),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+
+	f, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	var captured []CapturedResponse
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec CapturedResponse
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+
+		captured = append(captured, rec)
+	}
+
+	if assert.Len(t, captured, 10) {
+		rec := captured[0]
+		assert.Equal(t, "run1", rec.RunID)
+		assert.Equal(t, "helloworld.Greeter.SayHello", rec.Call)
+		assert.Equal(t, "OK", rec.Status)
+		assert.NotEmpty(t, rec.WorkerID)
+		assert.True(t, rec.Latency > 0)
+		assert.JSONEq(t, `{"message":"Hello bob"}`, string(rec.Response))
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithResponseCapture(0, filepath.Join(t.TempDir(), "missing", "responses.ndjson")),
+	)
+
+	assert.Error(t, err)
+}
+
+func TestResponseCapture_sampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.ndjson")
+
+	rc, err := newResponseCapture(&RunConfig{capturePath: path})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCaptureRate, rc.rate)
+
+	rc.rate = 0.5
+
+	ctd := &CallData{WorkerID: "g0c0", FullyQualifiedName: "helloworld.Greeter.SayHello"}
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, rc.record(ctd, time.Now(), time.Millisecond, nil, nil))
+	}
+
+	assert.NoError(t, rc.close())
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	lines := strings.Count(string(b), "\n")
+	assert.True(t, lines > 350 && lines < 650, "captured %d of 1000", lines)
+
+	// no capture without a file
+	rc, err = newResponseCapture(&RunConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, rc)
+	assert.NoError(t, rc.record(ctd, time.Now(), time.Millisecond, nil, nil))
+	assert.NoError(t, rc.close())
+}
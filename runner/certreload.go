@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the client certificate, loading it again from its files once they change,
+// so a long test outlives the rotation of a short lived certificate.
+// The files are polled on each handshake rather than watched, which works on every platform.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// newCertReloader loads the client key pair, failing if it cannot be loaded
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cert = &cert
+	r.modified = r.modTime()
+
+	return r, nil
+}
+
+// modTime returns the latest modification time of the certificate and key files
+func (r *certReloader) modTime() time.Time {
+	var latest time.Time
+
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(f); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest
+}
+
+// GetClientCertificate returns the client certificate, reloaded if its files changed.
+// The previous certificate is kept while the new one cannot be loaded, such as halfway through its rotation.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if modified := r.modTime(); modified.After(r.modified) {
+		if cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile); err == nil {
+			r.cert = &cert
+			r.modified = modified
+		}
+	}
+
+	return r.cert, nil
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKeyPair writes a self-signed certificate of the common name and its key to the files
+func writeKeyPair(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghz")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	commonName := func(r *certReloader) string {
+		cert, err := r.GetClientCertificate(nil)
+		assert.NoError(t, err)
+
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NoError(t, err)
+
+		return leaf.Subject.CommonName
+	}
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := newCertReloader(certFile, keyFile)
+		assert.Error(t, err)
+	})
+
+	writeKeyPair(t, certFile, keyFile, "first")
+
+	r, err := newCertReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", commonName(r))
+
+	t.Run("rotated", func(t *testing.T) {
+		writeKeyPair(t, certFile, keyFile, "second")
+
+		later := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(certFile, later, later))
+		assert.NoError(t, os.Chtimes(keyFile, later, later))
+
+		assert.Equal(t, "second", commonName(r))
+	})
+
+	t.Run("previous certificate kept while invalid", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+
+		later := time.Now().Add(2 * time.Minute)
+		assert.NoError(t, os.Chtimes(keyFile, later, later))
+
+		assert.Equal(t, "second", commonName(r))
+	})
+}
@@ -0,0 +1,253 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	channelzgrpc "google.golang.org/grpc/channelz/grpc_channelz_v1"
+	channelzsvc "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// channelzTarget is the target of the in-process connection used to query channelz
+const channelzTarget = "ghz-channelz"
+
+// ChannelzSnapshot holds the channelz statistics of the client connections to the host
+// at a point in time, for diagnosing the transport
+type ChannelzSnapshot struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Channels  []ChannelzChannel `json:"channels"`
+}
+
+// ChannelzChannel holds the channelz statistics of a client connection
+type ChannelzChannel struct {
+	ID             int64  `json:"id"`
+	Target         string `json:"target"`
+	State          string `json:"state"`
+	CallsStarted   int64  `json:"callsStarted"`
+	CallsSucceeded int64  `json:"callsSucceeded"`
+	CallsFailed    int64  `json:"callsFailed"`
+
+	Subchannels []ChannelzChannel `json:"subchannels,omitempty"`
+	Sockets     []ChannelzSocket  `json:"sockets,omitempty"`
+}
+
+// ChannelzSocket holds the channelz statistics of a transport connection
+type ChannelzSocket struct {
+	ID     int64  `json:"id"`
+	Local  string `json:"local,omitempty"`
+	Remote string `json:"remote,omitempty"`
+
+	StreamsStarted   int64 `json:"streamsStarted"`
+	StreamsSucceeded int64 `json:"streamsSucceeded"`
+	StreamsFailed    int64 `json:"streamsFailed"`
+	MessagesSent     int64 `json:"messagesSent"`
+	MessagesReceived int64 `json:"messagesReceived"`
+	KeepAlivesSent   int64 `json:"keepAlivesSent"`
+
+	// the flow control windows in bytes, if known
+	LocalFlowControlWindow  int64 `json:"localFlowControlWindow,omitempty"`
+	RemoteFlowControlWindow int64 `json:"remoteFlowControlWindow,omitempty"`
+}
+
+// channelzCollector takes snapshots of the channelz statistics of the connections to the host,
+// using the channelz service served in-process
+type channelzCollector struct {
+	host string
+
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client channelzgrpc.ChannelzClient
+
+	stopCh chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	lock      sync.Mutex
+	snapshots []ChannelzSnapshot
+}
+
+func newChannelzCollector(host string) (*channelzCollector, error) {
+	lis := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	channelzsvc.RegisterChannelzServiceToServer(server)
+
+	go func() {
+		_ = server.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial(channelzTarget, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+
+	return &channelzCollector{
+		host:   host,
+		server: server,
+		conn:   conn,
+		client: channelzgrpc.NewChannelzClient(conn),
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// run takes a snapshot every interval until finished. If the interval is 0
+// only the final snapshot is taken.
+func (c *channelzCollector) run(interval time.Duration) {
+	defer close(c.done)
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			_ = c.snapshot()
+		}
+	}
+}
+
+// snapshot records the current statistics of the top level channels to the host
+func (c *channelzCollector) snapshot() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := ChannelzSnapshot{Timestamp: time.Now()}
+
+	var start int64
+	for {
+		res, err := c.client.GetTopChannels(ctx, &channelzgrpc.GetTopChannelsRequest{StartChannelId: start})
+		if err != nil {
+			return err
+		}
+
+		for _, ch := range res.GetChannel() {
+			start = ch.GetRef().GetChannelId() + 1
+
+			if ch.GetData().GetTarget() != c.host {
+				continue
+			}
+
+			channel := channelzChannel(ch.GetRef().GetChannelId(), ch.GetData())
+			for _, ref := range ch.GetSubchannelRef() {
+				sub, err := c.subchannel(ctx, ref.GetSubchannelId())
+				if err != nil {
+					return err
+				}
+
+				channel.Subchannels = append(channel.Subchannels, sub)
+			}
+
+			s.Channels = append(s.Channels, channel)
+		}
+
+		if res.GetEnd() || len(res.GetChannel()) == 0 {
+			break
+		}
+	}
+
+	c.lock.Lock()
+	c.snapshots = append(c.snapshots, s)
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *channelzCollector) subchannel(ctx context.Context, id int64) (ChannelzChannel, error) {
+	res, err := c.client.GetSubchannel(ctx, &channelzgrpc.GetSubchannelRequest{SubchannelId: id})
+	if err != nil {
+		return ChannelzChannel{}, err
+	}
+
+	sub := channelzChannel(id, res.GetSubchannel().GetData())
+	for _, ref := range res.GetSubchannel().GetSocketRef() {
+		sres, err := c.client.GetSocket(ctx, &channelzgrpc.GetSocketRequest{SocketId: ref.GetSocketId()})
+		if err != nil {
+			return ChannelzChannel{}, err
+		}
+
+		sub.Sockets = append(sub.Sockets, channelzSocket(ref.GetSocketId(), sres.GetSocket()))
+	}
+
+	return sub, nil
+}
+
+// final stops taking snapshots and takes the final snapshot.
+// It has to be called before the client connections are closed, only the first call has any effect.
+func (c *channelzCollector) final() {
+	c.once.Do(func() {
+		close(c.stopCh)
+		<-c.done
+
+		_ = c.snapshot()
+	})
+}
+
+// finish takes the final snapshot if not yet taken, stops the channelz service and returns the snapshots
+func (c *channelzCollector) finish() []ChannelzSnapshot {
+	c.final()
+
+	_ = c.conn.Close()
+	c.server.Stop()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.snapshots
+}
+
+func channelzChannel(id int64, d *channelzgrpc.ChannelData) ChannelzChannel {
+	return ChannelzChannel{
+		ID:             id,
+		Target:         d.GetTarget(),
+		State:          d.GetState().GetState().String(),
+		CallsStarted:   d.GetCallsStarted(),
+		CallsSucceeded: d.GetCallsSucceeded(),
+		CallsFailed:    d.GetCallsFailed(),
+	}
+}
+
+func channelzSocket(id int64, s *channelzgrpc.Socket) ChannelzSocket {
+	d := s.GetData()
+
+	return ChannelzSocket{
+		ID:                      id,
+		Local:                   channelzAddress(s.GetLocal()),
+		Remote:                  channelzAddress(s.GetRemote()),
+		StreamsStarted:          d.GetStreamsStarted(),
+		StreamsSucceeded:        d.GetStreamsSucceeded(),
+		StreamsFailed:           d.GetStreamsFailed(),
+		MessagesSent:            d.GetMessagesSent(),
+		MessagesReceived:        d.GetMessagesReceived(),
+		KeepAlivesSent:          d.GetKeepAlivesSent(),
+		LocalFlowControlWindow:  d.GetLocalFlowControlWindow().GetValue(),
+		RemoteFlowControlWindow: d.GetRemoteFlowControlWindow().GetValue(),
+	}
+}
+
+func channelzAddress(a *channelzgrpc.Address) string {
+	if tcp := a.GetTcpipAddress(); tcp != nil {
+		return net.JoinHostPort(net.IP(tcp.GetIpAddress()).String(), strconv.Itoa(int(tcp.GetPort())))
+	}
+
+	if uds := a.GetUdsAddress(); uds != nil {
+		return uds.GetFilename()
+	}
+
+	return a.GetOtherAddress().GetName()
+}
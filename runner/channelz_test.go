@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	channelzgrpc "google.golang.org/grpc/channelz/grpc_channelz_v1"
+)
+
+func TestChannelzAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:50051", channelzAddress(&channelzgrpc.Address{
+		Address: &channelzgrpc.Address_TcpipAddress{TcpipAddress: &channelzgrpc.Address_TcpIpAddress{
+			IpAddress: net.ParseIP("127.0.0.1").To4(),
+			Port:      50051,
+		}},
+	}))
+
+	assert.Equal(t, "/tmp/ghz.sock", channelzAddress(&channelzgrpc.Address{
+		Address: &channelzgrpc.Address_UdsAddress_{UdsAddress: &channelzgrpc.Address_UdsAddress{Filename: "/tmp/ghz.sock"}},
+	}))
+
+	assert.Equal(t, "", channelzAddress(nil))
+}
+
+func TestRunChannelz(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(12),
+		WithConcurrency(2),
+		WithConnections(2),
+		WithChannelz(true, 0),
+		WithTimeout(time.Duration(20*time.Second)),
+		WithDialTimeout(time.Duration(20*time.Second)),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+	assert.Equal(t, 12, int(report.Count))
+
+	assert.Len(t, report.Channelz, 1)
+
+	channels := report.Channelz[0].Channels
+	assert.Len(t, channels, 2)
+
+	var calls int64
+	for _, ch := range channels {
+		assert.Equal(t, internal.TestLocalhost, ch.Target)
+		assert.Equal(t, "READY", ch.State)
+
+		calls += ch.CallsSucceeded
+
+		if assert.Len(t, ch.Subchannels, 1) && assert.Len(t, ch.Subchannels[0].Sockets, 1) {
+			socket := ch.Subchannels[0].Sockets[0]
+			assert.NotEmpty(t, socket.Remote)
+			assert.Equal(t, ch.CallsSucceeded, socket.StreamsSucceeded)
+		}
+	}
+
+	assert.Equal(t, int64(12), calls)
+}
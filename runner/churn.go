@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+)
+
+// errChurnClosed is returned when acquiring a connection after the connections are closed
+var errChurnClosed = errors.New("churned connections are closed")
+
+// ChurnStats holds the statistics of the connection churn
+type ChurnStats struct {
+	// Interval is the time and Requests the number of calls after which a connection is re-dialed
+	Interval time.Duration `json:"interval,omitempty"`
+	Requests uint          `json:"requests,omitempty"`
+
+	// Redials is the number of connections closed and established again
+	Redials uint64 `json:"redials"`
+
+	// Failed is the number of re-dials that failed, keeping the previous connection
+	Failed uint64 `json:"failed"`
+
+	// Lifetime is the average time the re-dialed connections were used, and Calls
+	// the average number of calls made on them
+	Lifetime time.Duration `json:"lifetime"`
+	Calls    float64       `json:"calls"`
+}
+
+// callChurnConnKey is the context key of the churned connection the call is made on
+type callChurnConnKey struct{}
+
+// churnConn is a connection of the pool which is re-dialed once it is due
+type churnConn struct {
+	conn   *grpc.ClientConn
+	stub   grpcdynamic.Stub
+	opened time.Time
+
+	// the number of calls made on the connection, and of those the number in flight
+	calls  uint64
+	active int
+
+	// whether the connection has been replaced, and is closed once its calls complete
+	retired bool
+}
+
+// connChurner periodically closes the connections of the pool and establishes them again,
+// after an interval or a number of calls on each connection, so that the cost of setting up the
+// connections is part of the test and the connections are spread again by L4 load balancers.
+// The calls in flight on a replaced connection complete before it is closed.
+type connChurner struct {
+	interval time.Duration
+	requests uint64
+	dial     func(n int) (*grpc.ClientConn, error)
+
+	lock   sync.Mutex
+	closed bool
+	conns  []*churnConn
+
+	// the replaced connections with calls in flight
+	retired map[*churnConn]struct{}
+
+	redials  uint64
+	failed   uint64
+	calls    uint64
+	lifetime time.Duration
+}
+
+func newConnChurner(interval time.Duration, requests uint, conns []*grpc.ClientConn, dial func(n int) (*grpc.ClientConn, error)) *connChurner {
+	if interval <= 0 && requests == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	c := &connChurner{
+		interval: interval,
+		requests: uint64(requests),
+		dial:     dial,
+		conns:    make([]*churnConn, len(conns)),
+		retired:  make(map[*churnConn]struct{}),
+	}
+
+	for n, cc := range conns {
+		c.conns[n] = &churnConn{conn: cc, stub: grpcdynamic.NewStub(cc), opened: now}
+	}
+
+	return c
+}
+
+// due returns whether the connection is to be re-dialed
+func (c *connChurner) due(cc *churnConn, now time.Time) bool {
+	return (c.interval > 0 && now.Sub(cc.opened) >= c.interval) ||
+		(c.requests > 0 && cc.calls >= c.requests)
+}
+
+// acquire returns the connection n of the pool for a call, re-dialing it first if it is due.
+// The connection has to be released once the call is complete.
+func (c *connChurner) acquire(n int) (*churnConn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return nil, errChurnClosed
+	}
+
+	cc := c.conns[n]
+
+	if now := time.Now(); c.due(cc, now) {
+		conn, err := c.dial(n)
+		if err != nil {
+			// the previous connection is used until the next attempt once it is due again
+			c.failed++
+			cc.opened = now
+			cc.calls = 0
+		} else {
+			c.redials++
+			c.calls += cc.calls
+			c.lifetime += now.Sub(cc.opened)
+
+			c.retire(cc)
+
+			cc = &churnConn{conn: conn, stub: grpcdynamic.NewStub(conn), opened: now}
+			c.conns[n] = cc
+		}
+	}
+
+	cc.calls++
+	cc.active++
+
+	return cc, nil
+}
+
+// retire closes the replaced connection, or once its calls in flight complete
+func (c *connChurner) retire(cc *churnConn) {
+	if cc.active == 0 {
+		_ = cc.conn.Close()
+
+		return
+	}
+
+	cc.retired = true
+	c.retired[cc] = struct{}{}
+}
+
+// releaseConn completes a call made on the connection
+func (c *connChurner) releaseConn(cc *churnConn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cc.active--
+
+	if cc.retired && cc.active == 0 {
+		delete(c.retired, cc)
+
+		if !c.closed {
+			_ = cc.conn.Close()
+		}
+	}
+}
+
+// close closes all the connections
+func (c *connChurner) close() {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+
+	for _, cc := range c.conns {
+		_ = cc.conn.Close()
+	}
+
+	for cc := range c.retired {
+		_ = cc.conn.Close()
+	}
+}
+
+func (c *connChurner) stats() *ChurnStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	st := &ChurnStats{
+		Interval: c.interval,
+		Requests: uint(c.requests),
+		Redials:  c.redials,
+		Failed:   c.failed,
+	}
+
+	if c.redials > 0 {
+		st.Lifetime = c.lifetime / time.Duration(c.redials)
+		st.Calls = float64(c.calls) / float64(c.redials)
+	}
+
+	return st
+}
+
+// route returns the context of the call with the current connection of the worker
+func (c *connChurner) route(ctx context.Context, n int) context.Context {
+	cc, err := c.acquire(n)
+	if err != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, callChurnConnKey{}, cc)
+}
+
+// release completes the call on the churned connection it was made on, if any
+func (c *connChurner) release(ctx context.Context) {
+	if cc, ok := ctx.Value(callChurnConnKey{}).(*churnConn); ok {
+		c.releaseConn(cc)
+	}
+}
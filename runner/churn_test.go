@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestConnChurner(t *testing.T) {
+	assert.Nil(t, newConnChurner(0, 0, nil, nil))
+
+	dial := func(n int) (*grpc.ClientConn, error) {
+		return grpc.Dial(internal.TestLocalhost, grpc.WithInsecure())
+	}
+
+	t.Run("requests", func(t *testing.T) {
+		cc, err := dial(0)
+		assert.NoError(t, err)
+
+		c := newConnChurner(0, 2, []*grpc.ClientConn{cc}, dial)
+
+		first, err := c.acquire(0)
+		assert.NoError(t, err)
+		assert.Same(t, cc, first.conn)
+		c.releaseConn(first)
+
+		// the second call is in flight when the third one re-dials the connection
+		second, err := c.acquire(0)
+		assert.NoError(t, err)
+		assert.Same(t, first, second)
+
+		third, err := c.acquire(0)
+		assert.NoError(t, err)
+		assert.NotSame(t, first, third)
+		assert.True(t, first.retired)
+		assert.Len(t, c.retired, 1)
+
+		c.releaseConn(second)
+		assert.Empty(t, c.retired)
+
+		c.releaseConn(third)
+
+		stats := c.stats()
+		assert.Equal(t, uint(2), stats.Requests)
+		assert.Equal(t, uint64(1), stats.Redials)
+		assert.Equal(t, 2.0, stats.Calls)
+
+		c.close()
+
+		_, err = c.acquire(0)
+		assert.Equal(t, errChurnClosed, err)
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		cc, err := dial(0)
+		assert.NoError(t, err)
+
+		c := newConnChurner(20*time.Millisecond, 0, []*grpc.ClientConn{cc}, dial)
+		defer c.close()
+
+		first, err := c.acquire(0)
+		assert.NoError(t, err)
+		c.releaseConn(first)
+
+		time.Sleep(30 * time.Millisecond)
+
+		second, err := c.acquire(0)
+		assert.NoError(t, err)
+		assert.NotSame(t, first, second)
+		c.releaseConn(second)
+
+		stats := c.stats()
+		assert.Equal(t, uint64(1), stats.Redials)
+		assert.True(t, stats.Lifetime >= 20*time.Millisecond)
+	})
+
+	t.Run("failed dial", func(t *testing.T) {
+		cc, err := dial(0)
+		assert.NoError(t, err)
+
+		c := newConnChurner(0, 1, []*grpc.ClientConn{cc}, func(n int) (*grpc.ClientConn, error) {
+			return nil, errors.New("dial failed")
+		})
+		defer c.close()
+
+		for i := 0; i < 3; i++ {
+			conn, err := c.acquire(0)
+			assert.NoError(t, err)
+			assert.Same(t, cc, conn.conn)
+			c.releaseConn(conn)
+		}
+
+		stats := c.stats()
+		assert.Equal(t, uint64(0), stats.Redials)
+		assert.Equal(t, uint64(2), stats.Failed)
+	})
+}
+
+func TestRunConnectionChurn(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(40),
+		WithConcurrency(1),
+		WithConnectionChurn(0, 5),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 40, int(report.Count))
+	assert.Equal(t, uint(5), report.Options.ChurnRequests)
+
+	// the connection is re-dialed after every 5 calls
+	if assert.NotNil(t, report.Churn) {
+		assert.Equal(t, uint64(7), report.Churn.Redials)
+		assert.Equal(t, uint64(0), report.Churn.Failed)
+		assert.Equal(t, 5.0, report.Churn.Calls)
+	}
+
+	// the re-dialed connections share the statistics of the connection they replaced
+	if assert.Len(t, report.Connections, 1) {
+		assert.Equal(t, uint64(40), report.Connections[0].Calls)
+		assert.Equal(t, uint64(8), report.Connections[0].Connects)
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithShardKey("user-id"),
+		WithConnectionChurn(time.Second, 0),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "connection churn cannot be used with a shard key")
+}
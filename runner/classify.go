@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/status"
+)
+
+// ErrClassifiedFailure is the error of the calls which completed without an error
+// but were classified as failed by the success classifier
+var ErrClassifiedFailure = errors.New("call classified as failed")
+
+// callResponseKey is the context key of the last response received by the call
+type callResponseKey struct{}
+
+// callResponse holds the last response received by a call for the success classifier.
+// The responses of streams may be received concurrently with the end of the call.
+type callResponse struct {
+	lock sync.Mutex
+	msg  *dynamic.Message
+}
+
+// set sets the received payload as the last response, if it is a dynamic message
+func (r *callResponse) set(payload interface{}) {
+	msg, ok := payload.(*dynamic.Message)
+	if !ok {
+		return
+	}
+
+	r.lock.Lock()
+	r.msg = msg
+	r.lock.Unlock()
+}
+
+// response returns the last response received by the call, nil if none
+func (r *callResponse) response() *dynamic.Message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.msg
+}
+
+// classify returns the error of the call as classified by the classifier
+func classify(classifier SuccessClassifierFunc, r *callResponse, err error) error {
+	success := classifier(status.Convert(err), r.response(), err)
+
+	switch {
+	case success:
+		return nil
+	case err == nil:
+		return ErrClassifiedFailure
+	}
+
+	return err
+}
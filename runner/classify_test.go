@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassify(t *testing.T) {
+	notFound := status.Error(codes.NotFound, "not found")
+	msg := &dynamic.Message{}
+
+	classifier := func(st *status.Status, resp *dynamic.Message, err error) bool {
+		return st.Code() == codes.NotFound || (err == nil && resp != nil)
+	}
+
+	assert.NoError(t, classify(classifier, &callResponse{}, notFound))
+	assert.NoError(t, classify(classifier, &callResponse{msg: msg}, nil))
+	assert.Equal(t, ErrClassifiedFailure, classify(classifier, &callResponse{}, nil))
+
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	assert.Equal(t, unavailable, classify(classifier, &callResponse{}, unavailable))
+
+	r := &callResponse{}
+	r.set("not a message")
+	assert.Nil(t, r.msg)
+	r.set(msg)
+	assert.Same(t, msg, r.msg)
+}
+
+func TestRunSuccessClassifier(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	var calls, greetings int64
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithSuccessClassifier(func(st *status.Status, resp *dynamic.Message, err error) bool {
+			atomic.AddInt64(&calls, 1)
+
+			if resp != nil && resp.GetFieldByName("message") == "Hello bob" {
+				atomic.AddInt64(&greetings, 1)
+			}
+
+			// the greeting is not the expected one
+			return resp != nil && resp.GetFieldByName("message") == "Hello alice"
+		}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), atomic.LoadInt64(&calls))
+	assert.Equal(t, int64(10), atomic.LoadInt64(&greetings))
+	assert.Equal(t, uint64(10), report.Count)
+	assert.Equal(t, map[string]int{"OK": 10}, report.StatusCodeDist)
+	assert.Equal(t, map[string]int{ErrClassifiedFailure.Error(): 10}, report.ErrorDist)
+
+	for _, d := range report.Details {
+		assert.Equal(t, ErrClassifiedFailure.Error(), d.Error)
+	}
+}
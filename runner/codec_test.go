@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestJSONCodec(t *testing.T) {
+	codec := encoding.GetCodec("json")
+	if !assert.NotNil(t, codec) {
+		return
+	}
+
+	b, err := codec.Marshal(&helloworld.HelloRequest{Name: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"bob"}`, string(b))
+
+	msg := &helloworld.HelloRequest{}
+	assert.NoError(t, codec.Unmarshal([]byte(`{"name":"kate","other":1}`), msg))
+	assert.Equal(t, "kate", msg.GetName())
+
+	b, err = codec.Marshal(map[string]string{"name": "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"bob"}`, string(b))
+}
+
+func TestRunCodec(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	gs.ResetCounters()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(3),
+		WithConcurrency(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithCodecName("json"),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), report.Count)
+	assert.Equal(t, "json", report.Options.Codec)
+	assert.Equal(t, map[string]int{"OK": 3}, report.StatusCodeDist)
+	assert.Equal(t, 3, gs.GetCount(helloworld.Unary))
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithAsync(true),
+		WithAsyncPools(2, 2),
+		WithCodecName("json"),
+	)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tolerances are the changes of the metrics from the baseline report beyond which they regress.
+// The tolerances of the latencies and the rate are relative changes, such as 0.1 for 10%,
+// and the tolerance of the error rate is a difference in percentage points.
+type Tolerances map[Metric]float64
+
+// Comparison holds the comparison of the report with a baseline report
+type Comparison struct {
+	// Name and Date are those of the baseline report
+	Name string    `json:"name,omitempty"`
+	Date time.Time `json:"date"`
+
+	// Metrics are the comparisons of the metrics, in the order of the thresholds
+	Metrics []MetricComparison `json:"metrics"`
+}
+
+// MetricComparison is the comparison of a metric of the report with that of the baseline report
+type MetricComparison struct {
+	Metric Metric `json:"metric"`
+
+	// Baseline and Current are the values of the metric, in milliseconds for the latencies,
+	// in percent for the error rate and in requests per second for the rate
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+
+	// Change is the relative change from the baseline, such as 0.1 for a value 10% higher,
+	// or the difference in percentage points for the error rate
+	Change float64 `json:"change"`
+
+	// Tolerance is the tolerance of the metric, if any. The metrics without a tolerance do not regress.
+	Tolerance *float64 `json:"tolerance,omitempty"`
+
+	// Regressed is whether the metric got worse than the baseline beyond the tolerance,
+	// being higher for the latencies and the error rate, and lower for the rate
+	Regressed bool `json:"regressed"`
+}
+
+// Regressions returns the metrics which regressed
+func (c *Comparison) Regressions() []Metric {
+	var res []Metric
+	for _, mc := range c.Metrics {
+		if mc.Regressed {
+			res = append(res, mc.Metric)
+		}
+	}
+
+	return res
+}
+
+// CompareReports compares the throughput, the latencies and the error rate of the current report
+// with those of the baseline report, flagging the metrics which regressed beyond their tolerances.
+// The percentiles which are not in both reports are left out.
+//	cmp := runner.CompareReports(baseline, report, runner.Tolerances{
+//		runner.MetricP99:       0.1,
+//		runner.MetricRPS:       0.05,
+//		runner.MetricErrorRate: 1,
+//	})
+func CompareReports(baseline, current *Report, tolerances Tolerances) *Comparison {
+	c := &Comparison{Name: baseline.Name, Date: baseline.Date}
+
+	for _, m := range thresholdMetrics {
+		if !m.isLatency() || m == MetricAverage || m == MetricFastest || m == MetricSlowest ||
+			(hasPercentile(baseline, m) && hasPercentile(current, m)) {
+			c.Metrics = append(c.Metrics, compareMetric(m, baseline, current, tolerances))
+		}
+	}
+
+	return c
+}
+
+// compareMetric compares the metric of the reports
+func compareMetric(m Metric, baseline, current *Report, tolerances Tolerances) MetricComparison {
+	mc := MetricComparison{
+		Metric:   m,
+		Baseline: math.Round(metricValue(m, baseline)*100) / 100,
+		Current:  math.Round(metricValue(m, current)*100) / 100,
+	}
+
+	if m == MetricErrorRate {
+		mc.Change = mc.Current - mc.Baseline
+	} else {
+		mc.Change = relativeChange(mc.Baseline, mc.Current)
+	}
+
+	mc.Change = math.Round(mc.Change*10000) / 10000
+
+	if t, ok := tolerances[m]; ok {
+		mc.Tolerance = &t
+
+		if m == MetricRPS {
+			mc.Regressed = mc.Change < -t
+		} else {
+			mc.Regressed = mc.Change > t
+		}
+	}
+
+	return mc
+}
+
+// hasPercentile returns whether the latency distribution of the report has the percentile of the metric
+func hasPercentile(r *Report, m Metric) bool {
+	pct, _ := strconv.Atoi(strings.TrimPrefix(string(m), "p"))
+	for _, ld := range r.LatencyDistribution {
+		if ld.Percentage == pct {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseTolerance parses a tolerance in the form of <metric>=<percent>. The percent of the latencies
+// and the rate is relative to the baseline, and that of the error rate is a difference in percentage
+// points. It can be followed by %.
+//	ParseTolerance("p99=10%")
+//	ParseTolerance("error-rate=0.5")
+func ParseTolerance(s string) (Metric, float64, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid tolerance %q: expected <metric>=<percent>", s)
+	}
+
+	m := Metric(strings.ToLower(strings.TrimSpace(parts[0])))
+	if !m.valid() {
+		return "", 0, fmt.Errorf("invalid tolerance %q: unknown metric %q", s, m)
+	}
+
+	value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(parts[1]), "%"))
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v < 0 {
+		return "", 0, fmt.Errorf("invalid tolerance %q: invalid percent %q", s, value)
+	}
+
+	if m != MetricErrorRate {
+		v /= 100
+	}
+
+	return m, v, nil
+}
+
+// ParseTolerances parses the tolerances using ParseTolerance, the last tolerance of a metric taking effect
+func ParseTolerances(tolerances ...string) (Tolerances, error) {
+	res := make(Tolerances, len(tolerances))
+	for _, s := range tolerances {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+
+		m, t, err := ParseTolerance(s)
+		if err != nil {
+			return nil, err
+		}
+
+		res[m] = t
+	}
+
+	return res, nil
+}
+
+// LoadReport reads the JSON report of a previous run from the file, such as a baseline to compare with
+func LoadReport(path string) (*Report, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %v", path, err)
+	}
+
+	return &r, nil
+}
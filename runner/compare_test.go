@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareReports(t *testing.T) {
+	baseline := &Report{
+		Name:    "nightly",
+		Date:    time.Date(2026, 10, 14, 2, 0, 0, 0, time.UTC),
+		Count:   1000,
+		Average: 10 * time.Millisecond,
+		Fastest: 2 * time.Millisecond,
+		Slowest: 80 * time.Millisecond,
+		Rps:     2000,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 9 * time.Millisecond},
+			{Percentage: 99, Latency: 40 * time.Millisecond},
+		},
+		ErrorDist: map[string]int{"unavailable": 1},
+	}
+
+	current := &Report{
+		Count:   1000,
+		Average: 11 * time.Millisecond,
+		Fastest: 2 * time.Millisecond,
+		Slowest: 90 * time.Millisecond,
+		Rps:     1800,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 9 * time.Millisecond},
+			{Percentage: 90, Latency: 20 * time.Millisecond},
+			{Percentage: 99, Latency: 50 * time.Millisecond},
+		},
+		ErrorDist: map[string]int{"unavailable": 5},
+	}
+
+	tolerance := func(v float64) *float64 {
+		return &v
+	}
+
+	c := CompareReports(baseline, current, Tolerances{MetricP99: 0.3, MetricRPS: 0.05, MetricErrorRate: 0.5})
+
+	assert.Equal(t, "nightly", c.Name)
+	assert.Equal(t, baseline.Date, c.Date)
+
+	// the p90 is only in the current report
+	assert.Equal(t, []MetricComparison{
+		{Metric: MetricAverage, Baseline: 10, Current: 11, Change: 0.1},
+		{Metric: MetricFastest, Baseline: 2, Current: 2},
+		{Metric: MetricSlowest, Baseline: 80, Current: 90, Change: 0.125},
+		{Metric: MetricP50, Baseline: 9, Current: 9},
+		{Metric: MetricP99, Baseline: 40, Current: 50, Change: 0.25, Tolerance: tolerance(0.3)},
+		{Metric: MetricErrorRate, Baseline: 0.1, Current: 0.5, Change: 0.4, Tolerance: tolerance(0.5)},
+		{Metric: MetricRPS, Baseline: 2000, Current: 1800, Change: -0.1, Tolerance: tolerance(0.05), Regressed: true},
+	}, c.Metrics)
+
+	assert.Equal(t, []Metric{MetricRPS}, c.Regressions())
+
+	c = CompareReports(baseline, current, Tolerances{MetricP99: 0.2, MetricErrorRate: 0.2})
+	assert.Equal(t, []Metric{MetricP99, MetricErrorRate}, c.Regressions())
+
+	// a report without regressions passes the thresholds
+	current.Comparison = CompareReports(baseline, current, nil)
+	assert.Empty(t, current.Comparison.Regressions())
+	assert.True(t, current.ThresholdsPassed())
+
+	current.Comparison = c
+	assert.False(t, current.ThresholdsPassed())
+}
+
+func TestParseTolerances(t *testing.T) {
+	tolerances, err := ParseTolerances("p99=10%", " rps = 5 ", "error-rate=0.5%", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Tolerances{MetricP99: 0.1, MetricRPS: 0.05, MetricErrorRate: 0.5}, tolerances)
+
+	_, err = ParseTolerances("p99")
+	assert.EqualError(t, err, `invalid tolerance "p99": expected <metric>=<percent>`)
+
+	_, err = ParseTolerances("p42=10%")
+	assert.EqualError(t, err, `invalid tolerance "p42=10%": unknown metric "p42"`)
+
+	_, err = ParseTolerances("rps=-5")
+	assert.EqualError(t, err, `invalid tolerance "rps=-5": invalid percent "-5"`)
+}
+
+func TestLoadReport(t *testing.T) {
+	report := &Report{
+		Name:    "nightly",
+		Count:   200,
+		Average: 12 * time.Millisecond,
+		Rps:     1000,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 99, Latency: 40 * time.Millisecond},
+		},
+		EndReason: ReasonNormalEnd,
+	}
+
+	b, err := json.Marshal(report)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	assert.NoError(t, ioutil.WriteFile(path, b, 0644))
+
+	loaded, err := LoadReport(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "nightly", loaded.Name)
+	assert.Equal(t, uint64(200), loaded.Count)
+	assert.Equal(t, 12*time.Millisecond, loaded.Average)
+	assert.Equal(t, report.LatencyDistribution, loaded.LatencyDistribution)
+	assert.Equal(t, ReasonNormalEnd, loaded.EndReason)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("{"), 0644))
+
+	_, err = LoadReport(path)
+	assert.Error(t, err)
+}
+
+func TestRunComparison(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	// the rate of the baseline cannot be reached
+	baseline := &Report{Name: "baseline", Count: 10, Average: time.Second, Rps: 1000000}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithComparison(baseline, Tolerances{MetricAverage: 0, MetricRPS: 0.1}),
+		WithInsecure(true),
+	)
+
+	assert.Equal(t, ErrThresholdsFailed, err)
+
+	if assert.NotNil(t, report) && assert.NotNil(t, report.Comparison) {
+		assert.Equal(t, "baseline", report.Comparison.Name)
+		assert.Equal(t, []Metric{MetricRPS}, report.Comparison.Regressions())
+	}
+}
@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// CompressionStats holds the bytes of the messages of the calls before and after compression.
+// The wire bytes include the 5 byte prefix of each message.
+type CompressionStats struct {
+	Compressor string `json:"compressor"`
+
+	BytesSent             uint64 `json:"bytesSent"`
+	UncompressedBytesSent uint64 `json:"uncompressedBytesSent"`
+
+	BytesReceived             uint64 `json:"bytesReceived"`
+	UncompressedBytesReceived uint64 `json:"uncompressedBytesReceived"`
+
+	// SentRatio and ReceivedRatio are the wire bytes as a fraction of the uncompressed bytes
+	SentRatio     float64 `json:"sentRatio"`
+	ReceivedRatio float64 `json:"receivedRatio"`
+}
+
+// compressorName returns the name of the compressor of the calls, or an empty string if the calls
+// are not compressed. The compressor takes precedence over the gzip compression being enabled.
+func (c *RunConfig) compressorName() string {
+	if c.compressor != "" {
+		return c.compressor
+	}
+
+	if c.enableCompression {
+		return gzip.Name
+	}
+
+	return ""
+}
+
+// compressionStats returns the bytes of the messages sent and received on the connections
+// of the stats handlers, or nil if the calls are not compressed
+func compressionStats(compressor string, handlers []*statsHandler) *CompressionStats {
+	if compressor == "" {
+		return nil
+	}
+
+	s := &CompressionStats{Compressor: compressor}
+	for _, h := range handlers {
+		h.connLock.Lock()
+		s.BytesSent += h.bytesSent
+		s.UncompressedBytesSent += h.payloadSent
+		s.BytesReceived += h.bytesReceived
+		s.UncompressedBytesReceived += h.payloadReceived
+		h.connLock.Unlock()
+	}
+
+	if s.UncompressedBytesSent > 0 {
+		s.SentRatio = float64(s.BytesSent) / float64(s.UncompressedBytesSent)
+	}
+
+	if s.UncompressedBytesReceived > 0 {
+		s.ReceivedRatio = float64(s.BytesReceived) / float64(s.UncompressedBytesReceived)
+	}
+
+	return s
+}
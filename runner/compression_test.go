@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCompression(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	name := strings.Repeat("bob", 1000)
+
+	t.Run("compressed", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(4),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": name}),
+			WithCompressor("gzip"),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "gzip", report.Options.Compressor)
+		assert.Equal(t, map[string]int{"OK": 4}, report.StatusCodeDist)
+
+		if assert.NotNil(t, report.Compression) {
+			assert.Equal(t, "gzip", report.Compression.Compressor)
+			assert.Equal(t, uint64(4*(len(name)+3)), report.Compression.UncompressedBytesSent)
+			assert.True(t, report.Compression.SentRatio < 0.1)
+			assert.NotZero(t, report.Compression.UncompressedBytesReceived)
+		}
+
+		// the responses are compressed with the compressor of the requests
+		if assert.NotNil(t, report.Decompression) {
+			assert.Equal(t, "gzip", report.Decompression.Compressor)
+			assert.Equal(t, uint64(4), report.Decompression.Messages)
+			assert.Equal(t, report.Compression.UncompressedBytesReceived, report.Decompression.DecompressedBytes)
+			assert.Equal(t, report.Compression.BytesReceived-4*5, report.Decompression.CompressedBytes)
+			assert.NotZero(t, report.Decompression.SavedBytes)
+			assert.NotZero(t, report.Decompression.Total)
+			assert.NotEmpty(t, report.Decompression.LatencyDistribution)
+		}
+	})
+
+	t.Run("uncompressed", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(4),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": name}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Nil(t, report.Compression)
+		assert.Nil(t, report.Decompression)
+	})
+}
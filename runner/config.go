@@ -1,12 +1,18 @@
 package runner
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
-	"path"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/jinzhu/configor"
+	"gopkg.in/yaml.v2"
 )
 
 // Duration is our duration with TOML support
@@ -63,13 +69,50 @@ type Config struct {
 	Cert                  string            `json:"cert" toml:"cert" yaml:"cert"`
 	Key                   string            `json:"key" toml:"key" yaml:"key"`
 	CountErrors           bool              `json:"count-errors" toml:"count-errors" yaml:"count-errors"`
+	CorrectionInterval    Duration          `json:"co-interval,omitempty" toml:"co-interval,omitempty" yaml:"co-interval,omitempty"`
+	GracePeriod           Duration          `json:"grace-period,omitempty" toml:"grace-period,omitempty" yaml:"grace-period,omitempty"`
+	Warmup                Duration          `json:"warmup,omitempty" toml:"warmup,omitempty" yaml:"warmup,omitempty"`
+	HedgePercent          float64           `json:"hedge-percent,omitempty" toml:"hedge-percent,omitempty" yaml:"hedge-percent,omitempty"`
+	HedgeDelay            Duration          `json:"hedge-delay,omitempty" toml:"hedge-delay,omitempty" yaml:"hedge-delay,omitempty"`
+	Channelz              bool              `json:"channelz,omitempty" toml:"channelz,omitempty" yaml:"channelz,omitempty"`
+	ChannelzInterval      Duration          `json:"channelz-interval,omitempty" toml:"channelz-interval,omitempty" yaml:"channelz-interval,omitempty"`
+	MetricsAddr           string            `json:"metrics-addr,omitempty" toml:"metrics-addr,omitempty" yaml:"metrics-addr,omitempty"`
+	DebugAddr             string            `json:"debug-addr,omitempty" toml:"debug-addr,omitempty" yaml:"debug-addr,omitempty"`
+	Push                  string            `json:"push,omitempty" toml:"push,omitempty" yaml:"push,omitempty"`
+	PushURL               string            `json:"push-url,omitempty" toml:"push-url,omitempty" yaml:"push-url,omitempty"`
+	PushInterval          Duration          `json:"push-interval,omitempty" toml:"push-interval,omitempty" yaml:"push-interval,omitempty"`
+	PushHeaders           []string          `json:"push-header,omitempty" toml:"push-header,omitempty" yaml:"push-header,omitempty"`
+	PushPrefix            string            `json:"push-prefix,omitempty" toml:"push-prefix,omitempty" yaml:"push-prefix,omitempty"`
+	HistogramBuckets      string            `json:"histogram-buckets,omitempty" toml:"histogram-buckets,omitempty" yaml:"histogram-buckets,omitempty"`
+	RawHistogram          bool              `json:"raw-histogram,omitempty" toml:"raw-histogram,omitempty" yaml:"raw-histogram,omitempty"`
+	StreamingPercentiles  bool              `json:"streaming-percentiles,omitempty" toml:"streaming-percentiles,omitempty" yaml:"streaming-percentiles,omitempty"`
+	CompactDetails        bool              `json:"compact-details,omitempty" toml:"compact-details,omitempty" yaml:"compact-details,omitempty"`
+	ApdexThreshold        Duration          `json:"apdex-threshold,omitempty" toml:"apdex-threshold,omitempty" yaml:"apdex-threshold,omitempty"`
+	TimeSeries            Duration          `json:"time-series,omitempty" toml:"time-series,omitempty" yaml:"time-series,omitempty"`
+	TimeSlice             Duration          `json:"time-slice,omitempty" toml:"time-slice,omitempty" yaml:"time-slice,omitempty"`
 	SkipTLSVerify         bool              `json:"skipTLS" toml:"skipTLS" yaml:"skipTLS"`
 	SkipFirst             uint              `json:"skipFirst" toml:"skipFirst" yaml:"skipFirst"`
 	CName                 string            `json:"cname" toml:"cname" yaml:"cname"`
 	Authority             string            `json:"authority" toml:"authority" yaml:"authority"`
+	OAuthTokenURL         string            `json:"oauth-token-url,omitempty" toml:"oauth-token-url,omitempty" yaml:"oauth-token-url,omitempty"`
+	OAuthClientID         string            `json:"oauth-client-id,omitempty" toml:"oauth-client-id,omitempty" yaml:"oauth-client-id,omitempty"`
+	OAuthClientSecret     string            `json:"oauth-client-secret,omitempty" toml:"oauth-client-secret,omitempty" yaml:"oauth-client-secret,omitempty"`
+	OAuthScopes           []string          `json:"oauth-scopes,omitempty" toml:"oauth-scopes,omitempty" yaml:"oauth-scopes,omitempty"`
+	TokenFile             string            `json:"token-file,omitempty" toml:"token-file,omitempty" yaml:"token-file,omitempty"`
+	TokenRefresh          Duration          `json:"token-refresh,omitempty" toml:"token-refresh,omitempty" yaml:"token-refresh,omitempty"`
+	Resolve               []string          `json:"resolve,omitempty" toml:"resolve,omitempty" yaml:"resolve,omitempty"`
+	Targets               []string          `json:"targets,omitempty" toml:"targets,omitempty" yaml:"targets,omitempty"`
+	ServiceConfig         string            `json:"service-config,omitempty" toml:"service-config,omitempty" yaml:"service-config,omitempty"`
+	IPVersion             uint              `json:"ip-version,omitempty" toml:"ip-version,omitempty" yaml:"ip-version,omitempty"`
+	Transport             string            `json:"transport,omitempty" toml:"transport,omitempty" yaml:"transport,omitempty"`
+	Proxy                 string            `json:"proxy,omitempty" toml:"proxy,omitempty" yaml:"proxy,omitempty"`
 	Insecure              bool              `json:"insecure,omitempty" toml:"insecure,omitempty" yaml:"insecure,omitempty"`
 	N                     uint              `json:"total" toml:"total" yaml:"total" default:"200"`
+	MaxErrors             uint              `json:"max-errors,omitempty" toml:"max-errors,omitempty" yaml:"max-errors,omitempty"`
+	FailFast              bool              `json:"fail-fast,omitempty" toml:"fail-fast,omitempty" yaml:"fail-fast,omitempty"`
 	Async                 bool              `json:"async,omitempty" toml:"async,omitempty" yaml:"async,omitempty"`
+	AsyncSenders          uint              `json:"async-senders,omitempty" toml:"async-senders,omitempty" yaml:"async-senders,omitempty"`
+	AsyncHandlers         uint              `json:"async-handlers,omitempty" toml:"async-handlers,omitempty" yaml:"async-handlers,omitempty"`
 	C                     uint              `json:"concurrency" toml:"concurrency" yaml:"concurrency" default:"50"`
 	CSchedule             string            `json:"concurrency-schedule" toml:"concurrency-schedule" yaml:"concurrency-schedule" default:"const"`
 	CStart                uint              `json:"concurrency-start" toml:"concurrency-start" yaml:"concurrency-start" default:"1"`
@@ -77,41 +120,183 @@ type Config struct {
 	CStep                 int               `json:"concurrency-step" toml:"concurrency-step" yaml:"concurrency-step" default:"0"`
 	CStepDuration         Duration          `json:"concurrency-step-duration" toml:"concurrency-step-duration" yaml:"concurrency-step-duration" default:"0"`
 	CMaxDuration          Duration          `json:"concurrency-max-duration" toml:"concurrency-max-duration" yaml:"concurrency-max-duration" default:"0"`
+	WorkerStagger         Duration          `json:"worker-stagger,omitempty" toml:"worker-stagger,omitempty" yaml:"worker-stagger,omitempty"`
+	WorkerPace            string            `json:"worker-pace,omitempty" toml:"worker-pace,omitempty" yaml:"worker-pace,omitempty"`
+	WorkerGroups          []string          `json:"worker-group,omitempty" toml:"worker-group,omitempty" yaml:"worker-group,omitempty"`
+	ControlFile           string            `json:"control-file,omitempty" toml:"control-file,omitempty" yaml:"control-file,omitempty"`
+	RateSocket            string            `json:"rate-socket,omitempty" toml:"rate-socket,omitempty" yaml:"rate-socket,omitempty"`
 	Connections           uint              `json:"connections" toml:"connections" yaml:"connections" default:"1"`
+	ConnectionMode        string            `json:"connection-mode,omitempty" toml:"connection-mode,omitempty" yaml:"connection-mode,omitempty"`
+	WorkersPerConnection  uint              `json:"workers-per-connection,omitempty" toml:"workers-per-connection,omitempty" yaml:"workers-per-connection,omitempty"`
+	ConnectionMap         []uint            `json:"connection-map,omitempty" toml:"connection-map,omitempty" yaml:"connection-map,omitempty"`
+	ShardKey              string            `json:"shard-key,omitempty" toml:"shard-key,omitempty" yaml:"shard-key,omitempty"`
+	ShardConns            uint              `json:"shard-conns,omitempty" toml:"shard-conns,omitempty" yaml:"shard-conns,omitempty"`
+	ChurnInterval         Duration          `json:"churn-interval,omitempty" toml:"churn-interval,omitempty" yaml:"churn-interval,omitempty"`
+	ChurnRequests         uint              `json:"churn-requests,omitempty" toml:"churn-requests,omitempty" yaml:"churn-requests,omitempty"`
+	ScaleConns            uint              `json:"scale-conns,omitempty" toml:"scale-conns,omitempty" yaml:"scale-conns,omitempty"`
+	ScaleRatio            float64           `json:"scale-ratio,omitempty" toml:"scale-ratio,omitempty" yaml:"scale-ratio,omitempty"`
+	ScaleInterval         Duration          `json:"scale-interval,omitempty" toml:"scale-interval,omitempty" yaml:"scale-interval,omitempty"`
+	ConnectPolicy         string            `json:"connect-policy,omitempty" toml:"connect-policy,omitempty" yaml:"connect-policy,omitempty"`
+	ConnectRetries        uint              `json:"connect-retries,omitempty" toml:"connect-retries,omitempty" yaml:"connect-retries,omitempty"`
+	Baseline              uint              `json:"baseline,omitempty" toml:"baseline,omitempty" yaml:"baseline,omitempty"`
 	RPS                   uint              `json:"rps" toml:"rps" yaml:"rps"`
 	Z                     Duration          `json:"duration" toml:"duration" yaml:"duration"`
 	ZStop                 string            `json:"duration-stop" toml:"duration-stop" yaml:"duration-stop" default:"close"`
+	DrainTimeout          Duration          `json:"drain-timeout,omitempty" toml:"drain-timeout,omitempty" yaml:"drain-timeout,omitempty"`
 	X                     Duration          `json:"max-duration" toml:"max-duration" yaml:"max-duration"`
+	UntilSignal           bool              `json:"until-signal,omitempty" toml:"until-signal,omitempty" yaml:"until-signal,omitempty"`
 	Timeout               Duration          `json:"timeout" toml:"timeout" yaml:"timeout" default:"20s"`
+	CallMaxDuration       Duration          `json:"call-max-duration,omitempty" toml:"call-max-duration,omitempty" yaml:"call-max-duration,omitempty"`
 	Data                  interface{}       `json:"data,omitempty" toml:"data,omitempty" yaml:"data,omitempty"`
 	DataPath              string            `json:"data-file" toml:"data-file" yaml:"data-file"`
+	DataLines             bool              `json:"data-lines,omitempty" toml:"data-lines,omitempty" yaml:"data-lines,omitempty"`
+	DataIndexed           bool              `json:"data-indexed,omitempty" toml:"data-indexed,omitempty" yaml:"data-indexed,omitempty"`
+	DataSelect            string            `json:"data-select,omitempty" toml:"data-select,omitempty" yaml:"data-select,omitempty"`
+	DataLabel             string            `json:"data-label,omitempty" toml:"data-label,omitempty" yaml:"data-label,omitempty"`
+	DataPartition         bool              `json:"data-partition,omitempty" toml:"data-partition,omitempty" yaml:"data-partition,omitempty"`
+	EmitDefaults          bool              `json:"emit-defaults,omitempty" toml:"emit-defaults,omitempty" yaml:"emit-defaults,omitempty"`
+	DataRedact            []string          `json:"data-redact,omitempty" toml:"data-redact,omitempty" yaml:"data-redact,omitempty"`
+	DataTokenize          []string          `json:"data-tokenize,omitempty" toml:"data-tokenize,omitempty" yaml:"data-tokenize,omitempty"`
+	DataTokenKey          string            `json:"data-token-key,omitempty" toml:"data-token-key,omitempty" yaml:"data-token-key,omitempty"`
 	BinData               []byte            `json:"-" toml:"-" yaml:"-"`
 	BinDataPath           string            `json:"binary-file" toml:"binary-file" yaml:"binary-file"`
+	PayloadFile           string            `json:"payload-file,omitempty" toml:"payload-file,omitempty" yaml:"payload-file,omitempty"`
+	PayloadField          string            `json:"payload-field,omitempty" toml:"payload-field,omitempty" yaml:"payload-field,omitempty"`
+	PayloadChunkSize      uint              `json:"payload-chunk-size,omitempty" toml:"payload-chunk-size,omitempty" yaml:"payload-chunk-size,omitempty"`
 	Metadata              map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty" yaml:"metadata,omitempty"`
 	MetadataPath          string            `json:"metadata-file" toml:"metadata-file" yaml:"metadata-file"`
 	SI                    Duration          `json:"stream-interval" toml:"stream-interval" yaml:"stream-interval"`
 	StreamCallDuration    Duration          `json:"stream-call-duration" toml:"stream-call-duration" yaml:"stream-call-duration"`
+	StreamMaxDuration     Duration          `json:"stream-max-duration,omitempty" toml:"stream-max-duration,omitempty" yaml:"stream-max-duration,omitempty"`
 	StreamCallCount       uint              `json:"stream-call-count" toml:"stream-call-count" yaml:"stream-call-count"`
 	StreamDynamicMessages bool              `json:"stream-dynamic-messages" toml:"stream-dynamic-messages" yaml:"stream-dynamic-messages"`
+	CorrelationField      string            `json:"stream-correlation-field,omitempty" toml:"stream-correlation-field,omitempty" yaml:"stream-correlation-field,omitempty"`
+	StreamAckField        string            `json:"stream-ack-field,omitempty" toml:"stream-ack-field,omitempty" yaml:"stream-ack-field,omitempty"`
+	StreamRecvDelay       Duration          `json:"stream-recv-delay,omitempty" toml:"stream-recv-delay,omitempty" yaml:"stream-recv-delay,omitempty"`
+	ResponseField         string            `json:"response-field,omitempty" toml:"response-field,omitempty" yaml:"response-field,omitempty"`
+	Assert                []string          `json:"assert,omitempty" toml:"assert,omitempty" yaml:"assert,omitempty"`
+	StageTiming           string            `json:"stage-timing,omitempty" toml:"stage-timing,omitempty" yaml:"stage-timing,omitempty"`
+	Parallel              []ParallelCall    `json:"parallel,omitempty" toml:"parallel,omitempty" yaml:"parallel,omitempty"`
+	Scenario              []ScenarioCall    `json:"scenario,omitempty" toml:"scenario,omitempty" yaml:"scenario,omitempty"`
+	ScenarioMode          string            `json:"scenario-mode,omitempty" toml:"scenario-mode,omitempty" yaml:"scenario-mode,omitempty"`
+	ScenarioRate          uint              `json:"scenario-rate,omitempty" toml:"scenario-rate,omitempty" yaml:"scenario-rate,omitempty"`
+	Tenants               []Tenant          `json:"tenants,omitempty" toml:"tenants,omitempty" yaml:"tenants,omitempty"`
+	ParallelBaseline      bool              `json:"parallel-baseline,omitempty" toml:"parallel-baseline,omitempty" yaml:"parallel-baseline,omitempty"`
+	ABHost                string            `json:"ab-host,omitempty" toml:"ab-host,omitempty" yaml:"ab-host,omitempty"`
+	ABAuthority           string            `json:"ab-authority,omitempty" toml:"ab-authority,omitempty" yaml:"ab-authority,omitempty"`
+	ABRounds              uint              `json:"ab-rounds,omitempty" toml:"ab-rounds,omitempty" yaml:"ab-rounds,omitempty"`
+	ABPause               Duration          `json:"ab-pause,omitempty" toml:"ab-pause,omitempty" yaml:"ab-pause,omitempty"`
+	Agents                []string          `json:"agents,omitempty" toml:"agents,omitempty" yaml:"agents,omitempty"`
+	IgnoreProtoDefaults   bool              `json:"ignore-proto-defaults,omitempty" toml:"ignore-proto-defaults,omitempty" yaml:"ignore-proto-defaults,omitempty"`
+	Ratio                 string            `json:"ratio,omitempty" toml:"ratio,omitempty" yaml:"ratio,omitempty"`
+	Paginate              bool              `json:"paginate,omitempty" toml:"paginate,omitempty" yaml:"paginate,omitempty"`
+	PageTokenFields       string            `json:"page-token-fields,omitempty" toml:"page-token-fields,omitempty" yaml:"page-token-fields,omitempty"`
+	MaxPages              uint              `json:"max-pages,omitempty" toml:"max-pages,omitempty" yaml:"max-pages,omitempty"`
+	SessionCall           string            `json:"session-call,omitempty" toml:"session-call,omitempty" yaml:"session-call,omitempty"`
+	SessionData           interface{}       `json:"session-data,omitempty" toml:"session-data,omitempty" yaml:"session-data,omitempty"`
+	SessionToken          string            `json:"session-token,omitempty" toml:"session-token,omitempty" yaml:"session-token,omitempty"`
+	SessionMetadata       map[string]string `json:"session-metadata,omitempty" toml:"session-metadata,omitempty" yaml:"session-metadata,omitempty"`
+	SessionCloseCall      string            `json:"session-close-call,omitempty" toml:"session-close-call,omitempty" yaml:"session-close-call,omitempty"`
+	SessionCloseData      interface{}       `json:"session-close-data,omitempty" toml:"session-close-data,omitempty" yaml:"session-close-data,omitempty"`
+	ReflectRefresh        bool              `json:"reflect-refresh,omitempty" toml:"reflect-refresh,omitempty" yaml:"reflect-refresh,omitempty"`
+	ReflectProtoset       string            `json:"reflect-protoset,omitempty" toml:"reflect-protoset,omitempty" yaml:"reflect-protoset,omitempty"`
+	ReflectCache          string            `json:"reflect-cache,omitempty" toml:"reflect-cache,omitempty" yaml:"reflect-cache,omitempty"`
+	ReflectCacheTTL       Duration          `json:"reflect-cache-ttl,omitempty" toml:"reflect-cache-ttl,omitempty" yaml:"reflect-cache-ttl,omitempty"`
+	SchemaDrift           bool              `json:"schema-drift,omitempty" toml:"schema-drift,omitempty" yaml:"schema-drift,omitempty"`
+	SchemaDriftFail       bool              `json:"schema-drift-fail,omitempty" toml:"schema-drift-fail,omitempty" yaml:"schema-drift-fail,omitempty"`
+	ServerInfo            bool              `json:"server-info,omitempty" toml:"server-info,omitempty" yaml:"server-info,omitempty"`
+	ServerVersionCall     string            `json:"server-version-call,omitempty" toml:"server-version-call,omitempty" yaml:"server-version-call,omitempty"`
+	ReadinessTimeout      Duration          `json:"readiness-timeout,omitempty" toml:"readiness-timeout,omitempty" yaml:"readiness-timeout,omitempty"`
+	ReadinessService      string            `json:"readiness-service,omitempty" toml:"readiness-service,omitempty" yaml:"readiness-service,omitempty"`
+	ReadinessCall         string            `json:"readiness-call,omitempty" toml:"readiness-call,omitempty" yaml:"readiness-call,omitempty"`
+	Canary                string            `json:"canary,omitempty" toml:"canary,omitempty" yaml:"canary,omitempty"`
+	CanaryData            interface{}       `json:"canary-data,omitempty" toml:"canary-data,omitempty" yaml:"canary-data,omitempty"`
+	CanaryMetadata        map[string]string `json:"canary-metadata,omitempty" toml:"canary-metadata,omitempty" yaml:"canary-metadata,omitempty"`
+	CanaryExpect          interface{}       `json:"canary-expect,omitempty" toml:"canary-expect,omitempty" yaml:"canary-expect,omitempty"`
+	CanaryIgnore          []string          `json:"canary-ignore,omitempty" toml:"canary-ignore,omitempty" yaml:"canary-ignore,omitempty"`
+	CanaryInterval        Duration          `json:"canary-interval,omitempty" toml:"canary-interval,omitempty" yaml:"canary-interval,omitempty"`
 	Output                string            `json:"output" toml:"output" yaml:"output"`
 	Format                string            `json:"format" toml:"format" yaml:"format" default:"summary"`
+	Template              string            `json:"template,omitempty" toml:"template,omitempty" yaml:"template,omitempty"`
+	SummaryOnly           bool              `json:"summary-only,omitempty" toml:"summary-only,omitempty" yaml:"summary-only,omitempty"`
+	OutputRotate          Duration          `json:"output-rotate,omitempty" toml:"output-rotate,omitempty" yaml:"output-rotate,omitempty"`
+	GrafanaDashboard      string            `json:"grafana-dashboard,omitempty" toml:"grafana-dashboard,omitempty" yaml:"grafana-dashboard,omitempty"`
+	GrafanaDatasource     string            `json:"grafana-datasource,omitempty" toml:"grafana-datasource,omitempty" yaml:"grafana-datasource,omitempty"`
 	DialTimeout           Duration          `json:"connect-timeout" toml:"connect-timeout" yaml:"connect-timeout" default:"10s"`
 	KeepaliveTime         Duration          `json:"keepalive" toml:"keepalive" yaml:"keepalive"`
 	CPUs                  uint              `json:"cpus" toml:"cpus" yaml:"cpus"`
 	ImportPaths           []string          `json:"import-paths,omitempty" toml:"import-paths,omitempty" yaml:"import-paths,omitempty"`
+	RunID                 string            `json:"run-id,omitempty" toml:"run-id,omitempty" yaml:"run-id,omitempty"`
+	RunIDHeader           string            `json:"run-id-header,omitempty" toml:"run-id-header,omitempty" yaml:"run-id-header,omitempty"`
+	NoRunIDHeader         bool              `json:"no-run-id-header,omitempty" toml:"no-run-id-header,omitempty" yaml:"no-run-id-header,omitempty"`
 	Name                  string            `json:"name,omitempty" toml:"name,omitempty" yaml:"name,omitempty"`
 	Tags                  map[string]string `json:"tags,omitempty" toml:"tags,omitempty" yaml:"tags,omitempty"`
 	ReflectMetadata       map[string]string `json:"reflect-metadata,omitempty" toml:"reflect-metadata,omitempty" yaml:"reflect-metadata,omitempty"`
 	Debug                 string            `json:"debug,omitempty" toml:"debug,omitempty" yaml:"debug,omitempty"`
+	DebugCalls            uint              `json:"debug-calls,omitempty" toml:"debug-calls,omitempty" yaml:"debug-calls,omitempty"`
+	DebugErrors           uint              `json:"debug-errors,omitempty" toml:"debug-errors,omitempty" yaml:"debug-errors,omitempty"`
+	WireLog               string            `json:"wire-log,omitempty" toml:"wire-log,omitempty" yaml:"wire-log,omitempty"`
+	WireLogCall           uint              `json:"wire-log-call,omitempty" toml:"wire-log-call,omitempty" yaml:"wire-log-call,omitempty"`
+	Progress              bool              `json:"progress,omitempty" toml:"progress,omitempty" yaml:"progress,omitempty"`
+	ProgressInterval      Duration          `json:"progress-interval,omitempty" toml:"progress-interval,omitempty" yaml:"progress-interval,omitempty"`
+	LogSlow               Duration          `json:"log-slow,omitempty" toml:"log-slow,omitempty" yaml:"log-slow,omitempty"`
+	LogSlowMax            uint              `json:"log-slow-max,omitempty" toml:"log-slow-max,omitempty" yaml:"log-slow-max,omitempty"`
+	LogSlowCapture        bool              `json:"log-slow-capture,omitempty" toml:"log-slow-capture,omitempty" yaml:"log-slow-capture,omitempty"`
+	CaptureFile           string            `json:"capture-file,omitempty" toml:"capture-file,omitempty" yaml:"capture-file,omitempty"`
+	CaptureRate           float64           `json:"capture-rate,omitempty" toml:"capture-rate,omitempty" yaml:"capture-rate,omitempty"`
+	TraceSample           float64           `json:"trace-sample,omitempty" toml:"trace-sample,omitempty" yaml:"trace-sample,omitempty"`
 	Host                  string            `json:"host" toml:"host" yaml:"host"`
 	EnableCompression     bool              `json:"enable-compression,omitempty" toml:"enable-compression,omitempty" yaml:"enable-compression,omitempty"`
+	Codec                 string            `json:"codec,omitempty" toml:"codec,omitempty" yaml:"codec,omitempty"`
+	Compressor            string            `json:"compressor,omitempty" toml:"compressor,omitempty" yaml:"compressor,omitempty"`
+	WaitForReady          bool              `json:"wait-for-ready,omitempty" toml:"wait-for-ready,omitempty" yaml:"wait-for-ready,omitempty"`
+	MaxRecvMsgSize        uint              `json:"max-recv-msg-size,omitempty" toml:"max-recv-msg-size,omitempty" yaml:"max-recv-msg-size,omitempty"`
+	MaxSendMsgSize        uint              `json:"max-send-msg-size,omitempty" toml:"max-send-msg-size,omitempty" yaml:"max-send-msg-size,omitempty"`
 	LoadSchedule          string            `json:"load-schedule" toml:"load-schedule" yaml:"load-schedule" default:"const"`
 	LoadStart             uint              `json:"load-start" toml:"load-start" yaml:"load-start"`
 	LoadEnd               uint              `json:"load-end" toml:"load-end" yaml:"load-end"`
 	LoadStep              int               `json:"load-step" toml:"load-step" yaml:"load-step"`
 	LoadStepDuration      Duration          `json:"load-step-duration" toml:"load-step-duration" yaml:"load-step-duration"`
 	LoadMaxDuration       Duration          `json:"load-max-duration" toml:"load-max-duration" yaml:"load-max-duration"`
+	BackoffErrorRate      float64           `json:"backoff-error-rate,omitempty" toml:"backoff-error-rate,omitempty" yaml:"backoff-error-rate,omitempty"`
+	BackoffInterval       Duration          `json:"backoff-interval,omitempty" toml:"backoff-interval,omitempty" yaml:"backoff-interval,omitempty"`
+	LatencyTarget         Duration          `json:"latency-target,omitempty" toml:"latency-target,omitempty" yaml:"latency-target,omitempty"`
+	LatencyInterval       Duration          `json:"latency-interval,omitempty" toml:"latency-interval,omitempty" yaml:"latency-interval,omitempty"`
 	LBStrategy            string            `json:"lb-strategy" toml:"lb-strategy" yaml:"lb-strategy"`
+	StatusThresholds      []string          `json:"status-thresholds,omitempty" toml:"status-thresholds,omitempty" yaml:"status-thresholds,omitempty"`
+	Thresholds            []string          `json:"thresholds,omitempty" toml:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+	ErrorTop              uint              `json:"error-top,omitempty" toml:"error-top,omitempty" yaml:"error-top,omitempty"`
+	ErrorSamples          uint              `json:"error-samples,omitempty" toml:"error-samples,omitempty" yaml:"error-samples,omitempty"`
+	RPSTolerance          float64           `json:"rps-tolerance,omitempty" toml:"rps-tolerance,omitempty" yaml:"rps-tolerance,omitempty"`
+	RPSInterval           Duration          `json:"rps-interval,omitempty" toml:"rps-interval,omitempty" yaml:"rps-interval,omitempty"`
+	Compare               string            `json:"compare,omitempty" toml:"compare,omitempty" yaml:"compare,omitempty"`
+	CompareTolerances     []string          `json:"compare-tolerances,omitempty" toml:"compare-tolerances,omitempty" yaml:"compare-tolerances,omitempty"`
+	Metrics               []string          `json:"metrics,omitempty" toml:"metrics,omitempty" yaml:"metrics,omitempty"`
+	Alerts                []string          `json:"alert,omitempty" toml:"alert,omitempty" yaml:"alert,omitempty"`
+	AlertWindow           Duration          `json:"alert-window,omitempty" toml:"alert-window,omitempty" yaml:"alert-window,omitempty"`
+	AlertCooldown         Duration          `json:"alert-cooldown,omitempty" toml:"alert-cooldown,omitempty" yaml:"alert-cooldown,omitempty"`
+	AlertWebhook          string            `json:"alert-webhook,omitempty" toml:"alert-webhook,omitempty" yaml:"alert-webhook,omitempty"`
+
+	// MetadataLists are the metadata values rotated per request
+	MetadataLists map[string][]string `json:"metadata-lists,omitempty" toml:"metadata-lists,omitempty" yaml:"metadata-lists,omitempty"`
+
+	// MethodMetadata is the metadata of the calls of the scenario by their method
+	MethodMetadata map[string]map[string]string `json:"method-metadata,omitempty" toml:"method-metadata,omitempty" yaml:"method-metadata,omitempty"`
+
+	// TargetOverrides are the authority, the TLS server name and the metadata of the host or the targets
+	TargetOverrides []TargetOverride `json:"target-overrides,omitempty" toml:"target-overrides,omitempty" yaml:"target-overrides,omitempty"`
+
+	// DataRewriteKeys are the field paths of the call data suffixed for each call, such as idempotency keys
+	DataRewriteKeys []string `json:"data-rewrite-key,omitempty" toml:"data-rewrite-key,omitempty" yaml:"data-rewrite-key,omitempty"`
+
+	// DeadlineBudget is whether the fraction of the timeout consumed by the calls is reported
+	DeadlineBudget bool `json:"deadline-budget,omitempty" toml:"deadline-budget,omitempty" yaml:"deadline-budget,omitempty"`
+
+	// HeaderLatency is whether the time to the response headers of unary calls is recorded
+	HeaderLatency bool `json:"header-latency,omitempty" toml:"header-latency,omitempty" yaml:"header-latency,omitempty"`
+
+	// ThinkTime is the random think time of the workers between their calls, the same as the worker pacing
+	ThinkTime string `json:"think-time,omitempty" toml:"think-time,omitempty" yaml:"think-time,omitempty"`
 }
 
 func checkData(data interface{}) error {
@@ -136,43 +321,136 @@ func checkData(data interface{}) error {
 	return nil
 }
 
-// LoadConfig loads the config from a file
+// LoadConfig loads the config from a file, or the JSON config from stdin if the path is "-"
 func LoadConfig(p string, c *Config) error {
+	if p == stdinPath {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		return LoadConfigJSON(data, c)
+	}
+
 	err := configor.Load(c, p)
 	if err != nil {
 		return err
 	}
 
-	if c.Data != nil {
-		ext := path.Ext(p)
-		if strings.EqualFold(ext, ".yaml") || strings.EqualFold(ext, ".yml") {
-			objData, isObjData2 := c.Data.(map[interface{}]interface{})
-			if isObjData2 {
-				nd := make(map[string]interface{})
-				for k, v := range objData {
-					sk, isString := k.(string)
-					if !isString {
-						return errors.New("Data key must string")
-					}
-					if len(sk) > 0 {
-						nd[sk] = v
-					}
-				}
-
-				c.Data = nd
+	ext := filepath.Ext(p)
+	if strings.EqualFold(ext, ".yaml") || strings.EqualFold(ext, ".yml") {
+		data := []*interface{}{&c.Data, &c.SessionData, &c.SessionCloseData, &c.CanaryData, &c.CanaryExpect}
+		for i := range c.Parallel {
+			data = append(data, &c.Parallel[i].Data)
+		}
+
+		for i := range c.Scenario {
+			data = append(data, &c.Scenario[i].Data)
+		}
+
+		for _, d := range data {
+			if *d == nil {
+				continue
 			}
+
+			if *d, err = fromYAMLObject(*d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return checkConfig(c)
+}
+
+// fromYAMLObject converts the YAML object data to a map with string keys
+func fromYAMLObject(data interface{}) (interface{}, error) {
+	objData, isObjData := data.(map[interface{}]interface{})
+	if !isObjData {
+		return data, nil
+	}
+
+	nd := make(map[string]interface{})
+	for k, v := range objData {
+		sk, isString := k.(string)
+		if !isString {
+			return nil, errors.New("Data key must string")
+		}
+		if len(sk) > 0 {
+			nd[sk] = v
 		}
+	}
+
+	return nd, nil
+}
+
+// LoadConfigJSON loads the config from JSON data.
+// Defaults are applied the same way as when using LoadConfig.
+func LoadConfigJSON(data []byte, c *Config) error {
+	if err := json.Unmarshal(data, c); err != nil {
+		return err
+	}
 
+	if err := configor.Load(c); err != nil {
+		return err
+	}
+
+	return checkConfig(c)
+}
+
+func checkConfig(c *Config) error {
+	if c.Data != nil {
 		err := checkData(c.Data)
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, pc := range c.Parallel {
+		if pc.Data != nil {
+			if err := checkData(pc.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, sc := range c.Scenario {
+		if sc.Data != nil {
+			if err := checkData(sc.Data); err != nil {
+				return err
+			}
+		}
+	}
+
 	c.ZStop = strings.ToLower(c.ZStop)
-	if c.ZStop != "close" && c.ZStop != "ignore" && c.ZStop != "wait" {
+	if c.ZStop != "close" && c.ZStop != "ignore" && c.ZStop != "wait" && c.ZStop != "drain" {
 		c.ZStop = "close"
 	}
 
 	return nil
 }
+
+// SaveConfig writes the config to a file so that it can later be loaded using LoadConfig.
+// The format is determined by the file extension: TOML, YAML and JSON are supported.
+// If the extension is not recognized JSON is used.
+func SaveConfig(p string, c *Config) error {
+	var b []byte
+	var err error
+
+	ext := strings.ToLower(filepath.Ext(p))
+	switch ext {
+	case ".toml":
+		buf := bytes.Buffer{}
+		err = toml.NewEncoder(&buf).Encode(c)
+		b = buf.Bytes()
+	case ".yaml", ".yml":
+		b, err = yaml.Marshal(c)
+	default:
+		b, err = json.MarshalIndent(c, "", "  ")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p, b, 0644)
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ConfigChange is a field of the config which differs between two configs
+type ConfigChange struct {
+	// Field is the name of the JSON field, dot separated for nested fields
+	Field string `json:"field"`
+
+	// Old and New are the values of the field, nil if the field is not set
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+func (c ConfigChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, configValue(c.Old), configValue(c.New))
+}
+
+func configValue(v interface{}) string {
+	if v == nil {
+		return "(unset)"
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+// DiffConfig returns the fields which differ between the previous config and the config,
+// sorted by their names. The configs are compared by their JSON fields.
+func DiffConfig(prev, c *Config) ([]ConfigChange, error) {
+	old, err := configFields(prev)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := configFields(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ConfigChange
+	diffFields("", old, cur, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+// configFields returns the JSON fields of the config
+func configFields(c *Config) (map[string]interface{}, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	err = json.Unmarshal(b, &fields)
+
+	return fields, err
+}
+
+// diffFields adds the changes of the fields, comparing nested objects field by field
+func diffFields(prefix string, old, cur map[string]interface{}, changes *[]ConfigChange) {
+	names := make(map[string]bool, len(old)+len(cur))
+	for name := range old {
+		names[name] = true
+	}
+
+	for name := range cur {
+		names[name] = true
+	}
+
+	for name := range names {
+		o, n := old[name], cur[name]
+
+		om, oIsObj := o.(map[string]interface{})
+		nm, nIsObj := n.(map[string]interface{})
+		if oIsObj && nIsObj {
+			diffFields(prefix+name+".", om, nm, changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(o, n) {
+			*changes = append(*changes, ConfigChange{Field: prefix + name, Old: o, New: n})
+		}
+	}
+}
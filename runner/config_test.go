@@ -2,6 +2,9 @@ package runner
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -121,3 +124,147 @@ func TestConfig_MarshalJSON(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestConfig_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghz_config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expected := &Config{
+		Proto:        "../testdata/greeter.proto",
+		Call:         "helloworld.Greeter.SayHello",
+		Insecure:     true,
+		N:            200,
+		C:            50,
+		CSchedule:    "const",
+		CStart:       1,
+		Connections:  1,
+		Z:            Duration(7 * time.Second),
+		ZStop:        "wait",
+		Timeout:      Duration(20 * time.Second),
+		Data:         map[string]interface{}{"name": "Bob"},
+		Metadata:     map[string]string{"token": "secret"},
+		Format:       "summary",
+		DialTimeout:  Duration(10 * time.Second),
+		ImportPaths:  []string{"/path/to/protos"},
+		Tags:         map[string]string{"env": "staging"},
+		Host:         "localhost:50051",
+		LoadSchedule: "const",
+	}
+
+	for _, ext := range []string{"json", "toml", "yaml"} {
+		t.Run(ext, func(t *testing.T) {
+			cfgPath := filepath.Join(dir, "saved."+ext)
+
+			err := SaveConfig(cfgPath, expected)
+			assert.NoError(t, err)
+
+			var actual Config
+			err = LoadConfig(cfgPath, &actual)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, &actual)
+		})
+	}
+}
+
+func TestConfig_LoadConfigJSON(t *testing.T) {
+	t.Run("applies defaults", func(t *testing.T) {
+		var c Config
+		err := LoadConfigJSON([]byte(`{"call":"helloworld.Greeter.SayHello","host":"localhost:50051","duration-stop":"WAIT","data":{"name":"Bob"}}`), &c)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "helloworld.Greeter.SayHello", c.Call)
+		assert.Equal(t, "localhost:50051", c.Host)
+		assert.Equal(t, uint(200), c.N)
+		assert.Equal(t, uint(50), c.C)
+		assert.Equal(t, Duration(20*time.Second), c.Timeout)
+		assert.Equal(t, "wait", c.ZStop)
+		assert.Equal(t, map[string]interface{}{"name": "Bob"}, c.Data)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		var c Config
+		err := LoadConfigJSON([]byte(`{"call":"helloworld.Greeter.SayHello","data":"foo"}`), &c)
+		assert.Error(t, err)
+	})
+
+	t.Run("parallel calls", func(t *testing.T) {
+		var c Config
+		err := LoadConfigJSON([]byte(`{"call":"helloworld.Greeter.SayHello","host":"localhost:50051","data":{"name":"Bob"},
+			"parallel":[{"name":"hello","rps":100},{"call":"helloworld.Greeter.SayHelloCS","concurrency":5,"duration":"30s"}],
+			"parallel-baseline":true}`), &c)
+		assert.NoError(t, err)
+
+		assert.True(t, c.ParallelBaseline)
+		assert.Equal(t, []ParallelCall{
+			{Name: "hello", RPS: 100},
+			{Call: "helloworld.Greeter.SayHelloCS", C: 5, Z: Duration(30 * time.Second)},
+		}, c.Parallel)
+
+		err = LoadConfigJSON([]byte(`{"call":"helloworld.Greeter.SayHello","parallel":[{"data":"foo"}]}`), &c)
+		assert.Error(t, err)
+	})
+
+	t.Run("scenario", func(t *testing.T) {
+		var c Config
+		err := LoadConfigJSON([]byte(`{"host":"localhost:50051","data":{"name":"Bob"},
+			"scenario":[{"call":"helloworld.Greeter.SayHello","weight":70},{"name":"joe","call":"helloworld.Greeter.SayHello","weight":30,"data":{"name":"Joe"}}]}`), &c)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []ScenarioCall{
+			{Call: "helloworld.Greeter.SayHello", Weight: 70},
+			{Name: "joe", Call: "helloworld.Greeter.SayHello", Weight: 30, Data: map[string]interface{}{"name": "Joe"}},
+		}, c.Scenario)
+
+		err = LoadConfigJSON([]byte(`{"scenario":[{"call":"helloworld.Greeter.SayHello","data":"foo"}]}`), &c)
+		assert.Error(t, err)
+
+		c = Config{}
+		err = LoadConfigJSON([]byte(`{"host":"localhost:50051","scenario-mode":"sequential",
+			"scenario":[{"name":"create","call":"chat.Chat.CreateSession","extract":{"session":"session_id"}}]}`), &c)
+		assert.NoError(t, err)
+		assert.Equal(t, ScenarioSequential, c.ScenarioMode)
+		assert.Equal(t, map[string]string{"session": "session_id"}, c.Scenario[0].Extract)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		var c Config
+		err := LoadConfigJSON([]byte(`{"call":`), &c)
+		assert.Error(t, err)
+	})
+}
+
+func TestDiffConfig(t *testing.T) {
+	prev := &Config{
+		Call:     "helloworld.Greeter.SayHello",
+		RPS:      100,
+		Data:     map[string]interface{}{"name": "bob", "size": "small"},
+		Metadata: map[string]string{"request-id": "1"},
+	}
+
+	c := &Config{
+		Call: "helloworld.Greeter.SayHello",
+		RPS:  200,
+		Data: map[string]interface{}{"name": "bob", "size": "large"},
+		Name: "run",
+	}
+
+	changes, err := DiffConfig(prev, c)
+	assert.NoError(t, err)
+
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+
+	assert.Equal(t, []string{
+		`data.size: "small" -> "large"`,
+		`metadata: {"request-id":"1"} -> (unset)`,
+		`name: (unset) -> "run"`,
+		`rps: 100 -> 200`,
+	}, lines)
+
+	changes, err = DiffConfig(c, c)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
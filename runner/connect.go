@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// The connect policies of the connections which cannot be established before the run
+const (
+	// ConnectPolicyFail fails the run if any of the connections cannot be established
+	ConnectPolicyFail = "fail"
+
+	// ConnectPolicyProceed runs with the connections which could be established, if any
+	ConnectPolicyProceed = "proceed"
+)
+
+// ConnectStats holds the statistics of establishing the connections before the run
+type ConnectStats struct {
+	// Policy is the connect policy of the run
+	Policy string `json:"policy"`
+
+	// Requested is the number of connections of the options, and Connected
+	// the number of connections established and used for the run
+	Requested int `json:"requested"`
+	Connected int `json:"connected"`
+
+	// Retries is the number of times the connections were dialed again
+	Retries int `json:"retries"`
+
+	// Failed is the number of connections which could not be established after the retries
+	Failed int `json:"failed"`
+}
+
+// awaitReady waits for the connection to be ready, returning an error if the attempt
+// to connect fails or the timeout elapses first. The timeout is not limited if it is 0.
+func awaitReady(cc *grpc.ClientConn, target string, timeout time.Duration) error {
+	ctx, cancel := requestContext(timeout)
+	defer cancel()
+
+	for {
+		state := cc.GetState()
+
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("connection to %s failed: %s", target, state)
+		}
+
+		if !cc.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("connection to %s timed out after %s", target, timeout)
+		}
+	}
+}
+
+// establishConns waits for the connections to be ready according to the connect policy, dialing
+// the failed connections again up to the number of retries. The connections which are not ready
+// are closed, and the ready connections are returned along with the indexes of their slots.
+func (b *Requester) establishConns(conns []*grpc.ClientConn, targets []string) ([]*grpc.ClientConn, []int, *ConnectStats, error) {
+	stats := &ConnectStats{Policy: b.config.connectPolicy, Requested: len(conns)}
+
+	ready := make([]*grpc.ClientConn, 0, len(conns))
+	slots := make([]int, 0, len(conns))
+
+	var lastErr error
+
+	for n, cc := range conns {
+		target := targets[n%len(targets)]
+
+		err := awaitReady(cc, target, b.config.dialTimeout)
+		for r := uint(0); err != nil && r < b.config.connectRetries; r++ {
+			if b.config.hasLog {
+				b.config.log.Debugw("Retrying client connection", "connection", n, "error", err.Error())
+			}
+
+			stats.Retries++
+
+			_ = cc.Close()
+
+			// the connection keeps the stats handler of its slot
+			redialed, dialErr := b.newClientConn(target, b.handlers[n])
+			if dialErr != nil {
+				err = dialErr
+
+				continue
+			}
+
+			cc = redialed
+			conns[n] = cc
+			err = awaitReady(cc, target, b.config.dialTimeout)
+		}
+
+		if err != nil {
+			if b.config.hasLog {
+				b.config.log.Errorw("Error establishing client connection", "connection", n, "error", err.Error())
+			}
+
+			stats.Failed++
+			lastErr = err
+
+			if b.config.connectPolicy == ConnectPolicyFail {
+				closeConns(conns)
+
+				return nil, nil, stats, err
+			}
+
+			_ = cc.Close()
+
+			continue
+		}
+
+		ready = append(ready, cc)
+		slots = append(slots, n)
+	}
+
+	if len(ready) == 0 {
+		return nil, nil, stats, fmt.Errorf("none of the %d connections could be established: %v", len(conns), lastErr)
+	}
+
+	stats.Connected = len(ready)
+
+	return ready, slots, stats, nil
+}
+
+// closeConns closes the connections
+func closeConns(conns []*grpc.ClientConn) {
+	for _, cc := range conns {
+		_ = cc.Close()
+	}
+}
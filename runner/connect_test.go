@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+// closedAddr returns the address of a port nothing is listening on
+func closedAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	return addr
+}
+
+func TestRunConnectPolicy(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	dead := closedAddr(t)
+
+	t.Run("proceed", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithConnections(2),
+			WithTargets(dead),
+			WithDialTimeout(time.Second),
+			WithConnectPolicy("proceed", 2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"OK": 10}, report.StatusCodeDist)
+		assert.Equal(t, 10, gs.GetCount(helloworld.Unary))
+		assert.Equal(t, &ConnectStats{Policy: "proceed", Requested: 2, Connected: 1, Retries: 2, Failed: 1}, report.Connect)
+		assert.Equal(t, "proceed", report.Options.ConnectPolicy)
+		assert.Equal(t, uint(2), report.Options.ConnectRetries)
+
+		// the connection which could not be established is left out
+		if assert.Len(t, report.Connections, 1) {
+			assert.Equal(t, uint64(10), report.Connections[0].Calls)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithConnections(2),
+			WithTargets(dead),
+			WithDialTimeout(time.Second),
+			WithConnectPolicy("", 1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "connection to "+dead+" failed: TRANSIENT_FAILURE")
+	})
+
+	t.Run("none established", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			dead,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithDialTimeout(time.Second),
+			WithConnectPolicy("proceed", 0),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "none of the 1 connections could be established: connection to "+dead+" failed: TRANSIENT_FAILURE")
+	})
+
+	t.Run("established", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(4),
+			WithConcurrency(2),
+			WithConnections(2),
+			WithConnectPolicy("fail", 0),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &ConnectStats{Policy: "fail", Requested: 2, Connected: 2}, report.Connect)
+		assert.Len(t, report.Connections, 2)
+	})
+}
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"errors"
+)
+
+// The modes of assigning the workers to the connections
+const (
+	// ConnRoundRobin assigns the workers to the connections in turn, the default
+	ConnRoundRobin = "round-robin"
+
+	// ConnPerWorker gives each worker a connection of its own
+	ConnPerWorker = "per-worker"
+
+	// ConnPacked assigns a fixed number of consecutive workers to each connection
+	ConnPacked = "packed"
+
+	// ConnMapped assigns the workers to the connections of an explicit mapping
+	ConnMapped = "mapped"
+)
+
+// maxWorkers returns the maximum number of concurrent workers of the concurrency schedule,
+// or 0 if the schedule has no end
+func (c *RunConfig) maxWorkers() int {
+	if c.cSchedule != ScheduleStep && c.cSchedule != ScheduleLine {
+		return c.c
+	}
+
+	if c.cEnd == 0 {
+		return 0
+	}
+
+	if c.cStart > c.cEnd {
+		return int(c.cStart)
+	}
+
+	return int(c.cEnd)
+}
+
+// resolveConnections sets the number of connections of the modes deriving it from the workers
+func resolveConnections(c *RunConfig) error {
+	switch c.connMode {
+	case ConnPerWorker, ConnPacked:
+		workers := c.maxWorkers()
+		if workers == 0 {
+			return errors.New("the connections of the workers require a concurrency schedule with an end")
+		}
+
+		perConn := 1
+		if c.connMode == ConnPacked {
+			perConn = c.workersPerConn
+		}
+
+		c.nConns = (workers + perConn - 1) / perConn
+	case ConnMapped:
+		max := 0
+		for _, n := range c.connMap {
+			if n > max {
+				max = n
+			}
+		}
+
+		c.nConns = max + 1
+	}
+
+	return nil
+}
+
+// workerConn returns the index of the connection of the worker with the index, out of the
+// connections established. The workers beyond those of the mode, such as those added by the
+// control file, wrap around the connections.
+func (c *RunConfig) workerConn(worker, conns int) int {
+	switch c.connMode {
+	case ConnPacked:
+		return (worker / c.workersPerConn) % conns
+	case ConnMapped:
+		return c.connMap[worker%len(c.connMap)] % conns
+	default:
+		return worker % conns
+	}
+}
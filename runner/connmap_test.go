@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveConnections(t *testing.T) {
+	var tests = []struct {
+		name     string
+		config   RunConfig
+		expected int
+		err      string
+	}{
+		{"round robin", RunConfig{c: 10, nConns: 3}, 3, ""},
+		{"per worker", RunConfig{c: 10, nConns: 3, connMode: ConnPerWorker}, 10, ""},
+		{"packed", RunConfig{c: 10, connMode: ConnPacked, workersPerConn: 4}, 3, ""},
+		{"packed schedule", RunConfig{c: 10, cSchedule: ScheduleStep, cStart: 5, cEnd: 40, connMode: ConnPacked, workersPerConn: 8}, 5, ""},
+		{"per worker schedule without end", RunConfig{c: 10, cSchedule: ScheduleLine, cStart: 5, connMode: ConnPerWorker}, 0, "the connections of the workers require a concurrency schedule with an end"},
+		{"mapped", RunConfig{c: 10, connMode: ConnMapped, connMap: []int{0, 0, 0, 2, 1}}, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolveConnections(&tt.config)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tt.config.nConns)
+		})
+	}
+}
+
+func TestRunConfig_workerConn(t *testing.T) {
+	conns := func(c *RunConfig, workers, n int) []int {
+		res := make([]int, workers)
+		for i := range res {
+			res[i] = c.workerConn(i, n)
+		}
+
+		return res
+	}
+
+	assert.Equal(t, []int{0, 1, 2, 0, 1, 2, 0}, conns(&RunConfig{}, 7, 3))
+	assert.Equal(t, []int{0, 1, 2, 3, 0}, conns(&RunConfig{connMode: ConnPerWorker}, 5, 4))
+	assert.Equal(t, []int{0, 0, 0, 1, 1, 1, 2, 2, 2, 0}, conns(&RunConfig{connMode: ConnPacked, workersPerConn: 3}, 10, 3))
+	assert.Equal(t, []int{0, 0, 1, 2, 0, 0}, conns(&RunConfig{connMode: ConnMapped, connMap: []int{0, 0, 1, 2}}, 6, 3))
+
+	// the connections which could not be established are wrapped around
+	assert.Equal(t, []int{0, 0, 1, 0}, conns(&RunConfig{connMode: ConnMapped, connMap: []int{0, 0, 1, 2}}, 4, 2))
+}
+
+func TestRunConnectionMapping(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	workers := func(report *Report) []uint64 {
+		var res []uint64
+		for _, c := range report.Connections {
+			res = append(res, c.Workers)
+		}
+
+		return res
+	}
+
+	t.Run("per worker", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(4),
+			WithConnectionMode(ConnPerWorker),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), report.Options.Connections)
+		assert.Equal(t, ConnPerWorker, report.Options.ConnectionMode)
+		assert.Equal(t, []uint64{1, 1, 1, 1}, workers(report))
+	})
+
+	t.Run("workers per connection", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(5),
+			WithWorkersPerConnection(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), report.Options.Connections)
+		assert.Equal(t, uint(2), report.Options.WorkersPerConnection)
+		assert.Equal(t, []uint64{2, 2, 1}, workers(report))
+	})
+
+	t.Run("connection map", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(5),
+			WithConnectionMap(0, 0, 0, 1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), report.Options.Connections)
+		assert.Equal(t, []int{0, 0, 0, 1}, report.Options.ConnectionMap)
+		assert.Equal(t, []uint64{4, 1}, workers(report))
+	})
+}
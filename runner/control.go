@@ -0,0 +1,271 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Adjustment is a change of the load made while the test was running
+type Adjustment struct {
+	// Elapsed is the elapsed duration of the test when the change was made
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Setting is the changed setting, "rps", "concurrency" or "paused"
+	Setting string `json:"setting"`
+
+	// Value is the new value of the setting, which is 1 when the test was paused and 0 when resumed
+	Value uint `json:"value"`
+}
+
+// errNotRunning is returned when the load of a test that is not running is changed
+var errNotRunning = errors.New("the test is not running")
+
+// SetRate changes the number of requests per second of the running test, 0 meaning no
+// rate limit. The new rate replaces the rate schedule and any adaptive rate control.
+func (b *Requester) SetRate(rps uint) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.control == nil || b.stopped {
+		return errNotRunning
+	}
+
+	b.control.SetRate(float64(rps))
+	b.adjustments = append(b.adjustments, Adjustment{
+		Elapsed: time.Since(b.start),
+		Setting: "rps",
+		Value:   rps,
+	})
+
+	return nil
+}
+
+// SetConcurrency changes the number of workers of the running test.
+// The concurrency schedule, if any, carries on from the new number of workers.
+func (b *Requester) SetConcurrency(c uint) error {
+	if c == 0 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	b.lock.Lock()
+	running := b.control != nil && !b.stopped
+	b.lock.Unlock()
+
+	if !running {
+		return errNotRunning
+	}
+
+	select {
+	case b.concurrency <- c:
+	case <-b.workersDone:
+		return errNotRunning
+	}
+
+	b.lock.Lock()
+	b.adjustments = append(b.adjustments, Adjustment{
+		Elapsed: time.Since(b.start),
+		Setting: "concurrency",
+		Value:   c,
+	})
+	b.lock.Unlock()
+
+	return nil
+}
+
+// Pause pauses the running test. No more requests are sent until the test is resumed, and
+// the calls in flight complete. The rate schedule carries on from where it was paused, while
+// the paused time still counts toward the duration of the test. Pausing a paused test is a no-op.
+func (b *Requester) Pause() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.control == nil || b.stopped {
+		return errNotRunning
+	}
+
+	if b.resume != nil {
+		return nil
+	}
+
+	b.resume = make(chan struct{})
+	b.adjustments = append(b.adjustments, Adjustment{
+		Elapsed: time.Since(b.start),
+		Setting: "paused",
+		Value:   1,
+	})
+
+	return nil
+}
+
+// Resume resumes the paused test. Resuming a test that is not paused is a no-op.
+func (b *Requester) Resume() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.control == nil || b.stopped {
+		return errNotRunning
+	}
+
+	if b.resume == nil {
+		return nil
+	}
+
+	close(b.resume)
+	b.resume = nil
+	b.adjustments = append(b.adjustments, Adjustment{
+		Elapsed: time.Since(b.start),
+		Setting: "paused",
+		Value:   0,
+	})
+
+	return nil
+}
+
+// Paused returns whether the test is paused
+func (b *Requester) Paused() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.resume != nil
+}
+
+// resumed returns the channel closed once the paused test is resumed, or nil if it is not paused
+func (b *Requester) resumed() <-chan struct{} {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.resume == nil {
+		return nil
+	}
+
+	return b.resume
+}
+
+// controlSettings are the settings of the control file, the ones left out are not changed
+type controlSettings struct {
+	Rps         *uint `json:"rps"`
+	Concurrency *uint `json:"concurrency"`
+	Paused      *bool `json:"paused"`
+}
+
+// controlFile applies the settings of the control file to the running test whenever
+// the file changes. The file is checked every interval, and right away when one of
+// the control signals is received.
+type controlFile struct {
+	path     string
+	interval time.Duration
+	reqr     *Requester
+	out      io.Writer
+
+	content []byte
+	applied controlSettings
+}
+
+func newControlFile(path string, reqr *Requester, out io.Writer) *controlFile {
+	if path == "" {
+		return nil
+	}
+
+	return &controlFile{path: path, interval: time.Second, reqr: reqr, out: out}
+}
+
+// watch watches the control file until done is closed
+func (f *controlFile) watch(done <-chan struct{}) {
+	sig := make(chan os.Signal, 1)
+	if len(controlSignals) > 0 {
+		signal.Notify(sig, controlSignals...)
+		defer signal.Stop(sig)
+	}
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-sig:
+		}
+
+		if err := f.check(); err != nil {
+			fmt.Fprintf(f.out, "Control file %s: %v\n", f.path, err)
+		}
+	}
+}
+
+// check reads the control file and applies the settings that changed since the last check.
+// A missing file is not an error, so the file can be created once the test is running.
+func (f *controlFile) check() error {
+	content, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if bytes.Equal(content, f.content) {
+		return nil
+	}
+
+	f.content = content
+
+	var s controlSettings
+	if len(bytes.TrimSpace(content)) > 0 {
+		if err := json.Unmarshal(content, &s); err != nil {
+			return err
+		}
+	}
+
+	if s.Rps != nil && (f.applied.Rps == nil || *s.Rps != *f.applied.Rps) {
+		if err := f.reqr.SetRate(*s.Rps); err != nil {
+			return err
+		}
+
+		f.applied.Rps = s.Rps
+		if *s.Rps == 0 {
+			fmt.Fprintf(f.out, "Control file %s: removed the rate limit\n", f.path)
+		} else {
+			fmt.Fprintf(f.out, "Control file %s: changed the rate to %d requests per second\n", f.path, *s.Rps)
+		}
+	}
+
+	if s.Concurrency != nil && (f.applied.Concurrency == nil || *s.Concurrency != *f.applied.Concurrency) {
+		if err := f.reqr.SetConcurrency(*s.Concurrency); err != nil {
+			return err
+		}
+
+		f.applied.Concurrency = s.Concurrency
+		fmt.Fprintf(f.out, "Control file %s: changed the concurrency to %d workers\n", f.path, *s.Concurrency)
+	}
+
+	if s.Paused != nil && (f.applied.Paused == nil || *s.Paused != *f.applied.Paused) {
+		if *s.Paused {
+			err = f.reqr.Pause()
+		} else {
+			err = f.reqr.Resume()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		f.applied.Paused = s.Paused
+		if *s.Paused {
+			fmt.Fprintf(f.out, "Control file %s: paused the test\n", f.path)
+		} else {
+			fmt.Fprintf(f.out, "Control file %s: resumed the test\n", f.path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequester_SetLoad(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	c, err := NewConfig(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(30),
+		WithConcurrency(1),
+		WithRPS(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+	assert.NoError(t, err)
+
+	reqr, err := NewRequester(c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, errNotRunning, reqr.SetRate(10))
+	assert.Equal(t, errNotRunning, reqr.SetConcurrency(10))
+	assert.Error(t, reqr.SetConcurrency(0))
+
+	type result struct {
+		report *Report
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		report, err := reqr.Run()
+		done <- result{report, err}
+	}()
+
+	for reqr.SetConcurrency(3) == errNotRunning {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// without the change the test would take 30 seconds
+	assert.NoError(t, reqr.SetRate(0))
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(10 * time.Second):
+		assert.FailNow(t, "the rate was not changed")
+	}
+
+	assert.NoError(t, res.err)
+	assert.Equal(t, uint64(30), res.report.Count)
+	assert.Len(t, reqr.workers, 3)
+
+	if assert.Len(t, res.report.Adjustments, 2) {
+		assert.Equal(t, "concurrency", res.report.Adjustments[0].Setting)
+		assert.Equal(t, uint(3), res.report.Adjustments[0].Value)
+		assert.Equal(t, "rps", res.report.Adjustments[1].Setting)
+		assert.Equal(t, uint(0), res.report.Adjustments[1].Value)
+	}
+
+	assert.Equal(t, errNotRunning, reqr.SetRate(10))
+	assert.Equal(t, errNotRunning, reqr.SetConcurrency(10))
+}
+
+func TestRequester_Pause(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	c, err := NewConfig(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(1),
+		WithRPS(50),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+	assert.NoError(t, err)
+
+	reqr, err := NewRequester(c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, errNotRunning, reqr.Pause())
+	assert.Equal(t, errNotRunning, reqr.Resume())
+	assert.False(t, reqr.Paused())
+
+	type result struct {
+		report *Report
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		report, err := reqr.Run()
+		done <- result{report, err}
+	}()
+
+	for reqr.Pause() == errNotRunning {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.True(t, reqr.Paused())
+
+	// pausing again is a no-op
+	assert.NoError(t, reqr.Pause())
+
+	// at most the request already paced is sent once paused
+	time.Sleep(100 * time.Millisecond)
+	count := reqr.Snapshot().Count
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, count, reqr.Snapshot().Count)
+	assert.True(t, count < 20)
+
+	select {
+	case <-done:
+		assert.FailNow(t, "the test was not paused")
+	default:
+	}
+
+	assert.NoError(t, reqr.Resume())
+	assert.False(t, reqr.Paused())
+	assert.NoError(t, reqr.Resume())
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(10 * time.Second):
+		assert.FailNow(t, "the test was not resumed")
+	}
+
+	assert.NoError(t, res.err)
+	assert.Equal(t, uint64(20), res.report.Count)
+
+	if assert.Len(t, res.report.Adjustments, 2) {
+		assert.Equal(t, Adjustment{Elapsed: res.report.Adjustments[0].Elapsed, Setting: "paused", Value: 1}, res.report.Adjustments[0])
+		assert.Equal(t, Adjustment{Elapsed: res.report.Adjustments[1].Elapsed, Setting: "paused", Value: 0}, res.report.Adjustments[1])
+	}
+
+	assert.Equal(t, errNotRunning, reqr.Pause())
+}
+
+func TestControlFile_check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghz-control")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "control.json")
+
+	assert.Nil(t, newControlFile("", nil, nil))
+
+	f := newControlFile(path, &Requester{}, &bytes.Buffer{})
+
+	t.Run("missing", func(t *testing.T) {
+		assert.NoError(t, f.check())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"rps":`), 0644))
+		assert.Error(t, f.check())
+
+		// the same content is not checked again
+		assert.NoError(t, f.check())
+	})
+
+	t.Run("not running", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"rps": 20}`), 0644))
+		assert.Equal(t, errNotRunning, f.check())
+		assert.Nil(t, f.applied.Rps)
+
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`{"paused": true}`), 0644))
+		assert.Equal(t, errNotRunning, f.check())
+		assert.Nil(t, f.applied.Paused)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert.NoError(t, ioutil.WriteFile(path, []byte("\n"), 0644))
+		assert.NoError(t, f.check())
+	})
+}
+
+func TestRunControlFile(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	dir, err := ioutil.TempDir("", "ghz-control")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "control.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"rps": 0, "concurrency": 2}`), 0644))
+
+	out := &bytes.Buffer{}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(1),
+		WithRPS(2),
+		WithControlFile(path),
+		WithDebugOutput(out),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+	assert.Equal(t, uint64(20), report.Count)
+	assert.Equal(t, path, report.Options.ControlFile)
+	assert.Len(t, report.Adjustments, 2)
+	assert.Contains(t, out.String(), "removed the rate limit")
+	assert.Contains(t, out.String(), "changed the concurrency to 2 workers")
+}
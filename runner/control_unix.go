@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// controlSignals are the signals to check the control file right away
+var controlSignals = []os.Signal{syscall.SIGUSR1}
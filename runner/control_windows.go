@@ -0,0 +1,7 @@
+package runner
+
+import "os"
+
+// controlSignals are the signals to check the control file right away,
+// there are none on Windows so the file is only checked periodically
+var controlSignals []os.Signal
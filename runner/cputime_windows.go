@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns the user and system CPU time used by the process so far,
+// or 0 if it could not be read
+func processCPUTime() time.Duration {
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0
+	}
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0
+	}
+
+	// the kernel and user times are durations in 100 nanosecond units
+	ticks := func(ft syscall.Filetime) int64 {
+		return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime)
+	}
+
+	return time.Duration((ticks(kernel) + ticks(user)) * 100)
+}
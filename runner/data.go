@@ -1,15 +1,16 @@
 package runner
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
 
-	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
@@ -53,6 +54,12 @@ type dataProvider struct {
 	arrayJSONData []string
 	hasActions    bool
 
+	// labels of the array data records, used to break down the latency
+	labels []string
+
+	// whether the unset fields with presence are set to their default values
+	emitDefaults bool
+
 	// cached messages only for binary
 	mutex          sync.RWMutex
 	cachedMessages []*dynamic.Message
@@ -61,17 +68,25 @@ type dataProvider struct {
 type mdProvider struct {
 	metadata []byte
 	preseed  metadata.MD
+
+	// metadata for each request when an array is used
+	arrayMetadata []string
+	arrayPreseed  []metadata.MD
+
+	// the metadata keys whose values are rotated per request
+	lists []metadataList
 }
 
 func newDataProvider(mtd *desc.MethodDescriptor,
 	binary bool, dataFunc BinaryDataFunc, data []byte,
-	funcs template.FuncMap) (*dataProvider, error) {
+	funcs template.FuncMap, labelKey string, emitDefaults bool) (*dataProvider, error) {
 
 	dp := dataProvider{
 		binary:         binary,
 		dataFunc:       dataFunc,
 		mtd:            mtd,
 		data:           data,
+		emitDefaults:   emitDefaults,
 		cachedMessages: nil,
 	}
 
@@ -80,11 +95,22 @@ func newDataProvider(mtd *desc.MethodDescriptor,
 	dp.arrayJSONData = nil
 	if !dp.binary {
 		if strings.IndexRune(string(data), '[') == 0 { // it's an array
-			var dat []map[string]interface{}
+			var dat []interface{}
 			if err := json.Unmarshal(data, &dat); err != nil {
 				return nil, err
 			}
 
+			if labelKey != "" {
+				if dp.labels, err = extractLabels(mtd, dat, labelKey); err != nil {
+					return nil, err
+				}
+
+				// the label key may have been removed from the records
+				if dp.data, err = json.Marshal(dat); err != nil {
+					return nil, err
+				}
+			}
+
 			dp.arrayJSONData = make([]string, len(dat))
 			for i, d := range dat {
 				var strd []byte
@@ -106,6 +132,16 @@ func newDataProvider(mtd *desc.MethodDescriptor,
 
 	dp.hasActions = ha
 
+	// the values without template actions are checked before the calls are made
+	if ha && !dp.binary {
+		var v interface{}
+		if json.Unmarshal(dp.data, &v) == nil {
+			if err := validateEnums(mtd.GetInputType(), v, "", true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if !ha {
 		if len(dp.arrayJSONData) > 0 {
 			dp.mutex.Lock()
@@ -131,9 +167,24 @@ func (dp *dataProvider) getDataForCall(ctd *CallData) ([]*dynamic.Message, error
 	if !dp.binary && !dp.mtd.IsClientStreaming() && len(dp.arrayJSONData) > 0 {
 		indx := int(ctd.RequestNumber % int64(len(dp.arrayJSONData))) // we want to start from inputs[0] so dec reqNum
 
+		// the workers use the records of their partitions in turn
+		if ctd.partition != nil {
+			lo, hi := ctd.partition.bounds(len(dp.arrayJSONData))
+			indx = lo + int(ctd.partitionCall%int64(hi-lo))
+		}
+
+		if indx < len(dp.labels) {
+			ctd.label = dp.labels[indx]
+		}
+
 		if inputs, err = dp.getMessages(ctd, indx, []byte(dp.arrayJSONData[indx])); err != nil {
 			return nil, err
 		}
+
+		// the cached messages of all the records may be returned
+		if len(inputs) > 1 {
+			inputs = inputs[indx : indx+1]
+		}
 	} else if inputs, err = dp.getMessages(ctd, -1, dp.data); err != nil {
 		return nil, err
 	}
@@ -148,6 +199,42 @@ func (dp *dataProvider) getDataForCall(ctd *CallData) ([]*dynamic.Message, error
 	return inputs, nil
 }
 
+// extractLabels returns the value of the label key of each record.
+// The key is removed from the records unless it is a field of the input message.
+// The binary records have no label.
+func extractLabels(mtd *desc.MethodDescriptor, records []interface{}, key string) ([]string, error) {
+	input := mtd.GetInputType()
+	isField := input.FindFieldByName(key) != nil || input.FindFieldByJSONName(key) != nil
+
+	labels := make([]string, len(records))
+	for i, rec := range records {
+		r, ok := rec.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		v, ok := r[key]
+		if !ok {
+			continue
+		}
+
+		switch v := v.(type) {
+		case string:
+			labels[i] = v
+		case float64, bool:
+			labels[i] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("data label %q of record %d must be a string, number or boolean", key, i)
+		}
+
+		if !isField {
+			delete(r, key)
+		}
+	}
+
+	return labels, nil
+}
+
 func (dp *dataProvider) getMessages(ctd *CallData, i int, inputData []byte) ([]*dynamic.Message, error) {
 	var inputs []*dynamic.Message
 	var err error
@@ -178,6 +265,10 @@ func (dp *dataProvider) getMessages(ctd *CallData, i int, inputData []byte) ([]*
 			return nil, err
 		}
 
+		if dp.emitDefaults {
+			emitDefaults(inputs)
+		}
+
 		// only cache JSON data if there are no template actions
 		if !dp.hasActions {
 			dp.mutex.Lock()
@@ -207,6 +298,10 @@ func (dp *dataProvider) getMessages(ctd *CallData, i int, inputData []byte) ([]*
 		if err != nil {
 			return nil, err
 		}
+
+		if dp.emitDefaults {
+			emitDefaults(inputs)
+		}
 		// We only cache in case we don't dynamically change the binary message
 		if dp.dataFunc == nil {
 			dp.mutex.Lock()
@@ -221,6 +316,16 @@ func (dp *dataProvider) getMessages(ctd *CallData, i int, inputData []byte) ([]*
 func newMetadataProvider(mtd *desc.MethodDescriptor, mdData []byte, funcs template.FuncMap) (*mdProvider, error) {
 	// Test if we can preseed data
 	ctd := newCallData(mtd, funcs, "", 0)
+
+	if strings.IndexRune(string(mdData), '[') == 0 { // it's an array
+		return newArrayMetadataProvider(ctd, mdData)
+	}
+
+	mdData, lists, err := extractMetadataLists(mdData)
+	if err != nil {
+		return nil, err
+	}
+
 	ha, err := ctd.hasAction(string(mdData))
 	if err != nil {
 		return nil, err
@@ -238,15 +343,112 @@ func newMetadataProvider(mtd *desc.MethodDescriptor, mdData []byte, funcs templa
 		}
 	}
 
-	return &mdProvider{metadata: mdData, preseed: preseed}, nil
+	dp := &mdProvider{metadata: mdData, preseed: preseed}
+	if err := dp.addLists(lists); err != nil {
+		return nil, err
+	}
+
+	return dp, nil
+}
+
+// addLists adds the metadata lists rotated per request, replacing the lists of the same keys
+func (dp *mdProvider) addLists(lists map[string][]string) error {
+	if len(lists) == 0 {
+		return nil
+	}
+
+	added, err := newMetadataLists(lists)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range dp.lists {
+		if _, ok := lists[l.key]; !ok {
+			added = append(added, l)
+		}
+	}
+
+	sort.Slice(added, func(i, j int) bool { return added[i].key < added[j].key })
+
+	dp.lists = added
+
+	return nil
+}
+
+// newArrayMetadataProvider creates a provider where each element of the array
+// is the metadata for the corresponding request, wrapping around
+func newArrayMetadataProvider(ctd *CallData, mdData []byte) (*mdProvider, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(mdData, &items); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("Metadata array must not be empty")
+	}
+
+	dp := mdProvider{
+		metadata:      mdData,
+		arrayMetadata: make([]string, len(items)),
+		arrayPreseed:  make([]metadata.MD, len(items)),
+	}
+
+	for i, item := range items {
+		dp.arrayMetadata[i] = string(item)
+
+		ha, err := ctd.hasAction(dp.arrayMetadata[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if ha {
+			continue
+		}
+
+		mdMap, err := ctd.executeMetadata(dp.arrayMetadata[i])
+		if err != nil {
+			return nil, err
+		}
+
+		dp.arrayPreseed[i] = metadata.New(mdMap)
+	}
+
+	return &dp, nil
 }
 
 func (dp *mdProvider) getMetadataForCall(ctd *CallData) (*metadata.MD, error) {
+	if len(dp.lists) > 0 {
+		md, err := dp.metadataForCall(ctd)
+		if err != nil {
+			return nil, err
+		}
+
+		return rotateMetadata(md, dp.lists, ctd.RequestNumber), nil
+	}
+
+	return dp.metadataForCall(ctd)
+}
+
+func (dp *mdProvider) metadataForCall(ctd *CallData) (*metadata.MD, error) {
+	if len(dp.arrayMetadata) > 0 {
+		indx := int(ctd.RequestNumber % int64(len(dp.arrayMetadata)))
+		if dp.arrayPreseed[indx] != nil {
+			return &dp.arrayPreseed[indx], nil
+		}
+
+		return dp.metadataFrom(ctd, dp.arrayMetadata[indx])
+	}
+
 	if dp.preseed != nil {
 		return &dp.preseed, nil
 	}
 
-	mdMap, err := ctd.executeMetadata(string(dp.metadata))
+	return dp.metadataFrom(ctd, string(dp.metadata))
+}
+
+func (dp *mdProvider) metadataFrom(ctd *CallData, mdData string) (*metadata.MD, error) {
+
+	mdMap, err := ctd.executeMetadata(mdData)
 	if err != nil {
 		return nil, err
 	}
@@ -268,7 +470,7 @@ func messageFromMap(input *dynamic.Message, data *map[string]interface{}) error
 		return err
 	}
 
-	err = jsonpb.UnmarshalString(string(strData), input)
+	err = unmarshalMessage(string(strData), input)
 	if err != nil {
 		return err
 	}
@@ -282,7 +484,7 @@ func createPayloadsFromJSON(data string, mtd *desc.MethodDescriptor) ([]*dynamic
 
 	if len(data) > 0 {
 		if strings.IndexRune(data, '[') == 0 {
-			dataArray := make([]map[string]interface{}, 5)
+			dataArray := make([]interface{}, 5)
 			err := json.Unmarshal([]byte(data), &dataArray)
 			if err != nil {
 				return nil, fmt.Errorf("Error unmarshalling payload. Data: '%v' Error: %v", data, err.Error())
@@ -295,7 +497,16 @@ func createPayloadsFromJSON(data string, mtd *desc.MethodDescriptor) ([]*dynamic
 
 			for i, elem := range dataArray {
 				elemMsg := dynamic.NewMessage(md)
-				err := messageFromMap(elemMsg, &elem)
+
+				if m, ok := elem.(map[string]interface{}); ok {
+					err = messageFromMap(elemMsg, &m)
+				} else {
+					var strElem []byte
+					if strElem, err = json.Marshal(elem); err == nil {
+						err = messageFromData(string(strElem), elemMsg)
+					}
+				}
+
 				if err != nil {
 					return nil, fmt.Errorf("Error creating message: %v", err.Error())
 				}
@@ -305,7 +516,7 @@ func createPayloadsFromJSON(data string, mtd *desc.MethodDescriptor) ([]*dynamic
 		} else {
 			inputs = make([]*dynamic.Message, 1)
 			inputs[0] = dynamic.NewMessage(md)
-			err := jsonpb.UnmarshalString(data, inputs[0])
+			err := messageFromData(data, inputs[0])
 			if err != nil {
 				return nil, fmt.Errorf("Error creating message from data. Data: '%v' Error: %v", data, err.Error())
 			}
@@ -315,6 +526,41 @@ func createPayloadsFromJSON(data string, mtd *desc.MethodDescriptor) ([]*dynamic
 	return inputs, nil
 }
 
+// messageFromData creates a message from the JSON data, or from the binary message
+// if the data is a JSON string of a base64 encoded binary message. The well-known
+// types with a JSON string format, such as wrappers and timestamps, are always JSON.
+func messageFromData(data string, input *dynamic.Message) error {
+	isWellKnown := strings.HasPrefix(input.GetMessageDescriptor().GetFullyQualifiedName(), "google.protobuf.")
+	if isWellKnown || strings.IndexRune(data, '"') != 0 {
+		return unmarshalMessage(data, input)
+	}
+
+	var s string
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return err
+	}
+
+	return messageFromBase64(input, s)
+}
+
+// messageFromBase64 creates a message from the base64 encoded binary message,
+// with either the standard or the URL encoding, padded or not
+func messageFromBase64(input *dynamic.Message, s string) error {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+
+	binData, err := enc.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid base64 encoded binary message: %v", err)
+	}
+
+	return proto.Unmarshal(binData, input)
+}
+
 func createPayloadsFromBinSingleMessage(binData []byte, mtd *desc.MethodDescriptor) ([]*dynamic.Message, error) {
 	inputs := make([]*dynamic.Message, 0, 1)
 	md := mtd.GetInputType()
@@ -384,6 +630,8 @@ type dynamicMessageProvider struct {
 	streamCallCount uint
 	counter         uint
 	indexCounter    uint
+
+	emitDefaults bool
 }
 
 func newDynamicMessageProvider(mtd *desc.MethodDescriptor, data []byte, streamCallCount uint) (*dynamicMessageProvider, error) {
@@ -397,7 +645,7 @@ func newDynamicMessageProvider(mtd *desc.MethodDescriptor, data []byte, streamCa
 	var err error
 
 	if strings.IndexRune(string(data), '[') == 0 { // it's an array
-		var dat []map[string]interface{}
+		var dat []interface{}
 		if err := json.Unmarshal(data, &dat); err != nil {
 			return nil, err
 		}
@@ -464,11 +712,15 @@ func (m *dynamicMessageProvider) GetStreamMessage(parentCallData *CallData) (*dy
 
 	md := m.mtd.GetInputType()
 	msg := dynamic.NewMessage(md)
-	err = jsonpb.UnmarshalString(string(buf), msg)
+	err = messageFromData(string(buf), msg)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating message from data. Data: '%v' Error: %v", data, err.Error())
 	}
 
+	if m.emitDefaults {
+		emitMessageDefaults(msg)
+	}
+
 	m.counter++
 	m.indexCounter++
 
@@ -1,12 +1,14 @@
 package runner
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"testing"
 	"text/template"
 
 	"github.com/golang/protobuf/proto"
 
+	"github.com/bojand/ghz/internal"
 	"github.com/bojand/ghz/internal/helloworld"
 	"github.com/bojand/ghz/protodesc"
 
@@ -223,6 +225,36 @@ func TestData_createPayloads(t *testing.T) {
 		assert.EqualValues(t, "foo", inputs[0].GetFieldByName("value"))
 	})
 
+	t.Run("create slice of messages from mixed JSON and base64 binary data", func(t *testing.T) {
+		binData, err := proto.Marshal(&helloworld.HelloRequest{Name: "bob"})
+		assert.NoError(t, err)
+
+		std := base64.StdEncoding.EncodeToString(binData)
+		raw := base64.RawURLEncoding.EncodeToString(binData)
+
+		jsonData := `[{"name":"kate"},"` + std + `","` + raw + `"]`
+
+		inputs, err := createPayloadsFromJSON(jsonData, mtdClientStreaming)
+		assert.NoError(t, err)
+		if assert.Len(t, inputs, 3) {
+			assert.Equal(t, "kate", inputs[0].GetFieldByName("name"))
+			assert.Equal(t, "bob", inputs[1].GetFieldByName("name"))
+			assert.Equal(t, "bob", inputs[2].GetFieldByName("name"))
+		}
+
+		inputs, err = createPayloadsFromJSON(`"`+std+`"`, mtdUnary)
+		assert.NoError(t, err)
+		if assert.Len(t, inputs, 1) {
+			assert.Equal(t, "bob", inputs[0].GetFieldByName("name"))
+		}
+
+		_, err = createPayloadsFromJSON(`[{"name":"kate"},"not base64!"]`, mtdUnary)
+		assert.Error(t, err)
+
+		_, err = createPayloadsFromJSON(`[{"name":"kate"},5]`, mtdUnary)
+		assert.Error(t, err)
+	})
+
 	t.Run("create slice from single message binary data", func(t *testing.T) {
 		msg1 := &helloworld.HelloRequest{}
 		msg1.Name = "bob"
@@ -371,4 +403,223 @@ func TestMetadata_getMetadataForCall(t *testing.T) {
 		assert.Equal(t, []string{"custom-value"}, md1.Get("token"))
 		assert.NotSame(t, mdp.preseed, md1)
 	})
+
+	t.Run("with array", func(t *testing.T) {
+		mtdUnary, err := protodesc.GetMethodDescFromProto(
+			"helloworld.Greeter.SayHello",
+			"../testdata/greeter.proto",
+			nil)
+		assert.NoError(t, err)
+
+		mdp, err := newMetadataProvider(mtdUnary, []byte(`[{"tenant":"a"},{"tenant":"b","request-id":"{{ .RequestNumber }}"}]`), nil)
+		assert.NoError(t, err)
+		assert.Nil(t, mdp.preseed)
+		assert.Len(t, mdp.arrayMetadata, 2)
+		assert.NotNil(t, mdp.arrayPreseed[0])
+		assert.Nil(t, mdp.arrayPreseed[1])
+
+		expected := []struct {
+			tenant    string
+			requestID []string
+		}{
+			{"a", nil},
+			{"b", []string{"1"}},
+			{"a", nil},
+			{"b", []string{"3"}},
+		}
+
+		for i, e := range expected {
+			md, err := mdp.getMetadataForCall(newCallData(mtdUnary, nil, "123", int64(i)))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{e.tenant}, md.Get("tenant"))
+			assert.Equal(t, e.requestID, md.Get("request-id"))
+		}
+	})
+
+	t.Run("with empty array", func(t *testing.T) {
+		mtdUnary, err := protodesc.GetMethodDescFromProto(
+			"helloworld.Greeter.SayHello",
+			"../testdata/greeter.proto",
+			nil)
+		assert.NoError(t, err)
+
+		mdp, err := newMetadataProvider(mtdUnary, []byte(`[]`), nil)
+		assert.Error(t, err)
+		assert.Nil(t, mdp)
+	})
+
+	t.Run("with lists", func(t *testing.T) {
+		mtdUnary, err := protodesc.GetMethodDescFromProto(
+			"helloworld.Greeter.SayHello",
+			"../testdata/greeter.proto",
+			nil)
+		assert.NoError(t, err)
+
+		mdp, err := newMetadataProvider(mtdUnary, []byte(`{"token":"asdf","x-tenant":["a","b","c"],"x-route":["r1","r2"]}`), nil)
+		assert.NoError(t, err)
+		assert.Len(t, mdp.lists, 2)
+
+		err = mdp.addLists(map[string][]string{"x-route": {"r3"}, "x-region": {"us", "eu"}})
+		assert.NoError(t, err)
+
+		expected := []struct {
+			tenant, route, region string
+		}{
+			{"a", "r3", "us"},
+			{"b", "r3", "eu"},
+			{"c", "r3", "us"},
+			{"a", "r3", "eu"},
+		}
+
+		for i, e := range expected {
+			md, err := mdp.getMetadataForCall(newCallData(mtdUnary, nil, "123", int64(i)))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"asdf"}, md.Get("token"))
+			assert.Equal(t, []string{e.tenant}, md.Get("x-tenant"))
+			assert.Equal(t, []string{e.route}, md.Get("x-route"))
+			assert.Equal(t, []string{e.region}, md.Get("x-region"))
+		}
+
+		// the preseeded metadata is not changed by the rotation
+		assert.Empty(t, mdp.preseed.Get("x-tenant"))
+	})
+
+	t.Run("with invalid lists", func(t *testing.T) {
+		mtdUnary, err := protodesc.GetMethodDescFromProto(
+			"helloworld.Greeter.SayHello",
+			"../testdata/greeter.proto",
+			nil)
+		assert.NoError(t, err)
+
+		_, err = newMetadataProvider(mtdUnary, []byte(`{"x-tenant":[]}`), nil)
+		assert.EqualError(t, err, `metadata list of "x-tenant" must not be empty`)
+
+		_, err = newMetadataProvider(mtdUnary, []byte(`{"x-tenant":[1,2]}`), nil)
+		assert.EqualError(t, err, `metadata list of "x-tenant" must be a list of strings`)
+	})
+}
+
+func TestData_newDataProvider(t *testing.T) {
+	mtdUnary, err := protodesc.GetMethodDescFromProto(
+		"helloworld.Greeter.SayHello",
+		"../testdata/greeter.proto",
+		nil)
+	assert.NoError(t, err)
+
+	t.Run("labels removed from records", func(t *testing.T) {
+		dp, err := newDataProvider(mtdUnary, false, nil,
+			[]byte(`[{"name":"bob","size":"small"},{"name":"kate","size":"large"},{"name":"jim"}]`), nil, "size", false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"small", "large", ""}, dp.labels)
+		assert.Equal(t, []string{`{"name":"bob"}`, `{"name":"kate"}`, `{"name":"jim"}`}, dp.arrayJSONData)
+
+		for i, expected := range []string{"small", "large", "", "small"} {
+			ctd := newCallData(mtdUnary, nil, "", int64(i))
+			inputs, err := dp.getDataForCall(ctd)
+			assert.NoError(t, err)
+			assert.Len(t, inputs, 1)
+			assert.Equal(t, expected, ctd.label)
+		}
+	})
+
+	t.Run("label from message field", func(t *testing.T) {
+		dp, err := newDataProvider(mtdUnary, false, nil,
+			[]byte(`[{"name":"bob"},{"name":"kate"}]`), nil, "name", false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bob", "kate"}, dp.labels)
+		assert.Equal(t, []string{`{"name":"bob"}`, `{"name":"kate"}`}, dp.arrayJSONData)
+	})
+
+	t.Run("mixed JSON and binary records", func(t *testing.T) {
+		binData, err := proto.Marshal(&helloworld.HelloRequest{Name: "bob"})
+		assert.NoError(t, err)
+
+		data := `[{"name":"kate","size":"small"},"` + base64.StdEncoding.EncodeToString(binData) + `"]`
+
+		dp, err := newDataProvider(mtdUnary, false, nil, []byte(data), nil, "size", false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"small", ""}, dp.labels)
+
+		for i, expected := range []string{"kate", "bob", "kate"} {
+			inputs, err := dp.getDataForCall(newCallData(mtdUnary, nil, "", int64(i)))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, inputs[0].GetFieldByName("name"))
+		}
+	})
+
+	t.Run("invalid label", func(t *testing.T) {
+		_, err := newDataProvider(mtdUnary, false, nil,
+			[]byte(`[{"name":"bob","size":{"bytes":1}}]`), nil, "size", false)
+		assert.Error(t, err)
+	})
+	t.Run("partitioned", func(t *testing.T) {
+		dp, err := newDataProvider(mtdUnary, false, nil,
+			[]byte(`[{"name":"a"},{"name":"b"},{"name":"c"},{"name":"d"},{"name":"e"}]`), nil, "", false)
+		assert.NoError(t, err)
+		assert.NoError(t, checkDataPartition(dp, 2))
+
+		expected := [][]string{{"a", "b", "a", "b"}, {"c", "d", "e", "c"}}
+		for p, names := range expected {
+			partition := &dataPartition{index: p, count: 2}
+
+			for i, name := range names {
+				// the request numbers of the workers are interleaved
+				ctd := newCallData(mtdUnary, nil, "", int64(2*i+p))
+				ctd.partition = partition
+				ctd.partitionCall = int64(i)
+
+				inputs, err := dp.getDataForCall(ctd)
+				assert.NoError(t, err)
+				assert.Equal(t, name, inputs[0].GetFieldByName("name"))
+			}
+		}
+
+		assert.EqualError(t, checkDataPartition(dp, 6),
+			"data partitioning requires at least as many data records as workers: 5 records for 6 workers")
+	})
+
+	t.Run("partitioned without array", func(t *testing.T) {
+		dp, err := newDataProvider(mtdUnary, false, nil, []byte(`{"name":"a"}`), nil, "", false)
+		assert.NoError(t, err)
+		assert.EqualError(t, checkDataPartition(dp, 2), "data partitioning requires an array of data records")
+	})
+}
+
+func TestRunDataPartitionAsync(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(40),
+		WithConcurrency(2),
+		WithAsync(true),
+		WithDataPartition(true),
+		WithData([]interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b"},
+			map[string]interface{}{"name": "c"},
+			map[string]interface{}{"name": "d"},
+		}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 40, int(report.Count))
+
+	// the concurrent calls of each worker use the records of its partition in turn
+	names := map[string]int{}
+	for _, msgs := range gs.GetCalls(helloworld.Unary) {
+		for _, msg := range msgs {
+			names[msg.GetName()]++
+		}
+	}
+
+	assert.Len(t, names, 4)
 }
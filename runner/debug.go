@@ -0,0 +1,216 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CallDebugRecord is the fully rendered detail of a single call
+// written out when call debugging is enabled
+type CallDebugRecord struct {
+	RunID         string          `json:"runId,omitempty"`
+	WorkerID      string          `json:"workerId"`
+	RequestNumber int64           `json:"requestNumber"`
+	Call          string          `json:"call"`
+	Metadata      metadata.MD     `json:"metadata,omitempty"`
+	Request       json.RawMessage `json:"request,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// callDebugger writes out the details of the first N calls
+// and of up to a maximum number of failed calls
+type callDebugger struct {
+	out       io.Writer
+	maxCalls  int
+	maxErrors int
+
+	lock       sync.Mutex
+	callCount  int
+	errorCount int
+}
+
+func newCallDebugger(out io.Writer, maxCalls, maxErrors int) *callDebugger {
+	if out == nil || (maxCalls <= 0 && maxErrors <= 0) {
+		return nil
+	}
+
+	return &callDebugger{
+		out:       out,
+		maxCalls:  maxCalls,
+		maxErrors: maxErrors,
+	}
+}
+
+// record writes the call details if we are still within the limits
+// a nil debugger is a no-op
+func (d *callDebugger) record(ctd *CallData, md *metadata.MD, req, res proto.Message, callErr error) error {
+	if d == nil {
+		return nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.callCount < d.maxCalls {
+		d.callCount++
+	} else if callErr != nil && d.errorCount < d.maxErrors {
+		d.errorCount++
+	} else {
+		return nil
+	}
+
+	rec := CallDebugRecord{
+		RunID:         ctd.RunID,
+		WorkerID:      ctd.WorkerID,
+		RequestNumber: ctd.RequestNumber,
+		Call:          ctd.FullyQualifiedName,
+		Status:        status.Code(callErr).String(),
+		Request:       messageToJSON(req),
+		Response:      messageToJSON(res),
+	}
+
+	if md != nil {
+		rec.Metadata = *md
+	}
+
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.out.Write(append(line, '\n'))
+	return err
+}
+
+// SlowCallRecord is the detail of a single call exceeding the slow call threshold
+// written out when slow call logging is enabled
+type SlowCallRecord struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	Latency       time.Duration   `json:"latency"`
+	RunID         string          `json:"runId,omitempty"`
+	WorkerID      string          `json:"workerId"`
+	RequestNumber int64           `json:"requestNumber"`
+	Call          string          `json:"call"`
+	Metadata      metadata.MD     `json:"metadata,omitempty"`
+	Request       json.RawMessage `json:"request,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// slowCallLogger writes out the details of up to a maximum number
+// of calls taking longer than the threshold
+type slowCallLogger struct {
+	out       io.Writer
+	threshold time.Duration
+	max       int
+	capture   bool
+
+	lock  sync.Mutex
+	count int
+}
+
+func newSlowCallLogger(out io.Writer, threshold time.Duration, max int, capture bool) *slowCallLogger {
+	if out == nil || threshold <= 0 || max <= 0 {
+		return nil
+	}
+
+	return &slowCallLogger{
+		out:       out,
+		threshold: threshold,
+		max:       max,
+		capture:   capture,
+	}
+}
+
+// record writes the call details if the call started at the time took longer
+// than the threshold and we are still within the limit.
+// The metadata, request and response are only included if capture is enabled.
+// a nil logger is a no-op
+func (l *slowCallLogger) record(ctd *CallData, start time.Time, latency time.Duration, md *metadata.MD,
+	req, res proto.Message, callErr error) error {
+	if l == nil {
+		return nil
+	}
+
+	if latency < l.threshold {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.count >= l.max {
+		return nil
+	}
+
+	l.count++
+
+	rec := SlowCallRecord{
+		Timestamp:     start,
+		Latency:       latency,
+		RunID:         ctd.RunID,
+		WorkerID:      ctd.WorkerID,
+		RequestNumber: ctd.RequestNumber,
+		Call:          ctd.FullyQualifiedName,
+		Status:        status.Code(callErr).String(),
+	}
+
+	if l.capture {
+		rec.Request = messageToJSON(req)
+		rec.Response = messageToJSON(res)
+
+		if md != nil {
+			rec.Metadata = *md
+		}
+	}
+
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.out.Write(append(line, '\n'))
+	return err
+}
+
+func messageToJSON(msg proto.Message) json.RawMessage {
+	if msg == nil {
+		return nil
+	}
+
+	if dm, ok := msg.(*dynamic.Message); ok {
+		if dm == nil {
+			return nil
+		}
+
+		if b, err := dm.MarshalJSON(); err == nil {
+			return b
+		}
+
+		return nil
+	}
+
+	if b, err := json.Marshal(msg); err == nil {
+		return b
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCallDebugger_record(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	req := dynamic.NewMessage(mtd.GetInputType())
+	req.SetFieldByName("name", "bob")
+
+	res := dynamic.NewMessage(mtd.GetOutputType())
+	res.SetFieldByName("message", "Hello bob")
+
+	md := metadata.New(map[string]string{"token": "secret"})
+
+	t.Run("nil when disabled", func(t *testing.T) {
+		d := newCallDebugger(&bytes.Buffer{}, 0, 0)
+		assert.Nil(t, d)
+		assert.NoError(t, d.record(newCallData(mtd, nil, "w1", 0), &md, req, res, nil))
+	})
+
+	t.Run("first n calls", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		d := newCallDebugger(buf, 2, 0)
+
+		for i := 0; i < 4; i++ {
+			err := d.record(newCallData(mtd, nil, "w1", int64(i)), &md, req, res, nil)
+			assert.NoError(t, err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+
+		var rec CallDebugRecord
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &rec))
+		assert.Equal(t, "w1", rec.WorkerID)
+		assert.Equal(t, int64(1), rec.RequestNumber)
+		assert.Equal(t, "helloworld.Greeter.SayHello", rec.Call)
+		assert.Equal(t, "OK", rec.Status)
+		assert.Empty(t, rec.Error)
+		assert.Equal(t, []string{"secret"}, rec.Metadata["token"])
+		assert.JSONEq(t, `{"name":"bob"}`, string(rec.Request))
+		assert.JSONEq(t, `{"message":"Hello bob"}`, string(rec.Response))
+	})
+
+	t.Run("errors up to cap", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		d := newCallDebugger(buf, 1, 2)
+
+		callErr := status.Error(codes.InvalidArgument, "bad name")
+		for i := 0; i < 5; i++ {
+			err := d.record(newCallData(mtd, nil, "w1", int64(i)), &md, req, nil, callErr)
+			assert.NoError(t, err)
+		}
+
+		// successful calls past the first n are not recorded
+		assert.NoError(t, d.record(newCallData(mtd, nil, "w1", 5), &md, req, res, nil))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 3)
+
+		var rec CallDebugRecord
+		assert.NoError(t, json.Unmarshal([]byte(lines[2]), &rec))
+		assert.Equal(t, "InvalidArgument", rec.Status)
+		assert.Equal(t, callErr.Error(), rec.Error)
+		assert.Empty(t, rec.Response)
+	})
+}
+
+func TestSlowCallLogger_record(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	req := dynamic.NewMessage(mtd.GetInputType())
+	req.SetFieldByName("name", "bob")
+
+	res := dynamic.NewMessage(mtd.GetOutputType())
+	res.SetFieldByName("message", "Hello bob")
+
+	md := metadata.New(map[string]string{"token": "secret"})
+
+	t.Run("nil when disabled", func(t *testing.T) {
+		l := newSlowCallLogger(&bytes.Buffer{}, 0, 100, false)
+		assert.Nil(t, l)
+		assert.NoError(t, l.record(newCallData(mtd, nil, "w1", 0), time.Now().Add(-time.Second), time.Second, &md, req, res, nil))
+	})
+
+	t.Run("slow calls up to max", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l := newSlowCallLogger(buf, 100*time.Millisecond, 2, false)
+
+		// fast call is not recorded
+		assert.NoError(t, l.record(newCallData(mtd, nil, "w1", 0), time.Now(), time.Millisecond, &md, req, res, nil))
+
+		start := time.Now().Add(-200 * time.Millisecond)
+		callErr := status.Error(codes.Unavailable, "slow")
+		for i := 1; i < 4; i++ {
+			err := l.record(newCallData(mtd, nil, "w2", int64(i)), start, time.Since(start), &md, req, nil, callErr)
+			assert.NoError(t, err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+
+		var rec SlowCallRecord
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+		assert.Equal(t, "w2", rec.WorkerID)
+		assert.Equal(t, int64(1), rec.RequestNumber)
+		assert.Equal(t, "helloworld.Greeter.SayHello", rec.Call)
+		assert.True(t, rec.Timestamp.Equal(start))
+		assert.True(t, rec.Latency >= 200*time.Millisecond)
+		assert.Equal(t, "Unavailable", rec.Status)
+		assert.Equal(t, callErr.Error(), rec.Error)
+		assert.Empty(t, rec.Metadata)
+		assert.Empty(t, rec.Request)
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		l := newSlowCallLogger(buf, time.Millisecond, 10, true)
+
+		err := l.record(newCallData(mtd, nil, "w1", 3), time.Now().Add(-time.Second), time.Second, &md, req, res, nil)
+		assert.NoError(t, err)
+
+		var rec SlowCallRecord
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+		assert.Equal(t, "OK", rec.Status)
+		assert.Empty(t, rec.Error)
+		assert.Equal(t, []string{"secret"}, rec.Metadata["token"])
+		assert.JSONEq(t, `{"name":"bob"}`, string(rec.Request))
+		assert.JSONEq(t, `{"message":"Hello bob"}`, string(rec.Response))
+	})
+}
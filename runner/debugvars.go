@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// debugVars tracks the state of the pacer published at the debug endpoint
+type debugVars struct {
+	lock  sync.Mutex
+	ticks uint64
+	lag   time.Duration
+}
+
+func newDebugVars(c *RunConfig) *debugVars {
+	if c.debugAddr == "" {
+		return nil
+	}
+
+	return &debugVars{}
+}
+
+// tick records a tick handed to a worker, with its lag behind the intended time of the rate, if any
+func (v *debugVars) tick(intended time.Time) {
+	if v == nil {
+		return
+	}
+
+	var lag time.Duration
+	if !intended.IsZero() {
+		lag = time.Since(intended)
+	}
+
+	v.lock.Lock()
+	v.ticks++
+	v.lag = lag
+	v.lock.Unlock()
+}
+
+// debugState is the internal state of the run published at the debug endpoint
+type debugState struct {
+	Elapsed time.Duration `json:"elapsed"`
+	Paused  bool          `json:"paused"`
+	Stopped bool          `json:"stopped"`
+
+	// the active workers and the calls in flight
+	Workers  int64 `json:"workers"`
+	InFlight int64 `json:"inFlight"`
+
+	// the results gathered by the reporter, and those waiting in the results channel
+	Completed       uint64 `json:"completed"`
+	Errors          uint64 `json:"errors"`
+	ResultsBacklog  int    `json:"resultsBacklog"`
+	ResultsCapacity int    `json:"resultsCapacity"`
+
+	// the ticks handed to the workers by the pacer, and how far the last one was behind its intended time
+	Ticks    uint64        `json:"ticks"`
+	PacerLag time.Duration `json:"pacerLag"`
+
+	// the async calls being sent and the responses queued for the handlers
+	AsyncInFlight uint64 `json:"asyncInFlight,omitempty"`
+	AsyncQueued   uint64 `json:"asyncQueued,omitempty"`
+
+	Goroutines int `json:"goroutines"`
+}
+
+// debugState returns the current internal state of the run
+func (b *Requester) debugState() debugState {
+	b.lock.Lock()
+	start, reporter, stopped := b.start, b.reporter, b.stopped
+	b.lock.Unlock()
+
+	s := debugState{
+		Paused:          b.Paused(),
+		Stopped:         stopped,
+		Workers:         b.metrics.activeWorkers(),
+		InFlight:        b.metrics.callsInFlight(),
+		ResultsBacklog:  len(b.results),
+		ResultsCapacity: cap(b.results),
+		Goroutines:      runtime.NumGoroutine(),
+	}
+
+	if !start.IsZero() {
+		s.Elapsed = time.Since(start)
+	}
+
+	if reporter != nil {
+		s.Completed, s.Errors = reporter.counts()
+	}
+
+	if b.vars != nil {
+		b.vars.lock.Lock()
+		s.Ticks, s.PacerLag = b.vars.ticks, b.vars.lag
+		b.vars.lock.Unlock()
+	}
+
+	if b.async != nil {
+		s.AsyncInFlight, s.AsyncQueued = b.async.depths()
+	}
+
+	return s
+}
+
+// serveDebugVars serves the state of the run along with the command line and the memory
+// statistics of the process, in the format of the expvar package
+func (b *Requester) serveDebugVars(w http.ResponseWriter, r *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	vars := []struct {
+		name  string
+		value interface{}
+	}{
+		{"cmdline", os.Args},
+		{"ghz", b.debugState()},
+		{"memstats", ms},
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	_, _ = fmt.Fprintf(w, "{\n")
+	for i, v := range vars {
+		data, err := json.Marshal(v.value)
+		if err != nil {
+			data = []byte("null")
+		}
+
+		sep := ",\n"
+		if i == len(vars)-1 {
+			sep = "\n"
+		}
+
+		_, _ = fmt.Fprintf(w, "%q: %s%s", v.name, data, sep)
+	}
+	_, _ = fmt.Fprintf(w, "}\n")
+}
+
+// serveDebug serves the state of the run at /debug/vars on the address until the returned server is closed
+func (b *Requester) serveDebug(addr string) (*http.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("debug server: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", b.serveDebugVars)
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	return srv, nil
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugVars_tick(t *testing.T) {
+	assert.Nil(t, newDebugVars(&RunConfig{}))
+
+	// nil vars are not tracked
+	var none *debugVars
+	none.tick(time.Now())
+
+	v := newDebugVars(&RunConfig{debugAddr: "localhost:6060"})
+	v.tick(time.Time{})
+	assert.Equal(t, uint64(1), v.ticks)
+	assert.Zero(t, v.lag)
+
+	v.tick(time.Now().Add(-50 * time.Millisecond))
+	assert.Equal(t, uint64(2), v.ticks)
+	assert.True(t, v.lag >= 50*time.Millisecond, "lag %v", v.lag)
+}
+
+func TestRequester_serveDebugVars(t *testing.T) {
+	b := &Requester{
+		config:  &RunConfig{debugAddr: "localhost:6060"},
+		results: make(chan *callResult, 10),
+		metrics: newLiveMetrics(),
+		vars:    &debugVars{ticks: 5, lag: time.Millisecond},
+		async:   newAsyncQueue(2, 1),
+		start:   time.Now().Add(-time.Second),
+	}
+
+	b.metrics.addWorkers(3)
+	b.metrics.begin()
+	b.async.send()
+	b.results <- &callResult{}
+	b.results <- &callResult{}
+
+	rec := httptest.NewRecorder()
+	b.serveDebugVars(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var vars struct {
+		Cmdline  []string               `json:"cmdline"`
+		Memstats map[string]interface{} `json:"memstats"`
+		Ghz      debugState             `json:"ghz"`
+	}
+
+	if !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars)) {
+		return
+	}
+
+	assert.NotEmpty(t, vars.Cmdline)
+	assert.Contains(t, vars.Memstats, "HeapAlloc")
+
+	s := vars.Ghz
+	assert.True(t, s.Elapsed >= time.Second, "elapsed %v", s.Elapsed)
+	assert.False(t, s.Paused)
+	assert.False(t, s.Stopped)
+	assert.Equal(t, int64(3), s.Workers)
+	assert.Equal(t, int64(1), s.InFlight)
+	assert.Equal(t, 2, s.ResultsBacklog)
+	assert.Equal(t, 10, s.ResultsCapacity)
+	assert.Equal(t, uint64(5), s.Ticks)
+	assert.Equal(t, time.Millisecond, s.PacerLag)
+	assert.Equal(t, uint64(1), s.AsyncInFlight)
+	assert.Zero(t, s.AsyncQueued)
+	assert.NotZero(t, s.Goroutines)
+}
+
+func TestRunDebugAddr(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	t.Run("same as metrics address", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+			WithMetricsAddr(addr),
+			WithDebugAddr(addr),
+		)
+
+		assert.EqualError(t, err, "the debug address cannot be the same as the metrics address")
+	})
+
+	t.Run("during the run", func(t *testing.T) {
+		done := make(chan *Report)
+		go func() {
+			report, err := Run(
+				"helloworld.Greeter.SayHello",
+				internal.TestLocalhost,
+				WithProtoFile("../testdata/greeter.proto", []string{}),
+				WithRunDuration(time.Second),
+				WithConcurrency(2),
+				WithRPS(50),
+				WithData(map[string]interface{}{"name": "bob"}),
+				WithInsecure(true),
+				WithDebugAddr(addr),
+			)
+
+			assert.NoError(t, err)
+			done <- report
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+
+		res, err := http.Get("http://" + addr + "/debug/vars")
+		if assert.NoError(t, err) {
+			var vars struct {
+				Ghz debugState `json:"ghz"`
+			}
+
+			err := json.NewDecoder(res.Body).Decode(&vars)
+			res.Body.Close()
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, int64(2), vars.Ghz.Workers)
+				assert.NotZero(t, vars.Ghz.Ticks)
+				assert.NotZero(t, vars.Ghz.Completed)
+				assert.True(t, vars.Ghz.Elapsed > 0)
+			}
+		}
+
+		report := <-done
+		assert.NotZero(t, report.Count)
+
+		// the server is stopped at the end of the run
+		_, err = http.Get("http://" + addr + "/debug/vars")
+		assert.Error(t, err)
+	})
+}
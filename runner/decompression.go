@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// DecompressionStats holds the sizes of the compressed responses received by the calls and the time
+// taken to decompress them on the client. The sizes exclude the 5 byte prefix of each message.
+type DecompressionStats struct {
+	Compressor string `json:"compressor"`
+	Messages   uint64 `json:"messages"`
+
+	CompressedBytes   uint64 `json:"compressedBytes"`
+	DecompressedBytes uint64 `json:"decompressedBytes"`
+
+	// SavedBytes are the bytes not transferred thanks to the compression
+	SavedBytes uint64 `json:"savedBytes"`
+
+	// Ratio is the compressed bytes as a fraction of the decompressed bytes
+	Ratio float64 `json:"ratio"`
+
+	// Total is the time taken to decompress all the messages, and Average, Fastest and Slowest
+	// the time taken to decompress a message
+	Total   time.Duration `json:"total"`
+	Average time.Duration `json:"average"`
+	Fastest time.Duration `json:"fastest"`
+	Slowest time.Duration `json:"slowest"`
+
+	// LatencyDistribution is the distribution of the time taken to decompress a message
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+
+	// Throughput is the decompressed bytes per second of decompression time
+	Throughput float64 `json:"throughput"`
+
+	// TimePerSavedMB is the decompression time spent for each megabyte saved
+	TimePerSavedMB time.Duration `json:"timePerSavedMB"`
+}
+
+// decompressionTracker decompresses the responses of the calls using the registered compressor,
+// recording the sizes of the messages and the time taken to decompress them. It is used as the
+// decompressor of the connections, which gRPC uses for the responses of the same encoding.
+type decompressionTracker struct {
+	name       string
+	compressor encoding.Compressor
+
+	lock         sync.Mutex
+	messages     uint64
+	compressed   uint64
+	decompressed uint64
+	total        time.Duration
+	fastest      time.Duration
+	slowest      time.Duration
+
+	// the times of the first messages for the distribution, in seconds
+	durations []float64
+}
+
+// newDecompressionTracker returns the tracker of the responses compressed with the compressor of the
+// calls, or with gzip if the calls are not compressed, or nil if the compressor is not registered
+func newDecompressionTracker(name string) *decompressionTracker {
+	if name == "" {
+		name = gzip.Name
+	}
+
+	compressor := encoding.GetCompressor(name)
+	if compressor == nil {
+		return nil
+	}
+
+	return &decompressionTracker{name: name, compressor: compressor}
+}
+
+// Type returns the name of the compressor of the responses
+func (d *decompressionTracker) Type() string {
+	return d.name
+}
+
+// Do decompresses the message read from the reader, which gRPC gives as a reader of the whole message
+func (d *decompressionTracker) Do(r io.Reader) ([]byte, error) {
+	var size int
+	if lr, ok := r.(interface{ Len() int }); ok {
+		size = lr.Len()
+	}
+
+	start := time.Now()
+
+	dr, err := d.compressor.Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	d.record(size, len(b), time.Since(start))
+
+	return b, nil
+}
+
+// record records the sizes of a message and the time taken to decompress it
+func (d *decompressionTracker) record(compressed, decompressed int, duration time.Duration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.messages++
+	d.compressed += uint64(compressed)
+	d.decompressed += uint64(decompressed)
+	d.total += duration
+
+	if d.fastest == 0 || duration < d.fastest {
+		d.fastest = duration
+	}
+
+	if duration > d.slowest {
+		d.slowest = duration
+	}
+
+	if len(d.durations) < maxResult {
+		d.durations = append(d.durations, duration.Seconds())
+	}
+}
+
+// stats returns the statistics of the decompressed responses, or nil if no compressed response
+// was received
+func (d *decompressionTracker) stats() *DecompressionStats {
+	if d == nil {
+		return nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.messages == 0 {
+		return nil
+	}
+
+	s := &DecompressionStats{
+		Compressor:        d.name,
+		Messages:          d.messages,
+		CompressedBytes:   d.compressed,
+		DecompressedBytes: d.decompressed,
+		Total:             d.total,
+		Average:           d.total / time.Duration(d.messages),
+		Fastest:           d.fastest,
+		Slowest:           d.slowest,
+	}
+
+	if d.decompressed > d.compressed {
+		s.SavedBytes = d.decompressed - d.compressed
+		s.TimePerSavedMB = time.Duration(float64(d.total) / float64(s.SavedBytes) * 1e6)
+	}
+
+	if d.decompressed > 0 {
+		s.Ratio = float64(d.compressed) / float64(d.decompressed)
+	}
+
+	if d.total > 0 {
+		s.Throughput = float64(d.decompressed) / d.total.Seconds()
+	}
+
+	durations := make([]float64, len(d.durations))
+	copy(durations, d.durations)
+	sort.Float64s(durations)
+	s.LatencyDistribution = latencies(durations)
+
+	return s
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecompressionTracker(t *testing.T) {
+	assert.Nil(t, newDecompressionTracker("missing"))
+
+	var tracker *decompressionTracker
+	assert.Nil(t, tracker.stats())
+
+	tracker = newDecompressionTracker("")
+	assert.Equal(t, "gzip", tracker.Type())
+	assert.Nil(t, tracker.stats())
+
+	message := strings.Repeat("hello", 200)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(message))
+	assert.NoError(t, zw.Close())
+
+	compressed := buf.Len()
+
+	b, err := tracker.Do(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, message, string(b))
+
+	_, err = tracker.Do(strings.NewReader("not compressed"))
+	assert.Error(t, err)
+
+	s := tracker.stats()
+	if assert.NotNil(t, s) {
+		assert.Equal(t, uint64(1), s.Messages)
+		assert.Equal(t, uint64(compressed), s.CompressedBytes)
+		assert.Equal(t, uint64(len(message)), s.DecompressedBytes)
+		assert.Equal(t, uint64(len(message)-compressed), s.SavedBytes)
+		assert.NotZero(t, s.Total)
+		assert.Equal(t, s.Total, s.Slowest)
+	}
+}
+
+func TestDecompressionTracker_stats(t *testing.T) {
+	tracker := newDecompressionTracker("gzip")
+	tracker.record(100, 1000, 2*time.Millisecond)
+	tracker.record(300, 1000, 4*time.Millisecond)
+
+	s := tracker.stats()
+
+	assert.Equal(t, uint64(2), s.Messages)
+	assert.Equal(t, uint64(400), s.CompressedBytes)
+	assert.Equal(t, uint64(2000), s.DecompressedBytes)
+	assert.Equal(t, uint64(1600), s.SavedBytes)
+	assert.Equal(t, 0.2, s.Ratio)
+	assert.Equal(t, 6*time.Millisecond, s.Total)
+	assert.Equal(t, 3*time.Millisecond, s.Average)
+	assert.Equal(t, 2*time.Millisecond, s.Fastest)
+	assert.Equal(t, 4*time.Millisecond, s.Slowest)
+	assert.Equal(t, float64(2000)/0.006, s.Throughput)
+	assert.Equal(t, 3750*time.Millisecond, s.TimePerSavedMB)
+	assert.Equal(t, LatencyDistribution{Percentage: 99, Latency: 4 * time.Millisecond}, s.LatencyDistribution[6])
+}
@@ -0,0 +1,250 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+)
+
+// the columns of the compact details, in the order they are decoded
+const (
+	colTimestamp = iota
+	colLatency
+	colLag
+	colHeaderLatency
+	colBytesSent
+	colBytesReceived
+	colMessagesSent
+	colMessagesReceived
+	colStatus
+	colError
+	colLabel
+	colTraceID
+	colWorker
+	colMethod
+
+	numDetailColumns
+)
+
+// DetailColumns holds the details of the calls in a compact columnar layout, taking several times
+// less memory than a slice of details. Each column is a stream of varints: the timestamps are
+// encoded as the delta from the previous detail, the status codes and the other strings as their
+// index in a dictionary of the distinct values. The details are read in order with a cursor.
+type DetailColumns struct {
+	count int
+	last  int64 // the timestamp of the last detail added, in unix nanoseconds
+
+	cols  [numDetailColumns][]byte
+	dict  []string
+	index map[string]uint64
+
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func newDetailColumns() *DetailColumns {
+	// the empty string is always the first entry of the dictionary
+	return &DetailColumns{dict: []string{""}, index: map[string]uint64{"": 0}}
+}
+
+// CompactDetails returns the details in the compact columnar layout
+func CompactDetails(details []ResultDetail) *DetailColumns {
+	c := newDetailColumns()
+	for i := range details {
+		c.add(&details[i])
+	}
+
+	return c
+}
+
+// Len returns the number of details
+func (c *DetailColumns) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	return c.count
+}
+
+func (c *DetailColumns) putInt(col int, v int64) {
+	c.cols[col] = append(c.cols[col], c.scratch[:binary.PutVarint(c.scratch[:], v)]...)
+}
+
+func (c *DetailColumns) putUint(col int, v uint64) {
+	c.cols[col] = append(c.cols[col], c.scratch[:binary.PutUvarint(c.scratch[:], v)]...)
+}
+
+func (c *DetailColumns) putString(col int, s string) {
+	i, ok := c.index[s]
+	if !ok {
+		i = uint64(len(c.dict))
+		c.dict = append(c.dict, s)
+		c.index[s] = i
+	}
+
+	c.putUint(col, i)
+}
+
+// add appends the detail to the columns
+func (c *DetailColumns) add(d *ResultDetail) {
+	ts := d.Timestamp.UnixNano()
+	c.putInt(colTimestamp, ts-c.last)
+	c.last = ts
+
+	c.putInt(colLatency, int64(d.Latency))
+	c.putInt(colLag, int64(d.Lag))
+	c.putInt(colHeaderLatency, int64(d.HeaderLatency))
+	c.putUint(colBytesSent, d.BytesSent)
+	c.putUint(colBytesReceived, d.BytesReceived)
+	c.putUint(colMessagesSent, d.MessagesSent)
+	c.putUint(colMessagesReceived, d.MessagesReceived)
+
+	c.putString(colStatus, d.Status)
+	c.putString(colError, d.Error)
+	c.putString(colLabel, d.Label)
+	c.putString(colTraceID, d.TraceID)
+	c.putString(colWorker, d.Worker)
+	c.putString(colMethod, d.Method)
+
+	c.count++
+}
+
+// Cursor returns a cursor reading the details from the first one
+func (c *DetailColumns) Cursor() *DetailCursor {
+	return &DetailCursor{c: c}
+}
+
+// Details returns the decoded details
+func (c *DetailColumns) Details() []ResultDetail {
+	if c == nil {
+		return nil
+	}
+
+	details := make([]ResultDetail, c.count)
+
+	cur := c.Cursor()
+	for i := range details {
+		cur.Next(&details[i])
+	}
+
+	return details
+}
+
+// detailIter iterates over the details of a run, kept either as a slice or as compact details
+type detailIter struct {
+	details []ResultDetail
+	columns *DetailColumns
+}
+
+// reportDetails returns an iterator over the details of the report
+func reportDetails(r *Report) detailIter {
+	return detailIter{details: r.Details, columns: r.DetailColumns}
+}
+
+// Len returns the number of details
+func (it detailIter) Len() int {
+	return len(it.details) + it.columns.Len()
+}
+
+// each calls f with each detail in order, decoding the compact details one at a time into the
+// same detail, so f must not keep it
+func (it detailIter) each(f func(d *ResultDetail)) {
+	for i := range it.details {
+		f(&it.details[i])
+	}
+
+	if it.columns == nil {
+		return
+	}
+
+	var d ResultDetail
+	cur := it.columns.Cursor()
+	for cur.Next(&d) {
+		f(&d)
+	}
+}
+
+// MarshalJSON encodes the details as an array of details, decoding one at a time
+func (c *DetailColumns) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+
+	var d ResultDetail
+	cur := c.Cursor()
+	for i := 0; cur.Next(&d); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(b)
+	}
+
+	buf.WriteByte(']')
+
+	return buf.Bytes(), nil
+}
+
+// DetailCursor reads the details of the columns in order
+type DetailCursor struct {
+	c    *DetailColumns
+	next int
+	ts   int64
+	offs [numDetailColumns]int
+}
+
+func (r *DetailCursor) int(col int) int64 {
+	v, n := binary.Varint(r.c.cols[col][r.offs[col]:])
+	r.offs[col] += n
+
+	return v
+}
+
+func (r *DetailCursor) uint(col int) uint64 {
+	v, n := binary.Uvarint(r.c.cols[col][r.offs[col]:])
+	r.offs[col] += n
+
+	return v
+}
+
+func (r *DetailCursor) string(col int) string {
+	return r.c.dict[r.uint(col)]
+}
+
+// Next decodes the next detail into d, returning false once all the details have been read
+func (r *DetailCursor) Next(d *ResultDetail) bool {
+	if r.next >= r.c.Len() {
+		return false
+	}
+
+	r.ts += r.int(colTimestamp)
+
+	*d = ResultDetail{
+		Timestamp:        time.Unix(0, r.ts),
+		Latency:          time.Duration(r.int(colLatency)),
+		Lag:              time.Duration(r.int(colLag)),
+		HeaderLatency:    time.Duration(r.int(colHeaderLatency)),
+		BytesSent:        r.uint(colBytesSent),
+		BytesReceived:    r.uint(colBytesReceived),
+		MessagesSent:     r.uint(colMessagesSent),
+		MessagesReceived: r.uint(colMessagesReceived),
+		Status:           r.string(colStatus),
+		Error:            r.string(colError),
+		Label:            r.string(colLabel),
+		TraceID:          r.string(colTraceID),
+		Worker:           r.string(colWorker),
+		Method:           r.string(colMethod),
+	}
+
+	r.next++
+
+	return true
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetailColumns(t *testing.T) {
+	start := time.Date(2021, 3, 7, 14, 43, 12, 0, time.UTC)
+
+	details := []ResultDetail{
+		{Timestamp: start, Latency: 5 * time.Millisecond, Status: "OK", Worker: "g0c0w0", BytesSent: 12, BytesReceived: 19},
+		{Timestamp: start.Add(time.Second), Latency: 7 * time.Millisecond, Status: "Unavailable", Error: "unavailable",
+			Worker: "g0c0w1", Lag: 2 * time.Millisecond, Label: "big", TraceID: "abc", Method: "SayHello"},
+		// out of order timestamps are delta encoded as well
+		{Timestamp: start.Add(500 * time.Millisecond), Latency: 3 * time.Millisecond, Status: "OK", Worker: "g0c0w0",
+			HeaderLatency: time.Millisecond, MessagesSent: 3, MessagesReceived: 1},
+	}
+
+	c := CompactDetails(details)
+	assert.Equal(t, 3, c.Len())
+	assert.Equal(t, []string{"", "OK", "g0c0w0", "Unavailable", "unavailable", "big", "abc", "g0c0w1", "SayHello"}, c.dict)
+
+	decoded := c.Details()
+	if assert.Len(t, decoded, 3) {
+		for i, d := range decoded {
+			assert.True(t, details[i].Timestamp.Equal(d.Timestamp), "timestamp %d", i)
+
+			d.Timestamp = details[i].Timestamp
+			assert.Equal(t, details[i], d)
+		}
+	}
+
+	var d ResultDetail
+	cur := c.Cursor()
+	assert.True(t, cur.Next(&d))
+	assert.Equal(t, 5*time.Millisecond, d.Latency)
+	assert.True(t, cur.Next(&d))
+	assert.True(t, cur.Next(&d))
+	assert.False(t, cur.Next(&d))
+
+	// the details are encoded in JSON the same way
+	expected, err := json.Marshal(decoded)
+	assert.NoError(t, err)
+
+	actual, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	var none *DetailColumns
+	assert.Zero(t, none.Len())
+	assert.Nil(t, none.Details())
+	assert.Empty(t, CompactDetails(nil).Details())
+}
+
+func TestDetailIter(t *testing.T) {
+	details := []ResultDetail{
+		{Latency: 5 * time.Millisecond, Status: "OK"},
+		{Latency: 7 * time.Millisecond, Status: "Unavailable", Error: "unavailable"},
+	}
+
+	latencies := func(it detailIter) []time.Duration {
+		var res []time.Duration
+		it.each(func(d *ResultDetail) { res = append(res, d.Latency) })
+		return res
+	}
+
+	expected := []time.Duration{5 * time.Millisecond, 7 * time.Millisecond}
+
+	it := detailIter{details: details}
+	assert.Equal(t, 2, it.Len())
+	assert.Equal(t, expected, latencies(it))
+
+	it = detailIter{columns: CompactDetails(details)}
+	assert.Equal(t, 2, it.Len())
+	assert.Equal(t, expected, latencies(it))
+
+	// the statistics are the same over the compact details
+	assert.Equal(t, apdex(detailIter{details: details}, 6*time.Millisecond), apdex(it, 6*time.Millisecond))
+
+	assert.Zero(t, detailIter{}.Len())
+	assert.Nil(t, latencies(detailIter{}))
+}
+
+func TestDetailColumns_size(t *testing.T) {
+	start := time.Now()
+	details := make([]ResultDetail, 10000)
+	for i := range details {
+		details[i] = ResultDetail{
+			Timestamp: start.Add(time.Duration(i) * time.Millisecond),
+			Latency:   time.Duration(1000+i%500) * time.Microsecond,
+			Status:    "OK",
+			Worker:    fmt.Sprintf("g0c0w%d", i%10),
+		}
+
+		if i%100 == 0 {
+			details[i].Status = "Unavailable"
+			details[i].Error = "rpc error: code = Unavailable desc = connection refused"
+		}
+	}
+
+	c := CompactDetails(details)
+
+	var size int
+	for _, col := range c.cols {
+		size += len(col)
+	}
+
+	// a detail takes several times less than the size of the struct alone
+	perDetail := size / len(details)
+	assert.True(t, perDetail < 20, "bytes per detail %d", perDetail)
+}
+
+func TestRunCompactDetails(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithCompactDetails(true),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Empty(t, report.Details)
+	assert.Equal(t, 20, report.DetailColumns.Len())
+	assert.Len(t, report.ResultDetails(), 20)
+	assert.NotZero(t, report.Fastest)
+	assert.NotEmpty(t, report.LatencyDistribution)
+
+	var count int
+	report.EachDetail(func(d *ResultDetail) {
+		assert.Equal(t, "OK", d.Status)
+		assert.NotZero(t, d.Latency)
+		count++
+	})
+
+	assert.Equal(t, 20, count)
+
+	b, err := json.Marshal(report)
+	assert.NoError(t, err)
+
+	var decoded Report
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Len(t, decoded.Details, 20)
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithCompactDetails(true),
+		WithStreamingPercentiles(true),
+	)
+
+	assert.EqualError(t, err, "compact details cannot be used with streaming percentiles, which keep no details")
+}
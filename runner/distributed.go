@@ -0,0 +1,490 @@
+package runner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc"
+)
+
+// AgentServiceName is the fully-qualified name of the agent service
+const AgentServiceName = "ghz.Agent"
+
+// agentProto is the definition of the agent service. The config of the run and the results
+// are encoded as JSON, so the agents accept the same config as the config file.
+const agentProto = `syntax = "proto3";
+
+package ghz;
+
+// Agent makes the share of a distributed run and streams its results back to the coordinator
+service Agent {
+  rpc Run (RunRequest) returns (stream RunEvent) {}
+}
+
+message RunRequest {
+  // the JSON config of the share of the run
+  bytes config = 1;
+}
+
+message RunEvent {
+  // the JSON array of the details of the results
+  bytes results = 1;
+
+  // the error of the run, if it failed
+  string error = 2;
+}
+`
+
+// agentBatch is the maximum number of results streamed in a single event
+const agentBatch = 500
+
+// agentBatchInterval is the maximum time the results are held back before being streamed
+const agentBatchInterval = 250 * time.Millisecond
+
+// Agent is the service making the shares of the runs distributed by RunDistributed.
+// The files referenced by the configs, such as the proto and data files, are read from
+// the file system of the agent.
+type Agent struct {
+	sd *desc.ServiceDescriptor
+}
+
+// NewAgent creates the agent service
+func NewAgent() (*Agent, error) {
+	sd, err := agentService()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Agent{sd: sd}, nil
+}
+
+func agentService() (*desc.ServiceDescriptor, error) {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"agent.proto": agentProto}),
+	}
+
+	fds, err := p.ParseFiles("agent.proto")
+	if err != nil {
+		return nil, err
+	}
+
+	return fds[0].FindService(AgentServiceName), nil
+}
+
+// Register registers the agent service with the gRPC server
+func (a *Agent) Register(gs *grpc.Server) error {
+	fdb, err := proto.Marshal(a.sd.GetFile().AsFileDescriptorProto())
+	if err != nil {
+		return err
+	}
+
+	// the reflection service expects a gzipped file descriptor
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(fdb); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	mtd := a.sd.FindMethodByName("Run")
+
+	gs.RegisterService(&grpc.ServiceDesc{
+		ServiceName: AgentServiceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    buf.Bytes(),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    mtd.GetName(),
+			ServerStreams: true,
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				return a.run(mtd, stream)
+			},
+		}},
+	}, a)
+
+	return nil
+}
+
+// run makes the run of the request, streaming its results in batches. The run is stopped
+// if the coordinator goes away, and its error is sent in the last event.
+func (a *Agent) run(mtd *desc.MethodDescriptor, stream grpc.ServerStream) error {
+	req := dynamic.NewMessage(mtd.GetInputType())
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(req.GetFieldByName("config").([]byte), &cfg); err != nil {
+		return fmt.Errorf("unmarshal config: %v", err)
+	}
+
+	// the results are sent from the goroutine of the reporter while the run is in progress,
+	// and from this one once it is done
+	var batch []ResultDetail
+	var sendErr error
+	last := time.Now()
+
+	send := func(runErr string) {
+		if sendErr != nil {
+			return
+		}
+
+		b, err := json.Marshal(batch)
+		if err != nil {
+			sendErr = err
+			return
+		}
+
+		ev := dynamic.NewMessage(mtd.GetOutputType())
+		ev.SetFieldByName("results", b)
+		ev.SetFieldByName("error", runErr)
+
+		sendErr = stream.SendMsg(ev)
+		batch = batch[:0]
+		last = time.Now()
+	}
+
+	c, err := NewConfig(cfg.Call, cfg.Host, WithConfig(&cfg), WithResultSink(func(r *ResultDetail) {
+		batch = append(batch, *r)
+		if len(batch) >= agentBatch || time.Since(last) >= agentBatchInterval {
+			send("")
+		}
+	}))
+
+	var reqr *Requester
+	if err == nil {
+		reqr, err = NewRequester(c)
+	}
+
+	if err != nil {
+		send(err.Error())
+		return sendErr
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-stream.Context().Done():
+			reqr.Stop(ReasonCancel)
+		case <-done:
+		}
+	}()
+
+	// the thresholds are checked by the coordinator using the merged report
+	var runErr string
+	if _, err := reqr.Run(); err != nil {
+		runErr = err.Error()
+	}
+
+	send(runErr)
+
+	return sendErr
+}
+
+// RunDistributed makes the run of the config on the agents started with `ghz agent`, and returns
+// the report of the results of all the agents merged together. The total number of requests,
+// the rate, the concurrency and the number of connections are divided between the agents, with
+// the remainders going to the first ones, while the rest of the config is the same for all of them.
+// The options apply to the merged report, such as the logger or the result sink.
+// If an agent fails the others are stopped, and the report of the results received until then
+// is returned along with the error.
+//
+//	report, err := runner.RunDistributed(&runner.Config{
+//		Call:   "helloworld.Greeter.SayHello",
+//		Host:   "greeter.internal:50051",
+//		Proto:  "greeter.proto",
+//		RPS:    30000,
+//		Z:      runner.Duration(time.Minute),
+//		Agents: []string{"agent-1:7777", "agent-2:7777", "agent-3:7777"},
+//	})
+func RunDistributed(cfg *Config, options ...Option) (*Report, error) {
+	if len(cfg.Agents) == 0 {
+		return nil, errors.New("a distributed run requires at least 1 agent")
+	}
+
+	shards, err := distributedShards(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *cfg
+	c, err := NewConfig(cfg.Call, cfg.Host, append([]Option{WithConfig(&merged)}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the agents run under the ID and the name of the merged run
+	for i := range shards {
+		shards[i].RunID = c.runID
+		shards[i].Name = c.name
+	}
+
+	if c.estimateFunc != nil {
+		c.estimateFunc(estimateLoad(c))
+	}
+
+	sd, err := agentService()
+	if err != nil {
+		return nil, err
+	}
+
+	mtd := sd.FindMethodByName("Run")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the agents have skipped their first results already
+	c.skipFirst = 0
+
+	results := make(chan *callResult, min(c.n, maxResult))
+	reporter := newReporter(results, c)
+	reporter.messages = &streamMessages{}
+
+	start := time.Now()
+	reporter.series = newTimeSeries(c.timeSeriesWindow, start.Add(c.warmup), c.countErrors)
+	reporter.slices = newTimeSlices(c.timeSlice, start.Add(c.warmup), c.countErrors)
+	reporter.rates = newThroughputCounter(c, start.Add(c.warmup))
+
+	go reporter.Run()
+
+	// the first agent failing stops the others, whose errors are then ignored
+	var errLock sync.Mutex
+	var runErr error
+	reason := ReasonNormalEnd
+
+	stop := func(r StopReason, err error) {
+		errLock.Lock()
+		defer errLock.Unlock()
+
+		if ctx.Err() == nil {
+			reason, runErr = r, err
+			cancel()
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			stop(ReasonInterrupt, nil)
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if err := runAgent(ctx, mtd, cfg.Agents[i], &shards[i], results); err != nil {
+				stop(ReasonCancel, fmt.Errorf("agent %s: %v", cfg.Agents[i], err))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	close(results)
+	<-reporter.done
+
+	total := time.Since(start)
+	if c.warmup > 0 && total > c.warmup {
+		total -= c.warmup
+	}
+
+	errLock.Lock()
+	defer errLock.Unlock()
+
+	rep := reporter.Finalize(reason, total)
+	if rep.StreamMessages != nil && reporter.messages.sent == 0 && reporter.messages.received == 0 {
+		rep.StreamMessages = nil
+	}
+
+	if len(c.metrics) > 0 {
+		rep.Metrics = computeMetrics(rep, c.metrics)
+	}
+
+	if runErr != nil {
+		return rep, runErr
+	}
+
+	if !rep.ThresholdsPassed() {
+		return rep, ErrThresholdsFailed
+	}
+
+	return rep, nil
+}
+
+// distributedShards returns the configs of the shares of the agents
+func distributedShards(cfg *Config) ([]Config, error) {
+	n := uint(len(cfg.Agents))
+
+	c := cfg.C
+	if c == 0 {
+		c = 50
+	}
+
+	if c < n {
+		return nil, fmt.Errorf("concurrency %d cannot be less than the %d agents", c, n)
+	}
+
+	// the total is only divided if the run is not limited by its duration
+	total := cfg.N
+	if cfg.Z > 0 {
+		total = 0
+	} else if total == 0 {
+		total = 200
+	}
+
+	if total > 0 && total < n {
+		return nil, fmt.Errorf("total number of requests %d cannot be less than the %d agents", total, n)
+	}
+
+	if cfg.RPS > 0 && cfg.RPS < n {
+		return nil, fmt.Errorf("rps %d cannot be less than the %d agents", cfg.RPS, n)
+	}
+
+	shards := make([]Config, n)
+	for i := range shards {
+		s := *cfg
+		s.Agents = nil
+
+		s.C = shareOf(c, n, uint(i))
+		s.RPS = shareOf(cfg.RPS, n, uint(i))
+
+		if total > 0 {
+			s.N = shareOf(total, n, uint(i))
+		}
+
+		if cfg.Connections > 0 {
+			s.Connections = shareOf(cfg.Connections, n, uint(i))
+			if s.Connections == 0 {
+				s.Connections = 1
+			}
+		}
+
+		shards[i] = s
+	}
+
+	return shards, nil
+}
+
+// shareOf returns the share of the i-th of n agents of the value, the first agents
+// getting one more than the others until the remainder is used up
+func shareOf(v, n, i uint) uint {
+	s := v / n
+	if i < v%n {
+		s++
+	}
+
+	return s
+}
+
+// runAgent makes the share of the run on the agent, sending the results received from it
+func runAgent(ctx context.Context, mtd *desc.MethodDescriptor, addr string, cfg *Config, results chan<- *callResult) error {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true},
+		"/"+AgentServiceName+"/"+mtd.GetName())
+	if err != nil {
+		return err
+	}
+
+	req := dynamic.NewMessage(mtd.GetInputType())
+	req.SetFieldByName("config", b)
+
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		ev := dynamic.NewMessage(mtd.GetOutputType())
+		err := stream.RecvMsg(ev)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		var details []ResultDetail
+		if b, _ := ev.GetFieldByName("results").([]byte); len(b) > 0 {
+			if err := json.Unmarshal(b, &details); err != nil {
+				return err
+			}
+		}
+
+		for i := range details {
+			results <- agentResult(addr, &details[i])
+		}
+
+		if msg, _ := ev.GetFieldByName("error").(string); msg != "" {
+			return errors.New(msg)
+		}
+	}
+}
+
+// agentResult returns the call result of the result detail received from the agent.
+// The workers are prefixed with the agent, since the agents number their workers alike.
+func agentResult(addr string, d *ResultDetail) *callResult {
+	res := &callResult{
+		status:       d.Status,
+		duration:     d.Latency,
+		timestamp:    d.Timestamp,
+		label:        d.Label,
+		lag:          d.Lag,
+		paced:        d.Lag > 0,
+		traceID:      d.TraceID,
+		worker:       addr + "/" + d.Worker,
+		sent:         d.BytesSent,
+		received:     d.BytesReceived,
+		sentMsgs:     d.MessagesSent,
+		receivedMsgs: d.MessagesReceived,
+		header:       d.HeaderLatency,
+		method:       d.Method,
+	}
+
+	if d.Error != "" {
+		res.err = errors.New(d.Error)
+	}
+
+	return res
+}
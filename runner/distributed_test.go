@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// startAgent starts an agent on a random port, returning its address
+func startAgent(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	a, err := NewAgent()
+	assert.NoError(t, err)
+
+	s := grpc.NewServer()
+	assert.NoError(t, a.Register(s))
+
+	go s.Serve(lis)
+
+	return lis.Addr().String(), s.Stop
+}
+
+func TestDistributedShards(t *testing.T) {
+	shards, err := distributedShards(&Config{N: 10, C: 5, RPS: 101, Connections: 2, Agents: []string{"a", "b", "c"}})
+	assert.NoError(t, err)
+
+	if assert.Len(t, shards, 3) {
+		var n, c, rps uint
+		for _, s := range shards {
+			assert.Nil(t, s.Agents)
+			assert.Equal(t, uint(1), s.Connections)
+
+			n += s.N
+			c += s.C
+			rps += s.RPS
+		}
+
+		assert.Equal(t, uint(10), n)
+		assert.Equal(t, uint(5), c)
+		assert.Equal(t, uint(101), rps)
+		assert.Equal(t, uint(4), shards[0].N)
+		assert.Equal(t, uint(3), shards[2].N)
+	}
+
+	// the defaults are divided, and the total of runs limited by a duration is not
+	shards, err = distributedShards(&Config{Z: Duration(60e9), Agents: []string{"a", "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(25), shards[1].C)
+	assert.Equal(t, uint(0), shards[1].N)
+	assert.Equal(t, uint(0), shards[1].Connections)
+
+	_, err = distributedShards(&Config{C: 2, Agents: []string{"a", "b", "c"}})
+	assert.EqualError(t, err, "concurrency 2 cannot be less than the 3 agents")
+
+	_, err = distributedShards(&Config{N: 2, C: 3, Agents: []string{"a", "b", "c"}})
+	assert.EqualError(t, err, "total number of requests 2 cannot be less than the 3 agents")
+
+	_, err = distributedShards(&Config{RPS: 2, C: 3, Agents: []string{"a", "b", "c"}})
+	assert.EqualError(t, err, "rps 2 cannot be less than the 3 agents")
+}
+
+func TestRunDistributed(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	a1, stop1 := startAgent(t)
+	defer stop1()
+
+	a2, stop2 := startAgent(t)
+	defer stop2()
+
+	t.Run("merged report", func(t *testing.T) {
+		gs.ResetCounters()
+
+		var workers []string
+
+		report, err := RunDistributed(&Config{
+			Call:     "helloworld.Greeter.SayHello",
+			Host:     internal.TestLocalhost,
+			Proto:    "../testdata/greeter.proto",
+			Insecure: true,
+			N:        21,
+			C:        4,
+			Data:     map[string]interface{}{"name": "bob"},
+			Name:     "distributed",
+			Agents:   []string{a1, a2},
+		}, WithResultSink(func(r *ResultDetail) {
+			workers = append(workers, r.Worker)
+		}))
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, report) {
+			assert.Equal(t, "distributed", report.Name)
+			assert.Equal(t, ReasonNormalEnd, report.EndReason)
+			assert.Equal(t, uint64(21), report.Count)
+			assert.Equal(t, map[string]int{"OK": 21}, report.StatusCodeDist)
+			assert.Nil(t, report.StreamMessages)
+		}
+
+		assert.Equal(t, 21, gs.GetCount(helloworld.Unary))
+
+		var fromA1, fromA2 int
+		for _, w := range workers {
+			switch {
+			case strings.HasPrefix(w, a1+"/"):
+				fromA1++
+			case strings.HasPrefix(w, a2+"/"):
+				fromA2++
+			}
+		}
+
+		assert.Equal(t, 11, fromA1)
+		assert.Equal(t, 10, fromA2)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		start := time.Now()
+
+		report, err := RunDistributed(&Config{
+			Call:     "helloworld.Greeter.SayHello",
+			Host:     internal.TestLocalhost,
+			Proto:    "../testdata/greeter.proto",
+			Insecure: true,
+			Z:        Duration(500 * time.Millisecond),
+			C:        2,
+			RPS:      40,
+			Data:     map[string]interface{}{"name": "bob"},
+			Agents:   []string{a1, a2},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, time.Since(start) < 5*time.Second)
+		if assert.NotNil(t, report) {
+			assert.True(t, report.Count > 0)
+		}
+	})
+
+	t.Run("agent error", func(t *testing.T) {
+		report, err := RunDistributed(&Config{
+			Call:     "helloworld.Greeter.Missing",
+			Host:     internal.TestLocalhost,
+			Proto:    "../testdata/greeter.proto",
+			Insecure: true,
+			N:        10,
+			C:        2,
+			Agents:   []string{a1, a2},
+		})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "agent ")
+		if assert.NotNil(t, report) {
+			assert.Equal(t, ReasonCancel, report.EndReason)
+			assert.Equal(t, uint64(0), report.Count)
+		}
+	})
+}
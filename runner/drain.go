@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// DrainStats holds the calls in flight when the run was stopped with the drain action.
+// For streaming calls these are the open streams.
+type DrainStats struct {
+	// Timeout is the grace period given to the calls in flight to complete
+	Timeout time.Duration `json:"timeout"`
+
+	// Duration is how long the drain took until the calls completed or were cut
+	Duration time.Duration `json:"duration"`
+
+	// InFlight is the number of calls in flight when the run was stopped
+	InFlight uint64 `json:"inFlight"`
+
+	// Drained is the number of calls completed during the drain
+	Drained uint64 `json:"drained"`
+
+	// Cut is the number of calls still in flight at the end of the grace period,
+	// which were cut by closing the connections
+	Cut uint64 `json:"cut"`
+}
+
+// drainTracker tracks the calls in flight of the workers, so that when stopping
+// the current calls can complete within the grace period before the connections are closed
+type drainTracker struct {
+	timeout time.Duration
+
+	lock     sync.Mutex
+	inFlight uint64
+	draining bool
+	started  bool
+	start    time.Time
+	idle     chan struct{}
+	stats    DrainStats
+}
+
+func newDrainTracker(timeout time.Duration) *drainTracker {
+	return &drainTracker{timeout: timeout, idle: make(chan struct{})}
+}
+
+// begin records the start of a call
+func (d *drainTracker) begin() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	d.inFlight++
+	d.lock.Unlock()
+}
+
+// end records the end of a call started with begin
+func (d *drainTracker) end() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.inFlight--
+
+	if d.draining {
+		d.stats.Drained++
+
+		if d.inFlight == 0 {
+			d.draining = false
+			d.stats.Duration = time.Since(d.start)
+			close(d.idle)
+		}
+	}
+}
+
+// drain waits until the calls in flight complete or the grace period is over, whichever is first.
+// The calls still in flight after the grace period are counted as cut.
+func (d *drainTracker) drain() {
+	d.lock.Lock()
+	d.started = true
+	d.start = time.Now()
+	d.stats = DrainStats{Timeout: d.timeout, InFlight: d.inFlight}
+
+	if d.inFlight == 0 {
+		d.lock.Unlock()
+		return
+	}
+
+	d.draining = true
+	d.lock.Unlock()
+
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-d.idle:
+	case <-timer.C:
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.draining {
+		d.draining = false
+		d.stats.Duration = time.Since(d.start)
+		d.stats.Cut = d.inFlight
+	}
+}
+
+// report returns the drain statistics, or nil if the run was not drained
+func (d *drainTracker) report() *DrainStats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.started {
+		return nil
+	}
+
+	stats := d.stats
+	if d.draining {
+		stats.Duration = time.Since(d.start)
+	}
+
+	return &stats
+}
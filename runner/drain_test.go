@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainTracker(t *testing.T) {
+	t.Run("not drained", func(t *testing.T) {
+		d := newDrainTracker(time.Second)
+		d.begin()
+		d.end()
+
+		assert.Nil(t, d.report())
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		d := newDrainTracker(time.Second)
+		d.drain()
+
+		assert.Equal(t, &DrainStats{Timeout: time.Second}, d.report())
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		d := newDrainTracker(time.Second)
+		d.begin()
+		d.begin()
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			d.end()
+			d.end()
+		}()
+
+		start := time.Now()
+		d.drain()
+
+		assert.True(t, time.Since(start) < time.Second)
+
+		stats := d.report()
+		assert.Equal(t, uint64(2), stats.InFlight)
+		assert.Equal(t, uint64(2), stats.Drained)
+		assert.Equal(t, uint64(0), stats.Cut)
+		assert.NotZero(t, stats.Duration)
+	})
+
+	t.Run("cut", func(t *testing.T) {
+		d := newDrainTracker(50 * time.Millisecond)
+		d.begin()
+		d.begin()
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			d.end()
+		}()
+
+		d.drain()
+
+		// the end of a cut call is not counted as drained
+		d.end()
+
+		stats := d.report()
+		assert.Equal(t, uint64(2), stats.InFlight)
+		assert.Equal(t, uint64(1), stats.Drained)
+		assert.Equal(t, uint64(1), stats.Cut)
+		assert.True(t, stats.Duration >= 50*time.Millisecond)
+	})
+}
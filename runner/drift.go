@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// SchemaDrift holds the unknown fields of the responses relative to the method descriptor used
+// for the test, which are found when the server is newer than the protos
+type SchemaDrift struct {
+	// Responses is the number of responses checked, and Drifted of those the number with unknown fields
+	Responses uint64 `json:"responses"`
+	Drifted   uint64 `json:"drifted"`
+
+	// Fields are the unknown fields, most frequent first
+	Fields []DriftField `json:"fields,omitempty"`
+}
+
+// DriftField is an unknown field of the responses
+type DriftField struct {
+	// Field is the path of the field, with the number of the unknown field last,
+	// such as 7 or details.items[].3
+	Field string `json:"field"`
+
+	// Message is the fully qualified name of the message with the unknown field
+	Message string `json:"message"`
+
+	// Count is the number of responses with the field
+	Count uint64 `json:"count"`
+}
+
+// driftTracker gathers the unknown fields of the responses
+type driftTracker struct {
+	lock      sync.Mutex
+	responses uint64
+	drifted   uint64
+	fields    map[string]*DriftField
+}
+
+func newDriftTracker() *driftTracker {
+	return &driftTracker{fields: make(map[string]*DriftField)}
+}
+
+// record records the unknown fields of a response
+func (t *driftTracker) record(msg proto.Message) {
+	dm, err := dynamic.AsDynamicMessage(msg)
+	if err != nil {
+		return
+	}
+
+	found := make(map[string]*DriftField)
+	unknownFields(dm, "", found)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.responses++
+
+	if len(found) == 0 {
+		return
+	}
+
+	t.drifted++
+
+	for path, f := range found {
+		if c := t.fields[path]; c != nil {
+			c.Count++
+		} else {
+			f.Count = 1
+			t.fields[path] = f
+		}
+	}
+}
+
+// unknownFields adds the unknown fields of the message and of its nested messages by their path
+func unknownFields(dm *dynamic.Message, prefix string, found map[string]*DriftField) {
+	md := dm.GetMessageDescriptor()
+
+	for _, n := range dm.GetUnknownFields() {
+		path := prefix + strconv.Itoa(int(n))
+		found[path] = &DriftField{Field: path, Message: md.GetFullyQualifiedName()}
+	}
+
+	for _, fd := range dm.GetKnownFields() {
+		if fd.GetMessageType() == nil {
+			continue
+		}
+
+		path := prefix + fd.GetName()
+
+		switch v := dm.GetField(fd).(type) {
+		case *dynamic.Message:
+			if v != nil {
+				unknownFields(v, path+".", found)
+			}
+		case []interface{}:
+			for _, e := range v {
+				if em, ok := e.(*dynamic.Message); ok {
+					unknownFields(em, path+"[].", found)
+				}
+			}
+		case map[interface{}]interface{}:
+			for _, e := range v {
+				if em, ok := e.(*dynamic.Message); ok {
+					unknownFields(em, path+"{}.", found)
+				}
+			}
+		}
+	}
+}
+
+func (t *driftTracker) stats() *SchemaDrift {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := &SchemaDrift{Responses: t.responses, Drifted: t.drifted}
+
+	for _, f := range t.fields {
+		s.Fields = append(s.Fields, *f)
+	}
+
+	sort.Slice(s.Fields, func(i, j int) bool {
+		if s.Fields[i].Count != s.Fields[j].Count {
+			return s.Fields[i].Count > s.Fields[j].Count
+		}
+
+		return s.Fields[i].Field < s.Fields[j].Field
+	})
+
+	return s
+}
+
+// threshold returns the result of the check that no response has unknown fields
+func (s *SchemaDrift) threshold() ThresholdResult {
+	return ThresholdResult{
+		Threshold: "no unknown response fields",
+		Actual:    float64(s.Drifted),
+		Pass:      s.Drifted == 0,
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriftTracker(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/Query", "../testdata/fields.proto", []string{})
+	assert.NoError(t, err)
+
+	decode := func(b []byte) *dynamic.Message {
+		m := dynamic.NewMessage(mtd.GetOutputType())
+		assert.NoError(t, m.Unmarshal(b))
+		return m
+	}
+
+	tracker := newDriftTracker()
+
+	// server = "a", no unknown fields
+	tracker.record(decode([]byte{0x2a, 0x01, 'a'}))
+
+	// server = "a" and the unknown field 9 = 1
+	tracker.record(decode([]byte{0x2a, 0x01, 'a', 0x48, 0x01}))
+
+	// stats with queue_depth = 1 and the unknown field 3 = 5, and the unknown field 9 = 1
+	tracker.record(decode([]byte{0x32, 0x04, 0x08, 0x01, 0x18, 0x05, 0x48, 0x01}))
+
+	s := tracker.stats()
+
+	assert.Equal(t, &SchemaDrift{
+		Responses: 3,
+		Drifted:   2,
+		Fields: []DriftField{
+			{Field: "9", Message: "fields.QueryReply", Count: 2},
+			{Field: "stats.3", Message: "fields.QueryStats", Count: 1},
+		},
+	}, s)
+
+	assert.Equal(t, ThresholdResult{Threshold: "no unknown response fields", Actual: 2, Pass: false}, s.threshold())
+	assert.True(t, (&SchemaDrift{Responses: 3}).threshold().Pass)
+}
@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// unmarshalMessage unmarshals the JSON data into the message. Enum values can be given
+// by name, including aliases, or by number. If an enum value is invalid the error lists
+// the allowed names of the enum.
+func unmarshalMessage(data string, msg *dynamic.Message) error {
+	err := jsonpb.UnmarshalString(data, msg)
+	if err == nil {
+		return nil
+	}
+
+	var v interface{}
+	if json.Unmarshal([]byte(data), &v) == nil {
+		if enumErr := validateEnums(msg.GetMessageDescriptor(), v, "", false); enumErr != nil {
+			return enumErr
+		}
+	}
+
+	return err
+}
+
+// validateEnums checks the enum values of the decoded JSON data of the message,
+// which can be a single message or an array of messages. If skipActions is true
+// the values with template actions are not checked.
+func validateEnums(md *desc.MessageDescriptor, data interface{}, path string, skipActions bool) error {
+	switch v := data.(type) {
+	case []interface{}:
+		for i, e := range v {
+			if err := validateEnums(md, e, fmt.Sprintf("%s[%d]", path, i), skipActions); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		// the well-known types have their own JSON formats
+		if strings.HasPrefix(md.GetFullyQualifiedName(), "google.protobuf.") {
+			return nil
+		}
+
+		for k, e := range v {
+			fd := md.FindFieldByJSONName(k)
+			if fd == nil {
+				fd = md.FindFieldByName(k)
+			}
+
+			if fd == nil || e == nil {
+				continue
+			}
+
+			if err := validateFieldEnums(fd, e, joinPath(path, k), skipActions); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateFieldEnums(fd *desc.FieldDescriptor, data interface{}, path string, skipActions bool) error {
+	if fd.IsMap() {
+		values, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		vfd := fd.GetMapValueType()
+		for k, e := range values {
+			if err := validateFieldEnums(vfd, e, fmt.Sprintf("%s[%q]", path, k), skipActions); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if fd.IsRepeated() {
+		if values, ok := data.([]interface{}); ok {
+			for i, e := range values {
+				if err := validateValueEnums(fd, e, fmt.Sprintf("%s[%d]", path, i), skipActions); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+
+	return validateValueEnums(fd, data, path, skipActions)
+}
+
+func validateValueEnums(fd *desc.FieldDescriptor, data interface{}, path string, skipActions bool) error {
+	if md := fd.GetMessageType(); md != nil {
+		if _, ok := data.(map[string]interface{}); ok {
+			return validateEnums(md, data, path, skipActions)
+		}
+
+		return nil
+	}
+
+	ed := fd.GetEnumType()
+	if ed == nil || data == nil {
+		return nil
+	}
+
+	switch v := data.(type) {
+	case string:
+		if skipActions && strings.Contains(v, "{{") {
+			return nil
+		}
+
+		if ed.FindValueByName(v) != nil {
+			return nil
+		}
+
+		if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+			return validateEnumNumber(ed, n, path)
+		}
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return validateEnumNumber(ed, int64(v), path)
+		}
+	}
+
+	return fmt.Errorf("invalid value %v of enum field %q of type %s, the allowed values are %s",
+		jsonValue(data), path, ed.GetFullyQualifiedName(), enumNames(ed))
+}
+
+// validateEnumNumber checks the number of the enum value. Proto3 enums are open so
+// any number is allowed, while proto2 enums only allow the defined numbers.
+func validateEnumNumber(ed *desc.EnumDescriptor, n int64, path string) error {
+	if ed.GetFile().IsProto3() || ed.FindValueByNumber(int32(n)) != nil {
+		return nil
+	}
+
+	return fmt.Errorf("invalid value %d of enum field %q of type %s, the allowed values are %s",
+		n, path, ed.GetFullyQualifiedName(), enumNames(ed))
+}
+
+// enumNames lists the names of the enum values with their numbers, including the aliases
+func enumNames(ed *desc.EnumDescriptor) string {
+	values := ed.GetValues()
+	names := make([]string, len(values))
+	for i, vd := range values {
+		names[i] = fmt.Sprintf("%s (%d)", vd.GetName(), vd.GetNumber())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func jsonValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
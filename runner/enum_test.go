@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnums(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("enums.Enums/Set", "../testdata/enum.proto", []string{})
+	assert.NoError(t, err)
+
+	input := mtd.GetInputType()
+
+	t.Run("names, aliases and numbers", func(t *testing.T) {
+		inputs, err := createPayloadsFromJSON(
+			`[{"color":"COLOR_RED"},{"color":"COLOR_CRIMSON"},{"color":2},{"color":"2"},{"color":7}]`, mtd)
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 5)
+
+		for i, expected := range []int32{1, 1, 2, 2, 7} {
+			assert.Equal(t, expected, inputs[i].GetFieldByName("color"), "message %d", i)
+		}
+	})
+
+	t.Run("nested values", func(t *testing.T) {
+		inputs, err := createPayloadsFromJSON(
+			`{"palette":["COLOR_BLUE",1],"named":{"a":"COLOR_CRIMSON"},"inner":{"color":"1"},"inners":[{"color":"COLOR_BLUE"}]}`, mtd)
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 1)
+		assert.Equal(t, []interface{}{int32(2), int32(1)}, inputs[0].GetFieldByName("palette"))
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := createPayloadsFromJSON(`{"color":"COLOR_GREEN"}`, mtd)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value "COLOR_GREEN" of enum field "color" of type enums.Color, the allowed values are COLOR_UNKNOWN (0), COLOR_RED (1), COLOR_CRIMSON (1), COLOR_BLUE (2)`)
+	})
+
+	t.Run("invalid nested values", func(t *testing.T) {
+		err := validateEnums(input, map[string]interface{}{"palette": []interface{}{"COLOR_RED", "red"}}, "", false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value "red" of enum field "palette[1]"`)
+
+		err = validateEnums(input, []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"inners": []interface{}{map[string]interface{}{"color": 1.5}}},
+		}, "", false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value 1.5 of enum field "[1].inners[0].color"`)
+
+		err = validateEnums(input, map[string]interface{}{"named": map[string]interface{}{"a": true}}, "", false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value true of enum field "named[\"a\"]"`)
+	})
+
+	t.Run("template actions", func(t *testing.T) {
+		data := map[string]interface{}{"color": "{{.RequestNumber}}", "name": "COLOR_GREEN"}
+
+		assert.NoError(t, validateEnums(input, data, "", true))
+		assert.Error(t, validateEnums(input, data, "", false))
+	})
+
+	t.Run("validated at setup", func(t *testing.T) {
+		_, err := newDataProvider(mtd, false, nil,
+			[]byte(`[{"name":"{{.RequestNumber}}","color":"COLOR_RED"},{"name":"{{.RequestNumber}}","color":"GREEN"}]`), nil, "", false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value "GREEN" of enum field "[1].color"`)
+
+		dp, err := newDataProvider(mtd, false, nil,
+			[]byte(`{"name":"{{.RequestNumber}}","color":"{{if eq .RequestNumber 1}}COLOR_BLUE{{else}}GREEN{{end}}"}`), nil, "", false)
+		assert.NoError(t, err)
+
+		inputs, err := dp.getDataForCall(newCallData(mtd, nil, "", 1))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), inputs[0].GetFieldByName("color"))
+
+		_, err = dp.getDataForCall(newCallData(mtd, nil, "", 2))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "the allowed values are COLOR_UNKNOWN (0)")
+	})
+
+	t.Run("closed enum", func(t *testing.T) {
+		p := protoparse.Parser{
+			Accessor: protoparse.FileContentsFromMap(map[string]string{"closed.proto": `
+syntax = "proto2";
+package closed;
+enum Level { LOW = 1; HIGH = 2; }
+message Req { optional Level level = 1; }`}),
+		}
+
+		fds, err := p.ParseFiles("closed.proto")
+		assert.NoError(t, err)
+
+		md := fds[0].FindMessage("closed.Req")
+		assert.NoError(t, validateEnums(md, map[string]interface{}{"level": 2.0}, "", false))
+
+		err = validateEnums(md, map[string]interface{}{"level": 3.0}, "", false)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid value 3 of enum field "level" of type closed.Level, the allowed values are LOW (1), HIGH (2)`)
+	})
+}
@@ -0,0 +1,226 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/bojand/ghz/load"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// maxEstimateDuration is the longest run duration the rate schedule is integrated for
+const maxEstimateDuration = 10 * 365 * 24 * time.Hour
+
+// Estimate is the upfront estimate of the load of a run, made without connecting to the host
+type Estimate struct {
+	Call        string `json:"call"`
+	Host        string `json:"host"`
+	Concurrency uint   `json:"concurrency"`
+	Connections uint   `json:"connections"`
+
+	// Paced is whether the requests are rate-paced, either using a RPS or a load schedule.
+	// The duration of count based runs and the number of requests of duration based runs
+	// can only be estimated for rate-paced runs, and otherwise depend on the latency.
+	Paced bool `json:"paced"`
+
+	// Unlimited is whether the run goes on until it is interrupted, in which case
+	// neither the number of requests nor the duration can be estimated
+	Unlimited bool `json:"unlimited,omitempty"`
+
+	// Requests is the expected total number of requests, or 0 if it depends on the latency
+	Requests uint64 `json:"requests"`
+
+	// Duration is the expected duration of the run, or 0 if it depends on the latency.
+	// With a max duration it is the longest the run can take.
+	Duration time.Duration `json:"duration"`
+
+	// PeakRate is the peak number of requests per second of rate-paced runs
+	PeakRate float64 `json:"peakRate"`
+
+	// RequestSize is the average size in bytes of the request messages of a call
+	RequestSize int `json:"requestSize"`
+
+	// MessagesPerCall is the number of request messages sent in a call, or 0 if the
+	// request size is not known as the method is resolved using server reflection
+	MessagesPerCall int `json:"messagesPerCall"`
+
+	// PeakBandwidth is the peak number of request bytes sent per second of rate-paced runs
+	PeakBandwidth float64 `json:"peakBandwidth"`
+}
+
+// EstimateRun estimates the duration, number of requests and peak bandwidth of a run
+// with the options, without making any calls. The request size is only estimated
+// when the method is resolved using a proto file or a protoset.
+func EstimateRun(call, host string, options ...Option) (*Estimate, error) {
+	c, err := NewConfig(call, host, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lazily read data is not read for the estimate
+	if c.dataFile != nil {
+		defer c.dataFile.Close()
+	}
+
+	defer c.lines.close()
+
+	e := estimateLoad(c)
+
+	var mtd *desc.MethodDescriptor
+	if c.proto != "" {
+		mtd, err = protodesc.GetMethodDescFromProto(c.call, c.proto, c.importPaths)
+	} else if c.protoset != "" {
+		mtd, err = protodesc.GetMethodDescFromProtoSet(c.call, c.protoset)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the lazily read or indexed data is not known upfront
+	if mtd != nil && c.dataReader == nil && c.dataIndexedPath == "" {
+		if e.RequestSize, e.MessagesPerCall, err = estimateRequestSize(c, mtd); err != nil {
+			return nil, err
+		}
+	}
+
+	e.PeakBandwidth = e.PeakRate * float64(e.RequestSize*e.MessagesPerCall)
+
+	return e, nil
+}
+
+// estimateLoad estimates the duration, number of requests and peak rate of the run of the config
+func estimateLoad(c *RunConfig) *Estimate {
+	e := &Estimate{
+		Call:        c.call,
+		Host:        c.host,
+		Concurrency: uint(c.c),
+		Connections: uint(c.nConns),
+		Paced:       c.pacer != nil || c.loadSchedule != ScheduleConst || c.rps > 0,
+	}
+
+	switch {
+	case c.untilSignal:
+		e.Unlimited = true
+
+		// only the peak rate of a constant load is known upfront
+		if e.Paced && c.pacer == nil && c.loadSchedule == ScheduleConst {
+			e.PeakRate = float64(c.rps)
+		}
+	case e.Paced:
+		e.Requests, e.Duration, e.PeakRate = estimateSchedule(createPacer(c), uint64(c.n), c.runLimit())
+	default:
+		// the duration of the runs which are not rate-paced is only known when they are limited by it
+		e.Duration = c.runLimit()
+		if c.z == 0 {
+			e.Requests = uint64(c.n)
+		}
+	}
+
+	return e
+}
+
+// estimateSchedule integrates the rate of the pacer over time, returning the number of
+// requests sent until the maximum number of requests or the duration is reached,
+// the time it takes and the peak rate. A max or duration of 0 is unlimited.
+func estimateSchedule(p load.Pacer, max uint64, duration time.Duration) (uint64, time.Duration, float64) {
+	var elapsed time.Duration
+	var hits, peak float64
+
+	dt := 100 * time.Millisecond
+	for i := 1; ; i++ {
+		if duration > 0 && elapsed >= duration {
+			break
+		}
+
+		rate := p.Rate(elapsed)
+
+		// the schedule stops at a rate of 0
+		if rate <= 0 || elapsed >= maxEstimateDuration {
+			break
+		}
+
+		if rate > peak {
+			peak = rate
+		}
+
+		step := dt
+		if duration > 0 && elapsed+step > duration {
+			step = duration - elapsed
+		}
+
+		if max > 0 && hits+rate*step.Seconds() >= float64(max) {
+			elapsed += time.Duration((float64(max) - hits) / rate * float64(time.Second))
+			hits = float64(max)
+			break
+		}
+
+		hits += rate * step.Seconds()
+		elapsed += step
+
+		// long runs are integrated with a coarser step
+		if i%100000 == 0 {
+			dt *= 2
+		}
+	}
+
+	return uint64(hits), elapsed, peak
+}
+
+// estimateRequestSize returns the average size of the request messages of the first call
+// and the number of messages sent in a call
+func estimateRequestSize(c *RunConfig, mtd *desc.MethodDescriptor) (int, int, error) {
+	ctd := newCallData(mtd, c.funcs, "g0c0", 0)
+	ctd.RunID = c.runID
+
+	var dataProvider DataProviderFunc
+	if c.dataProviderFunc != nil {
+		dataProvider = c.dataProviderFunc
+	} else {
+		dp, err := newDataProvider(mtd, c.binary, c.dataFunc, c.data, c.funcs, c.dataLabel, c.emitDefaults)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		dataProvider = dp.getDataForCall
+	}
+
+	inputs, err := dataProvider(ctd)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(inputs) == 0 {
+		return 0, 1, nil
+	}
+
+	var size int
+	for _, in := range inputs {
+		b, err := in.Marshal()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		size += len(b)
+	}
+
+	size = size / len(inputs)
+
+	if !mtd.IsClientStreaming() {
+		return size, 1, nil
+	}
+
+	messages := len(inputs)
+	if c.streamCallCount > 0 {
+		messages = int(c.streamCallCount)
+	}
+
+	// the stream is closed after the maximum stream call duration
+	if c.streamCallDuration > 0 && c.streamInterval > 0 {
+		if n := int(c.streamCallDuration / c.streamInterval); n >= 1 && n < messages {
+			messages = n
+		}
+	}
+
+	return size, messages, nil
+}
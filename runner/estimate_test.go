@@ -0,0 +1,206 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/load"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateSchedule(t *testing.T) {
+	t.Run("constant count", func(t *testing.T) {
+		p := &load.ConstantPacer{Freq: 500, Max: 1000000}
+
+		requests, duration, peak := estimateSchedule(p, 1000000, 0)
+		assert.Equal(t, uint64(1000000), requests)
+		assert.InDelta(t, (2000 * time.Second).Seconds(), duration.Seconds(), 0.01)
+		assert.Equal(t, 500.0, peak)
+	})
+
+	t.Run("constant duration", func(t *testing.T) {
+		p := &load.ConstantPacer{Freq: 200}
+
+		requests, duration, peak := estimateSchedule(p, 0, 90*time.Second)
+		assert.InDelta(t, 18000, float64(requests), 1)
+		assert.Equal(t, 90*time.Second, duration)
+		assert.Equal(t, 200.0, peak)
+	})
+
+	t.Run("step", func(t *testing.T) {
+		p := &load.StepPacer{
+			Start:        load.ConstantPacer{Freq: 10},
+			Step:         10,
+			StepDuration: 10 * time.Second,
+			Stop:         load.ConstantPacer{Freq: 50},
+		}
+
+		requests, duration, peak := estimateSchedule(p, 0, time.Minute)
+		// 100 + 200 + 300 + 400 over the first 40s, then 50 rps for 20s
+		assert.InDelta(t, 2000, float64(requests), 1)
+		assert.Equal(t, time.Minute, duration)
+		assert.Equal(t, 50.0, peak)
+	})
+
+	t.Run("step down to zero", func(t *testing.T) {
+		p := &load.StepPacer{
+			Start:        load.ConstantPacer{Freq: 30},
+			Step:         -10,
+			StepDuration: 10 * time.Second,
+		}
+
+		requests, duration, peak := estimateSchedule(p, 0, time.Hour)
+		assert.InDelta(t, 600, float64(requests), 1)
+		assert.Equal(t, 30*time.Second, duration)
+		assert.InDelta(t, 30, peak, 0.001)
+	})
+}
+
+func TestEstimateRun(t *testing.T) {
+	t.Run("paced", func(t *testing.T) {
+		e, err := EstimateRun(
+			"helloworld.Greeter.SayHello", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithTotalRequests(1000),
+			WithRPS(100),
+			WithConcurrency(10),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, e.Paced)
+		assert.Equal(t, uint64(1000), e.Requests)
+		assert.InDelta(t, (10 * time.Second).Seconds(), e.Duration.Seconds(), 0.01)
+		assert.Equal(t, 100.0, e.PeakRate)
+		assert.Equal(t, uint(10), e.Concurrency)
+		assert.Equal(t, 1, e.MessagesPerCall)
+		// field tag and length prefix followed by "bob"
+		assert.Equal(t, 5, e.RequestSize)
+		assert.Equal(t, 500.0, e.PeakBandwidth)
+	})
+
+	t.Run("until signal", func(t *testing.T) {
+		e, err := EstimateRun(
+			"helloworld.Greeter.SayHello", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithRPS(100),
+			WithRunUntilSignal(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, e.Paced)
+		assert.True(t, e.Unlimited)
+		assert.Zero(t, e.Requests)
+		assert.Zero(t, e.Duration)
+		assert.Equal(t, 100.0, e.PeakRate)
+		assert.Equal(t, 5, e.RequestSize)
+	})
+
+	t.Run("unpaced", func(t *testing.T) {
+		e, err := EstimateRun(
+			"helloworld.Greeter.SayHello", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithRunDuration(time.Minute),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.NoError(t, err)
+		assert.False(t, e.Paced)
+		assert.Equal(t, uint64(0), e.Requests)
+		assert.Equal(t, time.Minute, e.Duration)
+		assert.Equal(t, 0.0, e.PeakBandwidth)
+	})
+
+	t.Run("unpaced with max duration", func(t *testing.T) {
+		e, err := EstimateRun(
+			"helloworld.Greeter.SayHello", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithTotalRequests(500),
+			WithMaxDuration(time.Minute),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.NoError(t, err)
+		assert.False(t, e.Paced)
+		assert.Equal(t, uint64(500), e.Requests)
+		assert.Equal(t, time.Minute, e.Duration)
+	})
+
+	t.Run("client streaming", func(t *testing.T) {
+		e, err := EstimateRun(
+			"helloworld.Greeter.SayHelloCS", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithTotalRequests(10),
+			WithData([]interface{}{
+				map[string]interface{}{"name": "bob"},
+				map[string]interface{}{"name": "alice"},
+			}),
+			WithStreamCallCount(3),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, e.MessagesPerCall)
+		assert.Equal(t, 6, e.RequestSize)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := EstimateRun(
+			"helloworld.Greeter.SayHello", "localhost:50051",
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithInsecure(true),
+			WithDataFromJSON(`{"unknown":"bob"}`),
+		)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestRunEstimateFunc(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	gs.ResetCounters()
+
+	var est *Estimate
+	calls := -1
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(1),
+		WithRPS(100),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithEstimateFunc(func(e *Estimate) {
+			est = e
+			calls = gs.GetCount(helloworld.Unary)
+		}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+
+	// the estimate is made before any call
+	assert.Equal(t, 0, calls)
+
+	if assert.NotNil(t, est) {
+		assert.True(t, est.Paced)
+		assert.Equal(t, uint64(10), est.Requests)
+		assert.Equal(t, 100*time.Millisecond, est.Duration.Round(10*time.Millisecond))
+		assert.Equal(t, 0, est.RequestSize)
+	}
+}
@@ -3,9 +3,13 @@ package runner_test
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/bojand/ghz/internal/helloworld"
 	"github.com/bojand/ghz/printer"
 	"github.com/bojand/ghz/runner"
+	"github.com/jhump/protoreflect/dynamic"
 )
 
 // ExampleRun demonstrates how to use runner package to perform a gRPC load test programmatically.
@@ -31,3 +35,51 @@ func ExampleRun() {
 
 	printer.Print("pretty")
 }
+
+// ExampleWithDataProvider demonstrates how to generate the request payload of each call
+// programmatically, rather than from JSON data.
+func ExampleWithDataProvider() {
+	report, err := runner.Run(
+		"helloworld.Greeter.SayHello",
+		"localhost:50051",
+		runner.WithProtoFile("greeter.proto", []string{}),
+		runner.WithDataProvider(func(cd *runner.CallData) ([]*dynamic.Message, error) {
+			msg, err := dynamic.AsDynamicMessage(&helloworld.HelloRequest{
+				Name: cd.WorkerID + ":" + strconv.FormatInt(cd.RequestNumber, 10),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return []*dynamic.Message{msg}, nil
+		}),
+		runner.WithInsecure(true),
+	)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println(report.Count)
+}
+
+// ExampleWithResultSink demonstrates how to stream the results as the calls complete,
+// rather than waiting for the report at the end of the run.
+func ExampleWithResultSink() {
+	_, err := runner.Run(
+		"helloworld.Greeter.SayHello",
+		"localhost:50051",
+		runner.WithProtoFile("greeter.proto", []string{}),
+		runner.WithDataFromFile("data.json"),
+		runner.WithInsecure(true),
+		runner.WithResultSink(func(r *runner.ResultDetail) {
+			fmt.Println(r.Timestamp.Format(time.RFC3339Nano), r.Status, r.Latency)
+		}),
+	)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
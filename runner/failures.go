@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxFailureMessages is the maximum number of distinct error messages counted for each
+// status code, so that messages with unique parts such as request IDs do not grow unbounded
+const maxFailureMessages = 1000
+
+// otherFailureMessage is the message counting the errors beyond the maximum number of messages
+const otherFailureMessage = "(other)"
+
+// StatusBreakdown holds the failed calls of a status code
+type StatusBreakdown struct {
+	Code  string `json:"code"`
+	Count uint64 `json:"count"`
+
+	// Messages are the most frequent error messages of the code, most frequent first
+	Messages []ErrorMessage `json:"messages,omitempty"`
+
+	// Samples are the status details and the trailers of the first failed calls of the code
+	Samples []ErrorSample `json:"samples,omitempty"`
+}
+
+// ErrorMessage is the count of an error message
+type ErrorMessage struct {
+	Message string `json:"message"`
+	Count   uint64 `json:"count"`
+}
+
+// ErrorSample holds the status details and the trailer of a failed call
+type ErrorSample struct {
+	Message string      `json:"message"`
+	Details []string    `json:"details,omitempty"`
+	Trailer metadata.MD `json:"trailer,omitempty"`
+}
+
+// failureTracker gathers the failed calls by status code
+type failureTracker struct {
+	top     int
+	samples int
+
+	codes map[string]*failureCode
+}
+
+// failureCode holds the failed calls of a status code
+type failureCode struct {
+	count    uint64
+	messages map[string]uint64
+	samples  []ErrorSample
+}
+
+func newFailureTracker(top, samples int) *failureTracker {
+	if top <= 0 && samples <= 0 {
+		return nil
+	}
+
+	return &failureTracker{top: top, samples: samples, codes: make(map[string]*failureCode)}
+}
+
+// add adds the failed call of the result
+func (t *failureTracker) add(res *callResult) {
+	if t == nil || res.err == nil {
+		return
+	}
+
+	c := t.codes[res.status]
+	if c == nil {
+		c = &failureCode{messages: make(map[string]uint64)}
+		t.codes[res.status] = c
+	}
+
+	c.count++
+
+	msg := res.err.Error()
+	st, isStatus := status.FromError(res.err)
+	if isStatus {
+		msg = st.Message()
+	}
+
+	if _, ok := c.messages[msg]; ok || len(c.messages) < maxFailureMessages {
+		c.messages[msg]++
+	} else {
+		c.messages[otherFailureMessage]++
+	}
+
+	if len(c.samples) < t.samples {
+		sample := ErrorSample{Message: msg, Trailer: res.trailer}
+		if isStatus {
+			sample.Details = statusDetails(st)
+		}
+
+		c.samples = append(c.samples, sample)
+	}
+}
+
+// statusDetails returns the details of the status in the text format, or the type URL
+// of the details whose type is not known
+func statusDetails(st *status.Status) []string {
+	anys := st.Proto().GetDetails()
+	if len(anys) == 0 {
+		return nil
+	}
+
+	details := make([]string, len(anys))
+	for i, d := range st.Details() {
+		if msg, ok := d.(proto.Message); ok {
+			details[i] = proto.MessageName(msg) + ": " + strings.TrimSpace(proto.CompactTextString(msg))
+		} else {
+			details[i] = anys[i].GetTypeUrl()
+		}
+	}
+
+	return details
+}
+
+// breakdown returns the failed calls by status code, most frequent first
+func (t *failureTracker) breakdown() []StatusBreakdown {
+	res := make([]StatusBreakdown, 0, len(t.codes))
+	for code, c := range t.codes {
+		b := StatusBreakdown{Code: code, Count: c.count, Samples: c.samples}
+
+		if t.top > 0 {
+			for msg, n := range c.messages {
+				b.Messages = append(b.Messages, ErrorMessage{Message: msg, Count: n})
+			}
+
+			sort.Slice(b.Messages, func(i, j int) bool {
+				if b.Messages[i].Count != b.Messages[j].Count {
+					return b.Messages[i].Count > b.Messages[j].Count
+				}
+
+				return b.Messages[i].Message < b.Messages[j].Message
+			})
+
+			if len(b.Messages) > t.top {
+				b.Messages = b.Messages[:t.top]
+			}
+		}
+
+		res = append(res, b)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+
+		return res[i].Code < res[j].Code
+	})
+
+	return res
+}
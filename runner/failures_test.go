@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailureTracker(t *testing.T) {
+	st, err := status.New(codes.Unavailable, "overloaded").WithDetails(&wrappers.StringValue{Value: "retry later"})
+	assert.NoError(t, err)
+
+	trailer := metadata.Pairs("retry-after", "1")
+
+	tracker := newFailureTracker(1, 1)
+
+	tracker.add(&callResult{status: "OK"})
+	tracker.add(&callResult{status: "Unavailable", err: st.Err(), trailer: trailer})
+	tracker.add(&callResult{status: "Unavailable", err: st.Err()})
+	tracker.add(&callResult{status: "Unavailable", err: status.Error(codes.Unavailable, "connection refused")})
+	tracker.add(&callResult{status: "Unknown", err: errors.New("boom")})
+
+	assert.Equal(t, []StatusBreakdown{
+		{
+			Code:     "Unavailable",
+			Count:    3,
+			Messages: []ErrorMessage{{Message: "overloaded", Count: 2}},
+			Samples: []ErrorSample{{
+				Message: "overloaded",
+				Details: []string{`google.protobuf.StringValue: value:"retry later"`},
+				Trailer: trailer,
+			}},
+		},
+		{
+			Code:     "Unknown",
+			Count:    1,
+			Messages: []ErrorMessage{{Message: "boom", Count: 1}},
+			Samples:  []ErrorSample{{Message: "boom"}},
+		},
+	}, tracker.breakdown())
+
+	assert.Nil(t, newFailureTracker(0, 0))
+}
@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// ResponseFieldStats holds the distribution of the values of a response field,
+// such as a server reported queue depth or result count
+type ResponseFieldStats struct {
+	// Field is the response field
+	Field string `json:"field"`
+
+	// Count is the number of responses with the field
+	Count uint64 `json:"count"`
+
+	// Values is the distribution of numeric fields
+	Values *FieldValueStats `json:"values,omitempty"`
+
+	// Categories are the counts of the values of enum and bool fields, most frequent first
+	Categories []FieldCategory `json:"categories,omitempty"`
+}
+
+// FieldValueStats holds the distribution of the values of a numeric response field
+type FieldValueStats struct {
+	Min          float64             `json:"min"`
+	Mean         float64             `json:"mean"`
+	Max          float64             `json:"max"`
+	Distribution []FieldDistribution `json:"distribution"`
+
+	// Correlation is the Pearson correlation coefficient of the values and
+	// the latencies of the calls, between -1 and 1
+	Correlation float64 `json:"correlation"`
+}
+
+// FieldDistribution is a percentile of the values of a numeric response field
+type FieldDistribution struct {
+	Percentage int     `json:"percentage"`
+	Value      float64 `json:"value"`
+}
+
+// FieldCategory holds the count of a value of an enum or bool response field
+// and the average latency of the calls with the value
+type FieldCategory struct {
+	Value   string        `json:"value"`
+	Count   uint64        `json:"count"`
+	Average time.Duration `json:"average"`
+}
+
+// fieldTracker gathers the values of a response field
+type fieldTracker struct {
+	field       string
+	categorical bool
+
+	lock       sync.Mutex
+	count      uint64
+	values     []float64
+	latencies  []float64
+	categories map[string]*fieldCategory
+}
+
+type fieldCategory struct {
+	count      uint64
+	latencySec float64
+}
+
+func newFieldTracker(mtd *desc.MethodDescriptor, field string) (*fieldTracker, error) {
+	if mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("response field is only supported for unary and client streaming calls")
+	}
+
+	fd, err := findFieldPath(mtd.GetOutputType(), field)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &fieldTracker{field: field}
+
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_ENUM, descriptor.FieldDescriptorProto_TYPE_BOOL:
+		t.categorical = true
+		t.categories = make(map[string]*fieldCategory)
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return nil, fmt.Errorf("response field %q of message %s must be numeric, enum or bool",
+			field, mtd.GetOutputType().GetFullyQualifiedName())
+	}
+
+	return t, nil
+}
+
+// record records the field value of the response of a call with the latency
+func (t *fieldTracker) record(msg proto.Message, latency time.Duration) {
+	dm, err := dynamic.AsDynamicMessage(msg)
+	if err != nil {
+		return
+	}
+
+	fd, v, ok := lookupField(dm, t.field)
+	if !ok {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.count++
+
+	if t.categorical {
+		key := fmt.Sprint(v)
+		if n, isEnum := v.(int32); isEnum {
+			if ev := fd.GetEnumType().FindValueByNumber(n); ev != nil {
+				key = ev.GetName()
+			}
+		}
+
+		c := t.categories[key]
+		if c == nil {
+			c = &fieldCategory{}
+			t.categories[key] = c
+		}
+
+		c.count++
+		c.latencySec += latency.Seconds()
+
+		return
+	}
+
+	if len(t.values) < maxResult {
+		t.values = append(t.values, numericValue(v))
+		t.latencies = append(t.latencies, latency.Seconds())
+	}
+}
+
+func (t *fieldTracker) stats() *ResponseFieldStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := &ResponseFieldStats{Field: t.field, Count: t.count}
+
+	if t.categorical {
+		for k, c := range t.categories {
+			s.Categories = append(s.Categories, FieldCategory{
+				Value:   k,
+				Count:   c.count,
+				Average: time.Duration(c.latencySec / float64(c.count) * float64(time.Second)),
+			})
+		}
+
+		sort.Slice(s.Categories, func(i, j int) bool {
+			if s.Categories[i].Count != s.Categories[j].Count {
+				return s.Categories[i].Count > s.Categories[j].Count
+			}
+
+			return s.Categories[i].Value < s.Categories[j].Value
+		})
+
+		return s
+	}
+
+	if len(t.values) > 0 {
+		s.Values = fieldValueStats(t.values, t.latencies)
+	}
+
+	return s
+}
+
+func fieldValueStats(values, lats []float64) *FieldValueStats {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	n := float64(len(values))
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+
+	s := &FieldValueStats{
+		Min:         sorted[0],
+		Mean:        sum / n,
+		Max:         sorted[len(sorted)-1],
+		Correlation: correlation(values, lats),
+	}
+
+	for _, p := range []int{10, 25, 50, 75, 90, 95, 99} {
+		// same ranks as the latency distribution
+		ip := (float64(p) / 100.0) * n
+		di := int(ip)
+		if ip == float64(di) {
+			di = di - 1
+		}
+
+		if di < 0 {
+			di = 0
+		}
+
+		s.Distribution = append(s.Distribution, FieldDistribution{Percentage: p, Value: sorted[di]})
+	}
+
+	return s
+}
+
+// correlation returns the Pearson correlation coefficient of the samples,
+// or 0 if either of them has no variance
+func correlation(xs, ys []float64) float64 {
+	n := float64(len(xs))
+
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+
+	mx, my := sx/n, sy/n
+
+	var cov, vx, vy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(vx*vy)
+}
+
+// numericValue converts the value of a numeric field to float64
+func numericValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	}
+
+	return 0
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldTracker(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/Query", "../testdata/fields.proto", []string{})
+	assert.NoError(t, err)
+
+	reply := func(fields map[string]interface{}) *dynamic.Message {
+		res := dynamic.NewMessage(mtd.GetOutputType())
+		for k, v := range fields {
+			if k == "depth" {
+				stats := dynamic.NewMessage(mtd.GetOutputType().FindFieldByName("stats").GetMessageType())
+				stats.SetFieldByName("queue_depth", v)
+				res.SetFieldByName("stats", stats)
+				continue
+			}
+
+			res.SetFieldByName(k, v)
+		}
+
+		return res
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newFieldTracker(mtd, "missing")
+		assert.Error(t, err)
+
+		_, err = newFieldTracker(mtd, "server")
+		assert.Error(t, err)
+
+		_, err = newFieldTracker(mtd, "stats")
+		assert.Error(t, err)
+
+		streamMtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/QueryStream", "../testdata/fields.proto", []string{})
+		assert.NoError(t, err)
+
+		_, err = newFieldTracker(streamMtd, "result_count")
+		assert.Error(t, err)
+	})
+
+	t.Run("numeric", func(t *testing.T) {
+		tracker, err := newFieldTracker(mtd, "stats.queueDepth")
+		assert.NoError(t, err)
+
+		for i := 1; i <= 10; i++ {
+			tracker.record(reply(map[string]interface{}{"depth": int32(i)}), time.Duration(i)*time.Millisecond)
+		}
+
+		s := tracker.stats()
+		assert.Equal(t, "stats.queueDepth", s.Field)
+		assert.Equal(t, uint64(10), s.Count)
+		assert.Empty(t, s.Categories)
+		assert.NotNil(t, s.Values)
+		assert.Equal(t, 1.0, s.Values.Min)
+		assert.Equal(t, 5.5, s.Values.Mean)
+		assert.Equal(t, 10.0, s.Values.Max)
+		assert.InDelta(t, 1.0, s.Values.Correlation, 1e-9)
+		assert.Len(t, s.Values.Distribution, 7)
+		assert.Equal(t, FieldDistribution{Percentage: 50, Value: 5}, s.Values.Distribution[2])
+		assert.Equal(t, FieldDistribution{Percentage: 99, Value: 10}, s.Values.Distribution[6])
+	})
+
+	t.Run("enum", func(t *testing.T) {
+		tracker, err := newFieldTracker(mtd, "source")
+		assert.NoError(t, err)
+
+		tracker.record(reply(map[string]interface{}{"source": int32(1)}), 2*time.Millisecond)
+		tracker.record(reply(map[string]interface{}{"source": int32(2)}), 20*time.Millisecond)
+		tracker.record(reply(map[string]interface{}{"source": int32(1)}), 4*time.Millisecond)
+		tracker.record(reply(nil), 10*time.Millisecond)
+
+		s := tracker.stats()
+		assert.Equal(t, uint64(4), s.Count)
+		assert.Nil(t, s.Values)
+		assert.Equal(t, []FieldCategory{
+			{Value: "SOURCE_CACHE", Count: 2, Average: 3 * time.Millisecond},
+			{Value: "SOURCE_DATABASE", Count: 1, Average: 20 * time.Millisecond},
+			{Value: "SOURCE_UNKNOWN", Count: 1, Average: 10 * time.Millisecond},
+		}, s.Categories)
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		tracker, err := newFieldTracker(mtd, "partial")
+		assert.NoError(t, err)
+
+		tracker.record(reply(map[string]interface{}{"partial": true}), time.Millisecond)
+
+		s := tracker.stats()
+		assert.Equal(t, []FieldCategory{{Value: "true", Count: 1, Average: time.Millisecond}}, s.Categories)
+	})
+}
+
+func TestCorrelation(t *testing.T) {
+	assert.InDelta(t, -1.0, correlation([]float64{1, 2, 3}, []float64{3, 2, 1}), 1e-9)
+	assert.Equal(t, 0.0, correlation([]float64{1, 1, 1}, []float64{3, 2, 1}))
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// graceRetryDelay is the delay before retrying a call that failed during the grace period
+const graceRetryDelay = 50 * time.Millisecond
+
+// callGraceKey is the context key of the calls made during the grace period
+type callGraceKey struct{}
+
+// GraceRetries holds the calls that failed with Unavailable during the grace period at
+// the start of the run, such as while sidecars are warming up. The calls were retried
+// and are not counted in the results.
+type GraceRetries struct {
+	// Period is the grace period
+	Period time.Duration `json:"period"`
+
+	// Count is the number of retried calls
+	Count uint64 `json:"count"`
+
+	ErrorDist map[string]int `json:"errorDistribution"`
+}
+
+// gracePeriod gathers the calls retried during the grace period of the run
+type gracePeriod struct {
+	period time.Duration
+	until  time.Time
+
+	lock      sync.Mutex
+	count     uint64
+	errorDist map[string]int
+}
+
+func newGracePeriod(period time.Duration) *gracePeriod {
+	if period <= 0 {
+		return nil
+	}
+
+	return &gracePeriod{period: period, errorDist: make(map[string]int)}
+}
+
+// begin starts the grace period, it has to be called before the workers are started
+func (g *gracePeriod) begin(start time.Time) {
+	if g != nil {
+		g.until = start.Add(g.period)
+	}
+}
+
+// active returns whether the calls made now are within the grace period
+func (g *gracePeriod) active() bool {
+	return g != nil && time.Now().Before(g.until)
+}
+
+func (g *gracePeriod) stats() *GraceRetries {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return &GraceRetries{Period: g.period, Count: g.count, ErrorDist: g.errorDist}
+}
+
+// graceCall is a call made during the grace period
+type graceCall struct {
+	grace *gracePeriod
+
+	lock    sync.Mutex
+	retried bool
+}
+
+// drop returns whether the result of the call with the error should be dropped and the call retried
+func (c *graceCall) drop(err error) bool {
+	if statusCode(err) != codes.Unavailable {
+		return false
+	}
+
+	c.lock.Lock()
+	c.retried = true
+	c.lock.Unlock()
+
+	c.grace.lock.Lock()
+	c.grace.count++
+	c.grace.errorDist[err.Error()]++
+	c.grace.lock.Unlock()
+
+	return true
+}
+
+// retry returns whether the call was dropped and has to be retried. A nil call is never retried.
+func (c *graceCall) retry() bool {
+	if c == nil {
+		return false
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.retried
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGracePeriod(t *testing.T) {
+	t.Run("nil when disabled", func(t *testing.T) {
+		g := newGracePeriod(0)
+		assert.Nil(t, g)
+
+		g.begin(time.Now())
+		assert.False(t, g.active())
+	})
+
+	t.Run("active", func(t *testing.T) {
+		g := newGracePeriod(time.Minute)
+		g.begin(time.Now())
+		assert.True(t, g.active())
+
+		g.begin(time.Now().Add(-2 * time.Minute))
+		assert.False(t, g.active())
+	})
+
+	t.Run("drop unavailable", func(t *testing.T) {
+		g := newGracePeriod(time.Minute)
+
+		var gc *graceCall
+		assert.False(t, gc.retry())
+
+		gc = &graceCall{grace: g}
+		assert.False(t, gc.drop(nil))
+		assert.False(t, gc.drop(status.Error(codes.Internal, "internal")))
+		assert.False(t, gc.retry())
+
+		unavailable := status.Error(codes.Unavailable, "connection refused")
+		assert.True(t, gc.drop(unavailable))
+		assert.True(t, gc.retry())
+
+		assert.Equal(t, &GraceRetries{
+			Period:    time.Minute,
+			Count:     1,
+			ErrorDist: map[string]int{unavailable.Error(): 1},
+		}, g.stats())
+	})
+}
+
+func TestRunGracePeriod(t *testing.T) {
+	// reserve a port for the server started after the run
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	host := lis.Addr().String()
+	assert.NoError(t, lis.Close())
+
+	s := grpc.NewServer()
+	helloworld.RegisterGreeterServer(s, helloworld.NewGreeter())
+	defer s.Stop()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+
+		lis, err := net.Listen("tcp", host)
+		if err != nil {
+			return
+		}
+
+		_ = s.Serve(lis)
+	}()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		host,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithGracePeriod(5*time.Second),
+		WithTimeout(time.Duration(20*time.Second)),
+		WithDialTimeout(time.Duration(20*time.Second)),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+
+	assert.Equal(t, 20, int(report.Count))
+	assert.Empty(t, report.ErrorDist)
+	assert.Equal(t, map[string]int{"OK": 20}, report.StatusCodeDist)
+	assert.Equal(t, 5*time.Second, report.Options.GracePeriod)
+
+	assert.NotNil(t, report.GraceRetries)
+	assert.True(t, report.GraceRetries.Count > 0)
+	assert.NotEmpty(t, report.GraceRetries.ErrorDist)
+}
@@ -0,0 +1,543 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// The transports of the calls
+const (
+	// TransportGRPC makes the calls using gRPC over HTTP/2, which is the default
+	TransportGRPC = "grpc"
+
+	// TransportGRPCWeb makes the calls using the gRPC-Web protocol over HTTP/1.1,
+	// or HTTP/2 if the server negotiates it over TLS
+	TransportGRPCWeb = "grpc-web"
+)
+
+// the flags of the frames of the gRPC-Web protocol
+const (
+	webFrameCompressed = 0x01
+	webFrameTrailer    = 0x80
+)
+
+// webConn is a connection of the pool making the unary calls using the gRPC-Web protocol,
+// such as to load test Envoy or other gRPC-Web gateways. It implements the channel of the
+// dynamic stubs, and reports the calls and the HTTP connections to the stats handler of
+// the connection the same way as a gRPC connection does.
+type webConn struct {
+	base    string
+	client  *http.Client
+	handler *statsHandler
+
+	// the per-call credentials of the run, if any
+	creds credentials.PerRPCCredentials
+}
+
+// webBaseURL returns the base URL of the calls to the target, which may be a URL
+// with a path prefix, or an address using the scheme of the transport security
+func webBaseURL(target string, insecure bool) string {
+	if strings.Contains(target, "://") {
+		return strings.TrimRight(target, "/")
+	}
+
+	if insecure {
+		return "http://" + target
+	}
+
+	return "https://" + target
+}
+
+// newWebConn creates the gRPC-Web connection to the target, reporting to the stats handler
+func (b *Requester) newWebConn(target string, sh *statsHandler) *webConn {
+	dial := b.config.connDialer()
+	if dial == nil {
+		dial = contextDialer(nil, 0)
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, addr)
+			if err != nil || sh == nil {
+				return conn, err
+			}
+
+			return newWebTransportConn(conn, sh), nil
+		},
+		MaxIdleConnsPerHost: b.config.c,
+		IdleConnTimeout:     90 * time.Second,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: b.config.dialTimeout,
+	}
+
+	if !b.config.insecure {
+		transport.TLSClientConfig = b.config.tlsConfig.Clone()
+	}
+
+	return &webConn{
+		base:    webBaseURL(target, b.config.insecure),
+		client:  &http.Client{Transport: transport},
+		handler: sh,
+		creds:   b.config.callCreds,
+	}
+}
+
+// webTransportConn reports the lifetime of the HTTP connection to the stats handler
+type webTransportConn struct {
+	net.Conn
+
+	ctx     context.Context
+	handler *statsHandler
+	once    sync.Once
+}
+
+func newWebTransportConn(conn net.Conn, sh *statsHandler) *webTransportConn {
+	ctx := sh.TagConn(context.Background(), &stats.ConnTagInfo{RemoteAddr: conn.RemoteAddr(), LocalAddr: conn.LocalAddr()})
+	sh.HandleConn(ctx, &stats.ConnBegin{Client: true})
+
+	return &webTransportConn{Conn: conn, ctx: ctx, handler: sh}
+}
+
+func (c *webTransportConn) Close() error {
+	c.once.Do(func() {
+		c.handler.HandleConn(c.ctx, &stats.ConnEnd{Client: true})
+	})
+
+	return c.Conn.Close()
+}
+
+// close closes the idle HTTP connections
+func (c *webConn) close() {
+	c.client.CloseIdleConnections()
+}
+
+// webCallOptions are the call options which apply to the gRPC-Web calls
+type webCallOptions struct {
+	codec          encoding.Codec
+	contentSubtype string
+	compressor     string
+	maxRecv        int
+	maxSend        int
+	header         *metadata.MD
+	trailer        *metadata.MD
+}
+
+func newWebCallOptions(opts []grpc.CallOption) *webCallOptions {
+	o := &webCallOptions{contentSubtype: "proto", maxRecv: -1, maxSend: -1}
+
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.ForceCodecCallOption:
+			o.codec = opt.Codec
+		case grpc.ContentSubtypeCallOption:
+			o.contentSubtype = strings.ToLower(opt.ContentSubtype)
+		case grpc.CompressorCallOption:
+			o.compressor = opt.CompressorType
+		case grpc.MaxRecvMsgSizeCallOption:
+			o.maxRecv = opt.MaxRecvMsgSize
+		case grpc.MaxSendMsgSizeCallOption:
+			o.maxSend = opt.MaxSendMsgSize
+		case grpc.HeaderCallOption:
+			o.header = opt.HeaderAddr
+		case grpc.TrailerCallOption:
+			o.trailer = opt.TrailerAddr
+		}
+	}
+
+	return o
+}
+
+// Invoke makes the unary call using the gRPC-Web protocol
+func (c *webConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	if c.handler != nil {
+		ctx = c.handler.TagRPC(ctx, &stats.RPCTagInfo{FullMethodName: method})
+	}
+
+	begin := time.Now()
+	c.handleRPC(ctx, &stats.Begin{Client: true, BeginTime: begin})
+
+	trailer, err := c.invoke(ctx, method, args, reply, newWebCallOptions(opts))
+
+	c.handleRPC(ctx, &stats.End{Client: true, BeginTime: begin, EndTime: time.Now(), Trailer: trailer, Error: err})
+
+	return err
+}
+
+// NewStream implements the channel of the stubs, the streaming calls are not supported
+func (c *webConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streaming calls are not supported with gRPC-Web")
+}
+
+func (c *webConn) handleRPC(ctx context.Context, rs stats.RPCStats) {
+	if c.handler != nil {
+		c.handler.HandleRPC(ctx, rs)
+	}
+}
+
+func (c *webConn) invoke(ctx context.Context, method string, args, reply interface{}, o *webCallOptions) (metadata.MD, error) {
+	data, err := webMarshal(o.codec, args)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "grpc: error while marshaling: %v", err)
+	}
+
+	if o.maxSend >= 0 && len(data) > o.maxSend {
+		return nil, status.Errorf(codes.ResourceExhausted, "grpc: trying to send message larger than max (%d vs. %d)", len(data), o.maxSend)
+	}
+
+	payload := data
+	var flag byte
+	if o.compressor == "gzip" {
+		if payload, err = webCompress(data); err != nil {
+			return nil, status.Errorf(codes.Internal, "grpc: error while compressing: %v", err)
+		}
+
+		flag = webFrameCompressed
+	}
+
+	body := make([]byte, 5+len(payload))
+	body[0] = flag
+	binary.BigEndian.PutUint32(body[1:5], uint32(len(payload)))
+	copy(body[5:], payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if err := c.setHeaders(ctx, req, method, o); err != nil {
+		return nil, err
+	}
+
+	c.handleRPC(ctx, &stats.OutPayload{Client: true, Payload: args, Data: data, Length: len(data),
+		WireLength: len(body), SentTime: time.Now()})
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, webRequestError(ctx, err)
+	}
+
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+
+	header := webMetadata(res.Header)
+	if o.header != nil {
+		*o.header = header
+	}
+
+	// the status of trailers-only responses is sent in the headers
+	if st := res.Header.Get("grpc-status"); st != "" {
+		return header, webStatus(st, res.Header.Get("grpc-message"))
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, status.Errorf(webHTTPCode(res.StatusCode), "unexpected HTTP status code received from server: %d (%s)",
+			res.StatusCode, http.StatusText(res.StatusCode))
+	}
+
+	c.handleRPC(ctx, &stats.InHeader{Client: true, Header: header, FullMethod: method,
+		Compression: res.Header.Get("grpc-encoding")})
+
+	received := false
+
+	for {
+		flag, payload, err := readWebFrame(res.Body, o.maxRecv)
+		if err == io.EOF {
+			return nil, status.Error(codes.Internal, "server closed the stream without sending trailers")
+		}
+
+		if err != nil {
+			return nil, webRequestError(ctx, err)
+		}
+
+		wireLength := 5 + len(payload)
+
+		if flag&webFrameCompressed != 0 {
+			if payload, err = webDecompress(payload); err != nil {
+				return nil, status.Errorf(codes.Internal, "grpc: failed to decompress the received message: %v", err)
+			}
+		}
+
+		if flag&webFrameTrailer != 0 {
+			trailer, err := parseWebTrailer(payload)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "malformed trailers: %v", err)
+			}
+
+			c.handleRPC(ctx, &stats.InTrailer{Client: true, Trailer: trailer, WireLength: wireLength})
+
+			if o.trailer != nil {
+				*o.trailer = trailer
+			}
+
+			st := trailer.Get("grpc-status")
+			if len(st) == 0 {
+				return trailer, status.Error(codes.Internal, "server sent trailers without a status")
+			}
+
+			var msg string
+			if m := trailer.Get("grpc-message"); len(m) > 0 {
+				msg = m[0]
+			}
+
+			if err := webStatus(st[0], msg); err != nil {
+				return trailer, err
+			}
+
+			if !received {
+				return trailer, status.Error(codes.Internal, "grpc: server sent no response message")
+			}
+
+			return trailer, nil
+		}
+
+		if received {
+			return nil, status.Error(codes.Internal, "grpc: server sent more than one response message")
+		}
+
+		if err := webUnmarshal(o.codec, payload, reply); err != nil {
+			return nil, status.Errorf(codes.Internal, "grpc: failed to unmarshal the received message: %v", err)
+		}
+
+		received = true
+
+		c.handleRPC(ctx, &stats.InPayload{Client: true, Payload: reply, Data: payload, Length: len(payload),
+			WireLength: wireLength, RecvTime: time.Now()})
+	}
+}
+
+// setHeaders sets the headers of the call, being the metadata of the call and the per-call
+// credentials, with the values of the binary keys base64 encoded
+func (c *webConn) setHeaders(ctx context.Context, req *http.Request, method string, o *webCallOptions) error {
+	req.Header.Set("Content-Type", "application/grpc-web+"+o.contentSubtype)
+	req.Header.Set("Accept", "application/grpc-web+"+o.contentSubtype)
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("X-User-Agent", "grpc-web-ghz")
+
+	if o.compressor != "" {
+		req.Header.Set("Grpc-Encoding", o.compressor)
+		req.Header.Set("Grpc-Accept-Encoding", o.compressor)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+		}
+
+		req.Header.Set("Grpc-Timeout", strconv.FormatInt(int64(timeout/time.Millisecond)+1, 10)+"m")
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for k, vs := range md {
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				v = base64.StdEncoding.EncodeToString([]byte(v))
+			}
+
+			req.Header.Add(k, v)
+		}
+	}
+
+	if c.creds != nil {
+		credsMD, err := c.creds.GetRequestMetadata(ctx, c.base+method[:strings.LastIndex(method, "/")])
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, "transport: per-RPC creds failed due to error: %v", err)
+		}
+
+		for k, v := range credsMD {
+			req.Header.Set(k, v)
+		}
+	}
+
+	return nil
+}
+
+// readWebFrame reads a frame of the response, which is not larger than the maximum unless it is negative
+func readWebFrame(r io.Reader, max int) (byte, []byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, status.Error(codes.Internal, "grpc: truncated frame header")
+		}
+
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(hdr[1:])
+	if hdr[0]&webFrameTrailer == 0 && max >= 0 && int64(length) > int64(max) {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "grpc: received message larger than max (%d vs. %d)", length, max)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, status.Error(codes.Internal, "grpc: truncated frame")
+	}
+
+	return hdr[0], payload, nil
+}
+
+// parseWebTrailer parses the trailer frame of HTTP/1 header lines
+func parseWebTrailer(payload []byte) (metadata.MD, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(payload, '\r', '\n'))))
+
+	h, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return webMetadata(http.Header(h)), nil
+}
+
+// webMetadata returns the metadata of the HTTP headers, with lower case keys
+// and the values of the binary keys base64 decoded
+func webMetadata(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, vs := range h {
+		k = strings.ToLower(k)
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+					v = string(b)
+				} else if b, err := base64.RawStdEncoding.DecodeString(v); err == nil {
+					v = string(b)
+				}
+			}
+
+			md[k] = append(md[k], v)
+		}
+	}
+
+	return md
+}
+
+// webStatus returns the error of the status code and the percent-encoded message, nil if OK
+func webStatus(code, msg string) error {
+	c, err := strconv.ParseUint(strings.TrimSpace(code), 10, 32)
+	if err != nil {
+		return status.Errorf(codes.Internal, "malformed grpc-status: %q", code)
+	}
+
+	if codes.Code(c) == codes.OK {
+		return nil
+	}
+
+	if m, err := url.PathUnescape(msg); err == nil {
+		msg = m
+	}
+
+	return status.Error(codes.Code(c), msg)
+}
+
+// webHTTPCode returns the status code of an HTTP status code without a gRPC status
+func webHTTPCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// webRequestError returns the status error of a failed request
+func webRequestError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+	case context.Canceled:
+		return status.Error(codes.Canceled, ctx.Err().Error())
+	}
+
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+func webMarshal(codec encoding.Codec, v interface{}) ([]byte, error) {
+	if codec != nil {
+		return codec.Marshal(v)
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto message", v)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func webUnmarshal(codec encoding.Codec, data []byte, v interface{}) error {
+	if codec != nil {
+		return codec.Unmarshal(data, v)
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto message", v)
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+func webCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func webDecompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = zr.Close()
+	}()
+
+	return ioutil.ReadAll(zr)
+}
@@ -0,0 +1,312 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// testWebGreeter serves the SayHello calls using the gRPC-Web protocol, recording the headers
+type testWebGreeter struct {
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (g *testWebGreeter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	g.headers = append(g.headers, r.Header.Clone())
+	g.mu.Unlock()
+
+	if r.URL.Path != "/helloworld.Greeter/SayHello" {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	var req helloworld.HelloRequest
+	if err := proto.Unmarshal(body[5:], &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc-web+proto")
+
+	// the errors are sent as trailers-only responses
+	if req.Name == "invalid" {
+		w.Header().Set("Grpc-Status", "3")
+		w.Header().Set("Grpc-Message", "invalid%20name")
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	reply, _ := proto.Marshal(&helloworld.HelloReply{Message: "Hello " + req.Name})
+
+	var frames bytes.Buffer
+	writeTestWebFrame(&frames, 0, reply)
+	writeTestWebFrame(&frames, webFrameTrailer, []byte("grpc-status: 0\r\ngrpc-message: \r\n"))
+
+	_, _ = w.Write(frames.Bytes())
+}
+
+func writeTestWebFrame(w io.Writer, flag byte, payload []byte) {
+	var hdr [5]byte
+	hdr[0] = flag
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(payload)))
+
+	_, _ = w.Write(hdr[:])
+	_, _ = w.Write(payload)
+}
+
+// testConnectProxy is an HTTP proxy tunneling the connections using the CONNECT method
+type testConnectProxy struct {
+	auth    string
+	tunnels int32
+}
+
+func (p *testConnectProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if p.auth != "" && r.Header.Get("Proxy-Authorization") != p.auth {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+
+		return
+	}
+
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+
+		return
+	}
+
+	atomic.AddInt32(&p.tunnels, 1)
+
+	conn, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		_ = upstream.Close()
+
+		return
+	}
+
+	_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+	go func() {
+		_, _ = io.Copy(upstream, brw)
+		_ = upstream.Close()
+	}()
+
+	_, _ = io.Copy(conn, upstream)
+	_ = conn.Close()
+}
+
+func TestReadWebFrames(t *testing.T) {
+	var frames bytes.Buffer
+	writeTestWebFrame(&frames, 0, []byte("abc"))
+	writeTestWebFrame(&frames, webFrameTrailer, []byte("Grpc-Status: 5\r\ngrpc-message: not%20found\r\ntrace-bin: AQI="))
+
+	flag, payload, err := readWebFrame(&frames, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0), flag)
+	assert.Equal(t, "abc", string(payload))
+
+	flag, payload, err = readWebFrame(&frames, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(webFrameTrailer), flag)
+
+	trailer, err := parseWebTrailer(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.MD{"grpc-status": {"5"}, "grpc-message": {"not%20found"}, "trace-bin": {"\x01\x02"}}, trailer)
+	assert.EqualError(t, webStatus(trailer.Get("grpc-status")[0], trailer.Get("grpc-message")[0]),
+		"rpc error: code = NotFound desc = not found")
+
+	_, _, err = readWebFrame(&frames, 3)
+	assert.Equal(t, io.EOF, err)
+
+	writeTestWebFrame(&frames, 0, []byte("abcd"))
+	_, _, err = readWebFrame(&frames, 3)
+	assert.EqualError(t, err, "rpc error: code = ResourceExhausted desc = grpc: received message larger than max (4 vs. 3)")
+
+	assert.Equal(t, "https://api.example.com:443", webBaseURL("api.example.com:443", false))
+	assert.Equal(t, "http://localhost:8080", webBaseURL("localhost:8080", true))
+	assert.Equal(t, "http://localhost:8080/grpc", webBaseURL("http://localhost:8080/grpc/", false))
+	assert.Equal(t, codes.Unavailable, webHTTPCode(http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Unimplemented, webHTTPCode(http.StatusNotFound))
+}
+
+func TestRunGRPCWeb(t *testing.T) {
+	greeter := &testWebGreeter{}
+
+	ts := httptest.NewServer(greeter)
+	defer ts.Close()
+
+	t.Run("unary", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			strings.TrimPrefix(ts.URL, "http://"),
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTransport("grpc-web"),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithMetadata(map[string]string{"request-id": "{{.RequestNumber}}", "trace-bin": "AQI="}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, int(report.Count))
+		assert.Equal(t, map[string]int{"OK": 10}, report.StatusCodeDist)
+		assert.Empty(t, report.ErrorDist)
+		assert.Equal(t, "grpc-web", report.Options.Transport)
+
+		greeter.mu.Lock()
+		defer greeter.mu.Unlock()
+
+		assert.Len(t, greeter.headers, 10)
+		for _, h := range greeter.headers {
+			assert.Equal(t, "application/grpc-web+proto", h.Get("Content-Type"))
+			assert.Equal(t, "1", h.Get("X-Grpc-Web"))
+			assert.Equal(t, "AQI=", h.Get("Trace-Bin"))
+			assert.NotEmpty(t, h.Get("Request-Id"))
+			assert.NotEmpty(t, h.Get("Grpc-Timeout"))
+		}
+	})
+
+	t.Run("trailers-only error", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			ts.URL,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTransport("grpc-web"),
+			WithTotalRequests(4),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": "invalid"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, int(report.Count))
+		assert.Equal(t, map[string]int{"InvalidArgument": 4}, report.StatusCodeDist)
+		assert.Equal(t, map[string]int{"rpc error: code = InvalidArgument desc = invalid name": 4}, report.ErrorDist)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHelloCS",
+			ts.URL,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTransport("grpc-web"),
+			WithTotalRequests(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.Nil(t, report)
+		assert.EqualError(t, err, "gRPC-Web is only supported for unary calls")
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		proxy := &testConnectProxy{}
+
+		ps := httptest.NewServer(proxy)
+		defer ps.Close()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			ts.URL,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTransport("grpc-web"),
+			WithProxy(ps.URL),
+			WithTotalRequests(5),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"OK": 5}, report.StatusCodeDist)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&proxy.tunnels))
+	})
+}
+
+func TestRunProxy(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	proxy := &testConnectProxy{auth: "Basic Z2h6OnNlY3JldA=="}
+
+	ps := httptest.NewServer(proxy)
+	defer ps.Close()
+
+	proxyURL, _ := url.Parse(ps.URL)
+	proxyURL.User = url.UserPassword("ghz", "secret")
+
+	t.Run("connect", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithProxy(proxyURL.String()),
+			WithTotalRequests(6),
+			WithConcurrency(2),
+			WithConnections(2),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"OK": 6}, report.StatusCodeDist)
+		assert.Equal(t, 6, gs.GetCount(helloworld.Unary))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&proxy.tunnels))
+		assert.Equal(t, strings.Replace(proxyURL.String(), "secret", "xxxxx", 1), report.Options.Proxy)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithProxy(ps.URL),
+			WithConnectPolicy("fail", 0),
+			WithDialTimeout(time.Second),
+			WithTotalRequests(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.Nil(t, report)
+		assert.Error(t, err)
+	})
+}
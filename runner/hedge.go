@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/metadata"
+)
+
+// callHedgeKey is the context key of the attempts of hedged calls
+type callHedgeKey struct{}
+
+// HedgeStats holds the results of the hedging of a percentage of the unary calls, each sending
+// a duplicate request when no response is received within the delay and canceling the loser.
+// The calls not selected for hedging are the control group the hedged calls are compared with.
+type HedgeStats struct {
+	Percent float64       `json:"percent"`
+	Delay   time.Duration `json:"delay"`
+
+	// Calls is the number of calls selected for hedging, and Hedged of those the number
+	// which were hedged since no response was received within the delay
+	Calls  uint64 `json:"calls"`
+	Hedged uint64 `json:"hedged"`
+
+	// Wins is the number of hedged calls completed first by the hedge request,
+	// and WinRate the fraction of the hedged calls it is
+	Wins    uint64  `json:"wins"`
+	WinRate float64 `json:"winRate"`
+
+	// The latencies of the calls selected for hedging and of the calls of the control group
+	Average        time.Duration `json:"average"`
+	P99            time.Duration `json:"p99"`
+	ControlAverage time.Duration `json:"controlAverage"`
+	ControlP99     time.Duration `json:"controlP99"`
+
+	// Improvement is the decrease of the 99th percentile latency of the hedged calls from the
+	// control group, as a fraction of the control group latency. It's negative if it increased.
+	Improvement float64 `json:"improvement"`
+}
+
+// hedgeTracker selects the calls to hedge and gathers the latencies of the hedged calls
+// and of the calls of the control group
+type hedgeTracker struct {
+	percent float64
+	delay   time.Duration
+
+	lock     sync.Mutex
+	hedged   uint64
+	wins     uint64
+	selected []time.Duration
+	control  []time.Duration
+}
+
+func newHedgeTracker(percent float64, delay time.Duration) *hedgeTracker {
+	if percent <= 0 {
+		return nil
+	}
+
+	return &hedgeTracker{percent: percent, delay: delay}
+}
+
+// selectCall returns whether the next call should be hedged
+func (h *hedgeTracker) selectCall() bool {
+	return rand.Float64()*100 < h.percent
+}
+
+// record records the latency of a call, and whether it was selected, hedged and won by the hedge request
+func (h *hedgeTracker) record(latency time.Duration, selected, hedged, won bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !selected {
+		h.control = append(h.control, latency)
+		return
+	}
+
+	h.selected = append(h.selected, latency)
+
+	if hedged {
+		h.hedged++
+	}
+
+	if won {
+		h.wins++
+	}
+}
+
+func (h *hedgeTracker) stats() *HedgeStats {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	s := &HedgeStats{
+		Percent: h.percent,
+		Delay:   h.delay,
+		Calls:   uint64(len(h.selected)),
+		Hedged:  h.hedged,
+		Wins:    h.wins,
+	}
+
+	if h.hedged > 0 {
+		s.WinRate = float64(h.wins) / float64(h.hedged)
+	}
+
+	s.Average, s.P99 = hedgeLatencies(h.selected)
+	s.ControlAverage, s.ControlP99 = hedgeLatencies(h.control)
+
+	if s.ControlP99 > 0 && s.P99 > 0 {
+		s.Improvement = float64(s.ControlP99-s.P99) / float64(s.ControlP99)
+	}
+
+	return s
+}
+
+// hedgeLatencies returns the average and the 99th percentile of the latencies
+func hedgeLatencies(lats []time.Duration) (time.Duration, time.Duration) {
+	if len(lats) == 0 {
+		return 0, 0
+	}
+
+	sorted := append([]time.Duration(nil), lats...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, l := range sorted {
+		sum += l
+	}
+
+	return sum / time.Duration(len(sorted)), sorted[(len(sorted)*99-1)/100]
+}
+
+// hedgeCall is a hedged call, whose first completed attempt is its result
+type hedgeCall struct {
+	start time.Time
+
+	lock sync.Mutex
+	done bool
+}
+
+// hedgeAttempt is one of the requests of a hedged call
+type hedgeAttempt struct {
+	call  *hedgeCall
+	hedge bool
+	won   bool
+}
+
+// complete returns whether the attempt is the first completed attempt of the call,
+// whose result is the result of the call. The results of the other attempts are dropped.
+func (a *hedgeAttempt) complete() bool {
+	a.call.lock.Lock()
+	defer a.call.lock.Unlock()
+
+	if a.call.done {
+		return false
+	}
+
+	a.call.done = true
+	a.won = true
+
+	return true
+}
+
+// makeHedgedUnaryRequest makes the unary call, hedging it if it's selected for hedging:
+// if no response is received within the delay a duplicate request is sent, the first
+// completed request is the result of the call and the other request is canceled
+func (w *Worker) makeHedgedUnaryRequest(ctx *context.Context, reqMD *metadata.MD, input *dynamic.Message) (proto.Message, error) {
+	start := time.Now()
+
+	if !w.hedge.selectCall() {
+		res, err := w.makeUnaryRequest(ctx, reqMD, input)
+		w.hedge.record(time.Since(start), false, false, false)
+
+		return res, err
+	}
+
+	type attemptResult struct {
+		attempt *hedgeAttempt
+		res     proto.Message
+		err     error
+	}
+
+	call := &hedgeCall{start: start}
+	results := make(chan attemptResult, 2)
+
+	send := func(hedge bool) context.CancelFunc {
+		attempt := &hedgeAttempt{call: call, hedge: hedge}
+		attemptCtx, cancel := context.WithCancel(context.WithValue(*ctx, callHedgeKey{}, attempt))
+
+		go func() {
+			res, err := w.makeUnaryRequest(&attemptCtx, reqMD, input)
+			results <- attemptResult{attempt, res, err}
+		}()
+
+		return cancel
+	}
+
+	cancels := []context.CancelFunc{send(false)}
+
+	timer := time.NewTimer(w.hedge.delay)
+	defer timer.Stop()
+
+	var first attemptResult
+	select {
+	case first = <-results:
+	case <-timer.C:
+		cancels = append(cancels, send(true))
+		first = <-results
+	}
+
+	// the loser is canceled, and its result awaited so the call does not outlive the worker request
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	winner := first
+	if len(cancels) > 1 {
+		if second := <-results; second.attempt.won {
+			winner = second
+		}
+	}
+
+	w.hedge.record(time.Since(start), true, len(cancels) > 1, winner.attempt.hedge)
+
+	return winner.res, winner.err
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgeTracker_stats(t *testing.T) {
+	h := newHedgeTracker(50, 10*time.Millisecond)
+
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i)*time.Millisecond, false, false, false)
+	}
+
+	h.record(10*time.Millisecond, true, false, false)
+	h.record(20*time.Millisecond, true, true, true)
+	h.record(30*time.Millisecond, true, true, false)
+	h.record(40*time.Millisecond, true, true, true)
+
+	stats := h.stats()
+
+	assert.Equal(t, 50.0, stats.Percent)
+	assert.Equal(t, 10*time.Millisecond, stats.Delay)
+	assert.Equal(t, uint64(4), stats.Calls)
+	assert.Equal(t, uint64(3), stats.Hedged)
+	assert.Equal(t, uint64(2), stats.Wins)
+	assert.InDelta(t, 2.0/3, stats.WinRate, 0.001)
+	assert.Equal(t, 25*time.Millisecond, stats.Average)
+	assert.Equal(t, 40*time.Millisecond, stats.P99)
+	assert.Equal(t, 50500*time.Microsecond, stats.ControlAverage)
+	assert.Equal(t, 99*time.Millisecond, stats.ControlP99)
+	assert.InDelta(t, 59.0/99, stats.Improvement, 0.001)
+
+	assert.Nil(t, newHedgeTracker(0, time.Millisecond))
+}
+
+func TestHedgeAttempt_complete(t *testing.T) {
+	call := &hedgeCall{start: time.Now()}
+	primary := &hedgeAttempt{call: call}
+	hedge := &hedgeAttempt{call: call, hedge: true}
+
+	assert.True(t, hedge.complete())
+	assert.False(t, primary.complete())
+	assert.True(t, hedge.won)
+	assert.False(t, primary.won)
+}
+
+func TestRunHedging(t *testing.T) {
+	_, s, err := internal.StartSleepServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	report, err := Run(
+		"main.SleepService.SleepFor",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/sleep.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"Milliseconds": "50"}),
+		WithHedging(100, 10*time.Millisecond),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+
+	// the canceled requests are not counted
+	assert.Equal(t, map[string]int{"OK": 10}, report.StatusCodeDist)
+	assert.Equal(t, 100.0, report.Options.HedgePercent)
+
+	if assert.NotNil(t, report.Hedging) {
+		assert.Equal(t, uint64(10), report.Hedging.Calls)
+		assert.Equal(t, uint64(10), report.Hedging.Hedged)
+		assert.True(t, report.Hedging.Average >= 50*time.Millisecond)
+	}
+
+	for _, d := range report.Details {
+		assert.True(t, d.Latency >= 50*time.Millisecond, d.Latency.String())
+	}
+
+	_, err = Run(
+		"main.SleepService.SleepFor",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/sleep.proto", []string{}),
+		WithHedging(10, 0),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "hedging delay must be greater than 0")
+}
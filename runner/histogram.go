@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHistogramBuckets is the maximum number of histogram buckets
+const maxHistogramBuckets = 1000
+
+// parseHistogramBuckets parses the latency histogram bucket boundaries. The boundaries are
+// either a comma separated list of ascending durations, or exponential or linear buckets
+// in the form of exp:<start>,<factor>,<count> or linear:<start>,<width>,<count>,
+// same as the buckets of Prometheus histograms.
+func parseHistogramBuckets(s string) ([]time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	var kind string
+	if i := strings.Index(s, ":"); i >= 0 {
+		kind = strings.ToLower(strings.TrimSpace(s[:i]))
+		s = s[i+1:]
+	}
+
+	parts := strings.Split(s, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	var buckets []time.Duration
+
+	switch kind {
+	case "":
+		for _, p := range parts {
+			d, err := time.ParseDuration(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid histogram bucket %q: %v", p, err)
+			}
+
+			buckets = append(buckets, d)
+		}
+	case "exp", "linear":
+		param := "factor"
+		if kind == "linear" {
+			param = "width"
+		}
+
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid %s histogram buckets %q: expected %s:<start>,<%s>,<count>", kind, s, kind, param)
+		}
+
+		start, err := time.ParseDuration(parts[0])
+		if err != nil || start <= 0 {
+			return nil, fmt.Errorf("invalid histogram bucket start %q", parts[0])
+		}
+
+		count, err := strconv.Atoi(parts[2])
+		if err != nil || count < 1 || count > maxHistogramBuckets {
+			return nil, fmt.Errorf("invalid histogram bucket count %q: must be between 1 and %d", parts[2], maxHistogramBuckets)
+		}
+
+		if kind == "exp" {
+			factor, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || factor <= 1 {
+				return nil, fmt.Errorf("invalid histogram bucket factor %q: must be greater than 1", parts[1])
+			}
+
+			for i := 0; i < count; i++ {
+				buckets = append(buckets, time.Duration(float64(start)*math.Pow(factor, float64(i))))
+			}
+		} else {
+			width, err := time.ParseDuration(parts[1])
+			if err != nil || width <= 0 {
+				return nil, fmt.Errorf("invalid histogram bucket width %q", parts[1])
+			}
+
+			for i := 0; i < count; i++ {
+				buckets = append(buckets, start+time.Duration(i)*width)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown histogram buckets %q: expected a list of durations, exp or linear", kind)
+	}
+
+	if err := checkHistogramBuckets(buckets); err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// checkHistogramBuckets checks the number and the order of the histogram bucket boundaries
+func checkHistogramBuckets(buckets []time.Duration) error {
+	if len(buckets) > maxHistogramBuckets {
+		return fmt.Errorf("too many histogram buckets: %d, maximum is %d", len(buckets), maxHistogramBuckets)
+	}
+
+	for i, b := range buckets {
+		if b <= 0 || (i > 0 && b <= buckets[i-1]) {
+			return fmt.Errorf("histogram buckets must be positive and in ascending order: %v", b)
+		}
+	}
+
+	return nil
+}
+
+// bucketHistogram returns the histogram of the sorted latencies using the bucket boundaries.
+// Each bucket counts the latencies up to its mark that are above the previous mark, and the
+// latencies above the last boundary are counted in a bucket marked with the slowest latency.
+func bucketHistogram(latencies []float64, boundaries []time.Duration) []Bucket {
+	res := make([]Bucket, 0, len(boundaries)+1)
+	for _, b := range boundaries {
+		res = append(res, Bucket{Mark: b.Seconds()})
+	}
+
+	if slowest := latencies[len(latencies)-1]; slowest > res[len(res)-1].Mark {
+		res = append(res, Bucket{Mark: slowest})
+	}
+
+	bi := 0
+	for _, l := range latencies {
+		for l > res[bi].Mark {
+			bi++
+		}
+
+		res[bi].Count++
+	}
+
+	for i := range res {
+		res[i].Frequency = float64(res[i].Count) / float64(len(latencies))
+	}
+
+	return res
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHistogramBuckets(t *testing.T) {
+	ms := time.Millisecond
+
+	var tests = []struct {
+		in       string
+		expected []time.Duration
+	}{
+		{"5ms,10ms,25ms", []time.Duration{5 * ms, 10 * ms, 25 * ms}},
+		{" 500us , 1s ", []time.Duration{500 * time.Microsecond, time.Second}},
+		{"exp:1ms,2,4", []time.Duration{ms, 2 * ms, 4 * ms, 8 * ms}},
+		{"EXP: 10ms, 1.5, 3", []time.Duration{10 * ms, 15 * ms, 22500 * time.Microsecond}},
+		{"linear:10ms,5ms,3", []time.Duration{10 * ms, 15 * ms, 20 * ms}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			actual, err := parseHistogramBuckets(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	for _, in := range []string{"", "5ms,foo", "10ms,5ms", "5ms,5ms", "0s,1ms", "exp:1ms,2", "exp:1ms,1,5", "exp:0s,2,5",
+		"exp:1ms,2,0", "exp:1ms,2,1001", "linear:1ms,0s,5", "log:1ms,2,5"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := parseHistogramBuckets(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReport_bucketHistogram(t *testing.T) {
+	lats := []float64{0.001, 0.004, 0.005, 0.006, 0.012, 0.03}
+
+	actual := bucketHistogram(lats, []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond})
+	assert.Equal(t, []Bucket{
+		{Mark: 0.005, Count: 3, Frequency: 0.5},
+		{Mark: 0.01, Count: 1, Frequency: 1.0 / 6},
+		{Mark: 0.02, Count: 1, Frequency: 1.0 / 6},
+		{Mark: 0.03, Count: 1, Frequency: 1.0 / 6},
+	}, actual)
+
+	// no overflow bucket when all the latencies are within the boundaries
+	actual = bucketHistogram(lats, []time.Duration{10 * time.Millisecond, 50 * time.Millisecond})
+	assert.Equal(t, []Bucket{
+		{Mark: 0.01, Count: 4, Frequency: 4.0 / 6},
+		{Mark: 0.05, Count: 2, Frequency: 2.0 / 6},
+	}, actual)
+}
+
+func TestWithHistogramBoundaries(t *testing.T) {
+	ms := time.Millisecond
+
+	c := &RunConfig{}
+	assert.NoError(t, WithHistogramBoundaries([]time.Duration{5 * ms, 10 * ms})(c))
+	assert.Equal(t, []time.Duration{5 * ms, 10 * ms}, c.histogramBuckets)
+
+	assert.NoError(t, WithHistogramBoundaries(nil)(c))
+	assert.Nil(t, c.histogramBuckets)
+
+	err := WithHistogramBoundaries([]time.Duration{10 * ms, 5 * ms})(c)
+	assert.EqualError(t, err, "histogram buckets must be positive and in ascending order: 5ms")
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/status"
+)
+
+// CallResult is the result of a call passed to the after call hook
+type CallResult struct {
+	// CallData is the call data of the call
+	CallData *CallData
+
+	// Start is the time the call started, and Latency is the time it took
+	Start   time.Time
+	Latency time.Duration
+
+	// Request is the request message of unary and server streaming calls, and Response is the
+	// response message of unary and client streaming calls, nil for the other calls or if none
+	// was received
+	Request  proto.Message
+	Response proto.Message
+
+	// Status is the status of the call, and Err is the error of the call, nil if it succeeded
+	Status *status.Status
+	Err    error
+}
+
+// afterCall calls the after call hook, if any, with the result of the call
+func (w *Worker) afterCall(ctd *CallData, start time.Time, latency time.Duration, req, res proto.Message, callErr error) {
+	if w.config.afterCall == nil {
+		return
+	}
+
+	w.config.afterCall(&CallResult{
+		CallData: ctd,
+		Start:    start,
+		Latency:  latency,
+		Request:  req,
+		Response: res,
+		Status:   status.Convert(callErr),
+		Err:      callErr,
+	})
+}
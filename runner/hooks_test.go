@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRunHooks(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	var lock sync.Mutex
+	var intercepted, results []string
+	var streams int
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithTotalRequests(6),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithBeforeCall(func(ctx context.Context, cd *CallData) context.Context {
+			return metadata.AppendToOutgoingContext(ctx, "x-request-id", cd.WorkerID)
+		}),
+		WithUnaryInterceptors(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+			invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+
+			lock.Lock()
+			intercepted = append(intercepted, method+" "+md.Get("x-request-id")[0])
+			lock.Unlock()
+
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		WithStreamInterceptors(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+			streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			lock.Lock()
+			streams++
+			lock.Unlock()
+
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+		WithAfterCall(func(r *CallResult) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			assert.Equal(t, codes.OK, r.Status.Code())
+			assert.NoError(t, r.Err)
+			assert.NotNil(t, r.Request)
+			assert.NotNil(t, r.Response)
+			assert.True(t, r.Latency > 0)
+
+			results = append(results, r.CallData.WorkerID)
+		}),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), report.Count)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Len(t, intercepted, 6)
+	for _, call := range intercepted {
+		assert.Regexp(t, `^/helloworld.Greeter/SayHello g\dc\d$`, call)
+	}
+
+	assert.Len(t, results, 6)
+
+	// the method is resolved using the reflection stream, which is intercepted as well
+	assert.True(t, streams > 0)
+}
+
+func TestRunHooks_grpcWeb(t *testing.T) {
+	_, err := NewConfig(
+		"helloworld.Greeter.SayHello",
+		"localhost:50050",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTransport(TransportGRPCWeb),
+		WithDialOptions(grpc.WithUserAgent("test")),
+	)
+
+	assert.EqualError(t, err, "gRPC-Web cannot be used with dial options or interceptors")
+}
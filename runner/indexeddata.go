@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// The selections of the records of the indexed data for each call
+const (
+	SelectRoundRobin = "round-robin"
+	SelectRandom     = "random"
+)
+
+// indexedDataProvider reads the data of each call from a file whose records are indexed
+// when the run starts rather than loaded, so that files with millions of records can be used.
+// The records are either JSON objects, one per line, or binary messages each prefixed with
+// its length as a varint, as written by the delimited writers of the protobuf libraries.
+type indexedDataProvider struct {
+	mtd          *desc.MethodDescriptor
+	binary       bool
+	random       bool
+	emitDefaults bool
+
+	file    *os.File
+	offsets []int64
+	lengths []int32
+}
+
+func newIndexedDataProvider(mtd *desc.MethodDescriptor, path string, binary bool, selection string) (*indexedDataProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dp := &indexedDataProvider{mtd: mtd, binary: binary, random: selection == SelectRandom, file: file}
+
+	if binary {
+		err = dp.indexMessages()
+	} else {
+		err = dp.indexLines()
+	}
+
+	if err == nil && len(dp.offsets) == 0 {
+		err = errors.New("no records")
+	}
+
+	if err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("error indexing data file %s: %v", path, err)
+	}
+
+	return dp, nil
+}
+
+// indexLines indexes the lines of the file which are not blank
+func (dp *indexedDataProvider) indexLines() error {
+	r := bufio.NewReader(dp.file)
+
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if len(strings.TrimSpace(string(line))) > 0 {
+			dp.offsets = append(dp.offsets, offset)
+			dp.lengths = append(dp.lengths, int32(len(line)))
+		}
+
+		offset += int64(len(line))
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// indexMessages indexes the length-delimited messages of the file
+func (dp *indexedDataProvider) indexMessages() error {
+	r := bufio.NewReader(dp.file)
+
+	var offset int64
+	for {
+		length, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("message %d: %v", len(dp.offsets)+1, err)
+		}
+
+		offset += int64(proto.SizeVarint(length))
+
+		if _, err := r.Discard(int(length)); err != nil {
+			return fmt.Errorf("message %d: %v", len(dp.offsets)+1, io.ErrUnexpectedEOF)
+		}
+
+		dp.offsets = append(dp.offsets, offset)
+		dp.lengths = append(dp.lengths, int32(length))
+
+		offset += int64(length)
+	}
+}
+
+func (dp *indexedDataProvider) getDataForCall(ctd *CallData) ([]*dynamic.Message, error) {
+	i := int(ctd.RequestNumber % int64(len(dp.offsets)))
+	if dp.random {
+		i = rand.Intn(len(dp.offsets))
+	}
+
+	// reading at an offset is safe for concurrent use
+	data := make([]byte, dp.lengths[i])
+	if _, err := dp.file.ReadAt(data, dp.offsets[i]); err != nil {
+		return nil, fmt.Errorf("data record %d: %v", i+1, err)
+	}
+
+	var inputs []*dynamic.Message
+	if dp.binary {
+		msg := dynamic.NewMessage(dp.mtd.GetInputType())
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return nil, fmt.Errorf("data record %d: %v", i+1, err)
+		}
+
+		inputs = []*dynamic.Message{msg}
+	} else {
+		input, err := ctd.ExecuteData(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("data record %d: %v", i+1, err)
+		}
+
+		if inputs, err = createPayloadsFromJSON(string(input), dp.mtd); err != nil {
+			return nil, fmt.Errorf("data record %d: %v", i+1, err)
+		}
+	}
+
+	if dp.emitDefaults {
+		emitDefaults(inputs)
+	}
+
+	return inputs, nil
+}
+
+// close closes the data file once the run is done
+func (dp *indexedDataProvider) close() {
+	if dp != nil {
+		_ = dp.file.Close()
+	}
+}
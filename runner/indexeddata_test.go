@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDelimited(t *testing.T, path string, names ...string) {
+	var buf []byte
+	for _, name := range names {
+		b, err := proto.Marshal(&helloworld.HelloRequest{Name: name})
+		assert.NoError(t, err)
+
+		buf = append(buf, proto.EncodeVarint(uint64(len(b)))...)
+		buf = append(buf, b...)
+	}
+
+	assert.NoError(t, ioutil.WriteFile(path, buf, 0644))
+}
+
+func TestIndexedDataProvider(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "ghz-indexed")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("json lines round robin", func(t *testing.T) {
+		path := filepath.Join(dir, "data.ndjson")
+		assert.NoError(t, ioutil.WriteFile(path, []byte("{\"name\":\"a\"}\n\n  \n{\"name\":\"{{.RequestNumber}}\"}\n{\"name\":\"c\"}"), 0644))
+
+		dp, err := newIndexedDataProvider(mtd, path, false, SelectRoundRobin)
+		assert.NoError(t, err)
+		defer dp.close()
+
+		assert.Len(t, dp.offsets, 3)
+
+		for i, expected := range []string{"a", "1", "c", "a", "4"} {
+			inputs, err := dp.getDataForCall(newCallData(mtd, nil, "", int64(i)))
+			assert.NoError(t, err)
+			assert.Len(t, inputs, 1)
+			assert.Equal(t, expected, inputs[0].GetFieldByName("name"))
+		}
+	})
+
+	t.Run("binary random", func(t *testing.T) {
+		path := filepath.Join(dir, "data.bin")
+		writeDelimited(t, path, "a", "", string(make([]byte, 200)), "d")
+
+		dp, err := newIndexedDataProvider(mtd, path, true, SelectRandom)
+		assert.NoError(t, err)
+		defer dp.close()
+
+		assert.Len(t, dp.offsets, 4)
+
+		seen := make(map[string]bool)
+		for i := 0; i < 200; i++ {
+			inputs, err := dp.getDataForCall(newCallData(mtd, nil, "", 0))
+			assert.NoError(t, err)
+			assert.Len(t, inputs, 1)
+
+			seen[inputs[0].GetFieldByName("name").(string)] = true
+		}
+
+		assert.Len(t, seen, 4)
+		assert.True(t, seen["d"])
+	})
+
+	t.Run("truncated binary", func(t *testing.T) {
+		path := filepath.Join(dir, "truncated.bin")
+		assert.NoError(t, ioutil.WriteFile(path, []byte{10, 1, 2}, 0644))
+
+		_, err := newIndexedDataProvider(mtd, path, true, SelectRoundRobin)
+		assert.EqualError(t, err, "error indexing data file "+path+": message 1: unexpected EOF")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.ndjson")
+		assert.NoError(t, ioutil.WriteFile(path, []byte("\n\n"), 0644))
+
+		_, err := newIndexedDataProvider(mtd, path, false, SelectRoundRobin)
+		assert.EqualError(t, err, "error indexing data file "+path+": no records")
+	})
+}
+
+func TestRunIndexedData(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	dir, err := ioutil.TempDir("", "ghz-indexed")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.bin")
+	writeDelimited(t, path, "a", "b", "c")
+
+	gs.ResetCounters()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithIndexedBinaryDataFromFile(path),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+	assert.Equal(t, 10, gs.GetCount(helloworld.Unary))
+
+	names := make(map[string]int)
+	for _, c := range gs.GetCalls(helloworld.Unary) {
+		names[c[0].GetName()]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 4, "b": 3, "c": 3}, names)
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithIndexedDataFromFile(path),
+		WithDataSelection("sequential"),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, `unknown data selection "sequential": expected round-robin or random`)
+}
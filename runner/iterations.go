@@ -0,0 +1,237 @@
+package runner
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IterationStats holds the statistics of the iterations of the scenario paced by the scenario rate.
+// Each tick of the pacer starts an iteration, which makes a pass of the calls of the scenario, so the
+// rate is that of the iterations however many calls they make and however long the calls take.
+type IterationStats struct {
+	// Rate is the target rate of the iterations per second
+	Rate uint `json:"rate"`
+
+	// Calls is the number of calls of an iteration
+	Calls int `json:"calls"`
+
+	// Iterations is the number of iterations, and Rps the rate of the iterations over the run
+	Iterations uint64  `json:"iterations"`
+	Rps        float64 `json:"rps"`
+
+	// Completed is the number of iterations whose calls all succeeded
+	Completed uint64 `json:"completed"`
+
+	// Failed is the number of iterations with a failed call. The iterations of a sequential
+	// scenario stop at the failed call.
+	Failed uint64 `json:"failed"`
+
+	// The latency of the iterations, from the start of the first call to the end of the last one
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+
+	// Waterfall holds the average timings of each step of the iterations, in the order of the pass
+	Waterfall []IterationStep `json:"waterfall,omitempty"`
+}
+
+// IterationStep holds the average timings of a step of the iterations, being a call of the pass of
+// the scenario, so the slow step of a user journey can be told apart
+type IterationStep struct {
+	// Step is the position of the call within the pass, from 1, and Method the name of the call
+	Step   int    `json:"step"`
+	Method string `json:"method"`
+
+	// Count is the number of iterations which made the call
+	Count uint64 `json:"count"`
+
+	// Offset is the average time from the start of the iteration to the start of the call
+	Offset time.Duration `json:"offset"`
+
+	// Gap is the average time from the end of the previous call of the iteration, or its start,
+	// to the start of the call, spent in the client between the steps
+	Gap time.Duration `json:"gap"`
+
+	// Average and Slowest are the latencies of the call
+	Average time.Duration `json:"average"`
+	Slowest time.Duration `json:"slowest"`
+
+	// Share is the percentage of the average latency of the iterations spent in the call
+	Share float64 `json:"share"`
+}
+
+// iterationCall is the timing of a call of an iteration, relative to the start of the iteration
+type iterationCall struct {
+	pos     int
+	offset  time.Duration
+	latency time.Duration
+}
+
+// stepTimings are the sums of the timings of a step of the iterations
+type stepTimings struct {
+	count   uint64
+	offset  time.Duration
+	gap     time.Duration
+	latency time.Duration
+	slowest time.Duration
+}
+
+// iterationTracker gathers the iterations of the scenario
+type iterationTracker struct {
+	rate  uint
+	calls int
+
+	// the names of the calls of the pass, in order
+	names []string
+
+	lock      sync.Mutex
+	completed uint64
+	failed    uint64
+	latencies []float64
+	steps     []stepTimings
+}
+
+func newIterationTracker(c *RunConfig, s *scenario) *iterationTracker {
+	if c.scenarioRate == 0 || s == nil {
+		return nil
+	}
+
+	names := make([]string, len(s.order))
+	for i, call := range s.order {
+		names[i] = s.calls[call].name
+	}
+
+	return &iterationTracker{rate: c.scenarioRate, calls: len(s.order), names: names, steps: make([]stepTimings, len(s.order))}
+}
+
+// record records an iteration with its latency and the timings of its calls
+func (t *iterationTracker) record(latency time.Duration, failed bool, calls []iterationCall) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if failed {
+		t.failed++
+	} else {
+		t.completed++
+	}
+
+	if len(t.latencies) < maxResult {
+		t.latencies = append(t.latencies, latency.Seconds())
+	}
+
+	var prevEnd time.Duration
+	for _, c := range calls {
+		if c.pos < 0 || c.pos >= len(t.steps) {
+			continue
+		}
+
+		st := &t.steps[c.pos]
+		st.count++
+		st.offset += c.offset
+		st.latency += c.latency
+
+		if gap := c.offset - prevEnd; gap > 0 {
+			st.gap += gap
+		}
+
+		if c.latency > st.slowest {
+			st.slowest = c.latency
+		}
+
+		prevEnd = c.offset + c.latency
+	}
+}
+
+func (t *iterationTracker) stats(total time.Duration) *IterationStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := &IterationStats{
+		Rate:       t.rate,
+		Calls:      t.calls,
+		Iterations: t.completed + t.failed,
+		Completed:  t.completed,
+		Failed:     t.failed,
+	}
+
+	if total > 0 {
+		s.Rps = float64(s.Iterations) / total.Seconds()
+	}
+
+	if len(t.latencies) == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), t.latencies...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, l := range sorted {
+		sum += l
+	}
+
+	s.Average = time.Duration(sum / float64(len(sorted)) * float64(time.Second))
+	s.Fastest = time.Duration(sorted[0] * float64(time.Second))
+	s.Slowest = time.Duration(sorted[len(sorted)-1] * float64(time.Second))
+	s.LatencyDistribution = latencies(sorted)
+
+	for i, st := range t.steps {
+		if st.count == 0 {
+			continue
+		}
+
+		n := time.Duration(st.count)
+		step := IterationStep{
+			Step:    i + 1,
+			Method:  t.names[i],
+			Count:   st.count,
+			Offset:  st.offset / n,
+			Gap:     st.gap / n,
+			Average: st.latency / n,
+			Slowest: st.slowest,
+		}
+
+		if s.Average > 0 {
+			step.Share = math.Round(10000*float64(step.Average)/float64(s.Average)) / 100
+		}
+
+		s.Waterfall = append(s.Waterfall, step)
+	}
+
+	return s
+}
+
+// makeIteration makes a pass of the calls of the scenario for the tick. Only the first call is
+// paced, the following calls are made as soon as the previous one completes. The pass stops at
+// a failed call of a sequential scenario, since the following calls depend on it.
+func (w *Worker) makeIteration(tv TickValue) error {
+	start := time.Now()
+	n := len(w.scenario.order)
+
+	w.chain = scenarioChain{started: start}
+
+	var err error
+	for i := 0; i < n; i++ {
+		if err = w.makeRequest(tv); err != nil {
+			break
+		}
+
+		tv.intended = time.Time{}
+
+		// the chain of a sequential scenario restarts from the first call after a failed call
+		if w.chain.failed || w.chain.pos != (i+1)%n {
+			w.chain.failed = true
+
+			if w.scenario.sequential {
+				break
+			}
+		}
+	}
+
+	w.iterations.record(time.Since(start), err != nil || w.chain.failed, w.chain.calls)
+
+	return err
+}
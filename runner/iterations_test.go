@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterationTracker(t *testing.T) {
+	assert.Nil(t, newIterationTracker(&RunConfig{}, &scenario{}))
+
+	sc := &scenario{calls: []*scenarioCall{{name: "create"}, {name: "send"}}, order: []int{0, 1, 1}}
+	tr := newIterationTracker(&RunConfig{scenarioRate: 10}, sc)
+
+	s := tr.stats(time.Second)
+	assert.Equal(t, &IterationStats{Rate: 10, Calls: 3}, s)
+
+	ms := time.Millisecond
+	tr.record(30*ms, false, []iterationCall{{0, ms, 9 * ms}, {1, 12 * ms, 6 * ms}, {2, 20 * ms, 10 * ms}})
+	tr.record(10*ms, false, []iterationCall{{0, ms, 3 * ms}, {1, 4 * ms, 2 * ms}, {2, 6 * ms, 4 * ms}})
+	tr.record(20*ms, true, []iterationCall{{0, ms, 19 * ms}})
+
+	s = tr.stats(2 * time.Second)
+	assert.Equal(t, uint64(3), s.Iterations)
+	assert.Equal(t, uint64(2), s.Completed)
+	assert.Equal(t, uint64(1), s.Failed)
+	assert.Equal(t, 1.5, s.Rps)
+	assert.Equal(t, 20*time.Millisecond, s.Average)
+	assert.Equal(t, 10*time.Millisecond, s.Fastest)
+	assert.Equal(t, 30*time.Millisecond, s.Slowest)
+	assert.NotEmpty(t, s.LatencyDistribution)
+
+	assert.Equal(t, []IterationStep{
+		{Step: 1, Method: "create", Count: 3, Offset: ms, Gap: ms, Average: 31 * ms / 3, Slowest: 19 * ms, Share: 51.67},
+		{Step: 2, Method: "send", Count: 2, Offset: 8 * ms, Gap: ms, Average: 4 * ms, Slowest: 6 * ms, Share: 20},
+		{Step: 3, Method: "send", Count: 2, Offset: 13 * ms, Gap: ms, Average: 7 * ms, Slowest: 10 * ms, Share: 35},
+	}, s.Waterfall)
+}
+
+func TestRunScenarioRate(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	calls := []ScenarioCall{
+		{Name: "create", Call: "helloworld.Greeter.SayHello", Data: map[string]interface{}{"name": "alice"},
+			Extract: map[string]string{"greeting": "message"}},
+		{Name: "send", Call: "helloworld.Greeter.SayHello", Weight: 2, Data: map[string]interface{}{"name": "{{.Vars.greeting}}!"}},
+	}
+
+	t.Run("iterations per second", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithScenario(calls...),
+			WithScenarioMode(ScenarioSequential),
+			WithScenarioRate(20),
+			WithInsecure(true),
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// each of the 10 iterations makes the 3 calls of the scenario
+		assert.Equal(t, uint64(30), report.Count)
+		assert.Equal(t, 30, gs.GetCount(helloworld.Unary))
+		assert.Equal(t, uint(20), report.Options.ScenarioRate)
+
+		// the iterations are paced, not the calls
+		assert.True(t, report.Total >= 400*time.Millisecond, "total %v", report.Total)
+
+		if assert.NotNil(t, report.Iterations) {
+			assert.Equal(t, uint(20), report.Iterations.Rate)
+			assert.Equal(t, 3, report.Iterations.Calls)
+			assert.Equal(t, uint64(10), report.Iterations.Iterations)
+			assert.Equal(t, uint64(10), report.Iterations.Completed)
+			assert.Zero(t, report.Iterations.Failed)
+			assert.NotZero(t, report.Iterations.Average)
+
+			if assert.Len(t, report.Iterations.Waterfall, 3) {
+				for i, step := range report.Iterations.Waterfall {
+					assert.Equal(t, i+1, step.Step)
+					assert.Equal(t, uint64(10), step.Count)
+					assert.NotZero(t, step.Average)
+				}
+
+				assert.Equal(t, "create", report.Iterations.Waterfall[0].Method)
+				assert.Equal(t, "send", report.Iterations.Waterfall[2].Method)
+				assert.True(t, report.Iterations.Waterfall[2].Offset > report.Iterations.Waterfall[1].Offset)
+			}
+		}
+
+		var names []string
+		for _, c := range gs.GetCalls(helloworld.Unary)[:3] {
+			names = append(names, c[0].GetName())
+		}
+
+		assert.Equal(t, []string{"alice", "Hello alice!", "Hello alice!"}, names)
+	})
+
+	t.Run("weighted scenario", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithScenario(calls...),
+			WithScenarioRate(20),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "a scenario rate requires the sequential or interleaved scenario mode")
+	})
+
+	t.Run("with a rate of requests", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithScenario(calls...),
+			WithScenarioMode(ScenarioInterleaved),
+			WithScenarioRate(20),
+			WithRPS(100),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "a scenario rate cannot be used with a rate of requests or a rate tolerance")
+	})
+
+	t.Run("without a scenario", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithScenarioRate(20),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "a scenario rate requires a scenario")
+	})
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// lastResponseRef is the reference to the previous response in the data and metadata templates
+var lastResponseRef = []byte(".LastResponse")
+
+// usesLastResponse returns whether any of the templates refers to the previous response
+func usesLastResponse(templates ...[]byte) bool {
+	for _, t := range templates {
+		if bytes.Contains(t, lastResponseRef) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseFields returns the fields of the response keyed by their proto names for the templates,
+// including the unset fields with their default values so that they can always be referred to.
+// The numbers are kept as they are in JSON, so large integers are not rounded or formatted as floats.
+func responseFields(res proto.Message) (map[string]interface{}, error) {
+	dm, ok := res.(*dynamic.Message)
+	if !ok {
+		var err error
+		if dm, err = dynamic.AsDynamicMessage(res); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := dm.MarshalJSONPB(&jsonpb.Marshaler{OrigName: true, EmitDefaults: true})
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
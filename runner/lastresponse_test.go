@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseFields(t *testing.T) {
+	fields, err := responseFields(&helloworld.HelloReply{Message: "hi"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"message": "hi"}, fields)
+
+	// unset fields are included with their defaults
+	fields, err = responseFields(&helloworld.HelloReply{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"message": ""}, fields)
+}
+
+func TestRunLastResponse(t *testing.T) {
+	callType := helloworld.Unary
+
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(3),
+		WithConcurrency(1),
+		WithDataFromJSON(`{"name":"{{ with .LastResponse }}{{ .message }}{{ else }}bob{{ end }}"}`),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), report.Count)
+
+	calls := gs.GetCalls(callType)
+	if assert.Len(t, calls, 3) {
+		assert.Equal(t, "bob", calls[0][0].GetName())
+		assert.Equal(t, "Hello bob", calls[1][0].GetName())
+		assert.Equal(t, "Hello Hello bob", calls[2][0].GetName())
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithAsync(true),
+		WithDataFromJSON(`{"name":"{{ with .LastResponse }}{{ .message }}{{ else }}bob{{ end }}"}`),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "the last response cannot be used in the templates of async calls")
+
+	assert.True(t, usesLastResponse([]byte(`{"name":"{{.LastResponse.message}}"}`)))
+	assert.False(t, usesLastResponse([]byte(`{"name":"{{.WorkerID}}"}`), nil))
+}
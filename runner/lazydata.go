@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// ErrEndData can be returned by a DataProviderFunc once the data is exhausted to end the run.
+// No more calls are made, and the calls in flight are completed.
+var ErrEndData = errors.New("end of data")
+
+// lazyDataProvider reads the data of each call from a reader as one JSON object per line,
+// so that the data can be generated while the run is in progress
+type lazyDataProvider struct {
+	mtd          *desc.MethodDescriptor
+	emitDefaults bool
+
+	lock   sync.Mutex
+	reader *bufio.Reader
+	closer io.Closer
+	line   int
+	done   bool
+}
+
+func newLazyDataProvider(mtd *desc.MethodDescriptor, r io.Reader) *lazyDataProvider {
+	dp := &lazyDataProvider{mtd: mtd, reader: bufio.NewReader(r)}
+
+	if c, ok := r.(io.Closer); ok {
+		dp.closer = c
+	}
+
+	return dp
+}
+
+func (dp *lazyDataProvider) getDataForCall(ctd *CallData) ([]*dynamic.Message, error) {
+	data, line, err := dp.next()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := ctd.ExecuteData(data)
+	if err != nil {
+		return nil, fmt.Errorf("data line %d: %v", line, err)
+	}
+
+	inputs, err := createPayloadsFromJSON(string(input), dp.mtd)
+	if err != nil {
+		return nil, fmt.Errorf("data line %d: %v", line, err)
+	}
+
+	if dp.emitDefaults {
+		emitDefaults(inputs)
+	}
+
+	return inputs, nil
+}
+
+// next returns the next line which is not empty and its number,
+// or ErrEndData once all the lines are read
+func (dp *lazyDataProvider) next() (string, int, error) {
+	dp.lock.Lock()
+	defer dp.lock.Unlock()
+
+	for !dp.done {
+		s, err := dp.reader.ReadString('\n')
+		if err != nil {
+			if err != io.EOF {
+				return "", 0, err
+			}
+
+			dp.done = true
+			if dp.closer != nil {
+				_ = dp.closer.Close()
+			}
+		}
+
+		dp.line++
+
+		if s = strings.TrimSpace(s); s != "" {
+			return s, dp.line, nil
+		}
+	}
+
+	return "", 0, ErrEndData
+}
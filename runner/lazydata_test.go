@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/stretchr/testify/assert"
+)
+
+type testReadCloser struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *testReadCloser) Close() error {
+	r.closed = true
+
+	return nil
+}
+
+func TestLazyDataProvider(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	t.Run("reads the lines", func(t *testing.T) {
+		r := &testReadCloser{Reader: strings.NewReader("{\"name\":\"bob\"}\n\n{\"name\":\"{{.RequestNumber}}\"}")}
+		dp := newLazyDataProvider(mtd, r)
+
+		inputs, err := dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 1)
+		assert.Equal(t, "bob", inputs[0].GetFieldByName("name"))
+		assert.False(t, r.closed)
+
+		inputs, err = dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 1)
+		assert.Equal(t, "0", inputs[0].GetFieldByName("name"))
+		assert.True(t, r.closed)
+
+		_, err = dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.Equal(t, ErrEndData, err)
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		dp := newLazyDataProvider(mtd, strings.NewReader("{\"name\":\"bob\"}\n{\"name\":\n"))
+
+		_, err := dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.NoError(t, err)
+
+		_, err = dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "data line 2:")
+	})
+}
+
+func TestRunLazyData(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	gs.ResetCounters()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithLazyDataFromReader(strings.NewReader("{\"name\":\"a\"}\n{\"name\":\"b\"}\n{\"name\":\"c\"}\n")),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+
+	assert.Equal(t, 3, int(report.Count))
+	assert.Equal(t, map[string]int{"OK": 3}, report.StatusCodeDist)
+	assert.Empty(t, report.ErrorDist)
+
+	assert.Equal(t, 3, gs.GetCount(helloworld.Unary))
+}
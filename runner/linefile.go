@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"text/template"
+)
+
+// lineFiles are the files read by the line and randomLine template functions of a run,
+// mapped into memory on their first use and shared by all the workers
+type lineFiles struct {
+	lock  sync.Mutex
+	files map[string]*lineFile
+}
+
+// lineFile is a file of lines, read in turn by the line function or at random by the randomLine
+// function. The empty lines are skipped, and the sequence starts over after the last line.
+type lineFile struct {
+	path  string
+	data  []byte
+	unmap func() error
+
+	// the offset of the next line of the sequence
+	lock sync.Mutex
+	next int
+
+	// the offsets of the lines, indexed on the first random line
+	indexOnce sync.Once
+	offsets   []int64
+}
+
+// templateFuncs returns the template functions with the line functions of the files added,
+// the functions of the same names taking precedence
+func (lf *lineFiles) templateFuncs(funcs template.FuncMap) template.FuncMap {
+	fns := template.FuncMap{
+		"line":       lf.line,
+		"randomLine": lf.randomLine,
+	}
+
+	for k, v := range funcs {
+		fns[k] = v
+	}
+
+	return fns
+}
+
+// line returns the next line of the file
+func (lf *lineFiles) line(path string) (string, error) {
+	f, err := lf.open(path)
+	if err != nil {
+		return "", err
+	}
+
+	return f.nextLine(), nil
+}
+
+// randomLine returns a random line of the file
+func (lf *lineFiles) randomLine(path string) (string, error) {
+	f, err := lf.open(path)
+	if err != nil {
+		return "", err
+	}
+
+	return f.randomLine(), nil
+}
+
+// open returns the file of the path, mapping it on its first use
+func (lf *lineFiles) open(path string) (*lineFile, error) {
+	lf.lock.Lock()
+	defer lf.lock.Unlock()
+
+	if f, ok := lf.files[path]; ok {
+		return f, nil
+	}
+
+	f, err := openLineFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if lf.files == nil {
+		lf.files = make(map[string]*lineFile)
+	}
+
+	lf.files[path] = f
+
+	return f, nil
+}
+
+// close unmaps the files
+func (lf *lineFiles) close() {
+	if lf == nil {
+		return
+	}
+
+	lf.lock.Lock()
+	defer lf.lock.Unlock()
+
+	for _, f := range lf.files {
+		_ = f.unmap()
+	}
+
+	lf.files = nil
+}
+
+func openLineFile(path string) (*lineFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("line file %s is too large to map", path)
+	}
+
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("line file %s has no lines", path)
+	}
+
+	data, unmap, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("error mapping line file %s: %v", path, err)
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		_ = unmap()
+
+		return nil, fmt.Errorf("line file %s has no lines", path)
+	}
+
+	return &lineFile{path: path, data: data, unmap: unmap}, nil
+}
+
+// lineAt returns the line starting at the offset and the offset of the following line
+func (f *lineFile) lineAt(offset int) (string, int) {
+	end := bytes.IndexByte(f.data[offset:], '\n')
+	if end < 0 {
+		end = len(f.data)
+	} else {
+		end += offset
+	}
+
+	return string(bytes.TrimSuffix(f.data[offset:end], []byte{'\r'})), end + 1
+}
+
+// nextLine returns the next line which is not empty, starting over after the last line
+func (f *lineFile) nextLine() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for {
+		if f.next >= len(f.data) {
+			f.next = 0
+		}
+
+		var line string
+		line, f.next = f.lineAt(f.next)
+		if line != "" {
+			return line
+		}
+	}
+}
+
+// randomLine returns a random line which is not empty
+func (f *lineFile) randomLine() string {
+	f.indexOnce.Do(func() {
+		for offset := 0; offset < len(f.data); {
+			line, next := f.lineAt(offset)
+			if line != "" {
+				f.offsets = append(f.offsets, int64(offset))
+			}
+
+			offset = next
+		}
+	})
+
+	line, _ := f.lineAt(int(f.offsets[rand.Intn(len(f.offsets))]))
+
+	return line
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeLineFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "ghz-lines")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "ids.txt")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	return path
+}
+
+func TestLineFiles(t *testing.T) {
+	lf := &lineFiles{}
+	defer lf.close()
+
+	path := writeLineFile(t, "id-1\r\n\nid-2\nid-3")
+
+	t.Run("line", func(t *testing.T) {
+		var lines []string
+		for i := 0; i < 7; i++ {
+			line, err := lf.line(path)
+			assert.NoError(t, err)
+
+			lines = append(lines, line)
+		}
+
+		assert.Equal(t, []string{"id-1", "id-2", "id-3", "id-1", "id-2", "id-3", "id-1"}, lines)
+	})
+
+	t.Run("random line", func(t *testing.T) {
+		seen := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			line, err := lf.randomLine(path)
+			assert.NoError(t, err)
+
+			seen[line] = true
+		}
+
+		assert.Equal(t, map[string]bool{"id-1": true, "id-2": true, "id-3": true}, seen)
+	})
+
+	t.Run("no lines", func(t *testing.T) {
+		_, err := lf.line(writeLineFile(t, "\n \r\n"))
+		assert.Contains(t, err.Error(), "has no lines")
+
+		_, err = lf.line(writeLineFile(t, ""))
+		assert.Contains(t, err.Error(), "has no lines")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := lf.randomLine("missing.txt")
+		assert.Error(t, err)
+	})
+}
+
+func TestRunLineFile(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	path := writeLineFile(t, "alice\nbob\ncarol\n")
+
+	gs.ResetCounters()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(6),
+		WithConcurrency(1),
+		WithDataFromJSON(`{"name":"{{line "`+filepath.ToSlash(path)+`"}}"}`),
+		WithInsecure(true),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]int{"OK": 6}, report.StatusCodeDist)
+
+	var names []string
+	for _, c := range gs.GetCalls(helloworld.Unary) {
+		names = append(names, c[0].GetName())
+	}
+
+	assert.Equal(t, []string{"alice", "bob", "carol", "alice", "bob", "carol"}, names)
+}
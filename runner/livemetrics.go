@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// liveLatencyBuckets are the upper bounds in seconds of the buckets of the live latency histogram
+var liveLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// liveMetrics holds the statistics of the run while it is in progress,
+// exported in the Prometheus text exposition format
+type liveMetrics struct {
+	inFlight int64
+	workers  int64
+
+	lock     sync.Mutex
+	statuses map[string]uint64
+	buckets  []uint64
+	count    uint64
+	sum      float64
+}
+
+func newLiveMetrics() *liveMetrics {
+	return &liveMetrics{
+		statuses: make(map[string]uint64),
+		buckets:  make([]uint64, len(liveLatencyBuckets)),
+	}
+}
+
+// begin records the start of a call
+func (m *liveMetrics) begin() {
+	if m != nil {
+		atomic.AddInt64(&m.inFlight, 1)
+	}
+}
+
+// end records the end of a call started with begin
+func (m *liveMetrics) end() {
+	if m != nil {
+		atomic.AddInt64(&m.inFlight, -1)
+	}
+}
+
+// addWorkers adds to the number of active workers
+func (m *liveMetrics) addWorkers(n int64) {
+	if m != nil {
+		atomic.AddInt64(&m.workers, n)
+	}
+}
+
+// activeWorkers returns the number of active workers
+func (m *liveMetrics) activeWorkers() int64 {
+	if m == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&m.workers)
+}
+
+// callsInFlight returns the number of calls in flight
+func (m *liveMetrics) callsInFlight() int64 {
+	if m == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&m.inFlight)
+}
+
+// observe records a completed call of the results
+func (m *liveMetrics) observe(status string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+
+	s := latency.Seconds()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.statuses[status]++
+	m.count++
+	m.sum += s
+
+	for i, le := range liveLatencyBuckets {
+		if s <= le {
+			m.buckets[i]++
+			break
+		}
+	}
+}
+
+// write writes the metrics in the Prometheus text exposition format
+func (m *liveMetrics) write(w io.Writer) error {
+	m.lock.Lock()
+	statuses := make([]string, 0, len(m.statuses))
+	for st := range m.statuses {
+		statuses = append(statuses, st)
+	}
+	sort.Strings(statuses)
+
+	counts := make([]uint64, len(statuses))
+	for i, st := range statuses {
+		counts[i] = m.statuses[st]
+	}
+
+	buckets := append([]uint64(nil), m.buckets...)
+	count, sum := m.count, m.sum
+	m.lock.Unlock()
+
+	b := &errWriter{w: w}
+
+	b.printf("# HELP ghz_requests_in_flight Number of requests in flight.\n")
+	b.printf("# TYPE ghz_requests_in_flight gauge\n")
+	b.printf("ghz_requests_in_flight %d\n", atomic.LoadInt64(&m.inFlight))
+
+	b.printf("# HELP ghz_workers_active Number of active workers.\n")
+	b.printf("# TYPE ghz_workers_active gauge\n")
+	b.printf("ghz_workers_active %d\n", atomic.LoadInt64(&m.workers))
+
+	b.printf("# HELP ghz_requests_total Number of completed requests by status code.\n")
+	b.printf("# TYPE ghz_requests_total counter\n")
+	for i, st := range statuses {
+		b.printf("ghz_requests_total{status=%q} %d\n", st, counts[i])
+	}
+
+	b.printf("# HELP ghz_request_duration_seconds Latency of the completed requests.\n")
+	b.printf("# TYPE ghz_request_duration_seconds histogram\n")
+
+	var cumulative uint64
+	for i, le := range liveLatencyBuckets {
+		cumulative += buckets[i]
+		b.printf("ghz_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+
+	b.printf("ghz_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", count)
+	b.printf("ghz_request_duration_seconds_sum %s\n", strconv.FormatFloat(sum, 'g', -1, 64))
+	b.printf("ghz_request_duration_seconds_count %d\n", count)
+
+	return b.err
+}
+
+// errWriter keeps the first error of the writes
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (b *errWriter) printf(format string, args ...interface{}) {
+	if b.err == nil {
+		_, b.err = fmt.Fprintf(b.w, format, args...)
+	}
+}
+
+// ServeHTTP serves the metrics
+func (m *liveMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.write(w)
+}
+
+// serve serves the metrics at /metrics on the address until the returned server is closed
+func (m *liveMetrics) serve(addr string) (*http.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics server: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	return srv, nil
+}
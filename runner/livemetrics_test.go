@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveMetrics_write(t *testing.T) {
+	m := newLiveMetrics()
+
+	m.addWorkers(2)
+	m.begin()
+	m.begin()
+	m.end()
+	m.observe("OK", 3*time.Millisecond)
+	m.observe("OK", 20*time.Millisecond)
+	m.observe("Unavailable", 20*time.Second)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, m.write(buf))
+
+	out := buf.String()
+	for _, line := range []string{
+		"ghz_requests_in_flight 1\n",
+		"ghz_workers_active 2\n",
+		"ghz_requests_total{status=\"OK\"} 2\n",
+		"ghz_requests_total{status=\"Unavailable\"} 1\n",
+		"ghz_request_duration_seconds_bucket{le=\"0.0025\"} 0\n",
+		"ghz_request_duration_seconds_bucket{le=\"0.005\"} 1\n",
+		"ghz_request_duration_seconds_bucket{le=\"0.025\"} 2\n",
+		"ghz_request_duration_seconds_bucket{le=\"10\"} 2\n",
+		"ghz_request_duration_seconds_bucket{le=\"+Inf\"} 3\n",
+		"ghz_request_duration_seconds_sum 20.023\n",
+		"ghz_request_duration_seconds_count 3\n",
+		"# TYPE ghz_request_duration_seconds histogram\n",
+	} {
+		assert.Contains(t, out, line)
+	}
+
+	// nil metrics are not collected
+	var none *liveMetrics
+	none.begin()
+	none.observe("OK", time.Millisecond)
+}
+
+func TestRunMetricsAddr(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	done := make(chan *Report)
+	go func() {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(time.Second),
+			WithConcurrency(2),
+			WithRPS(50),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+			WithMetricsAddr(addr),
+		)
+
+		assert.NoError(t, err)
+		done <- report
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+
+	res, err := http.Get("http://" + addr + "/metrics")
+	if assert.NoError(t, err) {
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.True(t, strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain"))
+		assert.Contains(t, string(body), "ghz_workers_active 2\n")
+		assert.Contains(t, string(body), "ghz_requests_total{status=\"OK\"} ")
+	}
+
+	report := <-done
+	assert.NotZero(t, report.Count)
+
+	// the server is stopped at the end of the run
+	_, err = http.Get("http://" + addr + "/metrics")
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// StatusClientCanceled is the status of the calls canceled by the client once their
+// maximum duration was reached. They are counted separately from the calls failed
+// by the server and are not included in the error distribution.
+const StatusClientCanceled = "client-canceled"
+
+// callMaxKey is the context key of the calls with a maximum duration
+type callMaxKey struct{}
+
+// callMax cancels a call once its maximum duration is reached
+type callMax struct {
+	expired uint32
+	timer   *time.Timer
+}
+
+// withCallMax returns the context of a call which is canceled after the maximum duration,
+// and the function to call once the call is done. The cancel function has to cancel the context.
+func withCallMax(ctx context.Context, max time.Duration, cancel context.CancelFunc) (context.Context, func()) {
+	if max <= 0 {
+		return ctx, func() {}
+	}
+
+	cm := &callMax{}
+	cm.timer = time.AfterFunc(max, func() {
+		atomic.StoreUint32(&cm.expired, 1)
+		cancel()
+	})
+
+	return context.WithValue(ctx, callMaxKey{}, cm), func() { cm.timer.Stop() }
+}
+
+// clientCanceled returns whether the call ended with the error was canceled by the client
+// once the maximum duration was reached
+func clientCanceled(ctx context.Context, err error) bool {
+	cm, ok := ctx.Value(callMaxKey{}).(*callMax)
+
+	return ok && atomic.LoadUint32(&cm.expired) == 1 && statusCode(err) == codes.Canceled
+}
+
+// maxCallDuration returns the maximum duration of the calls of the method
+func (w *Worker) maxCallDuration() time.Duration {
+	if w.mtd.IsClientStreaming() || w.mtd.IsServerStreaming() {
+		return w.config.streamMaxDuration
+	}
+
+	return w.config.callMaxDuration
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCallMax(t *testing.T) {
+	t.Run("no maximum", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		callCtx, done := withCallMax(ctx, 0, cancel)
+		done()
+
+		assert.Equal(t, ctx, callCtx)
+		assert.False(t, clientCanceled(callCtx, context.Canceled))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		callCtx, done := withCallMax(ctx, time.Millisecond, cancel)
+		defer done()
+
+		<-callCtx.Done()
+
+		assert.True(t, clientCanceled(callCtx, status.Error(codes.Canceled, "context canceled")))
+		assert.True(t, clientCanceled(callCtx, context.Canceled))
+		assert.False(t, clientCanceled(callCtx, status.Error(codes.Unavailable, "unavailable")))
+		assert.False(t, clientCanceled(callCtx, nil))
+	})
+
+	t.Run("done before maximum", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		callCtx, done := withCallMax(ctx, 20*time.Millisecond, cancel)
+		done()
+		cancel()
+
+		time.Sleep(40 * time.Millisecond)
+
+		assert.False(t, clientCanceled(callCtx, context.Canceled))
+	})
+}
+
+func TestRunMaxDuration(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("unary", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithCallMaxDuration(time.Nanosecond),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		assert.Equal(t, 10, int(report.Count))
+		assert.Equal(t, map[string]int{StatusClientCanceled: 10}, report.StatusCodeDist)
+		assert.Empty(t, report.ErrorDist)
+		assert.Equal(t, time.Nanosecond, report.Options.CallMaxDuration)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHelloBidi",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(4),
+			WithConcurrency(2),
+			WithStreamInterval(100*time.Millisecond),
+			WithStreamMaxDuration(150*time.Millisecond),
+			WithCallMaxDuration(time.Nanosecond),
+			WithData([]interface{}{
+				map[string]interface{}{"name": "bob"},
+				map[string]interface{}{"name": "Kate"},
+				map[string]interface{}{"name": "foo"},
+			}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		assert.Equal(t, 4, int(report.Count))
+		assert.Equal(t, map[string]int{StatusClientCanceled: 4}, report.StatusCodeDist)
+		assert.Empty(t, report.ErrorDist)
+		assert.True(t, report.Fastest >= 150*time.Millisecond)
+	})
+}
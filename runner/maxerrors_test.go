@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bojand/ghz/internal/echo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestRunMaxErrors(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	svc, err := echo.New(echo.Options{ErrorRate: 100, ErrorCode: codes.Unavailable})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	s := grpc.NewServer()
+	if err := svc.Register(s); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	reflection.Register(s)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	host := lis.Addr().String()
+
+	t.Run("max errors", func(t *testing.T) {
+		report, err := Run(
+			"echo.Echo.Echo",
+			host,
+			WithTotalRequests(1000),
+			WithConcurrency(2),
+			WithMaxErrors(5),
+			WithData(map[string]interface{}{"message": "hi"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonMaxErrors, report.EndReason)
+		assert.Equal(t, "rpc error: code = Unavailable desc = injected error", report.StopError)
+		assert.True(t, report.Count >= 5 && report.Count < 1000, "count %d", report.Count)
+		assert.Equal(t, uint(5), report.Options.MaxErrors)
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		report, err := Run(
+			"echo.Echo.Echo",
+			host,
+			WithTotalRequests(1000),
+			WithConcurrency(1),
+			WithFailFast(true),
+			WithData(map[string]interface{}{"message": "hi"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonMaxErrors, report.EndReason)
+		assert.Equal(t, "rpc error: code = Unavailable desc = injected error", report.StopError)
+		assert.True(t, report.Count < 1000, "count %d", report.Count)
+	})
+
+	t.Run("without a limit", func(t *testing.T) {
+		report, err := Run(
+			"echo.Echo.Echo",
+			host,
+			WithTotalRequests(20),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"message": "hi"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonNormalEnd, report.EndReason)
+		assert.Empty(t, report.StopError)
+		assert.Equal(t, uint64(20), report.Count)
+	})
+}
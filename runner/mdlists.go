@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataList is a metadata key whose values are rotated per request
+type metadataList struct {
+	key    string
+	values []string
+}
+
+// newMetadataLists returns the metadata lists sorted by key, with the values of binary
+// keys decoded from base64 as for the other metadata
+func newMetadataLists(lists map[string][]string) ([]metadataList, error) {
+	keys := make([]string, 0, len(lists))
+	for k := range lists {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	mls := make([]metadataList, 0, len(keys))
+	for _, k := range keys {
+		if len(lists[k]) == 0 {
+			return nil, fmt.Errorf("metadata list of %q must not be empty", k)
+		}
+
+		values := make([]string, len(lists[k]))
+		for i, v := range lists[k] {
+			if strings.HasSuffix(k, "-bin") {
+				decoded, err := base64.StdEncoding.DecodeString(v)
+				if err != nil {
+					return nil, err
+				}
+
+				v = string(decoded)
+			}
+
+			values[i] = v
+		}
+
+		mls = append(mls, metadataList{key: k, values: values})
+	}
+
+	return mls, nil
+}
+
+// extractMetadataLists removes the list values from the metadata object and returns them
+// separately, so they can be rotated per request. The metadata is returned unchanged
+// if it has no lists or is not a plain JSON object, such as when using template actions.
+func extractMetadataLists(mdData []byte) ([]byte, map[string][]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(mdData, &fields); err != nil {
+		return mdData, nil, nil
+	}
+
+	lists := make(map[string][]string)
+	for k, v := range fields {
+		if !bytes.HasPrefix(bytes.TrimSpace(v), []byte("[")) {
+			continue
+		}
+
+		var values []string
+		if err := json.Unmarshal(v, &values); err != nil {
+			return nil, nil, fmt.Errorf("metadata list of %q must be a list of strings", k)
+		}
+
+		lists[k] = values
+		delete(fields, k)
+	}
+
+	if len(lists) == 0 {
+		return mdData, nil, nil
+	}
+
+	// the template actions of the other values must not be escaped
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fields); err != nil {
+		return nil, nil, err
+	}
+
+	return bytes.TrimSpace(buf.Bytes()), lists, nil
+}
+
+// rotateMetadata returns a copy of the metadata with the value of each list
+// for the request number, wrapping around
+func rotateMetadata(md *metadata.MD, lists []metadataList, reqNum int64) *metadata.MD {
+	rotated := md.Copy()
+	for _, l := range lists {
+		rotated.Set(l.key, l.values[int(reqNum%int64(len(l.values)))])
+	}
+
+	return &rotated
+}
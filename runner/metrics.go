@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MetricFunc computes a custom derived metric from the finalized report
+type MetricFunc func(r *Report) (float64, error)
+
+// DerivedMetric is the value of a custom derived metric
+type DerivedMetric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+
+	// Error is the error computing the metric, if any
+	Error string `json:"error,omitempty"`
+}
+
+// metricDef is a named derived metric
+type metricDef struct {
+	name string
+	fn   MetricFunc
+}
+
+// the metric names are limited so they can be used as the keys of all the output formats
+var metricNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+var metricFuncMap = template.FuncMap{
+	"add":        func(a, b interface{}) (float64, error) { return metricOp(a, b, '+') },
+	"sub":        func(a, b interface{}) (float64, error) { return metricOp(a, b, '-') },
+	"mul":        func(a, b interface{}) (float64, error) { return metricOp(a, b, '*') },
+	"div":        func(a, b interface{}) (float64, error) { return metricOp(a, b, '/') },
+	"seconds":    func(d time.Duration) float64 { return d.Seconds() },
+	"ms":         func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) },
+	"percentile": metricPercentile,
+}
+
+func checkMetricName(name string) error {
+	if !metricNameRe.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q: expected letters, digits, underscores and dots", name)
+	}
+
+	return nil
+}
+
+// ParseMetricTemplate parses a derived metric in the form of <NAME>=<TEMPLATE>. The template
+// is executed with the finalized report and has to produce a number. Besides the standard
+// template functions it can use add, sub, mul and div for arithmetic, seconds and ms to convert
+// durations, and percentile to get a latency of the latency distribution.
+//
+//	ParseMetricTemplate("cost_per_1m={{ div (mul 0.42 1000000) .Count }}")
+//	ParseMetricTemplate("p99_ms={{ ms (percentile .LatencyDistribution 99) }}")
+func ParseMetricTemplate(s string) (string, MetricFunc, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", nil, fmt.Errorf("invalid metric %q: expected <name>=<template>", s)
+	}
+
+	name := strings.TrimSpace(s[:i])
+	if err := checkMetricName(name); err != nil {
+		return "", nil, err
+	}
+
+	t, err := template.New(name).Funcs(metricFuncMap).Parse(strings.TrimSpace(s[i+1:]))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid metric %q: %v", name, err)
+	}
+
+	fn := func(r *Report) (float64, error) {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, r); err != nil {
+			return 0, err
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(buf.String()), 64)
+		if err != nil {
+			return 0, fmt.Errorf("the result %q is not a number", buf.String())
+		}
+
+		return v, nil
+	}
+
+	return name, fn, nil
+}
+
+// computeMetrics computes the derived metrics of the report in the order they were specified
+func computeMetrics(r *Report, metrics []metricDef) []DerivedMetric {
+	values := make([]DerivedMetric, len(metrics))
+	for i, m := range metrics {
+		values[i].Name = m.name
+
+		v, err := m.fn(r)
+		if err != nil {
+			values[i].Error = err.Error()
+
+			continue
+		}
+
+		values[i].Value = v
+	}
+
+	return values
+}
+
+// metricPercentile returns the latency of the percentile of the latency distribution
+func metricPercentile(dist []LatencyDistribution, p int) (time.Duration, error) {
+	for _, ld := range dist {
+		if ld.Percentage == p {
+			return ld.Latency, nil
+		}
+	}
+
+	return 0, fmt.Errorf("percentile %d is not in the latency distribution", p)
+}
+
+func metricOp(a, b interface{}, op byte) (float64, error) {
+	x, err := metricNumber(a)
+	if err != nil {
+		return 0, err
+	}
+
+	y, err := metricNumber(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	}
+
+	if y == 0 {
+		return 0, errors.New("division by zero")
+	}
+
+	return x / y, nil
+}
+
+// metricNumber converts the numeric template value to a float, durations are in nanoseconds
+func metricNumber(v interface{}) (float64, error) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+
+	return 0, fmt.Errorf("%v is not a number", v)
+}
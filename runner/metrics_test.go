@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetricTemplate(t *testing.T) {
+	r := &Report{
+		Count:   2000,
+		Total:   4 * time.Second,
+		Average: 25 * time.Millisecond,
+		Rps:     500,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 20 * time.Millisecond},
+			{Percentage: 99, Latency: 80 * time.Millisecond},
+		},
+		StatusCodeDist: map[string]int{"OK": 1900, "Unavailable": 100},
+	}
+
+	var tests = []struct {
+		name     string
+		in       string
+		expected float64
+		err      string
+	}{
+		{"cost", "cost_per_1m={{ div (mul 0.42 1000000) .Count }}", 210, ""},
+		{"percentile", "p99_ms = {{ ms (percentile .LatencyDistribution 99) }}", 80, ""},
+		{"seconds", "total_s={{ seconds .Total }}", 4, ""},
+		{"status", `ok_ratio={{ div (index .StatusCodeDist "OK") .Count }}`, 0.95, ""},
+		{"add sub", "spare={{ sub (add .Rps 100) 50 }}", 550, ""},
+		{"constant", "budget=1.5", 1.5, ""},
+		{"missing percentile", "p95={{ percentile .LatencyDistribution 95 }}", 0, "percentile 95 is not in the latency distribution"},
+		{"division by zero", "x={{ div .Count 0 }}", 0, "division by zero"},
+		{"not a number", "x={{ .Name }}abc", 0, `the result "abc" is not a number`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, fn, err := ParseMetricTemplate(tt.in)
+			assert.NoError(t, err)
+
+			v, err := fn(r)
+			if tt.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.err)
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+
+	t.Run("name", func(t *testing.T) {
+		name, _, err := ParseMetricTemplate(" p99_ms = {{ .Count }}")
+		assert.NoError(t, err)
+		assert.Equal(t, "p99_ms", name)
+	})
+
+	var invalid = []string{
+		"no template",
+		"=1",
+		"1x=1",
+		"cost per call=1",
+		"x={{ .Count",
+	}
+
+	for _, in := range invalid {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, _, err := ParseMetricTemplate(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestComputeMetrics(t *testing.T) {
+	metrics := []metricDef{
+		{"count", func(r *Report) (float64, error) { return float64(r.Count), nil }},
+		{"failed", func(r *Report) (float64, error) { return 0, errors.New("failed") }},
+	}
+
+	actual := computeMetrics(&Report{Count: 10}, metrics)
+
+	assert.Equal(t, []DerivedMetric{
+		{Name: "count", Value: 10},
+		{Name: "failed", Error: "failed"},
+	}, actual)
+}
+
+func TestRunMetrics(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithMetricTemplates("cost_per_1m={{ div (mul 0.42 1000000) .Count }}", ""),
+		WithMetric("connections", func(r *Report) (float64, error) {
+			// the metrics see the complete report
+			return float64(len(r.Connections)), nil
+		}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.Len(t, report.Metrics, 2) {
+		assert.Equal(t, DerivedMetric{Name: "cost_per_1m", Value: 21000}, report.Metrics[0])
+		assert.Equal(t, DerivedMetric{Name: "connections", Value: 1}, report.Metrics[1])
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileMappingSupported is whether the files are mapped into memory rather than read
+const fileMappingSupported = true
+
+// mapFile maps the file read-only into memory, returning the mapping and the function to unmap it
+func mapFile(file *os.File, size int) ([]byte, func() error, error) {
+	data, err := syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package runner
+
+import (
+	"io"
+	"os"
+)
+
+// fileMappingSupported is whether the files are mapped into memory rather than read
+const fileMappingSupported = false
+
+// mapFile reads the file into memory, since it is not mapped on Windows,
+// returning the data and a function doing nothing in place of unmapping it
+func mapFile(file *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, nil, err
+	}
+
+	return data, func() error { return nil }, nil
+}
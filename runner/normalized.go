@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"time"
+)
+
+// Normalized holds the throughput of the run normalized per client CPU core and per connection,
+// to compare the results of load generators of different sizes
+type Normalized struct {
+	// CPUTime is the user and system CPU time the process used during the run,
+	// 0 if it could not be measured
+	CPUTime time.Duration `json:"cpuTime,omitempty"`
+
+	// CoresUsed is the average number of CPU cores used during the run
+	CoresUsed float64 `json:"coresUsed,omitempty"`
+
+	// RpsPerCore is the requests per second per CPU core used
+	RpsPerCore float64 `json:"rpsPerCore,omitempty"`
+
+	// RpsPerCPU is the requests per second per CPU the run was allowed to use
+	RpsPerCPU float64 `json:"rpsPerCPU"`
+
+	// RpsPerConnection is the requests per second per connection
+	RpsPerConnection float64 `json:"rpsPerConnection"`
+}
+
+// normalized returns the throughput of the run normalized by the CPU time used,
+// the number of CPUs and the number of connections
+func normalized(rps float64, total, cpuTime time.Duration, cpus, conns int) *Normalized {
+	n := &Normalized{CPUTime: cpuTime}
+
+	if cpuTime > 0 && total > 0 {
+		n.CoresUsed = cpuTime.Seconds() / total.Seconds()
+		n.RpsPerCore = rps / n.CoresUsed
+	}
+
+	if cpus > 0 {
+		n.RpsPerCPU = rps / float64(cpus)
+	}
+
+	if conns > 0 {
+		n.RpsPerConnection = rps / float64(conns)
+	}
+
+	return n
+}
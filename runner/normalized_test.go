@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalized(t *testing.T) {
+	assert.Equal(t, &Normalized{
+		CPUTime:          4 * time.Second,
+		CoresUsed:        2,
+		RpsPerCore:       5000,
+		RpsPerCPU:        1250,
+		RpsPerConnection: 2500,
+	}, normalized(10000, 2*time.Second, 4*time.Second, 8, 4))
+
+	// the CPU time could not be measured
+	assert.Equal(t, &Normalized{
+		RpsPerCPU:        1250,
+		RpsPerConnection: 10000,
+	}, normalized(10000, 2*time.Second, 0, 8, 1))
+}
+
+func TestProcessCPUTime(t *testing.T) {
+	start := processCPUTime()
+
+	// busy loop to use some CPU time
+	x := 0
+	for i := 0; i < 50000000; i++ {
+		x += i
+	}
+
+	assert.True(t, x > 0)
+	assert.True(t, processCPUTime() > start)
+}
@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// PacingStats holds the effective rates of the workers with the worker pacing, which include
+// the delays of the workers between their requests
+type PacingStats struct {
+	Pace string `json:"pace"`
+
+	// ThinkTime is the average time between the end of a call of a worker and the start of
+	// its next call, that is the delay of the pacing along with the overhead of the worker
+	ThinkTime time.Duration `json:"thinkTime"`
+
+	Workers int `json:"workers"`
+
+	// The rates of the workers, each the number of calls of the worker per second
+	// between the start of its first call and the start of its last call
+	AverageRate float64 `json:"averageRate"`
+	MinRate     float64 `json:"minRate"`
+	MaxRate     float64 `json:"maxRate"`
+}
+
+// The distributions of the delay of each worker before each of its requests
+const (
+	PaceFixed   = "fixed"
+	PaceUniform = "uniform"
+	PacePoisson = "poisson"
+)
+
+// workerPacing delays each request of the workers by a random delay of the distribution
+type workerPacing struct {
+	dist     string
+	interval time.Duration
+	jitter   time.Duration
+}
+
+// parseWorkerPacing parses the pacing of the workers in the form of fixed:<interval>,
+// uniform:<interval>,<jitter> for a delay within interval ± jitter, or poisson:<interval>
+// for exponentially distributed delays with a mean of interval, that is Poisson arrivals.
+func parseWorkerPacing(s string) (*workerPacing, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid worker pacing %q: expected <distribution>:<interval>", s)
+	}
+
+	p := &workerPacing{dist: strings.ToLower(strings.TrimSpace(s[:i]))}
+
+	parts := strings.Split(s[i+1:], ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	// exponentially distributed delays are Poisson arrivals
+	if p.dist == "exponential" {
+		p.dist = PacePoisson
+	}
+
+	switch p.dist {
+	case PaceFixed, PacePoisson:
+		if len(parts) != 1 {
+			return nil, fmt.Errorf("invalid %s worker pacing %q: expected %s:<interval>", p.dist, s, p.dist)
+		}
+	case PaceUniform:
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid uniform worker pacing %q: expected uniform:<interval>,<jitter>", s)
+		}
+
+		jitter, err := time.ParseDuration(parts[1])
+		if err != nil || jitter < 0 {
+			return nil, fmt.Errorf("invalid worker pacing jitter %q", parts[1])
+		}
+
+		p.jitter = jitter
+	default:
+		return nil, fmt.Errorf("unknown worker pacing %q: expected fixed, uniform, poisson or exponential", p.dist)
+	}
+
+	interval, err := time.ParseDuration(parts[0])
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid worker pacing interval %q", parts[0])
+	}
+
+	if p.jitter > interval {
+		return nil, fmt.Errorf("worker pacing jitter %v cannot be greater than the interval %v", p.jitter, interval)
+	}
+
+	p.interval = interval
+
+	return p, nil
+}
+
+// next returns a random delay of the distribution
+func (p *workerPacing) next() time.Duration {
+	switch p.dist {
+	case PaceUniform:
+		return p.interval + time.Duration((2*rand.Float64()-1)*float64(p.jitter))
+	case PacePoisson:
+		return time.Duration(rand.ExpFloat64() * float64(p.interval))
+	default:
+		return p.interval
+	}
+}
+
+func (p *workerPacing) String() string {
+	if p.dist == PaceUniform {
+		return fmt.Sprintf("%s:%v,%v", p.dist, p.interval, p.jitter)
+	}
+
+	return fmt.Sprintf("%s:%v", p.dist, p.interval)
+}
+
+// pacingStats returns the effective rates and the think time of the workers from the details of the calls
+func pacingStats(details detailIter, p *workerPacing) *PacingStats {
+	type span struct {
+		count     int
+		first     time.Time // the start of the first call
+		lastStart time.Time
+		lastEnd   time.Time
+	}
+
+	workers := make(map[string]*span)
+
+	var think time.Duration
+	var gaps int
+
+	details.each(func(d *ResultDetail) {
+		if d.Worker == "" {
+			return
+		}
+
+		start := d.Timestamp.Add(-d.Latency)
+
+		w, ok := workers[d.Worker]
+		if !ok {
+			workers[d.Worker] = &span{count: 1, first: start, lastStart: start, lastEnd: d.Timestamp}
+			return
+		}
+
+		// the details are in the order the calls ended, which is the order of the
+		// calls of the worker unless they overlap, as async calls do
+		if gap := start.Sub(w.lastEnd); gap > 0 {
+			think += gap
+		}
+
+		gaps++
+
+		w.count++
+		if start.Before(w.first) {
+			w.first = start
+		}
+
+		if start.After(w.lastStart) {
+			w.lastStart = start
+		}
+
+		if d.Timestamp.After(w.lastEnd) {
+			w.lastEnd = d.Timestamp
+		}
+	})
+
+	stats := &PacingStats{Pace: p.String(), Workers: len(workers)}
+	if gaps > 0 {
+		stats.ThinkTime = think / time.Duration(gaps)
+	}
+
+	var sum float64
+	var rated int
+
+	for _, w := range workers {
+		elapsed := w.lastStart.Sub(w.first)
+		if w.count < 2 || elapsed <= 0 {
+			continue
+		}
+
+		rate := float64(w.count-1) / elapsed.Seconds()
+		if rated == 0 || rate < stats.MinRate {
+			stats.MinRate = rate
+		}
+
+		if rate > stats.MaxRate {
+			stats.MaxRate = rate
+		}
+
+		sum += rate
+		rated++
+	}
+
+	if rated > 0 {
+		stats.AverageRate = sum / float64(rated)
+	}
+
+	return stats
+}
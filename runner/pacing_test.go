@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkerPacing(t *testing.T) {
+	var tests = []struct {
+		in       string
+		expected string
+		err      string
+	}{
+		{"fixed:100ms", "fixed:100ms", ""},
+		{" Uniform: 100ms, 20ms ", "uniform:100ms,20ms", ""},
+		{"poisson:1s", "poisson:1s", ""},
+		{"exponential:1s", "poisson:1s", ""},
+		{"100ms", "", `invalid worker pacing "100ms": expected <distribution>:<interval>`},
+		{"normal:100ms", "", `unknown worker pacing "normal": expected fixed, uniform, poisson or exponential`},
+		{"fixed:100ms,10ms", "", `invalid fixed worker pacing "fixed:100ms,10ms": expected fixed:<interval>`},
+		{"uniform:100ms", "", `invalid uniform worker pacing "uniform:100ms": expected uniform:<interval>,<jitter>`},
+		{"poisson:0s", "", `invalid worker pacing interval "0s"`},
+		{"uniform:100ms,x", "", `invalid worker pacing jitter "x"`},
+		{"uniform:100ms,200ms", "", "worker pacing jitter 200ms cannot be greater than the interval 100ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := parseWorkerPacing(tt.in)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p.String())
+		})
+	}
+}
+
+func TestWithThinkTime(t *testing.T) {
+	var tests = []struct {
+		in       string
+		expected string
+		err      string
+	}{
+		{"500ms", "fixed:500ms", ""},
+		{"uniform:2s,1s", "uniform:2s,1s", ""},
+		{"exponential:2s", "poisson:2s", ""},
+		{"x", "", `invalid think time: invalid worker pacing interval "x"`},
+		{"normal:1s", "", `invalid think time: unknown worker pacing "normal": expected fixed, uniform, poisson or exponential`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			c, err := NewConfig("call", "localhost:50050", WithThinkTime(tt.in))
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, c.workerPacing.String())
+		})
+	}
+
+	t.Run("with worker pacing", func(t *testing.T) {
+		_, err := NewConfig("call", "localhost:50050", WithConfig(&Config{
+			Call:       "call",
+			Host:       "localhost:50050",
+			WorkerPace: "fixed:1s",
+			ThinkTime:  "1s",
+		}))
+		assert.EqualError(t, err, "think time cannot be used with worker pacing, which it is an alias of")
+	})
+}
+
+func TestWorkerPacing_next(t *testing.T) {
+	t.Run("fixed", func(t *testing.T) {
+		p := &workerPacing{dist: PaceFixed, interval: 100 * time.Millisecond}
+		assert.Equal(t, 100*time.Millisecond, p.next())
+	})
+
+	t.Run("uniform", func(t *testing.T) {
+		p := &workerPacing{dist: PaceUniform, interval: 100 * time.Millisecond, jitter: 20 * time.Millisecond}
+		for i := 0; i < 1000; i++ {
+			d := p.next()
+			assert.True(t, d >= 80*time.Millisecond && d <= 120*time.Millisecond, d.String())
+		}
+	})
+
+	t.Run("poisson", func(t *testing.T) {
+		p := &workerPacing{dist: PacePoisson, interval: 100 * time.Millisecond}
+
+		var sum time.Duration
+		for i := 0; i < 10000; i++ {
+			d := p.next()
+			assert.True(t, d >= 0)
+			sum += d
+		}
+
+		assert.InDelta(t, 100*time.Millisecond, sum/10000, float64(10*time.Millisecond))
+	})
+}
+
+func TestPacingStats(t *testing.T) {
+	start := time.Now()
+	p := &workerPacing{dist: PaceFixed, interval: 90 * time.Millisecond}
+
+	details := []ResultDetail{
+		{Worker: "g0c0", Timestamp: start.Add(10 * time.Millisecond), Latency: 10 * time.Millisecond},
+		{Worker: "g0c1", Timestamp: start.Add(20 * time.Millisecond), Latency: 20 * time.Millisecond},
+		{Worker: "g0c0", Timestamp: start.Add(110 * time.Millisecond), Latency: 10 * time.Millisecond},
+		{Worker: "g0c1", Timestamp: start.Add(220 * time.Millisecond), Latency: 20 * time.Millisecond},
+		{Worker: "g0c0", Timestamp: start.Add(210 * time.Millisecond), Latency: 10 * time.Millisecond},
+		{Worker: "g0c1", Timestamp: start.Add(420 * time.Millisecond), Latency: 20 * time.Millisecond},
+	}
+
+	stats := pacingStats(detailIter{details: details}, p)
+
+	assert.Equal(t, "fixed:90ms", stats.Pace)
+	assert.Equal(t, 2, stats.Workers)
+	assert.Equal(t, 135*time.Millisecond, stats.ThinkTime)
+	assert.InDelta(t, 5.0, stats.MinRate, 0.001)
+	assert.InDelta(t, 10.0, stats.MaxRate, 0.001)
+	assert.InDelta(t, 7.5, stats.AverageRate, 0.001)
+}
+
+func TestRunWorkerPacing(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	start := time.Now()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(6),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithWorkerPacing("fixed:50ms"),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), report.Count)
+	assert.Equal(t, "fixed:50ms", report.Options.WorkerPace)
+
+	if assert.NotNil(t, report.Pacing) {
+		assert.Equal(t, 2, report.Pacing.Workers)
+		assert.True(t, report.Pacing.ThinkTime >= 50*time.Millisecond)
+		assert.True(t, report.Pacing.MaxRate < 20)
+	}
+
+	// each worker waits before each of its 3 requests
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithAsync(true),
+		WithAsyncPools(2, 2),
+		WithWorkerPacing("poisson:10ms"),
+	)
+
+	assert.EqualError(t, err, "worker pacing cannot be used with async sender pools")
+}
@@ -0,0 +1,234 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// defaultPageTokenFields are the page token fields of the request and the response
+// of the list methods following the common pagination design
+const defaultPageTokenFields = "page_token:next_page_token"
+
+// PaginationStats holds the statistics of the logical operations of the pagination mode.
+// Each operation repeats the call with the page token of the previous response until
+// the listing is exhausted, and its latency is the end-to-end latency of all of its pages.
+type PaginationStats struct {
+	// Fields are the page token fields of the request and the response
+	Fields string `json:"fields"`
+
+	// Operations is the number of logical operations
+	Operations uint64 `json:"operations"`
+
+	// Exhausted is the number of operations which received a response without a next page token
+	Exhausted uint64 `json:"exhausted"`
+
+	// Truncated is the number of operations stopped at the maximum number of pages
+	Truncated uint64 `json:"truncated"`
+
+	// Failed is the number of operations stopped by a failed call
+	Failed uint64 `json:"failed"`
+
+	// Pages is the number of calls made by all the operations
+	Pages        uint64  `json:"pages"`
+	AveragePages float64 `json:"averagePages"`
+	MostPages    uint64  `json:"mostPages"`
+
+	// The end-to-end latency of the operations
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// paginator gathers the logical operations of the pagination mode
+type paginator struct {
+	fields   string
+	reqField string // the page token field of the requests
+	resField string // the next page token field of the responses
+	maxPages uint
+
+	lock      sync.Mutex
+	exhausted uint64
+	truncated uint64
+	failed    uint64
+	pages     uint64
+	mostPages uint64
+	latencies []float64
+}
+
+func newPaginator(mtd *desc.MethodDescriptor, fields string, maxPages uint) (*paginator, error) {
+	if mtd.IsClientStreaming() || mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("pagination is only supported for unary calls")
+	}
+
+	if fields == "" {
+		fields = defaultPageTokenFields
+	}
+
+	p := &paginator{fields: fields, reqField: fields, resField: fields, maxPages: maxPages}
+	if i := strings.Index(fields, ":"); i >= 0 {
+		p.reqField = strings.TrimSpace(fields[:i])
+		p.resField = strings.TrimSpace(fields[i+1:])
+	}
+
+	if err := checkTokenField(mtd.GetInputType(), p.reqField); err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenField(mtd.GetOutputType(), p.resField); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// checkTokenField checks that the field path of the message is a string field
+func checkTokenField(md *desc.MessageDescriptor, path string) error {
+	fd, err := findFieldPath(md, path)
+	if err != nil {
+		return err
+	}
+
+	if fd.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING {
+		return fmt.Errorf("page token field %q of message %s must be a string", path, md.GetFullyQualifiedName())
+	}
+
+	return nil
+}
+
+// nextPage returns the request of the page following the response, or nil if the listing is exhausted
+func (p *paginator) nextPage(req *dynamic.Message, res proto.Message) (*dynamic.Message, error) {
+	dm, err := dynamic.AsDynamicMessage(res)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _ := fieldValue(dm, p.resField)
+	if token == "" {
+		return nil, nil
+	}
+
+	// the request may be shared by the calls of the other workers
+	next := dynamic.NewMessage(req.GetMessageDescriptor())
+	if err := next.MergeFrom(req); err != nil {
+		return nil, err
+	}
+
+	if err := setFieldPath(next, p.reqField, token); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// record records a logical operation with the number of its pages and its end-to-end latency
+func (p *paginator) record(pages uint, latency time.Duration, exhausted bool, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	switch {
+	case err != nil:
+		p.failed++
+	case exhausted:
+		p.exhausted++
+	default:
+		p.truncated++
+	}
+
+	p.pages += uint64(pages)
+	if uint64(pages) > p.mostPages {
+		p.mostPages = uint64(pages)
+	}
+
+	if len(p.latencies) < maxResult {
+		p.latencies = append(p.latencies, latency.Seconds())
+	}
+}
+
+func (p *paginator) stats() *PaginationStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := &PaginationStats{
+		Fields:     p.fields,
+		Operations: p.exhausted + p.truncated + p.failed,
+		Exhausted:  p.exhausted,
+		Truncated:  p.truncated,
+		Failed:     p.failed,
+		Pages:      p.pages,
+		MostPages:  p.mostPages,
+	}
+
+	if s.Operations > 0 {
+		s.AveragePages = float64(s.Pages) / float64(s.Operations)
+	}
+
+	if len(p.latencies) == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), p.latencies...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, l := range sorted {
+		sum += l
+	}
+
+	s.Average = time.Duration(sum / float64(len(sorted)) * float64(time.Second))
+	s.Fastest = time.Duration(sorted[0] * float64(time.Second))
+	s.Slowest = time.Duration(sorted[len(sorted)-1] * float64(time.Second))
+	s.LatencyDistribution = latencies(sorted)
+
+	return s
+}
+
+// setFieldPath sets the value of the dot separated field path of the message,
+// creating the parent messages which are not set
+func setFieldPath(msg *dynamic.Message, path string, v interface{}) error {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		md := msg.GetMessageDescriptor()
+
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(name)
+		}
+
+		if fd == nil {
+			return fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+		}
+
+		if i == len(parts)-1 {
+			return msg.TrySetField(fd, v)
+		}
+
+		if fd.GetMessageType() == nil {
+			return fmt.Errorf("field %q of message %s is not a message", name, md.GetFullyQualifiedName())
+		}
+
+		// the parent is copied since the message may share it with other messages
+		parent := dynamic.NewMessage(fd.GetMessageType())
+		if pm, ok := msg.GetField(fd).(proto.Message); ok && msg.HasField(fd) {
+			if err := parent.MergeFrom(pm); err != nil {
+				return err
+			}
+		}
+
+		if err := msg.TrySetField(fd, parent); err != nil {
+			return err
+		}
+
+		msg = parent
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginator(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	t.Run("fields", func(t *testing.T) {
+		p, err := newPaginator(mtd, "name:message", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "name", p.reqField)
+		assert.Equal(t, "message", p.resField)
+
+		_, err = newPaginator(mtd, "", 0)
+		assert.EqualError(t, err, `field "page_token" not found in message helloworld.HelloRequest`)
+
+		streamMtd, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHellos", "../testdata/greeter.proto", []string{})
+		assert.NoError(t, err)
+
+		_, err = newPaginator(streamMtd, "name:message", 0)
+		assert.EqualError(t, err, "pagination is only supported for unary calls")
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		p, err := newPaginator(mtd, "name:message", 0)
+		assert.NoError(t, err)
+
+		req := dynamic.NewMessage(mtd.GetInputType())
+		req.SetFieldByName("name", "bob")
+
+		next, err := p.nextPage(req, &helloworld.HelloReply{Message: "token"})
+		assert.NoError(t, err)
+		assert.Equal(t, "token", next.GetFieldByName("name"))
+
+		// the request is not modified
+		assert.Equal(t, "bob", req.GetFieldByName("name"))
+
+		next, err = p.nextPage(req, &helloworld.HelloReply{})
+		assert.NoError(t, err)
+		assert.Nil(t, next)
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		p, err := newPaginator(mtd, "name:message", 5)
+		assert.NoError(t, err)
+
+		p.record(2, 20*time.Millisecond, true, nil)
+		p.record(5, 50*time.Millisecond, false, nil)
+		p.record(1, 10*time.Millisecond, false, assert.AnError)
+
+		s := p.stats()
+		assert.Equal(t, "name:message", s.Fields)
+		assert.Equal(t, uint64(3), s.Operations)
+		assert.Equal(t, uint64(1), s.Exhausted)
+		assert.Equal(t, uint64(1), s.Truncated)
+		assert.Equal(t, uint64(1), s.Failed)
+		assert.Equal(t, uint64(8), s.Pages)
+		assert.InDelta(t, 2.67, s.AveragePages, 0.01)
+		assert.Equal(t, uint64(5), s.MostPages)
+		assert.Equal(t, 10*time.Millisecond, s.Fastest)
+		assert.Equal(t, 50*time.Millisecond, s.Slowest)
+		assert.Len(t, s.LatencyDistribution, 7)
+	})
+}
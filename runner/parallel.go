@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ParallelCall is a call of the config run in parallel with the other calls, each with its own load.
+// The fields which are not set are inherited from the config.
+type ParallelCall struct {
+	Name     string            `json:"name,omitempty" toml:"name,omitempty" yaml:"name,omitempty"`
+	Call     string            `json:"call,omitempty" toml:"call,omitempty" yaml:"call,omitempty"`
+	Host     string            `json:"host,omitempty" toml:"host,omitempty" yaml:"host,omitempty"`
+	Data     interface{}       `json:"data,omitempty" toml:"data,omitempty" yaml:"data,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty" yaml:"metadata,omitempty"`
+	C        uint              `json:"concurrency,omitempty" toml:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	N        uint              `json:"total,omitempty" toml:"total,omitempty" yaml:"total,omitempty"`
+	RPS      uint              `json:"rps,omitempty" toml:"rps,omitempty" yaml:"rps,omitempty"`
+	Z        Duration          `json:"duration,omitempty" toml:"duration,omitempty" yaml:"duration,omitempty"`
+}
+
+// Config returns the config of the parallel call, inheriting the fields which are not set from the config
+func (pc *ParallelCall) Config(c *Config) Config {
+	cfg := *c
+	cfg.Parallel = nil
+	cfg.ParallelBaseline = false
+
+	if pc.Call != "" {
+		cfg.Call = pc.Call
+	}
+
+	cfg.Name = pc.Name
+	if cfg.Name == "" {
+		cfg.Name = cfg.Call
+	}
+
+	if pc.Host != "" {
+		cfg.Host = pc.Host
+	}
+
+	if pc.Data != nil {
+		cfg.Data = pc.Data
+		cfg.DataPath = ""
+		cfg.BinData = nil
+		cfg.BinDataPath = ""
+	}
+
+	if pc.Metadata != nil {
+		cfg.Metadata = pc.Metadata
+		cfg.MetadataPath = ""
+	}
+
+	if pc.C > 0 {
+		cfg.C = pc.C
+	}
+
+	if pc.N > 0 {
+		cfg.N = pc.N
+	}
+
+	if pc.RPS > 0 {
+		cfg.RPS = pc.RPS
+	}
+
+	if pc.Z > 0 {
+		cfg.Z = pc.Z
+	}
+
+	return cfg
+}
+
+// ParallelRun is a run of RunParallel
+type ParallelRun struct {
+	// Name is the name of the run, the call is used if it is empty
+	Name string
+
+	Call    string
+	Host    string
+	Options []Option
+}
+
+// ParallelReport holds the reports of the runs made in parallel
+type ParallelReport struct {
+	// Reports are the reports of the runs made in parallel, in the order of the runs
+	Reports []*Report `json:"reports"`
+
+	// Baseline are the reports of the runs made one by one before the parallel runs, if any
+	Baseline []*Report `json:"baseline,omitempty"`
+
+	// Interference compares the results of each run made in parallel to its baseline
+	Interference []Interference `json:"interference,omitempty"`
+}
+
+// Interference compares the results of a run made in parallel with the other runs
+// to the results of the same run made alone
+type Interference struct {
+	Name string `json:"name"`
+
+	Rps         float64 `json:"rps"`
+	BaselineRps float64 `json:"baselineRps"`
+
+	Average         time.Duration `json:"average"`
+	BaselineAverage time.Duration `json:"baselineAverage"`
+
+	P99         time.Duration `json:"p99"`
+	BaselineP99 time.Duration `json:"baselineP99"`
+
+	// the percentages of the calls which failed
+	ErrorRate         float64 `json:"errorRate"`
+	BaselineErrorRate float64 `json:"baselineErrorRate"`
+
+	// the relative changes of the results made in parallel from the baseline, such as 0.25
+	// for an average latency 25% higher when the calls are made in parallel
+	RpsChange     float64 `json:"rpsChange"`
+	AverageChange float64 `json:"averageChange"`
+	P99Change     float64 `json:"p99Change"`
+}
+
+// RunParallel makes the runs in parallel, each with its own connections, workers and load,
+// and returns a separate report for each of them. With a baseline the runs are first made
+// one by one, and the interference of the runs made in parallel is compared to their baseline.
+// The reports made before an error are returned along with the error.
+//
+//	report, err := runner.RunParallel([]runner.ParallelRun{
+//		{Call: "helloworld.Greeter.SayHello", Host: "localhost:50051", Options: []runner.Option{runner.WithRPS(100)}},
+//		{Call: "helloworld.Greeter.ListGreetings", Host: "localhost:50051", Options: []runner.Option{runner.WithRPS(10)}},
+//	}, true)
+func RunParallel(runs []ParallelRun, baseline bool) (*ParallelReport, error) {
+	if len(runs) < 2 {
+		return nil, fmt.Errorf("parallel runs require at least 2 calls")
+	}
+
+	report := &ParallelReport{}
+
+	if baseline {
+		for _, r := range runs {
+			rep, err := runParallelCall(r)
+			if rep != nil {
+				report.Baseline = append(report.Baseline, rep)
+			}
+
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	report.Reports = make([]*Report, len(runs))
+	errs := make([]error, len(runs))
+
+	var wg sync.WaitGroup
+	for i, r := range runs {
+		wg.Add(1)
+
+		go func(i int, r ParallelRun) {
+			defer wg.Done()
+
+			report.Reports[i], errs[i] = runParallelCall(r)
+		}(i, r)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if baseline {
+		report.Interference = make([]Interference, len(runs))
+		for i, rep := range report.Reports {
+			report.Interference[i] = interference(report.Baseline[i], rep)
+		}
+	}
+
+	return report, nil
+}
+
+// runParallelCall makes the run, naming the report after the run
+func runParallelCall(r ParallelRun) (*Report, error) {
+	// the thresholds of each run are checked using its report
+	rep, err := Run(r.Call, r.Host, r.Options...)
+	if errors.Is(err, ErrThresholdsFailed) {
+		err = nil
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%s: %v", parallelName(r), err)
+	}
+
+	if rep != nil {
+		rep.Name = parallelName(r)
+	}
+
+	return rep, err
+}
+
+func parallelName(r ParallelRun) string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return r.Call
+}
+
+// interference compares the report of the run made in parallel to the report of the run made alone
+func interference(base, rep *Report) Interference {
+	in := Interference{
+		Name:              rep.Name,
+		Rps:               rep.Rps,
+		BaselineRps:       base.Rps,
+		Average:           rep.Average,
+		BaselineAverage:   base.Average,
+		P99:               reportP99(rep),
+		BaselineP99:       reportP99(base),
+		ErrorRate:         errorRate(rep),
+		BaselineErrorRate: errorRate(base),
+	}
+
+	in.RpsChange = relativeChange(in.BaselineRps, in.Rps)
+	in.AverageChange = relativeChange(float64(in.BaselineAverage), float64(in.Average))
+	in.P99Change = relativeChange(float64(in.BaselineP99), float64(in.P99))
+
+	return in
+}
+
+func reportP99(r *Report) time.Duration {
+	for _, ld := range r.LatencyDistribution {
+		if ld.Percentage == 99 {
+			return ld.Latency
+		}
+	}
+
+	return 0
+}
+
+func errorRate(r *Report) float64 {
+	if r.Count == 0 {
+		return 0
+	}
+
+	errs := 0
+	for _, n := range r.ErrorDist {
+		errs += n
+	}
+
+	return float64(errs) / float64(r.Count) * 100
+}
+
+func relativeChange(base, v float64) float64 {
+	if base == 0 {
+		return 0
+	}
+
+	return (v - base) / base
+}
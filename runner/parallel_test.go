@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelCall_Config(t *testing.T) {
+	base := &Config{
+		Name:             "base",
+		Call:             "helloworld.Greeter.SayHello",
+		Host:             "localhost:50051",
+		Data:             map[string]interface{}{"name": "Bob"},
+		DataPath:         "data.json",
+		Metadata:         map[string]string{"token": "abc"},
+		C:                50,
+		N:                200,
+		RPS:              100,
+		Insecure:         true,
+		Parallel:         []ParallelCall{{RPS: 10}, {RPS: 20}},
+		ParallelBaseline: true,
+	}
+
+	t.Run("inherited", func(t *testing.T) {
+		pc := &ParallelCall{RPS: 10}
+		cfg := pc.Config(base)
+
+		assert.Equal(t, "helloworld.Greeter.SayHello", cfg.Name)
+		assert.Equal(t, "helloworld.Greeter.SayHello", cfg.Call)
+		assert.Equal(t, "localhost:50051", cfg.Host)
+		assert.Equal(t, "data.json", cfg.DataPath)
+		assert.Equal(t, uint(50), cfg.C)
+		assert.Equal(t, uint(200), cfg.N)
+		assert.Equal(t, uint(10), cfg.RPS)
+		assert.True(t, cfg.Insecure)
+		assert.Nil(t, cfg.Parallel)
+		assert.False(t, cfg.ParallelBaseline)
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		pc := &ParallelCall{
+			Name:     "other",
+			Call:     "helloworld.Greeter.SayHelloCS",
+			Host:     "localhost:50052",
+			Data:     []interface{}{map[string]interface{}{"name": "Kate"}},
+			Metadata: map[string]string{"token": "def"},
+			C:        5,
+			N:        20,
+			Z:        Duration(time.Minute),
+		}
+		cfg := pc.Config(base)
+
+		assert.Equal(t, "other", cfg.Name)
+		assert.Equal(t, "helloworld.Greeter.SayHelloCS", cfg.Call)
+		assert.Equal(t, "localhost:50052", cfg.Host)
+		assert.Equal(t, pc.Data, cfg.Data)
+		assert.Empty(t, cfg.DataPath)
+		assert.Equal(t, map[string]string{"token": "def"}, cfg.Metadata)
+		assert.Equal(t, uint(5), cfg.C)
+		assert.Equal(t, uint(20), cfg.N)
+		assert.Equal(t, uint(100), cfg.RPS)
+		assert.Equal(t, Duration(time.Minute), cfg.Z)
+
+		// the config is not changed
+		assert.Equal(t, "base", base.Name)
+		assert.Len(t, base.Parallel, 2)
+	})
+}
+
+func TestParallel_interference(t *testing.T) {
+	base := &Report{
+		Count:               100,
+		Rps:                 200,
+		Average:             10 * time.Millisecond,
+		LatencyDistribution: []LatencyDistribution{{Percentage: 99, Latency: 20 * time.Millisecond}},
+		ErrorDist:           map[string]int{},
+	}
+
+	rep := &Report{
+		Name:                "hello",
+		Count:               100,
+		Rps:                 150,
+		Average:             15 * time.Millisecond,
+		LatencyDistribution: []LatencyDistribution{{Percentage: 99, Latency: 40 * time.Millisecond}},
+		ErrorDist:           map[string]int{"rpc error: code = Unavailable": 2, "rpc error: code = Internal": 3},
+	}
+
+	assert.Equal(t, Interference{
+		Name:              "hello",
+		Rps:               150,
+		BaselineRps:       200,
+		Average:           15 * time.Millisecond,
+		BaselineAverage:   10 * time.Millisecond,
+		P99:               40 * time.Millisecond,
+		BaselineP99:       20 * time.Millisecond,
+		ErrorRate:         5,
+		BaselineErrorRate: 0,
+		RpsChange:         -0.25,
+		AverageChange:     0.5,
+		P99Change:         1,
+	}, interference(base, rep))
+}
+
+func TestRunParallel(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	run := func(name string, n uint) ParallelRun {
+		return ParallelRun{
+			Name: name,
+			Call: "helloworld.Greeter.SayHello",
+			Host: internal.TestLocalhost,
+			Options: []Option{
+				WithProtoFile("../testdata/greeter.proto", []string{}),
+				WithTotalRequests(n),
+				WithConcurrency(2),
+				WithData(map[string]interface{}{"name": "bob"}),
+				WithInsecure(true),
+			},
+		}
+	}
+
+	t.Run("with baseline", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := RunParallel([]ParallelRun{run("first", 10), run("", 20)}, true)
+
+		assert.NoError(t, err)
+		assert.Len(t, report.Baseline, 2)
+		assert.Len(t, report.Reports, 2)
+		assert.Len(t, report.Interference, 2)
+
+		assert.Equal(t, "first", report.Reports[0].Name)
+		assert.Equal(t, uint64(10), report.Reports[0].Count)
+		assert.Equal(t, "helloworld.Greeter.SayHello", report.Reports[1].Name)
+		assert.Equal(t, uint64(20), report.Reports[1].Count)
+		assert.Equal(t, uint64(20), report.Baseline[1].Count)
+		assert.Equal(t, "first", report.Interference[0].Name)
+
+		assert.Equal(t, 60, gs.GetCount(helloworld.Unary))
+	})
+
+	t.Run("without baseline", func(t *testing.T) {
+		report, err := RunParallel([]ParallelRun{run("first", 10), run("second", 10)}, false)
+
+		assert.NoError(t, err)
+		assert.Empty(t, report.Baseline)
+		assert.Empty(t, report.Interference)
+		assert.Len(t, report.Reports, 2)
+	})
+
+	t.Run("invalid call", func(t *testing.T) {
+		bad := run("bad", 10)
+		bad.Call = "helloworld.Greeter.Missing"
+
+		report, err := RunParallel([]ParallelRun{run("first", 10), bad}, false)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "bad: ")
+		assert.Nil(t, report.Reports[1])
+	})
+
+	t.Run("single call", func(t *testing.T) {
+		_, err := RunParallel([]ParallelRun{run("first", 10)}, false)
+
+		assert.EqualError(t, err, "parallel runs require at least 2 calls")
+	})
+}
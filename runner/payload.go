@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// defaultChunkSize is the size of the chunks of the payload file if it is not set
+const defaultChunkSize = 1 << 20
+
+// UploadStats holds the upload throughput of the payload file streamed by the client streaming calls
+type UploadStats struct {
+	File      string `json:"file"`
+	Size      int64  `json:"size"`
+	ChunkSize int    `json:"chunkSize"`
+
+	// Chunks is the number of messages each upload of the payload is streamed in
+	Chunks int `json:"chunks"`
+
+	// Uploads is the number of calls which completed the upload successfully
+	Uploads uint64 `json:"uploads"`
+
+	// Throughput is the bytes of the payload uploaded per second by all the calls of the run,
+	// and CallThroughput those of a single call, by the average latency of the calls
+	Throughput     float64 `json:"throughput"`
+	CallThroughput float64 `json:"callThroughput"`
+}
+
+// chunkedPayload streams a large payload file in chunks set to a bytes field of the messages of
+// client streaming calls. The file is memory-mapped rather than loaded, so the chunks are slices
+// of the mapping shared by all the workers, and only the pages being sent are read into memory.
+type chunkedPayload struct {
+	path      string
+	data      []byte
+	field     *desc.FieldDescriptor
+	chunkSize int
+	unmap     func() error
+}
+
+func newChunkedPayload(mtd *desc.MethodDescriptor, path, field string, chunkSize int) (*chunkedPayload, error) {
+	if !mtd.IsClientStreaming() {
+		return nil, fmt.Errorf("a chunked payload requires a client streaming method")
+	}
+
+	fd := mtd.GetInputType().FindFieldByName(field)
+	if fd == nil {
+		return nil, fmt.Errorf("payload field %q not found in %s", field, mtd.GetInputType().GetFullyQualifiedName())
+	}
+
+	if fd.GetType() != descriptor.FieldDescriptorProto_TYPE_BYTES || fd.IsRepeated() {
+		return nil, fmt.Errorf("payload field %q must be a bytes field", field)
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("payload file %s is empty", path)
+	}
+
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("payload file %s is too large to map", path)
+	}
+
+	data, unmap, err := mapFile(file, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("error mapping payload file %s: %v", path, err)
+	}
+
+	return &chunkedPayload{path: path, data: data, field: fd, chunkSize: chunkSize, unmap: unmap}, nil
+}
+
+// chunks returns the number of chunks of the payload
+func (p *chunkedPayload) chunks() int {
+	return (len(p.data) + p.chunkSize - 1) / p.chunkSize
+}
+
+// provider returns the message provider of a call, streaming the chunks of the payload in the
+// messages made of the input of the call, the last chunk being the last message of the stream
+func (p *chunkedPayload) provider(input *dynamic.Message) StreamMessageProviderFunc {
+	offset := 0
+
+	return func(*CallData) (*dynamic.Message, error) {
+		if offset >= len(p.data) {
+			return nil, ErrEndStream
+		}
+
+		end := offset + p.chunkSize
+		if end > len(p.data) {
+			end = len(p.data)
+		}
+
+		msg := dynamic.NewMessage(p.field.GetOwner())
+		if input != nil {
+			if err := msg.MergeFrom(input); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := msg.TrySetField(p.field, p.data[offset:end]); err != nil {
+			return nil, err
+		}
+
+		offset = end
+
+		if offset == len(p.data) {
+			return msg, ErrLastMessage
+		}
+
+		return msg, nil
+	}
+}
+
+// stats returns the upload throughput of the calls of the report
+func (p *chunkedPayload) stats(report *Report) *UploadStats {
+	s := &UploadStats{
+		File:      p.path,
+		Size:      int64(len(p.data)),
+		ChunkSize: p.chunkSize,
+		Chunks:    p.chunks(),
+		Uploads:   uint64(report.StatusCodeDist["OK"]),
+	}
+
+	if report.Total > 0 {
+		s.Throughput = float64(s.Size) * float64(s.Uploads) / report.Total.Seconds()
+	}
+
+	if s.Uploads > 0 && report.Average > 0 {
+		s.CallThroughput = float64(s.Size) / report.Average.Seconds()
+	}
+
+	return s
+}
+
+func (p *chunkedPayload) close() {
+	if p != nil {
+		_ = p.unmap()
+	}
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bojand/ghz/internal/echo"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// writePayload writes a payload file of the size, returning its path
+func writePayload(t *testing.T, size int) string {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	dir, err := ioutil.TempDir("", "ghz-payload")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "payload.bin")
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	return path
+}
+
+func echoMethod(t *testing.T, name string) *desc.MethodDescriptor {
+	p := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"echo.proto": echo.Proto}),
+	}
+
+	fds, err := p.ParseFiles("echo.proto")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	return fds[0].FindService(echo.ServiceName).FindMethodByName(name)
+}
+
+func TestChunkedPayload(t *testing.T) {
+	path := writePayload(t, 10)
+	mtd := echoMethod(t, "EchoClientStream")
+
+	p, err := newChunkedPayload(mtd, path, "payload", 4)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer p.close()
+
+	assert.Equal(t, 3, p.chunks())
+
+	input := dynamic.NewMessage(mtd.GetInputType())
+	input.SetFieldByName("message", "upload")
+
+	provider := p.provider(input)
+
+	var chunks [][]byte
+	for {
+		msg, err := provider(nil)
+		if err == ErrEndStream {
+			break
+		}
+
+		assert.Equal(t, "upload", msg.GetFieldByName("message"))
+		chunks = append(chunks, msg.GetFieldByName("payload").([]byte))
+
+		if err == ErrLastMessage {
+			break
+		}
+
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, [][]byte{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}, chunks)
+
+	// the input of the call is left as is
+	assert.False(t, input.HasFieldName("payload"))
+
+	_, err = newChunkedPayload(echoMethod(t, "Echo"), path, "payload", 4)
+	assert.EqualError(t, err, "a chunked payload requires a client streaming method")
+
+	_, err = newChunkedPayload(mtd, path, "message", 4)
+	assert.EqualError(t, err, `payload field "message" must be a bytes field`)
+
+	_, err = newChunkedPayload(mtd, path, "data", 4)
+	assert.EqualError(t, err, `payload field "data" not found in echo.EchoRequest`)
+}
+
+func TestRunChunkedPayload(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	svc, err := echo.New(echo.Options{})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	s := grpc.NewServer()
+	if err := svc.Register(s); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	reflection.Register(s)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	path := writePayload(t, 100000)
+
+	report, err := Run(
+		"echo.Echo.EchoClientStream",
+		lis.Addr().String(),
+		WithTotalRequests(4),
+		WithConcurrency(2),
+		WithChunkedPayload(path, "payload", 16*1024),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"OK": 4}, report.StatusCodeDist)
+
+	if assert.NotNil(t, report.Upload) {
+		assert.Equal(t, int64(100000), report.Upload.Size)
+		assert.Equal(t, 16*1024, report.Upload.ChunkSize)
+		assert.Equal(t, 7, report.Upload.Chunks)
+		assert.Equal(t, uint64(4), report.Upload.Uploads)
+		assert.True(t, report.Upload.Throughput > 0)
+		assert.True(t, report.Upload.CallThroughput > 0)
+	}
+
+	for _, d := range report.Details {
+		assert.True(t, d.BytesSent > 100000, "bytes sent %d", d.BytesSent)
+	}
+
+	_, err = Run(
+		"echo.Echo.EchoClientStream",
+		lis.Addr().String(),
+		WithChunkedPayload(path, "payload", 0),
+		WithStreamCallCount(2),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "a chunked payload cannot be used with a scenario, async, stream message providers, a stream call count or dynamic stream messages")
+}
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchedulePhase holds the statistics of the calls started during a phase of the load
+// or concurrency schedule, that is while the scheduled rate and concurrency did not change
+type SchedulePhase struct {
+	Phase int `json:"phase"`
+
+	// Start is the start of the phase relative to the start of the run
+	Start    time.Duration `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	// The scheduled concurrency and rate of the phase. The rate is 0 if unlimited.
+	Concurrency int     `json:"concurrency"`
+	TargetRPS   float64 `json:"targetRps"`
+
+	Count  uint64 `json:"count"`
+	Errors uint64 `json:"errors"`
+
+	// Rps is the rate of the calls started during the phase
+	Rps                 float64               `json:"rps"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// phaseChange is a change of the scheduled concurrency or rate
+type phaseChange struct {
+	at          time.Duration
+	concurrency int
+	rps         float64
+}
+
+// phaseTracker records the changes of the scheduled concurrency and rate while running,
+// so the calls can be attributed to the phases of the schedule
+type phaseTracker struct {
+	start time.Time
+
+	// the rate of the load schedule at the elapsed time, nil for constant load
+	rate func(time.Duration) float64
+
+	lock    sync.Mutex
+	changes []phaseChange
+}
+
+// newPhaseTracker returns the phase tracker of the run, or nil if both the load
+// and the concurrency schedules are constant
+func newPhaseTracker(c *RunConfig, start time.Time) *phaseTracker {
+	if c.loadSchedule == ScheduleConst && c.cSchedule == ScheduleConst {
+		return nil
+	}
+
+	first := phaseChange{concurrency: c.c, rps: float64(c.rps)}
+	if c.cSchedule != ScheduleConst {
+		first.concurrency = int(c.cStart)
+	}
+
+	if c.loadSchedule != ScheduleConst {
+		first.rps = float64(c.loadStart)
+	}
+
+	return &phaseTracker{start: start, changes: []phaseChange{first}}
+}
+
+// setConcurrency records the scheduled concurrency, starting a new phase if it changed
+func (t *phaseTracker) setConcurrency(n int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	last := t.changes[len(t.changes)-1]
+	if last.concurrency != n {
+		t.changes = append(t.changes, phaseChange{at: time.Since(t.start), concurrency: n, rps: last.rps})
+	}
+}
+
+// pace records the rate of the load schedule at the elapsed time of the pacer,
+// starting a new phase if it changed
+func (t *phaseTracker) pace(elapsed time.Duration) {
+	if t.rate == nil {
+		return
+	}
+
+	rps := math.Round(t.rate(elapsed)*100) / 100
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	last := t.changes[len(t.changes)-1]
+	if last.rps != rps {
+		t.changes = append(t.changes, phaseChange{at: time.Since(t.start), concurrency: last.concurrency, rps: rps})
+	}
+}
+
+// stats returns the statistics of the phases of the run, with the calls of the details
+// attributed to the phase during which they were started
+func (t *phaseTracker) stats(details detailIter, total time.Duration, countErrors bool) []SchedulePhase {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	phases := make([]SchedulePhase, len(t.changes))
+	lats := make([][]float64, len(t.changes))
+
+	for i, c := range t.changes {
+		end := total
+		if i+1 < len(t.changes) {
+			end = t.changes[i+1].at
+		}
+
+		phases[i] = SchedulePhase{
+			Phase:       i + 1,
+			Start:       c.at,
+			Duration:    end - c.at,
+			Concurrency: c.concurrency,
+			TargetRPS:   c.rps,
+		}
+	}
+
+	details.each(func(d *ResultDetail) {
+		started := d.Timestamp.Add(-d.Latency).Sub(t.start)
+
+		i := sort.Search(len(t.changes), func(i int) bool { return t.changes[i].at > started }) - 1
+		if i < 0 {
+			i = 0
+		}
+
+		phases[i].Count++
+
+		if d.Error != "" {
+			phases[i].Errors++
+
+			if !countErrors {
+				return
+			}
+		}
+
+		lats[i] = append(lats[i], d.Latency.Seconds())
+	})
+
+	for i := range phases {
+		p := &phases[i]
+
+		if p.Duration > 0 {
+			p.Rps = float64(p.Count) / p.Duration.Seconds()
+		}
+
+		if len(lats[i]) == 0 {
+			continue
+		}
+
+		sort.Float64s(lats[i])
+
+		var sum float64
+		for _, l := range lats[i] {
+			sum += l
+		}
+
+		p.Average = time.Duration(sum / float64(len(lats[i])) * float64(time.Second))
+		p.Fastest = time.Duration(lats[i][0] * float64(time.Second))
+		p.Slowest = time.Duration(lats[i][len(lats[i])-1] * float64(time.Second))
+		p.LatencyDistribution = latencies(lats[i])
+	}
+
+	return phases
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseTracker(t *testing.T) {
+	t.Run("constant schedules", func(t *testing.T) {
+		assert.Nil(t, newPhaseTracker(&RunConfig{c: 10, loadSchedule: ScheduleConst, cSchedule: ScheduleConst}, time.Now()))
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		start := time.Now()
+		pt := newPhaseTracker(&RunConfig{c: 10, loadSchedule: ScheduleConst, cSchedule: ScheduleStep, cStart: 5}, start)
+
+		pt.setConcurrency(5)
+		pt.changes = append(pt.changes, phaseChange{at: time.Second, concurrency: 10})
+
+		details := []ResultDetail{
+			{Timestamp: start.Add(200 * time.Millisecond), Latency: 100 * time.Millisecond},
+			{Timestamp: start.Add(1100 * time.Millisecond), Latency: 200 * time.Millisecond},
+			{Timestamp: start.Add(1200 * time.Millisecond), Latency: 100 * time.Millisecond},
+			{Timestamp: start.Add(1500 * time.Millisecond), Latency: 10 * time.Millisecond, Error: "unavailable"},
+		}
+
+		phases := pt.stats(detailIter{details: details}, 3*time.Second, false)
+		if assert.Len(t, phases, 2) {
+			assert.Equal(t, 1, phases[0].Phase)
+			assert.Equal(t, 5, phases[0].Concurrency)
+			assert.Equal(t, time.Second, phases[0].Duration)
+			assert.Equal(t, uint64(2), phases[0].Count)
+			assert.Equal(t, 2.0, phases[0].Rps)
+			assert.Equal(t, 150*time.Millisecond, phases[0].Average)
+
+			assert.Equal(t, 2, phases[1].Phase)
+			assert.Equal(t, 10, phases[1].Concurrency)
+			assert.Equal(t, time.Second, phases[1].Start)
+			assert.Equal(t, 2*time.Second, phases[1].Duration)
+			assert.Equal(t, uint64(2), phases[1].Count)
+			assert.Equal(t, uint64(1), phases[1].Errors)
+			assert.Equal(t, 100*time.Millisecond, phases[1].Slowest)
+			assert.NotEmpty(t, phases[1].LatencyDistribution)
+		}
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		pt := newPhaseTracker(&RunConfig{c: 1, loadSchedule: ScheduleStep, cSchedule: ScheduleConst, loadStart: 10}, time.Now())
+		pt.rate = func(elapsed time.Duration) float64 { return float64(10 + 10*int(elapsed/time.Second)) }
+
+		pt.pace(0)
+		pt.pace(500 * time.Millisecond)
+		pt.pace(time.Second)
+		pt.pace(2 * time.Second)
+
+		if assert.Len(t, pt.changes, 3) {
+			assert.Equal(t, 10.0, pt.changes[0].rps)
+			assert.Equal(t, 20.0, pt.changes[1].rps)
+			assert.Equal(t, 30.0, pt.changes[2].rps)
+		}
+	})
+}
+
+func TestRunSchedulePhases(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithRunDuration(1200*time.Millisecond),
+		WithRPS(50),
+		WithConcurrencySchedule(ScheduleStep),
+		WithConcurrencyStart(1),
+		WithConcurrencyStep(1),
+		WithConcurrencyEnd(3),
+		WithConcurrencyStepDuration(500*time.Millisecond),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.Len(t, report.Phases, 3) {
+		var count uint64
+		for i, p := range report.Phases {
+			assert.Equal(t, i+1, p.Concurrency)
+			assert.Equal(t, 50.0, p.TargetRPS)
+			count += p.Count
+		}
+
+		assert.Equal(t, report.Count, count)
+		assert.Equal(t, time.Duration(0), report.Phases[0].Start)
+		assert.InDelta(t, 500*time.Millisecond, report.Phases[1].Start, float64(100*time.Millisecond))
+	}
+}
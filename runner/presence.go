@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// hasPresence returns whether setting the scalar field to its default value is distinguishable
+// from leaving it unset, which is the case for proto3 optional fields and proto2 optional fields.
+// The other fields of a oneof are not included as setting them would clear the set field.
+func hasPresence(fd *desc.FieldDescriptor) bool {
+	if fd.IsRepeated() || fd.GetMessageType() != nil {
+		return false
+	}
+
+	if fd.AsFieldDescriptorProto().GetProto3Optional() {
+		return true
+	}
+
+	return !fd.GetFile().IsProto3() && fd.GetOneOf() == nil
+}
+
+// emitDefaults sets the unset fields with presence of the messages, including the nested
+// messages, to their default values, so the default values are sent explicitly
+func emitDefaults(msgs []*dynamic.Message) {
+	for _, msg := range msgs {
+		emitMessageDefaults(msg)
+	}
+}
+
+func emitMessageDefaults(msg *dynamic.Message) {
+	if msg == nil {
+		return
+	}
+
+	for _, fd := range msg.GetMessageDescriptor().GetFields() {
+		if hasPresence(fd) {
+			if !msg.HasField(fd) {
+				msg.SetField(fd, fd.GetDefaultValue())
+			}
+
+			continue
+		}
+
+		if fd.GetMessageType() == nil || !msg.HasField(fd) {
+			continue
+		}
+
+		switch v := msg.GetField(fd).(type) {
+		case *dynamic.Message:
+			emitMessageDefaults(v)
+		case []interface{}:
+			for _, e := range v {
+				if m, ok := e.(*dynamic.Message); ok {
+					emitMessageDefaults(m)
+				}
+			}
+		case map[interface{}]interface{}:
+			for _, e := range v {
+				if m, ok := e.(*dynamic.Message); ok {
+					emitMessageDefaults(m)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresence(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("optional.Optional/Update", "../testdata/optional.proto", []string{})
+	assert.NoError(t, err)
+
+	mtdProto3, err := protodesc.GetMethodDescFromProto("helloworld.Greeter/SayHello", "../testdata/greeter.proto", []string{})
+	assert.NoError(t, err)
+
+	input := mtd.GetInputType()
+
+	t.Run("hasPresence", func(t *testing.T) {
+		assert.True(t, hasPresence(input.FindFieldByName("count")))
+		assert.True(t, hasPresence(input.FindFieldByName("mode")))
+		assert.False(t, hasPresence(input.FindFieldByName("id")))
+		assert.False(t, hasPresence(input.FindFieldByName("name")))
+		assert.False(t, hasPresence(input.FindFieldByName("limits")))
+		assert.False(t, hasPresence(input.FindFieldByName("history")))
+		assert.False(t, hasPresence(mtdProto3.GetInputType().FindFieldByName("name")))
+	})
+
+	t.Run("unset and zero values", func(t *testing.T) {
+		inputs, err := createPayloadsFromJSON(`{"id":"a","count":0,"note":""}`, mtd)
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 1)
+
+		msg := inputs[0]
+		assert.True(t, msg.HasField(input.FindFieldByName("count")))
+		assert.True(t, msg.HasField(input.FindFieldByName("note")))
+		assert.False(t, msg.HasField(input.FindFieldByName("enabled")))
+		assert.False(t, msg.HasField(input.FindFieldByName("mode")))
+
+		b, err := msg.Marshal()
+		assert.NoError(t, err)
+
+		// id, count and note are sent
+		res := dynamic.NewMessage(input)
+		assert.NoError(t, res.Unmarshal(b))
+		assert.True(t, res.HasField(input.FindFieldByName("count")))
+		assert.True(t, res.HasField(input.FindFieldByName("note")))
+		assert.False(t, res.HasField(input.FindFieldByName("enabled")))
+		assert.Len(t, b, 7)
+	})
+
+	t.Run("emitDefaults", func(t *testing.T) {
+		inputs, err := createPayloadsFromJSON(`{"id":"a","count":5,"limits":{},"history":[{"min":1},{"max":2}],"name":"n"}`, mtd)
+		assert.NoError(t, err)
+
+		emitDefaults(inputs)
+
+		msg := inputs[0]
+		assert.Equal(t, int32(5), msg.GetFieldByName("count"))
+		assert.True(t, msg.HasField(input.FindFieldByName("enabled")))
+		assert.Equal(t, false, msg.GetFieldByName("enabled"))
+		assert.True(t, msg.HasField(input.FindFieldByName("note")))
+		assert.True(t, msg.HasField(input.FindFieldByName("mode")))
+		assert.Equal(t, "n", msg.GetFieldByName("name"))
+		assert.False(t, msg.HasField(input.FindFieldByName("number")))
+
+		limits := input.FindFieldByName("limits").GetMessageType()
+		max := limits.FindFieldByName("max")
+		assert.True(t, msg.GetFieldByName("limits").(*dynamic.Message).HasField(max))
+
+		history := msg.GetFieldByName("history").([]interface{})
+		assert.Len(t, history, 2)
+		assert.Equal(t, uint32(0), history[0].(*dynamic.Message).GetField(max))
+		assert.True(t, history[0].(*dynamic.Message).HasField(max))
+		assert.Equal(t, uint32(2), history[1].(*dynamic.Message).GetField(max))
+	})
+
+	t.Run("data provider", func(t *testing.T) {
+		dp, err := newDataProvider(mtd, false, nil, []byte(`{"id":"a"}`), nil, "", true)
+		assert.NoError(t, err)
+
+		inputs, err := dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.NoError(t, err)
+		assert.Len(t, inputs, 1)
+		assert.True(t, inputs[0].HasField(input.FindFieldByName("count")))
+
+		dp, err = newDataProvider(mtd, false, nil, []byte(`{"id":"a"}`), nil, "", false)
+		assert.NoError(t, err)
+
+		inputs, err = dp.getDataForCall(newCallData(mtd, nil, "", 0))
+		assert.NoError(t, err)
+		assert.False(t, inputs[0].HasField(input.FindFieldByName("count")))
+	})
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// progressRecord is a single line JSON progress record of a running test
+type progressRecord struct {
+	Snapshot
+
+	// CurrentRps is the rate of the calls completed since the previous record
+	CurrentRps float64 `json:"currentRps"`
+
+	// Done is set on the last record, written once the test is done
+	Done bool `json:"done,omitempty"`
+}
+
+// progressWriter writes the progress of the running test as JSON lines every interval,
+// so wrappers can display the progress without parsing the human readable output
+type progressWriter struct {
+	interval time.Duration
+	reqr     *Requester
+	enc      *json.Encoder
+
+	last Snapshot
+}
+
+func newProgressWriter(enabled bool, interval time.Duration, reqr *Requester, out io.Writer) *progressWriter {
+	if !enabled {
+		return nil
+	}
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	return &progressWriter{interval: interval, reqr: reqr, enc: json.NewEncoder(out)}
+}
+
+// watch writes a record every interval until done is closed, and then the last record
+func (w *progressWriter) watch(done <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			w.write(w.reqr.Snapshot(), true)
+			return
+		case <-ticker.C:
+			w.write(w.reqr.Snapshot(), false)
+		}
+	}
+}
+
+func (w *progressWriter) write(s Snapshot, done bool) {
+	r := progressRecord{Snapshot: s, Done: done}
+
+	if d := s.Elapsed - w.last.Elapsed; d > 0 && s.Count >= w.last.Count {
+		r.CurrentRps = float64(s.Count-w.last.Count) / d.Seconds()
+	}
+
+	w.last = s
+
+	_ = w.enc.Encode(r)
+}
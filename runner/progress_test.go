@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressWriter_write(t *testing.T) {
+	var buf bytes.Buffer
+	w := newProgressWriter(true, 0, nil, &buf)
+
+	assert.Equal(t, time.Second, w.interval)
+
+	w.write(Snapshot{Elapsed: time.Second, Count: 100, Rps: 100}, false)
+	w.write(Snapshot{Elapsed: 3 * time.Second, Count: 500, ErrorCount: 2, Rps: 500.0 / 3}, true)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.JSONEq(t, `{"seq":0,"elapsed":1000000000,"count":100,"errorCount":0,"rps":100,"currentRps":100}`, lines[0])
+
+		var r progressRecord
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &r))
+		assert.Equal(t, uint64(500), r.Count)
+		assert.Equal(t, uint64(2), r.ErrorCount)
+		assert.Equal(t, 200.0, r.CurrentRps)
+		assert.True(t, r.Done)
+	}
+
+	assert.Nil(t, newProgressWriter(false, time.Second, nil, &buf))
+}
+
+func TestRunProgress(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	var buf bytes.Buffer
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(30),
+		WithConcurrency(1),
+		WithRPS(100),
+		WithProgress(true, 100*time.Millisecond),
+		WithDebugOutput(&buf),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.True(t, len(lines) > 1, "%d records", len(lines))
+
+	var last progressRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.True(t, last.Done)
+	assert.Equal(t, report.Count, last.Count)
+}
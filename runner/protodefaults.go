@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// The built-in defaults of the options which the defaults of the protos can replace
+const (
+	defaultTotal       = 200
+	defaultConcurrency = 50
+	defaultTimeout     = 20 * time.Second
+)
+
+// applyProtoDefaults applies the defaults set by the ghz options of the method and its service
+// to the options which were left at their built-in defaults, returning the names of the options set
+func applyProtoDefaults(c *RunConfig, mtd *desc.MethodDescriptor) ([]string, error) {
+	d, err := protodesc.GetMethodDefaults(mtd)
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	var applied []string
+
+	if d.Timeout > 0 && c.timeout == defaultTimeout {
+		c.timeout = d.Timeout
+		applied = append(applied, "timeout")
+	}
+
+	// the total and the duration only apply if neither is set
+	if c.n == defaultTotal && c.z == 0 && !c.untilSignal {
+		if d.Duration > 0 {
+			c.z = d.Duration
+			c.n = math.MaxInt32
+			applied = append(applied, "duration")
+		} else if d.Total > 0 {
+			c.n = int(d.Total)
+			applied = append(applied, "total")
+		}
+	}
+
+	if d.Concurrency >= uint(c.nConns) && c.c == defaultConcurrency {
+		c.c = int(d.Concurrency)
+		applied = append(applied, "concurrency")
+	}
+
+	if d.RPS > 0 && c.rps == 0 && c.loadSchedule == ScheduleConst && c.pacer == nil &&
+		c.latencyTarget == 0 && c.rateSocket == "" {
+		c.rps = int(d.RPS)
+		applied = append(applied, "rps")
+	}
+
+	if d.Data != "" && unsetJSON(c.data) && c.dataFunc == nil && c.dataProviderFunc == nil &&
+		c.dataReader == nil && c.dataIndexedPath == "" && c.dataStreamFunc == nil {
+		c.data = []byte(d.Data)
+		c.binary = false
+		applied = append(applied, "data")
+	}
+
+	if len(d.Metadata) > 0 && unsetJSON(c.metadata) && c.mdProviderFunc == nil && len(c.metadataLists) == 0 {
+		if c.metadata, err = json.Marshal(d.Metadata); err != nil {
+			return nil, err
+		}
+
+		applied = append(applied, "metadata")
+	}
+
+	return applied, nil
+}
+
+// unsetJSON returns whether the JSON data or metadata is not set, which is the JSON of nil
+// when set from a config without data
+func unsetJSON(b []byte) bool {
+	b = bytes.TrimSpace(b)
+
+	return len(b) == 0 || string(b) == "null"
+}
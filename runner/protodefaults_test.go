@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunProtoDefaults(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("defaults", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/defaults.proto", []string{}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, report) {
+			assert.Equal(t, uint64(12), report.Count)
+			assert.Equal(t, uint(2), report.Options.Concurrency)
+			assert.Equal(t, 2*time.Second, report.Options.Timeout)
+			assert.Equal(t, []string{"timeout", "total", "concurrency", "data", "metadata"}, report.Options.ProtoDefaults)
+		}
+
+		calls := gs.GetCalls(helloworld.Unary)
+		assert.Len(t, calls, 12)
+		for _, c := range calls {
+			assert.Equal(t, "bob", c[0].GetName())
+		}
+	})
+
+	t.Run("options take precedence", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/defaults.proto", []string{}),
+			WithTotalRequests(3),
+			WithData(&helloworld.HelloRequest{Name: "alice"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, report) {
+			assert.Equal(t, uint64(3), report.Count)
+			assert.Equal(t, []string{"timeout", "concurrency", "metadata"}, report.Options.ProtoDefaults)
+		}
+
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			assert.Equal(t, "alice", c[0].GetName())
+		}
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/defaults.proto", []string{}),
+			WithTotalRequests(3),
+			WithData(map[string]interface{}{}),
+			WithIgnoreProtoDefaults(true),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, report) {
+			assert.Equal(t, uint64(3), report.Count)
+			assert.Equal(t, defaultTimeout, report.Options.Timeout)
+			assert.Nil(t, report.Options.ProtoDefaults)
+		}
+
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			assert.Equal(t, "", c[0].GetName())
+		}
+	})
+}
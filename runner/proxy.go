@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// connDialer returns the dialer of the connections, which is nil if the connections
+// are dialed by the default dialer of the transport
+func (c *RunConfig) connDialer() func(context.Context, string) (net.Conn, error) {
+	switch {
+	case c.dialer != nil:
+		return c.dialer
+	case c.proxy != nil:
+		return connectDialer(c.proxy, c.resolve, c.ipVersion)
+	case len(c.resolve) > 0 || c.ipVersion != 0:
+		return contextDialer(c.resolve, c.ipVersion)
+	}
+
+	return nil
+}
+
+// connectDialer returns a dialer tunneling the connections through the HTTP proxy using the
+// CONNECT method. The name resolution overrides apply to the address the proxy connects to,
+// and the IP version to the connection to the proxy.
+func connectDialer(proxy *url.URL, resolve map[string]string, ipVersion uint) func(context.Context, string) (net.Conn, error) {
+	network := ipNetwork(ipVersion)
+
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+
+	var auth string
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+pass))
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		addr = resolveAddr(resolve, addr)
+
+		var d net.Dialer
+
+		conn, err := d.DialContext(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+
+		// the handshake with the proxy is bounded by the context of the dial
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+
+		if auth != "" {
+			req.Header.Set("Proxy-Authorization", auth)
+		}
+
+		if err := req.Write(conn); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+
+		br := bufio.NewReader(conn)
+
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+
+		_ = res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("proxy: CONNECT %s: %s", addr, res.Status)
+		}
+
+		_ = conn.SetDeadline(time.Time{})
+
+		// the server may have sent data along with the response of the proxy
+		if br.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+
+		return conn, nil
+	}
+}
+
+// bufferedConn is a connection reading the data buffered before the rest
+type bufferedConn struct {
+	net.Conn
+
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// redactedURL returns the URL with the password replaced, empty if there is no URL
+func redactedURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,381 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The targets the interval statistics can be pushed to
+const (
+	// PushLoki pushes the statistics as JSON log lines to the push API of Loki
+	PushLoki = "loki"
+
+	// PushGrafanaLive pushes the statistics in the Influx line protocol to a Grafana Live stream
+	PushGrafanaLive = "grafana-live"
+
+	// PushGraphite pushes the statistics in the plaintext protocol to a Graphite server
+	PushGraphite = "graphite"
+)
+
+// defaultPushInterval is the interval of the pushes when none is set
+const defaultPushInterval = 5 * time.Second
+
+// defaultPushPrefix is the prefix of the metric paths pushed to Graphite when none is set
+const defaultPushPrefix = "ghz"
+
+// pushTimeout is the maximum duration of a push request
+const pushTimeout = 10 * time.Second
+
+// pushedWindow is the log line of the statistics of an interval pushed to Loki
+type pushedWindow struct {
+	RunID string `json:"runId,omitempty"`
+
+	TimeWindow
+}
+
+// statsPusher pushes the statistics of the calls completed within each interval while the run is
+// in progress, so the run can be followed on Grafana dashboards without scraping the live metrics
+type statsPusher struct {
+	target   string
+	url      string
+	interval time.Duration
+	headers  http.Header
+	prefix   string
+
+	runID, call, name string
+
+	client *http.Client
+	log    Logger
+
+	// the start of the run, and the calls completed within the current interval
+	start  time.Time
+	lock   sync.Mutex
+	bucket *timeBucket
+
+	// the number of active workers
+	workers int64
+}
+
+func newStatsPusher(c *RunConfig) *statsPusher {
+	if c.pushTarget == "" {
+		return nil
+	}
+
+	interval := c.pushInterval
+	if interval <= 0 {
+		interval = defaultPushInterval
+	}
+
+	headers := http.Header{}
+	for k, v := range c.pushHeaders {
+		headers.Set(k, v)
+	}
+
+	prefix := c.pushPrefix
+	if prefix == "" {
+		prefix = defaultPushPrefix
+	}
+
+	p := &statsPusher{
+		target:   c.pushTarget,
+		url:      c.pushURL,
+		interval: interval,
+		headers:  headers,
+		prefix:   prefix,
+		runID:    c.runID,
+		call:     c.call,
+		name:     c.name,
+		client:   &http.Client{Timeout: pushTimeout},
+		bucket:   &timeBucket{},
+	}
+
+	if c.hasLog {
+		p.log = c.log
+	}
+
+	return p
+}
+
+// observe records a completed call within the current interval
+func (p *statsPusher) observe(status string, latency time.Duration) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.bucket.count++
+	if status != "OK" {
+		p.bucket.errors++
+	}
+
+	p.bucket.lats = append(p.bucket.lats, latency.Seconds())
+}
+
+// addWorkers adds to the number of active workers
+func (p *statsPusher) addWorkers(n int64) {
+	if p != nil {
+		atomic.AddInt64(&p.workers, n)
+	}
+}
+
+// begin starts pushing the statistics of each interval of the run, returning the function
+// which pushes those of the last interval and waits for the pushes to stop
+func (p *statsPusher) begin(start time.Time) func() {
+	if p == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	pushed := make(chan struct{})
+
+	go func() {
+		p.run(start, done)
+		close(pushed)
+	}()
+
+	return func() {
+		close(done)
+		<-pushed
+	}
+}
+
+// run pushes the statistics of each interval until done is closed, and then those of the last interval
+func (p *statsPusher) run(start time.Time, done <-chan struct{}) {
+	p.start = start
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	last := start
+	for {
+		select {
+		case <-done:
+			p.push(last, time.Now())
+			return
+		case now := <-ticker.C:
+			p.push(last, now)
+			last = now
+		}
+	}
+}
+
+// push pushes the statistics of the calls completed since the start of the interval.
+// The failed pushes are logged and do not affect the run.
+func (p *statsPusher) push(from, to time.Time) {
+	p.lock.Lock()
+	b := p.bucket
+	p.bucket = &timeBucket{}
+	p.lock.Unlock()
+
+	w := intervalWindow(b, from.Sub(p.start), to.Sub(from))
+
+	if err := p.send(w, to); err != nil && p.log != nil {
+		p.log.Errorw("Error pushing the interval statistics: "+err.Error(), "target", p.target, "error", err)
+	}
+}
+
+// intervalWindow returns the statistics of the calls of the bucket completed within the interval
+func intervalWindow(b *timeBucket, start, length time.Duration) TimeWindow {
+	w := TimeWindow{Start: start, Count: b.count, Errors: b.errors}
+
+	if b.count > 0 {
+		w.ErrorRate = float64(b.errors) / float64(b.count)
+	}
+
+	if length > 0 {
+		w.Rps = float64(b.count) / length.Seconds()
+	}
+
+	if len(b.lats) > 0 {
+		sort.Float64s(b.lats)
+
+		for _, ld := range latencies(b.lats) {
+			switch ld.Percentage {
+			case 50:
+				w.P50 = ld.Latency
+			case 95:
+				w.P95 = ld.Latency
+			case 99:
+				w.P99 = ld.Latency
+			}
+		}
+	}
+
+	return w
+}
+
+func (p *statsPusher) send(w TimeWindow, ts time.Time) error {
+	if p.target == PushGraphite {
+		return p.sendGraphite(w, ts)
+	}
+
+	var body []byte
+	var contentType string
+	var err error
+
+	switch p.target {
+	case PushLoki:
+		contentType = "application/json"
+		body, err = p.lokiBody(w, ts)
+	default:
+		contentType = "text/plain"
+		body = p.lineProtocol(w, ts)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range p.headers {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return nil
+}
+
+// lokiBody returns the push request of Loki with the statistics as a JSON log line.
+// The stream is labeled with the job, the call and the name of the run, if any.
+func (p *statsPusher) lokiBody(w TimeWindow, ts time.Time) ([]byte, error) {
+	line, err := json.Marshal(pushedWindow{RunID: p.runID, TimeWindow: w})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{"job": "ghz", "call": p.call}
+	if p.name != "" {
+		labels["name"] = p.name
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"streams": []interface{}{
+			map[string]interface{}{
+				"stream": labels,
+				"values": [][]string{{strconv.FormatInt(ts.UnixNano(), 10), string(line)}},
+			},
+		},
+	})
+}
+
+// lineProtocol returns the statistics in the Influx line protocol accepted by Grafana Live,
+// the latencies being in nanoseconds and the error rate a fraction
+func (p *statsPusher) lineProtocol(w TimeWindow, ts time.Time) []byte {
+	var b strings.Builder
+
+	b.WriteString("ghz,call=")
+	b.WriteString(escapeLineTag(p.call))
+
+	if p.name != "" {
+		b.WriteString(",name=")
+		b.WriteString(escapeLineTag(p.name))
+	}
+
+	if p.runID != "" {
+		b.WriteString(",run_id=")
+		b.WriteString(escapeLineTag(p.runID))
+	}
+
+	fmt.Fprintf(&b, " count=%di,errors=%di,error_rate=%s,rps=%s,p50=%di,p95=%di,p99=%di %d\n",
+		w.Count, w.Errors, strconv.FormatFloat(w.ErrorRate, 'f', -1, 64), strconv.FormatFloat(w.Rps, 'f', -1, 64),
+		w.P50.Nanoseconds(), w.P95.Nanoseconds(), w.P99.Nanoseconds(), ts.UnixNano())
+
+	return []byte(b.String())
+}
+
+// sendGraphite writes the statistics to a new connection to the Graphite server, so a restarted
+// server does not lose the statistics of the rest of the run
+func (p *statsPusher) sendGraphite(w TimeWindow, ts time.Time) error {
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout(u.Scheme, u.Host, pushTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(pushTimeout)); err != nil {
+		return err
+	}
+
+	_, err = conn.Write(p.plaintext(w, ts, atomic.LoadInt64(&p.workers)))
+
+	return err
+}
+
+// plaintext returns the statistics in the plaintext protocol of Graphite, each as a path prefixed
+// with the prefix and the call, the latencies being in milliseconds and the error rate a fraction
+func (p *statsPusher) plaintext(w TimeWindow, ts time.Time, workers int64) []byte {
+	path := p.prefix + "." + graphitePathNode(p.call) + "."
+	sec := ts.Unix()
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%scount %d %d\n", path, w.Count, sec)
+	fmt.Fprintf(&b, "%serrors %d %d\n", path, w.Errors, sec)
+	fmt.Fprintf(&b, "%serror_rate %s %d\n", path, strconv.FormatFloat(w.ErrorRate, 'f', -1, 64), sec)
+	fmt.Fprintf(&b, "%srps %s %d\n", path, strconv.FormatFloat(w.Rps, 'f', -1, 64), sec)
+	fmt.Fprintf(&b, "%sconcurrency %d %d\n", path, workers, sec)
+	fmt.Fprintf(&b, "%sp50 %s %d\n", path, ms(w.P50), sec)
+	fmt.Fprintf(&b, "%sp95 %s %d\n", path, ms(w.P95), sec)
+	fmt.Fprintf(&b, "%sp99 %s %d\n", path, ms(w.P99), sec)
+
+	return []byte(b.String())
+}
+
+// graphitePathNode replaces the characters of the call which are not allowed within a node of a
+// Graphite path, including the dots separating the nodes, with underscores
+func graphitePathNode(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+
+		return '_'
+	}, s)
+}
+
+// escapeLineTag escapes the commas, equal signs and spaces of the tag values of the line protocol
+func escapeLineTag(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
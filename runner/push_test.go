@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+// pushRecorder records the bodies and headers of the pushes
+type pushRecorder struct {
+	lock    sync.Mutex
+	bodies  []string
+	headers []http.Header
+}
+
+func (r *pushRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b, _ := ioutil.ReadAll(req.Body)
+
+	r.lock.Lock()
+	r.bodies = append(r.bodies, string(b))
+	r.headers = append(r.headers, req.Header.Clone())
+	r.lock.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestStatsPusher(t *testing.T) {
+	t.Run("interval window", func(t *testing.T) {
+		w := intervalWindow(&timeBucket{count: 4, errors: 1, lats: []float64{0.04, 0.01, 0.03, 0.02}},
+			10*time.Second, 2*time.Second)
+
+		assert.Equal(t, 10*time.Second, w.Start)
+		assert.Equal(t, uint64(4), w.Count)
+		assert.Equal(t, uint64(1), w.Errors)
+		assert.Equal(t, 0.25, w.ErrorRate)
+		assert.Equal(t, 2.0, w.Rps)
+		assert.Equal(t, 20*time.Millisecond, w.P50)
+		assert.Equal(t, 40*time.Millisecond, w.P99)
+
+		assert.Equal(t, TimeWindow{Start: time.Second}, intervalWindow(&timeBucket{}, time.Second, 0))
+	})
+
+	t.Run("loki", func(t *testing.T) {
+		rec := &pushRecorder{}
+		ts := httptest.NewServer(rec)
+		defer ts.Close()
+
+		p := newStatsPusher(&RunConfig{pushTarget: PushLoki, pushURL: ts.URL, runID: "run1",
+			call: "helloworld.Greeter.SayHello", pushHeaders: map[string]string{"X-Scope-OrgID": "tenant"}})
+
+		start := time.Now()
+		p.start = start
+
+		p.observe("OK", 10*time.Millisecond)
+		p.observe("Unavailable", 30*time.Millisecond)
+		p.push(start, start.Add(time.Second))
+
+		if assert.Len(t, rec.bodies, 1) {
+			assert.Equal(t, "tenant", rec.headers[0].Get("X-Scope-OrgID"))
+			assert.Equal(t, "application/json", rec.headers[0].Get("Content-Type"))
+
+			var body struct {
+				Streams []struct {
+					Stream map[string]string `json:"stream"`
+					Values [][]string        `json:"values"`
+				} `json:"streams"`
+			}
+
+			assert.NoError(t, json.Unmarshal([]byte(rec.bodies[0]), &body))
+			assert.Equal(t, map[string]string{"job": "ghz", "call": "helloworld.Greeter.SayHello"}, body.Streams[0].Stream)
+
+			var line map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(body.Streams[0].Values[0][1]), &line))
+			assert.Equal(t, "run1", line["runId"])
+			assert.Equal(t, 2.0, line["count"])
+			assert.Equal(t, 1.0, line["errors"])
+			assert.Equal(t, 2.0, line["rps"])
+		}
+
+		// the calls are counted in a single interval
+		p.push(start.Add(time.Second), start.Add(2*time.Second))
+		assert.Contains(t, rec.bodies[1], `\"count\":0`)
+	})
+
+	t.Run("grafana live", func(t *testing.T) {
+		rec := &pushRecorder{}
+		ts := httptest.NewServer(rec)
+		defer ts.Close()
+
+		p := newStatsPusher(&RunConfig{pushTarget: PushGrafanaLive, pushURL: ts.URL, runID: "run1",
+			call: "helloworld.Greeter.SayHello", name: "smoke test",
+			pushHeaders: map[string]string{"Authorization": "Bearer token"}})
+
+		start := time.Unix(100, 0)
+		p.start = start
+
+		p.observe("OK", 10*time.Millisecond)
+		p.push(start, start.Add(2*time.Second))
+
+		if assert.Len(t, rec.bodies, 1) {
+			assert.Equal(t, "Bearer token", rec.headers[0].Get("Authorization"))
+			assert.Equal(t, "ghz,call=helloworld.Greeter.SayHello,name=smoke\\ test,run_id=run1 "+
+				"count=1i,errors=0i,error_rate=0,rps=0.5,p50=10000000i,p95=10000000i,p99=10000000i 102000000000\n",
+				rec.bodies[0])
+		}
+	})
+
+	t.Run("graphite", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		defer lis.Close()
+
+		received := make(chan string, 1)
+		go func() {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+
+			b, _ := ioutil.ReadAll(conn)
+			received <- string(b)
+		}()
+
+		p := newStatsPusher(&RunConfig{pushTarget: PushGraphite, pushURL: "tcp://" + lis.Addr().String(),
+			pushPrefix: "perf", call: "helloworld.Greeter.SayHello"})
+
+		start := time.Unix(100, 0)
+		p.start = start
+
+		p.addWorkers(3)
+		p.addWorkers(-1)
+		p.observe("OK", 10*time.Millisecond)
+		p.observe("Unavailable", 30*time.Millisecond)
+		p.push(start, start.Add(2*time.Second))
+
+		select {
+		case body := <-received:
+			assert.Equal(t, "perf.helloworld_Greeter_SayHello.count 2 102\n"+
+				"perf.helloworld_Greeter_SayHello.errors 1 102\n"+
+				"perf.helloworld_Greeter_SayHello.error_rate 0.5 102\n"+
+				"perf.helloworld_Greeter_SayHello.rps 1 102\n"+
+				"perf.helloworld_Greeter_SayHello.concurrency 2 102\n"+
+				"perf.helloworld_Greeter_SayHello.p50 10 102\n"+
+				"perf.helloworld_Greeter_SayHello.p95 30 102\n"+
+				"perf.helloworld_Greeter_SayHello.p99 30 102\n", body)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "no statistics received")
+		}
+
+		// the default prefix is used if none is set
+		p = newStatsPusher(&RunConfig{pushTarget: PushGraphite, pushURL: "udp://localhost:2003", call: "pkg.Svc/Call"})
+		assert.True(t, strings.HasPrefix(string(p.plaintext(TimeWindow{}, start, 0)), "ghz.pkg_Svc_Call.count 0 100\n"))
+	})
+
+	t.Run("run", func(t *testing.T) {
+		_, s, err := internal.StartServer(false)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		defer s.Stop()
+
+		rec := &pushRecorder{}
+		ts := httptest.NewServer(rec)
+		defer ts.Close()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(2),
+			WithData(&helloworld.HelloRequest{Name: "bob"}),
+			WithStatsPush(PushGrafanaLive, ts.URL, time.Hour),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(20), report.Count)
+
+		// the statistics of the last interval are pushed at the end of the run
+		if assert.Len(t, rec.bodies, 1) {
+			assert.True(t, strings.HasPrefix(rec.bodies[0], "ghz,call=helloworld.Greeter.SayHello,run_id="))
+			assert.Contains(t, rec.bodies[0], " count=20i,errors=0i,")
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseRatio parses the methods and weights of a ratio such as Get=9,Put=1
+func parseRatio(ratio string) ([]ScenarioCall, error) {
+	var calls []ScenarioCall
+
+	for _, part := range strings.Split(ratio, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid ratio %q: expected method=weight[,method=weight]", ratio)
+		}
+
+		name := strings.TrimSpace(kv[0])
+		weight, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 32)
+		if name == "" || err != nil || weight == 0 {
+			return nil, fmt.Errorf("invalid ratio %q: expected method=weight[,method=weight]", ratio)
+		}
+
+		calls = append(calls, ScenarioCall{Name: name, Weight: uint(weight)})
+	}
+
+	if len(calls) < 2 {
+		return nil, fmt.Errorf("invalid ratio %q: expected at least 2 methods", ratio)
+	}
+
+	return calls, nil
+}
+
+// ratioScenario returns the interleaved scenario of the methods of the ratio. The methods are
+// resolved within the service of the call unless they are fully-qualified. If the data is an
+// object with a property for each of the methods, each method is called with its own data,
+// otherwise the data is shared by the methods.
+func ratioScenario(calls []ScenarioCall, call string, data []byte) ([]ScenarioCall, error) {
+	service := ""
+	if call = strings.TrimPrefix(strings.TrimSpace(call), "."); call != "" {
+		if i := strings.LastIndexAny(call, "./"); i > 0 {
+			service = call[:i]
+		}
+	}
+
+	var blocks map[string]json.RawMessage
+	if err := json.Unmarshal(data, &blocks); err == nil && len(blocks) > 0 {
+		for _, sc := range calls {
+			if _, ok := blocks[sc.Name]; !ok {
+				blocks = nil
+				break
+			}
+		}
+
+		if blocks != nil && len(blocks) != len(calls) {
+			blocks = nil
+		}
+	}
+
+	res := make([]ScenarioCall, len(calls))
+	for i, sc := range calls {
+		sc.Call = sc.Name
+		if !strings.ContainsAny(sc.Name, "./") {
+			if service == "" {
+				return nil, errors.New("a ratio of method names requires a call of their service")
+			}
+
+			sc.Call = service + "." + sc.Name
+		}
+
+		if blocks != nil {
+			sc.Data = blocks[sc.Name]
+		}
+
+		res[i] = sc
+	}
+
+	return res, nil
+}
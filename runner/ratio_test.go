@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRatio(t *testing.T) {
+	calls, err := parseRatio("Get=9, Put=1")
+	assert.NoError(t, err)
+	assert.Equal(t, []ScenarioCall{{Name: "Get", Weight: 9}, {Name: "Put", Weight: 1}}, calls)
+
+	for _, ratio := range []string{"Get", "Get=0,Put=1", "Get=a,Put=1", "=1,Put=1"} {
+		_, err := parseRatio(ratio)
+		assert.EqualError(t, err, `invalid ratio "`+ratio+`": expected method=weight[,method=weight]`)
+	}
+
+	_, err = parseRatio("Get=1")
+	assert.EqualError(t, err, `invalid ratio "Get=1": expected at least 2 methods`)
+}
+
+func TestRatioScenario(t *testing.T) {
+	ratio := []ScenarioCall{{Name: "Get", Weight: 9}, {Name: "store.Admin/Put", Weight: 1}}
+
+	calls, err := ratioScenario(ratio, "store.Store.Get", []byte(`{"id":"42"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []ScenarioCall{
+		{Name: "Get", Call: "store.Store.Get", Weight: 9},
+		{Name: "store.Admin/Put", Call: "store.Admin/Put", Weight: 1},
+	}, calls)
+
+	// the data of each method
+	calls, err = ratioScenario(ratio, "store.Store/Get", []byte(`{"Get":{"id":"42"},"store.Admin/Put":{"id":"43"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "store.Store.Get", calls[0].Call)
+	assert.Equal(t, json.RawMessage(`{"id":"42"}`), calls[0].Data)
+	assert.Equal(t, json.RawMessage(`{"id":"43"}`), calls[1].Data)
+
+	_, err = ratioScenario(ratio, "", nil)
+	assert.EqualError(t, err, "a ratio of method names requires a call of their service")
+}
+
+func TestRunRatio(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("interleaved calls", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{
+				"SayHello":                    map[string]interface{}{"name": "reader"},
+				"helloworld.Greeter/SayHello": map[string]interface{}{"name": "writer"},
+			}),
+			WithRatio("SayHello=3,helloworld.Greeter/SayHello=1"),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20, int(report.Count))
+		assert.Equal(t, "SayHello=3,helloworld.Greeter/SayHello=1", report.Options.Ratio)
+		assert.Equal(t, ScenarioInterleaved, report.Options.ScenarioMode)
+
+		var names []string
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			names = append(names, c[0].GetName())
+		}
+
+		if assert.Len(t, names, 20) {
+			assert.Equal(t, []string{"reader", "reader", "writer", "reader"}, names[:4])
+		}
+
+		if assert.Len(t, report.Methods, 2) {
+			assert.Equal(t, "SayHello", report.Methods[0].Method)
+			assert.Equal(t, uint64(15), report.Methods[0].Count)
+			assert.Equal(t, "helloworld.Greeter/SayHello", report.Methods[1].Method)
+			assert.Equal(t, uint64(5), report.Methods[1].Count)
+		}
+	})
+
+	t.Run("with scenario", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRatio("SayHello=3,SayHelloCS=1"),
+			WithScenario(ScenarioCall{Call: "helloworld.Greeter.SayHello"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "a ratio cannot be used with a scenario or a scenario mode")
+	})
+}
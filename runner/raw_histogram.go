@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// rawSubBucketBits is the number of bits of the linear sub-buckets of each power of two
+// of the raw histogram, so that the width of a bucket is within 1/128 of its lower bound
+const rawSubBucketBits = 7
+
+// RawHistogram is the full latency histogram of the calls in log-linear buckets, the same
+// layout as HdrHistogram, so that any percentile can be computed from the report later and
+// the histograms of multiple runs can be merged. The latencies below twice SubBuckets nanoseconds
+// have a bucket each, and every power of two above is split into SubBuckets linear buckets.
+type RawHistogram struct {
+	// SubBuckets is the number of linear buckets of the first power of two, setting the precision
+	SubBuckets int `json:"subBuckets"`
+
+	// Count is the number of latencies recorded
+	Count uint64 `json:"count"`
+
+	Min time.Duration `json:"min"`
+	Max time.Duration `json:"max"`
+
+	// Buckets are the non-empty buckets in ascending order
+	Buckets []RawBucket `json:"buckets"`
+}
+
+// RawBucket is a bucket of the raw histogram, counting the latencies from Lower up to
+// but not including Upper, in nanoseconds
+type RawBucket struct {
+	Lower time.Duration `json:"lower"`
+	Upper time.Duration `json:"upper"`
+	Count uint64        `json:"count"`
+}
+
+// rawRecorder records the latencies into the buckets of the raw histogram
+type rawRecorder struct {
+	counts   []uint64
+	count    uint64
+	min, max time.Duration
+}
+
+func newRawRecorder(enabled bool) *rawRecorder {
+	if !enabled {
+		return nil
+	}
+
+	return &rawRecorder{}
+}
+
+// rawBucketIndex returns the index of the bucket of the latency
+func rawBucketIndex(d time.Duration) int {
+	if d < 0 {
+		d = 0
+	}
+
+	v := uint64(d)
+	if v < 1<<rawSubBucketBits {
+		return int(v)
+	}
+
+	shift := bits.Len64(v) - 1 - rawSubBucketBits
+
+	return (shift+1)<<rawSubBucketBits + int(v>>uint(shift)) - 1<<rawSubBucketBits
+}
+
+// rawBucketBounds returns the lower and the upper bound of the bucket of the index
+func rawBucketBounds(i int) (time.Duration, time.Duration) {
+	if i < 1<<rawSubBucketBits {
+		return time.Duration(i), time.Duration(i + 1)
+	}
+
+	shift := i>>rawSubBucketBits - 1
+	lower := uint64(i-shift<<rawSubBucketBits) << uint(shift)
+
+	return time.Duration(lower), time.Duration(lower + 1<<uint(shift))
+}
+
+// record records the latency
+func (r *rawRecorder) record(d time.Duration) {
+	if r == nil {
+		return
+	}
+
+	i := rawBucketIndex(d)
+	for len(r.counts) <= i {
+		r.counts = append(r.counts, 0)
+	}
+
+	r.counts[i]++
+
+	if r.count == 0 || d < r.min {
+		r.min = d
+	}
+
+	if d > r.max {
+		r.max = d
+	}
+
+	r.count++
+}
+
+// histogram returns the non-empty buckets of the recorded latencies
+func (r *rawRecorder) histogram() *RawHistogram {
+	h := &RawHistogram{
+		SubBuckets: 1 << rawSubBucketBits,
+		Count:      r.count,
+		Min:        r.min,
+		Max:        r.max,
+		Buckets:    make([]RawBucket, 0),
+	}
+
+	for i, n := range r.counts {
+		if n > 0 {
+			lower, upper := rawBucketBounds(i)
+			h.Buckets = append(h.Buckets, RawBucket{Lower: lower, Upper: upper, Count: n})
+		}
+	}
+
+	return h
+}
+
+// Percentile returns the latency of the percentile from 0 to 100, being the largest latency
+// within the bucket of the percentile, capped at the slowest latency. It is 0 if the histogram is empty.
+func (h *RawHistogram) Percentile(p float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p / 100 * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var n uint64
+	for _, b := range h.Buckets {
+		n += b.Count
+		if n >= rank {
+			if v := b.Upper - 1; v < h.Max {
+				return v
+			}
+
+			break
+		}
+	}
+
+	return h.Max
+}
+
+// latencies returns the latency distribution of the histogram, with the same percentiles as
+// the latency distribution of the report
+func (h *RawHistogram) latencies() []LatencyDistribution {
+	pctls := []int{10, 25, 50, 75, 90, 95, 99}
+
+	res := make([]LatencyDistribution, len(pctls))
+	for i, p := range pctls {
+		res[i] = LatencyDistribution{Percentage: p, Latency: h.Percentile(float64(p))}
+	}
+
+	return res
+}
+
+// bucketHistogram returns the latency histogram of the report using the bucket boundaries, or the
+// 10 buckets between the fastest and the slowest latency if there are none. The latencies of each
+// bucket of the histogram are counted as its largest latency, same as the percentiles.
+func (h *RawHistogram) bucketHistogram(boundaries []time.Duration) []Bucket {
+	var marks []float64
+	if len(boundaries) > 0 {
+		for _, b := range boundaries {
+			marks = append(marks, b.Seconds())
+		}
+
+		if h.Max.Seconds() > marks[len(marks)-1] {
+			marks = append(marks, h.Max.Seconds())
+		}
+	} else {
+		bc := 10
+		fastest, slowest := h.Min.Seconds(), h.Max.Seconds()
+		bs := (slowest - fastest) / float64(bc)
+		for i := 0; i < bc; i++ {
+			marks = append(marks, fastest+bs*float64(i))
+		}
+
+		marks = append(marks, slowest)
+	}
+
+	res := make([]Bucket, len(marks))
+	for i, m := range marks {
+		res[i].Mark = m
+	}
+
+	bi := 0
+	for _, b := range h.Buckets {
+		v := b.Upper - 1
+		if v > h.Max {
+			v = h.Max
+		}
+
+		for bi < len(res)-1 && v.Seconds() > res[bi].Mark {
+			bi++
+		}
+
+		res[bi].Count += int(b.Count)
+	}
+
+	for i := range res {
+		res[i].Frequency = float64(res[i].Count) / float64(h.Count)
+	}
+
+	return res
+}
+
+// Merge adds the latencies of the other histogram, such as of another run of the same test.
+// Both histograms must have the same number of sub-buckets.
+func (h *RawHistogram) Merge(o *RawHistogram) error {
+	if o == nil || o.Count == 0 {
+		return nil
+	}
+
+	if h.SubBuckets != o.SubBuckets {
+		return errors.Errorf("cannot merge histograms of %d and %d sub-buckets", h.SubBuckets, o.SubBuckets)
+	}
+
+	if h.Count == 0 || o.Min < h.Min {
+		h.Min = o.Min
+	}
+
+	if o.Max > h.Max {
+		h.Max = o.Max
+	}
+
+	h.Count += o.Count
+
+	merged := make([]RawBucket, 0, len(h.Buckets)+len(o.Buckets))
+	i, j := 0, 0
+	for i < len(h.Buckets) || j < len(o.Buckets) {
+		switch {
+		case j == len(o.Buckets) || (i < len(h.Buckets) && h.Buckets[i].Lower < o.Buckets[j].Lower):
+			merged = append(merged, h.Buckets[i])
+			i++
+		case i == len(h.Buckets) || o.Buckets[j].Lower < h.Buckets[i].Lower:
+			merged = append(merged, o.Buckets[j])
+			j++
+		default:
+			b := h.Buckets[i]
+			b.Count += o.Buckets[j].Count
+			merged = append(merged, b)
+			i++
+			j++
+		}
+	}
+
+	h.Buckets = merged
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawBucketIndex(t *testing.T) {
+	var tests = []struct {
+		in    time.Duration
+		index int
+		lower time.Duration
+		upper time.Duration
+	}{
+		{0, 0, 0, 1},
+		{127, 127, 127, 128},
+		{255, 255, 255, 256},
+		{256, 256, 256, 258},
+		{257, 256, 256, 258},
+		{511, 383, 510, 512},
+		{512, 384, 512, 516},
+		{4021000, 2037, 4014080, 4030464},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in.String(), func(t *testing.T) {
+			i := rawBucketIndex(tt.in)
+			assert.Equal(t, tt.index, i)
+
+			lower, upper := rawBucketBounds(i)
+			assert.Equal(t, tt.lower, lower)
+			assert.Equal(t, tt.upper, upper)
+		})
+	}
+
+	t.Run("contiguous", func(t *testing.T) {
+		for i := 1; i < 5000; i++ {
+			_, upper := rawBucketBounds(i - 1)
+			lower, _ := rawBucketBounds(i)
+			assert.Equal(t, upper, lower, "bucket %d", i)
+		}
+	})
+}
+
+func TestRawHistogram(t *testing.T) {
+	t.Run("percentiles", func(t *testing.T) {
+		r := newRawRecorder(true)
+		rnd := rand.New(rand.NewSource(1))
+
+		lats := make([]float64, 10000)
+		for i := range lats {
+			d := time.Duration(rnd.Int63n(int64(100*time.Millisecond))) + time.Millisecond
+			r.record(d)
+			lats[i] = d.Seconds()
+		}
+
+		sort.Float64s(lats)
+
+		h := r.histogram()
+		assert.Equal(t, 128, h.SubBuckets)
+		assert.Equal(t, uint64(10000), h.Count)
+		assert.Equal(t, time.Duration(lats[0]*float64(time.Second)), h.Min)
+		assert.Equal(t, time.Duration(lats[len(lats)-1]*float64(time.Second)), h.Max)
+		assert.Equal(t, h.Max, h.Percentile(100))
+
+		var total uint64
+		for _, b := range h.Buckets {
+			total += b.Count
+		}
+
+		assert.Equal(t, h.Count, total)
+
+		for _, ld := range latencies(lats) {
+			actual := h.Percentile(float64(ld.Percentage))
+			assert.InEpsilon(t, float64(ld.Latency), float64(actual), 0.01, "p%d", ld.Percentage)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		h := newRawRecorder(true).histogram()
+		assert.Equal(t, uint64(0), h.Count)
+		assert.Empty(t, h.Buckets)
+		assert.Equal(t, time.Duration(0), h.Percentile(99))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r := newRawRecorder(false)
+		assert.Nil(t, r)
+		r.record(time.Millisecond)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		r1 := newRawRecorder(true)
+		r2 := newRawRecorder(true)
+		all := newRawRecorder(true)
+
+		for i := 1; i <= 1000; i++ {
+			d := time.Duration(i) * 37 * time.Microsecond
+			if i%3 == 0 {
+				r1.record(d)
+			} else {
+				r2.record(d)
+			}
+
+			all.record(d)
+		}
+
+		h := r1.histogram()
+		assert.NoError(t, h.Merge(r2.histogram()))
+		assert.Equal(t, all.histogram(), h)
+
+		assert.NoError(t, h.Merge(nil))
+		assert.Equal(t, all.histogram(), h)
+
+		empty := newRawRecorder(true).histogram()
+		assert.NoError(t, empty.Merge(h))
+		assert.Equal(t, h, empty)
+
+		err := h.Merge(&RawHistogram{SubBuckets: 64, Count: 1})
+		assert.EqualError(t, err, "cannot merge histograms of 128 and 64 sub-buckets")
+	})
+}
+
+func TestReporter_FinalizeRawHistogram(t *testing.T) {
+	c, err := NewConfig("call", "localhost:50050",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithRawHistogram(true))
+	assert.NoError(t, err)
+
+	r := newReporter(nil, c)
+	r.add(&callResult{duration: 10 * time.Millisecond, status: "OK"})
+	r.add(&callResult{duration: 20 * time.Millisecond, status: "OK"})
+	r.add(&callResult{duration: 30 * time.Millisecond, status: "Unavailable", err: errors.New("unavailable")})
+
+	rep := r.Finalize(ReasonNormalEnd, time.Second)
+
+	if assert.NotNil(t, rep.RawHistogram) {
+		assert.Equal(t, uint64(2), rep.RawHistogram.Count)
+		assert.Equal(t, 10*time.Millisecond, rep.RawHistogram.Min)
+		assert.Equal(t, 20*time.Millisecond, rep.RawHistogram.Max)
+		assert.Len(t, rep.RawHistogram.Buckets, 2)
+	}
+
+	assert.True(t, rep.Options.RawHistogram)
+
+	r = newReporter(nil, &RunConfig{})
+	r.add(&callResult{duration: 10 * time.Millisecond, status: "OK"})
+	assert.Nil(t, r.Finalize(ReasonNormalEnd, time.Second).RawHistogram)
+}
+
+func TestReporter_FinalizeStreamingPercentiles(t *testing.T) {
+	c, err := NewConfig("call", "localhost:50050",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithStreamingPercentiles(true),
+		WithHistogramBoundaries([]time.Duration{5 * time.Millisecond, 50 * time.Millisecond}))
+	assert.NoError(t, err)
+
+	r := newReporter(nil, c)
+	details := newReporter(nil, &RunConfig{})
+	for i := 1; i <= 20000; i++ {
+		res := &callResult{duration: time.Duration(i) * 10 * time.Microsecond, status: "OK"}
+		r.add(res)
+		details.add(res)
+	}
+
+	r.add(&callResult{duration: time.Second, status: "Unavailable", err: errors.New("unavailable")})
+
+	rep := r.Finalize(ReasonNormalEnd, 10*time.Second)
+	exact := details.Finalize(ReasonNormalEnd, 10*time.Second)
+
+	assert.Empty(t, rep.Details)
+	assert.Nil(t, rep.RawHistogram)
+	assert.True(t, rep.Options.StreamingPercentiles)
+	assert.Equal(t, uint64(20001), rep.Count)
+	assert.Equal(t, 2000.1, rep.Rps)
+	assert.Equal(t, 10*time.Microsecond, rep.Fastest)
+	assert.Equal(t, 200*time.Millisecond, rep.Slowest)
+
+	within := func(expected, actual time.Duration) {
+		assert.InDelta(t, float64(expected), float64(actual), float64(expected)/100)
+	}
+
+	if assert.Len(t, rep.LatencyDistribution, len(exact.LatencyDistribution)) {
+		for i, ld := range rep.LatencyDistribution {
+			assert.Equal(t, exact.LatencyDistribution[i].Percentage, ld.Percentage)
+			within(exact.LatencyDistribution[i].Latency, ld.Latency)
+		}
+	}
+
+	if assert.Len(t, rep.TailLatency, 2) && assert.Len(t, exact.TailLatency, 2) {
+		assert.Equal(t, 99.9, rep.TailLatency[0].Percentile)
+		within(199800*time.Microsecond, exact.TailLatency[0].Latency)
+		within(exact.TailLatency[0].Latency, rep.TailLatency[0].Latency)
+		assert.Equal(t, 99.99, rep.TailLatency[1].Percentile)
+		within(199980*time.Microsecond, exact.TailLatency[1].Latency)
+		within(exact.TailLatency[1].Latency, rep.TailLatency[1].Latency)
+	}
+
+	if assert.Len(t, rep.Histogram, 3) {
+		assert.Equal(t, 0.005, rep.Histogram[0].Mark)
+		assert.Equal(t, 0.05, rep.Histogram[1].Mark)
+		assert.Equal(t, 0.2, rep.Histogram[2].Mark)
+		assert.Equal(t, 20000, rep.Histogram[0].Count+rep.Histogram[1].Count+rep.Histogram[2].Count)
+		assert.InDelta(t, 500, rep.Histogram[0].Count, 5)
+		assert.InDelta(t, 4500, rep.Histogram[1].Count, 45)
+	}
+}
+
+func TestTailLatencies(t *testing.T) {
+	percentile := func(p float64) time.Duration {
+		return time.Duration(p * float64(time.Millisecond))
+	}
+
+	assert.Empty(t, tailLatencies(999, percentile))
+	assert.Equal(t, []TailLatency{{Percentile: 99.9, Latency: percentile(99.9)}}, tailLatencies(1000, percentile))
+	assert.Len(t, tailLatencies(10000, percentile), 2)
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DefaultReadinessTimeout is the default time to wait for the server to be ready
+const DefaultReadinessTimeout = 30 * time.Second
+
+// the backoff between the readiness probes of a connection, doubling from the minimum up to the maximum
+const (
+	readinessMinBackoff = 100 * time.Millisecond
+	readinessMaxBackoff = 2 * time.Second
+)
+
+// ReadinessStats is the wait for the server to be ready before the load started
+type ReadinessStats struct {
+	// Probe is the health checked service, the readiness call, or empty for the health of the whole server
+	Probe string `json:"probe,omitempty"`
+
+	// Connections is the number of connections probed
+	Connections int `json:"connections"`
+
+	// Attempts is the number of probes over all the connections, including the successful ones
+	Attempts uint64 `json:"attempts"`
+
+	// Wait is the time until all the connections were ready
+	Wait time.Duration `json:"wait"`
+}
+
+// readinessCallKey is the context key marking the readiness probes, which are not part of the results
+type readinessCallKey struct{}
+
+// waitReady probes each connection until the server is ready or the readiness timeout elapses.
+// The probes are the standard health check, or the readiness call with an empty request.
+func (b *Requester) waitReady(conns []*grpc.ClientConn) (*ReadinessStats, error) {
+	timeout := b.config.readinessTimeout
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	stats := &ReadinessStats{Probe: b.config.readinessService, Connections: len(conns)}
+	if b.readinessMtd != nil {
+		stats.Probe = b.config.readinessCall
+	}
+
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, readinessCallKey{}, true)
+	if len(b.config.rmd) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(b.config.rmd))
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+
+	for n, cc := range conns {
+		wg.Add(1)
+
+		go func(n int, cc *grpc.ClientConn) {
+			defer wg.Done()
+
+			attempts, err := b.probeConn(ctx, cc)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			stats.Attempts += attempts
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("server not ready after %v on connection %d: %v", timeout, n, err)
+
+				// the other connections are not waited for
+				cancel()
+			}
+		}(n, cc)
+	}
+
+	wg.Wait()
+
+	stats.Wait = time.Since(start)
+
+	if b.config.hasLog {
+		b.config.log.Debugw("Waited for the server to be ready", "probe", stats.Probe,
+			"attempts", stats.Attempts, "wait", stats.Wait, "error", firstErr)
+	}
+
+	return stats, firstErr
+}
+
+// probeConn probes the connection with backoff until the server is ready,
+// returning the number of probes and the error of the last one if it never was
+func (b *Requester) probeConn(ctx context.Context, cc *grpc.ClientConn) (uint64, error) {
+	var attempts uint64
+	wait := readinessMinBackoff
+
+	for {
+		attempts++
+
+		err := b.probe(ctx, cc)
+		if err == nil {
+			return attempts, nil
+		}
+
+		// a server without the health service is never going to be ready
+		if b.readinessMtd == nil && status.Code(err) == codes.Unimplemented {
+			return attempts, fmt.Errorf("the server does not implement the health service, use a readiness call instead: %v", err)
+		}
+
+		if b.config.hasLog {
+			b.config.log.Debugw("Server not ready", "attempt", attempts, "error", err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return attempts, err
+		case <-t.C:
+		}
+
+		if wait *= 2; wait > readinessMaxBackoff {
+			wait = readinessMaxBackoff
+		}
+	}
+}
+
+// probe makes a single readiness probe on the connection, bounded by the timeout of the calls
+func (b *Requester) probe(ctx context.Context, cc *grpc.ClientConn) error {
+	if b.config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.timeout)
+		defer cancel()
+	}
+
+	if b.readinessMtd != nil {
+		_, err := grpcdynamic.NewStub(cc).InvokeRpc(ctx, b.readinessMtd, dynamic.NewMessage(b.readinessMtd.GetInputType()))
+		return err
+	}
+
+	res, err := healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{Service: b.config.readinessService})
+	if err != nil {
+		return err
+	}
+
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("serving status is %s", res.GetStatus())
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package runner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRunReadiness(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	hs := health.NewServer()
+	hs.SetServingStatus("helloworld.Greeter", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	s := grpc.NewServer()
+	helloworld.RegisterGreeterServer(s, helloworld.NewGreeter())
+	healthpb.RegisterHealthServer(s, hs)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	host := lis.Addr().String()
+
+	t.Run("not ready in time", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithReadinessCheck("helloworld.Greeter", 300*time.Millisecond),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "server not ready after 300ms on connection 0: ")
+		}
+	})
+
+	t.Run("once serving", func(t *testing.T) {
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			hs.SetServingStatus("helloworld.Greeter", healthpb.HealthCheckResponse_SERVING)
+		}()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithConcurrency(2),
+			WithConnections(2),
+			WithReadinessCheck("helloworld.Greeter", 5*time.Second),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// the probes are not included in the results
+		assert.Equal(t, uint64(5), report.Count)
+		assert.Equal(t, map[string]int{"OK": 5}, report.StatusCodeDist)
+
+		if assert.NotNil(t, report.Readiness) {
+			assert.Equal(t, "helloworld.Greeter", report.Readiness.Probe)
+			assert.Equal(t, 2, report.Readiness.Connections)
+			assert.True(t, report.Readiness.Attempts > 2, "attempts %d", report.Readiness.Attempts)
+			assert.True(t, report.Readiness.Wait >= 300*time.Millisecond, "wait %v", report.Readiness.Wait)
+		}
+	})
+
+	t.Run("with a run duration shorter than the wait", func(t *testing.T) {
+		hs.SetServingStatus("helloworld.Greeter", healthpb.HealthCheckResponse_NOT_SERVING)
+
+		go func() {
+			time.Sleep(600 * time.Millisecond)
+			hs.SetServingStatus("helloworld.Greeter", healthpb.HealthCheckResponse_SERVING)
+		}()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(300*time.Millisecond),
+			WithRPS(50),
+			WithConcurrency(1),
+			WithReadinessCheck("helloworld.Greeter", 5*time.Second),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// the duration of the run starts once the server is ready
+		assert.Equal(t, ReasonTimeout, report.EndReason)
+		assert.True(t, report.StatusCodeDist["OK"] > 0)
+		assert.True(t, report.StatusCodeDist["OK"] >= int(report.Count)-1, "status %v", report.StatusCodeDist)
+
+		if assert.NotNil(t, report.Readiness) {
+			assert.True(t, report.Readiness.Wait >= 600*time.Millisecond, "wait %v", report.Readiness.Wait)
+		}
+	})
+}
+
+func TestRunReadiness_call(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("without the health service", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithReadinessCheck("", time.Second),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "the server does not implement the health service, use a readiness call instead")
+		}
+	})
+
+	t.Run("with readiness call", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithConcurrency(1),
+			WithReadinessCall("helloworld.Greeter.SayHello"),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, uint64(5), report.Count)
+		assert.Equal(t, 6, gs.GetCount(helloworld.Unary))
+
+		if assert.NotNil(t, report.Readiness) {
+			assert.Equal(t, "helloworld.Greeter.SayHello", report.Readiness.Probe)
+			assert.Equal(t, uint64(1), report.Readiness.Attempts)
+		}
+	})
+
+	t.Run("with streaming readiness call", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithReadinessCall("helloworld.Greeter.SayHelloCS"),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "readiness call helloworld.Greeter.SayHelloCS must be unary")
+	})
+}
@@ -18,6 +18,14 @@ func (s StopReason) String() string {
 		return "timeout"
 	}
 
+	if s == ReasonInterrupt {
+		return "interrupt"
+	}
+
+	if s == ReasonMaxErrors {
+		return "max-errors"
+	}
+
 	return "normal"
 }
 
@@ -48,6 +56,14 @@ func ReasonFromString(str string) StopReason {
 		s = ReasonTimeout
 	}
 
+	if str == "interrupt" {
+		s = ReasonInterrupt
+	}
+
+	if str == "max-errors" {
+		s = ReasonMaxErrors
+	}
+
 	return s
 }
 
@@ -60,4 +76,10 @@ const (
 
 	// ReasonTimeout indicates run ended due to Z parameter timeout
 	ReasonTimeout = StopReason("timeout")
+
+	// ReasonInterrupt indicates run ended due to an interrupt or termination signal
+	ReasonInterrupt = StopReason("interrupt")
+
+	// ReasonMaxErrors indicates run ended due to reaching the maximum number of errors
+	ReasonMaxErrors = StopReason("max-errors")
 )
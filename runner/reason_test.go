@@ -16,6 +16,8 @@ func TestReason_String(t *testing.T) {
 		{"normal", ReasonNormalEnd, "normal"},
 		{"cancel", ReasonCancel, "cancel"},
 		{"timeout", ReasonTimeout, "timeout"},
+		{"interrupt", ReasonInterrupt, "interrupt"},
+		{"max errors", ReasonMaxErrors, "max-errors"},
 		{"unknown", StopReason("foo"), "normal"},
 	}
 
@@ -36,6 +38,8 @@ func TestReason_StatusFromString(t *testing.T) {
 		{"normal", "normal", ReasonNormalEnd},
 		{"cancel", "cancel", ReasonCancel},
 		{"timeout", "timeout", ReasonTimeout},
+		{"interrupt", "interrupt", ReasonInterrupt},
+		{"max errors", "max-errors", ReasonMaxErrors},
 		{"unknown", "foo", ReasonNormalEnd},
 	}
 
@@ -59,6 +63,8 @@ func TestReason_UnmarshalJSON(t *testing.T) {
 		{"CANCEL", `"CANCEL"`, ReasonCancel},
 		{" CANCEL ", ` "CANCEL" `, ReasonCancel},
 		{"timeout", ` "timeout" `, ReasonTimeout},
+		{"interrupt", `"interrupt"`, ReasonInterrupt},
+		{"max errors", `"max-errors"`, ReasonMaxErrors},
 	}
 
 	for _, tt := range tests {
@@ -80,6 +86,8 @@ func TestReason_MarshalJSON(t *testing.T) {
 		{"normal", ReasonNormalEnd, `"normal"`},
 		{"cancel", ReasonCancel, `"cancel"`},
 		{"timeout", ReasonTimeout, `"timeout"`},
+		{"interrupt", ReasonInterrupt, `"interrupt"`},
+		{"max errors", ReasonMaxErrors, `"max-errors"`},
 		{"unknown", StopReason("foo"), `"normal"`},
 	}
 
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// streamLimit is the limit of concurrent streams per connection common to gRPC servers
+	// and HTTP/2 proxies, beyond which calls wait for a stream to be released
+	streamLimit = 100
+
+	// cpuSaturation is the fraction of the CPUs used above which the client is saturated
+	cpuSaturation = 0.9
+
+	// overloadErrorRate is the fraction of the calls failing with Unavailable or
+	// ResourceExhausted above which the server is considered overloaded
+	overloadErrorRate = 0.05
+
+	// lagThreshold is the average scheduler lag above which the workers do not keep up with the rate
+	lagThreshold = time.Millisecond
+)
+
+// Recommendation is a tuning recommendation derived from the statistics of the run
+type Recommendation struct {
+	// Reason is the condition found in the statistics, such as stream-limit
+	Reason string `json:"reason"`
+
+	// Option is the option the recommendation applies to, such as connections
+	Option string `json:"option"`
+
+	// Message describes the recommended change and the statistics it is based on
+	Message string `json:"message"`
+}
+
+// recommendations analyzes the connection, CPU, scheduling and error statistics of the report
+// and returns the tuning recommendations of the run
+func recommendations(report *Report, c *RunConfig) []Recommendation {
+	var recs []Recommendation
+
+	var maxStreams, reconnects uint64
+	for _, cs := range report.Connections {
+		if cs.MaxStreams > maxStreams {
+			maxStreams = cs.MaxStreams
+		}
+
+		if cs.Connects > 1 {
+			reconnects += cs.Connects - 1
+		}
+	}
+
+	if maxStreams > streamLimit {
+		conns := int((uint64(c.c) + streamLimit - 1) / streamLimit)
+		recs = append(recs, Recommendation{
+			Reason: "stream-limit",
+			Option: "connections",
+			Message: fmt.Sprintf("increase --connections to at least %d: up to %d concurrent streams were made on a single connection, "+
+				"above the limit of %d streams of many servers and proxies, so calls may wait for a stream", conns, maxStreams, streamLimit),
+		})
+	}
+
+	if reconnects > 0 {
+		recs = append(recs, Recommendation{
+			Reason: "reconnects",
+			Option: "connections",
+			Message: fmt.Sprintf("increase --connections: the connections reconnected %d times, likely closed by the server with GOAWAY, "+
+				"such as when reaching its maximum connection age, so more connections spread the calls affected by a reconnect", reconnects),
+		})
+	}
+
+	if n := report.Normalized; n != nil && c.cpus > 0 && n.CoresUsed >= cpuSaturation*float64(c.cpus) {
+		recs = append(recs, Recommendation{
+			Reason: "client-cpu",
+			Option: "concurrency",
+			Message: fmt.Sprintf("reduce --concurrency or increase --cpus: client CPU saturated, %.2f of %d CPUs were used, "+
+				"so the results may be limited by the client rather than the server", n.CoresUsed, c.cpus),
+		})
+	}
+
+	if lag := report.SchedulerLag; lag != nil && lag.Average > lagThreshold {
+		recs = append(recs, Recommendation{
+			Reason: "scheduler-lag",
+			Option: "concurrency",
+			Message: fmt.Sprintf("increase --concurrency: the rate-paced calls were sent %v late on average, "+
+				"so the workers do not keep up with the requested rate", lag.Average),
+		})
+	}
+
+	if report.Count > 0 {
+		overloaded := report.StatusCodeDist[codes.Unavailable.String()] + report.StatusCodeDist[codes.ResourceExhausted.String()]
+		if rate := float64(overloaded) / float64(report.Count); rate > overloadErrorRate {
+			recs = append(recs, Recommendation{
+				Reason: "server-overload",
+				Option: "rps",
+				Message: fmt.Sprintf("reduce --rps or --concurrency: %.2f%% of the calls failed with Unavailable or ResourceExhausted, "+
+					"so the server is rejecting the load", rate*100),
+			})
+		}
+	}
+
+	return recs
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reasons(recs []Recommendation) []string {
+	var r []string
+	for _, rec := range recs {
+		r = append(r, rec.Reason)
+	}
+
+	return r
+}
+
+func TestRecommendations(t *testing.T) {
+	c := &RunConfig{c: 250, cpus: 4, nConns: 1}
+
+	t.Run("none", func(t *testing.T) {
+		report := &Report{
+			Count:          100,
+			StatusCodeDist: map[string]int{"OK": 100},
+			Normalized:     &Normalized{CoresUsed: 1.2},
+			SchedulerLag:   &SchedulerLag{Average: 100 * time.Microsecond},
+			Connections:    []ConnectionStats{{Calls: 100, MaxStreams: 50, Connects: 1}},
+		}
+
+		assert.Empty(t, recommendations(report, c))
+	})
+
+	t.Run("stream limit", func(t *testing.T) {
+		report := &Report{
+			Connections: []ConnectionStats{{Calls: 1000, MaxStreams: 250, Connects: 1}},
+		}
+
+		recs := recommendations(report, c)
+		assert.Equal(t, []string{"stream-limit"}, reasons(recs))
+		assert.Equal(t, "connections", recs[0].Option)
+		assert.Contains(t, recs[0].Message, "increase --connections to at least 3")
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		report := &Report{
+			Connections: []ConnectionStats{{Connects: 3}, {Connects: 2}},
+		}
+
+		recs := recommendations(report, c)
+		assert.Equal(t, []string{"reconnects"}, reasons(recs))
+		assert.Contains(t, recs[0].Message, "reconnected 3 times")
+	})
+
+	t.Run("client cpu and scheduler lag", func(t *testing.T) {
+		report := &Report{
+			Normalized:   &Normalized{CoresUsed: 3.8},
+			SchedulerLag: &SchedulerLag{Average: 5 * time.Millisecond},
+		}
+
+		assert.Equal(t, []string{"client-cpu", "scheduler-lag"}, reasons(recommendations(report, c)))
+	})
+
+	t.Run("server overload", func(t *testing.T) {
+		report := &Report{
+			Count:          100,
+			StatusCodeDist: map[string]int{"OK": 80, "Unavailable": 15, "ResourceExhausted": 5},
+		}
+
+		recs := recommendations(report, c)
+		assert.Equal(t, []string{"server-overload"}, reasons(recs))
+		assert.Contains(t, recs[0].Message, "20.00%")
+	})
+}
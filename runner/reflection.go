@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+
+	"github.com/bojand/ghz/protodesc"
+)
+
+// defaultReflectCacheTTL is the duration the cached descriptors are used for if it is not set
+const defaultReflectCacheTTL = time.Hour
+
+// reflectedFiles resolves the methods via reflection, keeping the files of the resolved methods for
+// the protoset export and the reflection cache. With the cache the methods are resolved from the
+// descriptors cached by a previous run, and only those not found are resolved via reflection.
+type reflectedFiles struct {
+	// connect makes the reflection client, only once a method has to be resolved via reflection
+	connect func() (*grpcreflect.Client, error)
+	client  *grpcreflect.Client
+
+	cachePath string
+	cached    []*desc.FileDescriptor
+
+	files     []*desc.FileDescriptor
+	reflected bool
+}
+
+func newReflectedFiles(c *RunConfig, connect func() (*grpcreflect.Client, error)) *reflectedFiles {
+	r := &reflectedFiles{connect: connect}
+
+	if c.reflectCache == "" {
+		return r
+	}
+
+	ttl := c.reflectCacheTTL
+	if ttl <= 0 {
+		ttl = defaultReflectCacheTTL
+	}
+
+	r.cachePath = reflectionCachePath(c)
+
+	info, err := os.Stat(r.cachePath)
+	if err != nil || time.Since(info.ModTime()) > ttl {
+		return r
+	}
+
+	// an unreadable cache is resolved via reflection again and replaced
+	if r.cached, err = protodesc.LoadProtoSet(r.cachePath); err != nil && c.hasLog {
+		c.log.Debugw("Error loading the reflection cache: "+err.Error(), "path", r.cachePath, "error", err)
+	}
+
+	return r
+}
+
+// getMethod returns the descriptor of the method of the call
+func (r *reflectedFiles) getMethod(call string) (*desc.MethodDescriptor, error) {
+	if len(r.cached) > 0 {
+		if mtd, err := protodesc.GetMethodDescFromFiles(call, r.cached); err == nil {
+			r.add(mtd.GetFile())
+
+			return mtd, nil
+		}
+	}
+
+	if r.client == nil {
+		client, err := r.connect()
+		if err != nil {
+			return nil, err
+		}
+
+		r.client = client
+	}
+
+	mtd, err := protodesc.GetMethodDescFromReflect(call, r.client)
+	if err != nil {
+		return nil, err
+	}
+
+	r.reflected = true
+	r.add(mtd.GetFile())
+
+	return mtd, nil
+}
+
+// add keeps the file unless a file with the same name is kept already
+func (r *reflectedFiles) add(fd *desc.FileDescriptor) {
+	for _, f := range r.files {
+		if f.GetName() == fd.GetName() {
+			return
+		}
+	}
+
+	r.files = append(r.files, fd)
+}
+
+// save writes the files of the resolved methods to the protoset file, if any, and to the cache
+// along with the other cached files if any of the methods was resolved via reflection. The cache
+// is only an optimization, so the errors writing it are logged and do not fail the run.
+func (r *reflectedFiles) save(c *RunConfig) error {
+	if c.reflectProtoset != "" {
+		if err := protodesc.WriteProtoSet(c.reflectProtoset, r.files...); err != nil {
+			return fmt.Errorf("error writing protoset %s: %v", c.reflectProtoset, err)
+		}
+	}
+
+	if r.cachePath == "" || !r.reflected {
+		return nil
+	}
+
+	files := r.files
+	for _, fd := range r.cached {
+		found := false
+		for _, f := range r.files {
+			found = found || f.GetName() == fd.GetName()
+		}
+
+		if !found {
+			files = append(files, fd)
+		}
+	}
+
+	if err := protodesc.WriteProtoSet(r.cachePath, files...); err != nil && c.hasLog {
+		c.log.Debugw("Error writing the reflection cache: "+err.Error(), "path", r.cachePath, "error", err)
+	}
+
+	return nil
+}
+
+// reflectionCachePath returns the path of the cached descriptors of the host. The descriptors
+// are cached apart for each reflection metadata, which may select the services of the server.
+func reflectionCachePath(c *RunConfig) string {
+	keys := make([]string, 0, len(c.rmd))
+	for k := range c.rmd {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(c.host + "\n"))
+	for _, k := range keys {
+		h.Write([]byte(k + "=" + c.rmd[k] + "\n"))
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+
+		return '_'
+	}, c.host)
+
+	return filepath.Join(c.reflectCache, name+"-"+hex.EncodeToString(h.Sum(nil))[:12]+".protoset")
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequester_reflectionCache(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	dir := t.TempDir()
+	protoset := filepath.Join(dir, "greeter.protoset")
+
+	newRequester := func(options ...Option) (*Requester, error) {
+		options = append([]Option{WithInsecure(true), WithTotalRequests(1), WithData(map[string]interface{}{"name": "bob"})}, options...)
+
+		c, err := NewConfig("helloworld.Greeter.SayHello", internal.TestLocalhost, options...)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRequester(c)
+	}
+
+	t.Run("protoset", func(t *testing.T) {
+		_, err := newRequester(WithReflectionProtoset(protoset))
+		assert.NoError(t, err)
+
+		mtd, err := protodesc.GetMethodDescFromProtoSet("helloworld.Greeter.SayHello", protoset)
+		assert.NoError(t, err)
+		assert.NotNil(t, mtd)
+
+		// the exported protoset is used as is
+		reqr, err := newRequester(WithProtoset(protoset))
+		assert.NoError(t, err)
+		assert.NotNil(t, reqr)
+	})
+
+	t.Run("protoset without reflection", func(t *testing.T) {
+		_, err := newRequester(WithProtoset(protoset), WithReflectionProtoset(filepath.Join(dir, "other.protoset")))
+		assert.EqualError(t, err, "the protoset export and the reflection cache require the method to be resolved via reflection")
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		cache := filepath.Join(dir, "cache")
+
+		c, err := NewConfig("helloworld.Greeter.SayHello", internal.TestLocalhost, WithReflectionCache(cache, 0))
+		assert.NoError(t, err)
+
+		path := reflectionCachePath(c)
+
+		_, err = newRequester(WithReflectionCache(cache, 0))
+		assert.NoError(t, err)
+
+		info, err := os.Stat(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// the cached descriptors are used, so the cache is not written again
+		reflected := newReflectedFiles(c, nil)
+		mtd, err := reflected.getMethod("helloworld.Greeter.SayHello")
+		assert.NoError(t, err)
+		assert.NotNil(t, mtd)
+		assert.False(t, reflected.reflected)
+
+		_, err = newRequester(WithReflectionCache(cache, 0))
+		assert.NoError(t, err)
+
+		again, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, info.ModTime(), again.ModTime())
+
+		// the expired descriptors are resolved via reflection again
+		old := time.Now().Add(-2 * time.Minute)
+		assert.NoError(t, os.Chtimes(path, old, old))
+
+		_, err = newRequester(WithReflectionCache(cache, time.Minute))
+		assert.NoError(t, err)
+
+		again, err = os.Stat(path)
+		assert.NoError(t, err)
+		assert.True(t, again.ModTime().After(old))
+	})
+}
+
+func TestReflectionCachePath(t *testing.T) {
+	c, err := NewConfig("helloworld.Greeter.SayHello", "localhost:50051",
+		WithReflectionCache("/tmp/ghz", 0),
+		WithReflectionMetadata(map[string]string{"token": "a"}))
+	assert.NoError(t, err)
+
+	path := reflectionCachePath(c)
+	assert.Equal(t, "/tmp/ghz", filepath.Dir(path))
+	assert.Regexp(t, `^localhost_50051-[0-9a-f]{12}\.protoset$`, filepath.Base(path))
+
+	other, err := NewConfig("helloworld.Greeter.SayHello", "localhost:50051",
+		WithReflectionCache("/tmp/ghz", 0),
+		WithReflectionMetadata(map[string]string{"token": "b"}))
+	assert.NoError(t, err)
+
+	// the descriptors are cached apart for each reflection metadata
+	assert.NotEqual(t, path, reflectionCachePath(other))
+}
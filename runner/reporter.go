@@ -2,12 +2,26 @@ package runner
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"runtime"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/bojand/ghz/load"
+	"google.golang.org/grpc/codes"
 )
 
 // Reporter gathers all the results
 type Reporter struct {
+	// live counters that can be read while the run is in progress
+	// kept first for 64-bit alignment of atomic operations
+	liveCount      uint64
+	liveErrorCount uint64
+
 	config *RunConfig
 
 	results chan *callResult
@@ -17,9 +31,91 @@ type Reporter struct {
 
 	details []ResultDetail
 
+	// the details in the compact columnar layout instead, if enabled
+	columns *DetailColumns
+
+	// the results of each tenant in the fairness mode
+	tenants *tenants
+
+	// scheduler lag of rate-paced calls
+	lags []float64
+
+	// the time to the response headers of unary calls
+	headers []float64
+
+	// sampled traces
+	traces []Trace
+
+	// the messages of the streams, only counted for streaming calls
+	messages *streamMessages
+
+	// the bytes sent and received by the calls
+	bandwidth *bandwidthTracker
+
+	// the results bucketed into windows of the run
+	series *timeSeries
+
+	// the results summarized by time slices of the run
+	slices *timeSlices
+
+	// the calls started in each interval of the throughput check
+	rates *throughputCounter
+
+	// the groups of the workers pinned to CPUs
+	groups *workerGroups
+
+	// the failed calls by status code
+	failures *failureTracker
+
+	// the raw latency histogram
+	raw *rawRecorder
+
+	// latencies since the last latency feedback when the rate is controlled by the latency
+	recentLock sync.Mutex
+	recent     []float64
+
 	errorDist      map[string]int
 	statusCodeDist map[string]int
 	totalCount     uint64
+
+	// the error of the call reaching the maximum number of errors, and the function
+	// stopping the run when it is reached
+	maxErrorsErr string
+	onMaxErrors  func()
+
+	// results since the last rotation
+	period      *Reporter
+	periodStart time.Time
+	rotations   int
+
+	// the recent snapshots, so delta snapshots can be taken relative to them
+	snapshotLock sync.Mutex
+	snapshotSeq  uint64
+	snapshots    []Snapshot
+}
+
+// snapshotHistory is the number of recent snapshots delta snapshots can be taken relative to
+const snapshotHistory = 1000
+
+// ErrSnapshotNotFound is returned when a delta snapshot is requested relative to a snapshot
+// which is not one of the recent snapshots
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a point in time view of the progress of a run
+type Snapshot struct {
+	// Seq is the sequence number of the snapshot, increasing with every snapshot taken
+	Seq uint64 `json:"seq"`
+
+	// Since is the sequence number of the snapshot a delta snapshot is relative to,
+	// in which case the elapsed time, the counts and the rate are those of the interval
+	// between the two snapshots. It is 0 for cumulative snapshots and for delta snapshots
+	// relative to the start of the run.
+	Since uint64 `json:"since,omitempty"`
+
+	Elapsed    time.Duration `json:"elapsed"`
+	Count      uint64        `json:"count"`
+	ErrorCount uint64        `json:"errorCount"`
+	Rps        float64       `json:"rps"`
 }
 
 // Options represents the request options
@@ -31,14 +127,39 @@ type Options struct {
 	Protoset          string   `json:"protoset,omitempty"`
 	ImportPaths       []string `json:"import-paths,omitempty"`
 	EnableCompression bool     `json:"enable-compression,omitempty"`
+	Codec             string   `json:"codec,omitempty"`
+	WaitForReady      bool     `json:"wait-for-ready,omitempty"`
+	MaxRecvMsgSize    uint     `json:"max-recv-msg-size,omitempty"`
+	MaxSendMsgSize    uint     `json:"max-send-msg-size,omitempty"`
+
+	// Compressor is the name of the compressor of the calls, other than the gzip of EnableCompression
+	Compressor string `json:"compressor,omitempty"`
 
-	CACert    string `json:"cacert,omitempty"`
-	Cert      string `json:"cert,omitempty"`
-	Key       string `json:"key,omitempty"`
-	CName     string `json:"cname,omitempty"`
-	SkipTLS   bool   `json:"skipTLS,omitempty"`
-	Insecure  bool   `json:"insecure"`
-	Authority string `json:"authority,omitempty"`
+	CACert    string   `json:"cacert,omitempty"`
+	Cert      string   `json:"cert,omitempty"`
+	Key       string   `json:"key,omitempty"`
+	CName     string   `json:"cname,omitempty"`
+	SkipTLS   bool     `json:"skipTLS,omitempty"`
+	Insecure  bool     `json:"insecure"`
+	Authority string   `json:"authority,omitempty"`
+	Resolve   []string `json:"resolve,omitempty"`
+	Targets   []string `json:"targets,omitempty"`
+
+	TargetOverrides []TargetOverride `json:"target-overrides,omitempty"`
+
+	ServiceConfig string `json:"service-config,omitempty"`
+	IPVersion     uint   `json:"ip-version,omitempty"`
+
+	// the password of the proxy is redacted
+	Transport string `json:"transport,omitempty"`
+	Proxy     string `json:"proxy,omitempty"`
+
+	// the client secret of the OAuth2 client is not included
+	OAuthTokenURL string        `json:"oauth-token-url,omitempty"`
+	OAuthClientID string        `json:"oauth-client-id,omitempty"`
+	OAuthScopes   []string      `json:"oauth-scopes,omitempty"`
+	TokenFile     string        `json:"token-file,omitempty"`
+	TokenRefresh  time.Duration `json:"token-refresh,omitempty"`
 
 	RPS              uint          `json:"rps,omitempty"`
 	LoadSchedule     string        `json:"load-schedule"`
@@ -55,12 +176,24 @@ type Options struct {
 	CStep         int           `json:"concurrency-step"`
 	CStepDuration time.Duration `json:"concurrency-step-duration"`
 	CMaxDuration  time.Duration `json:"concurrency-max-duration"`
+	WorkerStagger time.Duration `json:"worker-stagger,omitempty"`
+	WorkerPace    string        `json:"worker-pace,omitempty"`
+	WorkerGroups  []string      `json:"worker-groups,omitempty"`
 
 	Total uint `json:"total,omitempty"`
 	Async bool `json:"async,omitempty"`
 
+	// ConnectionMode is the mode of assigning the workers to the connections, with the workers
+	// of each connection of the packed mode or the connection of each worker of the mapped mode
+	ConnectionMode       string `json:"connection-mode,omitempty"`
+	WorkersPerConnection uint   `json:"workers-per-connection,omitempty"`
+	ConnectionMap        []int  `json:"connection-map,omitempty"`
+
 	Connections   uint          `json:"connections,omitempty"`
 	Duration      time.Duration `json:"duration,omitempty"`
+	MaxDuration   time.Duration `json:"max-duration,omitempty"`
+	UntilSignal   bool          `json:"until-signal,omitempty"`
+	DrainTimeout  time.Duration `json:"drain-timeout,omitempty"`
 	Timeout       time.Duration `json:"timeout,omitempty"`
 	DialTimeout   time.Duration `json:"dial-timeout,omitempty"`
 	KeepaliveTime time.Duration `json:"keepalive,omitempty"`
@@ -69,18 +202,98 @@ type Options struct {
 	Binary   bool               `json:"binary"`
 	Metadata *map[string]string `json:"metadata,omitempty"`
 
+	// MetadataLists are the metadata values rotated per request
+	MetadataLists map[string][]string `json:"metadata-lists,omitempty"`
+
+	// Scenario are the weighted calls made instead of the call
+	Scenario     []ScenarioCall `json:"scenario,omitempty"`
+	ScenarioMode string         `json:"scenario-mode,omitempty"`
+	ScenarioRate uint           `json:"scenario-rate,omitempty"`
+
+	// Ratio are the methods and weights interleaved instead of the call
+	Ratio string `json:"ratio,omitempty"`
+
+	// Tenants are the names of the tenants the calls are made under in turn
+	Tenants []string `json:"tenants,omitempty"`
+
 	CPUs int    `json:"CPUs"`
 	Name string `json:"name,omitempty"`
 
-	SkipFirst   uint `json:"skipFirst,omitempty"`
-	CountErrors bool `json:"count-errors,omitempty"`
+	SkipFirst   uint   `json:"skipFirst,omitempty"`
+	CountErrors bool   `json:"count-errors,omitempty"`
+	DataLabel   string `json:"data-label,omitempty"`
+
+	DataPartition bool `json:"data-partition,omitempty"`
+
+	EmitDefaults bool `json:"emit-defaults,omitempty"`
+
+	// DataRedact and DataTokenize are the field paths of the call data anonymized, the key of the tokens being omitted
+	DataRedact   []string `json:"data-redact,omitempty"`
+	DataTokenize []string `json:"data-tokenize,omitempty"`
+
+	DataRewriteKeys []string `json:"data-rewrite-key,omitempty"`
+
+	// ProtoDefaults are the options set by the ghz options of the proto
+	ProtoDefaults []string `json:"proto-defaults,omitempty"`
+
+	CorrectionInterval time.Duration `json:"co-interval,omitempty"`
+	TraceSample        float64       `json:"trace-sample,omitempty"`
+	AsyncSenders       uint          `json:"async-senders,omitempty"`
+	AsyncHandlers      uint          `json:"async-handlers,omitempty"`
+	GracePeriod        time.Duration `json:"grace-period,omitempty"`
+	Warmup             time.Duration `json:"warmup,omitempty"`
+	HedgePercent       float64       `json:"hedge-percent,omitempty"`
+	HedgeDelay         time.Duration `json:"hedge-delay,omitempty"`
+	LatencyTarget      time.Duration `json:"latency-target,omitempty"`
+	RunIDHeader        string        `json:"run-id-header,omitempty"`
+	CallMaxDuration    time.Duration `json:"call-max-duration,omitempty"`
+	StreamMaxDuration  time.Duration `json:"stream-max-duration,omitempty"`
+	StreamRecvDelay    time.Duration `json:"stream-recv-delay,omitempty"`
+	ResponseField      string        `json:"response-field,omitempty"`
+	Assertions         []string      `json:"assertions,omitempty"`
+	StageTiming        string        `json:"stage-timing,omitempty"`
+	Paginate           bool          `json:"paginate,omitempty"`
+	PageTokenFields    string        `json:"page-token-fields,omitempty"`
+	MaxPages           uint          `json:"max-pages,omitempty"`
+	ShardKey           string        `json:"shard-key,omitempty"`
+	ShardConns         uint          `json:"shard-conns,omitempty"`
+	ChurnInterval      time.Duration `json:"churn-interval,omitempty"`
+	ChurnRequests      uint          `json:"churn-requests,omitempty"`
+	ConnectPolicy      string        `json:"connect-policy,omitempty"`
+	ConnectRetries     uint          `json:"connect-retries,omitempty"`
+	Baseline           uint          `json:"baseline,omitempty"`
+	ControlFile        string        `json:"control-file,omitempty"`
+	RateSocket         string        `json:"rate-socket,omitempty"`
+	ReflectRefresh     bool          `json:"reflect-refresh,omitempty"`
+	ServerInfo         bool          `json:"server-info,omitempty"`
+	ServerVersionCall  string        `json:"server-version-call,omitempty"`
+	Canary             string        `json:"canary,omitempty"`
+	ApdexThreshold     time.Duration `json:"apdex-threshold,omitempty"`
+	TimeSeries         time.Duration `json:"time-series,omitempty"`
+	TimeSlice          time.Duration `json:"time-slice,omitempty"`
+	RawHistogram       bool          `json:"raw-histogram,omitempty"`
+	DeadlineBudget     bool          `json:"deadline-budget,omitempty"`
+	HeaderLatency      bool          `json:"header-latency,omitempty"`
+	MaxErrors          uint          `json:"max-errors,omitempty"`
+
+	// StreamingPercentiles is whether the latency distribution is computed from the raw histogram
+	StreamingPercentiles bool `json:"streaming-percentiles,omitempty"`
 }
 
 // Report holds the data for the full test
 type Report struct {
+	RunID     string     `json:"runId,omitempty"`
 	Name      string     `json:"name,omitempty"`
 	EndReason StopReason `json:"endReason,omitempty"`
 
+	// StopError is the error of the call that stopped the run once the maximum number of
+	// errors was reached
+	StopError string `json:"stopError,omitempty"`
+
+	// Rotation is the number of the rotation for partial reports of the results
+	// within a rotation interval. It is 0 for the report of the full run.
+	Rotation int `json:"rotation,omitempty"`
+
 	Options Options   `json:"options,omitempty"`
 	Date    time.Time `json:"date"`
 
@@ -94,16 +307,197 @@ type Report struct {
 	ErrorDist      map[string]int `json:"errorDistribution"`
 	StatusCodeDist map[string]int `json:"statusCodeDistribution"`
 
+	// StatusBreakdown holds the failed calls by status code, if the error breakdown is enabled
+	StatusBreakdown []StatusBreakdown `json:"statusBreakdown,omitempty"`
+
+	Thresholds []ThresholdResult `json:"thresholds,omitempty"`
+
+	// Comparison is the comparison with the baseline report, if any
+	Comparison *Comparison `json:"comparison,omitempty"`
+
+	// Assertions are the number of calls which passed and failed each assertion
+	Assertions []AssertionStats `json:"assertions,omitempty"`
+
+	Backoff []load.AdaptiveAction `json:"backoff,omitempty"`
+
+	LatencyControl []load.LatencyAction `json:"latencyControl,omitempty"`
+
+	Stream *StreamStats `json:"stream,omitempty"`
+
+	StreamAcks *StreamAckStats `json:"streamAcks,omitempty"`
+
+	Backpressure *BackpressureStats `json:"backpressure,omitempty"`
+
+	ResponseField *ResponseFieldStats `json:"responseField,omitempty"`
+
+	// SchemaDrift holds the fields of the responses unknown to the method descriptor
+	SchemaDrift *SchemaDrift `json:"schemaDrift,omitempty"`
+
+	Pagination *PaginationStats `json:"pagination,omitempty"`
+
+	// Iterations holds the iterations of the scenario paced by the scenario rate
+	Iterations *IterationStats `json:"iterations,omitempty"`
+
+	// Tenants holds the statistics of the tenants in the multi-tenant fairness mode
+	Tenants *TenantFairness `json:"tenants,omitempty"`
+
+	// StageTiming are the server-side stage timings reported in the response metadata
+	StageTiming *StageTimingStats `json:"stageTiming,omitempty"`
+
+	// SchemaChanges are the changes of the method descriptor refreshed via reflection during the run
+	SchemaChanges []SchemaChange `json:"schemaChanges,omitempty"`
+
+	SchedulerLag *SchedulerLag `json:"schedulerLag,omitempty"`
+
+	// HeaderLatency holds the time to the response headers of unary calls
+	HeaderLatency *HeaderLatency `json:"headerLatency,omitempty"`
+
+	// Baseline holds the overhead of the client and the latency less the overhead
+	Baseline *Baseline `json:"baseline,omitempty"`
+
+	// Phases are the statistics of the phases of the load and concurrency schedules
+	Phases []SchedulePhase `json:"phases,omitempty"`
+
+	// Pacing holds the effective rates of the workers with the worker pacing
+	Pacing *PacingStats `json:"pacing,omitempty"`
+
+	AsyncQueue *AsyncQueueStats `json:"asyncQueue,omitempty"`
+
+	DeadlineBudget *DeadlineBudget `json:"deadlineBudget,omitempty"`
+
+	GraceRetries *GraceRetries `json:"graceRetries,omitempty"`
+
+	// StreamMessages holds the messages of the streams of streaming calls
+	StreamMessages *StreamMessages `json:"streamMessages,omitempty"`
+
+	// Bandwidth holds the bytes sent and received by the calls
+	Bandwidth *Bandwidth `json:"bandwidth,omitempty"`
+
+	// Drain holds the calls in flight when the run was stopped with the drain action
+	Drain *DrainStats `json:"drain,omitempty"`
+
+	// Hedging holds the results of the hedged calls and of the control group
+	Hedging *HedgeStats `json:"hedging,omitempty"`
+
+	// Compression holds the bytes of the messages before and after compression, if compressed
+	Compression *CompressionStats `json:"compression,omitempty"`
+
+	// Decompression holds the sizes of the compressed responses and the time taken to decompress them, if any
+	Decompression *DecompressionStats `json:"decompression,omitempty"`
+
+	// Upload holds the upload throughput of the chunked payload file, if any
+	Upload *UploadStats `json:"upload,omitempty"`
+
+	// Alerts are the events of the alerts emitted while the run was in progress
+	Alerts []AlertEvent `json:"alerts,omitempty"`
+
+	// Canary holds the results of the canary calls compared with the snapshot
+	Canary *CanaryStats `json:"canary,omitempty"`
+
+	// Traces are the sampled traces, slowest first
+	Traces []Trace `json:"traces,omitempty"`
+
+	Connections []ConnectionStats `json:"connections,omitempty"`
+
+	Shards *ShardStats `json:"shards,omitempty"`
+
+	// Churn is the statistics of the re-dialed connections
+	Churn *ChurnStats `json:"churn,omitempty"`
+
+	// Scaling is the statistics of the connections added while the calls queued for streams
+	Scaling *ScalingStats `json:"scaling,omitempty"`
+
+	// Connect is the statistics of establishing the connections with a connect policy
+	Connect *ConnectStats `json:"connect,omitempty"`
+
+	// Metrics are the custom metrics derived from the report
+	Metrics []DerivedMetric `json:"metrics,omitempty"`
+
+	// Server is the identity of the server tested
+	Server *ServerInfo `json:"server,omitempty"`
+
+	// Readiness is the wait for the server to be ready before the load started
+	Readiness *ReadinessStats `json:"readiness,omitempty"`
+
+	// Adjustments are the changes of the load made while the test was running
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+
+	// Channelz are the snapshots of the channelz statistics of the client connections
+	Channelz []ChannelzSnapshot `json:"channelz,omitempty"`
+
+	// Recommendations are the tuning recommendations derived from the statistics of the run
+	Recommendations []Recommendation `json:"recommendations,omitempty"`
+
 	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
-	Histogram           []Bucket              `json:"histogram"`
-	Details             []ResultDetail        `json:"details"`
+
+	// TailLatency holds the percentiles above 99 with enough calls to tell them from the slowest latency
+	TailLatency []TailLatency `json:"tailLatency,omitempty"`
+
+	Corrected    *CorrectedLatency  `json:"corrected,omitempty"`
+	Apdex        *Apdex             `json:"apdex,omitempty"`
+	Normalized   *Normalized        `json:"normalized,omitempty"`
+	LabelLatency []LabelLatency     `json:"labelLatency,omitempty"`
+	WorkerGroups []WorkerGroupStats `json:"workerGroups,omitempty"`
+	Methods      []MethodStats      `json:"methods,omitempty"`
+	TimeSeries   []TimeWindow       `json:"timeSeries,omitempty"`
+	TimeSlices   []TimeSlice        `json:"timeSlices,omitempty"`
+	Histogram    []Bucket           `json:"histogram"`
+	RawHistogram *RawHistogram      `json:"rawHistogram,omitempty"`
+	Details      []ResultDetail     `json:"details"`
+
+	// DetailColumns holds the details in the compact columnar layout instead of Details
+	// when the compact details are enabled. They are encoded as the details in JSON.
+	DetailColumns *DetailColumns `json:"-"`
 
 	Tags map[string]string `json:"tags,omitempty"`
+
+	Client *ClientInfo `json:"client,omitempty"`
+}
+
+// ClientInfo describes the platform the test was run from
+type ClientInfo struct {
+	Hostname  string `json:"hostname,omitempty"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	GoVersion string `json:"goVersion"`
+	NumCPU    int    `json:"numCPU"`
+	CPUs      int    `json:"CPUs"`
+
+	Capabilities ClientCapabilities `json:"capabilities"`
+}
+
+func newClientInfo(c *RunConfig) *ClientInfo {
+	hostname, _ := os.Hostname()
+
+	return &ClientInfo{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		NumCPU:    runtime.NumCPU(),
+		CPUs:      c.cpus,
+
+		Capabilities: clientCapabilities(),
+	}
 }
 
 // MarshalJSON is custom marshal for report to properly format the date
 func (r Report) MarshalJSON() ([]byte, error) {
 	type Alias Report
+
+	// the compact details take the place of the details
+	if r.DetailColumns != nil {
+		return json.Marshal(&struct {
+			Date    string         `json:"date"`
+			Details *DetailColumns `json:"details"`
+			*Alias
+		}{
+			Date:    r.Date.Format(time.RFC3339),
+			Details: r.DetailColumns,
+			Alias:   (*Alias)(&r),
+		})
+	}
+
 	return json.Marshal(&struct {
 		Date string `json:"date"`
 		*Alias
@@ -113,12 +507,33 @@ func (r Report) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// EachDetail calls f with each detail of the report in order, decoding the compact details
+// one at a time rather than all at once. The detail passed to f must not be kept.
+func (r *Report) EachDetail(f func(d *ResultDetail)) {
+	reportDetails(r).each(f)
+}
+
+// ResultDetails returns the details of the report, decoding the compact details if any
+func (r *Report) ResultDetails() []ResultDetail {
+	if r.DetailColumns != nil {
+		return r.DetailColumns.Details()
+	}
+
+	return r.Details
+}
+
 // LatencyDistribution holds latency distribution data
 type LatencyDistribution struct {
 	Percentage int           `json:"percentage"`
 	Latency    time.Duration `json:"latency"`
 }
 
+// TailLatency holds the latency of a percentile above 99, such as 99.9
+type TailLatency struct {
+	Percentile float64       `json:"percentile"`
+	Latency    time.Duration `json:"latency"`
+}
+
 // Bucket holds histogram data
 type Bucket struct {
 	// The Mark for histogram bucket in seconds
@@ -137,11 +552,108 @@ type ResultDetail struct {
 	Latency   time.Duration `json:"latency"`
 	Error     string        `json:"error"`
 	Status    string        `json:"status"`
+	Label     string        `json:"label,omitempty"`
+
+	// Lag is the delay between the intended send time of a rate-paced call and
+	// the time it was actually sent, which is the timestamp less the latency
+	Lag time.Duration `json:"lag,omitempty"`
+
+	// HeaderLatency is the time from the start of a unary call to receiving the response headers
+	HeaderLatency time.Duration `json:"headerLatency,omitempty"`
+
+	// TraceID is the trace ID of sampled calls
+	TraceID string `json:"traceId,omitempty"`
+
+	// Worker is the ID of the worker which made the call
+	Worker string `json:"worker,omitempty"`
+
+	// Method is the name of the scenario call
+	Method string `json:"method,omitempty"`
+
+	// BytesSent and BytesReceived are the wire bytes of the request and response messages
+	BytesSent     uint64 `json:"bytesSent,omitempty"`
+	BytesReceived uint64 `json:"bytesReceived,omitempty"`
+
+	// MessagesSent and MessagesReceived are the number of messages of the stream of streaming calls
+	MessagesSent     uint64 `json:"messagesSent,omitempty"`
+	MessagesReceived uint64 `json:"messagesReceived,omitempty"`
+}
+
+// CorrectedLatency holds the latency statistics corrected for coordinated omission.
+// Every latency above the expected interval is assumed to have held back the requests
+// that would have been sent within it, so the missing latencies are added, decreasing
+// by the interval down to the interval itself.
+type CorrectedLatency struct {
+	// Interval is the expected interval between the requests of a worker
+	Interval time.Duration `json:"interval"`
+
+	// Count is the number of latencies including the added ones
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// SchedulerLag holds the statistics of the delay between the intended send time of
+// rate-paced calls and the time they were actually sent. The lag is the time calls
+// spent waiting for a worker, and is not included in the latency
+type SchedulerLag struct {
+	// Count is the number of rate-paced calls
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	Max                 time.Duration         `json:"max"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// HeaderLatency holds the statistics of the time from the start of unary calls to receiving
+// the response headers, that is the time to the first response of servers sending the headers
+// before processing the request. Calls without response headers, such as errors sent as
+// trailers only, are not counted.
+type HeaderLatency struct {
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// DeadlineBudget holds the distribution of the fraction of the deadline consumed by the calls,
+// showing how close to the timeout the service runs under load. A fraction of 1 is the full deadline.
+type DeadlineBudget struct {
+	// Timeout is the deadline of the calls
+	Timeout time.Duration `json:"timeout"`
+
+	// Count is the number of calls
+	Count        uint64               `json:"count"`
+	Average      float64              `json:"average"`
+	Max          float64              `json:"max"`
+	Distribution []BudgetDistribution `json:"distribution"`
+
+	// Exceeded is the number of calls that failed with the deadline exceeded
+	Exceeded uint64 `json:"exceeded"`
+}
+
+// BudgetDistribution is a percentile of the fraction of the deadline consumed by the calls
+type BudgetDistribution struct {
+	Percentage int     `json:"percentage"`
+	Fraction   float64 `json:"fraction"`
+}
+
+// LabelLatency holds the latency statistics of the calls made using data records with the same label
+type LabelLatency struct {
+	Label               string                `json:"label"`
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
 }
 
 func newReporter(results chan *callResult, c *RunConfig) *Reporter {
 
 	cap := min(c.n, maxResult)
+	if c.streamingPercentiles || c.compactDetails {
+		cap = 0
+	}
 
 	return &Reporter{
 		config:  c,
@@ -151,52 +663,333 @@ func newReporter(results chan *callResult, c *RunConfig) *Reporter {
 
 		statusCodeDist: make(map[string]int),
 		errorDist:      make(map[string]int),
+
+		failures:  newFailureTracker(c.errorTop, c.errorSamples),
+		raw:       newRawRecorder(c.rawHistogram || c.streamingPercentiles),
+		bandwidth: &bandwidthTracker{},
 	}
 }
 
 // Run runs the reporter
 func (r *Reporter) Run() {
 	var skipCount int
+	var tick <-chan time.Time
+
+	if r.config.rotateInterval > 0 && r.config.rotateFunc != nil {
+		ticker := time.NewTicker(r.config.rotateInterval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+		r.newPeriod(time.Now())
+	}
+
+	for {
+		select {
+		case res, ok := <-r.results:
+			if !ok {
+				// emit the remainder of the run only if it was rotated at least once
+				// otherwise the partial report would be the same as the final one
+				if r.rotations > 0 && r.period.totalCount > 0 {
+					r.rotate(time.Now())
+				}
+
+				r.done <- true
+				return
+			}
+
+			if skipCount < r.config.skipFirst {
+				skipCount++
+				continue
+			}
+
+			r.add(res)
+
+			if r.period != nil {
+				r.period.add(res)
+			}
+
+			if r.config.resultFunc != nil {
+				detail := r.resultDetail(res)
+				r.config.resultFunc(&detail)
+			}
+		case now := <-tick:
+			r.rotate(now)
+		}
+	}
+}
+
+// add adds the result to the gathered data
+func (r *Reporter) add(res *callResult) {
+	errStr := ""
+	r.totalCount++
+	r.totalLatenciesSec += res.duration.Seconds()
+	r.statusCodeDist[res.status]++
+	atomic.AddUint64(&r.liveCount, 1)
 
-	for res := range r.results {
-		if skipCount < r.config.skipFirst {
-			skipCount++
-			continue
+	if res.err != nil {
+		errStr = res.err.Error()
+		r.errorDist[errStr]++
+		n := atomic.AddUint64(&r.liveErrorCount, 1)
+
+		if r.config.maxErrors > 0 && n == uint64(r.config.maxErrors) {
+			r.maxErrorsErr = errStr
+
+			if r.onMaxErrors != nil {
+				r.onMaxErrors()
+			}
 		}
+	}
 
-		errStr := ""
-		r.totalCount++
-		r.totalLatenciesSec += res.duration.Seconds()
-		r.statusCodeDist[res.status]++
+	// the latency distribution of the streaming percentiles is computed from the raw histogram
+	if r.detailCount() < maxResult && !r.config.streamingPercentiles {
+		if r.config.compactDetails {
+			if r.columns == nil {
+				r.columns = newDetailColumns()
+			}
 
-		if res.err != nil {
-			errStr = res.err.Error()
-			r.errorDist[errStr]++
+			d := r.resultDetail(res)
+			r.columns.add(&d)
+		} else {
+			r.details = append(r.details, r.resultDetail(res))
 		}
+	}
 
-		if len(r.details) < maxResult {
-			r.details = append(r.details, ResultDetail{
-				Latency:   res.duration,
-				Timestamp: res.timestamp,
-				Status:    res.status,
-				Error:     errStr,
-			})
+	if r.messages != nil {
+		r.messages.add(res.sentMsgs, res.receivedMsgs)
+	}
+
+	r.bandwidth.add(res)
+
+	r.series.add(res)
+	r.slices.add(res)
+	r.rates.add(res)
+	r.tenants.add(res)
+	r.failures.add(res)
+
+	if res.err == nil || r.config.countErrors {
+		r.raw.record(res.duration)
+	}
+
+	if res.traceID != "" && len(r.traces) < maxResult {
+		r.traces = append(r.traces, Trace{
+			TraceID:   res.traceID,
+			Timestamp: res.timestamp,
+			Latency:   res.duration,
+			Status:    res.status,
+			Error:     errStr,
+		})
+	}
+
+	if res.paced && len(r.lags) < maxResult {
+		r.lags = append(r.lags, res.lag.Seconds())
+	}
+
+	if res.header > 0 && len(r.headers) < maxResult {
+		r.headers = append(r.headers, res.header.Seconds())
+	}
+
+	if r.config.latencyTarget > 0 {
+		r.recentLock.Lock()
+		r.recent = append(r.recent, res.duration.Seconds())
+		r.recentLock.Unlock()
+	}
+}
+
+// detailCount returns the number of details kept
+func (r *Reporter) detailCount() int {
+	return len(r.details) + r.columns.Len()
+}
+
+// resultDetail returns the details of the result
+func (r *Reporter) resultDetail(res *callResult) ResultDetail {
+	d := ResultDetail{
+		Latency:       res.duration,
+		Timestamp:     res.timestamp,
+		Status:        res.status,
+		Label:         res.label,
+		Lag:           res.lag,
+		HeaderLatency: res.header,
+		TraceID:       res.traceID,
+		Worker:        res.worker,
+		Method:        res.method,
+		BytesSent:     res.sent,
+		BytesReceived: res.received,
+	}
+
+	if res.err != nil {
+		d.Error = res.err.Error()
+	}
+
+	if r.messages != nil {
+		d.MessagesSent = res.sentMsgs
+		d.MessagesReceived = res.receivedMsgs
+	}
+
+	return d
+}
+
+// newPeriod starts gathering the results of a new rotation interval
+func (r *Reporter) newPeriod(start time.Time) {
+	r.period = &Reporter{
+		config:         r.config,
+		statusCodeDist: make(map[string]int),
+		errorDist:      make(map[string]int),
+
+		failures:  newFailureTracker(r.config.errorTop, r.config.errorSamples),
+		raw:       newRawRecorder(r.config.rawHistogram || r.config.streamingPercentiles),
+		bandwidth: &bandwidthTracker{},
+		groups:    r.groups,
+	}
+
+	if r.messages != nil {
+		r.period.messages = &streamMessages{}
+	}
+
+	r.periodStart = start
+}
+
+// rotate emits the partial report of the results since the last rotation
+func (r *Reporter) rotate(now time.Time) {
+	r.rotations++
+
+	rep := r.period.Finalize("", now.Sub(r.periodStart))
+	rep.Date = r.periodStart
+	rep.Rotation = r.rotations
+
+	r.newPeriod(now)
+
+	r.config.rotateFunc(rep)
+}
+
+// counts returns the number of results and errors so far
+func (r *Reporter) counts() (uint64, uint64) {
+	return atomic.LoadUint64(&r.liveCount), atomic.LoadUint64(&r.liveErrorCount)
+}
+
+// latencyFeedback returns the number of results so far and the p99 latency of the results
+// since it was last called, or 0 if there are none
+func (r *Reporter) latencyFeedback() (uint64, time.Duration) {
+	r.recentLock.Lock()
+	recent := r.recent
+	r.recent = nil
+	r.recentLock.Unlock()
+
+	count := atomic.LoadUint64(&r.liveCount)
+	if len(recent) == 0 {
+		return count, 0
+	}
+
+	sort.Float64s(recent)
+
+	// the last percentile is the p99
+	dist := latencies(recent)
+
+	return count, dist[len(dist)-1].Latency
+}
+
+// snapshot returns the current progress given the elapsed time of the run
+func (r *Reporter) snapshot(elapsed time.Duration) Snapshot {
+	r.snapshotLock.Lock()
+	defer r.snapshotLock.Unlock()
+
+	return r.takeSnapshot(elapsed)
+}
+
+// snapshotSince returns the progress since the recent snapshot with the sequence number,
+// or since the start of the run if it is 0
+func (r *Reporter) snapshotSince(elapsed time.Duration, seq uint64) (Snapshot, error) {
+	r.snapshotLock.Lock()
+	defer r.snapshotLock.Unlock()
+
+	var prev Snapshot
+	if seq > 0 {
+		// the snapshots are ordered by their sequence numbers
+		i := sort.Search(len(r.snapshots), func(i int) bool { return r.snapshots[i].Seq >= seq })
+		if i == len(r.snapshots) || r.snapshots[i].Seq != seq {
+			return Snapshot{}, ErrSnapshotNotFound
 		}
+
+		prev = r.snapshots[i]
+	}
+
+	s := r.takeSnapshot(elapsed)
+
+	// the counts and the elapsed time of the snapshots taken in order do not decrease
+	d := Snapshot{
+		Seq:        s.Seq,
+		Since:      seq,
+		Elapsed:    s.Elapsed - prev.Elapsed,
+		Count:      s.Count - prev.Count,
+		ErrorCount: s.ErrorCount - prev.ErrorCount,
+	}
+
+	if d.Elapsed > 0 {
+		d.Rps = float64(d.Count) / d.Elapsed.Seconds()
+	}
+
+	return d, nil
+}
+
+// takeSnapshot takes the next snapshot and adds it to the recent snapshots
+// must be called with the snapshot lock held
+func (r *Reporter) takeSnapshot(elapsed time.Duration) Snapshot {
+	r.snapshotSeq++
+
+	s := Snapshot{
+		Seq:        r.snapshotSeq,
+		Elapsed:    elapsed,
+		Count:      atomic.LoadUint64(&r.liveCount),
+		ErrorCount: atomic.LoadUint64(&r.liveErrorCount),
+	}
+
+	if elapsed > 0 {
+		s.Rps = float64(s.Count) / elapsed.Seconds()
+	}
+
+	if len(r.snapshots) == snapshotHistory {
+		r.snapshots = append(r.snapshots[:0], r.snapshots[1:]...)
 	}
-	r.done <- true
+
+	r.snapshots = append(r.snapshots, s)
+
+	return s
 }
 
 // Finalize all the gathered data into a final report
 func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report {
+	// the statistics derived from the compact details are computed by decoding one at a time
+	details := detailIter{details: r.details, columns: r.columns}
+
 	rep := &Report{
+		RunID:          r.config.runID,
 		Name:           r.config.name,
 		EndReason:      stopReason,
 		Date:           time.Now(),
+		Client:         newClientInfo(r.config),
 		Count:          r.totalCount,
 		Total:          total,
 		ErrorDist:      r.errorDist,
 		StatusCodeDist: r.statusCodeDist}
 
+	if stopReason == ReasonMaxErrors {
+		rep.StopError = r.maxErrorsErr
+	}
+
+	var codec string
+	if r.config.codec != nil {
+		codec = r.config.codec.Name()
+	}
+
+	var workerPace string
+	if r.config.workerPacing != nil {
+		workerPace = r.config.workerPacing.String()
+	}
+
+	var drainTimeout time.Duration
+	if r.config.zstop == "drain" {
+		drainTimeout = r.config.drainTimeout
+	}
+
 	rep.Options = Options{
 		Call:              r.config.call,
 		Host:              r.config.host,
@@ -204,6 +997,11 @@ func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report
 		Protoset:          r.config.protoset,
 		ImportPaths:       r.config.importPaths,
 		EnableCompression: r.config.enableCompression,
+		Codec:             codec,
+		Compressor:        r.config.compressor,
+		WaitForReady:      r.config.waitForReady,
+		MaxRecvMsgSize:    r.config.maxRecvMsgSize,
+		MaxSendMsgSize:    r.config.maxSendMsgSize,
 
 		CACert:    r.config.cacert,
 		Cert:      r.config.cert,
@@ -212,6 +1010,22 @@ func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report
 		SkipTLS:   r.config.skipVerify,
 		Insecure:  r.config.insecure,
 		Authority: r.config.authority,
+		Resolve:   resolveEntries(r.config.resolve),
+		Targets:   r.config.targets,
+
+		TargetOverrides: r.config.targetOverrides,
+
+		ServiceConfig: r.config.serviceConfig,
+		IPVersion:     r.config.ipVersion,
+
+		Transport: r.config.transport,
+		Proxy:     redactedURL(r.config.proxy),
+
+		OAuthTokenURL: r.config.oauthTokenURL,
+		OAuthClientID: r.config.oauthClientID,
+		OAuthScopes:   r.config.oauthScopes,
+		TokenFile:     r.config.tokenFile,
+		TokenRefresh:  r.config.tokenRefresh,
 
 		RPS:              uint(r.config.rps),
 		LoadSchedule:     r.config.loadSchedule,
@@ -228,12 +1042,22 @@ func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report
 		CStep:         r.config.cStep,
 		CStepDuration: r.config.cStepDuration,
 		CMaxDuration:  r.config.cMaxDuration,
+		WorkerStagger: r.config.workerStagger,
+		WorkerPace:    workerPace,
+		WorkerGroups:  r.config.workerGroups,
 
 		Total: uint(r.config.n),
 		Async: r.config.async,
 
+		ConnectionMode:       r.config.connMode,
+		WorkersPerConnection: uint(r.config.workersPerConn),
+		ConnectionMap:        r.config.connMap,
+
 		Connections:   uint(r.config.nConns),
 		Duration:      r.config.z,
+		MaxDuration:   r.config.x,
+		UntilSignal:   r.config.untilSignal,
+		DrainTimeout:  drainTimeout,
 		Timeout:       r.config.timeout,
 		DialTimeout:   r.config.dialTimeout,
 		KeepaliveTime: r.config.keepaliveTime,
@@ -243,26 +1067,99 @@ func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report
 		Name:        r.config.name,
 		SkipFirst:   uint(r.config.skipFirst),
 		CountErrors: r.config.countErrors,
+		DataLabel:   r.config.dataLabel,
+
+		DataPartition: r.config.dataPartition,
+
+		EmitDefaults: r.config.emitDefaults,
+
+		DataRedact:   r.config.dataRedact,
+		DataTokenize: r.config.dataTokenize,
+
+		DataRewriteKeys: r.config.dataRewriteKeys,
+
+		ProtoDefaults: r.config.protoDefaults,
+
+		CorrectionInterval: r.config.coInterval,
+		TraceSample:        r.config.traceSample,
+		AsyncSenders:       r.config.asyncSenders,
+		AsyncHandlers:      r.config.asyncHandlers,
+		GracePeriod:        r.config.gracePeriod,
+		Warmup:             r.config.warmup,
+		HedgePercent:       r.config.hedgePercent,
+		HedgeDelay:         r.config.hedgeDelay,
+		LatencyTarget:      r.config.latencyTarget,
+		RunIDHeader:        r.config.runIDHeader,
+		CallMaxDuration:    r.config.callMaxDuration,
+		StreamMaxDuration:  r.config.streamMaxDuration,
+		StreamRecvDelay:    r.config.streamRecvDelay,
+		ResponseField:      r.config.responseField,
+		Assertions:         r.config.assertions,
+		Scenario:           r.config.scenario,
+		ScenarioMode:       r.config.scenarioMode,
+		ScenarioRate:       r.config.scenarioRate,
+		Ratio:              r.config.ratio,
+		Tenants:            tenantNames(r.config.tenants),
+		StageTiming:        r.config.stageTiming,
+		Paginate:           r.config.paginate,
+		PageTokenFields:    r.config.pageTokenFields,
+		MaxPages:           r.config.maxPages,
+		ShardKey:           r.config.shardKey,
+		ShardConns:         r.config.shardConns,
+		ChurnInterval:      r.config.churnInterval,
+		ChurnRequests:      r.config.churnRequests,
+		ConnectPolicy:      r.config.connectPolicy,
+		ConnectRetries:     r.config.connectRetries,
+		Baseline:           uint(r.config.baseline),
+		ControlFile:        r.config.controlFile,
+		RateSocket:         r.config.rateSocket,
+		ReflectRefresh:     r.config.reflectRefresh,
+		ServerInfo:         r.config.serverInfo,
+		ServerVersionCall:  r.config.serverVersionCall,
+		Canary:             r.config.canaryCall,
+		ApdexThreshold:     r.config.apdexThreshold,
+		TimeSeries:         r.config.timeSeriesWindow,
+		TimeSlice:          r.config.timeSlice,
+		RawHistogram:       r.config.rawHistogram,
+		DeadlineBudget:     r.config.deadlineBudget,
+		HeaderLatency:      r.config.headerLatency,
+		MaxErrors:          r.config.maxErrors,
+
+		StreamingPercentiles: r.config.streamingPercentiles,
 	}
 
-	_ = json.Unmarshal(r.config.data, &rep.Options.Data)
+	// the anonymized data is not included since the report would leak its original values
+	if len(r.config.dataRedact) == 0 && len(r.config.dataTokenize) == 0 {
+		_ = json.Unmarshal(r.config.data, &rep.Options.Data)
+	}
 
-	_ = json.Unmarshal(r.config.metadata, &rep.Options.Metadata)
+	md, mdLists, _ := extractMetadataLists(r.config.metadata)
+	_ = json.Unmarshal(md, &rep.Options.Metadata)
+
+	for k, v := range r.config.metadataLists {
+		if mdLists == nil {
+			mdLists = make(map[string][]string)
+		}
+
+		mdLists[k] = v
+	}
+
+	rep.Options.MetadataLists = mdLists
 
 	_ = json.Unmarshal(r.config.tags, &rep.Tags)
 
-	if len(r.details) > 0 {
+	if details.Len() > 0 {
 		average := r.totalLatenciesSec / float64(r.totalCount)
 		rep.Average = time.Duration(average * float64(time.Second))
 
 		rep.Rps = float64(r.totalCount) / total.Seconds()
 
 		okLats := make([]float64, 0)
-		for _, d := range r.details {
+		details.each(func(d *ResultDetail) {
 			if d.Error == "" || rep.Options.CountErrors {
 				okLats = append(okLats, d.Latency.Seconds())
 			}
-		}
+		})
 		sort.Float64s(okLats)
 		if len(okLats) > 0 {
 			var fastestNum, slowestNum float64
@@ -271,16 +1168,362 @@ func (r *Reporter) Finalize(stopReason StopReason, total time.Duration) *Report
 
 			rep.Fastest = time.Duration(fastestNum * float64(time.Second))
 			rep.Slowest = time.Duration(slowestNum * float64(time.Second))
-			rep.Histogram = histogram(okLats, slowestNum, fastestNum)
+			if len(r.config.histogramBuckets) > 0 {
+				rep.Histogram = bucketHistogram(okLats, r.config.histogramBuckets)
+			} else {
+				rep.Histogram = histogram(okLats, slowestNum, fastestNum)
+			}
 			rep.LatencyDistribution = latencies(okLats)
+			rep.TailLatency = tailLatencies(len(okLats), func(p float64) time.Duration {
+				return time.Duration(okLats[percentileIndex(len(okLats), p)] * float64(time.Second))
+			})
+
+			if r.config.coInterval > 0 {
+				rep.Corrected = correctedLatencies(okLats, r.config.coInterval)
+			}
+		}
+
+		rep.LabelLatency = labelLatencies(details, rep.Options.CountErrors)
+		rep.WorkerGroups = r.groups.stats(details, rep.Options.CountErrors)
+		rep.Methods = methodStats(details, total, rep.Options.CountErrors)
+
+		if r.config.apdexThreshold > 0 {
+			rep.Apdex = apdex(details, r.config.apdexThreshold)
+		}
+
+		if r.columns != nil {
+			rep.DetailColumns = r.columns
+		} else {
+			rep.Details = r.details
 		}
+	}
+
+	if r.config.streamingPercentiles && r.totalCount > 0 {
+		average := r.totalLatenciesSec / float64(r.totalCount)
+		rep.Average = time.Duration(average * float64(time.Second))
+		rep.Rps = float64(r.totalCount) / total.Seconds()
+
+		if h := r.raw.histogram(); h.Count > 0 {
+			rep.Fastest = h.Min
+			rep.Slowest = h.Max
+			rep.Histogram = h.bucketHistogram(r.config.histogramBuckets)
+			rep.LatencyDistribution = h.latencies()
+			rep.TailLatency = tailLatencies(int(h.Count), h.Percentile)
+		}
+	}
+
+	if len(r.lags) > 0 {
+		rep.SchedulerLag = schedulerLag(r.lags)
+	}
+
+	if len(r.headers) > 0 {
+		rep.HeaderLatency = headerLatency(r.headers)
+	}
+
+	if r.config.deadlineBudget && r.config.timeout > 0 && details.Len() > 0 {
+		rep.DeadlineBudget = deadlineBudget(details, r.config.timeout)
+	}
+
+	if len(r.traces) > 0 {
+		rep.Traces = slowestTraces(r.traces)
+	}
+
+	if r.messages != nil {
+		rep.StreamMessages = r.messages.stats(total)
+	}
+
+	rep.Bandwidth = r.bandwidth.stats(total)
+
+	if r.series != nil {
+		rep.TimeSeries = r.series.windows(total)
+	}
+
+	if r.slices != nil {
+		rep.TimeSlices = r.slices.summaries(total)
+	}
+
+	if r.tenants != nil {
+		rep.Tenants = r.tenants.fairness(total)
+	}
+
+	if r.failures != nil {
+		rep.StatusBreakdown = r.failures.breakdown()
+	}
+
+	if r.config.rawHistogram {
+		rep.RawHistogram = r.raw.histogram()
+	}
+
+	for _, t := range r.config.statusThresholds {
+		rep.Thresholds = append(rep.Thresholds, t.Check(rep))
+	}
+
+	for _, m := range thresholdMetrics {
+		if t, ok := r.config.thresholds[m]; ok {
+			rep.Thresholds = append(rep.Thresholds, t.Check(m, rep))
+		}
+	}
+
+	if r.config.rpsTolerance > 0 {
+		check := ThroughputCheck{RPS: r.config.rps, Tolerance: r.config.rpsTolerance, Interval: r.config.rpsInterval}
+		if r.rates != nil {
+			rep.Thresholds = append(rep.Thresholds, check.checkCounts(r.rates.counts, total))
+		} else {
+			rep.Thresholds = append(rep.Thresholds, check.check(details, total))
+		}
+	}
 
-		rep.Details = r.details
+	if r.config.compareBaseline != nil {
+		rep.Comparison = CompareReports(r.config.compareBaseline, rep, r.config.compareTolerances)
 	}
 
 	return rep
 }
 
+// ThresholdsPassed returns whether all the thresholds and the throughput check have passed,
+// and none of the metrics regressed from the baseline report. It is true if there are no thresholds.
+func (r *Report) ThresholdsPassed() bool {
+	for _, t := range r.Thresholds {
+		if !t.Pass {
+			return false
+		}
+	}
+
+	return r.Comparison == nil || len(r.Comparison.Regressions()) == 0
+}
+
+// labelLatencies returns the latency statistics for each data label, sorted by label
+func labelLatencies(details detailIter, countErrors bool) []LabelLatency {
+	lats := make(map[string][]float64)
+	details.each(func(d *ResultDetail) {
+		if d.Label != "" && (d.Error == "" || countErrors) {
+			lats[d.Label] = append(lats[d.Label], d.Latency.Seconds())
+		}
+	})
+
+	if len(lats) == 0 {
+		return nil
+	}
+
+	res := make([]LabelLatency, 0, len(lats))
+	for label, l := range lats {
+		sort.Float64s(l)
+
+		var total float64
+		for _, v := range l {
+			total += v
+		}
+
+		res = append(res, LabelLatency{
+			Label:               label,
+			Count:               uint64(len(l)),
+			Average:             time.Duration(total / float64(len(l)) * float64(time.Second)),
+			Fastest:             time.Duration(l[0] * float64(time.Second)),
+			Slowest:             time.Duration(l[len(l)-1] * float64(time.Second)),
+			LatencyDistribution: latencies(l),
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Label < res[j].Label
+	})
+
+	return res
+}
+
+// correctedLatencies returns the latency statistics of the sorted latencies corrected for
+// coordinated omission, the same as recording the values with an expected interval using
+// HdrHistogram. As the added latencies can be many times the recorded ones they are
+// counted rather than stored.
+func correctedLatencies(lats []float64, interval time.Duration) *CorrectedLatency {
+	iv := int64(interval)
+
+	// the latencies in nanoseconds, the number of latencies added for each,
+	// being v-iv, v-2*iv, ... down to iv, and the running count of all of them
+	ns := make([]int64, len(lats))
+	added := make([]int64, len(lats))
+	counts := make([]int64, len(lats)+1)
+
+	var sum float64
+	for i, l := range lats {
+		v := int64(l * float64(time.Second))
+		ns[i] = v
+
+		if k := v/iv - 1; k > 0 {
+			added[i] = k
+		}
+
+		k := added[i]
+		counts[i+1] = counts[i] + k + 1
+		sum += float64(k+1)*float64(v) - float64(iv)*float64(k)*float64(k+1)/2
+	}
+
+	count := counts[len(lats)]
+
+	// countUpTo returns the number of corrected latencies not greater than x
+	countUpTo := func(x int64) int64 {
+		i := sort.Search(len(ns), func(i int) bool { return ns[i] > x })
+		n := counts[i]
+
+		for ; i < len(ns); i++ {
+			// v - j*iv <= x for j >= ceil((v-x)/iv)
+			if j := (ns[i] - x + iv - 1) / iv; j <= added[i] {
+				n += added[i] - j + 1
+			}
+		}
+
+		return n
+	}
+
+	c := &CorrectedLatency{
+		Interval: interval,
+		Count:    uint64(count),
+		Average:  time.Duration(sum / float64(count)),
+	}
+
+	pctls := []int{10, 25, 50, 75, 90, 95, 99}
+	c.LatencyDistribution = make([]LatencyDistribution, len(pctls))
+
+	for i, p := range pctls {
+		// the same rank as for the uncorrected latencies
+		ip := (float64(p) / 100.0) * float64(count)
+		di := int64(ip)
+		if ip == float64(di) {
+			di = di - 1
+		}
+
+		if di < 0 {
+			di = 0
+		}
+
+		// the smallest latency with more than di latencies up to it
+		lo, hi := int64(0), ns[len(ns)-1]
+		for lo < hi {
+			mid := lo + (hi-lo)/2
+			if countUpTo(mid) > di {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+
+		if lo > 0 {
+			c.LatencyDistribution[i] = LatencyDistribution{Percentage: p, Latency: time.Duration(lo)}
+		}
+	}
+
+	return c
+}
+
+func schedulerLag(lags []float64) *SchedulerLag {
+	sorted := append([]float64(nil), lags...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, l := range sorted {
+		sum += l
+	}
+
+	return &SchedulerLag{
+		Count:               uint64(len(sorted)),
+		Average:             time.Duration(sum / float64(len(sorted)) * float64(time.Second)),
+		Max:                 time.Duration(sorted[len(sorted)-1] * float64(time.Second)),
+		LatencyDistribution: latencies(sorted),
+	}
+}
+
+func headerLatency(headers []float64) *HeaderLatency {
+	sorted := append([]float64(nil), headers...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, h := range sorted {
+		sum += h
+	}
+
+	return &HeaderLatency{
+		Count:               uint64(len(sorted)),
+		Average:             time.Duration(sum / float64(len(sorted)) * float64(time.Second)),
+		Fastest:             time.Duration(sorted[0] * float64(time.Second)),
+		Slowest:             time.Duration(sorted[len(sorted)-1] * float64(time.Second)),
+		LatencyDistribution: latencies(sorted),
+	}
+}
+
+// deadlineBudget returns the distribution of the fraction of the timeout consumed by the calls
+func deadlineBudget(details detailIter, timeout time.Duration) *DeadlineBudget {
+	fractions := make([]float64, 0, details.Len())
+
+	b := &DeadlineBudget{Timeout: timeout, Count: uint64(details.Len())}
+
+	var sum float64
+	details.each(func(d *ResultDetail) {
+		f := d.Latency.Seconds() / timeout.Seconds()
+		fractions = append(fractions, f)
+		sum += f
+
+		if d.Status == codes.DeadlineExceeded.String() {
+			b.Exceeded++
+		}
+	})
+
+	sort.Float64s(fractions)
+
+	b.Average = sum / float64(len(fractions))
+	b.Max = fractions[len(fractions)-1]
+
+	for _, p := range []int{10, 25, 50, 75, 90, 95, 99} {
+		// same ranks as the latency distribution
+		ip := (float64(p) / 100.0) * float64(len(fractions))
+		di := int(ip)
+		if ip == float64(di) {
+			di = di - 1
+		}
+
+		if di < 0 {
+			di = 0
+		}
+
+		b.Distribution = append(b.Distribution, BudgetDistribution{Percentage: p, Fraction: fractions[di]})
+	}
+
+	return b
+}
+
+// tailPercentiles are the percentiles of the tail latency
+var tailPercentiles = []float64{99.9, 99.99}
+
+// tailLatencies returns the latencies of the tail percentiles of the count of latencies, leaving
+// out those with too few latencies to tell them from the slowest, such as p99.99 of 5000 latencies
+func tailLatencies(count int, percentile func(float64) time.Duration) []TailLatency {
+	var res []TailLatency
+	for _, p := range tailPercentiles {
+		if float64(count) < math.Round(100/(100-p)) {
+			break
+		}
+
+		res = append(res, TailLatency{Percentile: p, Latency: percentile(p)})
+	}
+
+	return res
+}
+
+// percentileIndex returns the index of the percentile of the count of sorted latencies,
+// the same as of the latency distribution
+func percentileIndex(count int, p float64) int {
+	ip := p / 100 * float64(count)
+	di := int(ip)
+	if ip == float64(di) {
+		di = di - 1
+	}
+
+	if di < 0 {
+		di = 0
+	}
+
+	return di
+}
+
 func latencies(latencies []float64) []LatencyDistribution {
 	pctls := []int{10, 25, 50, 75, 90, 95, 99}
 	data := make([]float64, len(pctls))
@@ -3,6 +3,9 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"runtime"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
@@ -56,10 +59,329 @@ func TestReport_CorrectDetails(t *testing.T) {
 	report := reporter.Finalize("stop reason", time.Second)
 
 	assert.Equal(t, 2, len(report.Details))
+	assert.NotNil(t, report.Client)
+	assert.Equal(t, runtime.GOOS, report.Client.OS)
+	assert.Equal(t, runtime.GOARCH, report.Client.Arch)
+	assert.Equal(t, runtime.NumCPU(), report.Client.NumCPU)
+	assert.Equal(t, clientCapabilities(), report.Client.Capabilities)
 	assert.Equal(t, ResultDetail{Error: "", Latency: cr1.duration, Status: cr1.status, Timestamp: cr1.timestamp}, report.Details[0])
 	assert.Equal(t, ResultDetail{Error: cr2.err.Error(), Latency: cr2.duration, Status: cr2.status, Timestamp: cr2.timestamp}, report.Details[1])
 }
 
+func TestReport_LabelLatency(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+	config, _ := NewConfig("call", "host")
+	reporter := newReporter(callResultsChan, config)
+
+	go reporter.Run()
+
+	now := time.Now()
+	for _, cr := range []callResult{
+		{status: "OK", duration: 10 * time.Millisecond, timestamp: now, label: "small"},
+		{status: "OK", duration: 30 * time.Millisecond, timestamp: now, label: "small"},
+		{status: "OK", duration: 200 * time.Millisecond, timestamp: now, label: "large"},
+		{status: "DeadlineExceeded", duration: time.Second, err: context.DeadlineExceeded, timestamp: now, label: "large"},
+		{status: "OK", duration: 50 * time.Millisecond, timestamp: now},
+	} {
+		cr := cr
+		callResultsChan <- &cr
+	}
+
+	close(callResultsChan)
+	<-reporter.done
+	report := reporter.Finalize("stop reason", time.Second)
+
+	assert.Len(t, report.LabelLatency, 2)
+
+	large := report.LabelLatency[0]
+	assert.Equal(t, "large", large.Label)
+	assert.Equal(t, uint64(1), large.Count)
+	assert.Equal(t, 200*time.Millisecond, large.Average)
+	assert.Equal(t, 200*time.Millisecond, large.Slowest)
+
+	small := report.LabelLatency[1]
+	assert.Equal(t, "small", small.Label)
+	assert.Equal(t, uint64(2), small.Count)
+	assert.Equal(t, 20*time.Millisecond, small.Average)
+	assert.Equal(t, 10*time.Millisecond, small.Fastest)
+	assert.Equal(t, 30*time.Millisecond, small.Slowest)
+	assert.Len(t, small.LatencyDistribution, 7)
+	assert.Equal(t, "small", report.Details[0].Label)
+}
+
+func TestReport_SchedulerLag(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+	config, _ := NewConfig("call", "host")
+	reporter := newReporter(callResultsChan, config)
+
+	go reporter.Run()
+
+	now := time.Now()
+	for _, cr := range []callResult{
+		{status: "OK", duration: 10 * time.Millisecond, timestamp: now, paced: true},
+		{status: "OK", duration: 10 * time.Millisecond, timestamp: now, paced: true, lag: 20 * time.Millisecond},
+		{status: "OK", duration: 10 * time.Millisecond, timestamp: now, paced: true, lag: 40 * time.Millisecond},
+	} {
+		cr := cr
+		callResultsChan <- &cr
+	}
+
+	close(callResultsChan)
+	<-reporter.done
+	report := reporter.Finalize("stop reason", time.Second)
+
+	assert.NotNil(t, report.SchedulerLag)
+	assert.Equal(t, uint64(3), report.SchedulerLag.Count)
+	assert.Equal(t, 20*time.Millisecond, report.SchedulerLag.Average)
+	assert.Equal(t, 40*time.Millisecond, report.SchedulerLag.Max)
+	assert.Len(t, report.SchedulerLag.LatencyDistribution, 7)
+	assert.Equal(t, 40*time.Millisecond, report.Details[2].Lag)
+
+	// the latency does not include the lag
+	assert.Equal(t, 10*time.Millisecond, report.Slowest)
+}
+
+func TestReport_Traces(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+	config, _ := NewConfig("call", "host", WithTraceSampling(0.5))
+	reporter := newReporter(callResultsChan, config)
+
+	go reporter.Run()
+
+	now := time.Now()
+	for _, cr := range []callResult{
+		{status: "OK", duration: 10 * time.Millisecond, timestamp: now, traceID: "a"},
+		{status: "OK", duration: 5 * time.Millisecond, timestamp: now},
+		{status: "Unavailable", err: errors.New("unavailable"), duration: 30 * time.Millisecond, timestamp: now, traceID: "b"},
+		{status: "OK", duration: 20 * time.Millisecond, timestamp: now, traceID: "c"},
+	} {
+		cr := cr
+		callResultsChan <- &cr
+	}
+
+	close(callResultsChan)
+	<-reporter.done
+	report := reporter.Finalize("stop reason", time.Second)
+
+	assert.Equal(t, 0.5, report.Options.TraceSample)
+	assert.Len(t, report.Traces, 3)
+	assert.Equal(t, "b", report.Traces[0].TraceID)
+	assert.Equal(t, 30*time.Millisecond, report.Traces[0].Latency)
+	assert.Equal(t, "Unavailable", report.Traces[0].Status)
+	assert.Equal(t, "unavailable", report.Traces[0].Error)
+	assert.Equal(t, "c", report.Traces[1].TraceID)
+	assert.Equal(t, "a", report.Traces[2].TraceID)
+	assert.Equal(t, "a", report.Details[0].TraceID)
+	assert.Empty(t, report.Details[1].TraceID)
+}
+
+func TestReport_DeadlineBudget(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+	config, _ := NewConfig("call", "host", WithTimeout(100*time.Millisecond), WithDeadlineBudget(true))
+	reporter := newReporter(callResultsChan, config)
+
+	go reporter.Run()
+
+	now := time.Now()
+	for i := 1; i <= 9; i++ {
+		callResultsChan <- &callResult{status: "OK", duration: time.Duration(i*10) * time.Millisecond, timestamp: now}
+	}
+
+	callResultsChan <- &callResult{status: "DeadlineExceeded", err: errors.New("deadline exceeded"),
+		duration: 100 * time.Millisecond, timestamp: now}
+
+	close(callResultsChan)
+	<-reporter.done
+	report := reporter.Finalize("stop reason", time.Second)
+
+	b := report.DeadlineBudget
+	assert.NotNil(t, b)
+	assert.Equal(t, 100*time.Millisecond, b.Timeout)
+	assert.Equal(t, uint64(10), b.Count)
+	assert.InDelta(t, 0.55, b.Average, 0.0001)
+	assert.InDelta(t, 1, b.Max, 0.0001)
+	assert.Equal(t, uint64(1), b.Exceeded)
+	assert.Len(t, b.Distribution, 7)
+	assert.Equal(t, 50, b.Distribution[2].Percentage)
+	assert.InDelta(t, 0.5, b.Distribution[2].Fraction, 0.0001)
+	assert.Equal(t, 99, b.Distribution[6].Percentage)
+	assert.InDelta(t, 1, b.Distribution[6].Fraction, 0.0001)
+
+	assert.True(t, report.Options.DeadlineBudget)
+
+	// the deadline budget is only included when enabled
+	config, _ = NewConfig("call", "host", WithTimeout(100*time.Millisecond))
+	reporter = newReporter(make(chan *callResult), config)
+	reporter.add(&callResult{status: "OK", duration: time.Millisecond, timestamp: now})
+	assert.Nil(t, reporter.Finalize("", time.Second).DeadlineBudget)
+
+	_, err := NewConfig("call", "host", WithTimeout(0), WithDeadlineBudget(true))
+	assert.EqualError(t, err, "deadline budget requires a timeout")
+}
+
+func TestReporter_snapshotSince(t *testing.T) {
+	config, _ := NewConfig("call", "host")
+	reporter := newReporter(make(chan *callResult), config)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		reporter.add(&callResult{status: "OK", duration: time.Millisecond, timestamp: now})
+	}
+
+	s1 := reporter.snapshot(time.Second)
+	assert.Equal(t, Snapshot{Seq: 1, Elapsed: time.Second, Count: 10, Rps: 10}, s1)
+
+	for i := 0; i < 30; i++ {
+		reporter.add(&callResult{status: "Unavailable", err: errors.New("unavailable"), duration: time.Millisecond, timestamp: now})
+	}
+
+	d, err := reporter.snapshotSince(4*time.Second, s1.Seq)
+	assert.NoError(t, err)
+	assert.Equal(t, Snapshot{Seq: 2, Since: 1, Elapsed: 3 * time.Second, Count: 30, ErrorCount: 30, Rps: 10}, d)
+
+	// relative to the start of the run
+	d, err = reporter.snapshotSince(4*time.Second, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, Snapshot{Seq: 3, Elapsed: 4 * time.Second, Count: 40, ErrorCount: 30, Rps: 10}, d)
+
+	_, err = reporter.snapshotSince(5*time.Second, 10)
+	assert.Equal(t, ErrSnapshotNotFound, err)
+
+	// only the recent snapshots are kept
+	for i := 0; i < snapshotHistory; i++ {
+		reporter.snapshot(5 * time.Second)
+	}
+
+	_, err = reporter.snapshotSince(5*time.Second, s1.Seq)
+	assert.Equal(t, ErrSnapshotNotFound, err)
+
+	d, err = reporter.snapshotSince(6*time.Second, snapshotHistory+3)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d.Elapsed)
+	assert.Zero(t, d.Count)
+}
+
+func TestReport_correctedLatencies(t *testing.T) {
+	interval := 10 * time.Millisecond
+
+	lats := []float64{0.002, 0.005, 0.008, 0.009, 0.012, 0.02, 0.035, 0.25, 1.3}
+
+	// the latencies recorded with the expected interval
+	var expected []float64
+	for _, l := range lats {
+		v := time.Duration(l * float64(time.Second))
+		expected = append(expected, v.Seconds())
+		for m := v - interval; m >= interval; m -= interval {
+			expected = append(expected, m.Seconds())
+		}
+	}
+	sort.Float64s(expected)
+
+	var sum float64
+	for _, l := range expected {
+		sum += l
+	}
+
+	c := correctedLatencies(lats, interval)
+	assert.Equal(t, interval, c.Interval)
+	assert.Equal(t, uint64(len(expected)), c.Count)
+	assert.InDelta(t, sum/float64(len(expected))*float64(time.Second), float64(c.Average), 1000)
+
+	for i, ld := range latencies(expected) {
+		assert.Equal(t, ld.Percentage, c.LatencyDistribution[i].Percentage)
+		assert.InDelta(t, float64(ld.Latency), float64(c.LatencyDistribution[i].Latency), 1000)
+	}
+
+	t.Run("interval above latencies", func(t *testing.T) {
+		c := correctedLatencies(lats, 2*time.Second)
+		assert.Equal(t, uint64(len(lats)), c.Count)
+
+		for i, ld := range latencies(lats) {
+			assert.InDelta(t, float64(ld.Latency), float64(c.LatencyDistribution[i].Latency), 1000)
+		}
+	})
+}
+
+func TestReport_Rotation(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+	rotated := make(chan *Report, 100)
+	config, _ := NewConfig("call", "host", WithRotation(50*time.Millisecond, func(report *Report) {
+		rotated <- report
+	}))
+	reporter := newReporter(callResultsChan, config)
+
+	start := time.Now()
+	go reporter.Run()
+
+	for i := 0; i < 3; i++ {
+		callResultsChan <- &callResult{status: "OK", duration: 10 * time.Millisecond, timestamp: time.Now()}
+	}
+
+	first := <-rotated
+	assert.Equal(t, 1, first.Rotation)
+	assert.Equal(t, uint64(3), first.Count)
+	assert.Len(t, first.Details, 3)
+	assert.False(t, first.Date.Before(start))
+	assert.Equal(t, map[string]int{"OK": 3}, first.StatusCodeDist)
+
+	callResultsChan <- &callResult{status: "OK", duration: 20 * time.Millisecond, timestamp: time.Now()}
+	callResultsChan <- &callResult{status: "Unavailable", duration: 30 * time.Millisecond, err: context.Canceled, timestamp: time.Now()}
+
+	close(callResultsChan)
+	<-reporter.done
+	close(rotated)
+
+	report := reporter.Finalize("stop reason", time.Second)
+	assert.Equal(t, 0, report.Rotation)
+	assert.Equal(t, uint64(5), report.Count)
+	assert.Len(t, report.Details, 5)
+
+	// the partial reports cover the whole run
+	count := first.Count
+	n := 1
+	for r := range rotated {
+		n++
+		assert.Equal(t, n, r.Rotation)
+		count += r.Count
+	}
+
+	assert.True(t, n > 1)
+	assert.Equal(t, uint64(5), count)
+}
+
+func TestReport_ResultSink(t *testing.T) {
+	callResultsChan := make(chan *callResult)
+
+	var results []ResultDetail
+	config, _ := NewConfig("call", "host",
+		WithSkipFirst(1),
+		WithRotation(time.Hour, func(report *Report) {}),
+		WithResultSink(func(result *ResultDetail) {
+			results = append(results, *result)
+		}))
+	reporter := newReporter(callResultsChan, config)
+
+	go reporter.Run()
+
+	callResultsChan <- &callResult{status: "OK", duration: 5 * time.Millisecond, timestamp: time.Now()}
+	callResultsChan <- &callResult{status: "OK", duration: 10 * time.Millisecond, timestamp: time.Now(), worker: "g0c0"}
+	callResultsChan <- &callResult{status: "Unavailable", duration: 20 * time.Millisecond, err: context.Canceled, timestamp: time.Now()}
+
+	close(callResultsChan)
+	<-reporter.done
+
+	// the skipped result is not passed, and the results are passed once with the rotation
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, 10*time.Millisecond, results[0].Latency)
+		assert.Equal(t, "g0c0", results[0].Worker)
+		assert.Equal(t, "Unavailable", results[1].Status)
+		assert.Equal(t, context.Canceled.Error(), results[1].Error)
+	}
+
+	report := reporter.Finalize("stop reason", time.Second)
+	assert.Equal(t, results, report.Details)
+}
+
 func TestReport_latencies(t *testing.T) {
 	var tests = []struct {
 		input    []float64
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 
 	"go.uber.org/multierr"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
@@ -33,16 +35,70 @@ type callResult struct {
 	status    string
 	duration  time.Duration
 	timestamp time.Time
+	label     string
+	lag       time.Duration // the scheduler lag of rate-paced calls
+	paced     bool
+	traceID   string // the trace ID of sampled calls
+	worker    string // the ID of the worker which made the call
+	sent      uint64 // the wire bytes of the request messages
+	received  uint64 // the wire bytes of the response messages
+
+	// the number of messages sent and received, which are more than one for streaming calls
+	sentMsgs     uint64
+	receivedMsgs uint64
+
+	// the time to the response headers of unary calls, 0 if none were received
+	header time.Duration
+
+	// the trailer of failed calls, only captured for the samples of the error breakdown
+	trailer metadata.MD
+
+	// the name of the scenario call, if any
+	method string
+
+	// the tenant the call was made under, if any
+	tenant string
 }
 
 // Requester is used for doing the requests
 type Requester struct {
 	conns    []*grpc.ClientConn
+	webConns []*webConn
 	stubs    []grpcdynamic.Stub
 	handlers []*statsHandler
 
 	mtd      *desc.MethodDescriptor
 	reporter *Reporter
+	backoff  *load.AdaptivePacer
+	latency  *load.LatencyPacer
+	control  *load.OverridePacer
+	shared   *load.SharedPacer
+	stream   *streamTracker
+	acks     *ackTracker
+	fields   *fieldTracker
+	drift    *driftTracker
+	pages    *paginator
+	stages   *stageTiming
+
+	// the assertions of the calls, if any
+	assertions *assertionTracker
+	schema     *schemaRefresher
+
+	// the weighted calls made instead of the call of the run, if any
+	scenario *scenario
+
+	// the iterations of the scenario with scenario pacing
+	iterations *iterationTracker
+
+	// the tenants the calls are made under in the fairness mode
+	tenants *tenants
+
+	backpressure *backpressureTracker
+
+	sessionMtd      *desc.MethodDescriptor
+	sessionCloseMtd *desc.MethodDescriptor
+	versionMtd      *desc.MethodDescriptor
+	readinessMtd    *desc.MethodDescriptor
 
 	config *RunConfig
 
@@ -50,12 +106,54 @@ type Requester struct {
 	stopCh  chan bool
 	start   time.Time
 
+	// the CPU time of the process at the start of the run
+	cpuStart time.Duration
+
 	dataProvider     DataProviderFunc
+	indexedData      *indexedDataProvider
 	metadataProvider MetadataProviderFunc
-
-	lock       sync.Mutex
-	stopReason StopReason
-	workers    []*Worker
+	payload          *chunkedPayload
+	dataEnd          chan struct{}
+	dataEndOnce      sync.Once
+	debugger         *callDebugger
+	slowCalls        *slowCallLogger
+	capture          *responseCapture
+	wireLog          *wireLogger
+	async            *asyncQueue
+	grace            *gracePeriod
+	drain            *drainTracker
+	metrics          *liveMetrics
+	vars             *debugVars
+	push             *statsPusher
+	groups           *workerGroups
+	alerts           *alertMonitor
+	hedge            *hedgeTracker
+	shards           *shardRouter
+	churn            *connChurner
+	scaling          *connScaler
+	connect          *ConnectStats
+	baseline         *Baseline
+	channelz         *channelzCollector
+	server           *ServerInfo
+	readiness        *ReadinessStats
+	canary           *canaryProbe
+	phases           *phaseTracker
+	decompression    *decompressionTracker
+
+	concurrency chan uint
+	workersDone chan struct{}
+
+	// closed once the paused test is resumed, nil while it is not paused
+	resume chan struct{}
+
+	lock        sync.Mutex
+	stopReason  StopReason
+	stopped     bool
+	workers     []*Worker
+	adjustments []Adjustment
+
+	// whether the workers keep their last response for the templates
+	lastResponse bool
 }
 
 // NewRequester creates a new requestor from the passed RunConfig
@@ -72,43 +170,93 @@ func NewRequester(c *RunConfig) (*Requester, error) {
 		workers:    make([]*Worker, 0, c.c),
 		conns:      make([]*grpc.ClientConn, 0, c.nConns),
 		stubs:      make([]grpcdynamic.Stub, 0, c.nConns),
+		debugger:   newCallDebugger(c.debugOut, c.debugCalls, c.debugErrors),
+		slowCalls:  newSlowCallLogger(c.debugOut, c.slowThreshold, c.slowMax, c.slowCapture),
+		wireLog:    newWireLogger(c.wireLogOut, c.wireLogCall),
+		async:      newAsyncQueue(c.asyncSenders, c.asyncHandlers),
+		grace:      newGracePeriod(c.gracePeriod),
+		dataEnd:    make(chan struct{}),
+
+		concurrency: make(chan uint),
+		workersDone: make(chan struct{}),
+	}
+
+	if c.zstop == "drain" {
+		reqr.drain = newDrainTracker(c.drainTimeout)
+	}
+
+	// the debug endpoint publishes the workers and the calls in flight tracked by the live metrics
+	if c.metricsAddr != "" || c.debugAddr != "" {
+		reqr.metrics = newLiveMetrics()
+	}
+
+	reqr.vars = newDebugVars(c)
+
+	reqr.tenants = newTenants(c)
+	reqr.push = newStatsPusher(c)
+	reqr.groups = newWorkerGroups(c)
+	reqr.alerts = newAlertMonitor(c)
+
+	reqr.hedge = newHedgeTracker(c.hedgePercent, c.hedgeDelay)
+
+	var getMethod func(call string) (*desc.MethodDescriptor, error)
+
+	// the descriptors resolved via reflection, if any
+	var reflected *reflectedFiles
+
+	if (c.reflectProtoset != "" || c.reflectCache != "") && (c.proto != "" || c.protoset != "") {
+		return nil, fmt.Errorf("the protoset export and the reflection cache require the method to be resolved via reflection")
 	}
 
 	if c.proto != "" {
-		mtd, err = protodesc.GetMethodDescFromProto(c.call, c.proto, c.importPaths)
+		getMethod = func(call string) (*desc.MethodDescriptor, error) {
+			return protodesc.GetMethodDescFromProto(call, c.proto, c.importPaths)
+		}
 	} else if c.protoset != "" {
-		mtd, err = protodesc.GetMethodDescFromProtoSet(c.call, c.protoset)
+		getMethod = func(call string) (*desc.MethodDescriptor, error) {
+			return protodesc.GetMethodDescFromProtoSet(call, c.protoset)
+		}
 	} else {
 		// use reflection to get method descriptor
 		var cc *grpc.ClientConn
-		// temporary connection for reflection, do not store as requester connections
-		cc, err = reqr.newClientConn(false)
-		if err != nil {
-			return nil, err
-		}
 
 		defer func() {
 			// purposefully ignoring error as we do not care if there
 			// is an error on close
-			_ = cc.Close()
+			if cc != nil {
+				_ = cc.Close()
+			}
 		}()
 
-		// cancel is ignored here as connection.Close() is used.
-		// See https://godoc.org/google.golang.org/grpc#DialContext
-		ctx, _ := context.WithTimeout(context.Background(), c.dialTimeout)
+		// the reflection requests are bounded by the request timeout
+		ctx, cancel := requestContext(c.timeout)
+		defer cancel()
 
-		md := make(metadata.MD)
-		if c.rmd != nil && len(c.rmd) > 0 {
-			md = metadata.New(c.rmd)
-		}
+		// the connection is only made if a method is not found in the reflection cache
+		reflected = newReflectedFiles(c, func() (*grpcreflect.Client, error) {
+			var err error
 
-		refCtx := metadata.NewOutgoingContext(ctx, md)
+			// temporary connection for reflection, do not store as requester connections
+			cc, err = reqr.newClientConn(c.host, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			md := make(metadata.MD)
+			if c.rmd != nil && len(c.rmd) > 0 {
+				md = metadata.New(c.rmd)
+			}
+
+			refCtx := metadata.NewOutgoingContext(ctx, md)
 
-		refClient := grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(cc))
+			return grpcreflect.NewClient(refCtx, reflectpb.NewServerReflectionClient(cc)), nil
+		})
 
-		mtd, err = protodesc.GetMethodDescFromReflect(c.call, refClient)
+		getMethod = reflected.getMethod
 	}
 
+	mtd, err = getMethod(c.call)
+
 	if err != nil {
 		return nil, err
 	}
@@ -122,87 +270,592 @@ func NewRequester(c *RunConfig) (*Requester, error) {
 	// fill in the rest
 	reqr.mtd = mtd
 
-	if c.dataProviderFunc != nil {
+	// the calls of scenarios have their own data and metadata
+	if len(c.scenario) == 0 && !c.ignoreProtoDefaults {
+		if c.protoDefaults, err = applyProtoDefaults(c, mtd); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.scenario) > 0 {
+		if reqr.scenario, err = newScenario(c, getMethod); err != nil {
+			return nil, err
+		}
+
+		// the workers use the data and metadata of the call picked for each request
+		first := reqr.scenario.calls[0]
+		reqr.dataProvider, reqr.metadataProvider = first.dataProvider, first.metadataProvider
+
+		reqr.iterations = newIterationTracker(c, reqr.scenario)
+	} else if c.dataProviderFunc != nil {
 		reqr.dataProvider = c.dataProviderFunc
-	} else {
-		defaultDataProvider, err := newDataProvider(reqr.mtd, c.binary, c.dataFunc, c.data, c.funcs)
+	} else if c.dataReader != nil {
+		lazyDataProvider := newLazyDataProvider(reqr.mtd, c.dataReader)
+		lazyDataProvider.emitDefaults = c.emitDefaults
+		reqr.dataProvider = lazyDataProvider.getDataForCall
+	} else if c.dataIndexedPath != "" {
+		if reqr.indexedData, err = newIndexedDataProvider(reqr.mtd, c.dataIndexedPath, c.binary, c.dataSelection); err != nil {
+			return nil, err
+		}
+
+		reqr.indexedData.emitDefaults = c.emitDefaults
+		reqr.dataProvider = reqr.indexedData.getDataForCall
+	} else if reqr.dataProvider, err = newDefaultDataProvider(c, reqr.mtd); err != nil {
+		return nil, err
+	}
+
+	if len(c.dataRedact) > 0 || len(c.dataTokenize) > 0 {
+		an, err := newAnonymizer(reqr.mtd.GetInputType(), c.dataRedact, c.dataTokenize, c.dataTokenKey)
 		if err != nil {
+			reqr.indexedData.close()
+
 			return nil, err
 		}
-		reqr.dataProvider = defaultDataProvider.getDataForCall
+
+		reqr.dataProvider = an.wrap(reqr.dataProvider)
 	}
 
+	if len(c.dataRewriteKeys) > 0 {
+		rw, err := newKeyRewriter(reqr.mtd.GetInputType(), c.dataRewriteKeys)
+		if err != nil {
+			reqr.indexedData.close()
+
+			return nil, err
+		}
+
+		reqr.dataProvider = rw.wrap(reqr.dataProvider)
+	}
+
+	reqr.lastResponse = usesLastResponse(c.data, c.metadata)
+
 	if c.mdProviderFunc != nil {
 		reqr.metadataProvider = c.mdProviderFunc
-	} else {
+	} else if reqr.scenario == nil {
 		defaultMDProvider, err := newMetadataProvider(reqr.mtd, c.metadata, c.funcs)
 		if err != nil {
 			return nil, err
 		}
+
+		if err := defaultMDProvider.addLists(c.metadataLists); err != nil {
+			return nil, err
+		}
 		reqr.metadataProvider = defaultMDProvider.getMetadataForCall
 	}
 
+	if c.sessionCall != "" {
+		if reqr.sessionMtd, err = getSessionMethod(getMethod, c.sessionCall); err != nil {
+			return nil, err
+		}
+
+		if err = checkFieldPath(reqr.sessionMtd.GetOutputType(), c.sessionToken); err != nil {
+			return nil, fmt.Errorf("session token: %v", err)
+		}
+
+		if c.sessionCloseCall != "" {
+			if reqr.sessionCloseMtd, err = getSessionMethod(getMethod, c.sessionCloseCall); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.serverVersionCall != "" {
+		if reqr.versionMtd, err = getMethod(c.serverVersionCall); err != nil {
+			return nil, fmt.Errorf("server version call %s: %v", c.serverVersionCall, err)
+		}
+
+		if reqr.versionMtd.IsClientStreaming() || reqr.versionMtd.IsServerStreaming() {
+			return nil, fmt.Errorf("server version call %s must be unary", c.serverVersionCall)
+		}
+	}
+
+	if c.readinessCall != "" {
+		if reqr.readinessMtd, err = getMethod(c.readinessCall); err != nil {
+			return nil, fmt.Errorf("readiness call %s: %v", c.readinessCall, err)
+		}
+
+		if reqr.readinessMtd.IsClientStreaming() || reqr.readinessMtd.IsServerStreaming() {
+			return nil, fmt.Errorf("readiness call %s must be unary", c.readinessCall)
+		}
+	}
+
+	if reqr.canary, err = newCanaryProbe(c, getMethod); err != nil {
+		return nil, err
+	}
+
+	// all the methods are resolved by now
+	if reflected != nil {
+		if err = reflected.save(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.streamCorrelationField != "" {
+		if reqr.stream, err = newStreamTracker(reqr.mtd, c.streamCorrelationField); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.streamAckField != "" {
+		if reqr.acks, err = newAckTracker(reqr.mtd, c.streamAckField); err != nil {
+			return nil, err
+		}
+	}
+
+	reqr.decompression = newDecompressionTracker(c.compressorName())
+
+	if c.payloadPath != "" {
+		if reqr.payload, err = newChunkedPayload(reqr.mtd, c.payloadPath, c.payloadField, c.payloadChunkSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.streamRecvDelay > 0 {
+		if reqr.backpressure, err = newBackpressureTracker(reqr.mtd, c.streamRecvDelay); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.responseField != "" {
+		if reqr.fields, err = newFieldTracker(reqr.mtd, c.responseField); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.stageTiming != "" {
+		if reqr.stages, err = newStageTiming(c.stageTiming); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.assertions) > 0 || len(c.assertionFuncs) > 0 {
+		if reqr.assertions, err = newAssertionTracker(reqr.mtd, c.assertions, c.assertionFuncs); err != nil {
+			return nil, err
+		}
+
+		// the responses of the async pools are not decoded for the stats handler
+		if reqr.async != nil && reqr.assertions.responses() {
+			return nil, fmt.Errorf("response assertions are not supported with the async sender and response handler pools")
+		}
+	}
+
+	if c.schemaDrift {
+		reqr.drift = newDriftTracker()
+	}
+
+	if c.reflectRefresh {
+		if c.proto != "" || c.protoset != "" {
+			return nil, fmt.Errorf("reflection refresh requires the method to be resolved via reflection")
+		}
+
+		if c.dataProviderFunc != nil || c.dataReader != nil || c.dataIndexedPath != "" {
+			reqr.indexedData.close()
+
+			return nil, fmt.Errorf("reflection refresh is not supported with data provider functions, lazily read or indexed data")
+		}
+
+		reqr.schema = newSchemaRefresher(reqr)
+	}
+
+	if c.paginate {
+		if reqr.async != nil {
+			return nil, fmt.Errorf("pagination is not supported with the async sender and response handler pools")
+		}
+
+		if reqr.pages, err = newPaginator(reqr.mtd, c.pageTokenFields, c.maxPages); err != nil {
+			return nil, err
+		}
+	}
+
+	if reqr.async != nil && (mtd.IsClientStreaming() || mtd.IsServerStreaming()) {
+		return nil, fmt.Errorf("async sender and response handler pools are only supported for unary calls")
+	}
+
+	if reqr.hedge != nil && (mtd.IsClientStreaming() || mtd.IsServerStreaming()) {
+		return nil, fmt.Errorf("hedging is only supported for unary calls")
+	}
+
+	if c.headerLatency && (mtd.IsClientStreaming() || mtd.IsServerStreaming()) {
+		return nil, fmt.Errorf("header latency is only supported for unary calls")
+	}
+
+	if c.baseline > 0 && (mtd.IsClientStreaming() || mtd.IsServerStreaming()) {
+		return nil, fmt.Errorf("baseline is only supported for unary calls")
+	}
+
+	if c.transport == TransportGRPCWeb && (mtd.IsClientStreaming() || mtd.IsServerStreaming()) {
+		return nil, fmt.Errorf("gRPC-Web is only supported for unary calls")
+	}
+
+	// the capture file is created once nothing else can fail
+	if reqr.capture, err = newResponseCapture(c); err != nil {
+		return nil, fmt.Errorf("error creating response capture file: %v", err)
+	}
+
+	if c.estimateFunc != nil {
+		c.estimateFunc(estimateLoad(c))
+	}
+
 	return reqr, nil
 }
 
+// getSessionMethod returns the descriptor of a session call, which has to be unary
+func getSessionMethod(getMethod func(string) (*desc.MethodDescriptor, error), call string) (*desc.MethodDescriptor, error) {
+	mtd, err := getMethod(call)
+	if err != nil {
+		return nil, fmt.Errorf("session call %s: %v", call, err)
+	}
+
+	if mtd.IsClientStreaming() || mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("session call %s must be unary", call)
+	}
+
+	return mtd, nil
+}
+
+// RunWithContext makes all the requests like Run, stopping with ReasonCancel when the context
+// is cancelled or its deadline is exceeded. If the context is done before the run starts,
+// its error is returned without a report.
+func (b *Requester) RunWithContext(ctx context.Context) (*Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.Stop(ReasonCancel)
+		case <-done:
+		}
+	}()
+
+	return b.Run()
+}
+
 // Run makes all the requests and returns a report of results
-// It blocks until all work is done.
+// It blocks until all work is done, and stops the run once its duration or max duration is up.
 func (b *Requester) Run() (*Report, error) {
 
-	defer close(b.stopCh)
+	defer func() {
+		b.lock.Lock()
+		b.stopped = true
+		close(b.stopCh)
+		b.lock.Unlock()
+	}()
+
+	if cf := newControlFile(b.config.controlFile, b, b.config.debugOut); cf != nil {
+		done := make(chan struct{})
+		watched := make(chan struct{})
+
+		defer func() {
+			close(done)
+			<-watched
+		}()
+
+		go func() {
+			cf.watch(done)
+			close(watched)
+		}()
+	}
+
+	// the identity of the server is captured once it is ready
+	if b.config.serverInfo && !b.config.readiness {
+		if err := b.captureServerInfo(); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.config.rateSocket != "" {
+		shared, err := load.DialSharedPacer(b.config.rateSocket, uint64(b.config.n))
+		if err != nil {
+			return nil, err
+		}
+
+		defer shared.Close()
+
+		b.shared = shared
+	}
+
+	if b.config.metricsAddr != "" {
+		srv, err := b.metrics.serve(b.config.metricsAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		defer srv.Close()
+	}
+
+	if b.config.debugAddr != "" {
+		srv, err := b.serveDebug(b.config.debugAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		defer srv.Close()
+	}
+
+	// the overhead of the client is measured before the connections are open
+	if b.config.baseline > 0 {
+		bl, err := b.measureBaseline()
+		if err != nil {
+			return nil, err
+		}
+
+		b.lock.Lock()
+		b.baseline = bl
+		b.lock.Unlock()
+	}
 
 	cc, err := b.openClientConns()
 	if err != nil {
 		return nil, err
 	}
 
+	if b.config.readiness {
+		readiness, err := b.waitReady(cc)
+
+		b.lock.Lock()
+		b.readiness = readiness
+		b.lock.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if b.config.serverInfo {
+			if err := b.captureServerInfo(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the snapshot of the canary is captured before the load starts
+	if err = b.canary.open(b); err != nil {
+		return nil, err
+	}
+
+	var cz *channelzCollector
+	if b.config.channelz {
+		if cz, err = newChannelzCollector(b.config.host); err != nil {
+			return nil, err
+		}
+
+		b.lock.Lock()
+		b.channelz = cz
+		b.lock.Unlock()
+
+		go cz.run(b.config.channelzInterval)
+	}
+
 	start := time.Now()
 
+	// the run is stopped once its duration or max duration is up, whoever runs it,
+	// which leaves out the time taken to measure the baseline and to wait for the server
+	if limit := b.config.runLimit(); limit > 0 {
+		t := time.AfterFunc(limit, func() {
+			b.Stop(ReasonTimeout)
+		})
+		defer t.Stop()
+	}
+
 	b.lock.Lock()
 	b.start = start
+	b.cpuStart = processCPUTime()
+	b.phases = newPhaseTracker(b.config, start)
+	b.grace.begin(start)
 
 	// create a client stub for each connection
-	for n := 0; n < b.config.nConns; n++ {
+	for n := 0; n < len(cc); n++ {
 		stub := grpcdynamic.NewStub(cc[n])
 		b.stubs = append(b.stubs, stub)
 	}
 
+	for _, wc := range b.webConns {
+		b.stubs = append(b.stubs, grpcdynamic.NewStub(wc))
+	}
+
+	// the re-dialed connections keep the stats handlers of the connections, which are the first ones
+	targets := b.config.allTargets()
+	b.churn = newConnChurner(b.config.churnInterval, b.config.churnRequests, cc, func(n int) (*grpc.ClientConn, error) {
+		return b.newClientConn(targets[n%len(targets)], b.handlers[n])
+	})
+
+	// the added connections share a stats handler, so their statistics are those of a single connection
+	if b.config.scaleConns > 0 {
+		sh := b.newStatsHandler()
+		b.scaling = newConnScaler(b.config, cc, func(n int) (*grpc.ClientConn, error) {
+			return b.newClientConn(targets[n%len(targets)], sh)
+		})
+
+		for _, h := range b.handlers {
+			h.scaling = b.scaling
+		}
+	}
+
+	b.shards = newShardRouter(b.config.shardKey, b.stubs, cc)
+	if b.shards != nil && b.config.shardConns > 0 {
+		// the dedicated connections share a stats handler, so their statistics are those of a single connection
+		sh := b.newStatsHandler()
+		opened := 0
+		b.shards.pool = newShardConnPool(int(b.config.shardConns), func() (*grpc.ClientConn, error) {
+			opened++
+
+			return b.newClientConn(targets[(opened-1)%len(targets)], sh)
+		})
+	}
+
+	// the calls started during the warmup are not part of the results
+	if b.config.warmup > 0 {
+		for _, h := range b.handlers {
+			h.IgnoreBefore(start.Add(b.config.warmup))
+		}
+	}
+
 	b.reporter = newReporter(b.results, b.config)
+	b.reporter.groups = b.groups
+	b.reporter.series = newTimeSeries(b.config.timeSeriesWindow, start.Add(b.config.warmup), b.config.countErrors)
+	b.reporter.slices = newTimeSlices(b.config.timeSlice, start.Add(b.config.warmup), b.config.countErrors)
+	b.reporter.rates = newThroughputCounter(b.config, start.Add(b.config.warmup))
+	b.reporter.tenants = b.tenants
+	if b.mtd.IsClientStreaming() || b.mtd.IsServerStreaming() {
+		b.reporter.messages = &streamMessages{}
+	}
+	b.reporter.onMaxErrors = func() {
+		// stopping closes the connections, whose results have to be gathered meanwhile
+		go b.Stop(ReasonMaxErrors)
+	}
 	b.lock.Unlock()
 
 	go func() {
 		b.reporter.Run()
 	}()
 
+	stopPush := b.push.begin(start)
+	stopAlerts := b.alerts.begin(start)
+	stopCanary := b.canary.begin(start)
+	stopScaling := b.scaling.begin(start)
+
 	wt := createWorkerTicker(b.config)
 
 	p := createPacer(b.config)
+	if b.shared != nil {
+		p = b.shared
+	}
+
+	if b.phases != nil && b.config.loadSchedule != ScheduleConst {
+		b.phases.rate = p.Rate
+	}
+
+	if b.config.backoffErrorRate > 0 {
+		b.backoff = &load.AdaptivePacer{
+			Pacer:     p,
+			ErrorRate: b.config.backoffErrorRate / 100,
+			Interval:  b.config.backoffInterval,
+			Feedback:  b.reporter.counts,
+		}
+
+		p = b.backoff
+	}
+
+	if b.config.latencyTarget > 0 {
+		b.latency = &load.LatencyPacer{
+			Target:   b.config.latencyTarget,
+			Interval: b.config.latencyInterval,
+			Start:    float64(b.config.rps),
+			Max:      uint64(b.config.n),
+			Feedback: b.reporter.latencyFeedback,
+		}
+
+		p = b.latency
+	}
+
+	// the outermost pacer lets the rate be changed while running
+	b.lock.Lock()
+	b.control = &load.OverridePacer{Pacer: p, Max: uint64(b.config.n)}
+	b.lock.Unlock()
+
+	p = b.control
 
 	err = b.runWorkers(wt, p)
+	if err == nil && b.shared != nil && b.shared.Err() != nil {
+		err = fmt.Errorf("token server: %v", b.shared.Err())
+	}
+
+	stopScaling()
 
 	report := b.Finish()
 
+	stopPush()
+	report.Alerts = stopAlerts()
+	report.Canary = stopCanary()
+
+	b.indexedData.close()
+	b.payload.close()
+	b.config.lines.close()
+
+	if cerr := b.capture.close(); cerr != nil && b.config.hasLog {
+		b.config.log.Errorw("Error closing response capture file: "+cerr.Error(), "error", cerr)
+	}
+
+	if cz != nil {
+		report.Channelz = cz.finish()
+	}
+
+	// the derived metrics are computed once the report is complete
+	if len(b.config.metrics) > 0 {
+		report.Metrics = computeMetrics(report, b.config.metrics)
+	}
+
 	b.closeClientConns()
 
 	return report, err
 }
 
+// endData stops sending requests once the data is exhausted,
+// letting the requests in flight complete
+func (b *Requester) endData() {
+	b.dataEndOnce.Do(func() {
+		close(b.dataEnd)
+	})
+}
+
 // Stop stops the test
+// Only the first call has any effect, and calling it after the run is done is a no-op.
 func (b *Requester) Stop(reason StopReason) {
 
-	b.stopCh <- true
-
 	b.lock.Lock()
+	if b.stopped {
+		b.lock.Unlock()
+		return
+	}
+
+	b.stopped = true
+	b.stopCh <- true
 	b.stopReason = reason
 
 	if b.config.hasLog {
 		b.config.log.Debugf("Stopping with reason: %+v", reason)
 	}
+
+	cz := b.channelz
 	b.lock.Unlock()
 
-	if b.config.zstop == "close" {
+	// the calls in flight complete within the grace period before the connections are closed
+	if b.config.zstop == "drain" {
+		b.drain.drain()
+	}
+
+	// the final channelz snapshot is taken while the connections are still open
+	if cz != nil && b.config.zstop != "wait" {
+		cz.final()
+	}
+
+	switch b.config.zstop {
+	case "close", "drain":
 		b.closeClientConns()
-	} else if b.config.zstop == "ignore" {
+	case "ignore":
 		for _, h := range b.handlers {
 			h.Ignore(true)
 		}
@@ -210,11 +863,48 @@ func (b *Requester) Stop(reason StopReason) {
 	}
 }
 
+// Snapshot returns the progress of the run so far.
+// It is safe to call concurrently while the run is in progress.
+func (b *Requester) Snapshot() Snapshot {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.reporter == nil {
+		return Snapshot{}
+	}
+
+	return b.reporter.snapshot(time.Since(b.start))
+}
+
+// SnapshotSince returns the progress of the run since the snapshot with the sequence number,
+// which must be one of the recent snapshots, or since the start of the run if it is 0.
+// The returned snapshot is itself a recent snapshot, so pollers can pass its sequence number
+// to the next call to get the exact progress of each polling interval.
+func (b *Requester) SnapshotSince(seq uint64) (Snapshot, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.reporter == nil {
+		if seq > 0 {
+			return Snapshot{}, ErrSnapshotNotFound
+		}
+
+		return Snapshot{}, nil
+	}
+
+	return b.reporter.snapshotSince(time.Since(b.start), seq)
+}
+
 // Finish finishes the test run
 func (b *Requester) Finish() *Report {
 	close(b.results)
 	total := time.Since(b.start)
 
+	// the duration of the results is the time after the warmup
+	if b.config.warmup > 0 && total > b.config.warmup {
+		total -= b.config.warmup
+	}
+
 	if b.config.hasLog {
 		b.config.log.Debug("Waiting for report")
 	}
@@ -231,30 +921,191 @@ func (b *Requester) Finish() *Report {
 	r = b.stopReason
 	b.lock.Unlock()
 
-	return b.reporter.Finalize(r, total)
+	report := b.reporter.Finalize(r, total)
+
+	if report.Count > 0 {
+		// the CPU time is left out where it cannot be read
+		var cpuTime time.Duration
+		if end := processCPUTime(); b.cpuStart > 0 && end > b.cpuStart {
+			cpuTime = end - b.cpuStart
+		}
+
+		report.Normalized = normalized(report.Rps, total, cpuTime, b.config.cpus, b.config.nConns)
+	}
+
+	if b.backoff != nil {
+		report.Backoff = b.backoff.Actions()
+	}
+
+	if b.latency != nil {
+		report.LatencyControl = b.latency.Actions()
+	}
+
+	if b.stream != nil {
+		report.Stream = b.stream.stats(total)
+	}
+
+	if b.acks != nil {
+		report.StreamAcks = b.acks.stats()
+	}
+
+	if b.backpressure != nil {
+		report.Backpressure = b.backpressure.stats()
+	}
+
+	if b.fields != nil {
+		report.ResponseField = b.fields.stats()
+	}
+
+	if b.drift != nil {
+		report.SchemaDrift = b.drift.stats()
+
+		if b.config.schemaDriftFail {
+			report.Thresholds = append(report.Thresholds, report.SchemaDrift.threshold())
+		}
+	}
+
+	if b.pages != nil {
+		report.Pagination = b.pages.stats()
+	}
+
+	if b.iterations != nil {
+		report.Iterations = b.iterations.stats(total)
+	}
+
+	if b.stages != nil {
+		report.StageTiming = b.stages.stats()
+	}
+
+	if b.assertions != nil {
+		report.Assertions = b.assertions.stats()
+	}
+
+	if b.schema != nil {
+		report.SchemaChanges = b.schema.stats()
+	}
+
+	if b.async != nil {
+		report.AsyncQueue = b.async.stats()
+	}
+
+	if b.grace != nil {
+		report.GraceRetries = b.grace.stats()
+	}
+
+	if b.shards != nil {
+		report.Shards = b.shards.stats()
+	}
+
+	if b.churn != nil {
+		report.Churn = b.churn.stats()
+	}
+
+	if b.scaling != nil {
+		report.Scaling = b.scaling.stats()
+	}
+
+	b.lock.Lock()
+	report.Connect = b.connect
+	if b.baseline != nil && report.Count > 0 {
+		b.baseline.adjust(report)
+		report.Baseline = b.baseline
+	}
+	b.lock.Unlock()
+
+	if b.drain != nil {
+		report.Drain = b.drain.report()
+	}
+
+	if b.hedge != nil {
+		report.Hedging = b.hedge.stats()
+	}
+
+	if b.config.workerPacing != nil {
+		report.Pacing = pacingStats(reportDetails(report), b.config.workerPacing)
+	}
+
+	if b.phases != nil {
+		report.Phases = b.phases.stats(reportDetails(report), total, report.Options.CountErrors)
+	}
+
+	b.lock.Lock()
+	report.Adjustments = b.adjustments
+	report.Server = b.server
+	report.Readiness = b.readiness
+	b.lock.Unlock()
+
+	now := time.Now()
+	for _, h := range b.handlers {
+		report.Connections = append(report.Connections, h.connStats(now))
+	}
+
+	report.Compression = compressionStats(b.config.compressorName(), b.handlers)
+	report.Decompression = b.decompression.stats()
+
+	if b.payload != nil {
+		report.Upload = b.payload.stats(report)
+	}
+
+	report.Recommendations = recommendations(report, b.config)
+
+	return report
 }
 
 func (b *Requester) openClientConns() ([]*grpc.ClientConn, error) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
-	if len(b.conns) == b.config.nConns {
+	if len(b.conns) > 0 || len(b.webConns) > 0 {
 		return b.conns, nil
 	}
 
+	// the connections are distributed across the targets in turn
+	targets := b.config.allTargets()
+
+	// the gRPC-Web connections are not established before the calls
+	if b.config.transport == TransportGRPCWeb {
+		for n := 0; n < b.config.nConns; n++ {
+			b.webConns = append(b.webConns, b.newWebConn(targets[n%len(targets)], b.newStatsHandler()))
+		}
+
+		return nil, nil
+	}
+
+	conns := make([]*grpc.ClientConn, 0, b.config.nConns)
 	for n := 0; n < b.config.nConns; n++ {
-		c, err := b.newClientConn(true)
+		c, err := b.newClientConn(targets[n%len(targets)], b.newStatsHandler())
 		if err != nil {
 			if b.config.hasLog {
 				b.config.log.Errorf("Error creating client connection: %+v", err.Error())
 			}
 
+			closeConns(conns)
+
+			return nil, err
+		}
+
+		conns = append(conns, c)
+	}
+
+	if b.config.connectPolicy != "" {
+		ready, slots, stats, err := b.establishConns(conns, targets)
+		b.connect = stats
+		if err != nil {
 			return nil, err
 		}
 
-		b.conns = append(b.conns, c)
+		// the statistics of the connections which could not be established are left out
+		handlers := make([]*statsHandler, len(slots))
+		for i, n := range slots {
+			handlers[i] = b.handlers[n]
+		}
+
+		conns, b.handlers = ready, handlers
 	}
 
+	b.conns = conns
+
 	return b.conns, nil
 }
 
@@ -265,6 +1116,20 @@ func (b *Requester) closeClientConns() {
 
 	b.lock.Lock()
 	defer b.lock.Unlock()
+
+	if b.shards != nil {
+		b.shards.pool.close()
+	}
+
+	b.churn.close()
+	b.scaling.close()
+
+	for _, wc := range b.webConns {
+		wc.close()
+	}
+
+	b.webConns = nil
+
 	if b.conns == nil {
 		return
 	}
@@ -283,12 +1148,55 @@ func (b *Requester) closeClientConns() {
 	b.conns = nil
 }
 
-func (b *Requester) newClientConn(withStatsHandler bool) (*grpc.ClientConn, error) {
+// newDefaultDataProvider creates the data provider of the data of the config
+func newDefaultDataProvider(c *RunConfig, mtd *desc.MethodDescriptor) (DataProviderFunc, error) {
+	dp, err := newDataProvider(mtd, c.binary, c.dataFunc, c.data, c.funcs, c.dataLabel, c.emitDefaults)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.dataPartition {
+		if err := checkDataPartition(dp, dataPartitions(c)); err != nil {
+			return nil, err
+		}
+	}
+
+	return dp.getDataForCall, nil
+}
+
+// newStatsHandler creates the stats handler of a connection of the run
+func (b *Requester) newStatsHandler() *statsHandler {
+	sh := &statsHandler{
+		id:      len(b.handlers),
+		results: b.results,
+		hasLog:  b.config.hasLog,
+		log:     b.config.log,
+		stages:  b.stages,
+		metrics: b.metrics,
+		push:    b.push,
+		alerts:  b.alerts,
+		headers: b.config.headerLatency,
+
+		trailers:   b.config.errorSamples > 0,
+		classifier: b.config.successClassifier,
+		assertions: b.assertions,
+	}
+
+	b.handlers = append(b.handlers, sh)
+
+	return sh
+}
+
+// newClientConn creates a client connection to the target, with the stats handler of the run if any
+func (b *Requester) newClientConn(target string, sh *statsHandler) (*grpc.ClientConn, error) {
 	var opts []grpc.DialOption
 
-	if b.config.insecure {
+	switch {
+	case b.config.credsBundle != nil:
+		opts = append(opts, grpc.WithCredentialsBundle(b.config.credsBundle))
+	case b.config.insecure:
 		opts = append(opts, grpc.WithInsecure())
-	} else {
+	default:
 		opts = append(opts, grpc.WithTransportCredentials(b.config.creds))
 	}
 
@@ -296,10 +1204,17 @@ func (b *Requester) newClientConn(withStatsHandler bool) (*grpc.ClientConn, erro
 		opts = append(opts, grpc.WithAuthority(b.config.authority))
 	}
 
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, b.config.dialTimeout)
-	// cancel is ignored here as connection.Close() is used.
-	// See https://godoc.org/google.golang.org/grpc#DialContext
+	if b.config.callCreds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(b.config.callCreds))
+	}
+
+	// the dial does not block, so the dial timeout bounds each attempt to connect
+	if b.config.dialTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: b.config.dialTimeout,
+		}))
+	}
 
 	if b.config.keepaliveTime > 0 {
 		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -308,17 +1223,13 @@ func (b *Requester) newClientConn(withStatsHandler bool) (*grpc.ClientConn, erro
 		}))
 	}
 
-	if withStatsHandler {
-		sh := &statsHandler{
-			id:      len(b.handlers),
-			results: b.results,
-			hasLog:  b.config.hasLog,
-			log:     b.config.log,
-		}
-
-		b.handlers = append(b.handlers, sh)
-
+	if sh != nil {
 		opts = append(opts, grpc.WithStatsHandler(sh))
+
+		// the compressed responses of the calls are decompressed by the tracker timing their decompression
+		if b.decompression != nil {
+			opts = append(opts, grpc.WithDecompressor(b.decompression))
+		}
 	}
 
 	if b.config.hasLog {
@@ -336,8 +1247,37 @@ func (b *Requester) newClientConn(withStatsHandler bool) (*grpc.ClientConn, erro
 		opts = append(opts, grpc.WithBalancerName(b.config.lbStrategy))
 	}
 
+	if b.config.serviceConfig != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(b.config.serviceConfig))
+	}
+
+	if dialer := b.config.connDialer(); dialer != nil {
+		opts = append(opts, grpc.WithContextDialer(dialer))
+	}
+
+	if len(b.config.resolvers) > 0 {
+		opts = append(opts, grpc.WithResolvers(b.config.resolvers...))
+	}
+
+	// the later options take precedence, so the overrides of the target replace those of the run
+	if o := b.config.targetOverride(target); o != nil {
+		opts = append(opts, o.dialOptions(b.config)...)
+	}
+
+	opts = append(opts, b.config.dialOptions...)
+
 	// create client connection
-	return grpc.DialContext(ctx, b.config.host, opts...)
+	return grpc.DialContext(context.Background(), target, opts...)
+}
+
+// requestContext returns the context of the requests made outside of the run, such as the
+// reflection requests, bounded by the request timeout unless it is 0
+func requestContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+
+	return context.WithCancel(context.Background())
 }
 
 func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
@@ -357,15 +1297,13 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 	counter := Counter{}
 
 	go func() {
-		n := 0
 		wc := 0
-		for tv := range wct {
-			if b.config.hasLog {
-				b.config.log.Debugw("Worker ticker.", "delta", tv.Delta)
-			}
 
-			if tv.Delta > 0 {
-				for i := 0; i < tv.Delta; i++ {
+		adjust := func(delta int) {
+			if delta > 0 {
+				for i := 0; i < delta; i++ {
+					n := b.config.workerConn(wc, len(b.stubs))
+
 					wID := "g" + strconv.Itoa(wc) + "c" + strconv.Itoa(n)
 
 					if len(b.config.name) > 0 {
@@ -388,17 +1326,57 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 						metadataProvider: b.metadataProvider,
 						streamRecv:       b.config.recvMsgFunc,
 						msgProvider:      b.config.dataStreamFunc,
+						stream:           b.stream,
+						acks:             b.acks,
+						backpressure:     b.backpressure,
+						fields:           b.fields,
+						drift:            b.drift,
+						pages:            b.pages,
+						iterations:       b.iterations,
+						tenants:          b.tenants,
+						schema:           b.schema,
+						scenario:         b.scenario,
+						debugger:         b.debugger,
+						slowCalls:        b.slowCalls,
+						capture:          b.capture,
+						wireLog:          b.wireLog,
+						trackResponse:    b.lastResponse,
+						async:            b.async,
+						grace:            b.grace,
+						drain:            b.drain,
+						metrics:          b.metrics,
+						push:             b.push,
+						hedge:            b.hedge,
+						shards:           b.shards,
+						churn:            b.churn,
+						scaling:          b.scaling,
+						connIndex:        n,
+						endData:          b.endData,
+						startDelay:       time.Duration(i) * b.config.workerStagger,
+						payload:          b.payload,
 					}
 
-					wc++ // increment worker id
+					// the gRPC-Web connections have no client connection
+					if n < len(b.conns) {
+						w.conn = b.conns[n]
+					}
 
-					n++ // increment connection counter
+					w.group = b.groups.join(wID, wc)
 
-					// wrap around connections if needed
-					if n == b.config.nConns {
-						n = 0
+					if b.config.dataPartition {
+						w.partition = &dataPartition{index: wc % dataPartitions(b.config), count: dataPartitions(b.config)}
 					}
 
+					if b.sessionMtd != nil {
+						w.session = &workerSession{mtd: b.sessionMtd, closeMtd: b.sessionCloseMtd}
+					}
+
+					if n < len(b.handlers) {
+						b.handlers[n].addWorker()
+					}
+
+					wc++ // increment worker id
+
 					wm.Lock()
 					b.workers = append(b.workers, &w)
 					wm.Unlock()
@@ -407,8 +1385,8 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 						errC <- w.runWorker()
 					}()
 				}
-			} else if tv.Delta < 0 {
-				nd := -1 * tv.Delta
+			} else if delta < 0 {
+				nd := -1 * delta
 				wm.Lock()
 				wdc := 0
 				for _, wrk := range b.workers {
@@ -424,6 +1402,49 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 				}
 				wm.Unlock()
 			}
+
+			if b.phases != nil {
+				wm.Lock()
+				active := 0
+				for _, wrk := range b.workers {
+					if wrk.active {
+						active++
+					}
+				}
+				wm.Unlock()
+
+				b.phases.setConcurrency(active)
+			}
+		}
+
+		for {
+			select {
+			case tv, ok := <-wct:
+				if !ok {
+					return
+				}
+
+				if b.config.hasLog {
+					b.config.log.Debugw("Worker ticker.", "delta", tv.Delta)
+				}
+
+				adjust(tv.Delta)
+			case c := <-b.concurrency:
+				wm.Lock()
+				active := 0
+				for _, wrk := range b.workers {
+					if wrk.active {
+						active++
+					}
+				}
+				wm.Unlock()
+
+				if b.config.hasLog {
+					b.config.log.Debugw("Concurrency changed.", "concurrency", c, "active", active)
+				}
+
+				adjust(int(c) - active)
+			}
 		}
 	}()
 
@@ -442,8 +1463,35 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 
 		began := time.Now()
 
+		var intended time.Time
+
+		// the paused time is left out of the elapsed time of the pacer,
+		// so the rate schedule carries on from where it was paused
+		var paused time.Duration
+
 		for {
-			wait, stop := p.Pace(time.Since(began), counter.Get())
+			if resume := b.resumed(); resume != nil {
+				pausedAt := time.Now()
+
+				select {
+				case <-resume:
+					paused += time.Since(pausedAt)
+					intended = time.Time{}
+				case <-b.stopCh:
+					if b.config.hasLog {
+						b.config.log.Debugw("Signal received from stop channel while paused.", "count", counter.Get())
+					}
+					done <- struct{}{}
+					return
+				}
+			}
+
+			elapsed := time.Since(began) - paused
+			wait, stop := p.Pace(elapsed, counter.Get())
+
+			if b.phases != nil {
+				b.phases.pace(elapsed)
+			}
 
 			if stop {
 				if b.config.hasLog {
@@ -453,12 +1501,15 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 				return
 			}
 
+			intended = intendedTime(p, began.Add(paused), elapsed, wait, intended)
+
 			if wait > 0 {
 				time.Sleep(wait)
 			}
 
 			select {
-			case ticks <- TickValue{instant: time.Now(), reqNumber: counter.Inc() - 1}:
+			case ticks <- TickValue{instant: time.Now(), reqNumber: counter.Inc() - 1, intended: intended}:
+				b.vars.tick(intended)
 				continue
 			case <-b.stopCh:
 				if b.config.hasLog {
@@ -466,12 +1517,20 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 				}
 				done <- struct{}{}
 				return
+			case <-b.dataEnd:
+				if b.config.hasLog {
+					b.config.log.Debugw("Received end of data.", "count", counter.Get())
+				}
+				done <- struct{}{}
+				return
 			}
 		}
 	}()
 
 	<-done
 
+	close(b.workersDone)
+
 	var err error
 	wm.Lock()
 	nw := len(b.workers)
@@ -483,6 +1542,34 @@ func (b *Requester) runWorkers(wt load.WorkerTicker, p load.Pacer) error {
 	return err
 }
 
+// dataPartitions returns the number of partitions of the data, one for each worker
+// up to the maximum concurrency
+func dataPartitions(c *RunConfig) int {
+	if int(c.cEnd) > c.c {
+		return int(c.cEnd)
+	}
+
+	return c.c
+}
+
+// checkDataPartition returns an error if the data of the provider can not be split into the partitions
+func checkDataPartition(dp *dataProvider, partitions int) error {
+	if dp.binary || len(dp.arrayJSONData) == 0 {
+		return errors.New("data partitioning requires an array of data records")
+	}
+
+	if dp.mtd.IsClientStreaming() {
+		return errors.New("data partitioning is not supported for client streaming calls")
+	}
+
+	if len(dp.arrayJSONData) < partitions {
+		return fmt.Errorf("data partitioning requires at least as many data records as workers: %d records for %d workers",
+			len(dp.arrayJSONData), partitions)
+	}
+
+	return nil
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -521,6 +1608,27 @@ func createWorkerTicker(config *RunConfig) load.WorkerTicker {
 	return wt
 }
 
+// intendedTime returns the time the next request is due to be sent by a rate-limited pacer,
+// or the zero time if the pacer is not rate limited
+func intendedTime(p load.Pacer, began time.Time, elapsed, wait time.Duration, last time.Time) time.Time {
+	rate := p.Rate(elapsed)
+	if rate <= 0 {
+		return time.Time{}
+	}
+
+	next := began.Add(elapsed + wait)
+
+	// when running behind the pacer asks for the request to be sent right away
+	// without telling when it was due, so it is spaced out from the last one
+	if wait == 0 && !last.IsZero() {
+		if due := last.Add(time.Duration(float64(time.Second) / rate)); due.Before(next) {
+			next = due
+		}
+	}
+
+	return next
+}
+
 func createPacer(config *RunConfig) load.Pacer {
 	if config.pacer != nil {
 		return config.pacer
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/load"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntendedTime(t *testing.T) {
+	began := time.Date(2020, time.March, 7, 14, 5, 9, 0, time.UTC)
+	p := &load.ConstantPacer{Freq: 10}
+
+	t.Run("unlimited rate", func(t *testing.T) {
+		actual := intendedTime(&load.ConstantPacer{}, began, time.Second, 0, time.Time{})
+		assert.True(t, actual.IsZero())
+	})
+
+	t.Run("on schedule", func(t *testing.T) {
+		actual := intendedTime(p, began, 250*time.Millisecond, 50*time.Millisecond, time.Time{})
+		assert.Equal(t, began.Add(300*time.Millisecond), actual)
+	})
+
+	t.Run("overdue", func(t *testing.T) {
+		actual := intendedTime(p, began, 350*time.Millisecond, -50*time.Millisecond, time.Time{})
+		assert.Equal(t, began.Add(300*time.Millisecond), actual)
+	})
+
+	t.Run("running behind", func(t *testing.T) {
+		last := began.Add(time.Second)
+		actual := intendedTime(p, began, 3*time.Second, 0, last)
+		assert.Equal(t, began.Add(1100*time.Millisecond), actual)
+	})
+
+	t.Run("first request", func(t *testing.T) {
+		actual := intendedTime(p, began, 0, 0, time.Time{})
+		assert.Equal(t, began, actual)
+	})
+
+	t.Run("not behind", func(t *testing.T) {
+		last := began.Add(time.Second)
+		actual := intendedTime(p, began, time.Second+time.Millisecond, 0, last)
+		assert.Equal(t, began.Add(time.Second+time.Millisecond), actual)
+	})
+}
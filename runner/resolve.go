@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// parseResolve parses a name resolution override in the form of <host:port>=<ip:port>.
+// The port of the address can be left out, in which case the port of the host is used.
+func parseResolve(s string) (string, string, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid resolve entry %q: expected <host:port>=<ip:port>", s)
+	}
+
+	from, to := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+
+	host, port, err := net.SplitHostPort(from)
+	if err != nil || host == "" || port == "" {
+		return "", "", fmt.Errorf("invalid resolve entry %q: expected <host:port>=<ip:port>", s)
+	}
+
+	if _, _, err := net.SplitHostPort(to); err != nil {
+		to = net.JoinHostPort(strings.Trim(to, "[]"), port)
+	}
+
+	if ip, _, _ := net.SplitHostPort(to); net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("invalid resolve entry %q: %q is not an IP address", s, ip)
+	}
+
+	return net.JoinHostPort(strings.ToLower(host), port), to, nil
+}
+
+// resolveEntries returns the name resolution overrides as sorted <host:port>=<ip:port> entries
+func resolveEntries(resolve map[string]string) []string {
+	if len(resolve) == 0 {
+		return nil
+	}
+
+	entries := make([]string, 0, len(resolve))
+	for from, to := range resolve {
+		entries = append(entries, from+"="+to)
+	}
+
+	sort.Strings(entries)
+
+	return entries
+}
+
+// isUnixTarget returns whether the target is a Unix domain socket, which is
+// resolved by the unix resolver of gRPC rather than dialed over TCP
+func isUnixTarget(target string) bool {
+	return strings.HasPrefix(target, "unix:")
+}
+
+// targetScheme returns the name resolver scheme of the target, such as xds for xds:///service,
+// or an empty string for an address resolved by the default resolver
+func targetScheme(target string) string {
+	i := strings.Index(target, "://")
+	if i <= 0 {
+		return ""
+	}
+
+	return target[:i]
+}
+
+// ipNetwork returns the network to dial for the IP version, 0 for either version
+func ipNetwork(version uint) string {
+	switch version {
+	case 4:
+		return "tcp4"
+	case 6:
+		return "tcp6"
+	}
+
+	return "tcp"
+}
+
+// contextDialer returns a dialer connecting to the overridden address of the host, if any,
+// so the connections keep the host name as the authority and the TLS server name.
+// The addresses are resolved and dialed using only the given IP version, if any.
+func contextDialer(resolve map[string]string, ipVersion uint) func(context.Context, string) (net.Conn, error) {
+	network := ipNetwork(ipVersion)
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+
+		return d.DialContext(ctx, network, resolveAddr(resolve, addr))
+	}
+}
+
+// resolveAddr returns the overridden address of the host, or the address if it is not overridden
+func resolveAddr(resolve map[string]string, addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if to, ok := resolve[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+			return to
+		}
+	}
+
+	return addr
+}
@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestParseResolve(t *testing.T) {
+	var tests = []struct {
+		name string
+		in   string
+		from string
+		to   string
+		err  string
+	}{
+		{"ip and port", "api.example.com:443=10.0.0.12:8443", "api.example.com:443", "10.0.0.12:8443", ""},
+		{"ip only", " API.example.com:443 = 10.0.0.12 ", "api.example.com:443", "10.0.0.12:443", ""},
+		{"ipv6", "api.example.com:443=[::1]:50051", "api.example.com:443", "[::1]:50051", ""},
+		{"ipv6 only", "api.example.com:443=::1", "api.example.com:443", "[::1]:443", ""},
+		{"no address", "api.example.com:443", "", "", "expected <host:port>=<ip:port>"},
+		{"no port", "api.example.com=10.0.0.12:443", "", "", "expected <host:port>=<ip:port>"},
+		{"host name", "api.example.com:443=backend-1:443", "", "", `"backend-1" is not an IP address`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parseResolve(tt.in)
+			if tt.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.err)
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.from, from)
+			assert.Equal(t, tt.to, to)
+		})
+	}
+}
+
+func TestRunResolve(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	_, port, _ := net.SplitHostPort(internal.TestLocalhost)
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		"greeter.invalid:443",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithResolve("greeter.invalid:443=127.0.0.1:"+port),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), report.Count)
+	assert.Equal(t, 5, report.StatusCodeDist["OK"])
+	assert.Equal(t, []string{"greeter.invalid:443=127.0.0.1:" + port}, report.Options.Resolve)
+}
+
+func TestRunIPVersion(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	_, port, _ := net.SplitHostPort(internal.TestLocalhost)
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithIPVersion(4),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), report.Count)
+	assert.Equal(t, uint(4), report.Options.IPVersion)
+
+	if assert.Len(t, report.Connections, 1) {
+		assert.Equal(t, "ipv4", report.Connections[0].Family)
+		assert.Equal(t, "127.0.0.1:"+port, report.Connections[0].RemoteAddr)
+	}
+}
+
+func TestRunUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ghz-unix")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "greeter.sock")
+
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	gs, s := internal.StartServerOnListener(lis)
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		"unix://"+path,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"OK": 5}, report.StatusCodeDist)
+	assert.Equal(t, 5, gs.GetCount(helloworld.Unary))
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		"unix://"+path,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithIPVersion(4),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "name resolution overrides and an IP version cannot be used with unix targets")
+}
+
+func TestRunContextDialer(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+
+	gs, s := internal.StartServerOnListener(lis)
+	defer s.Stop()
+
+	var addrs []string
+	var lock sync.Mutex
+
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		lock.Lock()
+		addrs = append(addrs, addr)
+		lock.Unlock()
+
+		return lis.Dial()
+	}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		"bufnet",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithConnections(2),
+		WithContextDialer(dialer),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"OK": 10}, report.StatusCodeDist)
+	assert.Equal(t, 10, gs.GetCount(helloworld.Unary))
+	assert.Equal(t, []string{"bufnet", "bufnet"}, addrs)
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		"bufnet",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithContextDialer(dialer),
+		WithResolve("bufnet:443=10.0.0.12"),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "a custom dialer cannot be used with name resolution overrides or an IP version")
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// keyRewriter suffixes the key fields of the call data with the run ID and the request number,
+// so the replayed records, such as those holding idempotency keys, are not deduplicated by the
+// server across the passes over the data or across the runs
+type keyRewriter struct {
+	paths [][]string
+}
+
+// newKeyRewriter creates the rewriter of the string or bytes fields of the paths of the message
+func newKeyRewriter(md *desc.MessageDescriptor, paths []string) (*keyRewriter, error) {
+	rw := &keyRewriter{}
+
+	for _, path := range paths {
+		fd, err := findAnonymizedField(md, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if fd.IsMap() || (fd.GetType() != descriptor.FieldDescriptorProto_TYPE_STRING &&
+			fd.GetType() != descriptor.FieldDescriptorProto_TYPE_BYTES) {
+			return nil, fmt.Errorf("field %q of message %s cannot be rewritten: expected a string or bytes field",
+				path, md.GetFullyQualifiedName())
+		}
+
+		rw.paths = append(rw.paths, strings.Split(path, "."))
+	}
+
+	return rw, nil
+}
+
+// wrap returns the data provider rewriting the keys of the messages of the data provider
+func (rw *keyRewriter) wrap(dp DataProviderFunc) DataProviderFunc {
+	return func(ctd *CallData) ([]*dynamic.Message, error) {
+		inputs, err := dp(ctd)
+		if err != nil {
+			return inputs, err
+		}
+
+		suffix := "-" + ctd.RunID + "-" + strconv.FormatInt(ctd.RequestNumber, 10)
+
+		res := make([]*dynamic.Message, len(inputs))
+		for i, input := range inputs {
+			if res[i], err = rw.rewrite(input, suffix); err != nil {
+				return nil, fmt.Errorf("rewriting the keys of the call data: %v", err)
+			}
+		}
+
+		return res, nil
+	}
+}
+
+// rewrite returns the copy of the message with the suffix appended to its keys.
+// The message is copied since the data providers may share it between the calls.
+func (rw *keyRewriter) rewrite(msg *dynamic.Message, suffix string) (*dynamic.Message, error) {
+	b, err := msg.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	res := dynamic.NewMessage(msg.GetMessageDescriptor())
+	if err := res.Unmarshal(b); err != nil {
+		return nil, err
+	}
+
+	for _, path := range rw.paths {
+		if err := rw.apply(res, path, suffix); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// apply suffixes the field path of the message, and of each message of the repeated fields.
+// The empty keys are kept, so the fields which are not set are not sent.
+func (rw *keyRewriter) apply(msg *dynamic.Message, path []string, suffix string) error {
+	md := msg.GetMessageDescriptor()
+
+	fd := md.FindFieldByName(path[0])
+	if fd == nil {
+		fd = md.FindFieldByJSONName(path[0])
+	}
+
+	if fd == nil || !msg.HasField(fd) {
+		return nil
+	}
+
+	v := msg.GetField(fd)
+
+	if len(path) == 1 {
+		if values, ok := v.([]interface{}); ok {
+			keys := make([]interface{}, len(values))
+			for i, value := range values {
+				keys[i] = suffixKey(value, suffix)
+			}
+
+			return msg.TrySetField(fd, keys)
+		}
+
+		return msg.TrySetField(fd, suffixKey(v, suffix))
+	}
+
+	if values, ok := v.([]interface{}); ok {
+		for _, value := range values {
+			if m, ok := value.(*dynamic.Message); ok {
+				if err := rw.apply(m, path[1:], suffix); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if m, ok := v.(*dynamic.Message); ok {
+		return rw.apply(m, path[1:], suffix)
+	}
+
+	return nil
+}
+
+func suffixKey(v interface{}, suffix string) interface{} {
+	switch value := v.(type) {
+	case string:
+		if value != "" {
+			return value + suffix
+		}
+	case []byte:
+		if len(value) > 0 {
+			return append(append([]byte{}, value...), suffix...)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/bojand/ghz/protodesc"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRewriter(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto("fields.FieldService/Query", "../testdata/fields.proto", []string{})
+	assert.NoError(t, err)
+
+	md := mtd.GetOutputType()
+
+	t.Run("invalid paths", func(t *testing.T) {
+		_, err := newKeyRewriter(md, []string{"missing"})
+		assert.EqualError(t, err, `field "missing" not found in message fields.QueryReply`)
+
+		_, err = newKeyRewriter(md, []string{"result_count"})
+		assert.EqualError(t, err, `field "result_count" of message fields.QueryReply cannot be rewritten: expected a string or bytes field`)
+
+		_, err = newKeyRewriter(md, []string{"stats.queue_depth"})
+		assert.EqualError(t, err, `field "stats.queue_depth" of message fields.QueryReply cannot be rewritten: expected a string or bytes field`)
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		rw, err := newKeyRewriter(md, []string{"results", "server"})
+		assert.NoError(t, err)
+
+		msg := dynamic.NewMessage(md)
+		msg.SetFieldByName("results", []string{"alice", "", "bob"})
+		msg.SetFieldByName("server", "db-1")
+		msg.SetFieldByName("result_count", uint64(3))
+
+		dp := rw.wrap(func(*CallData) ([]*dynamic.Message, error) {
+			return []*dynamic.Message{msg}, nil
+		})
+
+		for _, n := range []int64{1, 2} {
+			inputs, err := dp(&CallData{RunID: "run", RequestNumber: n})
+			assert.NoError(t, err)
+			assert.Len(t, inputs, 1)
+
+			suffix := "-run-" + strconv.FormatInt(n, 10)
+			res := inputs[0]
+			assert.Equal(t, []interface{}{"alice" + suffix, "", "bob" + suffix}, res.GetFieldByName("results"))
+			assert.Equal(t, "db-1"+suffix, res.GetFieldByName("server"))
+			assert.Equal(t, uint64(3), res.GetFieldByName("result_count"))
+		}
+
+		// the original message is not modified
+		assert.Equal(t, "db-1", msg.GetFieldByName("server"))
+
+		// the fields which are not set are kept unset
+		empty, err := rw.rewrite(dynamic.NewMessage(md), "-run-1")
+		assert.NoError(t, err)
+		assert.False(t, empty.HasFieldName("server"))
+	})
+}
+
+func TestRunRewrittenKeys(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	binData, err := proto.Marshal(&helloworld.HelloRequest{Name: "order-1"})
+	assert.NoError(t, err)
+
+	gs.ResetCounters()
+
+	// the same binary record is replayed with a different key for each call
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(3),
+		WithConcurrency(1),
+		WithTimeout(time.Duration(20*time.Second)),
+		WithDialTimeout(time.Duration(20*time.Second)),
+		WithBinaryData(binData),
+		WithRunID("run1"),
+		WithDataKeyRewrite("name"),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), report.Count)
+	assert.Equal(t, []string{"name"}, report.Options.DataRewriteKeys)
+
+	names := map[string]bool{}
+	for _, c := range gs.GetCalls(helloworld.Unary) {
+		assert.True(t, strings.HasPrefix(c[0].GetName(), "order-1-run1-"), c[0].GetName())
+		names[c[0].GetName()] = true
+	}
+
+	assert.Len(t, names, 3)
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithDataKeyRewrite("missing"),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, `field "missing" not found in message helloworld.HelloRequest`)
+}
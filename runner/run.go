@@ -1,13 +1,15 @@
 package runner
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"runtime"
-	"time"
+	"syscall"
 )
 
-// Run executes the test
+// Run executes the test. If any of the thresholds failed the report is returned
+// along with ErrThresholdsFailed.
 //
 //	report, err := runner.Run(
 //		"helloworld.Greeter.SayHello",
@@ -17,6 +19,29 @@ import (
 //		WithInsecure(true),
 //	)
 func Run(call, host string, options ...Option) (*Report, error) {
+	return RunWithContext(context.Background(), call, host, options...)
+}
+
+// RunWithContext executes the test like Run, stopping it when the context is cancelled or its
+// deadline is exceeded. The calls in flight are then handled according to the stop option of
+// WithDurationStopAction and the end reason of the report is ReasonCancel. If the context is done
+// before the test starts, its error is returned without a report.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//
+//	report, err := runner.RunWithContext(ctx,
+//		"helloworld.Greeter.SayHello",
+//		"localhost:50051",
+//		WithProtoFile("greeter.proto", []string{}),
+//		WithDataFromFile("data.json"),
+//		WithInsecure(true),
+//	)
+func RunWithContext(ctx context.Context, call, host string, options ...Option) (*Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	c, err := NewConfig(call, host, options...)
 
 	if err != nil {
@@ -35,21 +60,37 @@ func Run(call, host string, options ...Option) (*Report, error) {
 	}
 
 	cancel := make(chan os.Signal, 1)
-	signal.Notify(cancel, os.Interrupt)
+	signal.Notify(cancel, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(cancel)
 
 	go func() {
 		<-cancel
-		reqr.Stop(ReasonCancel)
+
+		// restore the default behaviour so a second signal terminates right away
+		signal.Stop(cancel)
+
+		reqr.Stop(ReasonInterrupt)
 	}()
 
-	if c.z > 0 {
+	if pw := newProgressWriter(c.progress, c.progressInterval, reqr, c.debugOut); pw != nil {
+		done := make(chan struct{})
+		written := make(chan struct{})
+
+		defer func() {
+			close(done)
+			<-written
+		}()
+
 		go func() {
-			time.Sleep(c.z)
-			reqr.Stop(ReasonTimeout)
+			pw.watch(done)
+			close(written)
 		}()
 	}
 
-	rep, err := reqr.Run()
+	rep, err := reqr.RunWithContext(ctx)
+	if err == nil && rep != nil && !rep.ThresholdsPassed() {
+		err = ErrThresholdsFailed
+	}
 
 	return rep, err
 }
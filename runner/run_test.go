@@ -1,8 +1,14 @@
 package runner
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"text/template"
 	"time"
@@ -14,7 +20,12 @@ import (
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/local"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 )
 
 func changeFunc(mtd *desc.MethodDescriptor, cd *CallData) []byte {
@@ -63,6 +74,7 @@ func TestRunUnary(t *testing.T) {
 		assert.NotZero(t, report.Slowest)
 		assert.NotZero(t, report.Rps)
 		assert.Empty(t, report.Name)
+		assert.Len(t, report.RunID, 26)
 		assert.NotEmpty(t, report.Date)
 		assert.NotEmpty(t, report.Options)
 		assert.NotEmpty(t, report.Details)
@@ -70,14 +82,58 @@ func TestRunUnary(t *testing.T) {
 		assert.NotEmpty(t, report.LatencyDistribution)
 		assert.Equal(t, ReasonNormalEnd, report.EndReason)
 		assert.Empty(t, report.ErrorDist)
+		assert.Nil(t, report.SchedulerLag)
 
 		assert.Equal(t, report.Average, report.Slowest)
 		assert.Equal(t, report.Average, report.Fastest)
 
+		assert.Equal(t, "g0c0", report.Details[0].Worker)
+		assert.NotZero(t, report.Details[0].BytesSent)
+		assert.NotZero(t, report.Details[0].BytesReceived)
+
+		// the time to the response headers is only recorded when enabled
+		assert.Nil(t, report.HeaderLatency)
+		assert.Zero(t, report.Details[0].HeaderLatency)
+
 		count := gs.GetCount(callType)
 		assert.Equal(t, 1, count)
 	})
 
+	t.Run("test header latency", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithConcurrency(1),
+			WithHeaderLatency(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, report.Options.HeaderLatency)
+
+		// the response headers are received before the response message
+		if assert.NotNil(t, report.HeaderLatency) {
+			assert.Equal(t, uint64(1), report.HeaderLatency.Count)
+			assert.True(t, report.HeaderLatency.Average <= report.Average)
+		}
+
+		assert.NotZero(t, report.Details[0].HeaderLatency)
+
+		_, err = Run(
+			"helloworld.Greeter.SayHellos",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithHeaderLatency(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "header latency is only supported for unary calls")
+	})
+
 	t.Run("test predefined template functions", func(t *testing.T) {
 		gs.ResetCounters()
 
@@ -320,6 +376,43 @@ func TestRunUnary(t *testing.T) {
 		assert.Equal(t, 1, connCount)
 	})
 
+	t.Run("test max duration", func(t *testing.T) {
+		gs.ResetCounters()
+
+		// the total number of requests is reached first
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(2),
+			WithMaxDuration(10*time.Second),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20, int(report.Count))
+		assert.Equal(t, ReasonNormalEnd, report.EndReason)
+		assert.Equal(t, 10*time.Second, report.Options.MaxDuration)
+
+		// the maximum duration is reached first
+		report, err = Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1000000),
+			WithConcurrency(2),
+			WithMaxDuration(500*time.Millisecond),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, report.Count < 1000000)
+		assert.Equal(t, ReasonTimeout, report.EndReason)
+	})
+
 	t.Run("test run duration", func(t *testing.T) {
 		gs.ResetCounters()
 
@@ -364,6 +457,182 @@ func TestRunUnary(t *testing.T) {
 		assert.Equal(t, 1, connCount)
 	})
 
+	t.Run("test interrupt", func(t *testing.T) {
+		gs.ResetCounters()
+
+		data := make(map[string]interface{})
+		data["name"] = "bob"
+
+		go func() {
+			time.Sleep(1 * time.Second)
+			p, _ := os.FindProcess(os.Getpid())
+			_ = p.Signal(syscall.SIGTERM)
+		}()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(10*time.Second),
+			WithData(data),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		assert.NotNil(t, report)
+
+		assert.True(t, report.Count > 0)
+		assert.True(t, report.Total < 5*time.Second, fmt.Sprintf("duration %d expected value", report.Total.Milliseconds()))
+		assert.NotEmpty(t, report.Details)
+		assert.Equal(t, ReasonInterrupt, report.EndReason)
+	})
+
+	t.Run("test backoff", func(t *testing.T) {
+		gs.ResetCounters()
+
+		data := make(map[string]interface{})
+		data["name"] = "bob"
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(1500*time.Millisecond),
+			WithRPS(200),
+			WithConcurrency(5),
+			WithTimeout(time.Nanosecond),
+			WithBackoff(50, 200*time.Millisecond),
+			WithData(data),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		assert.True(t, report.Count > 0)
+		assert.True(t, report.Count < 200, fmt.Sprintf("count %d expected value", report.Count))
+		assert.NotEmpty(t, report.Backoff)
+		assert.Equal(t, 0.5, report.Backoff[0].Factor)
+		assert.True(t, report.Backoff[0].ErrorRate >= 0.5)
+	})
+
+	t.Run("test latency target", func(t *testing.T) {
+		gs.ResetCounters()
+
+		data := make(map[string]interface{})
+		data["name"] = "bob"
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithRunDuration(1500*time.Millisecond),
+			WithRPS(20),
+			WithConcurrency(5),
+			WithLatencyTarget(time.Second, 200*time.Millisecond),
+			WithData(data),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		assert.True(t, report.Count > 0)
+		assert.Equal(t, time.Second, report.Options.LatencyTarget)
+		assert.NotEmpty(t, report.LatencyControl)
+
+		// the latency is well below the target so the rate is raised
+		assert.True(t, report.LatencyControl[0].Rate > 20, fmt.Sprintf("rate %f expected value", report.LatencyControl[0].Rate))
+		assert.True(t, report.LatencyControl[0].Latency < time.Second)
+	})
+
+	t.Run("test stop after run", func(t *testing.T) {
+		c, err := NewConfig(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+		assert.NoError(t, err)
+
+		reqr, err := NewRequester(c)
+		assert.NoError(t, err)
+
+		report, err := reqr.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonNormalEnd, report.EndReason)
+
+		assert.NotPanics(t, func() {
+			reqr.Stop(ReasonCancel)
+			reqr.Stop(ReasonTimeout)
+		})
+	})
+
+	t.Run("test until signal", func(t *testing.T) {
+		c, err := NewConfig(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithConcurrency(1),
+			WithRPS(100),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+			WithRunUntilSignal(true),
+			WithDurationStopAction("wait"),
+		)
+		assert.NoError(t, err)
+
+		reqr, err := NewRequester(c)
+		assert.NoError(t, err)
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			reqr.Stop(ReasonInterrupt)
+		}()
+
+		report, err := reqr.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonInterrupt, report.EndReason)
+		assert.True(t, report.Options.UntilSignal)
+
+		// the total number of requests is ignored
+		assert.True(t, report.Count > 5, fmt.Sprintf("count %d expected value", report.Count))
+	})
+
+	t.Run("test worker stagger", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithConcurrency(3),
+			WithRunDuration(500*time.Millisecond),
+			WithWorkerStagger(100*time.Millisecond),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 100*time.Millisecond, report.Options.WorkerStagger)
+
+		first := map[string]time.Time{}
+		for _, d := range report.Details {
+			started := d.Timestamp.Add(-d.Latency)
+			if f, ok := first[d.Worker]; !ok || started.Before(f) {
+				first[d.Worker] = started
+			}
+		}
+
+		if assert.Len(t, first, 3) {
+			assert.True(t, first["g1c0"].Sub(first["g0c0"]) >= 90*time.Millisecond)
+			assert.True(t, first["g2c0"].Sub(first["g0c0"]) >= 190*time.Millisecond)
+		}
+	})
+
 	t.Run("test RPS", func(t *testing.T) {
 
 		gs.ResetCounters()
@@ -405,6 +674,59 @@ func TestRunUnary(t *testing.T) {
 		assert.NotEqual(t, report.Average, report.Slowest)
 		assert.NotEqual(t, report.Average, report.Fastest)
 		assert.NotEqual(t, report.Slowest, report.Fastest)
+
+		assert.NotNil(t, report.SchedulerLag)
+		assert.Equal(t, uint64(10), report.SchedulerLag.Count)
+		assert.True(t, report.SchedulerLag.Max < time.Second)
+	})
+
+	t.Run("test async pools", func(t *testing.T) {
+		gs.ResetCounters()
+
+		data := make(map[string]interface{})
+		data["name"] = "bob"
+
+		debugOut := &bytes.Buffer{}
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(100),
+			WithConcurrency(2),
+			WithAsync(true),
+			WithAsyncPools(5, 2),
+			WithDebugCalls(3),
+			WithDebugOutput(debugOut),
+			WithTimeout(time.Duration(20*time.Second)),
+			WithDialTimeout(time.Duration(20*time.Second)),
+			WithData(data),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		assert.NotNil(t, report)
+
+		assert.Equal(t, 100, int(report.Count))
+		assert.Equal(t, ReasonNormalEnd, report.EndReason)
+		assert.Empty(t, report.ErrorDist)
+		assert.Equal(t, uint(5), report.Options.AsyncSenders)
+		assert.Equal(t, uint(2), report.Options.AsyncHandlers)
+
+		assert.NotNil(t, report.AsyncQueue)
+		assert.Equal(t, uint(5), report.AsyncQueue.Senders)
+		assert.Equal(t, uint(2), report.AsyncQueue.Handlers)
+		assert.True(t, report.AsyncQueue.MaxInFlight >= 1 && report.AsyncQueue.MaxInFlight <= 10)
+		assert.True(t, report.AsyncQueue.MaxQueued >= 1)
+
+		// the responses are decoded by the handlers
+		lines := strings.Split(strings.TrimSpace(debugOut.String()), "\n")
+		assert.Len(t, lines, 3)
+		assert.Contains(t, lines[0], `"message":"Hello bob"`)
+
+		count := gs.GetCount(callType)
+		assert.Equal(t, 100, count)
 	})
 
 	t.Run("test binary", func(t *testing.T) {
@@ -548,6 +870,16 @@ func TestRunUnary(t *testing.T) {
 
 		connCount := gs.GetConnectionCount()
 		assert.Equal(t, 5, connCount)
+
+		assert.Len(t, report.Connections, 5)
+		var calls uint64
+		for i, c := range report.Connections {
+			assert.Equal(t, i, c.ID)
+			assert.Equal(t, uint64(1), c.Connects)
+			assert.NotZero(t, c.Lifetime)
+			calls += c.Calls
+		}
+		assert.Equal(t, uint64(5), calls)
 	})
 
 	t.Run("test round-robin c = 2", func(t *testing.T) {
@@ -923,8 +1255,111 @@ func TestRunUnary(t *testing.T) {
 		assert.Equal(t, 3, count)
 
 		calls := gs.GetCalls(callType)
-		assert.NotNil(t, calls)
-		assert.Len(t, calls, 3)
+		assert.NotNil(t, calls)
+		assert.Len(t, calls, 3)
+		names := make([]string, 0)
+		for _, msgs := range calls {
+			for _, msg := range msgs {
+				names = append(names, msg.GetName())
+			}
+		}
+
+		assert.Equal(t, []string{
+			"__record_metadata__||token:custom-value",
+			"__record_metadata__||token:custom-value",
+			"__record_metadata__||token:custom-value",
+		}, names)
+	})
+
+	t.Run("with session", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(3),
+			WithConcurrency(1),
+			WithTimeout(time.Duration(20*time.Second)),
+			WithDialTimeout(time.Duration(20*time.Second)),
+			WithData(map[string]interface{}{"name": "__record_metadata__"}),
+			WithSession("helloworld.Greeter.SayHello", map[string]interface{}{"name": "{{.WorkerID}}"}, "message"),
+			WithSessionMetadata(map[string]string{"token": "{{.SessionToken}}"}),
+			WithSessionClose("helloworld.Greeter.SayHello", map[string]interface{}{"name": "bye {{.SessionToken}}"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		// the session calls are not included in the report
+		assert.Equal(t, 3, int(report.Count))
+		assert.Equal(t, 5, gs.GetCount(callType))
+
+		calls := gs.GetCalls(callType)
+		names := make([]string, 0)
+		for _, msgs := range calls {
+			for _, msg := range msgs {
+				names = append(names, msg.GetName())
+			}
+		}
+
+		assert.Equal(t, []string{
+			"g0c0",
+			"__record_metadata__||token:Hello g0c0",
+			"__record_metadata__||token:Hello g0c0",
+			"__record_metadata__||token:Hello g0c0",
+			"bye Hello g0c0",
+		}, names)
+
+		_, err = Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithInsecure(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithSession("helloworld.Greeter.SayHellos", nil, "message"),
+		)
+		assert.Error(t, err)
+
+		_, err = Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithInsecure(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithSession("helloworld.Greeter.SayHello", nil, "token"),
+		)
+		assert.Error(t, err)
+	})
+
+	t.Run("with pagination", func(t *testing.T) {
+		gs.ResetCounters()
+
+		// the message of each reply is used as the name of the next request
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(2),
+			WithConcurrency(1),
+			WithTimeout(time.Duration(20*time.Second)),
+			WithDialTimeout(time.Duration(20*time.Second)),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithPagination(true, "name:message", 3),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		// all the pages are counted as calls
+		assert.Equal(t, 6, int(report.Count))
+		assert.Equal(t, 6, gs.GetCount(callType))
+
+		calls := gs.GetCalls(callType)
 		names := make([]string, 0)
 		for _, msgs := range calls {
 			for _, msg := range msgs {
@@ -933,10 +1368,33 @@ func TestRunUnary(t *testing.T) {
 		}
 
 		assert.Equal(t, []string{
-			"__record_metadata__||token:custom-value",
-			"__record_metadata__||token:custom-value",
-			"__record_metadata__||token:custom-value",
+			"bob", "Hello bob", "Hello Hello bob",
+			"bob", "Hello bob", "Hello Hello bob",
 		}, names)
+
+		assert.True(t, report.Options.Paginate)
+		assert.Equal(t, uint(3), report.Options.MaxPages)
+
+		p := report.Pagination
+		assert.NotNil(t, p)
+		assert.Equal(t, "name:message", p.Fields)
+		assert.Equal(t, uint64(2), p.Operations)
+		assert.Equal(t, uint64(2), p.Truncated)
+		assert.Equal(t, uint64(6), p.Pages)
+		assert.Equal(t, 3.0, p.AveragePages)
+		assert.Equal(t, uint64(3), p.MostPages)
+		assert.True(t, p.Fastest > 0)
+
+		_, err = Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithInsecure(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithPagination(true, "", 0),
+		)
+		assert.Error(t, err)
 	})
 }
 
@@ -990,6 +1448,20 @@ func TestRunServerStreaming(t *testing.T) {
 		assert.Equal(t, ReasonNormalEnd, report.EndReason)
 		assert.Empty(t, report.ErrorDist)
 
+		assert.Nil(t, report.HeaderLatency)
+
+		if assert.NotNil(t, report.StreamMessages) {
+			assert.Equal(t, uint64(15), report.StreamMessages.Streams)
+			assert.Equal(t, uint64(15), report.StreamMessages.Sent)
+			assert.Equal(t, uint64(60), report.StreamMessages.Received)
+			assert.Equal(t, 4.0, report.StreamMessages.ReceivedPerStream)
+			assert.Equal(t, uint64(4), report.StreamMessages.MaxReceived)
+			assert.NotZero(t, report.StreamMessages.ReceiveRate)
+		}
+
+		assert.Equal(t, uint64(1), report.Details[0].MessagesSent)
+		assert.Equal(t, uint64(4), report.Details[0].MessagesReceived)
+
 		assert.NotEqual(t, report.Average, report.Slowest)
 		assert.NotEqual(t, report.Average, report.Fastest)
 		assert.NotEqual(t, report.Slowest, report.Fastest)
@@ -2648,6 +3120,53 @@ func TestRunBidi(t *testing.T) {
 		assert.Equal(t, "g0c0: 0", msgs[0].GetName())
 		assert.Equal(t, "g0c0: 6", msgs[6].GetName())
 	})
+
+	t.Run("with correlation field", func(t *testing.T) {
+		gs.ResetCounters()
+
+		data := []interface{}{
+			map[string]interface{}{"name": "bob"},
+			map[string]interface{}{"name": "Kate"},
+		}
+
+		report, err := Run(
+			"helloworld.Greeter.SayHelloBidi",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(6),
+			WithConcurrency(2),
+			WithTimeout(time.Duration(20*time.Second)),
+			WithDialTimeout(time.Duration(20*time.Second)),
+			WithData(data),
+			WithInsecure(true),
+			WithStreamCorrelationField("name:message"),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+		assert.Equal(t, 6, int(report.Count))
+
+		// the replies are "Hello <name>" so none of them match
+		assert.NotNil(t, report.Stream)
+		assert.Equal(t, "name:message", report.Stream.Field)
+		assert.Equal(t, uint64(12), report.Stream.Sent)
+		assert.Equal(t, uint64(12), report.Stream.Received)
+		assert.Equal(t, uint64(12), report.Stream.Pushed)
+		assert.Equal(t, uint64(12), report.Stream.Unanswered)
+		assert.Zero(t, report.Stream.Matched)
+
+		_, err = Run(
+			"helloworld.Greeter.SayHelloBidi",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithData(data),
+			WithInsecure(true),
+			WithStreamCorrelationField("id"),
+		)
+
+		assert.Error(t, err)
+	})
 }
 
 func TestRunUnarySecure(t *testing.T) {
@@ -3065,6 +3584,76 @@ func TestRunUnaryDurationStop(t *testing.T) {
 		assert.Len(t, report.StatusCodeDist, 1)
 		assert.Equal(t, 6, report.StatusCodeDist["OK"])
 	})
+
+	t.Run("test drain", func(t *testing.T) {
+
+		data := make(map[string]interface{})
+		data["Milliseconds"] = "150"
+
+		report, err := Run(
+			"main.SleepService.SleepFor",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/sleep.proto", []string{}),
+			WithConnections(1),
+			WithConcurrency(1),
+			WithData(data),
+			WithRunDuration(time.Duration(1*time.Second)),
+			WithDurationStopAction("drain"),
+			WithTimeout(time.Duration(200*time.Millisecond)),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		assert.NotNil(t, report)
+
+		assert.Equal(t, 7, int(report.Count))
+		assert.Equal(t, ReasonTimeout, report.EndReason)
+		assert.Len(t, report.ErrorDist, 0)
+		assert.Equal(t, 7, report.StatusCodeDist["OK"])
+		assert.Equal(t, 10*time.Second, report.Options.DrainTimeout)
+
+		if assert.NotNil(t, report.Drain) {
+			assert.Equal(t, uint64(1), report.Drain.InFlight)
+			assert.Equal(t, uint64(1), report.Drain.Drained)
+			assert.Equal(t, uint64(0), report.Drain.Cut)
+		}
+	})
+
+	t.Run("test drain timeout", func(t *testing.T) {
+
+		data := make(map[string]interface{})
+		data["Milliseconds"] = "300"
+
+		report, err := Run(
+			"main.SleepService.SleepFor",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/sleep.proto", []string{}),
+			WithConnections(1),
+			WithConcurrency(1),
+			WithData(data),
+			WithRunDuration(time.Duration(1*time.Second)),
+			WithDurationStopAction("drain"),
+			WithDrainTimeout(50*time.Millisecond),
+			WithTimeout(time.Duration(time.Second)),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+
+		assert.NotNil(t, report)
+
+		// the call in flight when stopping is cut
+		assert.Equal(t, int(report.Count)-1, report.StatusCodeDist["OK"])
+		assert.Equal(t, 50*time.Millisecond, report.Options.DrainTimeout)
+
+		if assert.NotNil(t, report.Drain) {
+			assert.Equal(t, uint64(1), report.Drain.InFlight)
+			assert.Equal(t, uint64(0), report.Drain.Drained)
+			assert.Equal(t, uint64(1), report.Drain.Cut)
+			assert.Equal(t, 50*time.Millisecond, report.Drain.Timeout)
+		}
+	})
 }
 
 func TestRunWrappedUnary(t *testing.T) {
@@ -3240,3 +3829,295 @@ func TestRunGtimeUnary(t *testing.T) {
 		assert.Equal(t, expectedDur, ts.LastDuration)
 	})
 }
+
+func TestRunWarmup(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	var lock sync.Mutex
+	var starts []time.Time
+
+	// the warmup ends at least 500ms after the run is started
+	before := time.Now()
+	warmupEnd := before.Add(500 * time.Millisecond)
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithRunDuration(time.Second),
+		WithRPS(20),
+		WithConcurrency(1),
+		WithWarmup(500*time.Millisecond),
+		WithBeforeCall(func(ctx context.Context, cd *CallData) context.Context {
+			lock.Lock()
+			starts = append(starts, time.Now())
+			lock.Unlock()
+
+			return ctx
+		}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	elapsed := time.Since(before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, report.Options.Warmup)
+
+	// the calls in flight once the run is stopped may not reach the server
+	assert.True(t, gs.GetCount(helloworld.Unary) <= len(starts))
+
+	// the calls started during the warmup are made but not counted
+	var warmupCalls int
+	for _, start := range starts {
+		if start.Before(warmupEnd) {
+			warmupCalls++
+		}
+	}
+
+	assert.True(t, int(report.Count) <= len(starts)-warmupCalls, "calls: %d, warmup calls: %d, count: %d",
+		len(starts), warmupCalls, report.Count)
+
+	for _, d := range report.Details {
+		assert.False(t, d.Timestamp.Add(-d.Latency).Before(warmupEnd), "call started during the warmup")
+	}
+
+	// the duration of the results leaves out the warmup
+	assert.True(t, report.Total >= 500*time.Millisecond && report.Total <= elapsed-500*time.Millisecond,
+		"total: %s, elapsed: %s", report.Total, elapsed)
+}
+
+func TestRunTargets(t *testing.T) {
+	gs1, s1, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s1.Stop()
+
+	host := internal.TestLocalhost
+
+	gs2, s2, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s2.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		host,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithConnections(2),
+		WithTargets(internal.TestLocalhost),
+		WithServiceConfig(`{"loadBalancingConfig":[{"pick_first":{}}]}`),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), report.Count)
+	assert.Equal(t, []string{internal.TestLocalhost}, report.Options.Targets)
+
+	// each target has a connection of its own
+	assert.NotZero(t, gs1.GetCount(helloworld.Unary))
+	assert.NotZero(t, gs2.GetCount(helloworld.Unary))
+	assert.Equal(t, 20, gs1.GetCount(helloworld.Unary)+gs2.GetCount(helloworld.Unary))
+
+	if assert.Len(t, report.Connections, 2) {
+		assert.True(t, strings.HasSuffix(report.Connections[0].RemoteAddr, ":"+strings.TrimPrefix(host, "localhost:")))
+		assert.True(t, strings.HasSuffix(report.Connections[1].RemoteAddr, ":"+internal.TestPort))
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		host,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTargets(internal.TestLocalhost),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "number of connections cannot be less than the 2 targets")
+
+	// the metadata of the override is only sent to its target
+	gs1.ResetCounters()
+	gs2.ResetCounters()
+
+	report, err = Run(
+		"helloworld.Greeter.SayHello",
+		host,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithConnections(2),
+		WithTargets(internal.TestLocalhost),
+		WithTargetOverrides(TargetOverride{Target: internal.TestLocalhost, Metadata: map[string]string{"token": "b"}}),
+		WithData(map[string]interface{}{"name": "__record_metadata__"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+	assert.Len(t, report.Options.TargetOverrides, 1)
+
+	for _, c := range gs1.GetCalls(helloworld.Unary) {
+		assert.Equal(t, "__record_metadata__||", c[0].GetName())
+	}
+
+	assert.NotZero(t, gs2.GetCount(helloworld.Unary))
+	for _, c := range gs2.GetCalls(helloworld.Unary) {
+		assert.Equal(t, "__record_metadata__||token:b", c[0].GetName())
+	}
+}
+
+func TestRunCallOptions(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	// the response of "Hello bob" exceeds the size
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithWaitForReady(true),
+		WithMaxMessageSize(5, 0),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"ResourceExhausted": 5}, report.StatusCodeDist)
+	assert.Equal(t, 5, gs.GetCount(helloworld.Unary))
+	assert.True(t, report.Options.WaitForReady)
+	assert.Equal(t, uint(5), report.Options.MaxRecvMsgSize)
+
+	gs.ResetCounters()
+
+	// the custom options override the options of the run, and the requests are not sent
+	report, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithMaxMessageSize(0, 1024),
+		WithCallOptions(grpc.MaxCallSendMsgSize(1)),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"ResourceExhausted": 5}, report.StatusCodeDist)
+	assert.Equal(t, 0, gs.GetCount(helloworld.Unary))
+}
+
+func TestRunWithContext(t *testing.T) {
+	_, s, err := internal.StartSleepServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+
+		report, err := RunWithContext(ctx,
+			"main.SleepService.SleepFor",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/sleep.proto", []string{}),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"Milliseconds": "100"}),
+			WithRunDuration(time.Minute),
+			WithDurationStopAction("wait"),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+
+		if assert.NotNil(t, report) {
+			assert.Equal(t, ReasonCancel, report.EndReason)
+			assert.NotZero(t, report.Count)
+			assert.Equal(t, int(report.Count), report.StatusCodeDist["OK"])
+		}
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report, err := RunWithContext(ctx,
+			"main.SleepService.SleepFor",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/sleep.proto", []string{}),
+			WithTotalRequests(5),
+			WithData(map[string]interface{}{"Milliseconds": "100"}),
+			WithInsecure(true),
+		)
+
+		assert.Equal(t, context.Canceled, err)
+		assert.Nil(t, report)
+	})
+}
+
+// localBundle is a credentials bundle of the local transport credentials
+type localBundle struct{}
+
+func (localBundle) TransportCredentials() credentials.TransportCredentials {
+	return local.NewCredentials()
+}
+
+func (localBundle) PerRPCCredentials() credentials.PerRPCCredentials {
+	return nil
+}
+
+func (b localBundle) NewWithMode(string) (credentials.Bundle, error) {
+	return b, nil
+}
+
+func TestRunResolvers(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	r := manual.NewBuilderWithScheme("mesh")
+	r.InitialState(resolver.State{Addresses: []resolver.Address{{Addr: internal.TestLocalhost}}})
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		"mesh:///greeter",
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(5),
+		WithConcurrency(1),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithResolvers(r),
+		WithCredentialsBundle(localBundle{}),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]int{"OK": 5}, report.StatusCodeDist)
+	assert.Equal(t, 5, gs.GetCount(helloworld.Unary))
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	crand "crypto/rand"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// the Crockford base32 alphabet used by ULIDs
+const ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// DefaultRunIDHeader is the default metadata key the run ID is sent as in every call
+const DefaultRunIDHeader = "x-load-test-run"
+
+// NewRunID returns a new ULID: a 48 bit millisecond timestamp followed by 80 random bits,
+// encoded as 26 characters so that IDs sort lexicographically by creation time
+func NewRunID() string {
+	var id [16]byte
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+
+	if _, err := crand.Read(id[6:]); err != nil {
+		seededRand.Read(id[6:])
+	}
+
+	var out [26]byte
+	var acc, n uint
+	pos := len(out) - 1
+	for i := len(id) - 1; i >= 0; i-- {
+		acc |= uint(id[i]) << n
+		n += 8
+		for n >= 5 {
+			out[pos] = ulidEncoding[acc&31]
+			pos--
+			acc >>= 5
+			n -= 5
+		}
+	}
+
+	out[0] = ulidEncoding[acc&31]
+
+	return string(out[:])
+}
+
+// withRunIDHeader returns the request metadata with the run ID header added,
+// unless the header is disabled or already set
+func withRunIDHeader(reqMD *metadata.MD, header, runID string) *metadata.MD {
+	if header == "" {
+		return reqMD
+	}
+
+	// the request metadata may be shared between calls
+	md := metadata.MD{}
+	if reqMD != nil {
+		if len(reqMD.Get(header)) > 0 {
+			return reqMD
+		}
+
+		md = reqMD.Copy()
+	}
+
+	md.Set(header, runID)
+
+	return &md
+}
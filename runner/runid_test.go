@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRunID_NewRunID(t *testing.T) {
+	t.Run("format", func(t *testing.T) {
+		id := NewRunID()
+
+		assert.Len(t, id, 26)
+		for _, c := range id {
+			assert.True(t, strings.ContainsRune(ulidEncoding, c), "unexpected character %q in %s", c, id)
+		}
+
+		// 48 bit timestamp leaves the top 3 bits unused so first character is at most 7
+		assert.True(t, id[0] <= '7')
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		seen := make(map[string]bool, 1000)
+		for i := 0; i < 1000; i++ {
+			id := NewRunID()
+			assert.False(t, seen[id])
+			seen[id] = true
+		}
+	})
+
+	t.Run("sorted by time", func(t *testing.T) {
+		first := NewRunID()
+		time.Sleep(2 * time.Millisecond)
+		second := NewRunID()
+
+		assert.True(t, first[:10] < second[:10], "%s should sort before %s", first, second)
+	})
+}
+
+func TestRunID_withRunIDHeader(t *testing.T) {
+	t.Run("nil metadata", func(t *testing.T) {
+		md := withRunIDHeader(nil, DefaultRunIDHeader, "run1")
+
+		assert.Equal(t, metadata.MD{"x-load-test-run": []string{"run1"}}, *md)
+	})
+
+	t.Run("copies the metadata", func(t *testing.T) {
+		reqMD := metadata.MD{"token": []string{"abc"}}
+		md := withRunIDHeader(&reqMD, "x-run", "run1")
+
+		assert.Equal(t, metadata.MD{"token": []string{"abc"}, "x-run": []string{"run1"}}, *md)
+		assert.Equal(t, metadata.MD{"token": []string{"abc"}}, reqMD)
+	})
+
+	t.Run("already set", func(t *testing.T) {
+		reqMD := metadata.MD{"x-run": []string{"mine"}}
+		md := withRunIDHeader(&reqMD, "x-run", "run1")
+
+		assert.Equal(t, &reqMD, md)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		reqMD := metadata.MD{"token": []string{"abc"}}
+		md := withRunIDHeader(&reqMD, "", "run1")
+
+		assert.Equal(t, &reqMD, md)
+	})
+}
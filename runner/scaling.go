@@ -0,0 +1,286 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+)
+
+// DefaultScalingRatio is the default ratio of the time the calls wait for a stream
+// to the rest of their time above which a connection is added
+const DefaultScalingRatio = 1.0
+
+// DefaultScalingInterval is the default interval of checking whether to add a connection
+const DefaultScalingInterval = 5 * time.Second
+
+// minScalingCalls is the number of calls of an interval below which no connection is added
+const minScalingCalls = 10
+
+// ScalingStats holds the statistics of the adaptive connection scaling
+type ScalingStats struct {
+	// Max is the maximum number of connections, Ratio the ratio of the queuing time to the
+	// server time above which a connection is added, and Interval the interval of the checks
+	Max      uint          `json:"max"`
+	Ratio    float64       `json:"ratio"`
+	Interval time.Duration `json:"interval"`
+
+	// Connections is the number of connections at the end of the run, including the added ones
+	Connections int `json:"connections"`
+
+	// Failed is the number of connections which could not be added
+	Failed uint64 `json:"failed"`
+
+	// Events are the connections added, in order
+	Events []ScalingEvent `json:"events,omitempty"`
+}
+
+// ScalingEvent is a connection added during the run, with the average times of the calls
+// of the interval before and of the interval after, which is the effect of the connection
+type ScalingEvent struct {
+	// Time is the time since the start of the run at which the connection was added
+	Time time.Duration `json:"time"`
+
+	// Connections is the number of connections once the connection was added
+	Connections int `json:"connections"`
+
+	// Queue is the average time the calls waited for a stream on their connection,
+	// and Server the average rest of their time, until the response
+	Queue  time.Duration `json:"queue"`
+	Server time.Duration `json:"server"`
+
+	// QueueAfter and ServerAfter are the averages of the calls of the next interval
+	QueueAfter  time.Duration `json:"queueAfter"`
+	ServerAfter time.Duration `json:"serverAfter"`
+}
+
+// callScaledConnKey is the context key of the connection the call is routed to by the scaler
+type callScaledConnKey struct{}
+
+// scaledConn is a connection the calls are routed to by the scaler
+type scaledConn struct {
+	conn *grpc.ClientConn
+	stub grpcdynamic.Stub
+}
+
+// connScaler adds connections during the run when the calls queue for the streams of their
+// connections, which is when the time from the start of the calls to sending their headers
+// exceeds the rest of their time by the ratio. Once a connection is added, the calls are
+// routed to all the connections in turn.
+type connScaler struct {
+	max      int
+	ratio    float64
+	interval time.Duration
+	dial     func(n int) (*grpc.ClientConn, error)
+
+	hasLog bool
+	log    Logger
+
+	start time.Time
+
+	lock   sync.Mutex
+	closed bool
+	conns  []*scaledConn
+	added  int
+	next   uint64
+	failed uint64
+	events []ScalingEvent
+
+	// the event whose effect is measured over the current interval, if any
+	pending *ScalingEvent
+
+	// the times of the calls of the current interval
+	calls  int64
+	queue  time.Duration
+	server time.Duration
+}
+
+func newConnScaler(c *RunConfig, conns []*grpc.ClientConn, dial func(n int) (*grpc.ClientConn, error)) *connScaler {
+	if c.scaleConns == 0 {
+		return nil
+	}
+
+	s := &connScaler{
+		max:      int(c.scaleConns),
+		ratio:    c.scaleRatio,
+		interval: c.scaleInterval,
+		dial:     dial,
+		hasLog:   c.hasLog,
+		log:      c.log,
+		conns:    make([]*scaledConn, len(conns)),
+	}
+
+	if s.ratio <= 0 {
+		s.ratio = DefaultScalingRatio
+	}
+
+	if s.interval <= 0 {
+		s.interval = DefaultScalingInterval
+	}
+
+	for n, cc := range conns {
+		s.conns[n] = &scaledConn{conn: cc, stub: grpcdynamic.NewStub(cc)}
+	}
+
+	return s
+}
+
+// observe records the time the call waited for a stream and the rest of its time
+func (s *connScaler) observe(queue, server time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.calls++
+	s.queue += queue
+	s.server += server
+	s.lock.Unlock()
+}
+
+// begin checks whether to add a connection after each interval, until the returned function is called
+func (s *connScaler) begin(start time.Time) func() {
+	if s == nil {
+		return func() {}
+	}
+
+	s.start = start
+
+	done := make(chan struct{})
+	checked := make(chan struct{})
+
+	go func() {
+		defer close(checked)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.check(now)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-checked
+	}
+}
+
+// check adds a connection if the calls of the interval queued for the streams of their connections,
+// and records the effect of the connection added at the previous check
+func (s *connScaler) check(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	calls, queue, server := s.calls, s.queue, s.server
+	s.calls, s.queue, s.server = 0, 0, 0
+
+	var avgQueue, avgServer time.Duration
+	if calls > 0 {
+		avgQueue = queue / time.Duration(calls)
+		avgServer = server / time.Duration(calls)
+	}
+
+	if s.pending != nil {
+		s.pending.QueueAfter = avgQueue
+		s.pending.ServerAfter = avgServer
+
+		if s.hasLog {
+			s.log.Debugw("Effect of the added connection", "connections", s.pending.Connections,
+				"queue", avgQueue, "server", avgServer,
+				"queueBefore", s.pending.Queue, "serverBefore", s.pending.Server)
+		}
+
+		s.pending = nil
+	}
+
+	if s.closed || calls < minScalingCalls || len(s.conns) >= s.max ||
+		float64(avgQueue) <= s.ratio*float64(avgServer) {
+		return
+	}
+
+	n := len(s.conns)
+
+	conn, err := s.dial(n)
+	if err != nil {
+		s.failed++
+
+		if s.hasLog {
+			s.log.Errorw("Error adding a connection: "+err.Error(), "connections", n, "error", err)
+		}
+
+		return
+	}
+
+	s.conns = append(s.conns, &scaledConn{conn: conn, stub: grpcdynamic.NewStub(conn)})
+	s.added++
+
+	s.events = append(s.events, ScalingEvent{
+		Time:        now.Sub(s.start),
+		Connections: n + 1,
+		Queue:       avgQueue,
+		Server:      avgServer,
+	})
+
+	s.pending = &s.events[len(s.events)-1]
+
+	if s.hasLog {
+		s.log.Debugw("Added a connection", "connections", n+1, "queue", avgQueue, "server", avgServer)
+	}
+}
+
+// route returns the context of the call with the next connection, once connections have been added
+func (s *connScaler) route(ctx context.Context) context.Context {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.added == 0 {
+		return ctx
+	}
+
+	sc := s.conns[s.next%uint64(len(s.conns))]
+	s.next++
+
+	return context.WithValue(ctx, callScaledConnKey{}, sc)
+}
+
+// close closes the added connections
+func (s *connScaler) close() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+
+	for _, sc := range s.conns[len(s.conns)-s.added:] {
+		_ = sc.conn.Close()
+	}
+}
+
+func (s *connScaler) stats() *ScalingStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return &ScalingStats{
+		Max:         uint(s.max),
+		Ratio:       s.ratio,
+		Interval:    s.interval,
+		Connections: len(s.conns),
+		Failed:      s.failed,
+		Events:      append([]ScalingEvent(nil), s.events...),
+	}
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestConnScaler(t *testing.T) {
+	assert.Nil(t, newConnScaler(&RunConfig{}, nil, nil))
+
+	dial := func(n int) (*grpc.ClientConn, error) {
+		return grpc.Dial(internal.TestLocalhost, grpc.WithInsecure())
+	}
+
+	observe := func(s *connScaler, calls int, queue, server time.Duration) {
+		for i := 0; i < calls; i++ {
+			s.observe(queue, server)
+		}
+	}
+
+	t.Run("adds connections while the calls queue", func(t *testing.T) {
+		cc, err := dial(0)
+		assert.NoError(t, err)
+
+		s := newConnScaler(&RunConfig{scaleConns: 3, scaleRatio: 2}, []*grpc.ClientConn{cc}, dial)
+		assert.Equal(t, DefaultScalingInterval, s.interval)
+
+		start := time.Now()
+		s.start = start
+
+		// the calls are routed to their own connections until a connection is added
+		ctx := s.route(context.Background())
+		assert.Nil(t, ctx.Value(callScaledConnKey{}))
+
+		// the queuing time does not exceed the ratio
+		observe(s, 10, 20*time.Millisecond, 10*time.Millisecond)
+		s.check(start.Add(time.Second))
+		assert.Empty(t, s.events)
+
+		// too few calls
+		observe(s, 5, 50*time.Millisecond, 10*time.Millisecond)
+		s.check(start.Add(2 * time.Second))
+		assert.Empty(t, s.events)
+
+		observe(s, 10, 50*time.Millisecond, 10*time.Millisecond)
+		s.check(start.Add(3 * time.Second))
+		assert.Len(t, s.conns, 2)
+
+		first := s.route(context.Background()).Value(callScaledConnKey{}).(*scaledConn)
+		second := s.route(context.Background()).Value(callScaledConnKey{}).(*scaledConn)
+		assert.Same(t, cc, first.conn)
+		assert.NotSame(t, cc, second.conn)
+
+		// the effect of the connection is that of the next interval, in which the calls still queue
+		observe(s, 10, 30*time.Millisecond, 10*time.Millisecond)
+		s.check(start.Add(4 * time.Second))
+
+		// the maximum is reached
+		observe(s, 10, 30*time.Millisecond, 10*time.Millisecond)
+		s.check(start.Add(5 * time.Second))
+
+		stats := s.stats()
+		assert.Equal(t, uint(3), stats.Max)
+		assert.Equal(t, 2.0, stats.Ratio)
+		assert.Equal(t, 3, stats.Connections)
+		assert.Equal(t, []ScalingEvent{
+			{
+				Time: 3 * time.Second, Connections: 2,
+				Queue: 50 * time.Millisecond, Server: 10 * time.Millisecond,
+				QueueAfter: 30 * time.Millisecond, ServerAfter: 10 * time.Millisecond,
+			},
+			{
+				Time: 4 * time.Second, Connections: 3,
+				Queue: 30 * time.Millisecond, Server: 10 * time.Millisecond,
+				QueueAfter: 30 * time.Millisecond, ServerAfter: 10 * time.Millisecond,
+			},
+		}, stats.Events)
+
+		s.close()
+		_ = cc.Close()
+	})
+
+	t.Run("failed dial", func(t *testing.T) {
+		cc, err := dial(0)
+		assert.NoError(t, err)
+
+		s := newConnScaler(&RunConfig{scaleConns: 2}, []*grpc.ClientConn{cc}, func(n int) (*grpc.ClientConn, error) {
+			return nil, errors.New("dial failed")
+		})
+
+		observe(s, 10, 20*time.Millisecond, 10*time.Millisecond)
+		s.check(time.Now())
+
+		stats := s.stats()
+		assert.Equal(t, DefaultScalingRatio, stats.Ratio)
+		assert.Equal(t, 1, stats.Connections)
+		assert.Equal(t, uint64(1), stats.Failed)
+		assert.Empty(t, stats.Events)
+
+		s.close()
+		_ = cc.Close()
+	})
+}
+
+func TestRunConnectionScaling(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithConnectionScaling(2, 0, 10*time.Millisecond),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]int{"OK": 20}, report.StatusCodeDist)
+	assert.NotNil(t, report.Scaling)
+	assert.Equal(t, uint(2), report.Scaling.Max)
+	assert.Equal(t, 10*time.Millisecond, report.Scaling.Interval)
+
+	// the added connections share the stats handler of the last connection
+	assert.Len(t, report.Connections, 2)
+}
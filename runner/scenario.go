@@ -0,0 +1,434 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// maxScenarioWeight is the maximum total weight of the calls of a scenario
+const maxScenarioWeight = 10000
+
+// The modes of the scenarios
+const (
+	// ScenarioWeighted picks the call of each request by the weights of the calls, which is the default
+	ScenarioWeighted = "weighted"
+
+	// ScenarioSequential makes the calls in order within each worker, each repeated by its weight,
+	// so the fields extracted from the responses of the calls can be used by the following calls
+	ScenarioSequential = "sequential"
+
+	// ScenarioInterleaved makes the calls in the weighted round robin order within each worker,
+	// so the calls of each worker are in the fixed ratio of the weights
+	ScenarioInterleaved = "interleaved"
+)
+
+// ScenarioCall is a call of a scenario, made for its share of the requests of the run
+// according to its weight. The data which is not set is inherited from the config, while the
+// metadata is merged over the metadata of the config and the metadata of the method.
+type ScenarioCall struct {
+	Name     string            `json:"name,omitempty" toml:"name,omitempty" yaml:"name,omitempty"`
+	Call     string            `json:"call,omitempty" toml:"call,omitempty" yaml:"call,omitempty"`
+	Weight   uint              `json:"weight,omitempty" toml:"weight,omitempty" yaml:"weight,omitempty"`
+	Data     interface{}       `json:"data,omitempty" toml:"data,omitempty" yaml:"data,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty" yaml:"metadata,omitempty"`
+
+	// Extract maps the names of the variables to the dot separated paths of the scalar fields of
+	// the response extracted into them, for the templates of the following calls of a sequential scenario
+	Extract map[string]string `json:"extract,omitempty" toml:"extract,omitempty" yaml:"extract,omitempty"`
+}
+
+// MethodStats holds the statistics of the calls of a scenario call
+type MethodStats struct {
+	// Method is the name of the scenario call, which is the call unless it is named
+	Method string `json:"method"`
+
+	Count               uint64                `json:"count"`
+	Rps                 float64               `json:"rps"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+	ErrorDist           map[string]int        `json:"errorDistribution,omitempty"`
+	StatusCodeDist      map[string]int        `json:"statusCodeDistribution"`
+}
+
+// scenarioCall is the method, data and metadata of a call of the scenario
+type scenarioCall struct {
+	name             string
+	mtd              *desc.MethodDescriptor
+	dataProvider     DataProviderFunc
+	metadataProvider MetadataProviderFunc
+
+	// the variables extracted from the response and their field paths
+	extract map[string]string
+}
+
+// scenario picks the call of each request by the weights of the calls,
+// or by the position of the worker within the calls if it is sequential or interleaved
+type scenario struct {
+	calls       []*scenarioCall
+	sequential  bool
+	interleaved bool
+
+	// the indexes of the calls in a weighted round robin, or in order if sequential, repeated for the requests
+	order []int
+}
+
+// scenarioChain is the position of a worker within a sequential scenario, and the
+// variables extracted from the responses of the calls made in the current pass
+type scenarioChain struct {
+	pos  int
+	vars map[string]interface{}
+
+	// whether a call of the iteration failed, when it started and the timings of its calls,
+	// with scenario pacing
+	failed  bool
+	started time.Time
+	calls   []iterationCall
+}
+
+// newScenario creates the scenario of the config, resolving the methods of the calls
+func newScenario(c *RunConfig, getMethod func(string) (*desc.MethodDescriptor, error)) (*scenario, error) {
+	s := &scenario{
+		sequential:  c.scenarioMode == ScenarioSequential,
+		interleaved: c.scenarioMode == ScenarioInterleaved,
+	}
+	weights := make([]uint, len(c.scenario))
+
+	for i, sc := range c.scenario {
+		mtd, err := getMethod(sc.Call)
+		if err != nil {
+			return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+		}
+
+		if mtd.IsClientStreaming() || mtd.IsServerStreaming() {
+			return nil, fmt.Errorf("scenario call %s must be unary", sc.Name)
+		}
+
+		for _, path := range sc.Extract {
+			if err := checkFieldPath(mtd.GetOutputType(), path); err != nil {
+				return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+			}
+		}
+
+		data := c.data
+		if sc.Data != nil {
+			if data, err = json.Marshal(sc.Data); err != nil {
+				return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+			}
+		}
+
+		dp, err := newDataProvider(mtd, false, nil, data, c.funcs, c.dataLabel, c.emitDefaults)
+		if err != nil {
+			return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+		}
+
+		md, err := mergeMetadata(c.metadata, c.methodMetadata[sc.Call], sc.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+		}
+
+		mp, err := newMetadataProvider(mtd, md, c.funcs)
+		if err != nil {
+			return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+		}
+
+		if err := mp.addLists(c.metadataLists); err != nil {
+			return nil, fmt.Errorf("scenario call %s: %v", sc.Name, err)
+		}
+
+		s.calls = append(s.calls, &scenarioCall{
+			name:             sc.Name,
+			mtd:              mtd,
+			dataProvider:     dp.getDataForCall,
+			metadataProvider: mp.getMetadataForCall,
+			extract:          sc.Extract,
+		})
+
+		weights[i] = sc.Weight
+	}
+
+	if s.sequential {
+		s.order = sequentialOrder(weights)
+	} else {
+		s.order = weightedOrder(weights)
+	}
+
+	return s, nil
+}
+
+// scenarioHasCall returns whether a call of the scenario is of the method
+func scenarioHasCall(calls []ScenarioCall, call string) bool {
+	for _, sc := range calls {
+		if sc.Call == call {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeMetadata returns the metadata with the keys of each of the layers set over it in turn, so a
+// key of a layer overrides the same key of the metadata and of the layers before it regardless of
+// its case. The keys are set over each element of array metadata.
+func mergeMetadata(md []byte, layers ...map[string]string) ([]byte, error) {
+	n := 0
+	for _, l := range layers {
+		n += len(l)
+	}
+
+	if n == 0 {
+		return md, nil
+	}
+
+	md = bytes.TrimSpace(md)
+	if len(md) == 0 {
+		md = []byte("{}")
+	}
+
+	if md[0] == '[' {
+		var elems []map[string]json.RawMessage
+		if err := json.Unmarshal(md, &elems); err != nil {
+			return nil, errors.New("the metadata must be a JSON object or array to be merged")
+		}
+
+		for i := range elems {
+			if elems[i] == nil {
+				elems[i] = make(map[string]json.RawMessage, n)
+			}
+
+			if err := setMetadata(elems[i], layers); err != nil {
+				return nil, err
+			}
+		}
+
+		return json.Marshal(elems)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(md, &fields); err != nil || fields == nil {
+		return nil, errors.New("the metadata must be a JSON object or array to be merged")
+	}
+
+	if err := setMetadata(fields, layers); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+// setMetadata sets the keys of the layers in turn, replacing the keys of any case
+func setMetadata(fields map[string]json.RawMessage, layers []map[string]string) error {
+	for _, l := range layers {
+		for k, v := range l {
+			for existing := range fields {
+				if strings.EqualFold(existing, k) {
+					delete(fields, existing)
+				}
+			}
+
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+
+			fields[k] = b
+		}
+	}
+
+	return nil
+}
+
+// pick returns the call of the request, being the call at the position of the chain of the worker
+// if sequential or interleaved
+func (s *scenario) pick(reqNum uint64, chain *scenarioChain) *scenarioCall {
+	if s.sequential || s.interleaved {
+		return s.calls[s.order[chain.pos]]
+	}
+
+	return s.calls[s.order[reqNum%uint64(len(s.order))]]
+}
+
+// advance moves the chain of the worker past the completed call, extracting the variables of its
+// response. The chain restarts from the first call if the call failed or a variable could not be
+// extracted, since the following calls depend on them. The variables are cleared on each pass.
+func (s *scenario) advance(chain *scenarioChain, sc *scenarioCall, res proto.Message, callErr error) error {
+	if callErr != nil {
+		chain.failed = true
+	}
+
+	if s.interleaved {
+		chain.pos = (chain.pos + 1) % len(s.order)
+
+		return nil
+	}
+
+	if !s.sequential {
+		return nil
+	}
+
+	if callErr != nil || res == nil {
+		chain.pos, chain.vars = 0, nil
+
+		return nil
+	}
+
+	if len(sc.extract) > 0 {
+		dm, err := dynamic.AsDynamicMessage(res)
+		if err != nil {
+			chain.pos, chain.vars = 0, nil
+
+			return fmt.Errorf("scenario call %s: %v", sc.name, err)
+		}
+
+		for name, path := range sc.extract {
+			_, v, ok := lookupField(dm, path)
+			if !ok || v == "" {
+				chain.pos, chain.vars = 0, nil
+
+				return fmt.Errorf("scenario call %s: no %q in the response", sc.name, path)
+			}
+
+			if chain.vars == nil {
+				chain.vars = make(map[string]interface{}, len(sc.extract))
+			}
+
+			chain.vars[name] = v
+		}
+	}
+
+	if chain.pos++; chain.pos == len(s.order) {
+		chain.pos, chain.vars = 0, nil
+	}
+
+	return nil
+}
+
+// sequentialOrder returns the indexes of the weights in order, each repeated by its weight
+func sequentialOrder(weights []uint) []int {
+	var order []int
+	for i, w := range weights {
+		for j := uint(0); j < w; j++ {
+			order = append(order, i)
+		}
+	}
+
+	return order
+}
+
+// weightedOrder returns the order of the indexes of the weights using a smooth weighted
+// round robin, so the calls are spread out rather than made in bursts. Each index is
+// included as many times as its weight divided by the greatest common divisor.
+func weightedOrder(weights []uint) []int {
+	g := weights[0]
+	for _, w := range weights[1:] {
+		g = gcd(g, w)
+	}
+
+	total := 0
+	reduced := make([]int, len(weights))
+	for i, w := range weights {
+		reduced[i] = int(w / g)
+		total += reduced[i]
+	}
+
+	current := make([]int, len(weights))
+	order := make([]int, 0, total)
+	for len(order) < total {
+		best := 0
+		for i, w := range reduced {
+			current[i] += w
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+
+		current[best] -= total
+		order = append(order, best)
+	}
+
+	return order
+}
+
+func gcd(a, b uint) uint {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// methodStats returns the statistics of the calls of each scenario call, sorted by the name of the call
+func methodStats(details detailIter, total time.Duration, countErrors bool) []MethodStats {
+	stats := make(map[string]*MethodStats)
+	lats := make(map[string][]float64)
+
+	details.each(func(d *ResultDetail) {
+		if d.Method == "" {
+			return
+		}
+
+		ms, ok := stats[d.Method]
+		if !ok {
+			ms = &MethodStats{Method: d.Method, StatusCodeDist: make(map[string]int)}
+			stats[d.Method] = ms
+		}
+
+		ms.Count++
+		ms.StatusCodeDist[d.Status]++
+
+		if d.Error != "" {
+			if ms.ErrorDist == nil {
+				ms.ErrorDist = make(map[string]int)
+			}
+
+			ms.ErrorDist[d.Error]++
+		}
+
+		if d.Error == "" || countErrors {
+			lats[d.Method] = append(lats[d.Method], d.Latency.Seconds())
+		}
+	})
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	res := make([]MethodStats, 0, len(stats))
+	for method, ms := range stats {
+		if total > 0 {
+			ms.Rps = float64(ms.Count) / total.Seconds()
+		}
+
+		if l := lats[method]; len(l) > 0 {
+			sort.Float64s(l)
+
+			var sum float64
+			for _, v := range l {
+				sum += v
+			}
+
+			ms.Average = time.Duration(sum / float64(len(l)) * float64(time.Second))
+			ms.Fastest = time.Duration(l[0] * float64(time.Second))
+			ms.Slowest = time.Duration(l[len(l)-1] * float64(time.Second))
+			ms.LatencyDistribution = latencies(l)
+		}
+
+		res = append(res, *ms)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Method < res[j].Method
+	})
+
+	return res
+}
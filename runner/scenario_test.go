@@ -0,0 +1,314 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedOrder(t *testing.T) {
+	assert.Equal(t, []int{0}, weightedOrder([]uint{5}))
+	assert.Equal(t, []int{0, 1, 2, 0, 0, 1, 0, 2, 1, 0}, weightedOrder([]uint{50, 30, 20}))
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 2, 0, 0, 1, 0}, weightedOrder([]uint{7000, 2000, 1000}))
+}
+
+func TestSequentialOrder(t *testing.T) {
+	assert.Equal(t, []int{0}, sequentialOrder([]uint{1}))
+	assert.Equal(t, []int{0, 1, 1, 1, 2}, sequentialOrder([]uint{1, 3, 1}))
+}
+
+func TestScenarioAdvance(t *testing.T) {
+	create := &scenarioCall{name: "create", extract: map[string]string{"greeting": "message"}}
+	send := &scenarioCall{name: "send"}
+
+	s := &scenario{calls: []*scenarioCall{create, send}, sequential: true, order: []int{0, 1, 1}}
+
+	chain := &scenarioChain{}
+	assert.Equal(t, create, s.pick(5, chain))
+
+	assert.NoError(t, s.advance(chain, create, &helloworld.HelloReply{Message: "Hello bob"}, nil))
+	assert.Equal(t, 1, chain.pos)
+	assert.Equal(t, map[string]interface{}{"greeting": "Hello bob"}, chain.vars)
+	assert.Equal(t, send, s.pick(0, chain))
+
+	assert.NoError(t, s.advance(chain, send, &helloworld.HelloReply{}, nil))
+	assert.Equal(t, 2, chain.pos)
+	assert.Equal(t, map[string]interface{}{"greeting": "Hello bob"}, chain.vars)
+
+	// the variables are cleared on each pass
+	assert.NoError(t, s.advance(chain, send, &helloworld.HelloReply{}, nil))
+	assert.Equal(t, 0, chain.pos)
+	assert.Nil(t, chain.vars)
+
+	// the chain restarts if a call fails or a variable is missing
+	assert.NoError(t, s.advance(chain, create, &helloworld.HelloReply{Message: "Hello bob"}, nil))
+	assert.NoError(t, s.advance(chain, send, nil, assert.AnError))
+	assert.Equal(t, 0, chain.pos)
+	assert.Nil(t, chain.vars)
+	assert.True(t, chain.failed)
+
+	err := s.advance(chain, create, &helloworld.HelloReply{}, nil)
+	assert.EqualError(t, err, `scenario call create: no "message" in the response`)
+	assert.Equal(t, 0, chain.pos)
+
+	// the weighted scenarios are not chained
+	weighted := &scenario{calls: []*scenarioCall{create, send}, order: []int{0, 1, 1}}
+	assert.Equal(t, send, weighted.pick(4, chain))
+	assert.NoError(t, weighted.advance(chain, create, nil, assert.AnError))
+
+	// the interleaved scenarios follow the order within the worker whatever the results
+	interleaved := &scenario{calls: []*scenarioCall{create, send}, interleaved: true, order: []int{0, 1, 1}}
+	chain = &scenarioChain{}
+	assert.Equal(t, create, interleaved.pick(1, chain))
+	assert.NoError(t, interleaved.advance(chain, create, nil, assert.AnError))
+	assert.Equal(t, send, interleaved.pick(1, chain))
+	assert.NoError(t, interleaved.advance(chain, send, nil, nil))
+	assert.NoError(t, interleaved.advance(chain, send, nil, nil))
+	assert.Equal(t, 0, chain.pos)
+}
+
+func TestMergeMetadata(t *testing.T) {
+	md, err := mergeMetadata([]byte(`{"authorization":"Bearer abc","x-run":"1"}`),
+		map[string]string{"x-route": "writer", "X-Run": "2"},
+		map[string]string{"x-route": "reader"},
+	)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"authorization":"Bearer abc","X-Run":"2","x-route":"reader"}`, string(md))
+
+	// the keys are set over each element of array metadata
+	md, err = mergeMetadata([]byte(`[{"x-run":"1"},{"x-run":"2"}]`), map[string]string{"x-route": "writer"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"x-run":"1","x-route":"writer"},{"x-run":"2","x-route":"writer"}]`, string(md))
+
+	md, err = mergeMetadata(nil, nil, map[string]string{"token": "abc"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"token":"abc"}`, string(md))
+
+	// the metadata is left as is without any keys to set
+	md, err = mergeMetadata([]byte(`{{ .Vars }}`), nil, map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{{ .Vars }}`, string(md))
+
+	_, err = mergeMetadata([]byte(`{{ .Vars }}`), map[string]string{"token": "abc"})
+	assert.EqualError(t, err, "the metadata must be a JSON object or array to be merged")
+}
+
+func TestMethodStats(t *testing.T) {
+	assert.Nil(t, methodStats(detailIter{details: []ResultDetail{{Status: "OK"}}}, time.Second, false))
+
+	stats := methodStats(detailIter{details: []ResultDetail{
+		{Method: "list", Status: "OK", Latency: 30 * time.Millisecond},
+		{Method: "get", Status: "OK", Latency: 10 * time.Millisecond},
+		{Method: "get", Status: "OK", Latency: 20 * time.Millisecond},
+		{Method: "get", Status: "NotFound", Error: "not found", Latency: 90 * time.Millisecond},
+	}}, 2*time.Second, false)
+
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, "get", stats[0].Method)
+		assert.Equal(t, uint64(3), stats[0].Count)
+		assert.Equal(t, 1.5, stats[0].Rps)
+		assert.Equal(t, 15*time.Millisecond, stats[0].Average)
+		assert.Equal(t, 10*time.Millisecond, stats[0].Fastest)
+		assert.Equal(t, 20*time.Millisecond, stats[0].Slowest)
+		assert.Equal(t, map[string]int{"OK": 2, "NotFound": 1}, stats[0].StatusCodeDist)
+		assert.Equal(t, map[string]int{"not found": 1}, stats[0].ErrorDist)
+
+		assert.Equal(t, "list", stats[1].Method)
+		assert.Equal(t, uint64(1), stats[1].Count)
+		assert.Equal(t, 30*time.Millisecond, stats[1].Average)
+		assert.Nil(t, stats[1].ErrorDist)
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("weighted calls", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(40),
+			WithConcurrency(4),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithScenario(
+				ScenarioCall{Name: "alice", Call: "helloworld.Greeter.SayHello", Weight: 3, Data: map[string]interface{}{"name": "alice"}},
+				ScenarioCall{Call: "helloworld.Greeter.SayHello", Metadata: map[string]string{"token": "abc"}},
+			),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 40, int(report.Count))
+		assert.Equal(t, "helloworld.Greeter.SayHello", report.Options.Call)
+		assert.Len(t, report.Options.Scenario, 2)
+		assert.Equal(t, 40, gs.GetCount(helloworld.Unary))
+
+		names := make(map[string]int)
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			names[c[0].GetName()]++
+		}
+
+		assert.Equal(t, map[string]int{"alice": 30, "bob": 10}, names)
+
+		if assert.Len(t, report.Methods, 2) {
+			assert.Equal(t, "alice", report.Methods[0].Method)
+			assert.Equal(t, uint64(30), report.Methods[0].Count)
+			assert.Equal(t, map[string]int{"OK": 30}, report.Methods[0].StatusCodeDist)
+			assert.NotEmpty(t, report.Methods[0].LatencyDistribution)
+
+			assert.Equal(t, "helloworld.Greeter.SayHello", report.Methods[1].Method)
+			assert.Equal(t, uint64(10), report.Methods[1].Count)
+		}
+
+		for _, d := range report.Details {
+			assert.NotEmpty(t, d.Method)
+		}
+	})
+
+	t.Run("method metadata", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(20),
+			WithConcurrency(2),
+			WithData(map[string]interface{}{"name": "__record_metadata__"}),
+			WithMetadata(map[string]string{"token": "run", "authorization": "Bearer run"}),
+			WithMethodMetadata(map[string]map[string]string{
+				"helloworld.Greeter.SayHello": {"authorization": "Bearer method"},
+			}),
+			WithScenario(
+				ScenarioCall{Name: "method", Call: "helloworld.Greeter.SayHello"},
+				ScenarioCall{Name: "step", Call: "helloworld.Greeter.SayHello", Metadata: map[string]string{"token": "step"}},
+			),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20, int(report.Count))
+
+		// the recorded metadata is in no particular order
+		tokens := make(map[string]int)
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			name := c[0].GetName()
+			assert.Contains(t, name, "authorization:Bearer method")
+
+			for _, token := range []string{"token:run", "token:step"} {
+				if strings.Contains(name, token) {
+					tokens[token]++
+				}
+			}
+		}
+
+		assert.Equal(t, map[string]int{"token:run": 10, "token:step": 10}, tokens)
+	})
+
+	t.Run("method metadata of an unknown method", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithMethodMetadata(map[string]map[string]string{"helloworld.Greeter.SayHi": {"token": "abc"}}),
+			WithScenario(ScenarioCall{Call: "helloworld.Greeter.SayHello"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "method metadata of helloworld.Greeter.SayHi, which is not called by the scenario")
+	})
+
+	t.Run("streaming call", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithScenario(
+				ScenarioCall{Call: "helloworld.Greeter.SayHello"},
+				ScenarioCall{Call: "helloworld.Greeter.SayHelloCS"},
+			),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "scenario call helloworld.Greeter.SayHelloCS must be unary")
+	})
+
+	t.Run("sequential calls", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(6),
+			WithConcurrency(1),
+			WithScenario(
+				ScenarioCall{
+					Name:    "create",
+					Call:    "helloworld.Greeter.SayHello",
+					Data:    map[string]interface{}{"name": "alice"},
+					Extract: map[string]string{"greeting": "message"},
+				},
+				ScenarioCall{
+					Name:   "send",
+					Call:   "helloworld.Greeter.SayHello",
+					Weight: 2,
+					Data:   map[string]interface{}{"name": "{{.Vars.greeting}}!"},
+				},
+			),
+			WithScenarioMode(ScenarioSequential),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 6, int(report.Count))
+		assert.Equal(t, ScenarioSequential, report.Options.ScenarioMode)
+
+		var names []string
+		for _, c := range gs.GetCalls(helloworld.Unary) {
+			names = append(names, c[0].GetName())
+		}
+
+		assert.Equal(t, []string{
+			"alice", "Hello alice!", "Hello alice!",
+			"alice", "Hello alice!", "Hello alice!",
+		}, names)
+
+		if assert.Len(t, report.Methods, 2) {
+			assert.Equal(t, uint64(2), report.Methods[0].Count)
+			assert.Equal(t, uint64(4), report.Methods[1].Count)
+		}
+	})
+
+	t.Run("invalid extraction", func(t *testing.T) {
+		_, err := Run(
+			"",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithScenario(
+				ScenarioCall{Name: "create", Call: "helloworld.Greeter.SayHello", Extract: map[string]string{"id": "session_id"}},
+			),
+			WithScenarioMode(ScenarioSequential),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, `scenario call create: field "session_id" not found in message helloworld.HelloReply`)
+	})
+}
@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// schemaRefreshInterval is the minimum interval between the refreshes of the method descriptor,
+// so persistent errors which are not caused by a schema change do not flood the server with
+// reflection requests
+const schemaRefreshInterval = 5 * time.Second
+
+// SchemaChange is a change of the method descriptor found by refreshing it via reflection
+// during the run
+type SchemaChange struct {
+	// Elapsed is the elapsed duration of the test when the descriptor was swapped
+	Elapsed time.Duration `json:"elapsed"`
+
+	// Changes are the descriptions of the changed method, messages and fields
+	Changes []string `json:"changes"`
+}
+
+// schemaRefresher re-resolves the method descriptor via reflection when calls fail with
+// schema errors, and swaps the descriptor and the data provider used by the workers
+type schemaRefresher struct {
+	b *Requester
+
+	// refreshLock serializes the refreshes so the workers are not blocked by them
+	refreshLock sync.Mutex
+	last        time.Time
+
+	lock         sync.RWMutex
+	mtd          *desc.MethodDescriptor
+	dataProvider DataProviderFunc
+	changes      []SchemaChange
+}
+
+func newSchemaRefresher(b *Requester) *schemaRefresher {
+	return &schemaRefresher{b: b, mtd: b.mtd, dataProvider: b.dataProvider}
+}
+
+// current returns the method descriptor and the data provider to use for the next call
+func (s *schemaRefresher) current() (*desc.MethodDescriptor, DataProviderFunc) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.mtd, s.dataProvider
+}
+
+// refresh re-resolves the method descriptor after a call made with the descriptor seen failed
+// with a schema error. The descriptor is only swapped if the schema of the method changed.
+func (s *schemaRefresher) refresh(seen *desc.MethodDescriptor) {
+	s.refreshLock.Lock()
+	defer s.refreshLock.Unlock()
+
+	mtd, _ := s.current()
+
+	// the descriptor was already refreshed after the call was made
+	if mtd != seen || time.Since(s.last) < schemaRefreshInterval {
+		return
+	}
+
+	s.last = time.Now()
+
+	c := s.b.config
+
+	refreshed, err := s.b.resolveMethod()
+	if err != nil {
+		if c.hasLog {
+			c.log.Errorw("Error refreshing the method descriptor: "+err.Error(), "call", c.call, "error", err)
+		}
+
+		return
+	}
+
+	changes := schemaChanges(mtd, refreshed)
+	if len(changes) == 0 {
+		return
+	}
+
+	dataProvider, err := newDefaultDataProvider(c, refreshed)
+	if err != nil {
+		if c.hasLog {
+			c.log.Errorw("Error using the refreshed method descriptor: "+err.Error(), "call", c.call, "error", err)
+		}
+
+		return
+	}
+
+	s.lock.Lock()
+	s.mtd = refreshed
+	s.dataProvider = dataProvider
+	s.changes = append(s.changes, SchemaChange{Elapsed: time.Since(s.b.start), Changes: changes})
+	s.lock.Unlock()
+
+	if c.hasLog {
+		c.log.Debugw("Method descriptor changed", "call", c.call, "changes", changes)
+	}
+}
+
+func (s *schemaRefresher) stats() []SchemaChange {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.changes
+}
+
+// resolveMethod resolves the method descriptor of the call via reflection on a new connection
+func (b *Requester) resolveMethod() (*desc.MethodDescriptor, error) {
+	cc, err := b.newClientConn(b.config.host, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		// purposefully ignoring error as we do not care if there
+		// is an error on close
+		_ = cc.Close()
+	}()
+
+	ctx, cancel := requestContext(b.config.timeout)
+	defer cancel()
+
+	if len(b.config.rmd) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(b.config.rmd))
+	}
+
+	refClient := grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(cc))
+	defer refClient.Reset()
+
+	return protodesc.GetMethodDescFromReflect(b.config.call, refClient)
+}
+
+// isSchemaError returns whether the call failed because the client and the server disagree
+// on the schema of the method, such as a request or response which can not be decoded
+func isSchemaError(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+
+	switch s.Code() {
+	case codes.Unimplemented:
+		return true
+	case codes.Internal:
+		return strings.Contains(s.Message(), "unmarshal")
+	}
+
+	return false
+}
+
+// schemaChanges returns the differences of the method and of its input and output messages,
+// including the nested messages of their fields
+func schemaChanges(old, cur *desc.MethodDescriptor) []string {
+	var changes []string
+
+	if old.IsClientStreaming() != cur.IsClientStreaming() || old.IsServerStreaming() != cur.IsServerStreaming() {
+		changes = append(changes, fmt.Sprintf("changed streaming of method %s", cur.GetFullyQualifiedName()))
+	}
+
+	oldMsgs := map[string]*desc.MessageDescriptor{}
+	collectMessages(old.GetInputType(), oldMsgs)
+	collectMessages(old.GetOutputType(), oldMsgs)
+
+	newMsgs := map[string]*desc.MessageDescriptor{}
+	collectMessages(cur.GetInputType(), newMsgs)
+	collectMessages(cur.GetOutputType(), newMsgs)
+
+	if old.GetInputType().GetFullyQualifiedName() != cur.GetInputType().GetFullyQualifiedName() {
+		changes = append(changes, fmt.Sprintf("changed input of method %s to %s",
+			cur.GetFullyQualifiedName(), cur.GetInputType().GetFullyQualifiedName()))
+	}
+
+	if old.GetOutputType().GetFullyQualifiedName() != cur.GetOutputType().GetFullyQualifiedName() {
+		changes = append(changes, fmt.Sprintf("changed output of method %s to %s",
+			cur.GetFullyQualifiedName(), cur.GetOutputType().GetFullyQualifiedName()))
+	}
+
+	names := make([]string, 0, len(newMsgs))
+	for name := range newMsgs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if om, ok := oldMsgs[name]; ok {
+			changes = append(changes, fieldChanges(om, newMsgs[name])...)
+		}
+	}
+
+	return changes
+}
+
+// collectMessages adds the message and the messages of its fields by their names
+func collectMessages(md *desc.MessageDescriptor, msgs map[string]*desc.MessageDescriptor) {
+	if _, ok := msgs[md.GetFullyQualifiedName()]; ok {
+		return
+	}
+
+	msgs[md.GetFullyQualifiedName()] = md
+
+	for _, fd := range md.GetFields() {
+		if fmd := fd.GetMessageType(); fmd != nil {
+			collectMessages(fmd, msgs)
+		}
+	}
+}
+
+// fieldChanges returns the added, removed and changed fields of the message
+func fieldChanges(old, cur *desc.MessageDescriptor) []string {
+	var changes []string
+
+	name := cur.GetFullyQualifiedName()
+
+	for _, fd := range cur.GetFields() {
+		ofd := old.FindFieldByName(fd.GetName())
+		if ofd == nil {
+			changes = append(changes, fmt.Sprintf("added field %s.%s", name, fd.GetName()))
+		} else if fieldSignature(ofd) != fieldSignature(fd) {
+			changes = append(changes, fmt.Sprintf("changed field %s.%s", name, fd.GetName()))
+		}
+	}
+
+	for _, ofd := range old.GetFields() {
+		if cur.FindFieldByName(ofd.GetName()) == nil {
+			changes = append(changes, fmt.Sprintf("removed field %s.%s", name, ofd.GetName()))
+		}
+	}
+
+	return changes
+}
+
+// fieldSignature describes the number, label and type of the field
+func fieldSignature(fd *desc.FieldDescriptor) string {
+	typeName := fd.GetType().String()
+	if md := fd.GetMessageType(); md != nil {
+		typeName = md.GetFullyQualifiedName()
+	} else if ed := fd.GetEnumType(); ed != nil {
+		typeName = ed.GetFullyQualifiedName()
+	}
+
+	return fmt.Sprintf("%d %s %s", fd.GetNumber(), fd.GetLabel(), typeName)
+}
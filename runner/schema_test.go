@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchema_schemaChanges(t *testing.T) {
+	parse := func(src string) *protoparse.Parser {
+		return &protoparse.Parser{
+			Accessor: protoparse.FileContentsFromMap(map[string]string{"greeter.proto": src}),
+		}
+	}
+
+	old, err := parse(`
+syntax = "proto3";
+package helloworld;
+service Greeter { rpc SayHello (HelloRequest) returns (HelloReply) {} }
+message HelloRequest { string name = 1; Info info = 2; }
+message Info { int32 age = 1; string note = 2; }
+message HelloReply { string message = 1; }`).ParseFiles("greeter.proto")
+	assert.NoError(t, err)
+
+	cur, err := parse(`
+syntax = "proto3";
+package helloworld;
+service Greeter { rpc SayHello (HelloRequest) returns (stream HelloReply) {} }
+message HelloRequest { string name = 1; Info info = 2; }
+message Info { int64 age = 1; string email = 3; }
+message HelloReply { string message = 1; }`).ParseFiles("greeter.proto")
+	assert.NoError(t, err)
+
+	oldMtd := old[0].FindService("helloworld.Greeter").FindMethodByName("SayHello")
+	curMtd := cur[0].FindService("helloworld.Greeter").FindMethodByName("SayHello")
+
+	assert.Empty(t, schemaChanges(oldMtd, oldMtd))
+	assert.Equal(t, []string{
+		"changed streaming of method helloworld.Greeter.SayHello",
+		"changed field helloworld.Info.age",
+		"added field helloworld.Info.email",
+		"removed field helloworld.Info.note",
+	}, schemaChanges(oldMtd, curMtd))
+}
+
+func TestSchema_isSchemaError(t *testing.T) {
+	assert.True(t, isSchemaError(status.Error(codes.Internal, "grpc: error unmarshalling request: unexpected EOF")))
+	assert.True(t, isSchemaError(status.Error(codes.Unimplemented, "unknown method SayHello")))
+	assert.False(t, isSchemaError(status.Error(codes.Internal, "internal failure")))
+	assert.False(t, isSchemaError(status.Error(codes.Unavailable, "connection refused")))
+	assert.False(t, isSchemaError(nil))
+}
+
+func TestRunReflectionRefresh(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("unchanged schema", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithReflectionRefresh(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(10), report.Count)
+		assert.Len(t, report.ErrorDist, 0)
+		assert.Empty(t, report.SchemaChanges)
+	})
+
+	t.Run("with proto file", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithReflectionRefresh(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "reflection refresh requires the method to be resolved via reflection")
+	})
+
+	t.Run("with async", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithTotalRequests(10),
+			WithAsync(true),
+			WithReflectionRefresh(true),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "reflection refresh cannot be used with async")
+	})
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc/metadata"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// ServerInfo is the identity of the server tested, captured before the test starts
+type ServerInfo struct {
+	// Services are the names of the services listed by the server reflection,
+	// if the server supports reflection
+	Services []string `json:"services,omitempty"`
+
+	// Health is the serving status from the standard health service,
+	// if the server implements it
+	Health string `json:"health,omitempty"`
+
+	// VersionCall is the method called to get the version of the server
+	VersionCall string `json:"versionCall,omitempty"`
+
+	// Version is the JSON response of the version call
+	Version json.RawMessage `json:"version,omitempty"`
+}
+
+// captureServerInfo captures the identity of the server for the report
+func (b *Requester) captureServerInfo() error {
+	info, err := b.getServerInfo()
+	if err != nil {
+		return err
+	}
+
+	b.lock.Lock()
+	b.server = info
+	b.lock.Unlock()
+
+	return nil
+}
+
+// getServerInfo captures the identity of the server on a separate connection, so the calls
+// are not included in the results. The reflection and health services are optional, while
+// the version call has to succeed.
+func (b *Requester) getServerInfo() (*ServerInfo, error) {
+	cc, err := b.newClientConn(b.config.host, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		// purposefully ignoring error as we do not care if there
+		// is an error on close
+		_ = cc.Close()
+	}()
+
+	ctx, cancel := requestContext(b.config.timeout)
+	defer cancel()
+
+	if len(b.config.rmd) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(b.config.rmd))
+	}
+
+	info := &ServerInfo{}
+
+	refClient := grpcreflect.NewClient(ctx, reflectpb.NewServerReflectionClient(cc))
+	if services, err := refClient.ListServices(); err == nil {
+		sort.Strings(services)
+		info.Services = services
+	} else if b.config.hasLog {
+		b.config.log.Debugw("Server reflection not available", "error", err)
+	}
+
+	refClient.Reset()
+
+	if res, err := healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		info.Health = res.GetStatus().String()
+	} else if b.config.hasLog {
+		b.config.log.Debugw("Server health not available", "error", err)
+	}
+
+	if b.versionMtd != nil {
+		info.VersionCall = b.config.serverVersionCall
+
+		if info.Version, err = callVersion(ctx, grpcdynamic.NewStub(cc), b.versionMtd); err != nil {
+			return nil, fmt.Errorf("server version call %s: %v", b.config.serverVersionCall, err)
+		}
+	}
+
+	return info, nil
+}
+
+// callVersion makes the version call with an empty request and returns the JSON response
+func callVersion(ctx context.Context, stub grpcdynamic.Stub, mtd *desc.MethodDescriptor) (json.RawMessage, error) {
+	res, err := stub.InvokeRpc(ctx, mtd, dynamic.NewMessage(mtd.GetInputType()))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := (&jsonpb.Marshaler{}).MarshalToString(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(data), nil
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/reflection"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRunServerInfo(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	s := grpc.NewServer()
+	helloworld.RegisterGreeterServer(s, helloworld.NewGreeter())
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	reflection.Register(s)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	host := lis.Addr().String()
+
+	t.Run("with version call", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithConcurrency(1),
+			WithServerVersionCall("helloworld.Greeter.SayHello"),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, report)
+
+		// the server info calls are not included in the results
+		assert.Equal(t, uint64(5), report.Count)
+
+		if assert.NotNil(t, report.Server) {
+			assert.Equal(t, []string{"grpc.health.v1.Health", "grpc.reflection.v1alpha.ServerReflection", "helloworld.Greeter"}, report.Server.Services)
+			assert.Equal(t, "SERVING", report.Server.Health)
+			assert.Equal(t, "helloworld.Greeter.SayHello", report.Server.VersionCall)
+			assert.JSONEq(t, `{"message":"Hello "}`, string(report.Server.Version))
+		}
+
+		assert.True(t, report.Options.ServerInfo)
+		assert.Equal(t, "helloworld.Greeter.SayHello", report.Options.ServerVersionCall)
+	})
+
+	t.Run("without server info", func(t *testing.T) {
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(5),
+			WithConcurrency(1),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Nil(t, report.Server)
+	})
+
+	t.Run("invalid version call", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			host,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithServerVersionCall("helloworld.Greeter.SayHelloCS"),
+			WithData(map[string]interface{}{"name": "bob"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "server version call helloworld.Greeter.SayHelloCS must be unary")
+	})
+}
+
+func TestRunServerInfo_unsupported(t *testing.T) {
+	_, s, err := internal.StartSleepServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"main.SleepService.SleepFor",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/sleep.proto", []string{}),
+		WithTotalRequests(2),
+		WithConcurrency(1),
+		WithServerInfo(true),
+		WithData(map[string]interface{}{"Milliseconds": 1}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, report.Server) {
+		assert.Equal(t, []string{"grpc.reflection.v1alpha.ServerReflection", "main.SleepService"}, report.Server.Services)
+		assert.Empty(t, report.Server.Health)
+		assert.Nil(t, report.Server.Version)
+	}
+}
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultSessionMetadata is the metadata attached to the calls of a session
+// when no session metadata is specified
+const defaultSessionMetadata = `{"authorization":"{{.SessionToken}}"}`
+
+// sessionCallKey is the context key marking the session calls of the workers
+// so that they are not included in the results
+type sessionCallKey struct{}
+
+// workerSession is the session of a single worker, established with the
+// session call on the first request and closed when the worker stops
+type workerSession struct {
+	mtd      *desc.MethodDescriptor
+	closeMtd *desc.MethodDescriptor
+
+	lock  sync.Mutex
+	token string
+}
+
+// getToken returns the session token, making the session call if the session
+// has not been established yet
+func (s *workerSession) getToken(w *Worker, ctd *CallData) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	res, err := w.makeSessionCall(s.mtd, ctd, w.config.sessionData)
+	if err != nil {
+		return "", fmt.Errorf("error creating session: %v", err)
+	}
+
+	token, _ := fieldValue(res, w.config.sessionToken)
+	if token == "" {
+		return "", fmt.Errorf("error creating session: no %q in the response of %s",
+			w.config.sessionToken, s.mtd.GetFullyQualifiedName())
+	}
+
+	s.token = token
+
+	if w.config.hasLog {
+		w.config.log.Debugw("Session created", "workerID", w.workerID,
+			"call", s.mtd.GetFullyQualifiedName())
+	}
+
+	return token, nil
+}
+
+// close makes the session close call if the session was established
+func (s *workerSession) close(w *Worker) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token == "" || s.closeMtd == nil {
+		return nil
+	}
+
+	ctd := newCallData(s.closeMtd, w.config.funcs, w.workerID, 0)
+	ctd.RunID = w.config.runID
+	ctd.SessionToken = s.token
+
+	s.token = ""
+
+	if _, err := w.makeSessionCall(s.closeMtd, ctd, w.config.sessionCloseData); err != nil {
+		return fmt.Errorf("error closing session: %v", err)
+	}
+
+	return nil
+}
+
+// sessionMetadata returns the request metadata with the session metadata added
+func (w *Worker) sessionMetadata(ctd *CallData, reqMD *metadata.MD) (*metadata.MD, error) {
+	mdData := w.config.sessionMetadata
+	if len(mdData) == 0 {
+		mdData = []byte(defaultSessionMetadata)
+	}
+
+	mdMap, err := ctd.executeMetadata(string(mdData))
+	if err != nil {
+		return nil, err
+	}
+
+	// the request metadata may be shared between calls
+	md := metadata.MD{}
+	if reqMD != nil {
+		md = reqMD.Copy()
+	}
+
+	for k, v := range mdMap {
+		md.Set(k, v)
+	}
+
+	return &md, nil
+}
+
+// makeSessionCall makes a unary session call which is excluded from the results
+func (w *Worker) makeSessionCall(mtd *desc.MethodDescriptor, ctd *CallData, data []byte) (*dynamic.Message, error) {
+	input, err := ctd.ExecuteData(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := createPayloadsFromJSON(string(input), mtd)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.config.emitDefaults {
+		emitDefaults(inputs)
+	}
+
+	payload := dynamic.NewMessage(mtd.GetInputType())
+	if len(inputs) > 0 {
+		payload = inputs[0]
+	}
+
+	ctx := context.WithValue(context.Background(), sessionCallKey{}, true)
+	var cancel context.CancelFunc
+
+	if w.config.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, w.config.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	reqMD, err := w.metadataProvider(ctd)
+	if err != nil {
+		return nil, err
+	}
+
+	// the close call is made within the session
+	if ctd.SessionToken != "" {
+		if reqMD, err = w.sessionMetadata(ctd, reqMD); err != nil {
+			return nil, err
+		}
+	}
+
+	reqMD = withRunIDHeader(reqMD, w.config.runIDHeader, w.config.runID)
+
+	if reqMD != nil {
+		ctx = metadata.NewOutgoingContext(ctx, *reqMD)
+	}
+
+	res, err := w.stub.InvokeRpc(ctx, mtd, payload)
+
+	if w.config.hasLog {
+		w.config.log.Debugw("Session call", "workerID", w.workerID,
+			"call", mtd.GetFullyQualifiedName(), "input", payload,
+			"response", res, "error", err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamic.AsDynamicMessage(res)
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"context"
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ShardStats holds the number of calls routed to each connection by the shard key
+type ShardStats struct {
+	// Key is the metadata key of the requests used as the shard key
+	Key string `json:"key"`
+
+	// Counts is the number of calls routed to each connection
+	Counts []uint64 `json:"counts,omitempty"`
+
+	// Unkeyed is the number of calls without the shard key,
+	// which were made on the connection of the worker
+	Unkeyed uint64 `json:"unkeyed"`
+
+	// Connections are the statistics of the dedicated connections of the values,
+	// which are used instead of the connections of the pool if a maximum is set
+	Connections *ShardConnStats `json:"connections,omitempty"`
+}
+
+// callShardKey is the context key of the index of the connection the call is routed to
+type callShardKey struct{}
+
+// shardRouter routes the calls to the connections by the value of the shard key in the
+// request metadata using consistent hashing, so all the calls with the same value are made
+// on the same connection, and most values stay on the same connection if the number of
+// connections is changed. With the dedicated connections each value has a connection of its own.
+type shardRouter struct {
+	key   string
+	stubs []grpcdynamic.Stub
+	conns []*grpc.ClientConn
+
+	counts  []uint64
+	unkeyed uint64
+
+	// the dedicated connections of the values, if any
+	pool *shardConnPool
+}
+
+func newShardRouter(key string, stubs []grpcdynamic.Stub, conns []*grpc.ClientConn) *shardRouter {
+	if key == "" {
+		return nil
+	}
+
+	return &shardRouter{
+		key:    key,
+		stubs:  stubs,
+		conns:  conns,
+		counts: make([]uint64, len(conns)),
+	}
+}
+
+// route returns the context of the call with the connection the call is routed to
+func (s *shardRouter) route(ctx context.Context, reqMD *metadata.MD) context.Context {
+	var values []string
+	if reqMD != nil {
+		values = reqMD.Get(s.key)
+	}
+
+	if len(values) == 0 || values[0] == "" {
+		atomic.AddUint64(&s.unkeyed, 1)
+
+		return ctx
+	}
+
+	if s.pool != nil {
+		// the call falls back to the connection of the worker if the connection can not be opened
+		sc, err := s.pool.acquire(values[0])
+		if err != nil {
+			return ctx
+		}
+
+		return context.WithValue(ctx, callShardConnKey{}, sc)
+	}
+
+	shard := shardIndex(values[0], len(s.conns))
+	atomic.AddUint64(&s.counts[shard], 1)
+
+	return context.WithValue(ctx, callShardKey{}, shard)
+}
+
+// release completes the call on the dedicated connection the call was routed to, if any
+func (s *shardRouter) release(ctx context.Context) {
+	if sc, ok := ctx.Value(callShardConnKey{}).(*shardConn); ok {
+		s.pool.release(sc)
+	}
+}
+
+func (s *shardRouter) stats() *ShardStats {
+	st := &ShardStats{
+		Key:     s.key,
+		Unkeyed: atomic.LoadUint64(&s.unkeyed),
+	}
+
+	if s.pool != nil {
+		st.Connections = s.pool.stats()
+
+		return st
+	}
+
+	st.Counts = make([]uint64, len(s.counts))
+
+	for i := range s.counts {
+		st.Counts[i] = atomic.LoadUint64(&s.counts[i])
+	}
+
+	return st
+}
+
+// shardIndex returns the index of the connection of the shard key value
+// using the jump consistent hash of the FNV-1a hash of the value
+func shardIndex(value string, n int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+
+	return jumpHash(h.Sum64(), n)
+}
+
+// jumpHash is the jump consistent hash of the key to one of n buckets,
+// see https://arxiv.org/abs/1406.2294
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// callStub returns the stub of the connection the call is routed to
+func (w *Worker) callStub(ctx context.Context) grpcdynamic.Stub {
+	if w.shards != nil {
+		if sc, ok := ctx.Value(callShardConnKey{}).(*shardConn); ok {
+			return sc.stub
+		}
+
+		if shard, ok := ctx.Value(callShardKey{}).(int); ok {
+			return w.shards.stubs[shard]
+		}
+	}
+
+	if cc, ok := ctx.Value(callChurnConnKey{}).(*churnConn); ok {
+		return cc.stub
+	}
+
+	if sc, ok := ctx.Value(callScaledConnKey{}).(*scaledConn); ok {
+		return sc.stub
+	}
+
+	return w.stub
+}
+
+// callConn returns the connection the call is routed to
+func (w *Worker) callConn(ctx context.Context) *grpc.ClientConn {
+	if w.shards != nil {
+		if sc, ok := ctx.Value(callShardConnKey{}).(*shardConn); ok {
+			return sc.conn
+		}
+
+		if shard, ok := ctx.Value(callShardKey{}).(int); ok {
+			return w.shards.conns[shard]
+		}
+	}
+
+	if cc, ok := ctx.Value(callChurnConnKey{}).(*churnConn); ok {
+		return cc.conn
+	}
+
+	if sc, ok := ctx.Value(callScaledConnKey{}).(*scaledConn); ok {
+		return sc.conn
+	}
+
+	return w.conn
+}
@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+)
+
+// errShardConnsClosed is returned when acquiring a connection after the connections are closed
+var errShardConnsClosed = errors.New("shard connections are closed")
+
+// ShardConnStats holds the statistics of the dedicated connections of the shard key values
+type ShardConnStats struct {
+	// Max is the maximum number of connections kept open
+	Max int `json:"max"`
+
+	// Calls is the number of calls made on the dedicated connections
+	Calls uint64 `json:"calls"`
+
+	// Opened is the number of connections opened, and Recycled of those the number closed
+	// as the least recently used to stay within the maximum
+	Opened   uint64 `json:"opened"`
+	Recycled uint64 `json:"recycled"`
+
+	// Reopened is the number of connections opened for values whose connection was recycled before
+	Reopened uint64 `json:"reopened"`
+
+	// Peak is the largest number of connections open at once
+	Peak int `json:"peak"`
+
+	// Overflows is the number of connections opened beyond the maximum,
+	// as all the open connections had calls in flight
+	Overflows uint64 `json:"overflows"`
+
+	// RecycledCalls is the average number of calls made on the recycled connections,
+	// and RecycledLifetime the average time they were open
+	RecycledCalls    float64       `json:"recycledCalls"`
+	RecycledLifetime time.Duration `json:"recycledLifetime"`
+}
+
+// callShardConnKey is the context key of the dedicated connection the call is routed to
+type callShardConnKey struct{}
+
+// shardConn is the dedicated connection of a shard key value
+type shardConn struct {
+	key    string
+	conn   *grpc.ClientConn
+	stub   grpcdynamic.Stub
+	opened time.Time
+
+	// the number of calls made on the connection, and of those the number in flight
+	calls  uint64
+	active int
+}
+
+// shardConnPool opens a dedicated connection for each shard key value, keeping at most
+// max connections open by recycling the least recently used connection without calls in flight
+type shardConnPool struct {
+	max  int
+	dial func() (*grpc.ClientConn, error)
+
+	lock   sync.Mutex
+	closed bool
+
+	// the open connections, most recently used first
+	lru   *list.List
+	conns map[string]*list.Element
+
+	// the values whose connection was recycled and has not been opened again
+	recycled map[string]struct{}
+
+	calls            uint64
+	opened           uint64
+	reopened         uint64
+	peak             int
+	overflows        uint64
+	recycledCount    uint64
+	recycledCalls    uint64
+	recycledLifetime time.Duration
+}
+
+func newShardConnPool(max int, dial func() (*grpc.ClientConn, error)) *shardConnPool {
+	if max <= 0 {
+		return nil
+	}
+
+	return &shardConnPool{
+		max:      max,
+		dial:     dial,
+		lru:      list.New(),
+		conns:    make(map[string]*list.Element),
+		recycled: make(map[string]struct{}),
+	}
+}
+
+// acquire returns the connection of the value, opening it if it is not open.
+// The connection has to be released once the call is complete.
+func (p *shardConnPool) acquire(key string) (*shardConn, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil, errShardConnsClosed
+	}
+
+	if e, ok := p.conns[key]; ok {
+		p.lru.MoveToFront(e)
+
+		sc := e.Value.(*shardConn)
+		sc.calls++
+		sc.active++
+		p.calls++
+
+		return sc, nil
+	}
+
+	cc, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &shardConn{key: key, conn: cc, stub: grpcdynamic.NewStub(cc), opened: time.Now(), calls: 1, active: 1}
+	p.conns[key] = p.lru.PushFront(sc)
+	p.calls++
+	p.opened++
+
+	if _, ok := p.recycled[key]; ok {
+		delete(p.recycled, key)
+		p.reopened++
+	}
+
+	p.trim()
+
+	if n := p.lru.Len(); n > p.max {
+		p.overflows++
+	}
+
+	if n := p.lru.Len(); n > p.peak {
+		p.peak = n
+	}
+
+	return sc, nil
+}
+
+// release completes a call made on the connection
+func (p *shardConnPool) release(sc *shardConn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sc.active--
+
+	if !p.closed {
+		p.trim()
+	}
+}
+
+// trim recycles the least recently used connections without calls in flight
+// until no more than the maximum number of connections are open
+func (p *shardConnPool) trim() {
+	for e := p.lru.Back(); e != nil && p.lru.Len() > p.max; {
+		prev := e.Prev()
+
+		if sc := e.Value.(*shardConn); sc.active == 0 {
+			p.lru.Remove(e)
+			delete(p.conns, sc.key)
+			p.recycled[sc.key] = struct{}{}
+
+			p.recycledCount++
+			p.recycledCalls += sc.calls
+			p.recycledLifetime += time.Since(sc.opened)
+
+			_ = sc.conn.Close()
+		}
+
+		e = prev
+	}
+}
+
+// close closes all the open connections
+func (p *shardConnPool) close() {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.closed = true
+
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		_ = e.Value.(*shardConn).conn.Close()
+	}
+}
+
+func (p *shardConnPool) stats() *ShardConnStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	st := &ShardConnStats{
+		Max:       p.max,
+		Calls:     p.calls,
+		Opened:    p.opened,
+		Recycled:  p.recycledCount,
+		Reopened:  p.reopened,
+		Peak:      p.peak,
+		Overflows: p.overflows,
+	}
+
+	if p.recycledCount > 0 {
+		st.RecycledCalls = float64(p.recycledCalls) / float64(p.recycledCount)
+		st.RecycledLifetime = p.recycledLifetime / time.Duration(p.recycledCount)
+	}
+
+	return st
+}
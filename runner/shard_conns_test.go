@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestShardConnPool(t *testing.T) {
+	assert.Nil(t, newShardConnPool(0, nil))
+
+	p := newShardConnPool(2, func() (*grpc.ClientConn, error) {
+		return grpc.Dial(internal.TestLocalhost, grpc.WithInsecure())
+	})
+
+	a, err := p.acquire("a")
+	assert.NoError(t, err)
+	p.release(a)
+
+	b, err := p.acquire("b")
+	assert.NoError(t, err)
+
+	a2, err := p.acquire("a")
+	assert.NoError(t, err)
+	assert.Same(t, a, a2)
+	p.release(a2)
+
+	// b is in flight, so the least recently used a is not recycled
+	_, err = p.acquire("b")
+	assert.NoError(t, err)
+	p.release(b)
+	p.release(b)
+
+	// a is recycled for c
+	c, err := p.acquire("c")
+	assert.NoError(t, err)
+
+	// b and c are in flight, so d overflows
+	b, err = p.acquire("b")
+	assert.NoError(t, err)
+
+	d, err := p.acquire("d")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, p.lru.Len())
+
+	// releasing c recycles it to return to the maximum
+	p.release(c)
+	assert.Equal(t, 2, p.lru.Len())
+
+	p.release(b)
+	p.release(d)
+
+	a, err = p.acquire("a")
+	assert.NoError(t, err)
+	p.release(a)
+
+	stats := p.stats()
+	assert.Equal(t, 2, stats.Max)
+	assert.Equal(t, uint64(8), stats.Calls)
+	assert.Equal(t, uint64(5), stats.Opened)
+	assert.Equal(t, uint64(3), stats.Recycled)
+	assert.Equal(t, uint64(1), stats.Reopened)
+	assert.Equal(t, 3, stats.Peak)
+	assert.Equal(t, uint64(1), stats.Overflows)
+	assert.Equal(t, 2.0, stats.RecycledCalls)
+
+	p.close()
+
+	_, err = p.acquire("a")
+	assert.Equal(t, errShardConnsClosed, err)
+}
+
+func TestRunShardConnections(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(40),
+		WithConcurrency(1),
+		WithShardKey("user-id"),
+		WithShardConnections(2),
+		WithMetadataFromJSON(`[{"user-id":"a"},{"user-id":"b"},{"user-id":"c"},{"other":"d"}]`),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 40, int(report.Count))
+	assert.Equal(t, uint(2), report.Options.ShardConns)
+
+	if assert.NotNil(t, report.Shards) && assert.NotNil(t, report.Shards.Connections) {
+		assert.Empty(t, report.Shards.Counts)
+		assert.Equal(t, uint64(10), report.Shards.Unkeyed)
+
+		// the three values cycle, so each keyed call but the first two opens a connection
+		conns := report.Shards.Connections
+		assert.Equal(t, uint64(30), conns.Calls)
+		assert.Equal(t, uint64(30), conns.Opened)
+		assert.Equal(t, uint64(28), conns.Recycled)
+		assert.Equal(t, uint64(27), conns.Reopened)
+		assert.Equal(t, 2, conns.Peak)
+		assert.Equal(t, 1.0, conns.RecycledCalls)
+	}
+
+	// the dedicated connections share the statistics of a single connection
+	if assert.Len(t, report.Connections, 2) {
+		assert.Equal(t, uint64(30), report.Connections[1].Calls)
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithShardConnections(2),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "shard connections require a shard key")
+}
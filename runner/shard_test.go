@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestJumpHash(t *testing.T) {
+	assert.Equal(t, 0, jumpHash(12345, 1))
+
+	for key := uint64(0); key < 1000; key++ {
+		b := jumpHash(key, 10)
+		assert.True(t, b >= 0 && b < 10)
+		assert.Equal(t, b, jumpHash(key, 10))
+
+		// the keys only move to the new bucket
+		if b11 := jumpHash(key*7919, 11); b11 != 10 {
+			assert.Equal(t, jumpHash(key*7919, 10), b11)
+		}
+	}
+}
+
+func TestShardRouter(t *testing.T) {
+	assert.Nil(t, newShardRouter("", nil, nil))
+
+	s := &shardRouter{key: "user-id", counts: make([]uint64, 4)}
+	s.conns = make([]*grpc.ClientConn, 4)
+
+	for i := 0; i < 20; i++ {
+		md := metadata.Pairs("user-id", "user-"+strconv.Itoa(i%5))
+		ctx := s.route(context.Background(), &md)
+
+		shard, ok := ctx.Value(callShardKey{}).(int)
+		assert.True(t, ok)
+		assert.Equal(t, shardIndex("user-"+strconv.Itoa(i%5), 4), shard)
+	}
+
+	ctx := s.route(context.Background(), &metadata.MD{})
+	assert.Nil(t, ctx.Value(callShardKey{}))
+
+	ctx = s.route(context.Background(), nil)
+	assert.Nil(t, ctx.Value(callShardKey{}))
+
+	stats := s.stats()
+	assert.Equal(t, "user-id", stats.Key)
+	assert.Equal(t, uint64(2), stats.Unkeyed)
+
+	var total uint64
+	for _, c := range stats.Counts {
+		total += c
+	}
+
+	assert.Equal(t, uint64(20), total)
+}
+
+func TestRunShardKey(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(40),
+		WithConcurrency(4),
+		WithConnections(4),
+		WithShardKey("User-ID"),
+		WithMetadataFromJSON(`[{"user-id":"a"},{"user-id":"b"},{"user-id":"c"},{"user-id":"d"},{"other":"e"}]`),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, report)
+
+	assert.Equal(t, 40, int(report.Count))
+	assert.Equal(t, "user-id", report.Options.ShardKey)
+
+	if assert.NotNil(t, report.Shards) {
+		assert.Equal(t, "user-id", report.Shards.Key)
+		assert.Equal(t, uint64(8), report.Shards.Unkeyed)
+		assert.Len(t, report.Shards.Counts, 4)
+
+		expected := make([]uint64, 4)
+		for _, key := range []string{"a", "b", "c", "d"} {
+			expected[shardIndex(key, 4)] += 8
+		}
+
+		assert.Equal(t, expected, report.Shards.Counts)
+
+		// the keyed calls are made on their connections
+		assert.Len(t, report.Connections, 4)
+		for i, c := range report.Connections {
+			assert.True(t, c.Calls >= expected[i], "connection %d", i)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"time"
+)
+
+// TimeSlice holds the summary of the calls completed within a time slice of the run,
+// so the degradation over the course of a long run is visible within the report
+type TimeSlice struct {
+	// Start and End are the bounds of the slice relative to the start of the run.
+	// The end of the final slice is the end of the run.
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+
+	Count     uint64  `json:"count"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	Rps       float64 `json:"rps"`
+
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+
+	StatusCodeDist map[string]int `json:"statusCodeDistribution"`
+	ErrorDist      map[string]int `json:"errorDistribution,omitempty"`
+}
+
+// timeSlices buckets the results into slices of a fixed duration by the time they completed.
+// The latencies of each slice are recorded in a raw histogram, so the memory used by the
+// slices of a long run is bounded.
+type timeSlices struct {
+	slice       time.Duration
+	start       time.Time
+	countErrors bool
+
+	slices []*sliceBucket
+}
+
+// sliceBucket holds the results of a slice
+type sliceBucket struct {
+	count          uint64
+	errors         uint64
+	totalLatencies time.Duration
+	raw            *rawRecorder
+	statusCodeDist map[string]int
+	errorDist      map[string]int
+}
+
+func newTimeSlices(slice time.Duration, start time.Time, countErrors bool) *timeSlices {
+	if slice <= 0 {
+		return nil
+	}
+
+	return &timeSlices{slice: slice, start: start, countErrors: countErrors}
+}
+
+// add adds the result to the slice it completed in
+func (t *timeSlices) add(res *callResult) {
+	if t == nil {
+		return
+	}
+
+	i := 0
+	if res.timestamp.After(t.start) {
+		i = int(res.timestamp.Sub(t.start) / t.slice)
+	}
+
+	for len(t.slices) <= i {
+		t.slices = append(t.slices, &sliceBucket{
+			raw:            newRawRecorder(true),
+			statusCodeDist: make(map[string]int),
+			errorDist:      make(map[string]int),
+		})
+	}
+
+	b := t.slices[i]
+	b.count++
+	b.totalLatencies += res.duration
+	b.statusCodeDist[res.status]++
+
+	if res.err != nil {
+		b.errors++
+		b.errorDist[res.err.Error()]++
+	}
+
+	if res.err == nil || t.countErrors {
+		b.raw.record(res.duration)
+	}
+}
+
+// summaries returns the summaries of the slices of the run of the total duration.
+// The rate of the final slice is relative to the part of it within the run.
+func (t *timeSlices) summaries(total time.Duration) []TimeSlice {
+	res := make([]TimeSlice, len(t.slices))
+	for i, b := range t.slices {
+		s := TimeSlice{
+			Start:          time.Duration(i) * t.slice,
+			End:            time.Duration(i+1) * t.slice,
+			Count:          b.count,
+			Errors:         b.errors,
+			StatusCodeDist: b.statusCodeDist,
+		}
+
+		if s.End > total && total > s.Start {
+			s.End = total
+		}
+
+		if len(b.errorDist) > 0 {
+			s.ErrorDist = b.errorDist
+		}
+
+		if b.count > 0 {
+			s.ErrorRate = float64(b.errors) / float64(b.count)
+			s.Average = b.totalLatencies / time.Duration(b.count)
+		}
+
+		s.Rps = float64(b.count) / (s.End - s.Start).Seconds()
+
+		if h := b.raw.histogram(); h.Count > 0 {
+			s.Fastest = h.Min
+			s.Slowest = h.Max
+			s.LatencyDistribution = h.latencies()
+		}
+
+		res[i] = s
+	}
+
+	return res
+}
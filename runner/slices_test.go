@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSlices_summaries(t *testing.T) {
+	start := time.Now()
+	ts := newTimeSlices(time.Second, start, false)
+
+	for i := 1; i <= 100; i++ {
+		ts.add(&callResult{
+			timestamp: start.Add(time.Duration(i) * 5 * time.Millisecond),
+			duration:  time.Duration(i) * time.Millisecond,
+			status:    "OK",
+		})
+	}
+
+	ts.add(&callResult{
+		timestamp: start.Add(2100 * time.Millisecond),
+		duration:  10 * time.Millisecond,
+		status:    "OK",
+	})
+
+	ts.add(&callResult{
+		timestamp: start.Add(2200 * time.Millisecond),
+		duration:  30 * time.Millisecond,
+		err:       errors.New("unavailable"),
+		status:    "Unavailable",
+	})
+
+	slices := ts.summaries(2500 * time.Millisecond)
+
+	if !assert.Len(t, slices, 3) {
+		return
+	}
+
+	s := slices[0]
+	assert.Equal(t, time.Duration(0), s.Start)
+	assert.Equal(t, time.Second, s.End)
+	assert.Equal(t, uint64(100), s.Count)
+	assert.Equal(t, float64(100), s.Rps)
+	assert.Equal(t, 50500*time.Microsecond, s.Average)
+	assert.Equal(t, time.Millisecond, s.Fastest)
+	assert.Equal(t, 100*time.Millisecond, s.Slowest)
+	assert.Equal(t, map[string]int{"OK": 100}, s.StatusCodeDist)
+	assert.Nil(t, s.ErrorDist)
+
+	if assert.NotEmpty(t, s.LatencyDistribution) {
+		for _, ld := range s.LatencyDistribution {
+			if ld.Percentage == 50 {
+				assert.InDelta(t, float64(50*time.Millisecond), float64(ld.Latency), float64(time.Millisecond))
+			}
+		}
+	}
+
+	// the slices without results are included
+	assert.Equal(t, TimeSlice{Start: time.Second, End: 2 * time.Second, StatusCodeDist: map[string]int{}}, slices[1])
+
+	// the failed calls are not included in the latencies, and the final slice ends with the run
+	s = slices[2]
+	assert.Equal(t, 2*time.Second, s.Start)
+	assert.Equal(t, 2500*time.Millisecond, s.End)
+	assert.Equal(t, uint64(2), s.Count)
+	assert.Equal(t, uint64(1), s.Errors)
+	assert.Equal(t, 0.5, s.ErrorRate)
+	assert.Equal(t, float64(4), s.Rps)
+	assert.Equal(t, 20*time.Millisecond, s.Average)
+	assert.Equal(t, 10*time.Millisecond, s.Fastest)
+	assert.Equal(t, 10*time.Millisecond, s.Slowest)
+	assert.Equal(t, map[string]int{"OK": 1, "Unavailable": 1}, s.StatusCodeDist)
+	assert.Equal(t, map[string]int{"unavailable": 1}, s.ErrorDist)
+
+	assert.Nil(t, newTimeSlices(0, start, false))
+}
+
+func TestRunTimeSlices(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithTimeSlices(time.Second),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, report.Options.TimeSlice)
+
+	var count uint64
+	for _, s := range report.TimeSlices {
+		count += s.Count
+	}
+
+	assert.NotEmpty(t, report.TimeSlices)
+	assert.Equal(t, uint64(20), count)
+}
@@ -0,0 +1,278 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// callStagesKey is the context key of the stage timings received for the call
+type callStagesKey struct{}
+
+// StageStats holds the timings of a server-side stage reported in the response metadata
+type StageStats struct {
+	// Stage is the name of the stage. Nested stages are separated by semicolons.
+	Stage string `json:"stage"`
+
+	// Key is the metadata key the timings of the stage are read from
+	Key string `json:"key"`
+
+	// Count is the number of calls which reported the stage
+	Count uint64 `json:"count"`
+
+	Total   time.Duration `json:"total"`
+	Average time.Duration `json:"average"`
+	Fastest time.Duration `json:"fastest"`
+	Slowest time.Duration `json:"slowest"`
+
+	// Share is the fraction of the total latency of the calls reporting stage timings
+	Share float64 `json:"share"`
+}
+
+// StageTimingStats holds the server-side stage timings aggregated across the calls
+type StageTimingStats struct {
+	// Calls is the number of calls which reported any of the stages
+	Calls uint64 `json:"calls"`
+
+	// Latency is the total latency of the calls which reported any of the stages
+	Latency time.Duration `json:"latency"`
+
+	Stages []StageStats `json:"stages"`
+}
+
+// stageKey is a metadata key holding the timings of one or more stages
+type stageKey struct {
+	// stage is the name of the stage, or the prefix of the stages of a Server-Timing value
+	stage string
+	key   string
+}
+
+// stageSample is the timing of a stage of a single call and the metadata key it was read from
+type stageSample struct {
+	key      string
+	duration time.Duration
+}
+
+// callStages holds the stage timings received in the header and the trailer of a single call
+type callStages struct {
+	lock    sync.Mutex
+	timings map[string]stageSample
+}
+
+// stageTiming aggregates the stage timings of the calls
+type stageTiming struct {
+	keys []stageKey
+
+	lock    sync.Mutex
+	calls   uint64
+	latency time.Duration
+	stages  map[string]*StageStats
+}
+
+// newStageTiming parses the comma separated list of stages as [stage=]key
+func newStageTiming(stages string) (*stageTiming, error) {
+	st := &stageTiming{stages: make(map[string]*StageStats)}
+
+	seen := make(map[string]bool)
+
+	for _, s := range strings.Split(stages, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		sk := stageKey{key: s}
+		if i := strings.Index(s, "="); i >= 0 {
+			sk.stage = strings.TrimSpace(s[:i])
+			sk.key = strings.TrimSpace(s[i+1:])
+		}
+
+		sk.key = strings.ToLower(sk.key)
+		if sk.key == "" {
+			return nil, fmt.Errorf("invalid stage timing %q: the metadata key must be specified", s)
+		}
+
+		for _, frame := range strings.Split(sk.stage, ";") {
+			if sk.stage != "" && strings.TrimSpace(frame) == "" {
+				return nil, fmt.Errorf("invalid stage timing %q: empty nested stage name", s)
+			}
+		}
+
+		if seen[sk.key] {
+			return nil, fmt.Errorf("invalid stage timing %q: duplicate metadata key", s)
+		}
+
+		seen[sk.key] = true
+
+		st.keys = append(st.keys, sk)
+	}
+
+	if len(st.keys) == 0 {
+		return nil, fmt.Errorf("no stage timings specified")
+	}
+
+	return st, nil
+}
+
+// collect adds the stage timings found in the header or the trailer of the call
+func (st *stageTiming) collect(cs *callStages, md metadata.MD) {
+	for _, sk := range st.keys {
+		vals := md.Get(sk.key)
+		if len(vals) == 0 {
+			continue
+		}
+
+		timings := parseStageValue(sk, strings.Join(vals, ","))
+		if len(timings) == 0 {
+			continue
+		}
+
+		cs.lock.Lock()
+		if cs.timings == nil {
+			cs.timings = make(map[string]stageSample, len(timings))
+		}
+
+		for stage, d := range timings {
+			cs.timings[stage] = stageSample{key: sk.key, duration: d}
+		}
+		cs.lock.Unlock()
+	}
+}
+
+// record records the stage timings of a completed call with its latency
+func (st *stageTiming) record(cs *callStages, latency time.Duration) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+
+	if len(cs.timings) == 0 {
+		return
+	}
+
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	st.calls++
+	st.latency += latency
+
+	for stage, sample := range cs.timings {
+		d := sample.duration
+
+		s, ok := st.stages[stage]
+		if !ok {
+			s = &StageStats{Stage: stage, Key: sample.key, Fastest: d, Slowest: d}
+			st.stages[stage] = s
+		}
+
+		s.Count++
+		s.Total += d
+
+		if d < s.Fastest {
+			s.Fastest = d
+		}
+
+		if d > s.Slowest {
+			s.Slowest = d
+		}
+	}
+}
+
+func (st *stageTiming) stats() *StageTimingStats {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	s := &StageTimingStats{
+		Calls:   st.calls,
+		Latency: st.latency,
+		Stages:  make([]StageStats, 0, len(st.stages)),
+	}
+
+	for _, ss := range st.stages {
+		stage := *ss
+		stage.Average = stage.Total / time.Duration(stage.Count)
+
+		if st.latency > 0 {
+			stage.Share = float64(stage.Total) / float64(st.latency)
+		}
+
+		s.Stages = append(s.Stages, stage)
+	}
+
+	// the nested stages follow their parents
+	sort.Slice(s.Stages, func(i, j int) bool {
+		return s.Stages[i].Stage < s.Stages[j].Stage
+	})
+
+	return s
+}
+
+// parseStageValue returns the stage timings of the metadata value. The value is either
+// a single duration, such as 12ms, or a number of milliseconds, or a Server-Timing list
+// of metrics with durations in milliseconds, such as db;dur=53.2, cache;dur=2.
+func parseStageValue(sk stageKey, v string) map[string]time.Duration {
+	v = strings.TrimSpace(v)
+
+	if !strings.Contains(v, "dur=") {
+		d, ok := parseStageDuration(v)
+		if !ok {
+			return nil
+		}
+
+		stage := sk.stage
+		if stage == "" {
+			stage = sk.key
+		}
+
+		return map[string]time.Duration{stage: d}
+	}
+
+	timings := make(map[string]time.Duration)
+
+	for _, metric := range strings.Split(v, ",") {
+		params := strings.Split(metric, ";")
+
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if !strings.HasPrefix(p, "dur=") {
+				continue
+			}
+
+			ms, err := strconv.ParseFloat(strings.Trim(p[len("dur="):], `"`), 64)
+			if err != nil || ms < 0 {
+				break
+			}
+
+			if sk.stage != "" {
+				name = sk.stage + ";" + name
+			}
+
+			timings[name] = time.Duration(ms * float64(time.Millisecond))
+
+			break
+		}
+	}
+
+	return timings
+}
+
+// parseStageDuration parses a duration, or a number of milliseconds
+func parseStageDuration(v string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d, true
+	}
+
+	if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal/echo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestStageTiming_newStageTiming(t *testing.T) {
+	st, err := newStageTiming(" handler=X-Handler-Ms, handler;db = x-db-ms ,server-timing")
+	assert.NoError(t, err)
+	assert.Equal(t, []stageKey{
+		{stage: "handler", key: "x-handler-ms"},
+		{stage: "handler;db", key: "x-db-ms"},
+		{key: "server-timing"},
+	}, st.keys)
+
+	_, err = newStageTiming("db=")
+	assert.EqualError(t, err, `invalid stage timing "db=": the metadata key must be specified`)
+
+	_, err = newStageTiming("handler;;db=x-db-ms")
+	assert.EqualError(t, err, `invalid stage timing "handler;;db=x-db-ms": empty nested stage name`)
+
+	_, err = newStageTiming("a=x-time,b=x-time")
+	assert.EqualError(t, err, `invalid stage timing "b=x-time": duplicate metadata key`)
+
+	_, err = newStageTiming(" , ")
+	assert.EqualError(t, err, "no stage timings specified")
+}
+
+func TestStageTiming_parseStageValue(t *testing.T) {
+	var tests = []struct {
+		name     string
+		sk       stageKey
+		value    string
+		expected map[string]time.Duration
+	}{
+		{"duration", stageKey{key: "x-db"}, "12.5ms", map[string]time.Duration{"x-db": 12500 * time.Microsecond}},
+		{"milliseconds", stageKey{stage: "db", key: "x-db"}, " 3 ", map[string]time.Duration{"db": 3 * time.Millisecond}},
+		{"invalid", stageKey{key: "x-db"}, "fast", nil},
+		{"negative", stageKey{key: "x-db"}, "-2", nil},
+		{"server timing", stageKey{key: "server-timing"}, `db;dur=53.2;desc="database", miss, cache;dur=2`,
+			map[string]time.Duration{"db": 53200 * time.Microsecond, "cache": 2 * time.Millisecond}},
+		{"server timing with stage", stageKey{stage: "app", key: "server-timing"}, "db;dur=5",
+			map[string]time.Duration{"app;db": 5 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseStageValue(tt.sk, tt.value))
+		})
+	}
+}
+
+func TestStageTiming_stats(t *testing.T) {
+	st, err := newStageTiming("handler=x-handler-ms,handler;db=x-db-ms")
+	assert.NoError(t, err)
+
+	cs := &callStages{}
+	st.collect(cs, metadata.Pairs("x-handler-ms", "8"))
+	st.collect(cs, metadata.Pairs("x-db-ms", "6"))
+	st.record(cs, 10*time.Millisecond)
+
+	cs = &callStages{}
+	st.collect(cs, metadata.Pairs("x-handler-ms", "4"))
+	st.record(cs, 10*time.Millisecond)
+
+	// calls without any stage timings are not counted
+	st.record(&callStages{}, 50*time.Millisecond)
+
+	assert.Equal(t, &StageTimingStats{
+		Calls:   2,
+		Latency: 20 * time.Millisecond,
+		Stages: []StageStats{
+			{Stage: "handler", Key: "x-handler-ms", Count: 2, Total: 12 * time.Millisecond, Average: 6 * time.Millisecond,
+				Fastest: 4 * time.Millisecond, Slowest: 8 * time.Millisecond, Share: 0.6},
+			{Stage: "handler;db", Key: "x-db-ms", Count: 1, Total: 6 * time.Millisecond, Average: 6 * time.Millisecond,
+				Fastest: 6 * time.Millisecond, Slowest: 6 * time.Millisecond, Share: 0.3},
+		},
+	}, st.stats())
+}
+
+func TestRunStageTiming(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	svc, err := echo.New(echo.Options{})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	timings := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-queue-ms", "1.5"))
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("server-timing", "db;dur=4, cache;dur=1"))
+
+		return handler(ctx, req)
+	}
+
+	s := grpc.NewServer(grpc.UnaryInterceptor(timings))
+	if err := svc.Register(s); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	reflection.Register(s)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	report, err := Run(
+		"echo.Echo.Echo",
+		lis.Addr().String(),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithStageTiming("queue=x-queue-ms,server-timing"),
+		WithData(map[string]interface{}{"message": "hi"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), report.Count)
+	assert.Equal(t, "queue=x-queue-ms,server-timing", report.Options.StageTiming)
+
+	st := report.StageTiming
+	if assert.NotNil(t, st) {
+		assert.Equal(t, uint64(20), st.Calls)
+		assert.Len(t, st.Stages, 3)
+
+		for i, stage := range []string{"cache", "db", "queue"} {
+			assert.Equal(t, stage, st.Stages[i].Stage)
+			assert.Equal(t, uint64(20), st.Stages[i].Count)
+		}
+
+		assert.Equal(t, "server-timing", st.Stages[0].Key)
+		assert.Equal(t, 4*time.Millisecond, st.Stages[1].Average)
+		assert.Equal(t, "x-queue-ms", st.Stages[2].Key)
+		assert.Equal(t, 1500*time.Microsecond, st.Stages[2].Average)
+	}
+}
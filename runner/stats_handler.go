@@ -2,12 +2,91 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
 )
 
+// callLabelKey is the context key of the data label of the call
+type callLabelKey struct{}
+
+// callIntendedKey is the context key of the intended send time of rate-paced calls
+type callIntendedKey struct{}
+
+// callWorkerKey is the context key of the ID of the worker making the call
+type callWorkerKey struct{}
+
+// callMethodKey is the context key of the name of the scenario call
+type callMethodKey struct{}
+
+// callTenantKey is the context key of the name of the tenant the call is made under
+type callTenantKey struct{}
+
+// callBytesKey is the context key of the wire bytes and messages sent and received by the call
+type callBytesKey struct{}
+
+// callBytes holds the wire bytes and the number of messages sent and received by a single call
+type callBytes struct {
+	sent         uint64
+	received     uint64
+	sentMsgs     uint64
+	receivedMsgs uint64
+
+	// the time to the response headers, only recorded for unary calls
+	header int64
+
+	// the time the request headers were sent, only recorded when scaling the connections
+	outHeader int64
+}
+
+// connTagKey is the context key of the transport connection tag
+type connTagKey struct{}
+
+// connTag identifies a transport connection of the client connection
+type connTag struct {
+	begin  time.Time
+	remote net.Addr
+}
+
+// ConnectionStats holds the statistics of a single client connection of the connection pool
+type ConnectionStats struct {
+	// ID is the index of the connection in the pool
+	ID int `json:"id"`
+
+	// Workers is the number of workers assigned to the connection
+	Workers uint64 `json:"workers"`
+
+	// Calls is the number of calls, or streams, made on the connection
+	Calls uint64 `json:"calls"`
+
+	// MaxStreams is the maximum number of concurrently active streams
+	MaxStreams uint64 `json:"maxStreams"`
+
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
+
+	// Connects is the number of transport connections established.
+	// More than one means the connection has reconnected.
+	Connects uint64 `json:"connects"`
+
+	// Lifetime is the total time the transport connections were open
+	Lifetime time.Duration `json:"lifetime"`
+
+	// RemoteAddr is the address of the last transport connection
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+
+	// Family is the address family of the transport connections, ipv4 or ipv6,
+	// or ipv4+ipv6 if the connection reconnected using the other family
+	Family string `json:"family,omitempty"`
+}
+
 // StatsHandler is for gRPC stats
 type statsHandler struct {
 	results chan *callResult
@@ -16,40 +95,292 @@ type statsHandler struct {
 	hasLog bool
 	log    Logger
 
+	// stages aggregates the server-side stage timings of the calls, if any
+	stages *stageTiming
+
+	// metrics exports the live statistics of the calls, if any
+	metrics *liveMetrics
+
+	// push pushes the statistics of the calls of each interval, if any
+	push *statsPusher
+
+	// alerts checks the thresholds of the alerts over the rolling window of the calls, if any
+	alerts *alertMonitor
+
+	// scaling adds connections when the calls queue for streams, if any
+	scaling *connScaler
+
+	// whether the time to the response headers is recorded, which is enabled for unary calls only
+	headers bool
+
+	// whether the trailers of the failed calls are captured
+	trailers bool
+
+	// the custom classification of the calls, if any
+	classifier SuccessClassifierFunc
+
+	// the assertions of the calls, if any
+	assertions *assertionTracker
+
 	lock   sync.RWMutex
 	ignore bool
+
+	// the calls started before are ignored, such as those of the warmup
+	ignoreBefore time.Time
+
+	connLock      sync.Mutex
+	workers       uint64
+	calls         uint64
+	activeStreams uint64
+	maxStreams    uint64
+	bytesSent     uint64
+	bytesReceived uint64
+	connects      uint64
+	lifetime      time.Duration
+	openConns     map[*connTag]struct{}
+	remoteAddr    string
+	ipv4          bool
+	ipv6          bool
+
+	// the bytes of the messages before compression
+	payloadSent     uint64
+	payloadReceived uint64
 }
 
 // HandleConn handle the connection
 func (c *statsHandler) HandleConn(ctx context.Context, cs stats.ConnStats) {
-	// no-op
+	tag, ok := ctx.Value(connTagKey{}).(*connTag)
+	if !ok {
+		return
+	}
+
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	switch cs.(type) {
+	case *stats.ConnBegin:
+		tag.begin = time.Now()
+		c.connects++
+
+		if c.openConns == nil {
+			c.openConns = make(map[*connTag]struct{})
+		}
+		c.openConns[tag] = struct{}{}
+
+		if tag.remote != nil {
+			c.remoteAddr = tag.remote.String()
+
+			if tcp, ok := tag.remote.(*net.TCPAddr); ok {
+				if tcp.IP.To4() != nil {
+					c.ipv4 = true
+				} else {
+					c.ipv6 = true
+				}
+			}
+		}
+	case *stats.ConnEnd:
+		if _, open := c.openConns[tag]; open {
+			c.lifetime += time.Since(tag.begin)
+			delete(c.openConns, tag)
+		}
+	}
 }
 
-// TagConn exists to satisfy gRPC stats.Handler.
+// TagConn tags the connection so its lifetime and address can be tracked
 func (c *statsHandler) TagConn(ctx context.Context, cti *stats.ConnTagInfo) context.Context {
-	// no-op
-	return ctx
+	return context.WithValue(ctx, connTagKey{}, &connTag{remote: cti.RemoteAddr})
+}
+
+// addWorker counts a worker assigned to the connection
+func (c *statsHandler) addWorker() {
+	c.connLock.Lock()
+	c.workers++
+	c.connLock.Unlock()
+}
+
+// connStats returns the statistics of the connection, with the lifetime
+// of the transport connections which are still open counted up to now
+func (c *statsHandler) connStats(now time.Time) ConnectionStats {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	lifetime := c.lifetime
+	for tag := range c.openConns {
+		lifetime += now.Sub(tag.begin)
+	}
+
+	var family string
+	switch {
+	case c.ipv4 && c.ipv6:
+		family = "ipv4+ipv6"
+	case c.ipv4:
+		family = "ipv4"
+	case c.ipv6:
+		family = "ipv6"
+	}
+
+	return ConnectionStats{
+		ID:            c.id,
+		Workers:       c.workers,
+		Calls:         c.calls,
+		MaxStreams:    c.maxStreams,
+		BytesSent:     c.bytesSent,
+		BytesReceived: c.bytesReceived,
+		Connects:      c.connects,
+		Lifetime:      lifetime,
+		RemoteAddr:    c.remoteAddr,
+		Family:        family,
+	}
 }
 
 // HandleRPC implements per-RPC tracing and stats instrumentation.
 func (c *statsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	c.handleConnRPC(rs)
+
+	if wl, ok := ctx.Value(wireLogKey{}).(*wireLogger); ok {
+		if err := wl.record(c.id, rs); err != nil && c.hasLog {
+			c.log.Errorw("Error writing wire log: "+err.Error(), "statsID", c.id, "error", err)
+		}
+	}
+
 	switch rs := rs.(type) {
+	case *stats.OutPayload:
+		if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok {
+			atomic.AddUint64(&cb.sent, uint64(rs.WireLength))
+			atomic.AddUint64(&cb.sentMsgs, 1)
+		}
+	case *stats.InPayload:
+		if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok {
+			atomic.AddUint64(&cb.received, uint64(rs.WireLength))
+			atomic.AddUint64(&cb.receivedMsgs, 1)
+		}
+
+		if cr, ok := ctx.Value(callResponseKey{}).(*callResponse); ok {
+			cr.set(rs.Payload)
+		}
+	case *stats.InHeader:
+		if cs, ok := ctx.Value(callStagesKey{}).(*callStages); ok {
+			c.stages.collect(cs, rs.Header)
+		}
+
+		if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok && c.headers {
+			atomic.StoreInt64(&cb.header, time.Now().UnixNano())
+		}
+	case *stats.InTrailer:
+		if cs, ok := ctx.Value(callStagesKey{}).(*callStages); ok {
+			c.stages.collect(cs, rs.Trailer)
+		}
+	case *stats.OutHeader:
+		if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok && c.scaling != nil {
+			atomic.StoreInt64(&cb.outHeader, time.Now().UnixNano())
+		}
+	case *stats.Begin:
+		c.metrics.begin()
 	case *stats.End:
+		c.metrics.end()
+
+		// the time the call waited for a stream is until its headers were sent, including that of the calls ignored
+		if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok && c.scaling != nil {
+			if at := atomic.LoadInt64(&cb.outHeader); at > 0 {
+				sent := time.Unix(0, at)
+				c.scaling.observe(sent.Sub(rs.BeginTime), rs.EndTime.Sub(sent))
+			}
+		}
+
 		ign := false
 		c.lock.RLock()
-		ign = c.ignore
+		ign = c.ignore || rs.BeginTime.Before(c.ignoreBefore)
 		c.lock.RUnlock()
 
+		// session calls of the workers are not part of the results
+		if ctx.Value(sessionCallKey{}) != nil {
+			ign = true
+		}
+
+		// readiness probes are made before the load starts
+		if ctx.Value(readinessCallKey{}) != nil {
+			ign = true
+		}
+
+		// calls failing during the grace period are retried
+		if gc, ok := ctx.Value(callGraceKey{}).(*graceCall); ok && gc.drop(rs.Error) {
+			ign = true
+		}
+
+		// only the first completed request of hedged calls is the result of the call,
+		// with the latency from the start of the call
+		hedgeStart := rs.BeginTime
+		if a, ok := ctx.Value(callHedgeKey{}).(*hedgeAttempt); ok && !ign {
+			ign = !a.complete()
+			hedgeStart = a.call.start
+		}
+
 		if !ign {
-			duration := rs.EndTime.Sub(rs.BeginTime)
+			duration := rs.EndTime.Sub(hedgeStart)
+
+			st := statusCode(rs.Error).String()
+
+			// calls canceled once their maximum duration is reached are not failed by the server
+			callErr := rs.Error
+			if clientCanceled(ctx, callErr) {
+				st = StatusClientCanceled
+				callErr = nil
+			} else if cr, ok := ctx.Value(callResponseKey{}).(*callResponse); ok {
+				if c.classifier != nil {
+					callErr = classify(c.classifier, cr, callErr)
+				}
+
+				// the calls which already failed keep their error
+				if c.assertions != nil {
+					if err := c.assertions.check(statusCode(rs.Error), cr.response()); err != nil && callErr == nil {
+						callErr = err
+					}
+				}
+			}
+
+			label, _ := ctx.Value(callLabelKey{}).(string)
 
-			var st string
-			s, ok := status.FromError(rs.Error)
-			if ok {
-				st = s.Code().String()
+			var lag time.Duration
+			intended, paced := ctx.Value(callIntendedKey{}).(time.Time)
+			if paced && rs.BeginTime.After(intended) {
+				lag = rs.BeginTime.Sub(intended)
 			}
 
-			c.results <- &callResult{rs.Error, st, duration, rs.EndTime}
+			traceID, _ := ctx.Value(callTraceKey{}).(string)
+			worker, _ := ctx.Value(callWorkerKey{}).(string)
+			method, _ := ctx.Value(callMethodKey{}).(string)
+			tenant, _ := ctx.Value(callTenantKey{}).(string)
+
+			var sent, received, sentMsgs, receivedMsgs uint64
+			var header time.Duration
+			if cb, ok := ctx.Value(callBytesKey{}).(*callBytes); ok {
+				sent = atomic.LoadUint64(&cb.sent)
+				received = atomic.LoadUint64(&cb.received)
+				sentMsgs = atomic.LoadUint64(&cb.sentMsgs)
+				receivedMsgs = atomic.LoadUint64(&cb.receivedMsgs)
+
+				if at := atomic.LoadInt64(&cb.header); at > 0 {
+					header = time.Unix(0, at).Sub(hedgeStart)
+				}
+			}
+
+			var trailer metadata.MD
+			if c.trailers && callErr != nil {
+				trailer = rs.Trailer
+			}
+
+			// the pushed statistics are observed before the result so they are complete once the results are
+			c.push.observe(st, duration)
+			c.alerts.observe(st, duration)
+
+			c.results <- &callResult{callErr, st, duration, rs.EndTime, label, lag, paced, traceID, worker,
+				sent, received, sentMsgs, receivedMsgs, header, trailer, method, tenant}
+
+			c.metrics.observe(st, duration)
+
+			if cs, ok := ctx.Value(callStagesKey{}).(*callStages); ok {
+				c.stages.record(cs, duration)
+			}
 
 			if c.hasLog {
 				c.log.Debugw("Received RPC Stats",
@@ -60,6 +391,31 @@ func (c *statsHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
 	}
 }
 
+// handleConnRPC records the connection level statistics of the RPC
+func (c *statsHandler) handleConnRPC(rs stats.RPCStats) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	switch rs := rs.(type) {
+	case *stats.Begin:
+		c.calls++
+		c.activeStreams++
+		if c.activeStreams > c.maxStreams {
+			c.maxStreams = c.activeStreams
+		}
+	case *stats.End:
+		if c.activeStreams > 0 {
+			c.activeStreams--
+		}
+	case *stats.OutPayload:
+		c.bytesSent += uint64(rs.WireLength)
+		c.payloadSent += uint64(rs.Length)
+	case *stats.InPayload:
+		c.bytesReceived += uint64(rs.WireLength)
+		c.payloadReceived += uint64(rs.Length)
+	}
+}
+
 func (c *statsHandler) Ignore(val bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -67,7 +423,51 @@ func (c *statsHandler) Ignore(val bool) {
 	c.ignore = val
 }
 
+// IgnoreBefore ignores the calls started before the time
+func (c *statsHandler) IgnoreBefore(t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.ignoreBefore = t
+}
+
 // TagRPC implements per-RPC context management.
 func (c *statsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	ctx = context.WithValue(ctx, callBytesKey{}, &callBytes{})
+
+	if c.stages != nil {
+		ctx = context.WithValue(ctx, callStagesKey{}, &callStages{})
+	}
+
+	if c.classifier != nil || c.assertions != nil {
+		ctx = context.WithValue(ctx, callResponseKey{}, &callResponse{})
+	}
+
 	return ctx
 }
+
+// statusCode returns the canonical gRPC code for the error of the call,
+// unwrapping errors that wrap a gRPC status
+func statusCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Code()
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+
+	return codes.Unknown
+}
@@ -2,6 +2,8 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/bojand/ghz/internal/helloworld"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestStatsHandler(t *testing.T) {
@@ -31,10 +35,12 @@ func TestStatsHandler(t *testing.T) {
 		done <- true
 	}()
 
+	sh := &statsHandler{id: 1, results: rChan}
+
 	conn, err := grpc.Dial(
 		internal.TestLocalhost,
 		grpc.WithInsecure(),
-		grpc.WithStatsHandler(&statsHandler{results: rChan}))
+		grpc.WithStatsHandler(sh))
 
 	if err != nil {
 		assert.FailNow(t, err.Error())
@@ -58,4 +64,42 @@ func TestStatsHandler(t *testing.T) {
 	assert.Equal(t, 2, len(results))
 	assert.NotNil(t, results[0])
 	assert.NotNil(t, results[1])
+
+	cs := sh.connStats(time.Now())
+	assert.Equal(t, 1, cs.ID)
+	assert.Equal(t, uint64(2), cs.Calls)
+	assert.Equal(t, uint64(1), cs.MaxStreams)
+	assert.Equal(t, uint64(1), cs.Connects)
+	assert.NotZero(t, cs.BytesSent)
+	assert.NotZero(t, cs.BytesReceived)
+	assert.NotZero(t, cs.Lifetime)
+
+	assert.NoError(t, conn.Close())
+
+	// the lifetime stops growing once the connection is closed
+	assert.Eventually(t, func() bool {
+		lifetime := sh.connStats(time.Now()).Lifetime
+		return lifetime == sh.connStats(time.Now().Add(time.Hour)).Lifetime
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStatsHandler_statusCode(t *testing.T) {
+	var tests = []struct {
+		name     string
+		err      error
+		expected codes.Code
+	}{
+		{"nil", nil, codes.OK},
+		{"status", status.Error(codes.Unavailable, "unavailable"), codes.Unavailable},
+		{"wrapped status", fmt.Errorf("stream failed: %w", status.Error(codes.ResourceExhausted, "too many")), codes.ResourceExhausted},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"wrapped canceled", fmt.Errorf("send: %w", context.Canceled), codes.Canceled},
+		{"other", errors.New("boom"), codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, statusCode(tt.err))
+		})
+	}
 }
@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// maxAckMismatches is the number of mismatching streams kept as samples in the report
+const maxAckMismatches = 10
+
+// StreamAckStats holds the checks of the acknowledged message counts of client streaming calls,
+// where the response holds the number of messages the server received within the stream
+type StreamAckStats struct {
+	// Field is the acknowledged count field of the response
+	Field string `json:"field"`
+
+	// Streams is the number of checked streams, which are those closed with a response
+	Streams uint64 `json:"streams"`
+
+	// Matched is the number of streams whose acknowledged count is the number of sent messages
+	Matched uint64 `json:"matched"`
+
+	// Mismatched is the number of streams whose acknowledged count is not the number of sent messages
+	Mismatched uint64 `json:"mismatched"`
+
+	// Dropped is the number of sent messages which were not acknowledged, over all the streams
+	Dropped uint64 `json:"dropped"`
+
+	// Missing is the number of responses without the acknowledged count field
+	Missing uint64 `json:"missing,omitempty"`
+
+	// Mismatches are samples of the mismatching streams
+	Mismatches []StreamAckMismatch `json:"mismatches,omitempty"`
+}
+
+// StreamAckMismatch is a stream whose acknowledged count is not the number of sent messages
+type StreamAckMismatch struct {
+	Sent  uint64 `json:"sent"`
+	Acked uint64 `json:"acked"`
+}
+
+// ackTracker checks the acknowledged message counts of all the streams of the run
+type ackTracker struct {
+	field string
+
+	lock       sync.Mutex
+	streams    uint64
+	matched    uint64
+	mismatched uint64
+	dropped    uint64
+	missing    uint64
+	mismatches []StreamAckMismatch
+}
+
+func newAckTracker(mtd *desc.MethodDescriptor, field string) (*ackTracker, error) {
+	if !mtd.IsClientStreaming() || mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("stream ack field is only supported for client streaming calls")
+	}
+
+	fd, err := findFieldPath(mtd.GetOutputType(), field)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isIntegerField(fd) {
+		return nil, fmt.Errorf("field %q of message %s must be an integer", field, mtd.GetOutputType().GetFullyQualifiedName())
+	}
+
+	return &ackTracker{field: field}, nil
+}
+
+// check compares the acknowledged count of the response of a stream to the number of sent messages
+func (t *ackTracker) check(sent uint64, res proto.Message) {
+	acked, ok := ackedCount(res, t.field)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !ok {
+		t.missing++
+		return
+	}
+
+	t.streams++
+
+	if acked == sent {
+		t.matched++
+		return
+	}
+
+	t.mismatched++
+	if acked < sent {
+		t.dropped += sent - acked
+	}
+
+	if len(t.mismatches) < maxAckMismatches {
+		t.mismatches = append(t.mismatches, StreamAckMismatch{Sent: sent, Acked: acked})
+	}
+}
+
+func (t *ackTracker) stats() *StreamAckStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return &StreamAckStats{
+		Field:      t.field,
+		Streams:    t.streams,
+		Matched:    t.matched,
+		Mismatched: t.mismatched,
+		Dropped:    t.dropped,
+		Missing:    t.missing,
+		Mismatches: append([]StreamAckMismatch(nil), t.mismatches...),
+	}
+}
+
+// ackedCount returns the value of the integer field path of the response
+func ackedCount(res proto.Message, path string) (uint64, bool) {
+	if res == nil {
+		return 0, false
+	}
+
+	dm, err := dynamic.AsDynamicMessage(res)
+	if err != nil {
+		return 0, false
+	}
+
+	_, v, ok := lookupField(dm, path)
+	if !ok {
+		return 0, false
+	}
+
+	switch n := v.(type) {
+	case int32:
+		return uint64(n), n >= 0
+	case int64:
+		return uint64(n), n >= 0
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// isIntegerField returns whether the field is of an integer type
+func isIntegerField(fd *desc.FieldDescriptor) bool {
+	switch fd.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32, descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bojand/ghz/internal/echo"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestAckTracker(t *testing.T) {
+	mtd := echoMethod(t, "EchoClientStream")
+
+	_, err := newAckTracker(echoMethod(t, "EchoBidi"), "count")
+	assert.EqualError(t, err, "stream ack field is only supported for client streaming calls")
+
+	_, err = newAckTracker(mtd, "message")
+	assert.EqualError(t, err, `field "message" of message echo.EchoReply must be an integer`)
+
+	_, err = newAckTracker(mtd, "offset")
+	assert.EqualError(t, err, `field "offset" not found in message echo.EchoReply`)
+
+	tr, err := newAckTracker(mtd, "count")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	reply := func(count int64) *dynamic.Message {
+		res := dynamic.NewMessage(mtd.GetOutputType())
+		res.SetFieldByName("count", count)
+		return res
+	}
+
+	tr.check(10, reply(10))
+	tr.check(10, reply(8))
+	tr.check(5, reply(6))
+	tr.check(5, nil)
+
+	assert.Equal(t, &StreamAckStats{
+		Field:      "count",
+		Streams:    3,
+		Matched:    1,
+		Mismatched: 2,
+		Dropped:    2,
+		Missing:    1,
+		Mismatches: []StreamAckMismatch{{Sent: 10, Acked: 8}, {Sent: 5, Acked: 6}},
+	}, tr.stats())
+}
+
+func TestRunStreamAcks(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	svc, err := echo.New(echo.Options{})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	s := grpc.NewServer()
+	if err := svc.Register(s); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	reflection.Register(s)
+
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	defer s.Stop()
+
+	report, err := Run(
+		"echo.Echo.EchoClientStream",
+		lis.Addr().String(),
+		WithTotalRequests(6),
+		WithConcurrency(2),
+		WithDataFromJSON(`[{"message":"a"},{"message":"b"},{"message":"c"}]`),
+		WithStreamAckField("count"),
+		WithInsecure(true),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]int{"OK": 6}, report.StatusCodeDist)
+	assert.Equal(t, &StreamAckStats{
+		Field:   "count",
+		Streams: 6,
+		Matched: 6,
+	}, report.StreamAcks)
+
+	_, err = Run(
+		"echo.Echo.Echo",
+		lis.Addr().String(),
+		WithDataFromJSON(`{"message":"a"}`),
+		WithStreamAckField("count"),
+		WithInsecure(true),
+	)
+
+	assert.EqualError(t, err, "stream ack field is only supported for client streaming calls")
+}
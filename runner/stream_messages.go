@@ -0,0 +1,70 @@
+package runner
+
+import "time"
+
+// StreamMessages holds the number of messages sent and received by the streams of a
+// streaming call, that is of client, server or bidi streaming methods
+type StreamMessages struct {
+	// Streams is the number of streams, that is calls
+	Streams uint64 `json:"streams"`
+
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+
+	// The average and maximum number of messages of each stream
+	SentPerStream     float64 `json:"sentPerStream"`
+	ReceivedPerStream float64 `json:"receivedPerStream"`
+	MaxSent           uint64  `json:"maxSent"`
+	MaxReceived       uint64  `json:"maxReceived"`
+
+	// SendRate and ReceiveRate are the number of messages per second of the run
+	SendRate    float64 `json:"sendRate"`
+	ReceiveRate float64 `json:"receiveRate"`
+}
+
+// streamMessages counts the messages of the streams of streaming calls
+type streamMessages struct {
+	streams     uint64
+	sent        uint64
+	received    uint64
+	maxSent     uint64
+	maxReceived uint64
+}
+
+// add records the messages sent and received by a stream
+func (m *streamMessages) add(sent, received uint64) {
+	m.streams++
+	m.sent += sent
+	m.received += received
+
+	if sent > m.maxSent {
+		m.maxSent = sent
+	}
+
+	if received > m.maxReceived {
+		m.maxReceived = received
+	}
+}
+
+// stats returns the message statistics of the streams over the total duration of the run
+func (m *streamMessages) stats(total time.Duration) *StreamMessages {
+	s := &StreamMessages{
+		Streams:     m.streams,
+		Sent:        m.sent,
+		Received:    m.received,
+		MaxSent:     m.maxSent,
+		MaxReceived: m.maxReceived,
+	}
+
+	if m.streams > 0 {
+		s.SentPerStream = float64(m.sent) / float64(m.streams)
+		s.ReceivedPerStream = float64(m.received) / float64(m.streams)
+	}
+
+	if total > 0 {
+		s.SendRate = float64(m.sent) / total.Seconds()
+		s.ReceiveRate = float64(m.received) / total.Seconds()
+	}
+
+	return s
+}
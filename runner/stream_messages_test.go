@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMessages(t *testing.T) {
+	m := &streamMessages{}
+	m.add(1, 4)
+	m.add(3, 2)
+	m.add(2, 0)
+
+	assert.Equal(t, &StreamMessages{
+		Streams:           3,
+		Sent:              6,
+		Received:          6,
+		SentPerStream:     2,
+		ReceivedPerStream: 2,
+		MaxSent:           3,
+		MaxReceived:       4,
+		SendRate:          3,
+		ReceiveRate:       3,
+	}, m.stats(2*time.Second))
+
+	assert.Equal(t, &StreamMessages{}, (&streamMessages{}).stats(0))
+}
@@ -0,0 +1,277 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// StreamStats holds the message statistics of bidi streaming calls
+// where a correlation field is used to match the responses to the sent messages
+type StreamStats struct {
+	// Field is the correlation field
+	Field string `json:"field"`
+
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+
+	// Matched is the number of responses matching a sent message
+	Matched uint64 `json:"matched"`
+
+	// Unanswered is the number of sent messages without a matching response
+	Unanswered uint64 `json:"unanswered"`
+
+	// Pushed is the number of server initiated messages not matching any sent message
+	Pushed uint64 `json:"pushed"`
+
+	// PushRate is the number of server initiated messages per second
+	PushRate float64 `json:"pushRate"`
+
+	// The latency from sending a message to receiving the matching response
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// streamTracker gathers the message statistics of all the streams of the run
+type streamTracker struct {
+	field    string
+	reqField string // field path in the sent messages
+	resField string // field path in the received messages
+
+	lock       sync.Mutex
+	sent       uint64
+	received   uint64
+	unanswered uint64
+	pushed     uint64
+	latencies  []float64
+}
+
+func newStreamTracker(mtd *desc.MethodDescriptor, field string) (*streamTracker, error) {
+	if !mtd.IsClientStreaming() || !mtd.IsServerStreaming() {
+		return nil, fmt.Errorf("stream correlation field is only supported for bidi streaming calls")
+	}
+
+	t := &streamTracker{field: field, reqField: field, resField: field}
+	if i := strings.Index(field, ":"); i >= 0 {
+		t.reqField = strings.TrimSpace(field[:i])
+		t.resField = strings.TrimSpace(field[i+1:])
+	}
+
+	if err := checkFieldPath(mtd.GetInputType(), t.reqField); err != nil {
+		return nil, err
+	}
+
+	if err := checkFieldPath(mtd.GetOutputType(), t.resField); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// newStream returns a correlator for the messages of a single stream
+func (t *streamTracker) newStream() *streamCorrelator {
+	return &streamCorrelator{t: t, pending: make(map[string][]time.Time)}
+}
+
+func (t *streamTracker) stats(total time.Duration) *StreamStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := &StreamStats{
+		Field:      t.field,
+		Sent:       t.sent,
+		Received:   t.received,
+		Matched:    uint64(len(t.latencies)),
+		Unanswered: t.unanswered,
+		Pushed:     t.pushed,
+	}
+
+	if total > 0 {
+		s.PushRate = float64(t.pushed) / total.Seconds()
+	}
+
+	if len(t.latencies) > 0 {
+		lats := append([]float64(nil), t.latencies...)
+		sort.Float64s(lats)
+
+		var sum float64
+		for _, l := range lats {
+			sum += l
+		}
+
+		s.Average = time.Duration(sum / float64(len(lats)) * float64(time.Second))
+		s.Fastest = time.Duration(lats[0] * float64(time.Second))
+		s.Slowest = time.Duration(lats[len(lats)-1] * float64(time.Second))
+		s.LatencyDistribution = latencies(lats)
+	}
+
+	return s
+}
+
+// streamCorrelator matches the received messages of a stream to the sent ones
+// using the value of the correlation field
+type streamCorrelator struct {
+	t *streamTracker
+
+	lock    sync.Mutex
+	pending map[string][]time.Time
+}
+
+// sent records a message about to be sent
+func (c *streamCorrelator) sent(msg *dynamic.Message) {
+	key, _ := fieldValue(msg, c.t.reqField)
+
+	c.lock.Lock()
+	if key != "" {
+		c.pending[key] = append(c.pending[key], time.Now())
+	}
+	c.lock.Unlock()
+
+	c.t.lock.Lock()
+	c.t.sent++
+	c.t.lock.Unlock()
+}
+
+// received records a received message, either as a response to a
+// pending sent message or as a server initiated message
+func (c *streamCorrelator) received(msg proto.Message) {
+	var key string
+	if dm, err := dynamic.AsDynamicMessage(msg); err == nil {
+		key, _ = fieldValue(dm, c.t.resField)
+	}
+
+	var latency time.Duration
+	matched := false
+
+	c.lock.Lock()
+	if times := c.pending[key]; key != "" && len(times) > 0 {
+		latency = time.Since(times[0])
+		matched = true
+
+		if len(times) == 1 {
+			delete(c.pending, key)
+		} else {
+			c.pending[key] = times[1:]
+		}
+	}
+	c.lock.Unlock()
+
+	c.t.lock.Lock()
+	defer c.t.lock.Unlock()
+
+	c.t.received++
+	if !matched {
+		c.t.pushed++
+	} else if len(c.t.latencies) < maxResult {
+		c.t.latencies = append(c.t.latencies, latency.Seconds())
+	}
+}
+
+// close records the sent messages left without a response
+func (c *streamCorrelator) close() {
+	c.lock.Lock()
+	var n uint64
+	for _, times := range c.pending {
+		n += uint64(len(times))
+	}
+	c.pending = make(map[string][]time.Time)
+	c.lock.Unlock()
+
+	c.t.lock.Lock()
+	c.t.unanswered += n
+	c.t.lock.Unlock()
+}
+
+// checkFieldPath checks that the dot separated field path exists in the message
+func checkFieldPath(md *desc.MessageDescriptor, path string) error {
+	_, err := findFieldPath(md, path)
+	return err
+}
+
+// findFieldPath returns the descriptor of the scalar field of the dot separated field path of the message
+func findFieldPath(md *desc.MessageDescriptor, path string) (*desc.FieldDescriptor, error) {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(name)
+		}
+
+		if fd == nil {
+			return nil, fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+		}
+
+		if i < len(parts)-1 {
+			if fd.GetMessageType() == nil || fd.IsRepeated() {
+				return nil, fmt.Errorf("field %q of message %s is not a message", name, md.GetFullyQualifiedName())
+			}
+
+			md = fd.GetMessageType()
+		} else if fd.GetMessageType() != nil || fd.IsRepeated() {
+			return nil, fmt.Errorf("field %q of message %s must be a scalar", path, md.GetFullyQualifiedName())
+		} else {
+			return fd, nil
+		}
+	}
+
+	return nil, fmt.Errorf("field %q not found in message %s", path, md.GetFullyQualifiedName())
+}
+
+// fieldValue returns the value of the dot separated field path of the message as a string
+func fieldValue(msg *dynamic.Message, path string) (string, bool) {
+	_, v, ok := lookupField(msg, path)
+	if !ok {
+		return "", false
+	}
+
+	return fmt.Sprint(v), true
+}
+
+// lookupField returns the descriptor and the value of the dot separated field path of the message
+func lookupField(msg *dynamic.Message, path string) (*desc.FieldDescriptor, interface{}, bool) {
+	parts := strings.Split(path, ".")
+	for i, name := range parts {
+		md := msg.GetMessageDescriptor()
+
+		fd := md.FindFieldByName(name)
+		if fd == nil {
+			fd = md.FindFieldByJSONName(name)
+		}
+
+		if fd == nil {
+			return nil, nil, false
+		}
+
+		v := msg.GetField(fd)
+		if i == len(parts)-1 {
+			return fd, v, true
+		}
+
+		pm, ok := v.(proto.Message)
+		if !ok {
+			return nil, nil, false
+		}
+
+		if msg, ok = pm.(*dynamic.Message); !ok {
+			var err error
+			if msg, err = dynamic.AsDynamicMessage(pm); err != nil {
+				return nil, nil, false
+			}
+		}
+
+		// unset message field
+		if msg == nil {
+			return nil, nil, false
+		}
+	}
+
+	return nil, nil, false
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/protodesc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamTracker(t *testing.T) {
+	mtdBidi, err := protodesc.GetMethodDescFromProto(
+		"helloworld.Greeter.SayHelloBidi",
+		"../testdata/greeter.proto",
+		nil)
+	assert.NoError(t, err)
+
+	mtdUnary, err := protodesc.GetMethodDescFromProto(
+		"helloworld.Greeter.SayHello",
+		"../testdata/greeter.proto",
+		nil)
+	assert.NoError(t, err)
+
+	t.Run("validates the fields", func(t *testing.T) {
+		_, err := newStreamTracker(mtdUnary, "name")
+		assert.Error(t, err)
+
+		_, err = newStreamTracker(mtdBidi, "name")
+		assert.Error(t, err)
+
+		_, err = newStreamTracker(mtdBidi, "name:foo")
+		assert.Error(t, err)
+
+		tr, err := newStreamTracker(mtdBidi, "name : message")
+		assert.NoError(t, err)
+		assert.Equal(t, "name", tr.reqField)
+		assert.Equal(t, "message", tr.resField)
+	})
+
+	t.Run("matches responses", func(t *testing.T) {
+		tr, err := newStreamTracker(mtdBidi, "name:message")
+		assert.NoError(t, err)
+
+		req := func(name string) *dynamic.Message {
+			m := dynamic.NewMessage(mtdBidi.GetInputType())
+			m.SetFieldByName("name", name)
+			return m
+		}
+
+		res := func(msg string) *dynamic.Message {
+			m := dynamic.NewMessage(mtdBidi.GetOutputType())
+			m.SetFieldByName("message", msg)
+			return m
+		}
+
+		sc := tr.newStream()
+		sc.sent(req("1"))
+		sc.sent(req("2"))
+		sc.sent(req("2"))
+		sc.sent(req(""))
+		time.Sleep(time.Millisecond)
+
+		sc.received(res("2"))
+		sc.received(res("push"))
+		sc.received(res("1"))
+		sc.received(res(""))
+		sc.close()
+
+		s := tr.stats(2 * time.Second)
+		assert.Equal(t, "name:message", s.Field)
+		assert.Equal(t, uint64(4), s.Sent)
+		assert.Equal(t, uint64(4), s.Received)
+		assert.Equal(t, uint64(2), s.Matched)
+		assert.Equal(t, uint64(1), s.Unanswered)
+		assert.Equal(t, uint64(2), s.Pushed)
+		assert.Equal(t, 1.0, s.PushRate)
+		assert.True(t, s.Fastest >= time.Millisecond)
+		assert.True(t, s.Slowest >= s.Fastest)
+		assert.Len(t, s.LatencyDistribution, 7)
+	})
+}
+
+func TestStreamStats_fieldValue(t *testing.T) {
+	mtd, err := protodesc.GetMethodDescFromProto(
+		"data.DataTestService.TestCallTwo",
+		"../testdata/data.proto",
+		nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, checkFieldPath(mtd.GetInputType(), "nested_prop.param_one"))
+	assert.NoError(t, checkFieldPath(mtd.GetInputType(), "nestedProp.paramOne"))
+	assert.Error(t, checkFieldPath(mtd.GetInputType(), "nested_prop"))
+	assert.Error(t, checkFieldPath(mtd.GetInputType(), "nested_prop.param_one.foo"))
+
+	nested := dynamic.NewMessage(mtd.GetInputType().FindFieldByName("nested_prop").GetMessageType())
+	nested.SetFieldByName("param_one", "abc")
+
+	msg := dynamic.NewMessage(mtd.GetInputType())
+	msg.SetFieldByName("nested_prop", nested)
+
+	v, ok := fieldValue(msg, "nested_prop.param_one")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", v)
+
+	_, ok = fieldValue(msg, "nested_prop.foo")
+	assert.False(t, ok)
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// TargetOverride overrides the authority, the TLS server name and the metadata of the connections
+// to a target, such as the backends of a multi-tenant gateway selected by the server name
+type TargetOverride struct {
+	Target     string            `json:"target" toml:"target" yaml:"target"`
+	Authority  string            `json:"authority,omitempty" toml:"authority,omitempty" yaml:"authority,omitempty"`
+	ServerName string            `json:"cname,omitempty" toml:"cname,omitempty" yaml:"cname,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// ParseTargetOverride parses a target override in the form of
+// <target>,authority=<authority>,cname=<name>,metadata.<key>=<value>, each part but the target being optional
+func ParseTargetOverride(s string) (TargetOverride, error) {
+	parts := strings.Split(s, ",")
+
+	o := TargetOverride{Target: strings.TrimSpace(parts[0])}
+	if o.Target == "" || len(parts) == 1 {
+		return TargetOverride{}, fmt.Errorf("invalid target override %q: expected <target>,<key>=<value>", s)
+	}
+
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return TargetOverride{}, fmt.Errorf("invalid target override %q: expected <target>,<key>=<value>", s)
+		}
+
+		value := strings.TrimSpace(kv[1])
+
+		switch {
+		case key == "authority":
+			o.Authority = value
+		case key == "cname":
+			o.ServerName = value
+		case strings.HasPrefix(key, "metadata.") && len(key) > len("metadata."):
+			if o.Metadata == nil {
+				o.Metadata = make(map[string]string)
+			}
+
+			o.Metadata[strings.TrimPrefix(key, "metadata.")] = value
+		default:
+			return TargetOverride{}, fmt.Errorf("invalid target override %q: unknown key %q", s, key)
+		}
+	}
+
+	return o, nil
+}
+
+// isTarget returns whether the target is the host or one of the additional targets
+func (c *RunConfig) isTarget(target string) bool {
+	for _, t := range c.allTargets() {
+		if t == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targetOverride returns the override of the target, if any
+func (c *RunConfig) targetOverride(target string) *TargetOverride {
+	for i := range c.targetOverrides {
+		if c.targetOverrides[i].Target == target {
+			return &c.targetOverrides[i]
+		}
+	}
+
+	return nil
+}
+
+// dialOptions returns the dial options of the override, replacing the authority and the server name
+// of the run and adding the metadata to the calls of the connection
+func (o *TargetOverride) dialOptions(c *RunConfig) []grpc.DialOption {
+	var opts []grpc.DialOption
+
+	if o.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(o.Authority))
+	}
+
+	if o.ServerName != "" && !c.insecure && c.credsBundle == nil {
+		tlsConf := c.tlsConfig.Clone()
+		tlsConf.ServerName = o.ServerName
+
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+	}
+
+	if len(o.Metadata) > 0 {
+		pairs := make([]string, 0, 2*len(o.Metadata))
+		for k, v := range o.Metadata {
+			pairs = append(pairs, strings.ToLower(k), v)
+		}
+
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{},
+				cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				return invoker(metadata.AppendToOutgoingContext(ctx, pairs...), method, req, reply, cc, opts...)
+			}),
+			grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+				method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(metadata.AppendToOutgoingContext(ctx, pairs...), desc, cc, method, opts...)
+			}))
+	}
+
+	return opts
+}
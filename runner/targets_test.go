@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTargetOverride(t *testing.T) {
+	o, err := ParseTargetOverride("10.0.0.13:50051, authority=b.example.com,cname=b.example.com,metadata.x-tenant=b")
+	assert.NoError(t, err)
+	assert.Equal(t, TargetOverride{
+		Target:     "10.0.0.13:50051",
+		Authority:  "b.example.com",
+		ServerName: "b.example.com",
+		Metadata:   map[string]string{"x-tenant": "b"},
+	}, o)
+
+	for _, s := range []string{"", "10.0.0.13:50051", ",cname=b", "10.0.0.13:50051,cname"} {
+		_, err = ParseTargetOverride(s)
+		assert.EqualError(t, err, `invalid target override "`+s+`": expected <target>,<key>=<value>`)
+	}
+
+	_, err = ParseTargetOverride("10.0.0.13:50051,metadata.=b")
+	assert.EqualError(t, err, `invalid target override "10.0.0.13:50051,metadata.=b": unknown key "metadata."`)
+}
+
+func TestTargetOverride_dialOptions(t *testing.T) {
+	c, err := NewConfig("call", "localhost:50050",
+		WithProtoFile("testdata/data.proto", []string{}),
+		WithConnections(2),
+		WithTargets("localhost:50051"),
+		WithTargetOverrides(
+			TargetOverride{Target: "localhost:50051", Authority: "b.example.com", ServerName: "b.example.com",
+				Metadata: map[string]string{"X-Tenant": "b"}},
+		),
+	)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Nil(t, c.targetOverride("localhost:50050"))
+
+	o := c.targetOverride("localhost:50051")
+	if assert.NotNil(t, o) {
+		// the authority, the credentials and the interceptors of the metadata
+		assert.Len(t, o.dialOptions(c), 4)
+	}
+
+	// the server name does not apply without TLS
+	c.insecure = true
+	assert.Len(t, (&TargetOverride{ServerName: "b.example.com"}).dialOptions(c), 0)
+
+	_, err = NewConfig("call", "localhost:50050",
+		WithProtoFile("testdata/data.proto", []string{}),
+		WithTargetOverrides(TargetOverride{Target: "localhost:50051", Authority: "b.example.com"}),
+	)
+
+	assert.EqualError(t, err, "target override of localhost:50051, which is not the host or a target")
+
+	_, err = NewConfig("call", "localhost:50050",
+		WithProtoFile("testdata/data.proto", []string{}),
+		WithTargetOverrides(
+			TargetOverride{Target: "localhost:50050", Authority: "a.example.com"},
+			TargetOverride{Target: "localhost:50050", Authority: "b.example.com"},
+		),
+	)
+
+	assert.EqualError(t, err, "duplicate target override of localhost:50050")
+}
@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Tenant is an identity the calls are made under in the multi-tenant fairness mode,
+// set by the metadata of the tenant or its token sent as the bearer authorization
+type Tenant struct {
+	Name     string            `json:"name" toml:"name" yaml:"name"`
+	Metadata map[string]string `json:"metadata,omitempty" toml:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Token    string            `json:"token,omitempty" toml:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// ParseTenant parses a tenant in the form of <name>=<token>
+func ParseTenant(s string) (Tenant, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return Tenant{}, fmt.Errorf("invalid tenant %q: expected <name>=<token>", s)
+	}
+
+	return Tenant{Name: strings.TrimSpace(parts[0]), Token: strings.TrimSpace(parts[1])}, nil
+}
+
+// TenantStats holds the statistics of the calls made under a tenant
+type TenantStats struct {
+	Tenant string `json:"tenant"`
+
+	Count     uint64  `json:"count"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+
+	// Rps is the rate of the calls of the tenant and Goodput that of its successful calls
+	Rps     float64 `json:"rps"`
+	Goodput float64 `json:"goodput"`
+
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+
+	StatusCodeDist map[string]int `json:"statusCodeDistribution"`
+	ErrorDist      map[string]int `json:"errorDistribution,omitempty"`
+}
+
+// TenantFairness holds the statistics of each tenant in the multi-tenant fairness mode, along with
+// the Jain's fairness indexes of the tenants, which range from 1 / tenants when a single tenant gets
+// all of the service up to 1 when all the tenants get the same service
+type TenantFairness struct {
+	Tenants []TenantStats `json:"tenants"`
+
+	// Fairness is the fairness index of the goodput of the tenants
+	Fairness float64 `json:"fairness"`
+
+	// LatencyFairness is the fairness index of the average latency of the tenants
+	LatencyFairness float64 `json:"latencyFairness"`
+}
+
+// tenants assigns the calls to the tenants in turn, so they are offered the same rate,
+// and gathers the results of each tenant
+type tenants struct {
+	tenants     []Tenant
+	countErrors bool
+
+	lock    sync.Mutex
+	buckets map[string]*tenantBucket
+}
+
+// tenantBucket holds the results of a tenant
+type tenantBucket struct {
+	count          uint64
+	errors         uint64
+	totalLatencies time.Duration
+	raw            *rawRecorder
+	statusCodeDist map[string]int
+	errorDist      map[string]int
+}
+
+func newTenants(c *RunConfig) *tenants {
+	if len(c.tenants) == 0 {
+		return nil
+	}
+
+	t := &tenants{tenants: c.tenants, countErrors: c.countErrors, buckets: make(map[string]*tenantBucket)}
+	for _, tenant := range c.tenants {
+		t.buckets[tenant.Name] = &tenantBucket{
+			raw:            newRawRecorder(true),
+			statusCodeDist: make(map[string]int),
+			errorDist:      make(map[string]int),
+		}
+	}
+
+	return t
+}
+
+// pick returns the tenant of the request
+func (t *tenants) pick(reqNum uint64) *Tenant {
+	return &t.tenants[reqNum%uint64(len(t.tenants))]
+}
+
+// metadata returns the request metadata with the metadata and the token of the tenant,
+// which take precedence over the metadata of the request
+func (t *Tenant) metadata(reqMD *metadata.MD) *metadata.MD {
+	// the request metadata may be shared between calls
+	md := metadata.MD{}
+	if reqMD != nil {
+		md = reqMD.Copy()
+	}
+
+	for k, v := range t.Metadata {
+		md.Set(k, v)
+	}
+
+	if t.Token != "" {
+		md.Set("authorization", "Bearer "+t.Token)
+	}
+
+	return &md
+}
+
+// add adds the result to the tenant it was made under, if any
+func (t *tenants) add(res *callResult) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	b, ok := t.buckets[res.tenant]
+	if !ok {
+		return
+	}
+
+	b.count++
+	b.totalLatencies += res.duration
+	b.statusCodeDist[res.status]++
+
+	if res.err != nil {
+		b.errors++
+		b.errorDist[res.err.Error()]++
+	}
+
+	if res.err == nil || t.countErrors {
+		b.raw.record(res.duration)
+	}
+}
+
+// fairness returns the statistics of the tenants over the run of the total duration
+func (t *tenants) fairness(total time.Duration) *TenantFairness {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	f := &TenantFairness{Tenants: make([]TenantStats, 0, len(t.tenants))}
+
+	goodputs := make([]float64, 0, len(t.tenants))
+	averages := make([]float64, 0, len(t.tenants))
+
+	for _, tenant := range t.tenants {
+		b := t.buckets[tenant.Name]
+
+		s := TenantStats{
+			Tenant:         tenant.Name,
+			Count:          b.count,
+			Errors:         b.errors,
+			StatusCodeDist: b.statusCodeDist,
+		}
+
+		if len(b.errorDist) > 0 {
+			s.ErrorDist = b.errorDist
+		}
+
+		if b.count > 0 {
+			s.ErrorRate = float64(b.errors) / float64(b.count)
+			s.Average = b.totalLatencies / time.Duration(b.count)
+		}
+
+		if total > 0 {
+			s.Rps = float64(b.count) / total.Seconds()
+			s.Goodput = float64(b.count-b.errors) / total.Seconds()
+		}
+
+		if h := b.raw.histogram(); h.Count > 0 {
+			s.Fastest = h.Min
+			s.Slowest = h.Max
+			s.LatencyDistribution = h.latencies()
+		}
+
+		f.Tenants = append(f.Tenants, s)
+		goodputs = append(goodputs, s.Goodput)
+		averages = append(averages, s.Average.Seconds())
+	}
+
+	f.Fairness = jainIndex(goodputs)
+	f.LatencyFairness = jainIndex(averages)
+
+	return f
+}
+
+// jainIndex returns the Jain's fairness index of the values, (sum x)^2 / (n * sum x^2),
+// which is 1 if the values are all 0
+func jainIndex(values []float64) float64 {
+	var sum, squares float64
+	for _, v := range values {
+		sum += v
+		squares += v * v
+	}
+
+	if squares == 0 {
+		return 1
+	}
+
+	return sum * sum / (float64(len(values)) * squares)
+}
+
+// tenantNames returns the names of the tenants, leaving out their tokens
+func tenantNames(tenants []Tenant) []string {
+	var names []string
+	for _, t := range tenants {
+		names = append(names, t.Name)
+	}
+
+	return names
+}
+
+// checkTenants checks the names of the tenants are set and distinct
+func checkTenants(tenants []Tenant) error {
+	if len(tenants) == 1 {
+		return errors.New("the fairness mode requires at least 2 tenants")
+	}
+
+	names := make([]string, 0, len(tenants))
+	for _, t := range tenants {
+		if strings.TrimSpace(t.Name) == "" {
+			return errors.New("tenant name required")
+		}
+
+		names = append(names, t.Name)
+	}
+
+	sort.Strings(names)
+	for i := 1; i < len(names); i++ {
+		if names[i] == names[i-1] {
+			return fmt.Errorf("duplicate tenant %q", names[i])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/bojand/ghz/internal/helloworld"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseTenant(t *testing.T) {
+	tenant, err := ParseTenant("acme=token1")
+	assert.NoError(t, err)
+	assert.Equal(t, Tenant{Name: "acme", Token: "token1"}, tenant)
+
+	tenant, err = ParseTenant(" globex = a=b ")
+	assert.NoError(t, err)
+	assert.Equal(t, Tenant{Name: "globex", Token: "a=b"}, tenant)
+
+	for _, s := range []string{"", "acme", "acme=", "=token1"} {
+		_, err = ParseTenant(s)
+		assert.EqualError(t, err, `invalid tenant "`+s+`": expected <name>=<token>`)
+	}
+}
+
+func TestCheckTenants(t *testing.T) {
+	assert.NoError(t, checkTenants([]Tenant{{Name: "acme"}, {Name: "globex"}}))
+	assert.EqualError(t, checkTenants([]Tenant{{Name: "acme"}}), "the fairness mode requires at least 2 tenants")
+	assert.EqualError(t, checkTenants([]Tenant{{Name: "acme"}, {Name: " "}}), "tenant name required")
+	assert.EqualError(t, checkTenants([]Tenant{{Name: "acme"}, {Name: "globex"}, {Name: "acme"}}), `duplicate tenant "acme"`)
+}
+
+func TestJainIndex(t *testing.T) {
+	assert.Equal(t, 1.0, jainIndex([]float64{0, 0}))
+	assert.Equal(t, 1.0, jainIndex([]float64{10, 10, 10}))
+	assert.Equal(t, 0.5, jainIndex([]float64{10, 0}))
+	assert.InDelta(t, 0.9, jainIndex([]float64{10, 5}), 0.0001)
+}
+
+func TestTenant_metadata(t *testing.T) {
+	reqMD := metadata.Pairs("authorization", "Bearer shared", "x-run", "1")
+
+	tenant := &Tenant{Name: "acme", Metadata: map[string]string{"x-tenant": "acme"}, Token: "token1"}
+	md := tenant.metadata(&reqMD)
+
+	assert.Equal(t, []string{"Bearer token1"}, md.Get("authorization"))
+	assert.Equal(t, []string{"acme"}, md.Get("x-tenant"))
+	assert.Equal(t, []string{"1"}, md.Get("x-run"))
+
+	// the request metadata is left as is
+	assert.Equal(t, []string{"Bearer shared"}, reqMD.Get("authorization"))
+	assert.Empty(t, reqMD.Get("x-tenant"))
+
+	md = (&Tenant{Name: "globex"}).metadata(nil)
+	assert.Empty(t, *md)
+}
+
+func TestTenants_fairness(t *testing.T) {
+	assert.Nil(t, newTenants(&RunConfig{}))
+
+	tr := newTenants(&RunConfig{tenants: []Tenant{{Name: "acme"}, {Name: "globex"}}})
+
+	assert.Equal(t, "acme", tr.pick(0).Name)
+	assert.Equal(t, "globex", tr.pick(1).Name)
+	assert.Equal(t, "acme", tr.pick(2).Name)
+
+	for i := 0; i < 4; i++ {
+		tr.add(&callResult{tenant: "acme", status: "OK", duration: 10 * time.Millisecond})
+	}
+
+	tr.add(&callResult{tenant: "globex", status: "OK", duration: 20 * time.Millisecond})
+	tr.add(&callResult{tenant: "globex", status: "OK", duration: 40 * time.Millisecond})
+	tr.add(&callResult{tenant: "globex", status: "Unavailable", err: errors.New("unavailable"), duration: 30 * time.Millisecond})
+	tr.add(&callResult{tenant: "globex", status: "Unavailable", err: errors.New("unavailable"), duration: 30 * time.Millisecond})
+
+	// the results of the calls made under no tenant are left out
+	tr.add(&callResult{status: "OK", duration: time.Millisecond})
+
+	f := tr.fairness(2 * time.Second)
+	assert.Len(t, f.Tenants, 2)
+
+	acme := f.Tenants[0]
+	assert.Equal(t, "acme", acme.Tenant)
+	assert.Equal(t, uint64(4), acme.Count)
+	assert.Equal(t, uint64(0), acme.Errors)
+	assert.Equal(t, 2.0, acme.Rps)
+	assert.Equal(t, 2.0, acme.Goodput)
+	assert.Equal(t, 10*time.Millisecond, acme.Average)
+	assert.Equal(t, map[string]int{"OK": 4}, acme.StatusCodeDist)
+	assert.Nil(t, acme.ErrorDist)
+
+	globex := f.Tenants[1]
+	assert.Equal(t, "globex", globex.Tenant)
+	assert.Equal(t, uint64(4), globex.Count)
+	assert.Equal(t, uint64(2), globex.Errors)
+	assert.Equal(t, 0.5, globex.ErrorRate)
+	assert.Equal(t, 1.0, globex.Goodput)
+	assert.Equal(t, 30*time.Millisecond, globex.Average)
+	assert.Equal(t, 20*time.Millisecond, globex.Fastest)
+	assert.Equal(t, 40*time.Millisecond, globex.Slowest)
+	assert.Equal(t, map[string]int{"unavailable": 2}, globex.ErrorDist)
+
+	assert.InDelta(t, 0.9, f.Fairness, 0.0001)
+	assert.InDelta(t, 0.8, f.LatencyFairness, 0.0001)
+}
+
+func TestRunTenants(t *testing.T) {
+	gs, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	t.Run("invalid tenants", func(t *testing.T) {
+		_, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTenants(Tenant{Name: "acme", Token: "token1"}),
+			WithInsecure(true),
+		)
+
+		assert.EqualError(t, err, "the fairness mode requires at least 2 tenants")
+	})
+
+	t.Run("calls made under the tenants in turn", func(t *testing.T) {
+		gs.ResetCounters()
+
+		report, err := Run(
+			"helloworld.Greeter.SayHello",
+			internal.TestLocalhost,
+			WithProtoFile("../testdata/greeter.proto", []string{}),
+			WithTotalRequests(10),
+			WithConcurrency(2),
+			WithTenants(
+				Tenant{Name: "acme", Token: "token1"},
+				Tenant{Name: "globex", Metadata: map[string]string{"token": "token2"}},
+			),
+			WithData(map[string]interface{}{"name": "__record_metadata__"}),
+			WithInsecure(true),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, int(report.Count))
+		assert.Equal(t, []string{"acme", "globex"}, report.Options.Tenants)
+
+		names := map[string]int{}
+		for _, msgs := range gs.GetCalls(helloworld.Unary) {
+			for _, msg := range msgs {
+				names[msg.GetName()]++
+			}
+		}
+
+		assert.Equal(t, map[string]int{
+			"__record_metadata__||authorization:Bearer token1": 5,
+			"__record_metadata__||token:token2":                5,
+		}, names)
+
+		assert.NotNil(t, report.Tenants)
+		assert.Len(t, report.Tenants.Tenants, 2)
+
+		for i, name := range []string{"acme", "globex"} {
+			ts := report.Tenants.Tenants[i]
+			assert.Equal(t, name, ts.Tenant)
+			assert.Equal(t, uint64(5), ts.Count)
+			assert.Equal(t, uint64(0), ts.Errors)
+			assert.Equal(t, map[string]int{"OK": 5}, ts.StatusCodeDist)
+			assert.NotEmpty(t, ts.LatencyDistribution)
+		}
+
+		assert.InDelta(t, 1.0, report.Tenants.Fairness, 0.0001)
+		assert.Greater(t, report.Tenants.LatencyFairness, 0.0)
+	})
+}
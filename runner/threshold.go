@@ -0,0 +1,474 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// ErrThresholdsFailed is returned by Run along with the report if any of the thresholds failed
+var ErrThresholdsFailed = errors.New("thresholds failed")
+
+// thresholdOperators are the supported comparison operators, longest first so that
+// "<=" is matched before "<"
+var thresholdOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// StatusThreshold is a limit for the number of responses with a given gRPC status code
+type StatusThreshold struct {
+	// Code is the gRPC status code
+	Code codes.Code
+
+	// Operator is the comparison operator, one of ==, !=, <, <=, > or >=
+	Operator string
+
+	// Value is the count of responses, or a percentage of all responses if Percent is set
+	Value float64
+
+	// Percent indicates that Value is a percentage of the total count
+	Percent bool
+}
+
+// ThresholdResult is the result of checking a threshold against the report
+type ThresholdResult struct {
+	Threshold string  `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Pass      bool    `json:"pass"`
+}
+
+// ParseStatusThreshold parses a status code threshold in the form of <CODE><OPERATOR><VALUE>.
+// The code can be the canonical name, like UNAVAILABLE or Unavailable, or the numeric code.
+// The value can be a count, or a percentage of all responses when followed by %.
+//	ParseStatusThreshold("UNAVAILABLE==0")
+//	ParseStatusThreshold("DeadlineExceeded<1%")
+func ParseStatusThreshold(s string) (StatusThreshold, error) {
+	var t StatusThreshold
+
+	s = strings.TrimSpace(s)
+
+	opIndex := -1
+	for _, op := range thresholdOperators {
+		if i := strings.Index(s, op); i > 0 {
+			opIndex = i
+			t.Operator = op
+			break
+		}
+	}
+
+	if opIndex < 0 {
+		return t, fmt.Errorf("invalid status threshold %q: expected <code><operator><value>", s)
+	}
+
+	code, err := parseCode(strings.TrimSpace(s[:opIndex]))
+	if err != nil {
+		return t, fmt.Errorf("invalid status threshold %q: %v", s, err)
+	}
+
+	t.Code = code
+
+	value := strings.TrimSpace(s[opIndex+len(t.Operator):])
+	if strings.HasSuffix(value, "%") {
+		t.Percent = true
+		value = strings.TrimSpace(strings.TrimSuffix(value, "%"))
+	}
+
+	if t.Value, err = strconv.ParseFloat(value, 64); err != nil || t.Value < 0 {
+		return t, fmt.Errorf("invalid status threshold %q: invalid value %q", s, value)
+	}
+
+	return t, nil
+}
+
+// String returns the threshold in the same form as it is parsed
+func (t StatusThreshold) String() string {
+	s := t.Code.String() + t.Operator + strconv.FormatFloat(t.Value, 'f', -1, 64)
+	if t.Percent {
+		s += "%"
+	}
+
+	return s
+}
+
+// Check checks the threshold against the status code distribution of the report
+func (t StatusThreshold) Check(r *Report) ThresholdResult {
+	actual := float64(r.StatusCodeDist[t.Code.String()])
+	if t.Percent {
+		actual = 0
+		if r.Count > 0 {
+			actual = 100 * float64(r.StatusCodeDist[t.Code.String()]) / float64(r.Count)
+		}
+	}
+
+	return ThresholdResult{Threshold: t.String(), Actual: actual, Pass: compare(actual, t.Operator, t.Value)}
+}
+
+// Metric is a metric of the report which a threshold can be set for
+type Metric string
+
+const (
+	// MetricAverage is the average latency
+	MetricAverage Metric = "average"
+
+	// MetricFastest is the fastest latency
+	MetricFastest Metric = "fastest"
+
+	// MetricSlowest is the slowest latency
+	MetricSlowest Metric = "slowest"
+
+	// MetricP50 is the 50th percentile latency
+	MetricP50 Metric = "p50"
+
+	// MetricP75 is the 75th percentile latency
+	MetricP75 Metric = "p75"
+
+	// MetricP90 is the 90th percentile latency
+	MetricP90 Metric = "p90"
+
+	// MetricP95 is the 95th percentile latency
+	MetricP95 Metric = "p95"
+
+	// MetricP99 is the 99th percentile latency
+	MetricP99 Metric = "p99"
+
+	// MetricErrorRate is the percentage of the calls which failed
+	MetricErrorRate Metric = "error-rate"
+
+	// MetricRPS is the rate of the calls in requests per second
+	MetricRPS Metric = "rps"
+)
+
+// thresholdMetrics are the metrics which thresholds can be set for, in the order they are checked
+var thresholdMetrics = []Metric{
+	MetricAverage, MetricFastest, MetricSlowest, MetricP50, MetricP75, MetricP90, MetricP95, MetricP99,
+	MetricErrorRate, MetricRPS,
+}
+
+// isLatency returns whether the metric is a latency
+func (m Metric) isLatency() bool {
+	return m != MetricErrorRate && m != MetricRPS
+}
+
+// Threshold is a limit for a metric of the report
+type Threshold struct {
+	// Operator is the comparison operator, one of ==, !=, <, <=, > or >=
+	Operator string
+
+	// Value is the limit, in milliseconds for the latencies, in percent for the error rate
+	// and in requests per second for the rate
+	Value float64
+}
+
+// ParseThreshold parses a threshold in the form of <METRIC><OPERATOR><VALUE>.
+// The value of a latency is a duration, or a number of milliseconds. The value of the error rate
+// can be followed by %.
+//	ParseThreshold("p99<200ms")
+//	ParseThreshold("error-rate<1%")
+//	ParseThreshold("rps>=1000")
+func ParseThreshold(s string) (Metric, Threshold, error) {
+	var t Threshold
+
+	s = strings.TrimSpace(s)
+
+	opIndex := -1
+	for _, op := range thresholdOperators {
+		if i := strings.Index(s, op); i > 0 {
+			opIndex = i
+			t.Operator = op
+			break
+		}
+	}
+
+	if opIndex < 0 {
+		return "", t, fmt.Errorf("invalid threshold %q: expected <metric><operator><value>", s)
+	}
+
+	m := Metric(strings.ToLower(strings.TrimSpace(s[:opIndex])))
+	if !m.valid() {
+		return "", t, fmt.Errorf("invalid threshold %q: unknown metric %q", s, m)
+	}
+
+	value := strings.TrimSpace(s[opIndex+len(t.Operator):])
+
+	var err error
+	if m.isLatency() {
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err == nil {
+			t.Value = float64(d) / float64(time.Millisecond)
+		} else {
+			t.Value, err = strconv.ParseFloat(value, 64)
+		}
+	} else {
+		if m == MetricErrorRate {
+			value = strings.TrimSpace(strings.TrimSuffix(value, "%"))
+		}
+
+		t.Value, err = strconv.ParseFloat(value, 64)
+	}
+
+	if err != nil || t.Value < 0 {
+		return "", t, fmt.Errorf("invalid threshold %q: invalid value %q", s, value)
+	}
+
+	return m, t, nil
+}
+
+// ParseThresholds parses the thresholds using ParseThreshold, the last threshold of a metric taking effect
+func ParseThresholds(thresholds ...string) (map[Metric]Threshold, error) {
+	res := make(map[Metric]Threshold, len(thresholds))
+	for _, s := range thresholds {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+
+		m, t, err := ParseThreshold(s)
+		if err != nil {
+			return nil, err
+		}
+
+		res[m] = t
+	}
+
+	return res, nil
+}
+
+// valid returns whether thresholds can be set for the metric
+func (m Metric) valid() bool {
+	for _, v := range thresholdMetrics {
+		if m == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the threshold of the metric in the same form as it is parsed
+func (t Threshold) String(m Metric) string {
+	value := strconv.FormatFloat(t.Value, 'f', -1, 64)
+	switch {
+	case m.isLatency():
+		value = time.Duration(t.Value * float64(time.Millisecond)).String()
+	case m == MetricErrorRate:
+		value += "%"
+	}
+
+	return string(m) + t.Operator + value
+}
+
+// Check checks the threshold of the metric against the report. The actual value of
+// a latency is in milliseconds.
+func (t Threshold) Check(m Metric, r *Report) ThresholdResult {
+	actual := metricValue(m, r)
+
+	return ThresholdResult{
+		Threshold: t.String(m),
+		Actual:    math.Round(actual*100) / 100,
+		Pass:      compare(actual, t.Operator, t.Value),
+	}
+}
+
+// metricValue returns the value of the metric of the report, in milliseconds for the latencies,
+// or 0 if the report has no such percentile
+func metricValue(m Metric, r *Report) float64 {
+	switch m {
+	case MetricAverage:
+		return float64(r.Average) / float64(time.Millisecond)
+	case MetricFastest:
+		return float64(r.Fastest) / float64(time.Millisecond)
+	case MetricSlowest:
+		return float64(r.Slowest) / float64(time.Millisecond)
+	case MetricErrorRate:
+		return errorRate(r)
+	case MetricRPS:
+		return r.Rps
+	}
+
+	pct, _ := strconv.Atoi(strings.TrimPrefix(string(m), "p"))
+	for _, ld := range r.LatencyDistribution {
+		if ld.Percentage == pct {
+			return float64(ld.Latency) / float64(time.Millisecond)
+		}
+	}
+
+	return 0
+}
+
+// validOperator returns whether the operator is a supported comparison operator
+func validOperator(operator string) bool {
+	for _, op := range thresholdOperators {
+		if operator == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare returns whether the comparison of the actual value to the value holds
+func compare(actual float64, operator string, value float64) bool {
+	switch operator {
+	case "==":
+		return actual == value
+	case "!=":
+		return actual != value
+	case "<":
+		return actual < value
+	case "<=":
+		return actual <= value
+	case ">":
+		return actual > value
+	case ">=":
+		return actual >= value
+	}
+
+	return false
+}
+
+// ThroughputCheck is a steady-state check that the achieved rate of the calls stayed within
+// a tolerance of the target rate in every interval of the run, validating that the pacing
+// of an open-loop run could be maintained
+type ThroughputCheck struct {
+	// RPS is the target rate
+	RPS int
+
+	// Tolerance is the allowed deviation from the target rate in percent
+	Tolerance float64
+
+	// Interval is the duration of the buckets the rate is checked in
+	Interval time.Duration
+}
+
+// String returns the description of the check
+func (t ThroughputCheck) String() string {
+	return fmt.Sprintf("rps within %s%% of %d every %s",
+		strconv.FormatFloat(t.Tolerance, 'f', -1, 64), t.RPS, t.Interval)
+}
+
+// Check checks the rate of the calls started in each full interval of the run of the total
+// duration. A run shorter than the interval is checked as a whole. The actual value is the
+// largest deviation from the target rate in percent.
+func (t ThroughputCheck) Check(details []ResultDetail, total time.Duration) ThresholdResult {
+	return t.check(detailIter{details: details}, total)
+}
+
+// check checks the rate of the calls of the details, reading them twice rather than keeping them
+func (t ThroughputCheck) check(details detailIter, total time.Duration) ThresholdResult {
+	var origin time.Time
+	details.each(func(d *ResultDetail) {
+		if start := d.Timestamp.Add(-d.Latency); origin.IsZero() || start.Before(origin) {
+			origin = start
+		}
+	})
+
+	counter := &throughputCounter{interval: t.Interval, start: origin}
+	details.each(func(d *ResultDetail) {
+		counter.count(d.Timestamp.Add(-d.Latency))
+	})
+
+	return t.checkCounts(counter.counts, total)
+}
+
+// checkCounts checks the numbers of calls started in each interval of the run of the total duration
+func (t ThroughputCheck) checkCounts(intervalCounts []int, total time.Duration) ThresholdResult {
+	interval := t.Interval
+	n := int(total / interval)
+	if n == 0 {
+		n, interval = 1, total
+	}
+
+	counts := make([]int, n)
+	for i, c := range intervalCounts {
+		switch {
+		case interval < t.Interval:
+			// a run shorter than the interval is checked as a whole
+			counts[0] += c
+		case i < n:
+			counts[i] = c
+		}
+	}
+
+	var worst float64
+	for _, c := range counts {
+		rate := 0.0
+		if interval > 0 {
+			rate = float64(c) / interval.Seconds()
+		}
+
+		if dev := 100 * math.Abs(rate-float64(t.RPS)) / float64(t.RPS); dev > worst {
+			worst = dev
+		}
+	}
+
+	worst = math.Round(worst*100) / 100
+
+	return ThresholdResult{Threshold: t.String(), Actual: worst, Pass: worst <= t.Tolerance}
+}
+
+// throughputCounter counts the calls started in each interval of the run for the throughput check,
+// from all the results rather than the details, which are capped or not kept at all
+type throughputCounter struct {
+	interval time.Duration
+	start    time.Time
+
+	counts []int
+}
+
+func newThroughputCounter(c *RunConfig, start time.Time) *throughputCounter {
+	if c.rpsTolerance <= 0 || c.rpsInterval <= 0 {
+		return nil
+	}
+
+	return &throughputCounter{interval: c.rpsInterval, start: start}
+}
+
+// add counts the result in the interval the call started in
+func (t *throughputCounter) add(res *callResult) {
+	if t == nil {
+		return
+	}
+
+	t.count(res.timestamp.Add(-res.duration))
+}
+
+// count counts a call started at the time, the calls started before the run in the first interval
+func (t *throughputCounter) count(started time.Time) {
+	i := 0
+	if started.After(t.start) {
+		i = int(started.Sub(t.start) / t.interval)
+	}
+
+	for len(t.counts) <= i {
+		t.counts = append(t.counts, 0)
+	}
+
+	t.counts[i]++
+}
+
+// parseCode parses the canonical name or the number of the code
+func parseCode(s string) (codes.Code, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		if n > uint64(codes.Unauthenticated) {
+			return 0, fmt.Errorf("unknown status code %q", s)
+		}
+
+		return codes.Code(n), nil
+	}
+
+	name := strings.ToLower(strings.Replace(s, "_", "", -1))
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.ToLower(c.String()) == name {
+			return c, nil
+		}
+	}
+
+	// the canonical name is CANCELLED while the Go code is Canceled
+	if name == "cancelled" {
+		return codes.Canceled, nil
+	}
+
+	return 0, fmt.Errorf("unknown status code %q", s)
+}
@@ -0,0 +1,311 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParseStatusThreshold(t *testing.T) {
+	var tests = []struct {
+		in       string
+		expected StatusThreshold
+		str      string
+	}{
+		{"UNAVAILABLE==0", StatusThreshold{Code: codes.Unavailable, Operator: "==", Value: 0}, "Unavailable==0"},
+		{"DeadlineExceeded<1%", StatusThreshold{Code: codes.DeadlineExceeded, Operator: "<", Value: 1, Percent: true}, "DeadlineExceeded<1%"},
+		{" deadline_exceeded <= 2.5 % ", StatusThreshold{Code: codes.DeadlineExceeded, Operator: "<=", Value: 2.5, Percent: true}, "DeadlineExceeded<=2.5%"},
+		{"OK>=100", StatusThreshold{Code: codes.OK, Operator: ">=", Value: 100}, "OK>=100"},
+		{"14!=5", StatusThreshold{Code: codes.Unavailable, Operator: "!=", Value: 5}, "Unavailable!=5"},
+		{"CANCELLED>0", StatusThreshold{Code: codes.Canceled, Operator: ">", Value: 0}, "Canceled>0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			actual, err := ParseStatusThreshold(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, tt.str, actual.String())
+		})
+	}
+
+	for _, in := range []string{"", "UNAVAILABLE", "==0", "FOO==0", "17==0", "OK==abc", "OK==-1", "OK=1"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, err := ParseStatusThreshold(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStatusThreshold_Check(t *testing.T) {
+	report := &Report{
+		Count: 200,
+		StatusCodeDist: map[string]int{
+			"OK":          190,
+			"Unavailable": 10,
+		},
+	}
+
+	var tests = []struct {
+		in     string
+		actual float64
+		pass   bool
+	}{
+		{"UNAVAILABLE==0", 10, false},
+		{"UNAVAILABLE<=10", 10, true},
+		{"UNAVAILABLE<5%", 5, false},
+		{"UNAVAILABLE<=5%", 5, true},
+		{"OK>=95%", 95, true},
+		{"OK>190", 190, false},
+		{"DEADLINE_EXCEEDED==0", 0, true},
+		{"DEADLINE_EXCEEDED!=0", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			th, err := ParseStatusThreshold(tt.in)
+			assert.NoError(t, err)
+
+			res := th.Check(report)
+			assert.Equal(t, th.String(), res.Threshold)
+			assert.Equal(t, tt.actual, res.Actual)
+			assert.Equal(t, tt.pass, res.Pass)
+		})
+	}
+
+	t.Run("passed", func(t *testing.T) {
+		r := &Report{}
+		assert.True(t, r.ThresholdsPassed())
+
+		r.Thresholds = []ThresholdResult{{Pass: true}, {Pass: false}}
+		assert.False(t, r.ThresholdsPassed())
+	})
+}
+
+func TestParseThreshold(t *testing.T) {
+	var tests = []struct {
+		in       string
+		metric   Metric
+		expected Threshold
+		str      string
+	}{
+		{"p99<200ms", MetricP99, Threshold{Operator: "<", Value: 200}, "p99<200ms"},
+		{" P95 <= 1.5s ", MetricP95, Threshold{Operator: "<=", Value: 1500}, "p95<=1.5s"},
+		{"average<250", MetricAverage, Threshold{Operator: "<", Value: 250}, "average<250ms"},
+		{"slowest<500us", MetricSlowest, Threshold{Operator: "<", Value: 0.5}, "slowest<500µs"},
+		{"error-rate<1%", MetricErrorRate, Threshold{Operator: "<", Value: 1}, "error-rate<1%"},
+		{"error-rate==0", MetricErrorRate, Threshold{Operator: "==", Value: 0}, "error-rate==0%"},
+		{"rps>=1000", MetricRPS, Threshold{Operator: ">=", Value: 1000}, "rps>=1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			m, actual, err := ParseThreshold(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.metric, m)
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, tt.str, actual.String(m))
+		})
+	}
+
+	for _, in := range []string{"", "p99", "<200ms", "p42<200ms", "p99<fast", "p99<-1ms", "rps>=1000%", "p99=200ms"} {
+		t.Run("invalid "+in, func(t *testing.T) {
+			_, _, err := ParseThreshold(in)
+			assert.Error(t, err)
+		})
+	}
+
+	thresholds, err := ParseThresholds("p99<200ms", "", "p99<100ms", "rps>10")
+	assert.NoError(t, err)
+	assert.Equal(t, map[Metric]Threshold{
+		MetricP99: {Operator: "<", Value: 100},
+		MetricRPS: {Operator: ">", Value: 10},
+	}, thresholds)
+}
+
+func TestThreshold_Check(t *testing.T) {
+	report := &Report{
+		Count:   200,
+		Average: 50 * time.Millisecond,
+		Fastest: 2 * time.Millisecond,
+		Slowest: 450 * time.Millisecond,
+		Rps:     812.3456,
+		LatencyDistribution: []LatencyDistribution{
+			{Percentage: 50, Latency: 40 * time.Millisecond},
+			{Percentage: 99, Latency: 210500 * time.Microsecond},
+		},
+		ErrorDist: map[string]int{"rpc error: code = Unavailable": 3},
+	}
+
+	var tests = []struct {
+		in     string
+		actual float64
+		pass   bool
+	}{
+		{"p99<200ms", 210.5, false},
+		{"p99<250ms", 210.5, true},
+		{"p50<=40ms", 40, true},
+		{"average<50ms", 50, false},
+		{"fastest>=1ms", 2, true},
+		{"slowest<1s", 450, true},
+		{"error-rate<1%", 1.5, false},
+		{"error-rate<2%", 1.5, true},
+		{"rps>=1000", 812.35, false},
+		{"rps>800", 812.35, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			m, th, err := ParseThreshold(tt.in)
+			assert.NoError(t, err)
+
+			res := th.Check(m, report)
+			assert.Equal(t, th.String(m), res.Threshold)
+			assert.Equal(t, tt.actual, res.Actual)
+			assert.Equal(t, tt.pass, res.Pass)
+		})
+	}
+}
+
+func TestThroughputCheck_Check(t *testing.T) {
+	start := time.Now()
+
+	// 10 calls in the first second, 8 in the second and 3 in the final partial second
+	var details []ResultDetail
+	for i, n := range []int{10, 8, 3} {
+		for j := 0; j < n; j++ {
+			offset := time.Duration(i)*time.Second + time.Duration(j)*50*time.Millisecond
+			details = append(details, ResultDetail{
+				Timestamp: start.Add(offset + 10*time.Millisecond),
+				Latency:   10 * time.Millisecond,
+			})
+		}
+	}
+
+	check := ThroughputCheck{RPS: 10, Tolerance: 20, Interval: time.Second}
+	res := check.Check(details, 2500*time.Millisecond)
+
+	assert.Equal(t, "rps within 20% of 10 every 1s", res.Threshold)
+	assert.Equal(t, 20.0, res.Actual)
+	assert.True(t, res.Pass)
+
+	check.Tolerance = 10
+	assert.False(t, check.Check(details, 2500*time.Millisecond).Pass)
+
+	// a run shorter than the interval is checked as a whole
+	res = check.Check(details[:9], 900*time.Millisecond)
+	assert.Equal(t, 0.0, res.Actual)
+	assert.True(t, res.Pass)
+}
+
+func TestThroughputCounter(t *testing.T) {
+	assert.Nil(t, newThroughputCounter(&RunConfig{}, time.Now()))
+
+	start := time.Now()
+	counter := newThroughputCounter(&RunConfig{rpsTolerance: 20, rpsInterval: time.Second}, start)
+
+	// 10 calls started in the first second, including one started before the run, and 8 in the third
+	counter.add(&callResult{timestamp: start, duration: 10 * time.Millisecond})
+	for j := 1; j < 10; j++ {
+		counter.add(&callResult{timestamp: start.Add(time.Duration(j) * 50 * time.Millisecond), duration: 10 * time.Millisecond})
+	}
+
+	for j := 0; j < 8; j++ {
+		counter.add(&callResult{timestamp: start.Add(2*time.Second + time.Duration(j+1)*50*time.Millisecond), duration: 10 * time.Millisecond})
+	}
+
+	assert.Equal(t, []int{10, 0, 8}, counter.counts)
+
+	check := ThroughputCheck{RPS: 10, Tolerance: 20, Interval: time.Second}
+	res := check.checkCounts(counter.counts, 3*time.Second)
+	assert.Equal(t, 100.0, res.Actual)
+	assert.False(t, res.Pass)
+
+	// the final partial interval is left out
+	res = check.checkCounts([]int{10, 9, 2}, 2500*time.Millisecond)
+	assert.Equal(t, 10.0, res.Actual)
+	assert.True(t, res.Pass)
+}
+
+func TestRunThresholds(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithThresholds(map[Metric]Threshold{
+			MetricErrorRate: {Operator: "==", Value: 0},
+			MetricP99:       {Operator: "<", Value: 10000},
+		}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.Len(t, report.Thresholds, 2) {
+		assert.Equal(t, "p99<10s", report.Thresholds[0].Threshold)
+		assert.Equal(t, "error-rate==0%", report.Thresholds[1].Threshold)
+		assert.True(t, report.ThresholdsPassed())
+	}
+
+	report, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithThresholds(map[Metric]Threshold{MetricRPS: {Operator: ">=", Value: 1e9}}),
+		WithInsecure(true),
+	)
+
+	assert.Equal(t, ErrThresholdsFailed, err)
+
+	if assert.NotNil(t, report) && assert.Len(t, report.Thresholds, 1) {
+		assert.Equal(t, "rps>=1000000000", report.Thresholds[0].Threshold)
+		assert.False(t, report.Thresholds[0].Pass)
+	}
+}
+
+func TestRunThroughputCheck(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	// the rate is checked from all the calls, without the details of the streaming percentiles
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithRPS(50),
+		WithRunDuration(2*time.Second),
+		WithConcurrency(2),
+		WithStreamingPercentiles(true),
+		WithThroughputCheck(40, time.Second),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, report) && assert.Len(t, report.Thresholds, 1) {
+		assert.Empty(t, report.Details)
+		assert.Equal(t, "rps within 40% of 50 every 1s", report.Thresholds[0].Threshold)
+		assert.True(t, report.Thresholds[0].Pass, "deviation %v", report.Thresholds[0].Actual)
+	}
+}
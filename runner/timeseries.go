@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"sort"
+	"time"
+)
+
+// TimeWindow holds the statistics of the calls completed within a window of the run
+type TimeWindow struct {
+	// Start is the start of the window relative to the start of the run
+	Start time.Duration `json:"start"`
+
+	Count     uint64  `json:"count"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	Rps       float64 `json:"rps"`
+
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+}
+
+// timeSeries buckets the results into windows of a fixed duration by the time they completed,
+// so the degradation of the latency over the course of a run can be seen
+type timeSeries struct {
+	window      time.Duration
+	start       time.Time
+	countErrors bool
+
+	buckets []*timeBucket
+}
+
+// timeBucket holds the results of a window
+type timeBucket struct {
+	count  uint64
+	errors uint64
+	lats   []float64
+}
+
+func newTimeSeries(window time.Duration, start time.Time, countErrors bool) *timeSeries {
+	if window <= 0 {
+		return nil
+	}
+
+	return &timeSeries{window: window, start: start, countErrors: countErrors}
+}
+
+// add adds the result to the window it completed in
+func (t *timeSeries) add(res *callResult) {
+	if t == nil {
+		return
+	}
+
+	i := 0
+	if res.timestamp.After(t.start) {
+		i = int(res.timestamp.Sub(t.start) / t.window)
+	}
+
+	for len(t.buckets) <= i {
+		t.buckets = append(t.buckets, &timeBucket{})
+	}
+
+	b := t.buckets[i]
+	b.count++
+
+	if res.err != nil {
+		b.errors++
+	}
+
+	if res.err == nil || t.countErrors {
+		b.lats = append(b.lats, res.duration.Seconds())
+	}
+}
+
+// windows returns the statistics of the windows of the run of the total duration.
+// The rate of the final window is relative to the part of it within the run.
+func (t *timeSeries) windows(total time.Duration) []TimeWindow {
+	res := make([]TimeWindow, len(t.buckets))
+	for i, b := range t.buckets {
+		w := TimeWindow{
+			Start:  time.Duration(i) * t.window,
+			Count:  b.count,
+			Errors: b.errors,
+		}
+
+		if b.count > 0 {
+			w.ErrorRate = float64(b.errors) / float64(b.count)
+		}
+
+		length := t.window
+		if rest := total - w.Start; rest > 0 && rest < length {
+			length = rest
+		}
+
+		w.Rps = float64(b.count) / length.Seconds()
+
+		if len(b.lats) > 0 {
+			sort.Float64s(b.lats)
+
+			for _, ld := range latencies(b.lats) {
+				switch ld.Percentage {
+				case 50:
+					w.P50 = ld.Latency
+				case 95:
+					w.P95 = ld.Latency
+				case 99:
+					w.P99 = ld.Latency
+				}
+			}
+		}
+
+		res[i] = w
+	}
+
+	return res
+}
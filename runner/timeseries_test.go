@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSeries_windows(t *testing.T) {
+	start := time.Now()
+	ts := newTimeSeries(time.Second, start, false)
+
+	for i := 1; i <= 100; i++ {
+		ts.add(&callResult{
+			timestamp: start.Add(time.Duration(i) * 5 * time.Millisecond),
+			duration:  time.Duration(i) * time.Millisecond,
+			status:    "OK",
+		})
+	}
+
+	ts.add(&callResult{
+		timestamp: start.Add(2100 * time.Millisecond),
+		duration:  10 * time.Millisecond,
+		status:    "OK",
+	})
+
+	ts.add(&callResult{
+		timestamp: start.Add(2200 * time.Millisecond),
+		duration:  time.Second,
+		err:       errors.New("unavailable"),
+		status:    "Unavailable",
+	})
+
+	windows := ts.windows(2500 * time.Millisecond)
+
+	assert.Len(t, windows, 3)
+
+	assert.Equal(t, TimeWindow{
+		Count: 100,
+		Rps:   100,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}, windows[0])
+
+	// the windows without results are included
+	assert.Equal(t, TimeWindow{Start: time.Second}, windows[1])
+
+	// the failed calls are not included in the latencies, and the rate of the
+	// final window is relative to the part of it within the run
+	assert.Equal(t, TimeWindow{
+		Start:     2 * time.Second,
+		Count:     2,
+		Errors:    1,
+		ErrorRate: 0.5,
+		Rps:       4,
+		P50:       10 * time.Millisecond,
+		P95:       10 * time.Millisecond,
+		P99:       10 * time.Millisecond,
+	}, windows[2])
+
+	assert.Nil(t, newTimeSeries(0, start, false))
+}
+
+func TestRunTimeSeries(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(2),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithTimeSeries(time.Second),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, report.Options.TimeSeries)
+
+	var count uint64
+	for _, w := range report.TimeSeries {
+		count += w.Count
+	}
+
+	assert.NotEmpty(t, report.TimeSeries)
+	assert.Equal(t, uint64(20), count)
+}
@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timestampLayouts are the named layouts of the timestamp template functions
+var timestampLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// locations caches the time zones loaded by name
+var locations sync.Map
+
+// tsNow returns the current time formatted using the optional layout and time zone
+//
+//	{{tsNow}}
+//	{{tsNow "DateOnly" "America/New_York"}}
+func tsNow(opts ...string) (string, error) {
+	return formatTimestamp(time.Now(), opts)
+}
+
+// tsAdd returns the current time plus the offset formatted using the optional layout and time zone
+//
+//	{{tsAdd "-24h"}}
+//	{{tsAdd "7d" "2006-01-02T15:04:05" "UTC"}}
+func tsAdd(offset string, opts ...string) (string, error) {
+	d, err := parseOffset(offset)
+	if err != nil {
+		return "", err
+	}
+
+	return formatTimestamp(time.Now().Add(d), opts)
+}
+
+// tsRandom returns a random time between the current time plus the two offsets,
+// formatted using the optional layout and time zone
+//
+//	{{tsRandom "-30d" "0s"}}
+func tsRandom(from, to string, opts ...string) (string, error) {
+	start, err := parseOffset(from)
+	if err != nil {
+		return "", err
+	}
+
+	end, err := parseOffset(to)
+	if err != nil {
+		return "", err
+	}
+
+	if end < start {
+		start, end = end, start
+	}
+
+	d := start
+	if end > start {
+		d += time.Duration(seededRand.Int63n(int64(end - start)))
+	}
+
+	return formatTimestamp(time.Now().Add(d), opts)
+}
+
+// tsUnix returns the current time plus the optional offset as unix time in seconds
+//
+//	{{tsUnix "-1h"}}
+func tsUnix(offset ...string) (int64, error) {
+	t, err := offsetTime(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Unix(), nil
+}
+
+// tsUnixMilli returns the current time plus the optional offset as unix time in milliseconds
+func tsUnixMilli(offset ...string) (int64, error) {
+	t, err := offsetTime(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
+
+func offsetTime(offset []string) (time.Time, error) {
+	if len(offset) > 1 {
+		return time.Time{}, fmt.Errorf("expected at most one offset, got %d", len(offset))
+	}
+
+	now := time.Now()
+	if len(offset) == 0 {
+		return now, nil
+	}
+
+	d, err := parseOffset(offset[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return now.Add(d), nil
+}
+
+// parseOffset parses a duration, which may also be a number of days such as -7d
+func parseOffset(offset string) (time.Duration, error) {
+	offset = strings.TrimSpace(offset)
+
+	if strings.HasSuffix(offset, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(offset, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", offset)
+		}
+
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", offset)
+	}
+
+	return d, nil
+}
+
+// formatTimestamp formats the time using the optional layout, RFC3339 by default,
+// in the optional time zone, the local time zone by default
+func formatTimestamp(t time.Time, opts []string) (string, error) {
+	if len(opts) > 2 {
+		return "", fmt.Errorf("expected at most a layout and a time zone, got %d arguments", len(opts))
+	}
+
+	layout := time.RFC3339
+	if len(opts) > 0 && opts[0] != "" {
+		layout = opts[0]
+		if named, ok := timestampLayouts[layout]; ok {
+			layout = named
+		}
+	}
+
+	if len(opts) > 1 && opts[1] != "" {
+		loc, err := loadLocation(opts[1])
+		if err != nil {
+			return "", err
+		}
+
+		t = t.In(loc)
+	}
+
+	return t.Format(layout), nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %v", name, err)
+	}
+
+	locations.Store(name, loc)
+
+	return loc, nil
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamp_parseOffset(t *testing.T) {
+	var tests = []struct {
+		offset   string
+		expected time.Duration
+		err      string
+	}{
+		{"-24h", -24 * time.Hour, ""},
+		{" 90m ", 90 * time.Minute, ""},
+		{"7d", 7 * 24 * time.Hour, ""},
+		{"-1.5d", -36 * time.Hour, ""},
+		{"0s", 0, ""},
+		{"d", 0, `invalid offset "d"`},
+		{"tomorrow", 0, `invalid offset "tomorrow"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.offset, func(t *testing.T) {
+			d, err := parseOffset(tt.offset)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestTimestamp_formatTimestamp(t *testing.T) {
+	ts := time.Date(2021, 3, 14, 1, 59, 26, 535000000, time.UTC)
+
+	var tests = []struct {
+		name     string
+		opts     []string
+		expected string
+		err      string
+	}{
+		{"default", nil, ts.Local().Format(time.RFC3339), ""},
+		{"named layout", []string{"RFC3339Nano", "UTC"}, "2021-03-14T01:59:26.535Z", ""},
+		{"date only", []string{"DateOnly", "UTC"}, "2021-03-14", ""},
+		{"custom layout", []string{"02 Jan 06 15:04 MST", "UTC"}, "14 Mar 21 01:59 UTC", ""},
+		{"time zone", []string{"", "America/New_York"}, "2021-03-13T20:59:26-05:00", ""},
+		{"invalid time zone", []string{"RFC3339", "Mars/Olympus"}, "", `invalid time zone "Mars/Olympus"`},
+		{"too many arguments", []string{"RFC3339", "UTC", "en"}, "", "expected at most a layout and a time zone, got 3 arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := formatTimestamp(ts, tt.opts)
+			if tt.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTimestamp_tsRandom(t *testing.T) {
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		s, err := tsRandom("0s", "-2d", "RFC3339Nano")
+		assert.NoError(t, err)
+
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		assert.NoError(t, err)
+		assert.True(t, !ts.Before(now.Add(-48*time.Hour)) && !ts.After(time.Now()), "timestamp %s", s)
+	}
+
+	_, err := tsRandom("-1d", "soon")
+	assert.EqualError(t, err, `invalid offset "soon"`)
+}
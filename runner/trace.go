@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// traceparentKey is the W3C trace context metadata key
+const traceparentKey = "traceparent"
+
+// maxTraces is the maximum number of sampled traces listed in the report
+const maxTraces = 1000
+
+// callTraceKey is the context key of the trace ID of sampled calls
+type callTraceKey struct{}
+
+// Trace is a sampled call with the trace context propagated to the server.
+// The trace ID can be used to look up the call in the tracing backend.
+type Trace struct {
+	TraceID   string        `json:"traceId"`
+	Timestamp time.Time     `json:"timestamp"`
+	Latency   time.Duration `json:"latency"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// newTraceparent returns a new random trace ID and the W3C traceparent
+// value of a sampled root span within the trace
+func newTraceparent() (string, string, error) {
+	// 16 bytes of trace ID followed by 8 bytes of parent span ID
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", err
+	}
+
+	traceID := hex.EncodeToString(b[:16])
+
+	return traceID, "00-" + traceID + "-" + hex.EncodeToString(b[16:]) + "-01", nil
+}
+
+// slowestTraces returns up to maxTraces of the traces, slowest first
+func slowestTraces(traces []Trace) []Trace {
+	sorted := append([]Trace(nil), traces...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Latency > sorted[j].Latency
+	})
+
+	if len(sorted) > maxTraces {
+		sorted = sorted[:maxTraces]
+	}
+
+	return sorted
+}
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTraceparent(t *testing.T) {
+	traceID, traceparent, err := newTraceparent()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), traceID)
+	assert.Regexp(t, regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`), traceparent)
+	assert.True(t, strings.HasPrefix(traceparent, "00-"+traceID+"-"))
+
+	other, _, err := newTraceparent()
+	assert.NoError(t, err)
+	assert.NotEqual(t, traceID, other)
+}
+
+func TestSlowestTraces(t *testing.T) {
+	traces := make([]Trace, maxTraces+10)
+	for i := range traces {
+		traces[i] = Trace{Latency: time.Duration(i) * time.Millisecond}
+	}
+
+	slowest := slowestTraces(traces)
+	assert.Len(t, slowest, maxTraces)
+	assert.Equal(t, time.Duration(maxTraces+9)*time.Millisecond, slowest[0].Latency)
+	assert.Equal(t, 10*time.Millisecond, slowest[maxTraces-1].Latency)
+
+	// the traces are not modified
+	assert.Equal(t, time.Duration(0), traces[0].Latency)
+}
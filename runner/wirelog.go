@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// wireLogKey is the context key marking the sampled call of the wire log
+type wireLogKey struct{}
+
+// WireLogRecord is a single transport event of the call sampled for wire logging
+type WireLogRecord struct {
+	Time         time.Time       `json:"time"`
+	Event        string          `json:"event"`
+	ConnectionID int             `json:"connectionId"`
+	Method       string          `json:"method,omitempty"`
+	RemoteAddr   string          `json:"remoteAddr,omitempty"`
+	LocalAddr    string          `json:"localAddr,omitempty"`
+	Compression  string          `json:"compression,omitempty"`
+	Header       metadata.MD     `json:"header,omitempty"`
+	Trailer      metadata.MD     `json:"trailer,omitempty"`
+	Length       int             `json:"length,omitempty"`
+	WireLength   int             `json:"wireLength,omitempty"`
+	Message      json.RawMessage `json:"message,omitempty"`
+	Data         []byte          `json:"data,omitempty"`
+	Status       string          `json:"status,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// wireLogger writes out the transport events of a single sampled call
+type wireLogger struct {
+	out  io.Writer
+	call int64
+
+	lock sync.Mutex
+	done bool
+}
+
+func newWireLogger(out io.Writer, call int64) *wireLogger {
+	if out == nil || call < 0 {
+		return nil
+	}
+
+	return &wireLogger{
+		out:  out,
+		call: call,
+	}
+}
+
+// sampled returns whether the call with the request number is the sampled call
+// a nil logger samples no calls
+func (l *wireLogger) sampled(reqNum int64) bool {
+	return l != nil && reqNum == l.call
+}
+
+// record writes the event of the sampled call made on the connection.
+// Retries of the sampled call are not recorded once the call has ended.
+func (l *wireLogger) record(connID int, rs stats.RPCStats) error {
+	rec := WireLogRecord{
+		Time:         time.Now(),
+		ConnectionID: connID,
+	}
+
+	switch rs := rs.(type) {
+	case *stats.Begin:
+		rec.Event = "begin"
+		rec.Time = rs.BeginTime
+	case *stats.OutHeader:
+		rec.Event = "out-header"
+		rec.Method = rs.FullMethod
+		rec.Compression = rs.Compression
+		rec.Header = rs.Header
+
+		if rs.RemoteAddr != nil {
+			rec.RemoteAddr = rs.RemoteAddr.String()
+		}
+
+		if rs.LocalAddr != nil {
+			rec.LocalAddr = rs.LocalAddr.String()
+		}
+	case *stats.OutPayload:
+		rec.Event = "out-payload"
+		rec.Time = rs.SentTime
+		rec.Length = rs.Length
+		rec.WireLength = rs.WireLength
+		rec.Data = rs.Data
+
+		if msg, ok := rs.Payload.(proto.Message); ok {
+			rec.Message = messageToJSON(msg)
+		}
+	case *stats.InHeader:
+		rec.Event = "in-header"
+		rec.Compression = rs.Compression
+		rec.Header = rs.Header
+		rec.WireLength = rs.WireLength
+	case *stats.InPayload:
+		rec.Event = "in-payload"
+		rec.Time = rs.RecvTime
+		rec.Length = rs.Length
+		rec.WireLength = rs.WireLength
+		rec.Data = rs.Data
+
+		if msg, ok := rs.Payload.(proto.Message); ok {
+			rec.Message = messageToJSON(msg)
+		}
+	case *stats.InTrailer:
+		rec.Event = "in-trailer"
+		rec.Trailer = rs.Trailer
+		rec.WireLength = rs.WireLength
+	case *stats.End:
+		rec.Event = "end"
+		rec.Time = rs.EndTime
+		rec.Trailer = rs.Trailer
+		rec.Status = statusCode(rs.Error).String()
+
+		if rs.Error != nil {
+			rec.Error = rs.Error.Error()
+		}
+	default:
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.done {
+		return nil
+	}
+
+	if rec.Event == "end" {
+		l.done = true
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.out.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWireLog(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	defer s.Stop()
+
+	buf := &bytes.Buffer{}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(10),
+		WithConcurrency(2),
+		WithWireLog(buf, 3),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), report.Count)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	events := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var rec WireLogRecord
+		assert.NoError(t, json.Unmarshal([]byte(line), &rec))
+
+		events = append(events, rec.Event)
+
+		switch rec.Event {
+		case "out-header":
+			assert.Equal(t, "/helloworld.Greeter/SayHello", rec.Method)
+			assert.NotEmpty(t, rec.RemoteAddr)
+		case "out-payload":
+			assert.JSONEq(t, `{"name":"bob"}`, string(rec.Message))
+			assert.NotEmpty(t, rec.Data)
+			assert.True(t, rec.WireLength > rec.Length)
+		case "in-payload":
+			assert.JSONEq(t, `{"message":"Hello bob"}`, string(rec.Message))
+		case "end":
+			assert.Equal(t, "OK", rec.Status)
+		}
+	}
+
+	// the trailer may be received before the response message is handled
+	assert.ElementsMatch(t, []string{"begin", "out-header", "out-payload", "in-header", "in-payload", "in-trailer", "end"}, events)
+	assert.Equal(t, "begin", events[0])
+	assert.Equal(t, "end", events[len(events)-1])
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -14,7 +16,6 @@ import (
 	"go.uber.org/multierr"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -22,6 +23,7 @@ import (
 type TickValue struct {
 	instant   time.Time
 	reqNumber uint64
+	intended  time.Time // the intended send time of rate-paced requests
 }
 
 // Worker is used for doing a single stream of requests in parallel
@@ -35,34 +37,173 @@ type Worker struct {
 	stopCh   chan bool
 	ticks    <-chan TickValue
 
+	// the delay before the worker starts making requests
+	startDelay time.Duration
+
 	dataProvider     DataProviderFunc
 	metadataProvider MetadataProviderFunc
 	msgProvider      StreamMessageProviderFunc
+	payload          *chunkedPayload
+	stream           *streamTracker
+	acks             *ackTracker
+	backpressure     *backpressureTracker
+	fields           *fieldTracker
+	drift            *driftTracker
+	session          *workerSession
 
 	streamRecv StreamRecvMsgInterceptFunc
+
+	debugger  *callDebugger
+	slowCalls *slowCallLogger
+	wireLog   *wireLogger
+	capture   *responseCapture
+
+	// the sender and response handler pools of async unary calls
+	conn      *grpc.ClientConn
+	async     *asyncQueue
+	responses chan *asyncResponse
+
+	grace *gracePeriod
+
+	// drain tracks the calls in flight when stopping with the drain action
+	drain *drainTracker
+
+	// metrics counts the active workers for the live metrics
+	metrics *liveMetrics
+
+	// push counts the active workers for the pushed interval statistics
+	push *statsPusher
+
+	// group is the group whose CPUs the thread of the worker is pinned to, if any
+	group *workerGroup
+
+	// hedge hedges a percentage of the unary calls
+	hedge *hedgeTracker
+
+	// shards routes the calls to the connections by the shard key
+	shards *shardRouter
+
+	// churn re-dials the connection of the worker, the connection connIndex of the pool
+	churn     *connChurner
+	connIndex int
+
+	// scaling routes the calls to the connections once connections are added
+	scaling *connScaler
+
+	// endData ends the run once the data is exhausted
+	endData func()
+
+	// the previous successful response of the worker when it is used in the templates
+	trackResponse bool
+	lastResponse  map[string]interface{}
+
+	// the logical operations of the pagination mode
+	pages *paginator
+
+	// the iterations of the scenario, each tick making a pass of its calls, with scenario pacing
+	iterations *iterationTracker
+
+	// the tenants the calls are made under in turn in the fairness mode
+	tenants *tenants
+
+	// the refreshed method descriptor and data provider when schema errors refresh the descriptor
+	schema *schemaRefresher
+
+	// the weighted calls of the scenario, one of which is picked for each request,
+	// and the position of the worker within the calls if the scenario is sequential
+	scenario *scenario
+	chain    scenarioChain
+
+	// the data partition of the worker and the number of calls made using it
+	partition      *dataPartition
+	partitionCalls int64
 }
 
 func (w *Worker) runWorker() error {
+	if w.startDelay > 0 {
+		timer := time.NewTimer(w.startDelay)
+
+		select {
+		case <-timer.C:
+		case <-w.stopCh:
+			timer.Stop()
+
+			return nil
+		}
+	}
+
+	w.metrics.addWorkers(1)
+	defer w.metrics.addWorkers(-1)
+
+	w.push.addWorkers(1)
+	defer w.push.addWorkers(-1)
+
+	if w.group != nil {
+		if err := w.group.pin(); err != nil {
+			return err
+		}
+	}
+
+	if w.async != nil {
+		err := w.runAsyncPools()
+		w.closeSession()
+
+		return err
+	}
+
 	var err error
 	g := new(errgroup.Group)
 
+	// with pacing the worker waits for the delay before taking each tick
+	ticks := w.ticks
+	var timer *time.Timer
+	var paced <-chan time.Time
+	if w.config.workerPacing != nil {
+		timer = time.NewTimer(w.config.workerPacing.next())
+		defer timer.Stop()
+
+		ticks, paced = nil, timer.C
+	}
+
 	for {
 		select {
 		case <-w.stopCh:
 			if w.config.async {
-				return g.Wait()
+				err = g.Wait()
 			}
 
+			w.closeSession()
+
 			return err
-		case tv := <-w.ticks:
+		case <-paced:
+			ticks, paced = w.ticks, nil
+		case tv := <-ticks:
 			if w.config.async {
 				g.Go(func() error {
 					return w.makeRequest(tv)
 				})
+			} else if w.iterations != nil {
+				rErr := w.makeIteration(tv)
+				err = multierr.Append(err, rErr)
 			} else {
 				rErr := w.makeRequest(tv)
 				err = multierr.Append(err, rErr)
 			}
+
+			if timer != nil {
+				timer.Reset(w.config.workerPacing.next())
+				ticks, paced = nil, timer.C
+			}
+		}
+	}
+}
+
+// closeSession closes the session of the worker. The connections may already be
+// closed when stopping so failing to close the session does not fail the run.
+func (w *Worker) closeSession() {
+	if w.session != nil {
+		if cErr := w.session.close(w); cErr != nil && w.config.hasLog {
+			w.config.log.Errorw(cErr.Error(), "workerID", w.workerID, "error", cErr)
 		}
 	}
 }
@@ -80,40 +221,141 @@ func (w *Worker) Stop() {
 func (w *Worker) makeRequest(tv TickValue) error {
 	reqNum := int64(tv.reqNumber)
 
+	if w.schema != nil {
+		w.mtd, w.dataProvider = w.schema.current()
+	}
+
+	var method string
+	var sc *scenarioCall
+	if w.scenario != nil {
+		sc = w.scenario.pick(tv.reqNumber, &w.chain)
+		w.mtd, w.dataProvider, w.metadataProvider = sc.mtd, sc.dataProvider, sc.metadataProvider
+		method = sc.name
+	}
+
 	ctd := newCallData(w.mtd, w.config.funcs, w.workerID, reqNum)
+	ctd.RunID = w.config.runID
+	ctd.LastResponse = w.lastResponse
+	ctd.Vars = w.chain.vars
+
+	if w.partition != nil {
+		// the async calls of the worker are made concurrently, each with its own call number
+		ctd.partition = w.partition
+		ctd.partitionCall = atomic.AddInt64(&w.partitionCalls, 1) - 1
+	}
+
+	if w.session != nil {
+		token, err := w.session.getToken(w, ctd)
+		if err != nil {
+			return err
+		}
+
+		ctd.SessionToken = token
+	}
 
 	reqMD, err := w.metadataProvider(ctd)
 	if err != nil {
 		return err
 	}
 
-	if w.config.enableCompression {
-		reqMD.Append("grpc-accept-encoding", gzip.Name)
+	if w.session != nil {
+		if reqMD, err = w.sessionMetadata(ctd, reqMD); err != nil {
+			return err
+		}
 	}
 
-	ctx := context.Background()
-	var cancel context.CancelFunc
+	reqMD = withRunIDHeader(reqMD, w.config.runIDHeader, w.config.runID)
 
-	if w.config.timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, w.config.timeout)
-	} else {
-		ctx, cancel = context.WithCancel(ctx)
+	var tenant *Tenant
+	if w.tenants != nil {
+		tenant = w.tenants.pick(tv.reqNumber)
+		reqMD = tenant.metadata(reqMD)
 	}
-	defer cancel()
+
+	if compressor := w.config.compressorName(); compressor != "" {
+		reqMD.Append("grpc-accept-encoding", compressor)
+	}
+
+	var traceID string
+	if w.config.traceSample > 0 && rand.Float64() < w.config.traceSample {
+		var traceparent string
+		if traceID, traceparent, err = newTraceparent(); err != nil {
+			return err
+		}
+
+		// the request metadata may be shared between calls
+		md := metadata.MD{}
+		if reqMD != nil {
+			md = reqMD.Copy()
+		}
+
+		md.Set(traceparentKey, traceparent)
+		reqMD = &md
+	}
+
+	ctx := context.Background()
 
 	// include the metadata
 	if reqMD != nil {
 		ctx = metadata.NewOutgoingContext(ctx, *reqMD)
 	}
 
+	if w.shards != nil {
+		ctx = w.shards.route(ctx, reqMD)
+		defer w.shards.release(ctx)
+	}
+
+	if w.churn != nil {
+		ctx = w.churn.route(ctx, w.connIndex)
+		defer w.churn.release(ctx)
+	}
+
+	if w.scaling != nil {
+		ctx = w.scaling.route(ctx)
+	}
+
 	inputs, err := w.dataProvider(ctd)
 	if err != nil {
+		if errors.Is(err, ErrEndData) && w.endData != nil {
+			w.endData()
+
+			return nil
+		}
+
 		return err
 	}
 
+	if ctd.label != "" {
+		ctx = context.WithValue(ctx, callLabelKey{}, ctd.label)
+	}
+
+	ctx = context.WithValue(ctx, callWorkerKey{}, w.workerID)
+
+	if method != "" {
+		ctx = context.WithValue(ctx, callMethodKey{}, method)
+	}
+
+	if tenant != nil {
+		ctx = context.WithValue(ctx, callTenantKey{}, tenant.Name)
+	}
+
+	if !tv.intended.IsZero() {
+		ctx = context.WithValue(ctx, callIntendedKey{}, tv.intended)
+	}
+
+	if traceID != "" {
+		ctx = context.WithValue(ctx, callTraceKey{}, traceID)
+	}
+
+	if w.wireLog.sampled(reqNum) {
+		ctx = context.WithValue(ctx, wireLogKey{}, w.wireLog)
+	}
+
 	var msgProvider StreamMessageProviderFunc
 	if w.msgProvider != nil {
 		msgProvider = w.msgProvider
+	} else if w.payload != nil && len(inputs) > 0 {
+		msgProvider = w.payload.provider(inputs[0])
 	} else if w.mtd.IsClientStreaming() {
 		if w.config.streamDynamicMessages {
 			mp, err := newDynamicMessageProvider(w.mtd, w.config.data, w.config.streamCallCount)
@@ -121,6 +363,8 @@ func (w *Worker) makeRequest(tv TickValue) error {
 				return err
 			}
 
+			mp.emitDefaults = w.config.emitDefaults
+
 			msgProvider = mp.GetStreamMessage
 		} else {
 			mp, err := newStaticMessageProvider(w.config.streamCallCount, inputs)
@@ -152,29 +396,218 @@ func (w *Worker) makeRequest(tv TickValue) error {
 			"input", inputs, "metadata", reqMD)
 	}
 
-	// RPC errors are handled via stats handler
-	if w.mtd.IsClientStreaming() && w.mtd.IsServerStreaming() {
-		_ = w.makeBidiRequest(&ctx, ctd, msgProvider)
-	} else if w.mtd.IsClientStreaming() {
-		_ = w.makeClientStreamingRequest(&ctx, ctd, msgProvider)
-	} else if w.mtd.IsServerStreaming() {
-		_ = w.makeServerStreamingRequest(&ctx, inputs[0])
-	} else {
-		_ = w.makeUnaryRequest(&ctx, reqMD, inputs[0])
+	if w.config.beforeCall != nil {
+		if hctx := w.config.beforeCall(ctx, ctd); hctx != nil {
+			ctx = hctx
+		}
 	}
 
-	return err
+	if w.pages != nil {
+		w.paginate(ctx, ctd, reqMD, inputs[0])
+
+		return nil
+	}
+
+	pos, callStart := w.chain.pos, time.Now()
+	res, callErr := w.invoke(ctx, ctd, reqMD, inputs, msgProvider)
+
+	if w.iterations != nil {
+		w.chain.calls = append(w.chain.calls, iterationCall{
+			pos:     pos,
+			offset:  callStart.Sub(w.chain.started),
+			latency: time.Since(callStart),
+		})
+	}
+
+	if sc != nil {
+		if err := w.scenario.advance(&w.chain, sc, res, callErr); err != nil && w.config.hasLog {
+			w.config.log.Errorw("Error extracting the scenario variables: "+err.Error(), "workerID", w.workerID,
+				"error", err)
+		}
+	}
+
+	return nil
 }
 
-func (w *Worker) makeUnaryRequest(ctx *context.Context, reqMD *metadata.MD, input *dynamic.Message) error {
-	var res proto.Message
-	var resErr error
+// paginate makes the logical operation of the pagination mode, repeating the call with
+// the page token of each response until the listing is exhausted
+func (w *Worker) paginate(ctx context.Context, ctd *CallData, reqMD *metadata.MD, input *dynamic.Message) {
+	start := time.Now()
+	pages := uint(0)
+
+	for {
+		res, callErr := w.invoke(ctx, ctd, reqMD, []*dynamic.Message{input}, nil)
+		pages++
+
+		if callErr != nil {
+			w.pages.record(pages, time.Since(start), false, callErr)
+
+			return
+		}
+
+		next, err := w.pages.nextPage(input, res)
+		if err != nil {
+			w.pages.record(pages, time.Since(start), false, err)
+
+			if w.config.hasLog {
+				w.config.log.Errorw("Error making the next page request: "+err.Error(), "workerID", w.workerID,
+					"error", err)
+			}
+
+			return
+		}
+
+		if next == nil || (w.pages.maxPages > 0 && pages >= w.pages.maxPages) {
+			w.pages.record(pages, time.Since(start), next == nil, nil)
+
+			return
+		}
+
+		input = next
+	}
+}
+
+// invoke makes a single call and returns the response and the error of the call
+func (w *Worker) invoke(ctx context.Context, ctd *CallData, reqMD *metadata.MD, inputs []*dynamic.Message,
+	msgProvider StreamMessageProviderFunc) (proto.Message, error) {
+	var req, res proto.Message
+	var callErr error
+	var start time.Time
+
+	// async calls are not drained, their responses are handled by the response handlers
+	if w.responses == nil {
+		w.drain.begin()
+		defer w.drain.end()
+	}
+
+	for {
+		callCtx := ctx
+		var cancel context.CancelFunc
+
+		if w.config.timeout > 0 {
+			callCtx, cancel = context.WithTimeout(callCtx, w.config.timeout)
+		} else {
+			callCtx, cancel = context.WithCancel(callCtx)
+		}
+
+		var done func()
+		callCtx, done = withCallMax(callCtx, w.maxCallDuration(), cancel)
+
+		var gc *graceCall
+		if w.grace.active() {
+			gc = &graceCall{grace: w.grace}
+			callCtx = context.WithValue(callCtx, callGraceKey{}, gc)
+		}
+
+		start = time.Now()
+
+		// RPC errors are handled via stats handler
+		if w.mtd.IsClientStreaming() && w.mtd.IsServerStreaming() {
+			callErr = w.makeBidiRequest(&callCtx, ctd, msgProvider)
+		} else if w.mtd.IsClientStreaming() {
+			res, callErr = w.makeClientStreamingRequest(&callCtx, ctd, msgProvider)
+		} else if w.mtd.IsServerStreaming() {
+			req = inputs[0]
+			callErr = w.makeServerStreamingRequest(&callCtx, inputs[0])
+		} else if w.responses != nil {
+			// the response is handled by the response handlers
+			w.makeAsyncUnaryRequest(&callCtx, ctd, start, reqMD, inputs[0], gc)
+		} else if w.hedge != nil {
+			req = inputs[0]
+			res, callErr = w.makeHedgedUnaryRequest(&callCtx, reqMD, inputs[0])
+		} else {
+			req = inputs[0]
+			res, callErr = w.makeUnaryRequest(&callCtx, reqMD, inputs[0])
+		}
+
+		done()
+		cancel()
+
+		// calls failing during the grace period are not counted and are retried
+		if !gc.retry() {
+			break
+		}
+
+		if w.config.hasLog {
+			w.config.log.Debugw("Retrying call failed during grace period", "workerID", w.workerID,
+				"call", w.mtd.GetFullyQualifiedName(), "error", callErr)
+		}
+
+		time.Sleep(graceRetryDelay)
+	}
+
+	if w.responses != nil {
+		return nil, nil
+	}
+
+	latency := time.Since(start)
+
+	if w.schema != nil && isSchemaError(callErr) {
+		w.schema.refresh(w.mtd)
+	}
+
+	if w.fields != nil && res != nil && callErr == nil {
+		w.fields.record(res, latency)
+	}
+
+	if w.drift != nil && res != nil && callErr == nil {
+		w.drift.record(res)
+	}
+
+	if w.trackResponse && res != nil && callErr == nil {
+		if fields, err := responseFields(res); err == nil {
+			w.lastResponse = fields
+		} else if w.config.hasLog {
+			w.config.log.Errorw("Error decoding the last response: "+err.Error(), "workerID", w.workerID,
+				"error", err)
+		}
+	}
+
+	w.recordDebug(ctd, start, latency, reqMD, req, res, callErr)
+	w.afterCall(ctd, start, latency, req, res, callErr)
+
+	return res, callErr
+}
+
+func (w *Worker) recordDebug(ctd *CallData, start time.Time, latency time.Duration, reqMD *metadata.MD,
+	req, res proto.Message, callErr error) {
+	if err := w.debugger.record(ctd, reqMD, req, res, callErr); err != nil && w.config.hasLog {
+		w.config.log.Errorw("Error writing call debug details: "+err.Error(), "workerID", w.workerID,
+			"error", err)
+	}
+
+	if err := w.slowCalls.record(ctd, start, latency, reqMD, req, res, callErr); err != nil && w.config.hasLog {
+		w.config.log.Errorw("Error writing slow call details: "+err.Error(), "workerID", w.workerID,
+			"error", err)
+	}
+
+	if err := w.capture.record(ctd, start, latency, res, callErr); err != nil && w.config.hasLog {
+		w.config.log.Errorw("Error writing captured response: "+err.Error(), "workerID", w.workerID,
+			"error", err)
+	}
+}
+
+// callOptions returns the options of the calls, with the compressor and the codec if used,
+// followed by the call options of the run
+func (w *Worker) callOptions() []grpc.CallOption {
 	var callOptions = []grpc.CallOption{}
-	if w.config.enableCompression {
-		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
+	if compressor := w.config.compressorName(); compressor != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(compressor))
 	}
 
-	res, resErr = w.stub.InvokeRpc(*ctx, w.mtd, input, callOptions...)
+	if w.config.codec != nil {
+		callOptions = append(callOptions, grpc.ForceCodec(w.config.codec), grpc.CallContentSubtype(w.config.codec.Name()))
+	}
+
+	return append(callOptions, w.config.runCallOptions()...)
+}
+
+func (w *Worker) makeUnaryRequest(ctx *context.Context, reqMD *metadata.MD, input *dynamic.Message) (proto.Message, error) {
+	var res proto.Message
+	var resErr error
+	callOptions := w.callOptions()
+
+	res, resErr = w.callStub(*ctx).InvokeRpc(*ctx, w.mtd, input, callOptions...)
 
 	if w.config.hasLog {
 		w.config.log.Debugw("Received response", "workerID", w.workerID, "call type", "unary",
@@ -183,17 +616,14 @@ func (w *Worker) makeUnaryRequest(ctx *context.Context, reqMD *metadata.MD, inpu
 			"response", res, "error", resErr)
 	}
 
-	return resErr
+	return res, resErr
 }
 
 func (w *Worker) makeClientStreamingRequest(ctx *context.Context,
-	ctd *CallData, messageProvider StreamMessageProviderFunc) error {
+	ctd *CallData, messageProvider StreamMessageProviderFunc) (proto.Message, error) {
 	var str *grpcdynamic.ClientStream
-	var callOptions = []grpc.CallOption{}
-	if w.config.enableCompression {
-		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
-	}
-	str, err := w.stub.InvokeRpcClientStream(*ctx, w.mtd, callOptions...)
+	callOptions := w.callOptions()
+	str, err := w.callStub(*ctx).InvokeRpcClientStream(*ctx, w.mtd, callOptions...)
 	if err != nil {
 		if w.config.hasLog {
 			w.config.log.Errorw("Invoke Client Streaming RPC call error: "+err.Error(), "workerID", w.workerID,
@@ -201,16 +631,27 @@ func (w *Worker) makeClientStreamingRequest(ctx *context.Context,
 				"call", w.mtd.GetFullyQualifiedName(), "error", err)
 		}
 
-		return err
+		return nil, err
 	}
 
+	// the number of messages sent, including the last one, to check the acknowledged count
+	var sent uint64
+
+	var res proto.Message
 	closeStream := func() {
-		res, closeErr := str.CloseAndReceive()
+		closeRes, closeErr := str.CloseAndReceive()
+		if closeErr == nil {
+			res = closeRes
+
+			if w.acks != nil {
+				w.acks.check(sent, closeRes)
+			}
+		}
 
 		if w.config.hasLog {
 			w.config.log.Debugw("Close and receive", "workerID", w.workerID, "call type", "client-streaming",
 				"call", w.mtd.GetFullyQualifiedName(),
-				"response", res, "error", closeErr)
+				"response", closeRes, "error", closeErr)
 		}
 	}
 
@@ -273,6 +714,10 @@ func (w *Worker) makeClientStreamingRequest(ctx *context.Context,
 		}
 
 		end, err = performSend(payload)
+		if !end && err == nil {
+			sent++
+		}
+
 		if end || err != nil || isLast || len(cancel) > 0 {
 			break
 		}
@@ -307,19 +752,16 @@ func (w *Worker) makeClientStreamingRequest(ctx *context.Context,
 	close(doneCh)
 	close(cancel)
 
-	return nil
+	return res, nil
 }
 
 func (w *Worker) makeServerStreamingRequest(ctx *context.Context, input *dynamic.Message) error {
-	var callOptions = []grpc.CallOption{}
-	if w.config.enableCompression {
-		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
-	}
+	callOptions := w.callOptions()
 
 	callCtx, callCancel := context.WithCancel(*ctx)
 	defer callCancel()
 
-	str, err := w.stub.InvokeRpcServerStream(callCtx, w.mtd, input, callOptions...)
+	str, err := w.callStub(callCtx).InvokeRpcServerStream(callCtx, w.mtd, input, callOptions...)
 
 	if err != nil {
 		if w.config.hasLog {
@@ -350,6 +792,7 @@ func (w *Worker) makeServerStreamingRequest(ctx *context.Context, input *dynamic
 		}()
 	}
 
+	streamStart := time.Now()
 	interceptCanceled := false
 	counter := uint(0)
 	for err == nil {
@@ -360,9 +803,19 @@ func (w *Worker) makeServerStreamingRequest(ctx *context.Context, input *dynamic
 			break
 		}
 
+		recvStart := time.Now()
+
 		var res proto.Message
 		res, err = str.RecvMsg()
 
+		if w.backpressure != nil && err == nil && counter > 0 {
+			w.backpressure.recordWait(time.Since(recvStart))
+		}
+
+		if w.drift != nil && err == nil {
+			w.drift.record(res)
+		}
+
 		if w.config.hasLog {
 			w.config.log.Debugw("Receive message", "workerID", w.workerID, "call type", "server-streaming",
 				"call", w.mtd.GetFullyQualifiedName(),
@@ -402,6 +855,15 @@ func (w *Worker) makeServerStreamingRequest(ctx *context.Context, input *dynamic
 			<-cancel
 			callCancel()
 		}
+
+		// the remaining messages are still drained once the call is canceled
+		if w.backpressure != nil {
+			w.backpressure.wait(callCtx)
+		}
+	}
+
+	if w.backpressure != nil {
+		w.backpressure.recordStream(uint64(counter), time.Since(streamStart))
 	}
 
 	close(doneCh)
@@ -413,12 +875,9 @@ func (w *Worker) makeServerStreamingRequest(ctx *context.Context, input *dynamic
 func (w *Worker) makeBidiRequest(ctx *context.Context,
 	ctd *CallData, messageProvider StreamMessageProviderFunc) error {
 
-	var callOptions = []grpc.CallOption{}
+	callOptions := w.callOptions()
 
-	if w.config.enableCompression {
-		callOptions = append(callOptions, grpc.UseCompressor(gzip.Name))
-	}
-	str, err := w.stub.InvokeRpcBidiStream(*ctx, w.mtd, callOptions...)
+	str, err := w.callStub(*ctx).InvokeRpcBidiStream(*ctx, w.mtd, callOptions...)
 
 	if err != nil {
 		if w.config.hasLog {
@@ -430,6 +889,12 @@ func (w *Worker) makeBidiRequest(ctx *context.Context,
 		return err
 	}
 
+	var correlator *streamCorrelator
+	if w.stream != nil {
+		correlator = w.stream.newStream()
+		defer correlator.close()
+	}
+
 	counter := uint(0)
 	indexCounter := 0
 	recvDone := make(chan bool)
@@ -477,6 +942,14 @@ func (w *Worker) makeBidiRequest(ctx *context.Context,
 					"response", res, "error", recvErr)
 			}
 
+			if correlator != nil && recvErr == nil {
+				correlator.received(res)
+			}
+
+			if w.drift != nil && recvErr == nil {
+				w.drift.record(res)
+			}
+
 			if w.streamRecv != nil {
 				if converted, ok := res.(*dynamic.Message); ok {
 					iErr := w.streamRecv(converted, recvErr)
@@ -531,6 +1004,10 @@ func (w *Worker) makeBidiRequest(ctx *context.Context,
 				break
 			}
 
+			if correlator != nil {
+				correlator.sent(payload)
+			}
+
 			err = str.SendMsg(payload)
 			if err != nil {
 				if err == io.EOF {
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAffinityCPU is the number of CPUs the affinity masks can hold
+const maxAffinityCPU = 1024
+
+// anyCPU is the CPU list of a group whose workers are locked to their threads without an affinity
+const anyCPU = "any"
+
+// WorkerGroupStats holds the latency statistics of the calls made by the workers of a group
+type WorkerGroupStats struct {
+	// Group is the index of the group
+	Group int `json:"group"`
+
+	// CPUs is the CPU list the threads of the workers are pinned to, or any if they are not pinned
+	CPUs string `json:"cpus"`
+
+	// Workers is the number of workers which were in the group
+	Workers int `json:"workers"`
+
+	Count               uint64                `json:"count"`
+	Average             time.Duration         `json:"average"`
+	Fastest             time.Duration         `json:"fastest"`
+	Slowest             time.Duration         `json:"slowest"`
+	LatencyDistribution []LatencyDistribution `json:"latencyDistribution"`
+}
+
+// workerGroup is a group of workers whose threads are pinned to the same CPUs
+type workerGroup struct {
+	index int
+	spec  string
+	cpus  []int
+}
+
+// pin locks the calling goroutine to its thread and sets the affinity of the thread to the CPUs of
+// the group, if any. The thread is never unlocked, so it exits along with the goroutine of the worker
+// and its affinity is not passed on to the other goroutines.
+func (g *workerGroup) pin() error {
+	runtime.LockOSThread()
+
+	if len(g.cpus) == 0 {
+		return nil
+	}
+
+	if err := setThreadAffinity(g.cpus); err != nil {
+		return fmt.Errorf("error pinning the workers of group %d to CPUs %s: %v", g.index, g.spec, err)
+	}
+
+	return nil
+}
+
+// workerGroups assigns the workers to the groups in turn and tracks the group of each worker,
+// so the latency of the calls can be reported by group
+type workerGroups struct {
+	groups []*workerGroup
+
+	lock    sync.Mutex
+	members map[string]int
+}
+
+func newWorkerGroups(c *RunConfig) *workerGroups {
+	if len(c.workerGroups) == 0 {
+		return nil
+	}
+
+	g := &workerGroups{members: make(map[string]int)}
+	for i, spec := range c.workerGroups {
+		cpus, _ := parseCPUList(spec)
+		g.groups = append(g.groups, &workerGroup{index: i, spec: spec, cpus: cpus})
+	}
+
+	return g
+}
+
+// join assigns the worker with the ID and index to a group, returning the group
+func (g *workerGroups) join(workerID string, index int) *workerGroup {
+	if g == nil {
+		return nil
+	}
+
+	group := g.groups[index%len(g.groups)]
+
+	g.lock.Lock()
+	g.members[workerID] = group.index
+	g.lock.Unlock()
+
+	return group
+}
+
+// stats returns the latency statistics of each group, which are those of the successful calls
+// unless the errors are counted
+func (g *workerGroups) stats(details detailIter, countErrors bool) []WorkerGroupStats {
+	if g == nil {
+		return nil
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	lats := make([][]float64, len(g.groups))
+	details.each(func(d *ResultDetail) {
+		group, ok := g.members[d.Worker]
+		if ok && (d.Error == "" || countErrors) {
+			lats[group] = append(lats[group], d.Latency.Seconds())
+		}
+	})
+
+	workers := make([]int, len(g.groups))
+	for _, group := range g.members {
+		workers[group]++
+	}
+
+	res := make([]WorkerGroupStats, 0, len(g.groups))
+	for i, l := range lats {
+		s := WorkerGroupStats{Group: i, CPUs: g.groups[i].spec, Workers: workers[i], Count: uint64(len(l))}
+
+		if len(l) > 0 {
+			sort.Float64s(l)
+
+			var total float64
+			for _, v := range l {
+				total += v
+			}
+
+			s.Average = time.Duration(total / float64(len(l)) * float64(time.Second))
+			s.Fastest = time.Duration(l[0] * float64(time.Second))
+			s.Slowest = time.Duration(l[len(l)-1] * float64(time.Second))
+			s.LatencyDistribution = latencies(l)
+		}
+
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// parseCPUList parses a list of CPUs such as 0-3,6, in the format of the cpuset of Linux,
+// returning no CPUs for any
+func parseCPUList(s string) ([]int, error) {
+	if s == anyCPU {
+		return nil, nil
+	}
+
+	seen := make(map[int]bool)
+	var cpus []int
+
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q: expected CPUs and ranges of CPUs such as 0-3,6, or any", s)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU list %q: expected CPUs and ranges of CPUs such as 0-3,6, or any", s)
+			}
+		}
+
+		if last >= maxAffinityCPU {
+			return nil, fmt.Errorf("invalid CPU list %q: CPU %d is out of range", s, last)
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+
+	return cpus, nil
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUList(t *testing.T) {
+	var tests = []struct {
+		in       string
+		expected []int
+		err      string
+	}{
+		{"any", nil, ""},
+		{"0", []int{0}, ""},
+		{"0-3,6", []int{0, 1, 2, 3, 6}, ""},
+		{"2-3,3,1", []int{2, 3, 1}, ""},
+		{"3-1", nil, `invalid CPU list "3-1": expected CPUs and ranges of CPUs such as 0-3,6, or any`},
+		{"a", nil, `invalid CPU list "a": expected CPUs and ranges of CPUs such as 0-3,6, or any`},
+		{"0,", nil, `invalid CPU list "0,": expected CPUs and ranges of CPUs such as 0-3,6, or any`},
+		{"0-1024", nil, `invalid CPU list "0-1024": CPU 1024 is out of range`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			cpus, err := parseCPUList(tt.in)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cpus)
+		})
+	}
+}
+
+func TestWorkerGroups_stats(t *testing.T) {
+	g := newWorkerGroups(&RunConfig{workerGroups: []string{"0-1", "any"}})
+
+	assert.Equal(t, 0, g.join("g0c0", 0).index)
+	assert.Equal(t, 1, g.join("g1c1", 1).index)
+	assert.Equal(t, 0, g.join("g2c0", 2).index)
+	assert.Equal(t, []int{0, 1}, g.groups[0].cpus)
+
+	details := []ResultDetail{
+		{Worker: "g0c0", Latency: 10 * time.Millisecond, Status: "OK"},
+		{Worker: "g2c0", Latency: 30 * time.Millisecond, Status: "OK"},
+		{Worker: "g1c1", Latency: 20 * time.Millisecond, Status: "OK"},
+		{Worker: "g1c1", Latency: 50 * time.Millisecond, Status: "Unavailable", Error: "unavailable"},
+	}
+
+	stats := g.stats(detailIter{details: details}, false)
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, 0, stats[0].Group)
+		assert.Equal(t, "0-1", stats[0].CPUs)
+		assert.Equal(t, 2, stats[0].Workers)
+		assert.Equal(t, uint64(2), stats[0].Count)
+		assert.Equal(t, 20*time.Millisecond, stats[0].Average)
+		assert.Equal(t, 10*time.Millisecond, stats[0].Fastest)
+		assert.Equal(t, 30*time.Millisecond, stats[0].Slowest)
+
+		assert.Equal(t, "any", stats[1].CPUs)
+		assert.Equal(t, 1, stats[1].Workers)
+		assert.Equal(t, uint64(1), stats[1].Count)
+	}
+
+	// the errors are included when counted
+	stats = g.stats(detailIter{details: details}, true)
+	assert.Equal(t, uint64(2), stats[1].Count)
+	assert.Equal(t, 50*time.Millisecond, stats[1].Slowest)
+
+	var none *workerGroups
+	assert.Nil(t, none.join("g0c0", 0))
+	assert.Nil(t, none.stats(detailIter{details: details}, false))
+}
+
+func TestRunWorkerGroups(t *testing.T) {
+	_, s, err := internal.StartServer(false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer s.Stop()
+
+	groups := []string{"any"}
+	if affinitySupported {
+		groups = append(groups, "0")
+	}
+
+	report, err := Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithTotalRequests(20),
+		WithConcurrency(4),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithWorkerGroups(groups...),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), report.Count)
+	assert.Equal(t, groups, report.Options.WorkerGroups)
+
+	if assert.Len(t, report.WorkerGroups, len(groups)) {
+		var count uint64
+		for i, g := range report.WorkerGroups {
+			assert.Equal(t, i, g.Group)
+			assert.Equal(t, groups[i], g.CPUs)
+			assert.Equal(t, 4/len(groups), g.Workers)
+
+			count += g.Count
+		}
+
+		assert.Equal(t, uint64(20), count)
+	}
+
+	_, err = Run(
+		"helloworld.Greeter.SayHello",
+		internal.TestLocalhost,
+		WithProtoFile("../testdata/greeter.proto", []string{}),
+		WithData(map[string]interface{}{"name": "bob"}),
+		WithInsecure(true),
+		WithAsync(true),
+		WithAsyncPools(2, 2),
+		WithWorkerGroups("any"),
+	)
+
+	assert.EqualError(t, err, "worker groups cannot be used with async sender pools")
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bojand/ghz/runner"
+	"github.com/bojand/ghz/web/model"
+	"github.com/labstack/echo"
+)
+
+// CompareDatabase interface for encapsulating database access.
+type CompareDatabase interface {
+	FindProjectByID(uint) (*model.Project, error)
+	FindReportByID(uint) (*model.Report, error)
+	FindLatestReportForProject(uint) (*model.Report, error)
+	UpdateProjectBaseline(uint, uint) error
+}
+
+// The CompareAPI provides handlers for comparing reports against the baseline of a project.
+type CompareAPI struct {
+	DB CompareDatabase
+}
+
+// BaselineRequest sets the baseline report of a project
+type BaselineRequest struct {
+	// The id of the report, which has to belong to the project
+	ReportID uint `json:"reportId"`
+}
+
+// CompareRequest is the raw report to compare with the baseline
+type CompareRequest struct {
+	// Baseline is the report to compare with, being the id of a report of the project or latest.
+	// By default it is the baseline of the project, or the latest report if the project has none.
+	Baseline string `json:"baseline"`
+
+	// Report is the raw report
+	Report runner.Report `json:"report"`
+
+	// Tolerance is the regression tolerated, the zero values using the defaults
+	Tolerance model.Tolerance `json:"tolerance"`
+}
+
+// CompareResponse is the result of the comparison, suitable for a GitHub commit status
+type CompareResponse struct {
+	model.Comparison
+
+	// Context is the context of the commit status
+	Context string `json:"context"`
+
+	// Baseline is the baseline report compared with, if any
+	Baseline *model.Report `json:"baseline,omitempty"`
+}
+
+// SetBaseline sets the baseline report of a project
+func (api *CompareAPI) SetBaseline(ctx echo.Context) error {
+	var project *model.Project
+	var err error
+
+	if project, err = findProject(api.DB.FindProjectByID, ctx); err != nil {
+		return err
+	}
+
+	br := new(BaselineRequest)
+	if err := ctx.Bind(br); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	report, err := api.DB.FindReportByID(br.ReportID)
+	if err != nil || report.ProjectID != project.ID {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("report %d not found in project %d", br.ReportID, project.ID))
+	}
+
+	if err := api.DB.UpdateProjectBaseline(project.ID, report.ID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	project.BaselineID = report.ID
+
+	return ctx.JSON(http.StatusOK, project)
+}
+
+// Compare compares a raw report with the baseline of a project. The comparison is always
+// responded with OK, its state being error if the report could not be compared.
+func (api *CompareAPI) Compare(ctx echo.Context) error {
+	var project *model.Project
+	var err error
+
+	if project, err = findProject(api.DB.FindProjectByID, ctx); err != nil {
+		return err
+	}
+
+	cr := new(CompareRequest)
+	if err := ctx.Bind(cr); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if ctx.Echo().Validator != nil {
+		if err := ctx.Validate(cr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	res := &CompareResponse{Context: "ghz/" + project.Name}
+
+	baseline, err := api.findBaseline(project, cr.Baseline)
+	if err != nil {
+		res.State = model.CompareError
+		res.Description = err.Error()
+
+		return ctx.JSON(http.StatusOK, res)
+	}
+
+	report := convertIngestToReport(project.ID, (*IngestRequest)(&cr.Report))
+
+	res.Comparison = *model.CompareReports(baseline, report, cr.Tolerance)
+	res.Baseline = baseline
+
+	return ctx.JSON(http.StatusOK, res)
+}
+
+func (api *CompareAPI) findBaseline(project *model.Project, ref string) (*model.Report, error) {
+	if ref == "" && project.BaselineID != 0 {
+		ref = strconv.FormatUint(uint64(project.BaselineID), 10)
+	}
+
+	if ref == "" || ref == "latest" {
+		latest, err := api.DB.FindLatestReportForProject(project.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if latest == nil {
+			return nil, fmt.Errorf("no baseline report in project %d", project.ID)
+		}
+
+		return latest, nil
+	}
+
+	id, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline %q: expected a report id or latest", ref)
+	}
+
+	report, err := api.DB.FindReportByID(uint(id))
+	if err != nil || report.ProjectID != project.ID {
+		return nil, fmt.Errorf("baseline report %d not found in project %d", id, project.ID)
+	}
+
+	return report, nil
+}
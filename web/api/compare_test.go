@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bojand/ghz/web/database"
+	"github.com/bojand/ghz/web/model"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareAPI(t *testing.T) {
+	os.Remove(dbName)
+
+	defer os.Remove(dbName)
+
+	db, err := database.New("sqlite3", dbName, false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer db.Close()
+
+	api := CompareAPI{DB: db}
+	ingestAPI := IngestAPI{DB: db}
+
+	report1, err := ioutil.ReadFile("../test/SayHello/report1.json")
+	assert.NoError(t, err)
+
+	report2, err := ioutil.ReadFile("../test/SayHello/report2.json")
+	assert.NoError(t, err)
+
+	p := &model.Project{Name: "Compare"}
+	assert.NoError(t, db.CreateProject(p))
+	pid := strconv.FormatUint(uint64(p.ID), 10)
+
+	var rid uint
+
+	compare := func(t *testing.T, body string) *CompareResponse {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/projects/:pid/compare")
+		c.SetParamNames("pid")
+		c.SetParamValues(pid)
+
+		if !assert.NoError(t, api.Compare(c)) {
+			return nil
+		}
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		r := new(CompareResponse)
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(r))
+
+		return r
+	}
+
+	t.Run("no baseline", func(t *testing.T) {
+		r := compare(t, `{"report":`+string(report1)+`}`)
+
+		assert.Equal(t, model.CompareError, r.State)
+		assert.Equal(t, "no baseline report in project "+pid, r.Description)
+		assert.Equal(t, "ghz/Compare", r.Context)
+		assert.Nil(t, r.Baseline)
+	})
+
+	t.Run("ingest", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(report2)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/projects/:pid/ingest")
+		c.SetParamNames("pid")
+		c.SetParamValues(pid)
+
+		if assert.NoError(t, ingestAPI.IngestToProject(c)) {
+			r := new(IngestResponse)
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(r))
+			rid = r.Report.ID
+		}
+	})
+
+	t.Run("latest regressed", func(t *testing.T) {
+		r := compare(t, `{"report":`+string(report1)+`}`)
+
+		assert.Equal(t, model.CompareFailure, r.State)
+		assert.True(t, strings.HasPrefix(r.Description, "Regressed: average +14.1%"), r.Description)
+		assert.Len(t, r.Checks, 5)
+		if assert.NotNil(t, r.Baseline) {
+			assert.Equal(t, rid, r.Baseline.ID)
+		}
+	})
+
+	t.Run("latest with tolerance", func(t *testing.T) {
+		r := compare(t, `{"baseline":"latest","tolerance":{"latency":50,"rps":20},"report":`+string(report1)+`}`)
+
+		assert.Equal(t, model.CompareSuccess, r.State)
+		assert.True(t, strings.HasPrefix(r.Description, "No regression: average +14.1%"), r.Description)
+	})
+
+	t.Run("invalid baseline", func(t *testing.T) {
+		r := compare(t, `{"baseline":"first","report":`+string(report1)+`}`)
+
+		assert.Equal(t, model.CompareError, r.State)
+		assert.Equal(t, `invalid baseline "first": expected a report id or latest`, r.Description)
+	})
+
+	t.Run("missing baseline", func(t *testing.T) {
+		r := compare(t, `{"baseline":"4321","report":`+string(report1)+`}`)
+
+		assert.Equal(t, model.CompareError, r.State)
+		assert.Equal(t, "baseline report 4321 not found in project "+pid, r.Description)
+	})
+
+	setBaseline := func(body string) (*httptest.ResponseRecorder, error) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/projects/:pid/baseline")
+		c.SetParamNames("pid")
+		c.SetParamValues(pid)
+
+		return rec, api.SetBaseline(c)
+	}
+
+	t.Run("set missing baseline", func(t *testing.T) {
+		_, err := setBaseline(`{"reportId":4321}`)
+
+		if assert.Error(t, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("set baseline", func(t *testing.T) {
+		rec, err := setBaseline(`{"reportId":` + strconv.FormatUint(uint64(rid), 10) + `}`)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			r := new(model.Project)
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(r))
+			assert.Equal(t, rid, r.BaselineID)
+		}
+
+		project, err := db.FindProjectByID(p.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, rid, project.BaselineID)
+	})
+
+	t.Run("project baseline", func(t *testing.T) {
+		r := compare(t, `{"report":`+string(report2)+`}`)
+
+		assert.Equal(t, model.CompareSuccess, r.State)
+		assert.Equal(t, "No regression: average +0.0%, p99 +0.0%, rps +0.0%", r.Description)
+		if assert.NotNil(t, r.Baseline) {
+			assert.Equal(t, rid, r.Baseline.ID)
+		}
+	})
+}
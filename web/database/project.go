@@ -46,6 +46,15 @@ func (d *Database) UpdateProjectStatus(pid uint, status model.Status) error {
 	return d.DB.Model(p).UpdateColumn("status", status).Error
 }
 
+// UpdateProjectBaseline updates the project's baseline report
+func (d *Database) UpdateProjectBaseline(pid uint, rid uint) error {
+	p := new(model.Project)
+	p.ID = pid
+
+	// use UpdateColumn to circumvent update hooks and not modify updated at time
+	return d.DB.Model(p).UpdateColumn("baseline_id", rid).Error
+}
+
 // ListProjects lists projects using sorting
 func (d *Database) ListProjects(limit, page uint, sortField, order string) ([]*model.Project, error) {
 	if sortField != "name" && sortField != "id" {
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// The states of a comparison, which are those of a GitHub commit status
+const (
+	// CompareSuccess means the report did not regress against the baseline
+	CompareSuccess = "success"
+
+	// CompareFailure means the report regressed against the baseline
+	CompareFailure = "failure"
+
+	// CompareError means the report could not be compared, such as without a baseline
+	CompareError = "error"
+)
+
+// The default tolerances of the comparisons
+const (
+	// DefaultLatencyTolerance is the increase of the latency in percent
+	DefaultLatencyTolerance = 10
+
+	// DefaultRpsTolerance is the decrease of the rate in percent
+	DefaultRpsTolerance = 10
+
+	// DefaultErrorRateTolerance is the increase of the error rate in percentage points
+	DefaultErrorRateTolerance = 1
+)
+
+// maxCompareDescription is the maximum length of the description of a GitHub commit status
+const maxCompareDescription = 140
+
+// Tolerance is the regression tolerated by a comparison. The zero values use the defaults.
+type Tolerance struct {
+	// Latency is the increase of the average, p95 and p99 latency in percent
+	Latency float64 `json:"latency,omitempty"`
+
+	// Rps is the decrease of the rate in percent
+	Rps float64 `json:"rps,omitempty"`
+
+	// ErrorRate is the increase of the error rate in percentage points
+	ErrorRate float64 `json:"errorRate,omitempty"`
+}
+
+// CompareCheck is the comparison of a metric of the report with the baseline
+type CompareCheck struct {
+	// Metric is one of average, p95, p99, rps and errorRate
+	Metric string `json:"metric"`
+
+	// Baseline and Actual are the values of the baseline and the report, being
+	// milliseconds for the latency, the rate and the error rate in percent
+	Baseline float64 `json:"baseline"`
+	Actual   float64 `json:"actual"`
+
+	// Change is the change of the metric, in percent of the baseline, or the
+	// difference in percentage points for the error rate
+	Change float64 `json:"change"`
+
+	// Tolerance is the regression tolerated
+	Tolerance float64 `json:"tolerance"`
+
+	Pass bool `json:"pass"`
+}
+
+// Comparison is the result of comparing a report with a baseline report
+type Comparison struct {
+	// State is one of success, failure and error
+	State string `json:"state"`
+
+	// Description is the summary of the comparison, no longer than the description of a GitHub status
+	Description string `json:"description"`
+
+	Checks []*CompareCheck `json:"checks,omitempty"`
+}
+
+// CompareReports compares the report with the baseline report, failing if the latency or the error
+// rate increased, or the rate decreased, by more than tolerated. The percentiles of the latency are
+// only compared if both reports have them.
+func CompareReports(baseline, report *Report, tolerance Tolerance) *Comparison {
+	if tolerance.Latency <= 0 {
+		tolerance.Latency = DefaultLatencyTolerance
+	}
+
+	if tolerance.Rps <= 0 {
+		tolerance.Rps = DefaultRpsTolerance
+	}
+
+	if tolerance.ErrorRate <= 0 {
+		tolerance.ErrorRate = DefaultErrorRateTolerance
+	}
+
+	c := &Comparison{State: CompareSuccess}
+
+	c.Checks = append(c.Checks, latencyCheck("average", baseline.Average, report.Average, tolerance.Latency))
+
+	for _, p := range []float64{95, 99} {
+		b, okb := percentileLatency(baseline, p)
+		a, oka := percentileLatency(report, p)
+
+		if okb && oka {
+			c.Checks = append(c.Checks, latencyCheck(fmt.Sprintf("p%.0f", p), b, a, tolerance.Latency))
+		}
+	}
+
+	rps := &CompareCheck{Metric: "rps", Baseline: baseline.Rps, Actual: report.Rps, Tolerance: tolerance.Rps}
+	rps.Change = percentChange(rps.Baseline, rps.Actual)
+	rps.Pass = rps.Change >= -tolerance.Rps
+	c.Checks = append(c.Checks, rps)
+
+	errs := &CompareCheck{Metric: "errorRate", Baseline: errorRate(baseline), Actual: errorRate(report), Tolerance: tolerance.ErrorRate}
+	errs.Change = round(errs.Actual - errs.Baseline)
+	errs.Pass = errs.Change <= tolerance.ErrorRate
+	c.Checks = append(c.Checks, errs)
+
+	// the description has the failed checks, or the average, p99 and rate if none failed
+	var failed, passed []string
+	for _, check := range c.Checks {
+		if !check.Pass {
+			failed = append(failed, check.summary())
+		} else if check.Metric == "average" || check.Metric == "p99" || check.Metric == "rps" {
+			passed = append(passed, check.summary())
+		}
+	}
+
+	if len(failed) > 0 {
+		c.State = CompareFailure
+		c.Description = truncateDescription("Regressed: " + strings.Join(failed, ", "))
+	} else {
+		c.Description = truncateDescription("No regression: " + strings.Join(passed, ", "))
+	}
+
+	return c
+}
+
+func latencyCheck(metric string, baseline, actual time.Duration, tolerance float64) *CompareCheck {
+	check := &CompareCheck{
+		Metric:    metric,
+		Baseline:  round(float64(baseline) / float64(time.Millisecond)),
+		Actual:    round(float64(actual) / float64(time.Millisecond)),
+		Tolerance: tolerance,
+	}
+
+	check.Change = percentChange(float64(baseline), float64(actual))
+	check.Pass = check.Change <= tolerance
+
+	return check
+}
+
+// summary returns the change of the metric, such as p99 +12.5%
+func (c *CompareCheck) summary() string {
+	if c.Metric == "errorRate" {
+		return fmt.Sprintf("error rate %+.2fpp", c.Change)
+	}
+
+	return fmt.Sprintf("%s %+.1f%%", c.Metric, c.Change)
+}
+
+// percentileLatency returns the latency of the percentile of the report, if any
+func percentileLatency(r *Report, p float64) (time.Duration, bool) {
+	for _, ld := range r.LatencyDistribution {
+		if ld != nil && ld.Percentage == int(p) {
+			return ld.Latency, true
+		}
+	}
+
+	return 0, false
+}
+
+// errorRate returns the error rate of the report in percent
+func errorRate(r *Report) float64 {
+	if r.Count == 0 {
+		return 0
+	}
+
+	errs := 0
+	for _, n := range r.ErrorDist {
+		errs += n
+	}
+
+	return round(float64(errs) / float64(r.Count) * 100)
+}
+
+// percentChange returns the change from the baseline in percent, 0 if the baseline is 0
+func percentChange(baseline, actual float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+
+	return round((actual - baseline) / baseline * 100)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func truncateDescription(s string) string {
+	if len(s) <= maxCompareDescription {
+		return s
+	}
+
+	return s[:maxCompareDescription-3] + "..."
+}
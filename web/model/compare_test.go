@@ -0,0 +1,85 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareReports(t *testing.T) {
+	baseline := &Report{
+		Count:   100,
+		Average: 10 * time.Millisecond,
+		Rps:     1000,
+		LatencyDistribution: LatencyDistributionList{
+			{Percentage: 95, Latency: 20 * time.Millisecond},
+			{Percentage: 99, Latency: 40 * time.Millisecond},
+		},
+	}
+
+	t.Run("no regression", func(t *testing.T) {
+		report := &Report{
+			Count:   100,
+			Average: 10500 * time.Microsecond,
+			Rps:     950,
+			LatencyDistribution: LatencyDistributionList{
+				{Percentage: 95, Latency: 21 * time.Millisecond},
+				{Percentage: 99, Latency: 40 * time.Millisecond},
+			},
+		}
+
+		c := CompareReports(baseline, report, Tolerance{})
+
+		assert.Equal(t, CompareSuccess, c.State)
+		assert.Equal(t, "No regression: average +5.0%, p99 +0.0%, rps -5.0%", c.Description)
+		assert.Len(t, c.Checks, 5)
+		assert.Equal(t, &CompareCheck{Metric: "average", Baseline: 10, Actual: 10.5, Change: 5, Tolerance: 10, Pass: true}, c.Checks[0])
+		assert.Equal(t, &CompareCheck{Metric: "rps", Baseline: 1000, Actual: 950, Change: -5, Tolerance: 10, Pass: true}, c.Checks[3])
+	})
+
+	t.Run("regression", func(t *testing.T) {
+		report := &Report{
+			Count:     100,
+			Average:   12 * time.Millisecond,
+			Rps:       1000,
+			ErrorDist: map[string]int{"timeout": 2},
+		}
+
+		c := CompareReports(baseline, report, Tolerance{Latency: 25})
+
+		assert.Equal(t, CompareFailure, c.State)
+		assert.Equal(t, "Regressed: error rate +2.00pp", c.Description)
+		assert.Len(t, c.Checks, 3)
+		assert.Equal(t, &CompareCheck{Metric: "errorRate", Baseline: 0, Actual: 2, Change: 2, Tolerance: 1, Pass: false}, c.Checks[2])
+	})
+
+	t.Run("rate", func(t *testing.T) {
+		report := &Report{Count: 100, Average: 10 * time.Millisecond, Rps: 800}
+
+		c := CompareReports(baseline, report, Tolerance{})
+
+		assert.Equal(t, CompareFailure, c.State)
+		assert.Equal(t, "Regressed: rps -20.0%", c.Description)
+	})
+
+	t.Run("truncated description", func(t *testing.T) {
+		report := &Report{
+			Count:     100,
+			Average:   100 * time.Millisecond,
+			Rps:       10,
+			ErrorDist: map[string]int{"timeout": 50},
+			LatencyDistribution: LatencyDistributionList{
+				{Percentage: 95, Latency: 200 * time.Millisecond},
+				{Percentage: 99, Latency: 400 * time.Millisecond},
+			},
+		}
+
+		c := CompareReports(baseline, report, Tolerance{})
+
+		assert.Equal(t, CompareFailure, c.State)
+		assert.True(t, len(c.Description) <= maxCompareDescription)
+		assert.True(t, strings.HasPrefix(c.Description, "Regressed: average +900.0%, p95 +900.0%"))
+	})
+}
@@ -13,6 +13,7 @@ type Project struct {
 	Name        string `json:"name" gorm:"not null"`
 	Description string `json:"description"`
 	Status      Status `json:"status" gorm:"not null"`
+	BaselineID  uint   `json:"baselineId,omitempty"`
 }
 
 // BeforeCreate is a GORM hook called when a model is created
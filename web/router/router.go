@@ -95,6 +95,12 @@ func New(db *database.Database, appInfo *api.ApplicationInfo, conf *config.Confi
 	// Ingest to project
 	projectGroup.POST("/:pid/ingest/", ingestAPI.IngestToProject).Name = "ghz api: ingest to project"
 
+	// Compare
+
+	compareAPI := api.CompareAPI{DB: db}
+	projectGroup.PUT("/:pid/baseline/", compareAPI.SetBaseline).Name = "ghz api: set project baseline"
+	projectGroup.POST("/:pid/compare/", compareAPI.Compare).Name = "ghz api: compare to project baseline"
+
 	// Info
 
 	infoAPI := api.InfoAPI{Info: *appInfo}